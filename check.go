@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Problems found by the check subcommand, selected with -fail-on
+const (
+	failOnDown     = "down"
+	failOnSlow     = "slow"
+	failOnAnyError = "any-error"
+)
+
+// Exit codes of the check subcommand: checks that failed the gate are told apart from
+// checks that could not run
+const (
+	exitChecksFailed = 1
+	exitCheckError   = 2
+)
+
+// exitError is a subcommand error that exits with its own code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// checkReport is the JSON output of the check subcommand
+type checkReport struct {
+	Passed  bool         `json:"passed"`
+	Targets []checkedURL `json:"targets"`
+}
+
+// checkedURL is the outcome of one target of the check subcommand
+type checkedURL struct {
+	URL            string   `json:"url"`
+	Name           string   `json:"name,omitempty"`
+	Up             bool     `json:"up"`
+	StatusCode     int      `json:"statusCode"`
+	ResponseTimeMs int64    `json:"responseTimeMs"`
+	Error          string   `json:"error,omitempty"`
+	SkippedBy      string   `json:"skippedBy,omitempty"`
+	Problems       []string `json:"problems,omitempty"`
+	Failed         bool     `json:"failed"`
+}
+
+// runCheck implements the check subcommand: it checks the configured targets once with
+// the exporter's probing logic and exits non-zero when a problem selected with -fail-on is
+// found, so deployment pipelines can gate on the checks
+func runCheck(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	failOn := flags.String("fail-on", failOnDown, "comma-separated problems that fail the check: down, slow or any-error")
+	maxLatency := flags.Duration("max-latency", 0, "response time above which a target is slow, e.g. 500ms (default no limit)")
+	target := flags.String("target", "", "only check the target with this name or URL (default all)")
+	format := flags.String("format", "text", "output format, text or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	gate, err := parseCheckOptions(flags, *failOn, *maxLatency, *format)
+	if err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return &exitError{code: exitCheckError, err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+	if err := checker.LoadPlugins(cfg.Plugins); err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+
+	targets := cfg.AllTargets()
+	if *target != "" {
+		targets = selectTarget(targets, *target)
+		if len(targets) == 0 {
+			return &exitError{code: exitCheckError, err: fmt.Errorf("target not found: %s", *target)}
+		}
+	}
+
+	chk := checker.New(cfg)
+	for _, registered := range chk.Targets() {
+		if !containsTarget(targets, registered.URL) {
+			chk.RemoveTarget(registered.URL)
+		}
+	}
+	results, err := chk.RunCycle(context.Background())
+	if err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+
+	names := make(map[string]string, len(targets))
+	for _, t := range targets {
+		names[t.URL] = t.Name
+	}
+	report := checkReport{Passed: true}
+	failed := 0
+	for _, result := range results {
+		checked := gate.evaluate(result, names[result.URL])
+		if checked.Failed {
+			failed++
+			report.Passed = false
+		}
+		report.Targets = append(report.Targets, checked)
+	}
+
+	if err := writeCheckReport(stdout, *format, report); err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+	if failed > 0 {
+		return &exitError{code: exitChecksFailed, err: fmt.Errorf("%d of %d targets failed", failed, len(results))}
+	}
+	return nil
+}
+
+// checkGate decides which problems of a result fail the check subcommand
+type checkGate struct {
+	failOn     map[string]bool
+	maxLatency time.Duration
+}
+
+func parseCheckOptions(flags *flag.FlagSet, failOn string, maxLatency time.Duration, format string) (checkGate, error) {
+	if flags.NArg() > 0 {
+		return checkGate{}, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if format != "text" && format != "json" {
+		return checkGate{}, fmt.Errorf("unsupported format %q: must be text or json", format)
+	}
+	if maxLatency < 0 {
+		return checkGate{}, errors.New("max-latency must not be negative")
+	}
+
+	gate := checkGate{failOn: make(map[string]bool), maxLatency: maxLatency}
+	for _, problem := range strings.Split(failOn, ",") {
+		problem = strings.TrimSpace(problem)
+		switch problem {
+		case failOnDown, failOnAnyError:
+		case failOnSlow:
+			if maxLatency == 0 {
+				return checkGate{}, errors.New("fail-on=slow requires max-latency")
+			}
+		default:
+			return checkGate{}, fmt.Errorf("unsupported fail-on %q: must be down, slow or any-error", problem)
+		}
+		gate.failOn[problem] = true
+	}
+	return gate, nil
+}
+
+// evaluate lists the problems of the result and whether one of them fails the check. Any
+// problem fails it with any-error: the target being down or slow, or a failed assertion.
+func (g checkGate) evaluate(result checker.Result, name string) checkedURL {
+	checked := checkedURL{
+		URL:            config.RedactURL(result.URL),
+		Name:           name,
+		Up:             result.IsUp(),
+		StatusCode:     result.StatusCode,
+		ResponseTimeMs: result.ResponseTime.Milliseconds(),
+	}
+	if result.Error != nil {
+		checked.Error = result.Error.Error()
+	}
+
+	// A skipped target does not fail the down gate, its dependency that is down does
+	switch {
+	case result.IsSkipped():
+		checked.SkippedBy = config.RedactURL(result.SkippedBy)
+		checked.Problems = append(checked.Problems, "skipped")
+	case !checked.Up:
+		checked.Problems = append(checked.Problems, failOnDown)
+		checked.Failed = g.failOn[failOnDown]
+	}
+	if g.maxLatency > 0 && result.Error == nil && result.ResponseTime > g.maxLatency {
+		checked.Problems = append(checked.Problems, failOnSlow)
+		checked.Failed = checked.Failed || g.failOn[failOnSlow]
+	}
+	if result.BodyMatch != nil && !*result.BodyMatch {
+		checked.Problems = append(checked.Problems, "body mismatch")
+	}
+	if result.HeaderMatch != nil && !*result.HeaderMatch {
+		checked.Problems = append(checked.Problems, "header mismatch")
+	}
+	if result.SizeWithinBounds != nil && !*result.SizeWithinBounds {
+		checked.Problems = append(checked.Problems, "body size out of bounds")
+	}
+	if g.failOn[failOnAnyError] && len(checked.Problems) > 0 {
+		checked.Failed = true
+	}
+	return checked
+}
+
+func writeCheckReport(w io.Writer, format string, report checkReport) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, target := range report.Targets {
+		state := "PASS"
+		if target.Failed {
+			state = "FAIL"
+		}
+		details := strings.Join(target.Problems, ", ")
+		if target.SkippedBy != "" {
+			details += ": " + target.SkippedBy + " is down"
+		}
+		if target.Error != "" {
+			details = strings.TrimPrefix(details+": "+target.Error, ": ")
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%dms\t%s\n", state, target.URL, target.StatusCode, target.ResponseTimeMs, details)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+func selectTarget(targets []config.Target, selector string) []config.Target {
+	for _, target := range targets {
+		if target.Name == selector || target.URL == selector {
+			return []config.Target{target}
+		}
+	}
+	return nil
+}
+
+func containsTarget(targets []config.Target, url string) bool {
+	for _, target := range targets {
+		if target.URL == url {
+			return true
+		}
+	}
+	return false
+}
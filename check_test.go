@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCheckConfig(t *testing.T) (fast, slow, down string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	fast, slow, down = server.URL+"/fast", server.URL+"/slow", server.URL+"/down"
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	config := `retries: 0
+checks:
+  - url: "` + fast + `"
+    name: "fast"
+  - url: "` + slow + `"
+    name: "slow"
+  - url: "` + down + `"
+    name: "down"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+	t.Setenv("URL_CONFIG_FILE", configFile)
+	return fast, slow, down
+}
+
+func exitCode(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	return -1
+}
+
+func TestRunCheck(t *testing.T) {
+	fast, slow, down := writeCheckConfig(t)
+
+	var out bytes.Buffer
+	err := runCheck(nil, &out)
+	require.Error(t, err)
+	assert.Equal(t, exitChecksFailed, exitCode(err))
+	assert.Contains(t, err.Error(), "1 of 3 targets failed")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "FAIL  "+down), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "PASS  "+fast), lines[1])
+
+	out.Reset()
+	assert.NoError(t, runCheck([]string{"-target", "fast", "-fail-on", "down,slow", "-max-latency", "50ms"}, &out))
+	assert.NoError(t, runCheck([]string{"-target", slow}, &out), "slow targets pass without fail-on=slow")
+
+	out.Reset()
+	err = runCheck([]string{"-target", "slow", "-fail-on", "slow", "-max-latency", "50ms", "-format", "json"}, &out)
+	assert.Equal(t, exitChecksFailed, exitCode(err))
+	var report checkReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.False(t, report.Passed)
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, "slow", report.Targets[0].Name)
+	assert.True(t, report.Targets[0].Up)
+	assert.Equal(t, []string{"slow"}, report.Targets[0].Problems)
+
+	err = runCheck([]string{"-target", "down", "-fail-on", "slow", "-max-latency", "50ms"}, &out)
+	assert.NoError(t, err, "down targets pass unless fail-on selects them")
+	err = runCheck([]string{"-target", "down", "-fail-on", "any-error"}, &out)
+	assert.Equal(t, exitChecksFailed, exitCode(err))
+}
+
+func TestRunCheck_InvalidOptions(t *testing.T) {
+	writeCheckConfig(t)
+
+	invalid := [][]string{
+		{"-fail-on", "sometimes"},
+		{"-fail-on", "slow"},
+		{"-max-latency", "-1s"},
+		{"-format", "xml"},
+		{"-target", "missing"},
+		{"https://example.com"},
+	}
+	for _, args := range invalid {
+		err := runCheck(args, &bytes.Buffer{})
+		require.Error(t, err, args)
+		assert.Equal(t, exitCheckError, exitCode(err), args)
+	}
+}
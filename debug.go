@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// runDebug implements the debug subcommand: it checks one URL, or a configured target by
+// name, with the configured settings and prints every step of the check, so why the
+// exporter reports a target down can be answered in one command
+func runDebug(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	module := flags.String("module", "", "probe module to check an unconfigured URL with")
+	method := flags.String("method", "", "HTTP method to check an unconfigured URL with")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return &exitError{code: exitCheckError, err: fmt.Errorf("expected one URL or target name, got %d arguments", flags.NArg())}
+	}
+
+	cfg, err := config.LoadSettings()
+	if err != nil {
+		return &exitError{code: exitCheckError, err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+	if err := checker.LoadPlugins(cfg.Plugins); err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+
+	target, configured := debugTarget(cfg, flags.Arg(0))
+	if !configured {
+		target.Module = *module
+		target.Method = *method
+	}
+	resolved, err := cfg.ResolveModule(target)
+	if err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+
+	writeDebugTarget(stdout, cfg, resolved, configured)
+
+	debug := &checker.Debug{}
+	result, err := checker.New(cfg).CheckTarget(checker.WithDebug(context.Background(), debug), target)
+	if err != nil {
+		return &exitError{code: exitCheckError, err: err}
+	}
+
+	fmt.Fprintln(stdout)
+	for _, event := range debug.Events() {
+		fmt.Fprintf(stdout, "%9.3fs  %s\n", event.Elapsed.Seconds(), event.Message)
+		for _, detail := range event.Details {
+			fmt.Fprintf(stdout, "%11s  %s\n", "", detail)
+		}
+	}
+	fmt.Fprintln(stdout)
+	writeDebugResult(stdout, result)
+
+	if !result.IsUp() {
+		return &exitError{code: exitChecksFailed, err: fmt.Errorf("%s is down", config.RedactURL(target.URL))}
+	}
+	return nil
+}
+
+// debugTarget returns the configured target with the name or URL, or a target for the URL
+func debugTarget(cfg *config.Config, selector string) (config.Target, bool) {
+	if selected := selectTarget(cfg.AllTargets(), selector); len(selected) == 1 {
+		return selected[0], true
+	}
+	return config.Target{URL: selector}, false
+}
+
+func writeDebugTarget(w io.Writer, cfg *config.Config, target config.Target, configured bool) {
+	source := "not configured"
+	if configured {
+		source = "configured"
+	}
+	fmt.Fprintf(w, "Target:     %s (%s)\n", config.RedactURL(target.URL), source)
+	if target.Name != "" {
+		fmt.Fprintf(w, "Name:       %s\n", target.Name)
+	}
+	if target.Group != "" {
+		fmt.Fprintf(w, "Group:      %s\n", target.Group)
+	}
+	if target.Module != "" {
+		fmt.Fprintf(w, "Module:     %s\n", target.Module)
+	}
+	method := target.Method
+	switch {
+	case target.GraphQL != nil:
+		method = "POST of the GraphQL query " + strconv.Quote(target.GraphQL.Query)
+	case target.SOAP != nil:
+		method = "POST of a SOAP envelope, action " + strconv.Quote(target.SOAP.Action)
+	case method == "":
+		method = "HEAD, or GET when a body assertion needs the body"
+	}
+	fmt.Fprintf(w, "Method:     %s\n", method)
+	if target.GraphQL != nil {
+		for _, expectation := range target.GraphQL.Expect {
+			fmt.Fprintf(w, "Expect:     graphql %s matches %q\n", expectation.Path, expectation.Value)
+		}
+	}
+	fmt.Fprintf(w, "Timeout:    %s per attempt, %d retries\n", cfg.Timeout, cfg.Retries)
+	if source := cfg.SourceFor(target); !source.IsZero() {
+		fmt.Fprintf(w, "Source:     address %q, interface %q\n", source.Address, source.Interface)
+	}
+	if target.ExpectBody != "" {
+		fmt.Fprintf(w, "Expect:     body matches %q\n", target.ExpectBody)
+	}
+	if target.ExpectContentType != "" {
+		fmt.Fprintf(w, "Expect:     content type %s\n", target.ExpectContentType)
+	}
+	if target.HasBodySizeBounds() {
+		fmt.Fprintf(w, "Expect:     body of %d to %d bytes (0 for no bound)\n", target.MinBodyBytes, target.MaxBodyBytes)
+	}
+	if len(target.Steps) > 0 {
+		fmt.Fprintf(w, "Steps:      %d, run in order\n", len(target.Steps))
+	}
+	names := make([]string, 0, len(target.ExpectHeaders))
+	for name := range target.ExpectHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "Expect:     header %s matches %q\n", name, target.ExpectHeaders[name])
+	}
+}
+
+func writeDebugResult(w io.Writer, result checker.Result) {
+	state := "UP"
+	if !result.IsUp() {
+		state = "DOWN"
+	}
+	fmt.Fprintf(w, "Result:     %s, status %d in %s\n", state, result.StatusCode, result.ResponseTime.Round(time.Millisecond))
+	if result.HTTPVersion > 0 {
+		fmt.Fprintf(w, "HTTP:       %g\n", result.HTTPVersion)
+	}
+	if result.TLSVersion != "" {
+		legacy := ""
+		if result.TLSLegacy {
+			legacy = ", legacy TLS below the policy"
+		}
+		fmt.Fprintf(w, "TLS:        %s, %s%s\n", result.TLSVersion, result.TLSCipherSuite, legacy)
+	}
+	if result.BodyMatch != nil {
+		fmt.Fprintf(w, "Body:       %s\n", matched(*result.BodyMatch))
+	}
+	if result.HeaderMatch != nil {
+		fmt.Fprintf(w, "Headers:    %s\n", matched(*result.HeaderMatch))
+	}
+	if result.SizeWithinBounds != nil {
+		fmt.Fprintf(w, "Body size:  %s\n", matched(*result.SizeWithinBounds))
+	}
+	for _, step := range result.Steps {
+		outcome := "passed"
+		if step.Error != nil {
+			outcome = step.Error.Error()
+		} else if !step.Passed() {
+			outcome = "failed"
+		}
+		fmt.Fprintf(w, "Step:       %s, status %d in %s, %s\n", step.Name, step.StatusCode, step.ResponseTime.Round(time.Millisecond), outcome)
+	}
+	for _, name := range config.SecurityHeaderNames {
+		if present, audited := result.SecurityHeaders[name]; audited {
+			fmt.Fprintf(w, "Security:   %s %s\n", name, presence(present))
+		}
+	}
+	if result.Error != nil {
+		fmt.Fprintf(w, "Error:      %s\n", result.Error)
+	}
+	switch {
+	case result.Error == nil && result.Failure != "":
+		fmt.Fprintf(w, "Reason:     %s\n", result.Failure)
+	case result.Error == nil && !result.IsUp():
+		fmt.Fprintln(w, "Reason:     the status is not 2xx")
+	}
+}
+
+func presence(present bool) string {
+	if present {
+		return "present"
+	}
+	return "missing"
+}
+
+func matched(match bool) string {
+	if match {
+		return "assertion matched"
+	}
+	return "assertion failed"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDebug(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("X-Version", "42")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	config := `retries: 0
+checks:
+  - url: "` + server.URL + `/health"
+    name: "api"
+    expectBody: '"status":"ok"'
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	var out bytes.Buffer
+	require.NoError(t, runDebug([]string{"api"}, &out))
+	output := out.String()
+	assert.Contains(t, output, "Target:     "+server.URL+"/health (configured)")
+	assert.Contains(t, output, `Expect:     body matches "\"status\":\"ok\""`)
+	assert.Contains(t, output, "attempt 1: getting a connection to "+server.Listener.Addr().String())
+	assert.Contains(t, output, "connected to "+server.Listener.Addr().String())
+	assert.Contains(t, output, "request: GET "+server.URL+"/health")
+	assert.Contains(t, output, "User-Agent: url-exporter/1.0")
+	assert.Contains(t, output, "response: HTTP/1.1 200 OK, 15 body bytes")
+	assert.Contains(t, output, "X-Version: 42")
+	assert.Contains(t, output, "Result:     UP, status 200")
+	assert.Contains(t, output, "Body:       assertion matched")
+
+	out.Reset()
+	err := runDebug([]string{"-method", "GET", server.URL + "/down"}, &out)
+	assert.Equal(t, exitChecksFailed, exitCode(err))
+	assert.Contains(t, out.String(), "(not configured)")
+	assert.Contains(t, out.String(), "Method:     GET")
+	assert.Contains(t, out.String(), "Result:     DOWN, status 502")
+	assert.Contains(t, out.String(), "Reason:     the status is not 2xx")
+}
+
+func TestRunDebug_InvalidArguments(t *testing.T) {
+	t.Setenv("URL_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	assert.Equal(t, exitCheckError, exitCode(runDebug(nil, &bytes.Buffer{})))
+	assert.Equal(t, exitCheckError, exitCode(runDebug([]string{"a", "b"}, &bytes.Buffer{})))
+	assert.Equal(t, exitCheckError, exitCode(runDebug([]string{"-module", "missing", "https://example.com"}, &bytes.Buffer{})))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+)
+
+// runExport implements the export subcommand: it writes an availability report of the
+// check history saved by a stopped or running exporter (history.file) to stdout or a file
+func runExport(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", metrics.ExportFormatCSV, "report format, csv or json")
+	since := flags.Duration("since", 0, "only include checks from this period, e.g. 24h (default all)")
+	target := flags.String("target", "", "only include the target with this name or URL (default all)")
+	file := flags.String("file", "", "history file to read (default history.file from the configuration)")
+	output := flags.String("output", "", "file to write the report to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if *format != metrics.ExportFormatCSV && *format != metrics.ExportFormatJSON {
+		return fmt.Errorf("unsupported format %q: must be csv or json", *format)
+	}
+	if *since < 0 {
+		return errors.New("since must not be negative")
+	}
+
+	_, targets, saved, err := loadSavedHistory(*file)
+	if err != nil {
+		return err
+	}
+
+	report := metrics.HistoryReport{GeneratedAt: time.Now().UTC()}
+	var start time.Time
+	if *since > 0 {
+		start = time.Now().Add(-*since).UTC()
+		report.Since = &start
+	}
+	for _, t := range exportTargets(targets, saved.Targets) {
+		if *target != "" && t.Name != *target && t.URL != *target {
+			continue
+		}
+		report.Targets = append(report.Targets, metrics.NewTargetReport(t.URL, t.Name, metrics.HistorySince(saved.Targets[t.URL], start)))
+	}
+	if *target != "" && len(report.Targets) == 0 {
+		return fmt.Errorf("target not found: %s", *target)
+	}
+
+	return writeOutput(*output, stdout, func(w io.Writer) error {
+		if err := metrics.WriteHistoryReport(w, *format, report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	})
+}
+
+// loadSavedHistory loads the configuration, its targets (from the state file when there
+// is one, as the exporter does) and the history saved to the file, by default the
+// configured history.file
+func loadSavedHistory(file string) (*config.Config, []config.Target, *metrics.SavedHistory, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	targets := cfg.AllTargets()
+	if cfg.API.StateFile != "" {
+		state, ok, err := config.LoadState(cfg.API.StateFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to load target state: %w", err)
+		}
+		if ok {
+			targets = append(state.Targets, state.RuntimeTargets...)
+		}
+	}
+
+	if file == "" {
+		file = cfg.History.File
+	}
+	if file == "" {
+		return nil, nil, nil, errors.New("no history file: set history.file in the configuration or pass -file")
+	}
+	saved, err := metrics.ReadHistoryFile(file)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return cfg, targets, saved, nil
+}
+
+// writeOutput writes to stdout, or to the file when one is given
+func writeOutput(output string, stdout io.Writer, write func(io.Writer) error) error {
+	if output == "" {
+		return write(stdout)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportTargets returns the configured targets followed by those only found in the
+// history, such as targets removed since, ordered by URL
+func exportTargets(targets []config.Target, history map[string][]metrics.HistoryPoint) []config.Target {
+	known := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		known[target.URL] = true
+	}
+
+	var removed []config.Target
+	for url := range history {
+		if !known[url] {
+			removed = append(removed, config.Target{URL: url})
+		}
+	}
+
+	all := append(append([]config.Target{}, targets...), removed...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].URL < all[j].URL })
+	return all
+}
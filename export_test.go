@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExport(t *testing.T) {
+	dir := t.TempDir()
+	historyFile := filepath.Join(dir, "history.json")
+	configFile := filepath.Join(dir, "config.yaml")
+
+	config := `checks:
+  - url: "https://example.com/health"
+    name: "web"
+history:
+  file: "` + historyFile + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	history := map[string]map[string][]metrics.HistoryPoint{"targets": {
+		"https://example.com/health": {
+			{Timestamp: now.Add(-48 * time.Hour), Up: false, ResponseTime: 5 * time.Millisecond, Error: "timeout"},
+			{Timestamp: now.Add(-time.Hour), Up: true, StatusCode: 200, ResponseTime: 42 * time.Millisecond},
+		},
+		"https://removed.example.com": {
+			{Timestamp: now.Add(-time.Hour), Up: true, StatusCode: 200, ResponseTime: 7 * time.Millisecond},
+		},
+	}}
+	content, err := json.Marshal(history)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(historyFile, content, 0644))
+
+	var out bytes.Buffer
+	require.NoError(t, runExport([]string{"-since", "24h"}, &out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{
+		"url,name,timestamp,up,status_code,response_time_ms,error",
+		"https://example.com/health,web," + now.Add(-time.Hour).Format(time.RFC3339) + ",true,200,42,",
+		"https://removed.example.com,," + now.Add(-time.Hour).Format(time.RFC3339) + ",true,200,7,",
+	}, lines)
+
+	output := filepath.Join(dir, "report.json")
+	require.NoError(t, runExport([]string{"-format", "json", "-target", "web", "-output", output}, &out))
+	content, err = os.ReadFile(output)
+	require.NoError(t, err)
+	var report metrics.HistoryReport
+	require.NoError(t, json.Unmarshal(content, &report))
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, "web", report.Targets[0].Name)
+	assert.Equal(t, 2, report.Targets[0].Checks)
+	assert.Equal(t, 50.0, report.Targets[0].Availability)
+
+	assert.ErrorContains(t, runExport([]string{"-target", "unknown"}, &out), "target not found: unknown")
+	assert.ErrorContains(t, runExport([]string{"-format", "xml"}, &out), "unsupported format")
+	assert.ErrorContains(t, runExport([]string{"-file", filepath.Join(dir, "missing.json")}, &out), "failed to read history file")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/pingdom"
+	"github.com/jasoet/url-exporter/internal/route53"
+	"github.com/jasoet/url-exporter/internal/uptimerobot"
+	"gopkg.in/yaml.v3"
+)
+
+// importers are the sources the import subcommand reads existing checks from
+var importers = map[string]func(args []string, stdout io.Writer) error{
+	"pingdom":     runImportPingdom,
+	"route53":     runImportRoute53,
+	"uptimerobot": runImportUptimeRobot,
+}
+
+// importedConfig is the configuration written by the import subcommand, to be merged into
+// the configuration file
+type importedConfig struct {
+	Checks     []importedCheck    `yaml:"checks"`
+	Composites []config.Composite `yaml:"composites,omitempty"`
+}
+
+// importedCheck is an imported check with only the settings an import sets
+type importedCheck struct {
+	URL        string            `yaml:"url"`
+	Name       string            `yaml:"name,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	Method     string            `yaml:"method,omitempty"`
+	ExpectBody string            `yaml:"expectBody,omitempty"`
+	Interval   string            `yaml:"interval,omitempty"`
+	Disabled   bool              `yaml:"disabled,omitempty"`
+}
+
+// runImport implements the import subcommand: it converts the checks of another monitoring
+// system into exporter configuration, e.g. import route53 or import pingdom
+func runImport(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("missing source, e.g. import route53")
+	}
+	importer, exists := importers[args[0]]
+	if !exists {
+		return fmt.Errorf("unknown source %q, must be pingdom, route53 or uptimerobot", args[0])
+	}
+	return importer(args[1:], stdout)
+}
+
+// runImportRoute53 converts the Route53 health checks of the AWS account whose credentials
+// are in the environment into checks and composites
+func runImportRoute53(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import route53", flag.ContinueOnError)
+	endpoint := flags.String("endpoint", route53.DefaultEndpoint, "Route53 API endpoint")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each API request")
+	output := flags.String("output", "", "file to write the configuration to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	credentials, err := route53.CredentialsFromEnv()
+	if err != nil {
+		return err
+	}
+	healthChecks, err := route53.NewClient(*endpoint, credentials, *timeout).HealthChecks(context.Background())
+	if err != nil {
+		return err
+	}
+	imported := route53.Convert(healthChecks)
+
+	header := fmt.Sprintf("Imported from %d Route53 health checks", len(healthChecks))
+	return writeOutput(*output, stdout, func(w io.Writer) error {
+		return writeImportedConfig(w, header, imported.Warnings, imported.Checks, imported.Composites)
+	})
+}
+
+// runImportPingdom converts the http and tcp checks of the Pingdom account whose API token
+// is in PINGDOM_API_TOKEN into checks
+func runImportPingdom(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import pingdom", flag.ContinueOnError)
+	endpoint := flags.String("endpoint", pingdom.DefaultEndpoint, "Pingdom API base URL")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each API request")
+	output := flags.String("output", "", "file to write the configuration to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	token := os.Getenv("PINGDOM_API_TOKEN")
+	if token == "" {
+		return errors.New("PINGDOM_API_TOKEN must be set")
+	}
+	pingdomChecks, err := pingdom.NewClient(*endpoint, token, *timeout).Checks(context.Background())
+	if err != nil {
+		return err
+	}
+	checks, warnings := pingdom.Convert(pingdomChecks)
+
+	header := fmt.Sprintf("Imported from %d Pingdom checks", len(pingdomChecks))
+	return writeOutput(*output, stdout, func(w io.Writer) error {
+		return writeImportedConfig(w, header, warnings, checks, nil)
+	})
+}
+
+// runImportUptimeRobot converts the HTTP, keyword and port monitors of the UptimeRobot
+// account whose API key is in UPTIMEROBOT_API_KEY into checks
+func runImportUptimeRobot(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import uptimerobot", flag.ContinueOnError)
+	endpoint := flags.String("endpoint", uptimerobot.DefaultEndpoint, "UptimeRobot API base URL")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each API request")
+	output := flags.String("output", "", "file to write the configuration to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	apiKey := os.Getenv("UPTIMEROBOT_API_KEY")
+	if apiKey == "" {
+		return errors.New("UPTIMEROBOT_API_KEY must be set")
+	}
+	monitors, err := uptimerobot.NewClient(*endpoint, apiKey, *timeout).Monitors(context.Background())
+	if err != nil {
+		return err
+	}
+	checks, warnings := uptimerobot.Convert(monitors)
+
+	header := fmt.Sprintf("Imported from %d UptimeRobot monitors", len(monitors))
+	return writeOutput(*output, stdout, func(w io.Writer) error {
+		return writeImportedConfig(w, header, warnings, checks, nil)
+	})
+}
+
+// writeImportedConfig writes the checks and composites as YAML, after the header and the
+// warnings of the import as comments
+func writeImportedConfig(w io.Writer, header string, warnings []string, checks []config.Target, composites []config.Composite) error {
+	fmt.Fprintf(w, "# %s\n", header)
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "# %s\n", warning)
+	}
+
+	cfg := importedConfig{Checks: make([]importedCheck, 0, len(checks)), Composites: composites}
+	for _, check := range checks {
+		cfg.Checks = append(cfg.Checks, importedCheck{
+			URL:        check.URL,
+			Name:       check.Name,
+			Labels:     check.Labels,
+			Method:     check.Method,
+			ExpectBody: check.ExpectBody,
+			Interval:   check.Interval,
+			Disabled:   check.Disabled,
+		})
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to write configuration: %w", err)
+	}
+	return encoder.Close()
+}
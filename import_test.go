@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunImport_Route53(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2013-04-01/healthcheck" {
+			fmt.Fprint(w, `<ListHealthChecksResponse><HealthChecks>
+<HealthCheck><Id>hc-1</Id><HealthCheckConfig><Type>HTTPS_STR_MATCH</Type><FullyQualifiedDomainName>example.com</FullyQualifiedDomainName><ResourcePath>/health</ResourcePath><SearchString>ok</SearchString><RequestInterval>30</RequestInterval></HealthCheckConfig></HealthCheck>
+<HealthCheck><Id>hc-2</Id><HealthCheckConfig><Type>CLOUDWATCH_METRIC</Type></HealthCheckConfig></HealthCheck>
+</HealthChecks><IsTruncated>false</IsTruncated></ListHealthChecksResponse>`)
+			return
+		}
+		fmt.Fprint(w, `<ListTagsForResourcesResponse><ResourceTagSets><ResourceTagSet><ResourceId>hc-1</ResourceId><Tags><Tag><Key>Name</Key><Value>web</Value></Tag></Tags></ResourceTagSet></ResourceTagSets></ListTagsForResourcesResponse>`)
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	var out bytes.Buffer
+	require.NoError(t, runImport([]string{"route53", "-endpoint", server.URL}, &out))
+	assert.Equal(t, `# Imported from 2 Route53 health checks
+# health check hc-2: type CLOUDWATCH_METRIC has no equivalent, skipped
+checks:
+  - url: https://example.com/health
+    name: web
+    labels:
+      route53_health_check_id: hc-1
+    method: GET
+    expectBody: ok
+    interval: 30s
+`, out.String())
+
+	output := filepath.Join(t.TempDir(), "checks.yaml")
+	require.NoError(t, runImport([]string{"route53", "-endpoint", server.URL, "-output", output}, &out))
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "name: web")
+
+	assert.ErrorContains(t, runImport(nil, &out), "missing source")
+	assert.ErrorContains(t, runImport([]string{"consul"}, &out), `unknown source "consul"`)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	assert.ErrorContains(t, runImport([]string{"route53", "-endpoint", server.URL}, &out), "AWS_ACCESS_KEY_ID")
+}
+
+func TestRunImport_PingdomAndUptimeRobot(t *testing.T) {
+	pingdom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/checks" {
+			fmt.Fprint(w, `{"checks":[{"id":7}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"check":{"id":7,"name":"web","hostname":"example.com","resolution":1,"status":"up","type":{"http":{"url":"/","encryption":true}}}}`)
+	}))
+	defer pingdom.Close()
+	uptimeRobot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"stat":"ok","pagination":{"total":1},"monitors":[{"id":9,"friendly_name":"api","url":"https://api.example.com","type":1,"interval":300,"status":2}]}`)
+	}))
+	defer uptimeRobot.Close()
+
+	var out bytes.Buffer
+	assert.ErrorContains(t, runImport([]string{"pingdom", "-endpoint", pingdom.URL}, &out), "PINGDOM_API_TOKEN")
+	t.Setenv("PINGDOM_API_TOKEN", "token")
+	require.NoError(t, runImport([]string{"pingdom", "-endpoint", pingdom.URL}, &out))
+	assert.Equal(t, `# Imported from 1 Pingdom checks
+checks:
+  - url: https://example.com/
+    name: web
+    labels:
+      pingdom_check_id: "7"
+    interval: 1m
+`, out.String())
+
+	out.Reset()
+	assert.ErrorContains(t, runImport([]string{"uptimerobot", "-endpoint", uptimeRobot.URL}, &out), "UPTIMEROBOT_API_KEY")
+	t.Setenv("UPTIMEROBOT_API_KEY", "key")
+	require.NoError(t, runImport([]string{"uptimerobot", "-endpoint", uptimeRobot.URL}, &out))
+	assert.Equal(t, `# Imported from 1 UptimeRobot monitors
+checks:
+  - url: https://api.example.com
+    name: api
+    labels:
+      uptimerobot_monitor_id: "9"
+    interval: 300s
+`, out.String())
+}
@@ -0,0 +1,166 @@
+package checker
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Assertions holds the compiled response assertions configured for a target
+type Assertions struct {
+	Body    *regexp.Regexp
+	Headers map[string]*regexp.Regexp
+	// ContentType is the media type the response must have, lowercase
+	ContentType string
+	// SecurityHeaders audits the security headers of the response
+	SecurityHeaders bool
+	// MinBodyBytes and MaxBodyBytes bound the size of the body, 0 for no bound
+	MinBodyBytes, MaxBodyBytes int64
+	// FailOnBodySize fails the check when the body size is out of bounds
+	FailOnBodySize bool
+}
+
+// AssertionResult reports the outcome of each configured assertion. A nil field
+// means the corresponding assertion was not configured.
+type AssertionResult struct {
+	BodyMatch *bool
+	// HeaderMatch reports the header assertions and the content type together
+	HeaderMatch *bool
+	// SecurityHeaders reports the presence of each of config.SecurityHeaderNames
+	SecurityHeaders map[string]bool
+	// SizeWithinBounds reports whether the size of the body, BodyBytes, is within the bounds
+	SizeWithinBounds *bool
+	BodyBytes        int64
+}
+
+// NewAssertions compiles the assertions of a target, returning nil when none are configured
+func NewAssertions(target config.Target) (*Assertions, error) {
+	if !target.HasAssertions() && !target.SecurityHeaders {
+		return nil, nil
+	}
+
+	assertions := &Assertions{
+		SecurityHeaders: target.SecurityHeaders,
+		MinBodyBytes:    target.MinBodyBytes,
+		MaxBodyBytes:    target.MaxBodyBytes,
+		FailOnBodySize:  target.FailOnBodySize,
+	}
+
+	if target.ExpectBody != "" {
+		body, err := regexp.Compile(target.ExpectBody)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body assertion: %w", err)
+		}
+		assertions.Body = body
+	}
+
+	if len(target.ExpectHeaders) > 0 {
+		assertions.Headers = make(map[string]*regexp.Regexp, len(target.ExpectHeaders))
+		for name, pattern := range target.ExpectHeaders {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid header assertion for %s: %w", name, err)
+			}
+			assertions.Headers[http.CanonicalHeaderKey(name)] = re
+		}
+	}
+
+	if target.ExpectContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(target.ExpectContentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type assertion: %w", err)
+		}
+		assertions.ContentType = mediaType
+	}
+
+	return assertions, nil
+}
+
+// NeedsBody reports whether the assertions require the response body to be downloaded
+func (a *Assertions) NeedsBody() bool {
+	return a != nil && (a.Body != nil || a.MinBodyBytes > 0 || a.MaxBodyBytes > 0)
+}
+
+// Evaluate applies the assertions to a response's headers and body
+func (a *Assertions) Evaluate(header http.Header, body []byte) AssertionResult {
+	var result AssertionResult
+	if a == nil {
+		return result
+	}
+
+	if a.Body != nil {
+		match := a.Body.Match(body)
+		result.BodyMatch = &match
+	}
+
+	if len(a.Headers) > 0 || a.ContentType != "" {
+		match := true
+		for name, re := range a.Headers {
+			values := header.Values(name)
+			found := false
+			for _, value := range values {
+				if re.MatchString(value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				match = false
+				break
+			}
+		}
+		if a.ContentType != "" && contentType(header) != a.ContentType {
+			match = false
+		}
+		result.HeaderMatch = &match
+	}
+
+	if a.SecurityHeaders {
+		result.SecurityHeaders = AuditSecurityHeaders(header)
+	}
+
+	if a.MinBodyBytes > 0 || a.MaxBodyBytes > 0 {
+		size := int64(len(body))
+		within := size >= a.MinBodyBytes && (a.MaxBodyBytes == 0 || size <= a.MaxBodyBytes)
+		result.SizeWithinBounds = &within
+		result.BodyBytes = size
+	}
+
+	return result
+}
+
+// BodySizeFailure describes why the check fails when the body size of the result is out
+// of bounds and the assertions fail on it, empty otherwise
+func (a *Assertions) BodySizeFailure(result AssertionResult) string {
+	if a == nil || !a.FailOnBodySize || result.SizeWithinBounds == nil || *result.SizeWithinBounds {
+		return ""
+	}
+	if result.BodyBytes < a.MinBodyBytes {
+		return fmt.Sprintf("response body of %d bytes is below minBodyBytes %d", result.BodyBytes, a.MinBodyBytes)
+	}
+	return fmt.Sprintf("response body of %d bytes is above maxBodyBytes %d", result.BodyBytes, a.MaxBodyBytes)
+}
+
+// contentType returns the lowercase media type of the Content-Type header, without
+// parameters
+func contentType(header http.Header) string {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// AuditSecurityHeaders reports which of config.SecurityHeaderNames the headers carry with a
+// non-empty value
+func AuditSecurityHeaders(header http.Header) map[string]bool {
+	present := make(map[string]bool, len(config.SecurityHeaderNames))
+	for _, name := range config.SecurityHeaderNames {
+		present[name] = strings.TrimSpace(header.Get(name)) != ""
+	}
+	return present
+}
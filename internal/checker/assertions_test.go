@@ -0,0 +1,169 @@
+package checker
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAssertions_NoneConfigured(t *testing.T) {
+	assertions, err := NewAssertions(config.Target{URL: "https://example.com"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, assertions)
+	assert.False(t, assertions.NeedsBody())
+}
+
+func TestNewAssertions_InvalidPattern(t *testing.T) {
+	_, err := NewAssertions(config.Target{URL: "https://example.com", ExpectBody: "("})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid body assertion")
+
+	_, err = NewAssertions(config.Target{
+		URL:           "https://example.com",
+		ExpectHeaders: map[string]string{"content-type": "["},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid header assertion for content-type")
+}
+
+func TestAssertions_Evaluate(t *testing.T) {
+	assertions, err := NewAssertions(config.Target{
+		URL:        "https://example.com",
+		ExpectBody: `"status":\s*"ok"`,
+		ExpectHeaders: map[string]string{
+			"content-type": "^application/json",
+			"x-served-by":  "cache-.*",
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, assertions)
+	assert.True(t, assertions.NeedsBody())
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Add("X-Served-By", "origin")
+	header.Add("X-Served-By", "cache-ams")
+
+	result := assertions.Evaluate(header, []byte(`{"status": "ok"}`))
+	require.NotNil(t, result.BodyMatch)
+	require.NotNil(t, result.HeaderMatch)
+	assert.True(t, *result.BodyMatch)
+	assert.True(t, *result.HeaderMatch)
+
+	header.Set("Content-Type", "text/html")
+	result = assertions.Evaluate(header, []byte(`<html>error</html>`))
+	assert.False(t, *result.BodyMatch)
+	assert.False(t, *result.HeaderMatch)
+}
+
+func TestAssertions_Evaluate_MissingHeader(t *testing.T) {
+	assertions, err := NewAssertions(config.Target{
+		URL:           "https://example.com",
+		ExpectHeaders: map[string]string{"x-request-id": ".+"},
+	})
+	require.NoError(t, err)
+	assert.False(t, assertions.NeedsBody())
+
+	result := assertions.Evaluate(http.Header{}, nil)
+	assert.Nil(t, result.BodyMatch)
+	require.NotNil(t, result.HeaderMatch)
+	assert.False(t, *result.HeaderMatch)
+}
+
+func TestAssertions_Evaluate_ContentType(t *testing.T) {
+	assertions, err := NewAssertions(config.Target{URL: "https://example.com", ExpectContentType: "Application/JSON"})
+	require.NoError(t, err)
+	assert.False(t, assertions.NeedsBody())
+
+	tests := []struct {
+		contentType string
+		match       bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"text/html; charset=utf-8", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		result := assertions.Evaluate(http.Header{"Content-Type": {tt.contentType}}, nil)
+		require.NotNil(t, result.HeaderMatch, tt.contentType)
+		assert.Equal(t, tt.match, *result.HeaderMatch, tt.contentType)
+	}
+
+	// Both the headers and the content type must match
+	assertions, err = NewAssertions(config.Target{
+		URL:               "https://example.com",
+		ExpectHeaders:     map[string]string{"x-request-id": ".+"},
+		ExpectContentType: "application/json",
+	})
+	require.NoError(t, err)
+	result := assertions.Evaluate(http.Header{"Content-Type": {"application/json"}}, nil)
+	assert.False(t, *result.HeaderMatch)
+	result = assertions.Evaluate(http.Header{"Content-Type": {"text/html"}, "X-Request-Id": {"1"}}, nil)
+	assert.False(t, *result.HeaderMatch)
+	result = assertions.Evaluate(http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"1"}}, nil)
+	assert.True(t, *result.HeaderMatch)
+}
+
+func TestAssertions_Evaluate_BodySize(t *testing.T) {
+	assertions, err := NewAssertions(config.Target{URL: "https://example.com", MinBodyBytes: 5, MaxBodyBytes: 10})
+	require.NoError(t, err)
+	assert.True(t, assertions.NeedsBody())
+
+	tests := []struct {
+		body   string
+		within bool
+	}{
+		{"", false},
+		{"1234", false},
+		{"12345", true},
+		{"1234567890", true},
+		{"12345678901", false},
+	}
+	for _, tt := range tests {
+		result := assertions.Evaluate(http.Header{}, []byte(tt.body))
+		require.NotNil(t, result.SizeWithinBounds, tt.body)
+		assert.Equal(t, tt.within, *result.SizeWithinBounds, tt.body)
+		assert.Equal(t, int64(len(tt.body)), result.BodyBytes)
+		assert.Empty(t, assertions.BodySizeFailure(result), "only reported unless failOnBodySize")
+	}
+
+	assertions.FailOnBodySize = true
+	assert.Equal(t, "response body of 1 bytes is below minBodyBytes 5", assertions.BodySizeFailure(assertions.Evaluate(http.Header{}, []byte("1"))))
+	assert.Equal(t, "response body of 11 bytes is above maxBodyBytes 10", assertions.BodySizeFailure(assertions.Evaluate(http.Header{}, []byte("12345678901"))))
+	assert.Empty(t, assertions.BodySizeFailure(assertions.Evaluate(http.Header{}, []byte("12345"))))
+}
+
+func TestAssertions_Evaluate_Nil(t *testing.T) {
+	var assertions *Assertions
+
+	result := assertions.Evaluate(http.Header{}, nil)
+	assert.Nil(t, result.BodyMatch)
+	assert.Nil(t, result.HeaderMatch)
+}
+
+func TestAssertions_Evaluate_SecurityHeaders(t *testing.T) {
+	assertions, err := NewAssertions(config.Target{URL: "https://example.com", SecurityHeaders: true})
+	require.NoError(t, err)
+	require.NotNil(t, assertions)
+	assert.False(t, assertions.NeedsBody())
+
+	header := http.Header{}
+	header.Set("Strict-Transport-Security", "max-age=63072000")
+	header.Set("x-content-type-options", "nosniff")
+	header.Set("X-Frame-Options", " ")
+
+	result := assertions.Evaluate(header, nil)
+	assert.Nil(t, result.BodyMatch)
+	assert.Nil(t, result.HeaderMatch)
+	assert.Equal(t, map[string]bool{
+		"Strict-Transport-Security": true,
+		"Content-Security-Policy":   false,
+		"X-Content-Type-Options":    true,
+		"X-Frame-Options":           false,
+	}, result.SecurityHeaders)
+}
@@ -0,0 +1,87 @@
+package checker
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStatus describes the caching of an HTTP response as reported by its headers
+type CacheStatus struct {
+	// Hit reports whether the CDN served the response from its cache, from X-Cache or
+	// CF-Cache-Status; nil when neither reports a hit or a miss
+	Hit *bool
+	// Age is the Age header, nil when absent
+	Age *time.Duration
+	// MaxAge is the s-maxage, or else max-age, directive of Cache-Control, nil when absent
+	MaxAge *time.Duration
+}
+
+// parseCacheStatus reads the cache headers of a response, nil when it has none
+func parseCacheStatus(header http.Header) *CacheStatus {
+	status := CacheStatus{
+		Hit:    cacheHit(header),
+		Age:    seconds(header.Get("Age")),
+		MaxAge: maxAge(header.Values("Cache-Control")),
+	}
+	if status.Hit == nil && status.Age == nil && status.MaxAge == nil {
+		return nil
+	}
+	return &status
+}
+
+// cacheHit reads X-Cache, e.g. "HIT", "Miss from cloudfront" or "MISS, HIT" where the last
+// cache is the closest to the client, falling back to CF-Cache-Status
+func cacheHit(header http.Header) *bool {
+	value := header.Get("X-Cache")
+	if value == "" {
+		value = header.Get("CF-Cache-Status")
+	}
+	if i := strings.LastIndex(value, ","); i >= 0 {
+		value = value[i+1:]
+	}
+
+	value = strings.ToUpper(value)
+	switch {
+	case strings.Contains(value, "HIT"):
+		hit := true
+		return &hit
+	case strings.Contains(value, "MISS"), strings.Contains(value, "EXPIRED"):
+		hit := false
+		return &hit
+	default:
+		return nil
+	}
+}
+
+// maxAge returns the freshness lifetime shared caches apply: s-maxage when present,
+// max-age otherwise
+func maxAge(cacheControl []string) *time.Duration {
+	var maxAge, sharedMaxAge *time.Duration
+	for _, value := range cacheControl {
+		for _, directive := range strings.Split(value, ",") {
+			name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "max-age":
+				maxAge = seconds(strings.Trim(argument, `"`))
+			case "s-maxage":
+				sharedMaxAge = seconds(strings.Trim(argument, `"`))
+			}
+		}
+	}
+	if sharedMaxAge != nil {
+		return sharedMaxAge
+	}
+	return maxAge
+}
+
+// seconds parses a non-negative number of seconds, nil when it is not one
+func seconds(value string) *time.Duration {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return nil
+	}
+	duration := time.Duration(n) * time.Second
+	return &duration
+}
@@ -0,0 +1,61 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheStatus(t *testing.T) {
+	hit, miss := true, false
+	duration := func(d time.Duration) *time.Duration { return &d }
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected *CacheStatus
+	}{
+		{"no cache headers", http.Header{"Content-Type": {"text/html"}}, nil},
+		{"hit", http.Header{"X-Cache": {"HIT"}, "Age": {"120"}}, &CacheStatus{Hit: &hit, Age: duration(2 * time.Minute)}},
+		{"cloudfront miss", http.Header{"X-Cache": {"Miss from cloudfront"}}, &CacheStatus{Hit: &miss}},
+		{"closest cache decides", http.Header{"X-Cache": {"MISS, HIT"}}, &CacheStatus{Hit: &hit}},
+		{"cloudflare", http.Header{"Cf-Cache-Status": {"EXPIRED"}}, &CacheStatus{Hit: &miss}},
+		{"unknown status", http.Header{"Cf-Cache-Status": {"DYNAMIC"}, "Age": {"soon"}}, nil},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=300"}}, &CacheStatus{MaxAge: duration(5 * time.Minute)}},
+		{"s-maxage wins", http.Header{"Cache-Control": {`S-MAXAGE="60"`, "max-age=300"}}, &CacheStatus{MaxAge: duration(time.Minute)}},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseCacheStatus(tt.header))
+		})
+	}
+}
+
+func TestCheckTarget_CacheHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cache", "MISS")
+		w.Header().Set("Cache-Control", "max-age=600")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Timeout: 5 * time.Second})
+
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	require.NotNil(t, result.Cache)
+	require.NotNil(t, result.Cache.Hit)
+	assert.False(t, *result.Cache.Hit)
+	assert.Nil(t, result.Cache.Age)
+	require.NotNil(t, result.Cache.MaxAge)
+	assert.Equal(t, 10*time.Minute, *result.Cache.MaxAge)
+}
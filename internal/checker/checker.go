@@ -2,29 +2,90 @@ package checker
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/jasoet/pkg/concurrent"
 	"github.com/jasoet/pkg/rest"
 	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/dnscache"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
-// Result represents the result of a URL check
+// ErrTargetExists is returned when adding a target whose URL is already registered
+var ErrTargetExists = errors.New("target already exists")
+
+// Result represents the result of a URL check. URL is the target as configured and
+// identifies it, credentials included; show it through config.RedactURL. Host and Path
+// are redacted already.
 type Result struct {
 	URL          string
 	Host         string
 	Path         string
+	Protocol     string
 	StatusCode   int
 	ResponseTime time.Duration
+	HTTPVersion  float64
+	BodyMatch    *bool
+	HeaderMatch  *bool
 	Error        error
 	Timestamp    time.Time
+	// TLSVersion and TLSCipherSuite describe the TLS connection of HTTPS checks, e.g. "1.3"
+	// and "TLS_AES_128_GCM_SHA256". TLSLegacy reports a connection below the TLS policy.
+	TLSVersion     string
+	TLSCipherSuite string
+	TLSLegacy      bool
+	// TLSCertExpiry is when the certificate the server presented expires
+	TLSCertExpiry time.Time
+	// SecurityHeaders reports the presence of each audited security header, nil unless
+	// the target's module audits them
+	SecurityHeaders map[string]bool
+	// Cache is the caching of the response reported by its headers, nil without cache
+	// headers
+	Cache *CacheStatus
+	// ClockSkew is how far the Date header of the response is ahead of the local clock,
+	// to the second; nil without a Date header
+	ClockSkew *time.Duration
+	// SizeWithinBounds reports whether the size of the response body is within the
+	// target's bounds, nil unless it has some. It is set even when the size failed the check.
+	SizeWithinBounds *bool
+	// Failure describes the assertion that failed a check which completed, such as a body
+	// size out of bounds with failOnBodySize. The check is down while Error stays nil.
+	Failure string
+	// Steps are the outcomes of the steps of a transaction check, up to the first that
+	// failed
+	Steps []StepResult
+	// SkippedBy is the dependency, as configured, whose failure skipped the check; empty
+	// when the check ran
+	SkippedBy string
+	// CycleID identifies the check cycle that produced the result, empty for on-demand checks
+	CycleID string
+}
+
+// IsUp reports whether the check completed without error or failed assertion and returned
+// a 2xx status
+func (r Result) IsUp() bool {
+	return r.Error == nil && r.Failure == "" && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// IsSkipped reports whether the check was skipped because a dependency is down
+func (r Result) IsSkipped() bool {
+	return r.SkippedBy != ""
 }
 
 // ProtocolChecker defines the interface for checking different protocols
@@ -41,16 +102,134 @@ type HTTPChecker struct {
 // TelnetChecker handles non-HTTP protocol checks using telnet
 type TelnetChecker struct {
 	timeout time.Duration
+	// resolver, if set, resolves the target's host through the DNS cache
+	resolver *dnscache.Resolver
+}
+
+// checkSpec holds the per-target settings applied when a target is checked
+type checkSpec struct {
+	method     string
+	assertions *Assertions
+	// http overrides the default HTTP checker for targets in a configured group or whose
+	// module sets TLS options
+	http *HTTPChecker
+	// debug logs the target's checks at debug level regardless of the configured level
+	debug bool
+	// noDNSCache resolves the target's host on every check
+	noDNSCache bool
+	// keepAlive lets HTTP checks reuse pooled connections
+	keepAlive bool
+	// restricted limits the connections of the checks to those the target policy allows,
+	// each opened for the check
+	restricted bool
+	// source is where the connections of the checks originate
+	source config.SourceConfig
+	// tls decides whether the TLS connections of the checks are legacy TLS
+	tls tlsPolicy
+	// steps make the check a transaction, see config.Step
+	steps []transactionStep
+	// graphql makes the check a GraphQL query, see config.GraphQLConfig
+	graphql *graphQLQuery
+	// soap makes the check a SOAP request, see config.SOAPConfig
+	soap *soapRequest
+}
+
+// newCheckSpec resolves the target's module, validates the result and compiles its assertions
+func (c *Checker) newCheckSpec(target config.Target) (checkSpec, error) {
+	resolved, err := c.config.ResolveModule(target)
+	if err != nil {
+		return checkSpec{}, err
+	}
+	if err := resolved.Validate(); err != nil {
+		return checkSpec{}, err
+	}
+
+	assertions, err := NewAssertions(resolved)
+	if err != nil {
+		return checkSpec{}, fmt.Errorf("invalid assertions for %s: %w", config.RedactURL(target.URL), err)
+	}
+	steps, err := newTransaction(resolved)
+	if err != nil {
+		return checkSpec{}, fmt.Errorf("invalid steps for %s: %w", config.RedactURL(target.URL), err)
+	}
+	graphql, err := newGraphQLQuery(resolved)
+	if err != nil {
+		return checkSpec{}, fmt.Errorf("invalid graphql for %s: %w", config.RedactURL(target.URL), err)
+	}
+
+	return checkSpec{
+		method:     resolved.Method,
+		assertions: assertions,
+		http:       c.httpChecker(target),
+		debug:      c.config.DebugLogging(target),
+		noDNSCache: target.NoDNSCache,
+		keepAlive:  c.config.KeepAlive(target),
+		source:     c.config.SourceFor(target),
+		tls:        newTLSPolicy(c.config.TLSFor(resolved)),
+		steps:      steps,
+		graphql:    graphql,
+		soap:       newSOAPRequest(resolved),
+	}, nil
+}
+
+// CycleHandler receives every result produced by a single check cycle
+type CycleHandler func(ctx context.Context, results []Result)
+
+// ResultSink records the results of the check cycles one by one, e.g. the metrics
+// collector
+type ResultSink interface {
+	Record(result Result)
+}
+
+// ResultSinkFunc adapts a function to a ResultSink
+type ResultSinkFunc func(result Result)
+
+// Record calls f(result)
+func (f ResultSinkFunc) Record(result Result) {
+	f(result)
 }
 
 // Checker performs URL availability checks
 type Checker struct {
-	config      *config.Config
-	restClient  *rest.Client
-	results     chan Result
-	cancel      context.CancelFunc
-	mutex       sync.RWMutex
-	checkers    map[string]ProtocolChecker
+	config        *config.Config
+	restClient    *rest.Client
+	cancel        context.CancelFunc // aborts the checks in flight
+	stop          context.CancelFunc // stops starting new checks
+	stopped       chan struct{}      // closed when Start has returned
+	mutex         sync.RWMutex
+	checkers      map[string]ProtocolChecker
+	moduleTLS     map[string]*tls.Config // TLS options of the modules that set them
+	defaultTLS    *tls.Config            // global TLS options, nil when invalid
+	dnsCache      *dnscache.Resolver
+	policy        *TargetPolicy
+	dependencies  dependencyStates
+	targets       []config.Target
+	specs         map[string]checkSpec
+	sinks         []ResultSink
+	cycleHandlers []CycleHandler
+	hooks         []Hook
+	running       bool
+	lastCycle     time.Time
+	// version changes whenever targets are added or removed, see targetsVersion
+	version uint64
+	// byURL and byName hold the position of each target in targets, see indexTargets
+	byURL  map[string]int
+	byName map[string]int
+	// deliverMutex keeps cycles that complete at the same time from being delivered
+	// concurrently
+	deliverMutex sync.Mutex
+
+	// httpCheckers holds the dedicated HTTP checkers of groups and modules, see httpChecker
+	clientMutex  sync.Mutex
+	httpCheckers map[clientKey]*HTTPChecker
+
+	// subscribers receive every result as its check completes, see Subscribe
+	subMutex    sync.Mutex
+	subscribers map[chan Result]struct{}
+	closed      bool
+	dropped     atomic.Uint64
+	// droppedTargets counts the dropped results per target URL until logDrops reports them
+	droppedTargets map[string]int
 }
 
 // NewHTTPChecker creates a new HTTP protocol checker
@@ -62,11 +241,112 @@ func NewHTTPChecker(restClient *rest.Client) *HTTPChecker {
 
 // Check performs HTTP/HTTPS health check
 func (h *HTTPChecker) Check(ctx context.Context, target string) (int, error) {
+	_, statusCode, err := h.request(ctx, http.MethodHead, target)
+	return statusCode, err
+}
+
+// Inspection holds the protocol-level details collected during an HTTP check
+type Inspection struct {
+	Assertions  AssertionResult
+	HTTPVersion float64
+	// TLS is the state of the TLS connection, nil for plain HTTP
+	TLS *tls.ConnectionState
+	// Cache is the caching of the response reported by its headers
+	Cache *CacheStatus
+	// ClockSkew is the offset of the server clock from the local clock
+	ClockSkew *time.Duration
+	// Steps are the outcomes of the steps of a transaction check that ran
+	Steps []StepResult
+}
+
+// Inspect performs the health check, recording the negotiated HTTP version and evaluating
+// the target's response assertions. Unless a method is given, a GET request is used only when
+// a body assertion needs the response body; otherwise a HEAD request is sent.
+func (h *HTTPChecker) Inspect(ctx context.Context, target, method string, assertions *Assertions) (int, Inspection, error) {
+	if method == "" {
+		method = http.MethodHead
+		if assertions.NeedsBody() {
+			method = http.MethodGet
+		}
+	}
+
+	response, statusCode, err := h.request(ctx, method, target)
+	if err != nil || response == nil {
+		return statusCode, Inspection{}, err
+	}
+
+	return statusCode, newInspection(response, assertions), nil
+}
+
+// newInspection collects the protocol-level details of the response and evaluates the
+// assertions on it
+func newInspection(response *resty.Response, assertions *Assertions) Inspection {
+	inspection := Inspection{
+		Assertions: assertions.Evaluate(response.Header(), response.Body()),
+		Cache:      parseCacheStatus(response.Header()),
+		ClockSkew:  clockSkew(response.Header(), response.ReceivedAt()),
+	}
+	if raw := response.RawResponse; raw != nil {
+		inspection.HTTPVersion = httpVersion(raw.ProtoMajor, raw.ProtoMinor)
+		inspection.TLS = raw.TLS
+	}
+	return inspection
+}
+
+// clockSkew compares the Date header of a response with the local time it was received
+// at. Date has a resolution of a second, so the local time is truncated likewise.
+func clockSkew(header http.Header, received time.Time) *time.Duration {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return nil
+	}
+	skew := date.Sub(received.Truncate(time.Second))
+	return &skew
+}
+
+// httpVersion converts a protocol major/minor pair into a numeric version such as 1.1 or 2
+func httpVersion(major, minor int) float64 {
+	return float64(major) + float64(minor)/10
+}
+
+func (h *HTTPChecker) request(ctx context.Context, method, target string) (*resty.Response, int, error) {
+	return h.send(ctx, method, target, nil, "")
+}
+
+// send makes the request with the extra headers and, unless empty, the body
+func (h *HTTPChecker) send(ctx context.Context, method, target string, extra map[string]string, body string) (*resty.Response, int, error) {
 	headers := map[string]string{
 		"User-Agent": "url-exporter/1.0",
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+	for name, value := range requestHeaders(ctx) {
+		headers[name] = value
+	}
+	for name, value := range extra {
+		headers[name] = value
+	}
+	debug := debugFrom(ctx)
+	if debug != nil {
+		debug.recordRequest(method, target, headers)
+	}
 
-	response, err := h.restClient.MakeRequest(ctx, http.MethodHead, target, "", headers)
+	// The request is sent without rest.Client.MakeRequest, which logs the raw URL with its
+	// credentials; the check logs the redacted one
+	request := h.restClient.GetRestClient().R().
+		SetHeaders(headers).
+		SetContext(ctx)
+	if body != "" {
+		request.SetBody(body)
+	}
+	response, err := request.Execute(method, target)
+	if err != nil {
+		err = rest.NewExecutionError("Failed to make request", err)
+	} else {
+		err = h.restClient.HandleResponse(response)
+	}
+	if debug != nil {
+		debug.recordResponse(response, err)
+	}
 	if err != nil {
 		var executionErr *rest.ExecutionError
 		var unauthorizedErr *rest.UnauthorizedError
@@ -76,21 +356,21 @@ func (h *HTTPChecker) Check(ctx context.Context, target string) (int, error) {
 
 		switch {
 		case errors.As(err, &executionErr):
-			return 0, fmt.Errorf("network error: %w", executionErr)
+			return nil, 0, fmt.Errorf("network error: %w", executionErr)
 		case errors.As(err, &unauthorizedErr):
-			return unauthorizedErr.StatusCode, nil
+			return response, unauthorizedErr.StatusCode, nil
 		case errors.As(err, &notFoundErr):
-			return notFoundErr.StatusCode, nil
+			return response, notFoundErr.StatusCode, nil
 		case errors.As(err, &serverErr):
-			return serverErr.StatusCode, nil
+			return response, serverErr.StatusCode, nil
 		case errors.As(err, &responseErr):
-			return responseErr.StatusCode, nil
+			return response, responseErr.StatusCode, nil
 		default:
-			return 0, fmt.Errorf("request failed: %w", err)
+			return nil, 0, fmt.Errorf("request failed: %w", err)
 		}
 	}
 
-	return response.StatusCode(), nil
+	return response, response.StatusCode(), nil
 }
 
 // Protocol returns the protocol name
@@ -119,35 +399,21 @@ func (t *TelnetChecker) Check(ctx context.Context, target string) (int, error) {
 	
 	// If no port is specified, use default ports based on scheme
 	if port == "" {
-		switch u.Scheme {
-		case "ftp":
-			port = "21"
-		case "sftp", "ssh":
-			port = "22"
-		case "telnet":
-			port = "23"
-		case "smtp":
-			port = "25"
-		case "mysql":
-			port = "3306"
-		case "postgres", "postgresql":
-			port = "5432"
-		case "redis":
-			port = "6379"
-		case "mongodb":
-			port = "27017"
-		default:
+		defaultPort, exists := defaultPorts[u.Scheme]
+		if !exists {
 			return 0, fmt.Errorf("no default port for scheme: %s", u.Scheme)
 		}
+		port = strconv.Itoa(defaultPort)
 	}
 
 	// Create a dialer with timeout
-	dialer := net.Dialer{
-		Timeout: t.timeout,
+	dial := sourceDial(t.timeout)
+	if t.resolver != nil {
+		dial = t.resolver.Wrap(dial)
 	}
 
 	// Use context for cancellation
-	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	conn, err := dial(ctx, "tcp", net.JoinHostPort(host, port))
 	if err != nil {
 		return 0, fmt.Errorf("connection failed: %w", err)
 	}
@@ -163,72 +429,305 @@ func (t *TelnetChecker) Protocol() string {
 }
 
 func New(cfg *config.Config) *Checker {
-	restConfig := &rest.Config{
-		RetryCount:    cfg.Retries,
-		RetryWaitTime: time.Second,
-		Timeout:       cfg.Timeout,
+	var resolver *dnscache.Resolver
+	if cfg.DNSCache.Enabled {
+		resolver = dnscache.New(cfg.DNSCache)
 	}
 
-	restClient := rest.NewClient(rest.WithRestConfig(*restConfig))
+	defaultTLS, err := cfg.TLS.ClientConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("Ignoring invalid TLS options")
+		defaultTLS = nil
+	}
+	restClient := newRestClient(cfg, config.GroupConfig{}, defaultTLS, cfg.Source, resolver)
 
 	// Initialize protocol checkers
-	checkers := make(map[string]ProtocolChecker)
-	checkers["http"] = NewHTTPChecker(restClient)
-	checkers["https"] = NewHTTPChecker(restClient)
-	checkers["ftp"] = NewTelnetChecker(cfg.Timeout)
-	checkers["sftp"] = NewTelnetChecker(cfg.Timeout)
-	checkers["ssh"] = NewTelnetChecker(cfg.Timeout)
-	checkers["telnet"] = NewTelnetChecker(cfg.Timeout)
-	checkers["smtp"] = NewTelnetChecker(cfg.Timeout)
-	checkers["mysql"] = NewTelnetChecker(cfg.Timeout)
-	checkers["postgres"] = NewTelnetChecker(cfg.Timeout)
-	checkers["postgresql"] = NewTelnetChecker(cfg.Timeout)
-	checkers["redis"] = NewTelnetChecker(cfg.Timeout)
-	checkers["mongodb"] = NewTelnetChecker(cfg.Timeout)
-
-	return &Checker{
-		config:     cfg,
-		restClient: restClient,
-		results:    make(chan Result, len(cfg.Targets)*2),
-		checkers:   checkers,
+	checkers := registeredCheckers(cfg.Timeout, resolver)
+	for _, scheme := range httpSchemes {
+		checkers[scheme] = NewHTTPChecker(restClient)
+	}
+	for _, scheme := range telnetSchemes {
+		telnet := NewTelnetChecker(cfg.Timeout)
+		telnet.resolver = resolver
+		checkers[scheme] = telnet
 	}
+
+	targets := cfg.AllTargets()
+
+	policy, err := NewTargetPolicy(cfg.TargetPolicy)
+	if err != nil {
+		log.Error().Err(err).Msg("Ignoring invalid target policy, only link-local addresses are denied")
+		policy = &TargetPolicy{}
+	}
+
+	moduleTLS := make(map[string]*tls.Config)
+	for name, module := range cfg.Modules {
+		if module.TLS.IsZero() {
+			continue
+		}
+		tlsConfig, err := module.TLS.Merge(cfg.TLS).ClientConfig()
+		if err != nil {
+			log.Error().Err(err).Str("module", name).Msg("Ignoring invalid module TLS options")
+			continue
+		}
+		moduleTLS[strings.ToLower(name)] = tlsConfig
+	}
+
+	c := &Checker{
+		config:       cfg,
+		restClient:   restClient,
+		checkers:     checkers,
+		targets:      targets,
+		byURL:        make(map[string]int, len(targets)),
+		byName:       make(map[string]int),
+		specs:        make(map[string]checkSpec),
+		moduleTLS:    moduleTLS,
+		httpCheckers: make(map[clientKey]*HTTPChecker),
+		dnsCache:     resolver,
+		policy:       policy,
+		defaultTLS:   defaultTLS,
+	}
+	c.indexTargets(0)
+
+	for _, target := range targets {
+		spec, err := c.newCheckSpec(target)
+		if err != nil {
+			log.Error().Err(err).Str("url", config.RedactURL(target.URL)).Msg("Ignoring invalid check settings")
+			continue
+		}
+		c.specs[target.URL] = spec
+	}
+
+	return c
 }
 
+// Start checks every target once per interval until the context is done or the checker is
+// shut down. The checks of each target are spread across its interval, see scheduler.
 func (c *Checker) Start(ctx context.Context) {
-	ctx, cancel := context.WithCancel(ctx)
+	checkCtx, cancel := context.WithCancel(ctx)
+	scheduleCtx, stop := context.WithCancel(checkCtx)
+	stopped := make(chan struct{})
 	c.mutex.Lock()
 	c.cancel = cancel
+	c.stop = stop
+	c.stopped = stopped
+	c.running = true
 	c.mutex.Unlock()
 
-	ticker := time.NewTicker(c.config.CheckInterval)
-	defer ticker.Stop()
+	defer func() {
+		c.mutex.Lock()
+		c.running = false
+		c.mutex.Unlock()
+		cancel()
+		close(stopped)
+	}()
+
+	newScheduler(c).run(scheduleCtx, checkCtx)
+}
+
+// Running reports whether the background check loop started by Start is active
+func (c *Checker) Running() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.running
+}
+
+// LastCycle returns when the most recent check cycle completed, or the zero time if none has
+func (c *Checker) LastCycle() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.lastCycle
+}
+
+// Targets returns a copy of the targets currently being checked
+func (c *Checker) Targets() []config.Target {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	targets := make([]config.Target, len(c.targets))
+	copy(targets, c.targets)
+	return targets
+}
+
+// Lookup returns the registered target with the name or, failing that, the URL
+func (c *Checker) Lookup(nameOrURL string) (config.Target, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if target, exists := c.lookupName(nameOrURL); exists {
+		return target, true
+	}
+	if i, exists := c.byURL[nameOrURL]; exists {
+		return c.targets[i], true
+	}
+	return config.Target{}, false
+}
+
+// AddTarget registers a new target that is checked from the next cycle on
+func (c *Checker) AddTarget(target config.Target) error {
+	return c.addTarget(target, false)
+}
+
+// AddRuntimeTarget is AddTarget for a target that reached the exporter at runtime, e.g.
+// through the API or Kubernetes resources: it fails with ErrTargetNotAllowed for targets
+// the target policy rejects and the target's checks only connect to addresses the policy
+// allows
+func (c *Checker) AddRuntimeTarget(target config.Target) error {
+	return c.addTarget(target, true)
+}
+
+// IsRuntimeTarget reports whether the target with the URL was added with AddRuntimeTarget
+func (c *Checker) IsRuntimeTarget(targetURL string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.specs[targetURL].restricted
+}
+
+func (c *Checker) addTarget(target config.Target, restricted bool) error {
+	spec, err := c.newCheckSpec(target)
+	if err != nil {
+		return err
+	}
+	if err := c.config.ValidateTenant(target); err != nil {
+		return err
+	}
+	if restricted {
+		if err := c.policy.CheckURL(target.URL); err != nil {
+			return err
+		}
+		spec.restricted = true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.hasTarget(target.URL) {
+		return fmt.Errorf("%w: %s", ErrTargetExists, config.RedactURL(target.URL))
+	}
+	if _, exists := c.lookupName(target.Name); exists {
+		return fmt.Errorf("%w: name %s", ErrTargetExists, target.Name)
+	}
+	if len(target.DependsOn) > 0 {
+		targets := append(c.targets[:len(c.targets):len(c.targets)], target)
+		for _, selector := range target.DependsOn {
+			if _, exists := config.FindTarget(targets, selector); !exists {
+				return fmt.Errorf("%s depends on unknown target %q", config.RedactURL(target.URL), selector)
+			}
+		}
+		if err := config.DependencyCycle(targets); err != nil {
+			return err
+		}
+	}
+
+	c.targets = append(c.targets, target)
+	c.indexTargets(len(c.targets) - 1)
+	c.specs[target.URL] = spec
+	c.version++
+
+	return nil
+}
+
+// RemoveTarget deregisters a target, returning false if it was not registered. Results of
+// a cycle that is in flight while the target is removed are discarded.
+func (c *Checker) RemoveTarget(targetURL string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	i, exists := c.byURL[targetURL]
+	if !exists {
+		return false
+	}
 
-	c.checkAllURLs(ctx)
+	delete(c.byURL, targetURL)
+	delete(c.byName, c.targets[i].Name)
+	c.targets = slices.Delete(c.targets, i, i+1)
+	c.indexTargets(i)
+	delete(c.specs, targetURL)
+	c.dependencies.forget(targetURL)
+	c.version++
+	return true
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			close(c.results)
-			return
-		case <-ticker.C:
-			c.checkAllURLs(ctx)
+// indexTargets records the positions of the targets from the i-th on in byURL and byName,
+// after they were appended or moved. The caller must hold the mutex.
+func (c *Checker) indexTargets(i int) {
+	for ; i < len(c.targets); i++ {
+		c.byURL[c.targets[i].URL] = i
+		if name := c.targets[i].Name; name != "" {
+			c.byName[name] = i
 		}
 	}
 }
 
-func (c *Checker) Results() <-chan Result {
-	return c.results
+// AddSink registers a sink that records every result of the check cycles. Sinks are
+// called synchronously once all checks of a cycle have completed, before the cycle
+// handlers, so no result is lost: a slow sink delays the next cycle instead.
+func (c *Checker) AddSink(sink ResultSink) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sinks = append(c.sinks, sink)
+}
+
+// OnCycle registers a handler that is called once all checks of a cycle have completed
+func (c *Checker) OnCycle(handler CycleHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cycleHandlers = append(c.cycleHandlers, handler)
+}
+
+// targetsVersion returns a number that changes whenever targets are added or removed
+func (c *Checker) targetsVersion() uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.version
+}
+
+// checkBatch checks the targets as one cycle and delivers the results
+func (c *Checker) checkBatch(ctx context.Context, targets []config.Target) {
+	results, err := c.runChecks(ctx, targets)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to execute concurrent URL checks")
+		return
+	}
+
+	// The checks of a cycle interrupted by shutdown fail without the targets being down
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.deliver(ctx, results)
+}
+
+// RunCycle checks every target once and returns the results sorted by URL. Registered
+// sinks and cycle handlers receive them as well. It is used by the scrape-triggered probe
+// mode where no background loop is running.
+func (c *Checker) RunCycle(ctx context.Context) ([]Result, error) {
+	results, err := c.runChecks(ctx, c.Targets())
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute concurrent URL checks: %w", err)
+	}
+
+	return c.deliver(ctx, results), nil
 }
 
-func (c *Checker) checkAllURLs(ctx context.Context) {
+func (c *Checker) runChecks(ctx context.Context, targets []config.Target) (map[string]Result, error) {
+	id := newCycleID()
+	ctx = withCycleID(ctx, id)
+	ctx = withCycleProgress(ctx, newCycleProgress(targets))
+	log.Debug().Str("cycle_id", id).Msg("Starting check cycle")
+
 	funcs := make(map[string]concurrent.Func[Result])
 
-	for i, targetURL := range c.config.Targets {
+	for i, target := range targets {
+		if target.Disabled {
+			continue
+		}
 		funcKey := fmt.Sprintf("url_%d", i)
-		targetURL := targetURL
+		targetURL := target.URL
 
 		funcs[funcKey] = func(ctx context.Context) (Result, error) {
 			result := c.checkURL(ctx, targetURL)
+			c.publish(result)
 			if result.Error != nil {
 				return result, nil
 			}
@@ -237,44 +736,255 @@ func (c *Checker) checkAllURLs(ctx context.Context) {
 	}
 
 	results, err := concurrent.ExecuteConcurrently(ctx, funcs)
+	c.logDrops(id)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to execute concurrent URL checks")
-		return
+		return nil, err
 	}
 
-	for _, result := range results {
-		select {
-		case c.results <- result:
-		case <-ctx.Done():
-			return
+	c.mutex.Lock()
+	c.lastCycle = time.Now()
+	for key, result := range results {
+		if !c.hasTarget(result.URL) {
+			delete(results, key)
 		}
 	}
+	c.mutex.Unlock()
+
+	return results, nil
+}
+
+// deliver passes the results of a cycle, sorted by URL, to the sinks and then to the
+// cycle handlers and returns them
+func (c *Checker) deliver(ctx context.Context, results map[string]Result) []Result {
+	c.deliverMutex.Lock()
+	defer c.deliverMutex.Unlock()
+
+	c.mutex.RLock()
+	sinks := make([]ResultSink, len(c.sinks))
+	copy(sinks, c.sinks)
+	handlers := make([]CycleHandler, len(c.cycleHandlers))
+	copy(handlers, c.cycleHandlers)
+	c.mutex.RUnlock()
+
+	cycle := sortedResults(results)
+	for _, sink := range sinks {
+		for _, result := range cycle {
+			sink.Record(result)
+		}
+	}
+	for _, handler := range handlers {
+		handler(ctx, cycle)
+	}
+	return cycle
+}
+
+// CheckTarget runs a single on-demand check of an arbitrary target using the target's own
+// method and assertions. The result is neither passed to the sinks, subscribers nor cycle
+// handlers.
+func (c *Checker) CheckTarget(ctx context.Context, target config.Target) (Result, error) {
+	spec, err := c.newCheckSpec(target)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return c.checkTarget(ctx, target, spec), nil
+}
+
+// CheckRuntimeTarget is CheckTarget for a target that reached the exporter at runtime, e.g.
+// through /probe: it fails with ErrTargetNotAllowed for targets the target policy rejects
+// and the check only connects to addresses the policy allows
+func (c *Checker) CheckRuntimeTarget(ctx context.Context, target config.Target) (Result, error) {
+	spec, err := c.newCheckSpec(target)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := c.policy.CheckURL(target.URL); err != nil {
+		return Result{}, err
+	}
+	spec.restricted = true
+
+	return c.checkTarget(ctx, target, spec), nil
 }
 
 func (c *Checker) checkURL(ctx context.Context, targetURL string) Result {
-	host, path := parseURL(targetURL)
+	c.mutex.RLock()
+	spec := c.specs[targetURL]
+	target := config.Target{URL: targetURL}
+	if i, exists := c.byURL[targetURL]; exists {
+		target = c.targets[i]
+	}
+	c.mutex.RUnlock()
+	defer finish(ctx, targetURL)
+
+	if dependency, down := c.downDependency(ctx, target); down {
+		result := skip(ctx, target, dependency)
+		c.dependencies.record(result)
+		return result
+	}
+	result := c.checkTarget(ctx, target, spec)
+	c.dependencies.record(result)
+	return result
+}
+
+// hasTarget reports whether the URL is registered. The caller must hold the mutex.
+func (c *Checker) hasTarget(targetURL string) bool {
+	_, exists := c.byURL[targetURL]
+	return exists
+}
+
+// lookupName returns the registered target with the name. The caller must hold the mutex.
+func (c *Checker) lookupName(name string) (config.Target, bool) {
+	i, exists := c.byName[name]
+	if !exists {
+		return config.Target{}, false
+	}
+	return c.targets[i], true
+}
 
-	result := Result{
+// useDNSCache makes the client's connections resolve hosts through the DNS cache
+func useDNSCache(client *rest.Client, resolver *dnscache.Resolver) {
+	transport, err := client.GetRestClient().Transport()
+	if err != nil {
+		log.Warn().Err(err).Msg("DNS cache not used for HTTP checks")
+		return
+	}
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = resolver.Wrap(dial)
+}
+
+// DNSCache returns the resolver caching the targets' DNS answers, or nil when the DNS
+// cache is disabled
+func (c *Checker) DNSCache() *dnscache.Resolver {
+	return c.dnsCache
+}
+
+// checkTarget runs the hooks around the check of the target
+func (c *Checker) checkTarget(ctx context.Context, target config.Target, spec checkSpec) Result {
+	c.mutex.RLock()
+	hooks := make([]Hook, len(c.hooks))
+	copy(hooks, c.hooks)
+	c.mutex.RUnlock()
+
+	var result Result
+	var err error
+	// ran is the number of hooks whose BeforeCheck succeeded, the only ones to run AfterCheck
+	ran := 0
+	for _, hook := range hooks {
+		hookCtx, hookErr := hook.BeforeCheck(ctx, target)
+		if hookErr != nil {
+			err = hookErr
+			break
+		}
+		ctx = hookCtx
+		ran++
+	}
+	if err != nil {
+		result = newResult(ctx, target.URL)
+		result.Error = fmt.Errorf("check hook failed: %w", err)
+		log.Error().Str("url", config.RedactURL(target.URL)).Err(err).Msg("URL check failed")
+	} else {
+		result = c.check(ctx, target.URL, spec)
+	}
+
+	for i := ran - 1; i >= 0; i-- {
+		hooks[i].AfterCheck(ctx, target, &result)
+	}
+	return result
+}
+
+// newResult returns the result of a check of the URL that has not run yet
+func newResult(ctx context.Context, targetURL string) Result {
+	host, path := parseURL(targetURL)
+	return Result{
 		URL:       targetURL,
 		Host:      host,
 		Path:      path,
+		Protocol:  parseProtocol(targetURL),
 		Timestamp: time.Now(),
+		CycleID:   cycleID(ctx),
+	}
+}
+
+func (c *Checker) check(ctx context.Context, targetURL string, spec checkSpec) Result {
+	result := newResult(ctx, targetURL)
+
+	ctx, span := startCheckSpan(ctx, result)
+	defer func() { endCheckSpan(span, result) }()
+
+	if spec.noDNSCache {
+		ctx = dnscache.WithoutCache(ctx)
+	}
+	// The policy is enforced when dialing, so restricted checks never reuse a pooled
+	// connection, which a trusted check may have opened to the same host
+	if !spec.keepAlive || spec.restricted {
+		ctx = withFreshConnection(ctx)
+	}
+	if spec.restricted {
+		ctx = withTargetPolicy(ctx, c.policy)
+	}
+	ctx = withSource(ctx, spec.source)
+
+	logger := log.Logger
+	if result.CycleID != "" {
+		logger = logger.With().Str("cycle_id", result.CycleID).Logger()
+	}
+	if spec.debug {
+		logger = logger.Level(zerolog.DebugLevel)
+		logger.Debug().
+			Str("url", config.RedactURL(targetURL)).
+			Dur("timeout", c.config.Timeout).
+			Msg("Checking URL")
 	}
 
 	start := time.Now()
-	statusCode, err := c.performCheck(ctx, targetURL)
+	statusCode, inspection, err := c.performInspectedCheck(ctx, targetURL, spec)
 	elapsed := time.Since(start)
+	result.Steps = inspection.Steps
+	result.SizeWithinBounds = inspection.Assertions.SizeWithinBounds
 
 	if err == nil {
 		result.StatusCode = statusCode
 		result.ResponseTime = elapsed
+		result.HTTPVersion = inspection.HTTPVersion
+		if state := inspection.TLS; state != nil {
+			result.TLSVersion = tlsVersionName(state.Version)
+			result.TLSCipherSuite = tls.CipherSuiteName(state.CipherSuite)
+			result.TLSLegacy = spec.tls.legacy(state.Version, state.CipherSuite)
+			if len(state.PeerCertificates) > 0 {
+				result.TLSCertExpiry = state.PeerCertificates[0].NotAfter
+			}
+		}
+		result.BodyMatch = inspection.Assertions.BodyMatch
+		result.HeaderMatch = inspection.Assertions.HeaderMatch
+		result.SecurityHeaders = inspection.Assertions.SecurityHeaders
+		result.Cache = inspection.Cache
+		result.ClockSkew = inspection.ClockSkew
+		result.Failure = spec.assertions.BodySizeFailure(inspection.Assertions)
 		result.Error = nil
 
-		log.Debug().
-			Str("url", targetURL).
+		event := logger.Debug().
+			Str("url", config.RedactURL(targetURL)).
 			Int("status_code", statusCode).
-			Dur("response_time", elapsed).
-			Msg("URL check successful")
+			Dur("response_time", elapsed)
+		if inspection.HTTPVersion > 0 {
+			event = event.Float64("http_version", inspection.HTTPVersion)
+		}
+		if result.TLSLegacy {
+			event = event.Str("tls_version", result.TLSVersion).Str("tls_cipher_suite", result.TLSCipherSuite).Bool("tls_legacy", true)
+		}
+		if result.BodyMatch != nil {
+			event = event.Bool("body_match", *result.BodyMatch)
+		}
+		if result.HeaderMatch != nil {
+			event = event.Bool("header_match", *result.HeaderMatch)
+		}
+		if result.Failure != "" {
+			event = event.Str("failure", result.Failure)
+		}
+		event.Msg("URL check successful")
 
 		return result
 	}
@@ -282,8 +992,8 @@ func (c *Checker) checkURL(ctx context.Context, targetURL string) Result {
 	result.Error = err
 	result.StatusCode = 0
 
-	log.Error().
-		Str("url", targetURL).
+	logger.Error().
+		Str("url", config.RedactURL(targetURL)).
 		Err(err).
 		Msg("URL check failed")
 
@@ -307,6 +1017,52 @@ func (c *Checker) performCheck(ctx context.Context, targetURL string) (int, erro
 	return checker.Check(ctx, targetURL)
 }
 
+// performInspectedCheck runs HTTP targets through the HTTP checker's Inspect path so protocol
+// details and response assertions are captured, and falls back to performCheck otherwise
+func (c *Checker) performInspectedCheck(ctx context.Context, targetURL string, spec checkSpec) (int, Inspection, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return 0, Inspection{}, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	httpChecker, ok := c.checkers[u.Scheme].(*HTTPChecker)
+	if !ok {
+		statusCode, err := c.performCheck(ctx, targetURL)
+		return statusCode, Inspection{}, err
+	}
+	if spec.http != nil {
+		httpChecker = spec.http
+	}
+
+	if len(spec.steps) > 0 {
+		return httpChecker.runTransaction(ctx, targetURL, spec.steps)
+	}
+	if spec.graphql != nil {
+		return httpChecker.runGraphQL(ctx, targetURL, spec.graphql, spec.assertions)
+	}
+	if spec.soap != nil {
+		return httpChecker.runSOAP(ctx, targetURL, spec.soap, spec.assertions)
+	}
+	return httpChecker.Inspect(ctx, targetURL, spec.method, spec.assertions)
+}
+
+func sortedResults(results map[string]Result) []Result {
+	sorted := make([]Result, 0, len(results))
+	for _, result := range results {
+		sorted = append(sorted, result)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].URL < sorted[j].URL
+	})
+	return sorted
+}
+
+// TargetHost returns the host of the URL as exported in the host label of results
+func TargetHost(targetURL string) string {
+	host, _ := parseURL(targetURL)
+	return host
+}
+
 func parseURL(targetURL string) (host, path string) {
 	u, err := url.Parse(targetURL)
 	if err != nil {
@@ -321,19 +1077,42 @@ func parseURL(targetURL string) (host, path string) {
 	}
 
 	if u.RawQuery != "" {
-		path = path + "?" + u.RawQuery
+		path = path + "?" + config.RedactQuery(u.RawQuery)
 	}
 
 	return host, path
 }
 
-func (c *Checker) Shutdown(_ context.Context) error {
+// parseProtocol returns the URL scheme used to select the protocol checker, or "unknown"
+func parseProtocol(targetURL string) string {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Scheme == "" {
+		return "unknown"
+	}
+	return u.Scheme
+}
+
+// Shutdown stops starting new checks and waits until the checks in flight have finished
+// and their results were delivered, or until ctx is done, whichever comes first
+func (c *Checker) Shutdown(ctx context.Context) error {
 	c.mutex.RLock()
-	cancel := c.cancel
+	cancel, stop, stopped := c.cancel, c.stop, c.stopped
 	c.mutex.RUnlock()
+	defer c.closeSubscribers()
 
-	if cancel != nil {
-		cancel()
+	if stop == nil {
+		return nil
 	}
-	return nil
+
+	stop()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Checks still running at the deadline are abandoned and their results discarded
+	cancel()
+	<-stopped
+	return fmt.Errorf("checks still in flight at the shutdown deadline were canceled: %w", ctx.Err())
 }
@@ -1,16 +1,20 @@
 package checker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jasoet/pkg/rest"
 	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,9 +32,25 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, checker)
 	assert.Equal(t, cfg, checker.config)
 	assert.NotNil(t, checker.restClient)
-	assert.NotNil(t, checker.results)
 	assert.Equal(t, 5*time.Second, checker.restClient.GetRestConfig().Timeout)
-	assert.Equal(t, len(cfg.Targets)*2, cap(checker.results))
+}
+
+// resultRecorder is a ResultSink that keeps every recorded result
+type resultRecorder struct {
+	mutex   sync.Mutex
+	results []Result
+}
+
+func (r *resultRecorder) Record(result Result) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *resultRecorder) Results() []Result {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Result(nil), r.results...)
 }
 
 func TestNew_RestClientConfiguration(t *testing.T) {
@@ -248,6 +268,8 @@ func TestCheckURL_Success(t *testing.T) {
 	assert.Equal(t, server.URL, result.URL)
 	assert.Equal(t, server.URL, result.Host)
 	assert.Equal(t, "/", result.Path)
+	assert.Equal(t, "http", result.Protocol)
+	assert.Equal(t, 1.1, result.HTTPVersion)
 	assert.Equal(t, http.StatusOK, result.StatusCode)
 	assert.NoError(t, result.Error)
 	assert.True(t, result.ResponseTime > 0)
@@ -399,18 +421,157 @@ func TestCheckURL_WithQueryParams(t *testing.T) {
 	assert.NoError(t, result.Error)
 }
 
-func TestResults_Channel(t *testing.T) {
+func TestDNSCache(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
 	cfg := &config.Config{
-		Targets: []string{"https://example.com"},
+		Targets: []string{"tcp://localhost:" + port},
+		Checks: []config.Target{
+			{URL: "tcp://localhost:" + port + "/uncached", NoDNSCache: true},
+		},
+		Timeout:  5 * time.Second,
+		DNSCache: config.DNSCacheConfig{Enabled: true, MaxTTL: time.Minute},
+	}
+	checker := New(cfg)
+	require.NotNil(t, checker.DNSCache())
+
+	for i := 0; i < 2; i++ {
+		results, err := checker.RunCycle(context.Background())
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.True(t, result.IsUp(), result.URL)
+		}
+	}
+	assert.Equal(t, uint64(1), checker.DNSCache().Misses())
+	assert.Equal(t, uint64(1), checker.DNSCache().Hits())
+
+	assert.Nil(t, New(&config.Config{Timeout: time.Second}).DNSCache())
+}
+
+func TestAddSink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL + "/b", server.URL + "/a"},
 		Timeout: 5 * time.Second,
 		Retries: 1,
 	}
 
 	checker := New(cfg)
-	results := checker.Results()
+	first, second := &resultRecorder{}, &resultRecorder{}
+	checker.AddSink(first)
+	checker.AddSink(second)
+
+	var recordedBeforeHandler int
+	checker.OnCycle(func(_ context.Context, _ []Result) {
+		recordedBeforeHandler = len(first.Results())
+	})
+
+	checker.checkBatch(context.Background(), checker.Targets())
+
+	// Every sink receives every result, sorted by URL, before the cycle handlers run
+	for _, sink := range []*resultRecorder{first, second} {
+		results := sink.Results()
+		require.Len(t, results, 2)
+		assert.Equal(t, server.URL+"/a", results[0].URL)
+		assert.Equal(t, server.URL+"/b", results[1].URL)
+	}
+	assert.Equal(t, 2, recordedBeforeHandler)
+}
+
+func TestAddSink_Func(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Targets: []string{server.URL}, Timeout: 5 * time.Second})
+	var recorded []Result
+	checker.AddSink(ResultSinkFunc(func(result Result) {
+		recorded = append(recorded, result)
+	}))
+
+	_, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, server.URL, recorded[0].URL)
+}
+
+func TestAddSink_SlowSinkDelaysCycle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	targets := make([]string, 20)
+	for i := range targets {
+		targets[i] = fmt.Sprintf("%s/%02d", server.URL, i)
+	}
+	checker := New(&config.Config{Targets: targets, Timeout: 5 * time.Second, Retries: 1})
+
+	release := make(chan struct{})
+	slow := &blockingSink{release: release}
+	checker.AddSink(slow)
 
-	assert.NotNil(t, results)
-	assert.IsType(t, (<-chan Result)(nil), results)
+	done := make(chan struct{})
+	go func() {
+		checker.checkBatch(context.Background(), checker.Targets())
+		close(done)
+	}()
+
+	// The cycle waits for the sink instead of dropping results
+	select {
+	case <-done:
+		t.Fatal("Cycle completed while the sink was blocked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cycle did not complete after the sink was released")
+	}
+	assert.Len(t, slow.Results(), len(targets))
+}
+
+// blockingSink records results once release is closed
+type blockingSink struct {
+	resultRecorder
+	release chan struct{}
+}
+
+func (b *blockingSink) Record(result Result) {
+	<-b.release
+	b.resultRecorder.Record(result)
+}
+
+func TestCheckBatch_CanceledCycleNotDelivered(t *testing.T) {
+	checker := New(&config.Config{Targets: []string{"http://127.0.0.1:1"}, Timeout: time.Second, Retries: 1})
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checker.checkBatch(ctx, checker.Targets())
+
+	assert.Empty(t, sink.Results())
 }
 
 func TestShutdown(t *testing.T) {
@@ -432,6 +593,78 @@ func TestShutdown(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestShutdown_DrainsChecksInFlight(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{
+		Targets:       []string{server.URL},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+	})
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	go checker.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, checker.Shutdown(ctx))
+
+	// The check in flight finished and was recorded before Shutdown returned
+	results := sink.Results()
+	require.Len(t, results, 1)
+	assert.True(t, results[0].IsUp())
+	assert.False(t, checker.Running())
+}
+
+func TestShutdown_DeadlineCancelsChecksInFlight(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	checker := New(&config.Config{
+		Targets:       []string{server.URL},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+	})
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	go checker.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := checker.Shutdown(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The canceled check does not report the target as down
+	assert.Empty(t, sink.Results())
+	assert.False(t, checker.Running())
+}
+
 func TestStart_Integration(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -446,24 +679,18 @@ func TestStart_Integration(t *testing.T) {
 	}
 
 	checker := New(cfg)
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
 
 	go checker.Start(ctx)
 
-	var results []Result
-	timeout := time.After(400 * time.Millisecond)
-
-	for len(results) < 2 {
-		select {
-		case result := <-checker.Results():
-			results = append(results, result)
-		case <-timeout:
-			t.Fatal("Did not receive expected results within timeout")
-		}
-	}
+	require.Eventually(t, func() bool {
+		return len(sink.Results()) >= 2
+	}, 400*time.Millisecond, 10*time.Millisecond, "Did not receive expected results within timeout")
 
-	assert.GreaterOrEqual(t, len(results), 2)
+	results := sink.Results()
 	for _, result := range results {
 		assert.Equal(t, server.URL, result.URL)
 		assert.Equal(t, http.StatusOK, result.StatusCode)
@@ -518,7 +745,7 @@ func TestPerformCheck_UserAgent(t *testing.T) {
 	assert.Equal(t, "url-exporter/1.0", capturedUserAgent)
 }
 
-func TestCheckAllURLs_ConcurrentExecution(t *testing.T) {
+func TestCheckBatch_ConcurrentExecution(t *testing.T) {
 	serverCount := 3
 	servers := make([]*httptest.Server, serverCount)
 	urls := make([]string, serverCount)
@@ -545,26 +772,17 @@ func TestCheckAllURLs_ConcurrentExecution(t *testing.T) {
 	}
 
 	checker := New(cfg)
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
 	ctx := context.Background()
 
 	start := time.Now()
-	checker.checkAllURLs(ctx)
+	checker.checkBatch(ctx, checker.Targets())
 	elapsed := time.Since(start)
 
 	assert.Less(t, elapsed, 100*time.Millisecond, "Concurrent execution should be faster than sequential")
 
-	var results []Result
-	timeout := time.After(100 * time.Millisecond)
-
-	for len(results) < serverCount {
-		select {
-		case result := <-checker.Results():
-			results = append(results, result)
-		case <-timeout:
-			break
-		}
-	}
-
+	results := sink.Results()
 	assert.Equal(t, serverCount, len(results))
 	for _, result := range results {
 		assert.NoError(t, result.Error)
@@ -572,6 +790,439 @@ func TestCheckAllURLs_ConcurrentExecution(t *testing.T) {
 	}
 }
 
+func TestCheckBatch_NotifiesCycleHandlers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL + "/b", server.URL + "/a"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+
+	var cycles [][]Result
+	checker.OnCycle(func(_ context.Context, results []Result) {
+		cycles = append(cycles, results)
+	})
+
+	checker.checkBatch(context.Background(), checker.Targets())
+
+	require.Len(t, cycles, 1)
+	require.Len(t, cycles[0], 2)
+	assert.Equal(t, server.URL+"/a", cycles[0][0].URL)
+	assert.Equal(t, server.URL+"/b", cycles[0][1].URL)
+	for _, result := range cycles[0] {
+		assert.True(t, result.IsUp())
+	}
+}
+
+func TestCheckURL_WithAssertions(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: server.URL + "/body", ExpectBody: `"status":"ok"`, ExpectHeaders: map[string]string{"content-type": "json"}},
+			{URL: server.URL + "/headers", ExpectHeaders: map[string]string{"content-type": "text/html"}},
+		},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+
+	result := checker.checkURL(context.Background(), server.URL+"/body")
+	require.NoError(t, result.Error)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	require.NotNil(t, result.BodyMatch)
+	require.NotNil(t, result.HeaderMatch)
+	assert.True(t, *result.BodyMatch)
+	assert.True(t, *result.HeaderMatch)
+
+	result = checker.checkURL(context.Background(), server.URL+"/headers")
+	require.NoError(t, result.Error)
+	assert.Nil(t, result.BodyMatch)
+	require.NotNil(t, result.HeaderMatch)
+	assert.False(t, *result.HeaderMatch)
+
+	// Body assertions need a GET; header-only assertions keep using HEAD
+	assert.Equal(t, []string{http.MethodGet, http.MethodHead}, methods)
+}
+
+func TestCheckTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{"https://example.com"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL, ExpectBody: "hello"})
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+	assert.Equal(t, server.URL, result.URL)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	require.NotNil(t, result.BodyMatch)
+	assert.True(t, *result.BodyMatch)
+
+	// On-demand checks are not passed to the sinks
+	assert.Empty(t, sink.Results())
+
+	_, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL, ExpectBody: "("})
+	assert.Error(t, err)
+}
+
+func TestCheckTarget_Method(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{"https://example.com"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+
+	_, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Method: http.MethodGet})
+	require.NoError(t, err)
+	_, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Method: http.MethodPost, ExpectBody: "ok"})
+	require.NoError(t, err)
+	_, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{http.MethodGet, http.MethodPost, http.MethodHead}, methods)
+}
+
+func TestCheckTarget_Module(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("healthy"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{"https://example.com"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+		Modules: map[string]config.Module{
+			"https_insecure": {
+				Method:     http.MethodGet,
+				ExpectBody: "healthy",
+				TLS:        config.TLSConfig{InsecureSkipVerify: true},
+			},
+		},
+	}
+
+	checker := New(cfg)
+
+	// The test server's self-signed certificate is rejected without the module
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.Error(t, result.Error)
+
+	result, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Module: "HTTPS_Insecure"})
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+	assert.True(t, result.IsUp())
+	require.NotNil(t, result.BodyMatch)
+	assert.True(t, *result.BodyMatch)
+
+	_, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Module: "missing"})
+	assert.ErrorContains(t, err, `unknown module "missing"`)
+}
+
+func TestCheckTarget_SecurityHeadersModule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Timeout: 5 * time.Second})
+
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.Nil(t, result.SecurityHeaders)
+
+	result, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Module: config.SecurityHeadersModule})
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	assert.Equal(t, map[string]bool{
+		"Strict-Transport-Security": false,
+		"Content-Security-Policy":   true,
+		"X-Content-Type-Options":    false,
+		"X-Frame-Options":           true,
+	}, result.SecurityHeaders)
+}
+
+func TestCheckURL_WithoutAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+	result := checker.checkURL(context.Background(), server.URL)
+
+	assert.NoError(t, result.Error)
+	assert.Nil(t, result.BodyMatch)
+	assert.Nil(t, result.HeaderMatch)
+}
+
+func TestHTTPVersion(t *testing.T) {
+	assert.Equal(t, 1.0, httpVersion(1, 0))
+	assert.Equal(t, 1.1, httpVersion(1, 1))
+	assert.Equal(t, 2.0, httpVersion(2, 0))
+	assert.Equal(t, 3.0, httpVersion(3, 0))
+}
+
+func TestCheckURL_NetworkErrorHasNoHTTPVersion(t *testing.T) {
+	cfg := &config.Config{
+		Targets: []string{"http://127.0.0.1:1"},
+		Timeout: time.Second,
+		Retries: 0,
+	}
+
+	checker := New(cfg)
+	result := checker.checkURL(context.Background(), "http://127.0.0.1:1")
+
+	assert.Error(t, result.Error)
+	assert.Equal(t, float64(0), result.HTTPVersion)
+}
+
+func TestParseProtocol(t *testing.T) {
+	assert.Equal(t, "https", parseProtocol("https://example.com"))
+	assert.Equal(t, "tcp", parseProtocol("tcp://db.internal:5432"))
+	assert.Equal(t, "redis", parseProtocol("redis://localhost:6379"))
+	assert.Equal(t, "unknown", parseProtocol("example.com"))
+	assert.Equal(t, "unknown", parseProtocol("://invalid"))
+}
+
+func TestPerformCheck_ProtocolSelection_PlainTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	cfg := &config.Config{
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+
+	statusCode, err := checker.performCheck(context.Background(), "tcp://"+listener.Addr().String())
+	assert.NoError(t, err)
+	assert.Equal(t, 200, statusCode)
+
+	_, err = checker.performCheck(context.Background(), "tcp://127.0.0.1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no default port for scheme: tcp")
+}
+
+func TestRunCycle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL + "/b", server.URL + "/a"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	notified := 0
+	checker.OnCycle(func(_ context.Context, results []Result) {
+		notified = len(results)
+	})
+
+	results, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, server.URL+"/a", results[0].URL)
+	assert.Equal(t, server.URL+"/b", results[1].URL)
+	assert.Equal(t, 2, notified)
+
+	// Scrape-triggered cycles reach the sinks like scheduled ones
+	assert.Equal(t, results, sink.Results())
+}
+
+func TestChecker_RunningAndLastCycle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets:       []string{server.URL},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+		Retries:       1,
+	}
+
+	checker := New(cfg)
+	assert.False(t, checker.Running())
+	assert.True(t, checker.LastCycle().IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Start(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return checker.Running() && !checker.LastCycle().IsZero()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+	assert.False(t, checker.Running())
+}
+
+func TestChecker_AddRemoveTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL + "/a"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+
+	require.NoError(t, checker.AddTarget(config.Target{URL: server.URL + "/b", Name: "b", ExpectBody: "ok"}))
+	assert.ErrorIs(t, checker.AddTarget(config.Target{URL: server.URL + "/a"}), ErrTargetExists)
+	assert.ErrorIs(t, checker.AddTarget(config.Target{URL: server.URL + "/d", Name: "b"}), ErrTargetExists)
+	assert.Error(t, checker.AddTarget(config.Target{URL: server.URL + "/c", ExpectBody: "("}))
+	require.Len(t, checker.Targets(), 2)
+
+	results, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NotNil(t, results[1].BodyMatch)
+	assert.True(t, *results[1].BodyMatch)
+
+	target, ok := checker.Lookup("b")
+	require.True(t, ok)
+	assert.Equal(t, server.URL+"/b", target.URL)
+	_, ok = checker.Lookup(server.URL + "/a")
+	assert.True(t, ok)
+	_, ok = checker.Lookup("")
+	assert.False(t, ok)
+
+	assert.True(t, checker.RemoveTarget(server.URL+"/a"))
+	assert.False(t, checker.RemoveTarget(server.URL+"/a"))
+
+	// The targets after a removed one are still found
+	target, ok = checker.Lookup("b")
+	require.True(t, ok)
+	assert.Equal(t, server.URL+"/b", target.URL)
+	_, ok = checker.Lookup(server.URL + "/a")
+	assert.False(t, ok)
+
+	results, err = checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, server.URL+"/b", results[0].URL)
+
+	require.NoError(t, checker.AddTarget(config.Target{URL: server.URL + "/a", Name: "a"}))
+	target, ok = checker.Lookup("a")
+	require.True(t, ok)
+	assert.Equal(t, server.URL+"/a", target.URL)
+}
+
+// benchmarkChecker returns a checker with the given number of named targets
+func benchmarkChecker(b *testing.B, targets int) *Checker {
+	b.Helper()
+
+	checks := make([]config.Target, targets)
+	for i := range checks {
+		checks[i] = config.Target{URL: fmt.Sprintf("https://host-%d.example.com/health", i), Name: fmt.Sprintf("host-%d", i)}
+	}
+	return New(&config.Config{Checks: checks, Timeout: 5 * time.Second})
+}
+
+func BenchmarkChecker_Lookup(b *testing.B) {
+	checker := benchmarkChecker(b, 10000)
+	url, name := "https://host-9999.example.com/health", "host-9999"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		checker.Lookup(url)
+		checker.Lookup(name)
+	}
+}
+
+func BenchmarkChecker_AddRemoveTarget(b *testing.B) {
+	checker := benchmarkChecker(b, 10000)
+	target := config.Target{URL: "https://new.example.com/health", Name: "new"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := checker.AddTarget(target); err != nil {
+			b.Fatal(err)
+		}
+		checker.RemoveTarget(target.URL)
+	}
+}
+
+func TestResult_IsUp(t *testing.T) {
+	assert.True(t, Result{StatusCode: 200}.IsUp())
+	assert.True(t, Result{StatusCode: 204}.IsUp())
+	assert.False(t, Result{StatusCode: 301}.IsUp())
+	assert.False(t, Result{StatusCode: 500}.IsUp())
+	assert.False(t, Result{StatusCode: 200, Error: fmt.Errorf("boom")}.IsUp())
+}
+
 // Protocol Checker Tests
 
 func TestHTTPChecker_NewHTTPChecker(t *testing.T) {
@@ -1069,3 +1720,107 @@ func TestChecker_ProtocolSpecificErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestRunCycle_SkipsDisabledTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: server.URL + "/enabled"},
+			{URL: server.URL + "/disabled", Disabled: true},
+		},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+
+	results, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, server.URL+"/enabled", results[0].URL)
+	assert.Len(t, checker.Targets(), 2)
+}
+
+func TestCheckTarget_DebugTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	previousLogger, previousLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&output).Level(zerolog.InfoLevel)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	defer func() {
+		log.Logger = previousLogger
+		zerolog.SetGlobalLevel(previousLevel)
+	}()
+
+	cfg := &config.Config{
+		Timeout:      5 * time.Second,
+		Retries:      1,
+		DebugTargets: []string{"flaky"},
+	}
+	checker := New(cfg)
+
+	_, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL + "/stable"})
+	require.NoError(t, err)
+	assert.NotContains(t, output.String(), "URL check successful")
+
+	_, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL + "/flaky", Group: "flaky"})
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "Checking URL")
+	assert.Contains(t, output.String(), "URL check successful")
+	assert.Contains(t, output.String(), server.URL+"/flaky")
+}
+
+func TestCheckTarget_ClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Timeout: 5 * time.Second})
+
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	require.NotNil(t, result.ClockSkew)
+	assert.InDelta(t, time.Hour.Seconds(), result.ClockSkew.Seconds(), 1)
+
+	assert.Nil(t, clockSkew(http.Header{}, time.Now()))
+	skew := clockSkew(http.Header{"Date": {"Sun, 06 Nov 1994 08:49:37 GMT"}}, time.Date(1994, 11, 6, 8, 50, 0, 500, time.UTC))
+	require.NotNil(t, skew)
+	assert.Equal(t, -23*time.Second, *skew)
+}
+
+func TestCheckTarget_BodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Timeout: 5 * time.Second})
+
+	// A blank page is reported without affecting url_up
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL, MinBodyBytes: 100})
+	require.NoError(t, err)
+	assert.True(t, result.IsUp(), "%v", result.Error)
+	require.NotNil(t, result.SizeWithinBounds)
+	assert.False(t, *result.SizeWithinBounds)
+
+	// Failing on the size is an assertion failure, not an error
+	result, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL, MinBodyBytes: 100, FailOnBodySize: true})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+	assert.NoError(t, result.Error)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, "response body of 0 bytes is below minBodyBytes 100", result.Failure)
+	require.NotNil(t, result.SizeWithinBounds)
+	assert.False(t, *result.SizeWithinBounds)
+}
@@ -0,0 +1,129 @@
+package checker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jasoet/pkg/rest"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/dnscache"
+	"github.com/rs/zerolog/log"
+)
+
+// clientKey identifies a dedicated HTTP client: one per configured group, module with
+// TLS options and source other than the global one that targets actually use
+type clientKey struct {
+	group  string
+	module string
+	source config.SourceConfig
+}
+
+// newRestClient builds an HTTP client with the group's settings applied over the global
+// ones. Each client has its own connection pool, whose connections originate from the source.
+func newRestClient(cfg *config.Config, group config.GroupConfig, tlsConfig *tls.Config, source config.SourceConfig, resolver *dnscache.Resolver) *rest.Client {
+	restConfig := rest.Config{
+		RetryCount:    cfg.Retries,
+		RetryWaitTime: time.Second,
+		Timeout:       cfg.Timeout,
+	}
+	if group.Timeout > 0 {
+		restConfig.Timeout = group.Timeout
+	}
+	if group.Retries != nil {
+		restConfig.RetryCount = *group.Retries
+	}
+
+	client := rest.NewClient(rest.WithRestConfig(restConfig))
+	client.GetRestClient().SetLogger(restyLogger{})
+	if tlsConfig != nil {
+		client.GetRestClient().SetTLSClientConfig(tlsConfig)
+	}
+	if group.Proxy != "" {
+		client.GetRestClient().SetProxy(group.Proxy)
+	}
+	guardDials(client, source)
+	if resolver != nil {
+		useDNSCache(client, resolver)
+	}
+
+	connections := cfg.Connections
+	if group.MaxConnsPerHost > 0 {
+		connections.MaxConnsPerHost = group.MaxConnsPerHost
+	}
+	configureConnections(client, connections)
+
+	return client
+}
+
+// guardDials makes the client's connections originate from the source and pass the target
+// policy of restricted checks, keeping the dial settings of resty's default transport
+func guardDials(client *rest.Client, source config.SourceConfig) {
+	transport, err := client.GetRestClient().Transport()
+	if err != nil {
+		log.Warn().Err(err).Msg("Target policy not applied to HTTP checks")
+		return
+	}
+	dialer := newDialer(30*time.Second, source)
+	dialer.KeepAlive = 30 * time.Second
+	transport.DialContext = dialer.DialContext
+}
+
+// httpChecker returns the dedicated HTTP checker for the target, or nil when it uses the
+// default client. Only groups configured under groups get their own client, so groups
+// set through the API cannot grow the number of clients without bound.
+func (c *Checker) httpChecker(target config.Target) *HTTPChecker {
+	var key clientKey
+	group, grouped := c.config.Groups.Lookup(target.Group)
+	if grouped {
+		key.group = strings.ToLower(target.Group)
+	}
+	tlsConfig, hasTLS := c.moduleTLS[strings.ToLower(target.Module)]
+	if hasTLS {
+		key.module = strings.ToLower(target.Module)
+	} else {
+		tlsConfig = c.defaultTLS
+	}
+	source := c.config.SourceFor(target)
+	if source != c.config.Source {
+		key.source = source
+	}
+	if !grouped && !hasTLS && key.source.IsZero() {
+		return nil
+	}
+
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	if checker, exists := c.httpCheckers[key]; exists {
+		return checker
+	}
+	checker := NewHTTPChecker(newRestClient(c.config, group, tlsConfig, source, c.dnsCache))
+	c.httpCheckers[key] = checker
+	return checker
+}
+
+// urlPattern finds the URLs in resty's log messages, e.g. in the errors of failed attempts
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"]+`)
+
+// restyLogger writes resty's messages to the application log with the URLs in them redacted
+type restyLogger struct{}
+
+func (restyLogger) Errorf(format string, v ...any) {
+	log.Error().Msg(redactMessage(format, v))
+}
+
+func (restyLogger) Warnf(format string, v ...any) {
+	log.Warn().Msg(redactMessage(format, v))
+}
+
+func (restyLogger) Debugf(format string, v ...any) {
+	log.Debug().Msg(redactMessage(format, v))
+}
+
+func redactMessage(format string, v []any) string {
+	message := strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")
+	return urlPattern.ReplaceAllStringFunc(message, config.RedactURL)
+}
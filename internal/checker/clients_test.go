@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPChecker_Groups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{
+		Checks: []config.Target{
+			{URL: server.URL + "/a", Group: "Payments"},
+			{URL: server.URL + "/b", Group: "payments"},
+			{URL: server.URL + "/c", Group: "unconfigured"},
+			{URL: server.URL + "/d"},
+		},
+		Timeout: 5 * time.Second,
+		Groups:  config.GroupsConfig{"payments": {Timeout: time.Second}},
+	})
+
+	grouped := checker.specs[server.URL+"/a"].http
+	require.NotNil(t, grouped)
+	assert.Same(t, grouped, checker.specs[server.URL+"/b"].http, "targets of a group share its client")
+	assert.NotSame(t, checker.restClient, grouped.restClient)
+	assert.Nil(t, checker.specs[server.URL+"/c"].http, "groups without settings use the default client")
+	assert.Nil(t, checker.specs[server.URL+"/d"].http)
+
+	results, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.True(t, result.IsUp(), result.URL)
+	}
+}
+
+func TestHTTPChecker_GroupTimeoutAndRetries(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	noRetries := 0
+	checker := New(&config.Config{
+		Checks: []config.Target{
+			{URL: server.URL + "/slow", Group: "aggressive"},
+			{URL: server.URL + "/default"},
+		},
+		Timeout: 5 * time.Second,
+		Retries: 2,
+		Groups:  config.GroupsConfig{"aggressive": {Timeout: 50 * time.Millisecond, Retries: &noRetries}},
+	})
+
+	results, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	byURL := make(map[string]Result)
+	for _, result := range results {
+		byURL[result.URL] = result
+	}
+	assert.False(t, byURL[server.URL+"/slow"].IsUp(), "the group timeout applies")
+	assert.True(t, byURL[server.URL+"/default"].IsUp(), "other targets keep the global timeout")
+	assert.Equal(t, int64(2), requests.Load(), "the group does not retry")
+}
+
+func TestHTTPChecker_GroupProxy(t *testing.T) {
+	var proxied atomic.Int64
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{
+		Checks: []config.Target{
+			{URL: server.URL + "/proxied", Group: "dmz"},
+			{URL: server.URL + "/direct"},
+		},
+		Timeout: 5 * time.Second,
+		Groups:  config.GroupsConfig{"dmz": {Proxy: proxy.URL}},
+	})
+
+	results, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for _, result := range results {
+		switch result.URL {
+		case server.URL + "/proxied":
+			assert.Equal(t, http.StatusNoContent, result.StatusCode)
+		default:
+			assert.Equal(t, http.StatusOK, result.StatusCode)
+		}
+	}
+	assert.Equal(t, int64(1), proxied.Load())
+}
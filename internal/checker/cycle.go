@@ -0,0 +1,27 @@
+package checker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// cycleIDKey is the context key of the ID of the check cycle a check belongs to
+type cycleIDKey struct{}
+
+// newCycleID returns a random ID that correlates the results, logs and spans of a cycle
+func newCycleID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func withCycleID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, cycleIDKey{}, id)
+}
+
+// cycleID returns the ID of the cycle running the check, or "" for on-demand checks
+func cycleID(ctx context.Context) string {
+	id, _ := ctx.Value(cycleIDKey{}).(string)
+	return id
+}
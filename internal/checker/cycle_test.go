@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRunCycle_CycleID(t *testing.T) {
+	recorder := recordSpans(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL + "/a", server.URL + "/b"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+	chk := New(cfg)
+
+	first, err := chk.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.NotEmpty(t, first[0].CycleID)
+	assert.Equal(t, first[0].CycleID, first[1].CycleID)
+
+	for _, span := range recorder.Ended() {
+		assert.Contains(t, span.Attributes(), attribute.String("check.cycle_id", first[0].CycleID))
+	}
+
+	second, err := chk.RunCycle(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, first[0].CycleID, second[0].CycleID)
+
+	// On-demand checks are not part of a cycle
+	result, err := chk.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.Empty(t, result.CycleID)
+}
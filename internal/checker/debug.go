@@ -0,0 +1,179 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Debug collects what happens during the checks run with its context: DNS answers,
+// connection and TLS details, every attempt and the request and response headers. It
+// answers why a target is reported down, see the debug subcommand.
+type Debug struct {
+	start    time.Time
+	mutex    sync.Mutex
+	events   []DebugEvent
+	attempts int
+}
+
+// DebugEvent is a step of a check, with the time since the debug collection started
+type DebugEvent struct {
+	Elapsed time.Duration
+	Message string
+	// Details are further lines, e.g. headers
+	Details []string
+}
+
+// debugKey is the context key of the Debug collecting a check's events
+type debugKey struct{}
+
+// WithDebug returns a context whose checks record their events in the Debug
+func WithDebug(ctx context.Context, debug *Debug) context.Context {
+	debug.start = time.Now()
+	ctx = context.WithValue(ctx, debugKey{}, debug)
+	return httptrace.WithClientTrace(ctx, debug.clientTrace())
+}
+
+func debugFrom(ctx context.Context) *Debug {
+	debug, _ := ctx.Value(debugKey{}).(*Debug)
+	return debug
+}
+
+// Events returns the recorded events in order
+func (d *Debug) Events() []DebugEvent {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]DebugEvent{}, d.events...)
+}
+
+func (d *Debug) record(details []string, format string, args ...any) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.events = append(d.events, DebugEvent{
+		Elapsed: time.Since(d.start),
+		Message: fmt.Sprintf(format, args...),
+		Details: details,
+	})
+}
+
+// clientTrace records the connection steps of HTTP checks and, through the dialer, of
+// the connection checks of other protocols
+func (d *Debug) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			d.mutex.Lock()
+			d.attempts++
+			attempt := d.attempts
+			d.mutex.Unlock()
+			d.record(nil, "attempt %d: getting a connection to %s", attempt, hostPort)
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			d.record(nil, "DNS lookup of %s", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err != nil {
+				d.record(nil, "DNS lookup failed: %v", info.Err)
+				return
+			}
+			addrs := make([]string, 0, len(info.Addrs))
+			for _, addr := range info.Addrs {
+				addrs = append(addrs, addr.String())
+			}
+			d.record(nil, "DNS answer: %s", strings.Join(addrs, ", "))
+		},
+		ConnectStart: func(network, addr string) {
+			d.record(nil, "connecting to %s over %s", addr, network)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				d.record(nil, "connection to %s failed: %v", addr, err)
+				return
+			}
+			d.record(nil, "connected to %s", addr)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				d.record(nil, "reusing connection to %s, idle for %s", info.Conn.RemoteAddr(), info.IdleTime)
+			}
+		},
+		TLSHandshakeStart: func() {
+			d.record(nil, "TLS handshake started")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				d.record(nil, "TLS handshake failed: %v", err)
+				return
+			}
+			d.record(tlsDetails(state), "TLS handshake done: %s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err != nil {
+				d.record(nil, "writing the request failed: %v", info.Err)
+			}
+		},
+		GotFirstResponseByte: func() {
+			d.record(nil, "first response byte received")
+		},
+	}
+}
+
+// tlsDetails describes the negotiated protocol and the server's certificate chain
+func tlsDetails(state tls.ConnectionState) []string {
+	var details []string
+	if state.NegotiatedProtocol != "" {
+		details = append(details, "ALPN: "+state.NegotiatedProtocol)
+	}
+	if state.ServerName != "" {
+		details = append(details, "server name: "+state.ServerName)
+	}
+	for i, cert := range state.PeerCertificates {
+		details = append(details, fmt.Sprintf("certificate %d: %s, issued by %s, valid %s to %s",
+			i, cert.Subject, cert.Issuer,
+			cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339)))
+	}
+	return details
+}
+
+// formatHeaders lists headers as "Name: value" lines sorted by name
+func formatHeaders(headers http.Header) []string {
+	lines := make([]string, 0, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func (d *Debug) recordRequest(method, target string, headers map[string]string) {
+	lines := make([]string, 0, len(headers))
+	for name, value := range headers {
+		if strings.EqualFold(name, "Authorization") {
+			value = "<redacted>"
+		}
+		lines = append(lines, name+": "+value)
+	}
+	sort.Strings(lines)
+	d.record(lines, "request: %s %s", method, config.RedactURL(target))
+}
+
+func (d *Debug) recordResponse(response *resty.Response, err error) {
+	if response == nil || response.RawResponse == nil {
+		if err != nil {
+			d.record(nil, "request failed: %v", err)
+		}
+		return
+	}
+	raw := response.RawResponse
+	d.record(formatHeaders(raw.Header), "response: %s %s, %d body bytes", raw.Proto, raw.Status, len(response.Body()))
+}
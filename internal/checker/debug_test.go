@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func debugMessages(debug *Debug) []string {
+	var messages []string
+	for _, event := range debug.Events() {
+		messages = append(messages, event.Message)
+	}
+	return messages
+}
+
+func TestDebug_HTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout: 5 * time.Second,
+		Modules: map[string]config.Module{"insecure": {TLS: config.TLSConfig{InsecureSkipVerify: true}}},
+	}
+	debug := &Debug{}
+	result, err := New(cfg).CheckTarget(WithDebug(context.Background(), debug), config.Target{
+		URL:    server.URL,
+		Module: "insecure",
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsUp())
+
+	messages := strings.Join(debugMessages(debug), "\n")
+	assert.Contains(t, messages, "TLS handshake done: TLS 1.3")
+	assert.Contains(t, messages, "request: HEAD "+server.URL)
+	assert.Contains(t, messages, "response: HTTP/1.1 200 OK")
+
+	for _, event := range debug.Events() {
+		if strings.HasPrefix(event.Message, "TLS handshake done") {
+			require.NotEmpty(t, event.Details)
+			assert.Contains(t, event.Details[len(event.Details)-1], "certificate 0:")
+		}
+	}
+}
+
+func TestDebug_RedactsAuthorization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	debug := &Debug{}
+	ctx := WithRequestHeader(WithDebug(context.Background(), debug), "Authorization", "Bearer secret")
+	_, err := New(&config.Config{Timeout: 5 * time.Second}).CheckTarget(ctx, config.Target{URL: server.URL})
+	require.NoError(t, err)
+
+	for _, event := range debug.Events() {
+		for _, detail := range event.Details {
+			assert.NotContains(t, detail, "secret")
+		}
+	}
+}
+
+func TestDebug_ConnectionCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+
+	debug := &Debug{}
+	result, err := New(&config.Config{Timeout: time.Second}).CheckTarget(WithDebug(context.Background(), debug), config.Target{
+		URL: "tcp://" + listener.Addr().String(),
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+
+	messages := debugMessages(debug)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "connecting to "+listener.Addr().String()+" over tcp", messages[0])
+	assert.True(t, strings.HasPrefix(messages[1], "connection to "+listener.Addr().String()+" failed"), messages[1])
+}
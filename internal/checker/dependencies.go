@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// cycleProgress tracks which targets of a cycle have been checked, so the checks of
+// dependent targets wait for those of the targets they depend on
+type cycleProgress struct {
+	done map[string]chan struct{}
+}
+
+// cycleProgressKey is the context key of the progress of the cycle a check belongs to
+type cycleProgressKey struct{}
+
+func newCycleProgress(targets []config.Target) *cycleProgress {
+	progress := &cycleProgress{done: make(map[string]chan struct{}, len(targets))}
+	for _, target := range targets {
+		if !target.Disabled {
+			progress.done[target.URL] = make(chan struct{})
+		}
+	}
+	return progress
+}
+
+func withCycleProgress(ctx context.Context, progress *cycleProgress) context.Context {
+	return context.WithValue(ctx, cycleProgressKey{}, progress)
+}
+
+// finish marks the target checked in the cycle of the context
+func finish(ctx context.Context, targetURL string) {
+	if progress, _ := ctx.Value(cycleProgressKey{}).(*cycleProgress); progress != nil {
+		if done, exists := progress.done[targetURL]; exists {
+			close(done)
+		}
+	}
+}
+
+// await waits until the target is checked when it belongs to the cycle of the context
+func await(ctx context.Context, targetURL string) {
+	progress, _ := ctx.Value(cycleProgressKey{}).(*cycleProgress)
+	if progress == nil {
+		return
+	}
+	if done, exists := progress.done[targetURL]; exists {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// dependencyStates holds whether the latest scheduled check of each target was up
+type dependencyStates struct {
+	mutex sync.RWMutex
+	up    map[string]bool
+}
+
+func (s *dependencyStates) record(result Result) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.up == nil {
+		s.up = make(map[string]bool)
+	}
+	s.up[result.URL] = result.IsUp()
+}
+
+func (s *dependencyStates) forget(targetURL string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.up, targetURL)
+}
+
+// downDependency returns the first dependency of the target whose latest check was not
+// up, a skipped check included, after waiting for its check when it runs in the same
+// cycle. Dependencies that are not registered or not checked yet count as up.
+func (c *Checker) downDependency(ctx context.Context, target config.Target) (string, bool) {
+	for _, selector := range target.DependsOn {
+		parent, exists := c.Lookup(selector)
+		if !exists {
+			continue
+		}
+
+		await(ctx, parent.URL)
+		c.dependencies.mutex.RLock()
+		up, checked := c.dependencies.up[parent.URL]
+		c.dependencies.mutex.RUnlock()
+		if checked && !up {
+			return selector, true
+		}
+	}
+	return "", false
+}
+
+// skip returns the result of a check skipped because the dependency is down
+func skip(ctx context.Context, target config.Target, dependency string) Result {
+	result := newResult(ctx, target.URL)
+	result.SkippedBy = dependency
+	log.Info().
+		Str("url", config.RedactURL(target.URL)).
+		Str("dependency", config.RedactURL(dependency)).
+		Msg("URL check skipped, a dependency is down")
+	return result
+}
@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCycle_SkipsTargetsWithDownDependency(t *testing.T) {
+	var gatewayStatus atomic.Int32
+	gatewayStatus.Store(http.StatusBadGateway)
+	var apiRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gateway":
+			// Slower than the dependent checks, which must wait for it
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(int(gatewayStatus.Load()))
+		case "/api":
+			apiRequests.Add(1)
+		}
+	}))
+	defer server.Close()
+
+	c := New(&config.Config{
+		Timeout: 5 * time.Second,
+		Checks: []config.Target{
+			{URL: server.URL + "/api", DependsOn: []string{"gateway"}},
+			{URL: server.URL + "/gateway", Name: "gateway"},
+			{URL: server.URL + "/web", DependsOn: []string{server.URL + "/api"}},
+		},
+	})
+
+	results, err := c.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	byURL := make(map[string]Result, len(results))
+	for _, result := range results {
+		byURL[result.URL] = result
+	}
+	assert.False(t, byURL[server.URL+"/gateway"].IsSkipped())
+	assert.Equal(t, "gateway", byURL[server.URL+"/api"].SkippedBy)
+	assert.Equal(t, server.URL+"/api", byURL[server.URL+"/web"].SkippedBy, "skipped dependencies are down for their dependents")
+	assert.False(t, byURL[server.URL+"/api"].IsUp())
+	assert.Zero(t, apiRequests.Load())
+
+	gatewayStatus.Store(http.StatusOK)
+	results, err = c.RunCycle(context.Background())
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.False(t, result.IsSkipped(), result.URL)
+		assert.True(t, result.IsUp(), result.URL)
+	}
+	assert.Equal(t, int32(1), apiRequests.Load())
+
+	// On-demand checks are never skipped
+	gatewayStatus.Store(http.StatusBadGateway)
+	_, err = c.RunCycle(context.Background())
+	require.NoError(t, err)
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: server.URL + "/api", DependsOn: []string{"gateway"}})
+	require.NoError(t, err)
+	assert.False(t, result.IsSkipped())
+}
+
+func TestAddTarget_Dependencies(t *testing.T) {
+	c := New(&config.Config{Checks: []config.Target{{URL: "https://gateway.example.com", Name: "gateway"}}})
+
+	require.NoError(t, c.AddTarget(config.Target{URL: "https://api.example.com", Name: "api", DependsOn: []string{"gateway"}}))
+
+	err := c.AddTarget(config.Target{URL: "https://web.example.com", DependsOn: []string{"cache"}})
+	assert.ErrorContains(t, err, `depends on unknown target "cache"`)
+
+	err = c.AddTarget(config.Target{URL: "https://loop.example.com", Name: "loop", DependsOn: []string{"loop"}})
+	assert.ErrorContains(t, err, "dependency cycle")
+}
@@ -0,0 +1,107 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// graphQLQuery is the GraphQL query of a target, encoded as the request body, with the
+// regular expressions of its expectations compiled
+type graphQLQuery struct {
+	body   string
+	expect []graphQLExpectation
+}
+
+type graphQLExpectation struct {
+	path  string
+	value *regexp.Regexp
+}
+
+// newGraphQLQuery prepares the GraphQL query of the target, nil when it has none
+func newGraphQLQuery(target config.Target) (*graphQLQuery, error) {
+	if target.GraphQL == nil {
+		return nil, nil
+	}
+
+	request := struct {
+		Query         string          `json:"query"`
+		Variables     json.RawMessage `json:"variables,omitempty"`
+		OperationName string          `json:"operationName,omitempty"`
+	}{
+		Query:         target.GraphQL.Query,
+		OperationName: target.GraphQL.OperationName,
+	}
+	if target.GraphQL.Variables != "" {
+		request.Variables = json.RawMessage(target.GraphQL.Variables)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("invalid graphql query: %w", err)
+	}
+
+	query := &graphQLQuery{body: string(body)}
+	for _, expectation := range target.GraphQL.Expect {
+		re, err := regexp.Compile(expectation.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graphql expectation %s: %w", expectation.Path, err)
+		}
+		query.expect = append(query.expect, graphQLExpectation{path: expectation.Path, value: re})
+	}
+	return query, nil
+}
+
+// runGraphQL POSTs the query and inspects the response like Inspect does. A 2xx response
+// with GraphQL errors, or without an expected value, fails the check with an error.
+func (h *HTTPChecker) runGraphQL(ctx context.Context, target string, query *graphQLQuery, assertions *Assertions) (int, Inspection, error) {
+	headers := map[string]string{"Content-Type": "application/json", "Accept": "application/json"}
+	response, statusCode, err := h.send(ctx, http.MethodPost, target, headers, query.body)
+	if err != nil || response == nil {
+		return statusCode, Inspection{}, err
+	}
+
+	inspection := newInspection(response, assertions)
+	if statusCode < 200 || statusCode >= 300 {
+		return statusCode, inspection, nil
+	}
+	if err := query.evaluate(response.Body()); err != nil {
+		return statusCode, inspection, fmt.Errorf("graphql: %w", err)
+	}
+	return statusCode, inspection, nil
+}
+
+// evaluate checks that the response has no errors and every expected value
+func (q *graphQLQuery) evaluate(body []byte) error {
+	var response struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("response is not JSON: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, graphQLErr := range response.Errors {
+			messages = append(messages, graphQLErr.Message)
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+
+	for _, expectation := range q.expect {
+		value, err := jsonValue(body, expectation.path)
+		if err != nil {
+			return err
+		}
+		if !expectation.value.MatchString(value) {
+			return fmt.Errorf("%s is %q, expected %q", expectation.path, value, expectation.value)
+		}
+	}
+	return nil
+}
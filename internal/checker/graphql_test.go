@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTarget_GraphQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var request struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch request.Query {
+		case config.DefaultGraphQLQuery:
+			fmt.Fprint(w, `{"data":{"__typename":"Query"}}`)
+		case "{ broken }":
+			fmt.Fprint(w, `{"errors":[{"message":"Cannot query field \"broken\""},{"message":"second"}]}`)
+		default:
+			status := "OK"
+			if request.Variables["deep"] == true {
+				status = "DEGRADED"
+			}
+			fmt.Fprintf(w, `{"data":{"health":{"status":%q,"checks":[{"up":true}]}}}`, status)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5 * time.Second}
+	checker := New(cfg)
+	check := func(graphql *config.GraphQLConfig) Result {
+		result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL, GraphQL: graphql})
+		require.NoError(t, err)
+		return result
+	}
+
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Module: config.GraphQLModule})
+	require.NoError(t, err)
+	assert.True(t, result.IsUp(), "%v", result.Error)
+
+	result = check(&config.GraphQLConfig{Query: "{ broken }"})
+	assert.False(t, result.IsUp())
+	assert.EqualError(t, result.Error, `graphql: Cannot query field "broken"; second`)
+
+	expect := []config.GraphQLExpectation{
+		{Path: "data.health.status", Value: "^OK$"},
+		{Path: "data.health.checks.0.up", Value: "^true$"},
+	}
+	result = check(&config.GraphQLConfig{Query: "{ health { status } }", Expect: expect})
+	assert.True(t, result.IsUp(), "%v", result.Error)
+
+	result = check(&config.GraphQLConfig{Query: "{ health { status } }", Variables: `{"deep": true}`, Expect: expect})
+	assert.False(t, result.IsUp())
+	assert.EqualError(t, result.Error, `graphql: data.health.status is "DEGRADED", expected "^OK$"`)
+
+	result = check(&config.GraphQLConfig{Query: "{ health { status } }", Expect: []config.GraphQLExpectation{{Path: "data.version", Value: ".+"}}})
+	assert.EqualError(t, result.Error, "graphql: data.version not found")
+}
+
+func TestCheckTarget_GraphQLNotJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>maintenance</html>")
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Timeout: 5 * time.Second})
+
+	result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL, Module: config.GraphQLModule})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+	assert.ErrorContains(t, result.Error, "graphql: response is not JSON")
+}
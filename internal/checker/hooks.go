@@ -0,0 +1,48 @@
+package checker
+
+import (
+	"context"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Hook runs around every check, scheduled or on demand, e.g. to enrich, audit or alter
+// the checks of an embedding service
+type Hook interface {
+	// BeforeCheck runs before the target is checked and returns the context to check it
+	// with, e.g. carrying headers from WithRequestHeader. An error fails the check without
+	// running it or the BeforeCheck of later hooks.
+	BeforeCheck(ctx context.Context, target config.Target) (context.Context, error)
+	// AfterCheck runs once the check has completed, or failed in a later hook's BeforeCheck,
+	// and may change its result before the sinks and subscribers receive it. It does not run
+	// when the hook's own BeforeCheck failed.
+	AfterCheck(ctx context.Context, target config.Target, result *Result)
+}
+
+// AddHook registers a hook. BeforeCheck runs in the order the hooks were added, AfterCheck
+// in reverse order, so the first hook wraps all others.
+func (c *Checker) AddHook(hook Hook) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.hooks = append(c.hooks, hook)
+}
+
+// requestHeadersKey is the context key of the headers added to HTTP check requests
+type requestHeadersKey struct{}
+
+// WithRequestHeader returns a context whose HTTP checks send the header, e.g. a short-lived
+// token added by a hook's BeforeCheck. It replaces default headers of the same name.
+func WithRequestHeader(ctx context.Context, name, value string) context.Context {
+	previous := requestHeaders(ctx)
+	headers := make(map[string]string, len(previous)+1)
+	for key, existing := range previous {
+		headers[key] = existing
+	}
+	headers[name] = value
+	return context.WithValue(ctx, requestHeadersKey{}, headers)
+}
+
+func requestHeaders(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(requestHeadersKey{}).(map[string]string)
+	return headers
+}
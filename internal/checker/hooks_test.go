@@ -0,0 +1,101 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook records the order of its calls and optionally injects a header, fails the
+// check or marks the result
+type recordingHook struct {
+	name   string
+	calls  *[]string
+	header string
+	err    error
+	mutate func(result *Result)
+}
+
+func (h *recordingHook) BeforeCheck(ctx context.Context, target config.Target) (context.Context, error) {
+	*h.calls = append(*h.calls, "before "+h.name+" "+target.Name)
+	if h.err != nil {
+		return nil, h.err
+	}
+	if h.header != "" {
+		ctx = WithRequestHeader(ctx, "Authorization", h.header)
+	}
+	return ctx, nil
+}
+
+func (h *recordingHook) AfterCheck(ctx context.Context, target config.Target, result *Result) {
+	*h.calls = append(*h.calls, "after "+h.name)
+	if h.mutate != nil {
+		h.mutate(result)
+	}
+}
+
+func TestHooks_WrapEveryCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer short-lived" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Checks:  []config.Target{{URL: server.URL, Name: "api"}},
+		Timeout: 5 * time.Second,
+	}
+	c := New(cfg)
+
+	var calls []string
+	c.AddHook(&recordingHook{name: "auth", calls: &calls, header: "Bearer short-lived"})
+	c.AddHook(&recordingHook{name: "audit", calls: &calls, mutate: func(result *Result) {
+		result.CycleID = "audited"
+	}})
+
+	results, err := c.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, http.StatusOK, results[0].StatusCode, "the header from BeforeCheck is sent")
+	assert.Equal(t, "audited", results[0].CycleID, "AfterCheck changes the result")
+	assert.Equal(t, []string{"before auth api", "before audit api", "after audit", "after auth"}, calls)
+
+	calls = nil
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: server.URL, Name: "adhoc"})
+	require.NoError(t, err)
+	assert.True(t, result.IsUp())
+	assert.Equal(t, []string{"before auth adhoc", "before audit adhoc", "after audit", "after auth"}, calls)
+}
+
+func TestHooks_BeforeCheckFails(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	c := New(&config.Config{Timeout: 5 * time.Second})
+	var calls []string
+	c.AddHook(&recordingHook{name: "audit", calls: &calls, header: "Bearer audit"})
+	c.AddHook(&recordingHook{name: "token", calls: &calls, err: errors.New("token expired")})
+	c.AddHook(&recordingHook{name: "trace", calls: &calls})
+
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	require.Error(t, result.Error)
+	assert.Contains(t, result.Error.Error(), "token expired")
+	assert.False(t, result.IsUp())
+	assert.Equal(t, server.URL, result.URL)
+	assert.Equal(t, 0, requests, "the check does not run")
+	assert.Equal(t, []string{"before audit ", "before token ", "after audit"}, calls, "only the hooks that ran before run after")
+}
@@ -0,0 +1,54 @@
+package checker
+
+import (
+	"fmt"
+	"plugin"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	pluginsMutex sync.Mutex
+	// loadedPlugins holds the paths of the plugins already loaded, which cannot be unloaded
+	loadedPlugins = make(map[string]bool)
+	// openPlugin loads a Go plugin, running its init functions
+	openPlugin = func(path string) error {
+		_, err := plugin.Open(path)
+		return err
+	}
+)
+
+// LoadPlugins loads the Go plugins at the paths. A plugin registers its protocols from an
+// init function with RegisterProtocol (pkg/probe.RegisterProtocol outside this module) and
+// must be built with the same Go version and module versions as the exporter. Plugins
+// loaded before are skipped.
+func LoadPlugins(paths []string) error {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+
+	for _, path := range paths {
+		if loadedPlugins[path] {
+			continue
+		}
+
+		before := registeredProtocols()
+		if err := openPlugin(path); err != nil {
+			return fmt.Errorf("failed to load plugin %s: %w", path, err)
+		}
+		loadedPlugins[path] = true
+
+		added := registeredProtocols() - before
+		if added == 0 {
+			return fmt.Errorf("plugin %s registered no protocol", path)
+		}
+		log.Info().Str("plugin", path).Int("protocols", added).Msg("Plugin loaded")
+	}
+	return nil
+}
+
+func registeredProtocols() int {
+	protocolsMutex.RLock()
+	defer protocolsMutex.RUnlock()
+	return len(protocols)
+}
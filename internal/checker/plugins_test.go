@@ -0,0 +1,51 @@
+package checker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubPlugins(t *testing.T, open func(path string) error) {
+	t.Helper()
+	previous := openPlugin
+	openPlugin = open
+	t.Cleanup(func() { openPlugin = previous })
+}
+
+func TestLoadPlugins(t *testing.T) {
+	opened := 0
+	stubPlugins(t, func(path string) error {
+		opened++
+		RegisterProtocol("plugged", func(options ProtocolOptions) ProtocolChecker { return &dialChecker{options: options} })
+		return nil
+	})
+
+	require.NoError(t, LoadPlugins([]string{"/plugins/plugged.so"}))
+	require.NoError(t, LoadPlugins([]string{"/plugins/plugged.so"}), "a loaded plugin is skipped")
+	assert.Equal(t, 1, opened)
+}
+
+func TestLoadPlugins_Errors(t *testing.T) {
+	stubPlugins(t, func(path string) error {
+		if path == "/plugins/missing.so" {
+			return errors.New("no such file")
+		}
+		return nil
+	})
+
+	err := LoadPlugins([]string{"/plugins/missing.so"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load plugin /plugins/missing.so")
+
+	err = LoadPlugins([]string{"/plugins/empty.so"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "registered no protocol")
+}
+
+func TestLoadPlugins_NotAPlugin(t *testing.T) {
+	err := LoadPlugins([]string{t.TempDir() + "/missing.so"})
+	assert.Error(t, err)
+}
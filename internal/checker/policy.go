@@ -0,0 +1,200 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// ErrTargetNotAllowed is returned for runtime targets the target policy rejects
+var ErrTargetNotAllowed = errors.New("target not allowed")
+
+// defaultPorts are the ports checked for URLs without one
+var defaultPorts = map[string]int{
+	"http": 80, "https": 443,
+	"ftp": 21, "sftp": 22, "ssh": 22, "telnet": 23, "smtp": 25,
+	"mysql": 3306, "postgres": 5432, "postgresql": 5432, "redis": 6379, "mongodb": 27017,
+}
+
+// metadataAddresses are cloud metadata services outside the link-local ranges, denied
+// with them
+var metadataAddresses = []net.IP{
+	net.ParseIP("fd00:ec2::254"),   // AWS IPv6
+	net.ParseIP("100.100.100.200"), // Alibaba Cloud
+}
+
+// TargetPolicy decides which targets added at runtime may be checked and which addresses
+// their checks may connect to, so the API and /probe cannot be used to reach internal
+// services such as cloud metadata endpoints
+type TargetPolicy struct {
+	allowSchemes   map[string]bool
+	denySchemes    map[string]bool
+	allowPorts     map[int]bool
+	denyPorts      map[int]bool
+	allowNets      []*net.IPNet
+	denyNets       []*net.IPNet
+	allowLinkLocal bool
+}
+
+// NewTargetPolicy creates the policy of the configuration
+func NewTargetPolicy(cfg config.TargetPolicyConfig) (*TargetPolicy, error) {
+	policy := &TargetPolicy{
+		allowSchemes:   lowerSet(cfg.AllowSchemes),
+		denySchemes:    lowerSet(cfg.DenySchemes),
+		allowPorts:     make(map[int]bool, len(cfg.AllowPorts)),
+		denyPorts:      make(map[int]bool, len(cfg.DenyPorts)),
+		allowLinkLocal: cfg.AllowLinkLocal,
+	}
+	for _, port := range cfg.AllowPorts {
+		policy.allowPorts[port] = true
+	}
+	for _, port := range cfg.DenyPorts {
+		policy.denyPorts[port] = true
+	}
+
+	var err error
+	if policy.allowNets, err = parseCIDRs(cfg.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if policy.denyNets, err = parseCIDRs(cfg.DenyCIDRs); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(value)] = true
+	}
+	return set
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, network)
+	}
+	return nets, nil
+}
+
+// CheckURL checks the scheme and port of the target URL and, for hosts given as an IP
+// address, the address. Addresses that hostnames resolve to are checked when the check
+// connects, see CheckAddress.
+func (p *TargetPolicy) CheckURL(targetURL string) error {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Scheme == "" || u.Hostname() == "" {
+		return fmt.Errorf("%w: %s is not an absolute URL", ErrTargetNotAllowed, config.RedactURL(targetURL))
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if p.denySchemes[scheme] || (len(p.allowSchemes) > 0 && !p.allowSchemes[scheme]) {
+		return fmt.Errorf("%w: scheme %s", ErrTargetNotAllowed, scheme)
+	}
+
+	port, known := defaultPorts[scheme]
+	if u.Port() != "" {
+		port, err = strconv.Atoi(u.Port())
+		known = err == nil
+	}
+	if known {
+		if err := p.checkPort(port); err != nil {
+			return err
+		}
+	} else if len(p.allowPorts) > 0 {
+		return fmt.Errorf("%w: %s has no port", ErrTargetNotAllowed, config.RedactURL(targetURL))
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		return p.checkIP(ip)
+	}
+	return nil
+}
+
+// CheckAddress checks an address a check connects to, given as IP and port
+func (p *TargetPolicy) CheckAddress(address string) error {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrTargetNotAllowed, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s is not an IP address", ErrTargetNotAllowed, host)
+	}
+	if err := p.checkIP(ip); err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return fmt.Errorf("%w: port %s", ErrTargetNotAllowed, portText)
+	}
+	return p.checkPort(port)
+}
+
+func (p *TargetPolicy) checkPort(port int) error {
+	if p.denyPorts[port] || (len(p.allowPorts) > 0 && !p.allowPorts[port]) {
+		return fmt.Errorf("%w: port %d", ErrTargetNotAllowed, port)
+	}
+	return nil
+}
+
+func (p *TargetPolicy) checkIP(ip net.IP) error {
+	if !p.allowLinkLocal && isLinkLocal(ip) {
+		return fmt.Errorf("%w: %s is a link-local or metadata address", ErrTargetNotAllowed, ip)
+	}
+	for _, network := range p.denyNets {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: %s is in %s", ErrTargetNotAllowed, ip, network)
+		}
+	}
+	if len(p.allowNets) == 0 {
+		return nil
+	}
+	for _, network := range p.allowNets {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not in an allowed range", ErrTargetNotAllowed, ip)
+}
+
+func isLinkLocal(ip net.IP) bool {
+	if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return true
+	}
+	for _, metadata := range metadataAddresses {
+		if metadata.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetPolicyKey is the context key of the policy the connections of a check must pass
+type targetPolicyKey struct{}
+
+func withTargetPolicy(ctx context.Context, policy *TargetPolicy) context.Context {
+	return context.WithValue(ctx, targetPolicyKey{}, policy)
+}
+
+// guardConnection rejects connections the policy of the dialing check does not allow.
+// It is the Control function of the checks' dialers, so it sees the resolved address of
+// every connection, also after redirects.
+func guardConnection(ctx context.Context, network, address string, _ syscall.RawConn) error {
+	policy, _ := ctx.Value(targetPolicyKey{}).(*TargetPolicy)
+	if policy == nil {
+		return nil
+	}
+	return policy.CheckAddress(address)
+}
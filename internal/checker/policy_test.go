@@ -0,0 +1,147 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetPolicy_CheckURL(t *testing.T) {
+	policy, err := NewTargetPolicy(config.TargetPolicyConfig{
+		AllowSchemes: []string{"http", "HTTPS", "redis"},
+		DenyPorts:    []int{8080},
+		DenyCIDRs:    []string{"10.0.0.0/8"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/health", true},
+		{"redis://cache:6379", true},
+		{"http://192.168.1.10", true},
+		{"ftp://example.com", false},
+		{"http://example.com:8080", false},
+		{"http://10.1.2.3/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[fe80::1]/", false},
+		{"http://[fd00:ec2::254]/", false},
+		{"/relative", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := policy.CheckURL(tt.url)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrTargetNotAllowed)
+			}
+		})
+	}
+}
+
+func TestTargetPolicy_CheckAddress(t *testing.T) {
+	policy, err := NewTargetPolicy(config.TargetPolicyConfig{AllowCIDRs: []string{"192.0.2.0/24"}, AllowPorts: []int{443}})
+	require.NoError(t, err)
+
+	assert.NoError(t, policy.CheckAddress("192.0.2.10:443"))
+	assert.ErrorIs(t, policy.CheckAddress("192.0.2.10:80"), ErrTargetNotAllowed)
+	assert.ErrorIs(t, policy.CheckAddress("198.51.100.1:443"), ErrTargetNotAllowed)
+
+	open, err := NewTargetPolicy(config.TargetPolicyConfig{AllowLinkLocal: true})
+	require.NoError(t, err)
+	assert.NoError(t, open.CheckAddress("169.254.169.254:80"))
+	assert.NoError(t, open.CheckURL("http://169.254.169.254/latest/meta-data/"))
+}
+
+func TestAddRuntimeTarget_Policy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New(&config.Config{
+		Timeout:      5 * time.Second,
+		TargetPolicy: config.TargetPolicyConfig{DenyCIDRs: []string{"127.0.0.0/8"}, DenySchemes: []string{"redis"}},
+	})
+
+	err := c.AddRuntimeTarget(config.Target{URL: "redis://cache:6379"})
+	assert.ErrorIs(t, err, ErrTargetNotAllowed)
+	assert.Empty(t, c.Targets())
+
+	// Hostnames are checked once resolved, when the check connects
+	localhost := "http://localhost:" + strconv.Itoa(server.Listener.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, c.AddRuntimeTarget(config.Target{URL: localhost}))
+	results, err := c.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].IsUp())
+	assert.ErrorIs(t, results[0].Error, ErrTargetNotAllowed)
+
+	// Configured targets and checks are trusted
+	require.NoError(t, c.AddTarget(config.Target{URL: server.URL + "/trusted"}))
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.True(t, result.IsUp())
+
+	_, err = c.CheckRuntimeTarget(context.Background(), config.Target{URL: server.URL})
+	assert.ErrorIs(t, err, ErrTargetNotAllowed)
+}
+
+func TestCheckRuntimeTarget_PooledConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New(&config.Config{
+		Timeout:      5 * time.Second,
+		TargetPolicy: config.TargetPolicyConfig{DenyCIDRs: []string{"127.0.0.0/8"}},
+	})
+
+	// A configured target leaves a connection to the host in the pool
+	localhost := "http://localhost:" + strconv.Itoa(server.Listener.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, c.AddTarget(config.Target{URL: localhost + "/trusted"}))
+	results, err := c.RunCycle(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.True(t, results[0].IsUp())
+
+	result, err := c.CheckRuntimeTarget(context.Background(), config.Target{URL: localhost})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+	assert.ErrorIs(t, result.Error, ErrTargetNotAllowed, "the pooled connection is not reused")
+
+	require.NoError(t, c.AddRuntimeTarget(config.Target{URL: localhost + "/runtime"}))
+	results, err = c.RunCycle(context.Background())
+	require.NoError(t, err)
+	for _, result := range results {
+		if result.URL == localhost+"/runtime" {
+			assert.ErrorIs(t, result.Error, ErrTargetNotAllowed)
+		} else {
+			assert.True(t, result.IsUp())
+		}
+	}
+}
+
+func TestCheckRuntimeTarget_Redirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	c := New(&config.Config{Timeout: 5 * time.Second})
+	result, err := c.CheckRuntimeTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+	assert.ErrorIs(t, result.Error, ErrTargetNotAllowed)
+}
@@ -0,0 +1,93 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/dnscache"
+	"github.com/rs/zerolog/log"
+)
+
+// httpSchemes are the built-in protocols checked by the HTTP checker
+var httpSchemes = []string{"http", "https"}
+
+// telnetSchemes are the built-in protocols checked by connecting to the target's port
+var telnetSchemes = []string{
+	"ftp", "sftp", "ssh", "telnet", "tcp", "smtp",
+	"mysql", "postgres", "postgresql", "redis", "mongodb",
+}
+
+// ProtocolOptions are the settings a registered protocol's checker is created with
+type ProtocolOptions struct {
+	// Timeout is the configured timeout of a single check attempt
+	Timeout time.Duration
+	// Dial connects with the timeout from the target's source, resolving hosts through the
+	// DNS cache when enabled
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ProtocolFactory creates the checker of a registered protocol for each Checker
+type ProtocolFactory func(options ProtocolOptions) ProtocolChecker
+
+var (
+	protocolsMutex sync.RWMutex
+	protocols      = make(map[string]ProtocolFactory)
+)
+
+// RegisterProtocol makes targets with the URL scheme checkable by the checkers the factory
+// creates. A Check that returns a 2xx status counts as up. It is meant to be called from an
+// init function and panics if the scheme is empty, built in or already registered.
+func RegisterProtocol(scheme string, factory ProtocolFactory) {
+	scheme = strings.ToLower(scheme)
+	if scheme == "" {
+		panic("checker: RegisterProtocol with an empty scheme")
+	}
+	if factory == nil {
+		panic("checker: RegisterProtocol factory is nil for " + scheme)
+	}
+	if isBuiltinProtocol(scheme) {
+		panic("checker: RegisterProtocol called for built-in protocol " + scheme)
+	}
+
+	protocolsMutex.Lock()
+	defer protocolsMutex.Unlock()
+	if _, exists := protocols[scheme]; exists {
+		panic("checker: RegisterProtocol called twice for " + scheme)
+	}
+	protocols[scheme] = factory
+}
+
+func isBuiltinProtocol(scheme string) bool {
+	for _, builtin := range append(append([]string{}, httpSchemes...), telnetSchemes...) {
+		if scheme == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// registeredCheckers creates the checkers of the registered protocols
+func registeredCheckers(timeout time.Duration, resolver *dnscache.Resolver) map[string]ProtocolChecker {
+	dial := sourceDial(timeout)
+	if resolver != nil {
+		dial = resolver.Wrap(dial)
+	}
+	options := ProtocolOptions{Timeout: timeout, Dial: dial}
+
+	protocolsMutex.RLock()
+	defer protocolsMutex.RUnlock()
+
+	checkers := make(map[string]ProtocolChecker, len(protocols))
+	for scheme, factory := range protocols {
+		checker := factory(options)
+		if checker == nil {
+			log.Error().Str("protocol", scheme).Msg("Ignoring protocol whose factory returned no checker")
+			continue
+		}
+		checkers[scheme] = checker
+	}
+	return checkers
+}
@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialChecker is a registered protocol that is up when the target's host:port accepts a
+// connection
+type dialChecker struct {
+	options ProtocolOptions
+}
+
+func (d *dialChecker) Check(ctx context.Context, target string) (int, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := d.options.Dial(ctx, "tcp", u.Host)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return 200, nil
+}
+
+func (d *dialChecker) Protocol() string {
+	return "dial"
+}
+
+func TestRegisterProtocol(t *testing.T) {
+	var created ProtocolOptions
+	RegisterProtocol("Dial", func(options ProtocolOptions) ProtocolChecker {
+		created = options
+		return &dialChecker{options: options}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	c := New(&config.Config{Timeout: 2 * time.Second})
+	assert.Equal(t, 2*time.Second, created.Timeout)
+
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: "dial://" + listener.Addr().String()})
+	require.NoError(t, err)
+	assert.True(t, result.IsUp())
+	assert.Equal(t, "dial", result.Protocol)
+
+	listener.Close()
+	result, err = c.CheckTarget(context.Background(), config.Target{URL: "dial://" + listener.Addr().String()})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+}
+
+func TestRegisterProtocol_Invalid(t *testing.T) {
+	factory := func(options ProtocolOptions) ProtocolChecker { return &dialChecker{options: options} }
+
+	assert.Panics(t, func() { RegisterProtocol("", factory) }, "empty scheme")
+	assert.Panics(t, func() { RegisterProtocol("custom", nil) }, "nil factory")
+	assert.Panics(t, func() { RegisterProtocol("HTTPS", factory) }, "built-in protocol")
+	assert.Panics(t, func() { RegisterProtocol("redis", factory) }, "built-in protocol")
+
+	RegisterProtocol("twice", factory)
+	assert.Panics(t, func() { RegisterProtocol("twice", factory) }, "registered twice")
+}
+
+func TestRegisterProtocol_NilChecker(t *testing.T) {
+	RegisterProtocol("broken", func(ProtocolOptions) ProtocolChecker { return nil })
+
+	c := New(&config.Config{Timeout: time.Second})
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: "broken://example.com"})
+	require.NoError(t, err)
+	require.Error(t, result.Error)
+	assert.Contains(t, result.Error.Error(), "unsupported protocol")
+}
@@ -0,0 +1,228 @@
+package checker
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Bounds of the scheduler tick. Checks that come due within the same tick run as one
+// cycle, so the tick trades scheduling precision for fewer, larger cycles.
+const (
+	minSchedulerTick = 10 * time.Millisecond
+	maxSchedulerTick = time.Second
+)
+
+// scheduledTarget is a target's place in the schedule
+type scheduledTarget struct {
+	url      string
+	interval time.Duration
+	next     time.Time
+	// running is set while the target's check is in flight, so a check that takes longer
+	// than the interval is not started a second time
+	running bool
+	index   int
+}
+
+// schedule is a priority queue of the targets ordered by their next check
+type schedule []*scheduledTarget
+
+func (s schedule) Len() int           { return len(s) }
+func (s schedule) Less(i, j int) bool { return s[i].next.Before(s[j].next) }
+func (s schedule) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *schedule) Push(x any) {
+	entry := x.(*scheduledTarget)
+	entry.index = len(*s)
+	*s = append(*s, entry)
+}
+
+func (s *schedule) Pop() any {
+	old := *s
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*s = old[:len(old)-1]
+	entry.index = -1
+	return entry
+}
+
+// scheduler checks every target once per interval, its own or checkInterval. Targets are
+// spread evenly across their interval instead of all being checked at once, so CPU and
+// network usage stay flat however many targets there are.
+type scheduler struct {
+	checker *Checker
+	now     func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*scheduledTarget
+	queue   schedule
+	version uint64
+	tick    time.Duration
+	wg      sync.WaitGroup
+
+	// warmup, if set, is the window the first checks after startup are spread across
+	// instead of each target's interval
+	warmup time.Duration
+	synced bool
+}
+
+func newScheduler(c *Checker) *scheduler {
+	return &scheduler{
+		checker: c,
+		now:     time.Now,
+		entries: make(map[string]*scheduledTarget),
+		warmup:  c.config.Warmup,
+	}
+}
+
+// run starts the due checks every tick until ctx is done, then waits for the checks in
+// flight. The checks run with checkCtx, so they can finish after scheduling has stopped.
+func (s *scheduler) run(ctx, checkCtx context.Context) {
+	defer s.wg.Wait()
+
+	s.sync(s.now())
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		s.dispatch(checkCtx, s.now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if s.checker.targetsVersion() != s.version {
+			tick := s.tick
+			s.sync(s.now())
+			if s.tick != tick {
+				ticker.Reset(s.tick)
+			}
+		}
+	}
+}
+
+// sync brings the schedule in line with the registered targets. New targets are spread
+// evenly across their interval, or at startup across the warmup window, starting now;
+// targets whose interval changed are checked no later than one new interval from now.
+func (s *scheduler) sync(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.version = s.checker.targetsVersion()
+	targets := s.checker.Targets()
+
+	seen := make(map[string]bool, len(targets))
+	added := make(map[time.Duration][]*scheduledTarget)
+	var intervals []time.Duration
+	var all []*scheduledTarget
+	for _, target := range targets {
+		if target.Disabled {
+			continue
+		}
+		seen[target.URL] = true
+		interval := s.checker.config.TargetInterval(target)
+
+		entry, exists := s.entries[target.URL]
+		if !exists {
+			entry = &scheduledTarget{url: target.URL, interval: interval}
+			s.entries[target.URL] = entry
+			if len(added[interval]) == 0 {
+				intervals = append(intervals, interval)
+			}
+			added[interval] = append(added[interval], entry)
+			all = append(all, entry)
+			continue
+		}
+		if entry.interval != interval {
+			entry.interval = interval
+			if latest := now.Add(interval); entry.next.After(latest) {
+				entry.next = latest
+				heap.Fix(&s.queue, entry.index)
+			}
+		}
+	}
+
+	for url, entry := range s.entries {
+		if !seen[url] {
+			if entry.index >= 0 {
+				heap.Remove(&s.queue, entry.index)
+			}
+			delete(s.entries, url)
+		}
+	}
+
+	if !s.synced && s.warmup > 0 {
+		for i, entry := range all {
+			entry.next = now.Add(s.warmup * time.Duration(i) / time.Duration(len(all)))
+			heap.Push(&s.queue, entry)
+		}
+	} else {
+		for _, interval := range intervals {
+			group := added[interval]
+			for i, entry := range group {
+				entry.next = now.Add(interval * time.Duration(i) / time.Duration(len(group)))
+				heap.Push(&s.queue, entry)
+			}
+		}
+	}
+	s.synced = true
+
+	s.tick = maxSchedulerTick
+	for _, entry := range s.entries {
+		s.tick = min(s.tick, max(entry.interval/10, minSchedulerTick))
+	}
+}
+
+// dispatch starts the checks of the targets that are due as one cycle
+func (s *scheduler) dispatch(ctx context.Context, now time.Time) {
+	s.mutex.Lock()
+	var due []config.Target
+	for len(s.queue) > 0 && !s.queue[0].next.After(now) {
+		entry := s.queue[0]
+		entry.next = entry.next.Add(entry.interval)
+		if !entry.next.After(now) {
+			// Missed checks, e.g. after the process was suspended, are not caught up
+			entry.next = now.Add(entry.interval)
+		}
+		heap.Fix(&s.queue, 0)
+
+		if entry.running {
+			log.Warn().Str("url", config.RedactURL(entry.url)).Dur("interval", entry.interval).Msg("Skipping check still in flight from the previous interval")
+			continue
+		}
+		entry.running = true
+		due = append(due, config.Target{URL: entry.url})
+	}
+	s.mutex.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.checker.checkBatch(ctx, due)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, target := range due {
+			if entry, exists := s.entries[target.URL]; exists {
+				entry.running = false
+			}
+		}
+	}()
+}
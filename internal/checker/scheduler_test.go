@@ -0,0 +1,188 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_SpreadsTargetsAcrossInterval(t *testing.T) {
+	cfg := &config.Config{CheckInterval: 10 * time.Second, Timeout: time.Second}
+	for i := 0; i < 10; i++ {
+		cfg.Targets = append(cfg.Targets, fmt.Sprintf("https://service-%d.example.com", i))
+	}
+	cfg.Checks = []config.Target{
+		{URL: "https://slow.example.com", Interval: "1h"},
+		{URL: "https://disabled.example.com", Disabled: true},
+	}
+
+	s := newScheduler(New(cfg))
+	now := time.Now()
+	s.sync(now)
+
+	require.Len(t, s.entries, 11)
+	for i := 0; i < 10; i++ {
+		entry := s.entries[fmt.Sprintf("https://service-%d.example.com", i)]
+		assert.Equal(t, now.Add(time.Duration(i)*time.Second), entry.next)
+	}
+	assert.Equal(t, time.Hour, s.entries["https://slow.example.com"].interval)
+	assert.Equal(t, now, s.entries["https://slow.example.com"].next)
+	assert.Equal(t, time.Second, s.tick)
+}
+
+func TestScheduler_Warmup(t *testing.T) {
+	cfg := &config.Config{CheckInterval: time.Minute, Timeout: time.Second, Warmup: 10 * time.Second}
+	for i := 0; i < 5; i++ {
+		cfg.Targets = append(cfg.Targets, fmt.Sprintf("https://service-%d.example.com", i))
+	}
+	cfg.Checks = []config.Target{
+		{URL: "https://service-5.example.com", Interval: "1h"},
+	}
+	checker := New(cfg)
+
+	s := newScheduler(checker)
+	now := time.Now()
+	s.sync(now)
+
+	// All first checks fall within the warmup window whatever the target's interval
+	for i := 0; i < 6; i++ {
+		entry := s.entries[fmt.Sprintf("https://service-%d.example.com", i)]
+		assert.Equal(t, now.Add(time.Duration(i)*10*time.Second/6), entry.next)
+	}
+
+	// Targets added later are spread across their interval as usual
+	require.NoError(t, checker.AddTarget(config.Target{URL: "https://later.example.com"}))
+	s.sync(now.Add(time.Second))
+	assert.Equal(t, now.Add(time.Second), s.entries["https://later.example.com"].next)
+}
+
+func TestScheduler_Dispatch(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets:       []string{server.URL + "/a", server.URL + "/slow"},
+		CheckInterval: 10 * time.Second,
+		Timeout:       5 * time.Second,
+	}
+	checker := New(cfg)
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	s := newScheduler(checker)
+	now := time.Now()
+	s.sync(now)
+
+	// Only the first target is due at first, the second half an interval later
+	s.dispatch(context.Background(), now)
+	require.Eventually(t, func() bool { return len(sink.Results()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, server.URL+"/a", sink.Results()[0].URL)
+
+	s.dispatch(context.Background(), now.Add(5*time.Second))
+	require.Eventually(t, func() bool {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return s.entries[server.URL+"/slow"].running
+	}, time.Second, 10*time.Millisecond)
+
+	// A check still in flight is skipped rather than started twice
+	s.dispatch(context.Background(), now.Add(15*time.Second))
+	close(release)
+	s.wg.Wait()
+
+	urls := make(map[string]int)
+	for _, result := range sink.Results() {
+		urls[result.URL]++
+	}
+	assert.Equal(t, map[string]int{server.URL + "/a": 2, server.URL + "/slow": 1}, urls)
+	assert.False(t, s.entries[server.URL+"/slow"].running)
+	assert.Equal(t, now.Add(25*time.Second), s.entries[server.URL+"/slow"].next)
+}
+
+func TestScheduler_SyncsTargetChanges(t *testing.T) {
+	cfg := &config.Config{
+		Targets:       []string{"https://a.example.com", "https://b.example.com"},
+		CheckInterval: time.Hour,
+		Timeout:       time.Second,
+	}
+	checker := New(cfg)
+	s := newScheduler(checker)
+	now := time.Now()
+	s.sync(now)
+	version := s.version
+
+	require.True(t, checker.RemoveTarget("https://b.example.com"))
+	require.NoError(t, checker.AddTarget(config.Target{URL: "https://c.example.com", Interval: "1m"}))
+	require.True(t, checker.RemoveTarget("https://a.example.com"))
+	require.NoError(t, checker.AddTarget(config.Target{URL: "https://a.example.com", Interval: "1m"}))
+	assert.NotEqual(t, version, checker.targetsVersion())
+
+	later := now.Add(time.Second)
+	s.sync(later)
+
+	assert.Len(t, s.entries, 2)
+	assert.Len(t, s.queue, 2)
+	assert.Equal(t, later, s.entries["https://c.example.com"].next, "new targets are checked right away")
+	assert.Equal(t, now, s.entries["https://a.example.com"].next, "a due target stays due")
+	assert.Equal(t, time.Minute, s.entries["https://a.example.com"].interval)
+	assert.Equal(t, checker.targetsVersion(), s.version)
+}
+
+func TestStart_PerTargetIntervals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: server.URL + "/fast", Interval: "50ms"},
+			{URL: server.URL + "/slow"},
+		},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+	}
+	checker := New(cfg)
+	sink := &resultRecorder{}
+	checker.AddSink(sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Start(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		fast := 0
+		for _, result := range sink.Results() {
+			if result.URL == server.URL+"/fast" {
+				fast++
+			}
+		}
+		return fast >= 4
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	slow := 0
+	for _, result := range sink.Results() {
+		if result.URL == server.URL+"/slow" {
+			slow++
+		}
+	}
+	assert.LessOrEqual(t, slow, 1)
+}
@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// soapRequest is the SOAP request of a target, ready to send
+type soapRequest struct {
+	envelope string
+	headers  map[string]string
+}
+
+// newSOAPRequest prepares the SOAP request of the target, nil when it has none
+func newSOAPRequest(target config.Target) *soapRequest {
+	soap := target.SOAP
+	if soap == nil {
+		return nil
+	}
+
+	request := &soapRequest{envelope: soap.Envelope(), headers: make(map[string]string, 1)}
+	if soap.Version == config.SOAP12 {
+		contentType := "application/soap+xml; charset=utf-8"
+		if soap.Action != "" {
+			contentType += fmt.Sprintf("; action=%q", soap.Action)
+		}
+		request.headers["Content-Type"] = contentType
+	} else {
+		request.headers["Content-Type"] = "text/xml; charset=utf-8"
+		request.headers["SOAPAction"] = fmt.Sprintf("%q", soap.Action)
+	}
+	return request
+}
+
+// runSOAP POSTs the SOAP request and inspects the response like Inspect does. A Fault in
+// the response fails the check with an error, whatever the status; so does a 2xx response
+// that is not a SOAP envelope.
+func (h *HTTPChecker) runSOAP(ctx context.Context, target string, request *soapRequest, assertions *Assertions) (int, Inspection, error) {
+	response, statusCode, err := h.send(ctx, http.MethodPost, target, request.headers, request.envelope)
+	if err != nil || response == nil {
+		return statusCode, Inspection{}, err
+	}
+
+	inspection := newInspection(response, assertions)
+	// A non-2xx response that is no envelope is down by its status alone
+	err = soapFault(response.Body())
+	if errors.Is(err, errSOAPFault) || err != nil && statusCode >= 200 && statusCode < 300 {
+		return statusCode, inspection, fmt.Errorf("soap: %w", err)
+	}
+	return statusCode, inspection, nil
+}
+
+// errSOAPFault is the error of a response holding a Fault
+var errSOAPFault = errors.New("fault")
+
+// soapFault returns an error wrapping errSOAPFault with the fault string, or reason in
+// SOAP 1.2, when the envelope holds a Fault, and an error when the body is no envelope
+func soapFault(body []byte) error {
+	decoder := xml.NewDecoder(strings.NewReader(string(body)))
+	var (
+		envelope, fault bool
+		element         string
+		message         strings.Builder
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("response is not XML: %w", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			element = token.Name.Local
+			switch {
+			case !envelope && element != "Envelope":
+				return fmt.Errorf("response is a %s document, not a SOAP envelope", element)
+			case element == "Envelope":
+				envelope = true
+			case element == "Fault":
+				fault = true
+			}
+		case xml.EndElement:
+			element = ""
+		case xml.CharData:
+			// faultstring in SOAP 1.1, the Text of the Reason in SOAP 1.2
+			if fault && (element == "faultstring" || element == "Text") {
+				message.Write(token)
+			}
+		}
+	}
+
+	switch {
+	case !envelope:
+		return errors.New("response is not a SOAP envelope")
+	case fault && message.Len() > 0:
+		return fmt.Errorf("%w: %s", errSOAPFault, strings.TrimSpace(message.String()))
+	case fault:
+		return errSOAPFault
+	}
+	return nil
+}
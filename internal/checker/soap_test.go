@@ -0,0 +1,92 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSOAPFault(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"response", `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><m:Price>1</m:Price></soap:Body></soap:Envelope>`, ""},
+		{"fault 1.1", `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault><faultcode>soap:Server</faultcode><faultstring>Database unavailable</faultstring></soap:Fault></soap:Body></soap:Envelope>`, "fault: Database unavailable"},
+		{"fault 1.2", `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault><env:Code><env:Value>env:Receiver</env:Value></env:Code><env:Reason><env:Text xml:lang="en">Timeout</env:Text></env:Reason></env:Fault></env:Body></env:Envelope>`, "fault: Timeout"},
+		{"fault without string", `<Envelope><Body><Fault/></Body></Envelope>`, "fault"},
+		{"html", `<html><body>Maintenance</body></html>`, "response is a html document, not a SOAP envelope"},
+		{"not xml", `{"status":"ok"}`, "response is not a SOAP envelope"},
+		{"malformed", `<Envelope><Body>`, "response is not XML: XML syntax error on line 1: unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := soapFault([]byte(tt.body))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckTarget_SOAP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case r.URL.Path == "/v12":
+			assert.Equal(t, `application/soap+xml; charset=utf-8; action="urn:GetPrice"`, r.Header.Get("Content-Type"))
+			assert.Contains(t, string(body), `xmlns:soap="http://www.w3.org/2003/05/soap-envelope"`)
+			fmt.Fprint(w, `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><Price>1</Price></soap:Body></soap:Envelope>`)
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, "<html>unavailable</html>")
+		case strings.Contains(string(body), "Unknown"):
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault><faultcode>soap:Client</faultcode><faultstring>Unknown symbol</faultstring></soap:Fault></soap:Body></soap:Envelope>`)
+		default:
+			assert.Equal(t, `"urn:GetPrice"`, r.Header.Get("SOAPAction"))
+			assert.Contains(t, string(body), `<soap:Body><GetPrice><Symbol>ACME</Symbol></GetPrice></soap:Body>`)
+			fmt.Fprint(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><Price>1</Price></soap:Body></soap:Envelope>`)
+		}
+	}))
+	defer server.Close()
+
+	checker := New(&config.Config{Timeout: 5 * time.Second})
+	check := func(path string, soap *config.SOAPConfig) Result {
+		result, err := checker.CheckTarget(context.Background(), config.Target{URL: server.URL + path, SOAP: soap})
+		require.NoError(t, err)
+		return result
+	}
+
+	result := check("/", &config.SOAPConfig{Action: "urn:GetPrice", Body: "<GetPrice><Symbol>ACME</Symbol></GetPrice>"})
+	assert.True(t, result.IsUp(), "%v", result.Error)
+
+	result = check("/v12", &config.SOAPConfig{Version: config.SOAP12, Action: "urn:GetPrice", Body: "<GetPrice/>"})
+	assert.True(t, result.IsUp(), "%v", result.Error)
+
+	result = check("/", &config.SOAPConfig{Body: "<GetPrice><Symbol>Unknown</Symbol></GetPrice>"})
+	assert.False(t, result.IsUp())
+	assert.EqualError(t, result.Error, "soap: fault: Unknown symbol")
+
+	// Without a fault the status tells the check is down
+	result = check("/down", &config.SOAPConfig{Body: "<GetPrice/>"})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+}
@@ -0,0 +1,51 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/dnscache"
+)
+
+// sourceKey is the context key of the source a check's connections originate from
+type sourceKey struct{}
+
+func withSource(ctx context.Context, source config.SourceConfig) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func sourceFrom(ctx context.Context) config.SourceConfig {
+	source, _ := ctx.Value(sourceKey{}).(config.SourceConfig)
+	return source
+}
+
+// newDialer returns a dialer whose connections originate from the source and pass the
+// target policy of restricted checks
+func newDialer(timeout time.Duration, source config.SourceConfig) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout}
+	if ip := net.ParseIP(source.Address); ip != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	device := source.Interface
+	dialer.ControlContext = func(ctx context.Context, network, address string, conn syscall.RawConn) error {
+		if err := guardConnection(ctx, network, address, conn); err != nil {
+			return err
+		}
+		if device == "" {
+			return nil
+		}
+		return bindToDevice(conn, device)
+	}
+	return dialer
+}
+
+// sourceDial dials from the source of the check in the context, for checkers that are
+// shared by targets with different sources
+func sourceDial(timeout time.Duration) dnscache.DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return newDialer(timeout, sourceFrom(ctx)).DialContext(ctx, network, address)
+	}
+}
@@ -0,0 +1,20 @@
+package checker
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// bindToDevice sends the connection's packets through the network interface or VRF device
+func bindToDevice(conn syscall.RawConn, device string) error {
+	var bindErr error
+	if err := conn.Control(func(fd uintptr) {
+		bindErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, device)
+	}); err != nil {
+		return err
+	}
+	if bindErr != nil {
+		return fmt.Errorf("failed to bind to interface %s: %w", device, bindErr)
+	}
+	return nil
+}
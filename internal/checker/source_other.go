@@ -0,0 +1,13 @@
+//go:build !linux
+
+package checker
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// bindToDevice is not supported: only Linux binds sockets to a device
+func bindToDevice(_ syscall.RawConn, device string) error {
+	return fmt.Errorf("binding to interface %s is only supported on Linux", device)
+}
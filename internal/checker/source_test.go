@@ -0,0 +1,81 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck_SourceAddress(t *testing.T) {
+	remotes := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		remotes <- host
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout: 5 * time.Second,
+		Source:  config.SourceConfig{Address: "127.0.0.2"},
+		Checks: []config.Target{
+			{URL: server.URL + "/global"},
+			{URL: server.URL + "/own", Source: config.SourceConfig{Address: "127.0.0.3"}},
+		},
+	}
+	c := New(cfg)
+
+	result, err := c.CheckTarget(context.Background(), cfg.Checks[0])
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	assert.Equal(t, "127.0.0.2", <-remotes)
+
+	result, err = c.CheckTarget(context.Background(), cfg.Checks[1])
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	assert.Equal(t, "127.0.0.3", <-remotes)
+}
+
+func TestTelnetChecker_SourceAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	remotes := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		remotes <- host
+		conn.Close()
+	}()
+
+	target := config.Target{URL: "tcp://" + listener.Addr().String(), Source: config.SourceConfig{Address: "127.0.0.4"}}
+	c := New(&config.Config{Timeout: 5 * time.Second})
+	result, err := c.CheckTarget(context.Background(), target)
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	assert.Equal(t, "127.0.0.4", <-remotes)
+}
+
+func TestCheck_SourceInterfaceUnknown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New(&config.Config{Timeout: 5 * time.Second})
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: server.URL, Source: config.SourceConfig{Interface: "nonexistent0"}})
+	require.NoError(t, err)
+	assert.False(t, result.IsUp())
+	assert.Error(t, result.Error)
+}
@@ -0,0 +1,113 @@
+package checker
+
+import (
+	"sort"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// subscriberBuffer is the number of results a subscriber may fall behind before results are dropped for it
+const subscriberBuffer = 64
+
+// maxLoggedDrops caps the target URLs listed in the log of a cycle's dropped results
+const maxLoggedDrops = 20
+
+// Subscribe returns a channel that receives every scheduled check result as soon as the
+// check completes, in interval and scrape mode alike. Results are dropped for subscribers
+// that do not keep up, see DroppedResults; use AddSink where every result counts. The
+// returned function ends the subscription; the channel is also closed when the checker
+// shuts down.
+func (c *Checker) Subscribe() (<-chan Result, func()) {
+	ch := make(chan Result, subscriberBuffer)
+
+	c.subMutex.Lock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan Result]struct{})
+	}
+	if c.closed {
+		close(ch)
+	} else {
+		c.subscribers[ch] = struct{}{}
+	}
+	c.subMutex.Unlock()
+
+	unsubscribe := func() {
+		c.subMutex.Lock()
+		defer c.subMutex.Unlock()
+
+		if _, exists := c.subscribers[ch]; exists {
+			delete(c.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// publish fans the result out to all subscribers without blocking the check
+func (c *Checker) publish(result Result) {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	for ch := range c.subscribers {
+		select {
+		case ch <- result:
+		default:
+			c.dropped.Add(1)
+			if c.droppedTargets == nil {
+				c.droppedTargets = make(map[string]int)
+			}
+			c.droppedTargets[result.URL]++
+		}
+	}
+}
+
+// DroppedResults returns the number of results dropped for subscribers that did not keep
+// up since the checker was created
+func (c *Checker) DroppedResults() uint64 {
+	return c.dropped.Load()
+}
+
+// logDrops logs the redacted URLs of the targets whose results were dropped for subscribers
+// since the last call, once per cycle rather than once per result
+func (c *Checker) logDrops(cycleID string) {
+	c.subMutex.Lock()
+	dropped := c.droppedTargets
+	c.droppedTargets = nil
+	c.subMutex.Unlock()
+
+	if len(dropped) == 0 {
+		return
+	}
+
+	total := 0
+	targets := make([]string, 0, len(dropped))
+	for url, count := range dropped {
+		total += count
+		targets = append(targets, config.RedactURL(url))
+	}
+	sort.Strings(targets)
+	if len(targets) > maxLoggedDrops {
+		targets = targets[:maxLoggedDrops]
+	}
+
+	log.Warn().
+		Str("cycle_id", cycleID).
+		Int("dropped", total).
+		Int("affected_targets", len(dropped)).
+		Strs("targets", targets).
+		Msg("Dropped check results for result stream subscribers that did not keep up")
+}
+
+// closeSubscribers ends all subscriptions and refuses new ones
+func (c *Checker) closeSubscribers() {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	for ch := range c.subscribers {
+		delete(c.subscribers, ch)
+		close(ch)
+	}
+	c.closed = true
+}
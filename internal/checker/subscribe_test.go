@@ -0,0 +1,155 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe_ReceivesCycleResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets: []string{server.URL + "/a", server.URL + "/b"},
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	checker := New(cfg)
+	results, unsubscribe := checker.Subscribe()
+	defer unsubscribe()
+
+	_, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+
+	received := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		select {
+		case result := <-results:
+			received[result.URL] = result.StatusCode
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for published result")
+		}
+	}
+	assert.Equal(t, map[string]int{server.URL + "/a": 200, server.URL + "/b": 200}, received)
+
+	// On-demand checks are not published
+	_, err = checker.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestSubscribe_Unsubscribe(t *testing.T) {
+	checker := New(&config.Config{Timeout: time.Second})
+
+	results, unsubscribe := checker.Subscribe()
+	unsubscribe()
+	unsubscribe()
+
+	_, open := <-results
+	assert.False(t, open)
+
+	checker.publish(Result{URL: "https://example.com"})
+}
+
+func TestSubscribe_ClosedOnShutdown(t *testing.T) {
+	checker := New(&config.Config{Timeout: time.Second})
+
+	results, unsubscribe := checker.Subscribe()
+	defer unsubscribe()
+
+	require.NoError(t, checker.Shutdown(context.Background()))
+
+	_, open := <-results
+	assert.False(t, open)
+
+	// Subscriptions after shutdown are closed immediately
+	late, _ := checker.Subscribe()
+	_, open = <-late
+	assert.False(t, open)
+}
+
+func TestSubscribe_DropsForSlowSubscribers(t *testing.T) {
+	checker := New(&config.Config{Timeout: time.Second})
+
+	results, unsubscribe := checker.Subscribe()
+	defer unsubscribe()
+
+	// A second subscriber that keeps up loses nothing
+	other, unsubscribeOther := checker.Subscribe()
+	defer unsubscribeOther()
+	received := 0
+	for i := 0; i < subscriberBuffer+10; i++ {
+		checker.publish(Result{URL: "https://example.com"})
+		<-other
+		received++
+	}
+	assert.Len(t, results, subscriberBuffer)
+	assert.Equal(t, subscriberBuffer+10, received)
+	assert.Equal(t, uint64(10), checker.DroppedResults())
+}
+
+func TestSubscribe_LogsDroppedTargets(t *testing.T) {
+	var output bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&output)
+	defer func() { log.Logger = previousLogger }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	withCredentials := strings.Replace(server.URL, "http://", "http://u:secret@", 1) + "/b"
+	checker := New(&config.Config{Targets: []string{server.URL + "/a?token=abc", withCredentials}, Timeout: 5 * time.Second})
+	_, unsubscribe := checker.Subscribe()
+	defer unsubscribe()
+
+	// Fill the subscriber's buffer so the cycle's results are dropped
+	for i := 0; i < subscriberBuffer; i++ {
+		checker.publish(Result{URL: "https://example.com"})
+	}
+	require.Zero(t, checker.DroppedResults())
+
+	_, err := checker.RunCycle(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), checker.DroppedResults())
+
+	var entry struct {
+		Message  string   `json:"message"`
+		CycleID  string   `json:"cycle_id"`
+		Dropped  int      `json:"dropped"`
+		Affected int      `json:"affected_targets"`
+		Targets  []string `json:"targets"`
+	}
+	for _, line := range bytes.Split(output.Bytes(), []byte("\n")) {
+		if bytes.Contains(line, []byte("Dropped check results")) {
+			require.NoError(t, json.Unmarshal(line, &entry))
+		}
+	}
+	assert.NotEmpty(t, entry.CycleID)
+	assert.Equal(t, 2, entry.Dropped)
+	assert.Equal(t, 2, entry.Affected)
+	assert.Equal(t, []string{server.URL + "/a?token=xxxxx", config.RedactURL(withCredentials)}, entry.Targets)
+	assert.NotContains(t, output.String(), "secret")
+	assert.NotContains(t, output.String(), "token=abc")
+
+	// Drops are logged once
+	output.Reset()
+	checker.logDrops("")
+	assert.Empty(t, output.String())
+}
@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// tlsPolicy decides whether a TLS connection counts as legacy TLS: below the minimum
+// version or, up to TLS 1.2, with a cipher suite that is not allowed
+type tlsPolicy struct {
+	minVersion uint16
+	suites     []uint16
+}
+
+func newTLSPolicy(options config.TLSConfig) tlsPolicy {
+	minVersion, suites, err := options.Policy()
+	if err != nil {
+		log.Error().Err(err).Msg("Ignoring invalid TLS policy")
+		minVersion, suites, _ = config.TLSConfig{}.Policy()
+	}
+	return tlsPolicy{minVersion: minVersion, suites: suites}
+}
+
+func (p tlsPolicy) legacy(version, suite uint16) bool {
+	if version < p.minVersion {
+		return true
+	}
+	return version < tls.VersionTLS13 && len(p.suites) > 0 && !slices.Contains(p.suites, suite)
+}
+
+// tlsVersionName returns the TLS version as in the configuration, e.g. "1.2"
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLegacyTLSServer starts an HTTPS server that only speaks TLS 1.0 and 1.1
+func newLegacyTLSServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheck_TLSPolicy(t *testing.T) {
+	legacy := newLegacyTLSServer(t)
+	modern := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer modern.Close()
+
+	t.Run("enforced", func(t *testing.T) {
+		c := New(&config.Config{Timeout: 5 * time.Second, TLS: config.TLSConfig{InsecureSkipVerify: true}})
+
+		result, err := c.CheckTarget(context.Background(), config.Target{URL: legacy.URL})
+		require.NoError(t, err)
+		assert.Error(t, result.Error)
+		assert.Empty(t, result.TLSVersion)
+
+		result, err = c.CheckTarget(context.Background(), config.Target{URL: modern.URL})
+		require.NoError(t, err)
+		require.True(t, result.IsUp(), "%v", result.Error)
+		assert.Equal(t, "1.3", result.TLSVersion)
+		assert.Equal(t, "TLS_AES_128_GCM_SHA256", result.TLSCipherSuite)
+		assert.False(t, result.TLSLegacy)
+		assert.Equal(t, modern.Certificate().NotAfter, result.TLSCertExpiry)
+	})
+
+	t.Run("report only", func(t *testing.T) {
+		c := New(&config.Config{Timeout: 5 * time.Second, TLS: config.TLSConfig{InsecureSkipVerify: true, ReportOnly: true}})
+
+		result, err := c.CheckTarget(context.Background(), config.Target{URL: legacy.URL})
+		require.NoError(t, err)
+		require.True(t, result.IsUp(), "%v", result.Error)
+		assert.Equal(t, "1.1", result.TLSVersion)
+		assert.NotEmpty(t, result.TLSCipherSuite)
+		assert.True(t, result.TLSLegacy)
+
+		result, err = c.CheckTarget(context.Background(), config.Target{URL: modern.URL})
+		require.NoError(t, err)
+		require.True(t, result.IsUp(), "%v", result.Error)
+		assert.False(t, result.TLSLegacy)
+	})
+
+	t.Run("module overrides global", func(t *testing.T) {
+		c := New(&config.Config{
+			Timeout: 5 * time.Second,
+			TLS:     config.TLSConfig{InsecureSkipVerify: true},
+			Modules: map[string]config.Module{
+				"legacy": {TLS: config.TLSConfig{MinVersion: "1.0"}},
+			},
+		})
+
+		result, err := c.CheckTarget(context.Background(), config.Target{URL: legacy.URL, Module: "legacy"})
+		require.NoError(t, err)
+		require.True(t, result.IsUp(), "%v", result.Error)
+		assert.Equal(t, "1.1", result.TLSVersion)
+		assert.False(t, result.TLSLegacy)
+	})
+}
+
+func TestCheck_TLSCipherSuites(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	suites := []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}
+
+	c := New(&config.Config{Timeout: 5 * time.Second, TLS: config.TLSConfig{InsecureSkipVerify: true, CipherSuites: suites}})
+	result, err := c.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	assert.Error(t, result.Error)
+
+	c = New(&config.Config{Timeout: 5 * time.Second, TLS: config.TLSConfig{InsecureSkipVerify: true, CipherSuites: suites, ReportOnly: true}})
+	result, err = c.CheckTarget(context.Background(), config.Target{URL: server.URL})
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	assert.Equal(t, "1.2", result.TLSVersion)
+	assert.Equal(t, "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", result.TLSCipherSuite)
+	assert.True(t, result.TLSLegacy)
+}
+
+func TestTLSPolicy_Legacy(t *testing.T) {
+	policy := newTLSPolicy(config.TLSConfig{})
+	assert.True(t, policy.legacy(tls.VersionTLS11, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA))
+	assert.False(t, policy.legacy(tls.VersionTLS12, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA))
+
+	policy = newTLSPolicy(config.TLSConfig{MinVersion: "1.3"})
+	assert.True(t, policy.legacy(tls.VersionTLS12, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256))
+	assert.False(t, policy.legacy(tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256))
+
+	// Cipher suites restrict TLS 1.2 and below only
+	policy = newTLSPolicy(config.TLSConfig{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}})
+	assert.False(t, policy.legacy(tls.VersionTLS12, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256))
+	assert.True(t, policy.legacy(tls.VersionTLS12, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA))
+	assert.False(t, policy.legacy(tls.VersionTLS13, tls.TLS_AES_256_GCM_SHA384))
+}
+
+func TestTLSVersionName(t *testing.T) {
+	assert.Equal(t, "1.0", tlsVersionName(tls.VersionTLS10))
+	assert.Equal(t, "1.3", tlsVersionName(tls.VersionTLS13))
+	assert.Equal(t, "0x0300", tlsVersionName(tls.VersionSSL30))
+}
@@ -0,0 +1,58 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the checker as the instrumentation scope of the check spans
+const tracerName = "github.com/jasoet/url-exporter/internal/checker"
+
+// startCheckSpan starts the span of a check. Requests made with the returned context
+// carry the span as their parent, and HTTP checks propagate it in a traceparent header.
+// Until tracing is configured the global provider is a no-op, so checks are not traced.
+func startCheckSpan(ctx context.Context, result Result) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		semconv.URLFull(config.RedactURL(result.URL)),
+		attribute.String("check.protocol", result.Protocol),
+	}
+	if result.CycleID != "" {
+		attributes = append(attributes, attribute.String("check.cycle_id", result.CycleID))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, "check "+result.Protocol,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// endCheckSpan records the outcome of the check on its span and ends it. Failed checks
+// and non-2xx responses mark the span as an error.
+func endCheckSpan(span trace.Span, result Result) {
+	defer span.End()
+
+	span.SetAttributes(attribute.Bool("check.up", result.IsUp()))
+
+	if result.Error != nil {
+		span.RecordError(result.Error)
+		span.SetStatus(codes.Error, result.Error.Error())
+		return
+	}
+
+	if result.Protocol == "http" || result.Protocol == "https" {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(result.StatusCode))
+	}
+	switch {
+	case result.Failure != "":
+		span.SetStatus(codes.Error, result.Failure)
+	case !result.IsUp():
+		span.SetStatus(codes.Error, fmt.Sprintf("unexpected status code %d", result.StatusCode))
+	}
+}
@@ -0,0 +1,199 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// StepResult is the outcome of a step of a transaction check. A step passes with a 2xx
+// status, a matching body and every variable extracted.
+type StepResult struct {
+	Name         string
+	StatusCode   int
+	ResponseTime time.Duration
+	Error        error
+}
+
+// Passed reports whether the step completed without error and returned a 2xx status
+func (s StepResult) Passed() bool {
+	return s.Error == nil && s.StatusCode >= 200 && s.StatusCode < 300
+}
+
+// transactionStep is a step of a transaction check with its regular expressions compiled
+type transactionStep struct {
+	config.Step
+	expectBody *regexp.Regexp
+	extract    map[string]stepExtract
+}
+
+type stepExtract struct {
+	config.StepExtract
+	regex *regexp.Regexp
+}
+
+// newTransaction compiles the steps of the target, nil when it has none
+func newTransaction(target config.Target) ([]transactionStep, error) {
+	if len(target.Steps) == 0 {
+		return nil, nil
+	}
+
+	steps := make([]transactionStep, 0, len(target.Steps))
+	for _, step := range target.Steps {
+		compiled := transactionStep{Step: step, extract: make(map[string]stepExtract, len(step.Extract))}
+		if step.ExpectBody != "" {
+			re, err := regexp.Compile(step.ExpectBody)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expectBody for step %s: %w", step.Name, err)
+			}
+			compiled.expectBody = re
+		}
+		for name, extract := range step.Extract {
+			compiledExtract := stepExtract{StepExtract: extract}
+			if extract.Regex != "" {
+				re, err := regexp.Compile(extract.Regex)
+				if err != nil {
+					return nil, fmt.Errorf("invalid extract %s for step %s: %w", name, step.Name, err)
+				}
+				compiledExtract.regex = re
+			}
+			compiled.extract[name] = compiledExtract
+		}
+		steps = append(steps, compiled)
+	}
+	return steps, nil
+}
+
+// runTransaction runs the steps in order, each with the variables extracted by the steps
+// before it, and stops at the first step that does not pass. It returns the status of the
+// last step run; a step failing its body assertion or an extraction fails the check with
+// an error, like a network error does.
+func (h *HTTPChecker) runTransaction(ctx context.Context, target string, steps []transactionStep) (int, Inspection, error) {
+	base, err := url.Parse(target)
+	if err != nil {
+		return 0, Inspection{}, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	var inspection Inspection
+	variables := make(map[string]string)
+	for _, step := range steps {
+		stepURL, err := base.Parse(config.ExpandVariables(step.URL, variables))
+		if err != nil {
+			return 0, inspection, fmt.Errorf("step %s: invalid URL: %w", step.Name, err)
+		}
+		method := step.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		headers := make(map[string]string, len(step.Headers))
+		for name, value := range step.Headers {
+			headers[name] = config.ExpandVariables(value, variables)
+		}
+
+		start := time.Now()
+		response, statusCode, err := h.send(ctx, method, stepURL.String(), headers, config.ExpandVariables(step.Body, variables))
+		stepResult := StepResult{Name: step.Name, StatusCode: statusCode, ResponseTime: time.Since(start)}
+		if response != nil && response.RawResponse != nil {
+			inspection.HTTPVersion = httpVersion(response.RawResponse.ProtoMajor, response.RawResponse.ProtoMinor)
+			inspection.TLS = response.RawResponse.TLS
+			inspection.Cache = parseCacheStatus(response.Header())
+			inspection.ClockSkew = clockSkew(response.Header(), response.ReceivedAt())
+		}
+		if err == nil && stepResult.Passed() {
+			err = step.evaluate(response.Header(), response.Body(), variables)
+		}
+		stepResult.Error = err
+		inspection.Steps = append(inspection.Steps, stepResult)
+
+		if err != nil {
+			return 0, inspection, fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if !stepResult.Passed() {
+			// The status reports the failed step, as for a single request
+			return statusCode, inspection, nil
+		}
+	}
+	return inspection.Steps[len(inspection.Steps)-1].StatusCode, inspection, nil
+}
+
+// evaluate checks the body assertion of the step and adds the extracted values to the
+// variables
+func (s transactionStep) evaluate(header http.Header, body []byte, variables map[string]string) error {
+	if s.expectBody != nil && !s.expectBody.Match(body) {
+		return errors.New("body does not match expectBody")
+	}
+	for name, extract := range s.extract {
+		value, err := extract.value(header, body)
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", name, err)
+		}
+		variables[name] = value
+	}
+	return nil
+}
+
+func (e stepExtract) value(header http.Header, body []byte) (string, error) {
+	if e.JSON != "" {
+		return jsonValue(body, e.JSON)
+	}
+
+	text := string(body)
+	if e.Header != "" {
+		text = header.Get(e.Header)
+	}
+	match := e.regex.FindStringSubmatch(text)
+	switch {
+	case match == nil:
+		return "", errors.New("regex does not match")
+	case len(match) > 1:
+		return match[1], nil
+	default:
+		return match[0], nil
+	}
+}
+
+// jsonValue returns the field at the dotted path of the JSON document. Strings are
+// returned as they are, other values as JSON.
+func jsonValue(body []byte, path string) (string, error) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("body is not JSON: %w", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			field, exists := node[key]
+			if !exists {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			value = field
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			value = node[index]
+		default:
+			return "", fmt.Errorf("%s not found", path)
+		}
+	}
+
+	if text, isString := value.(string); isString {
+		return text, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,142 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTransactionServer serves a login returning a session header and a token, and an API
+// that requires both
+func newTransactionServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"user":"probe"}` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Session", "session=abc123; Path=/")
+		_, _ = w.Write([]byte(`{"data":{"token":"t0k3n","roles":["reader"]}}`))
+	})
+	mux.HandleFunc("GET /api/items", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0k3n" || r.Header.Get("X-Session-ID") != "abc123" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[{"id":42}]}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckTarget_Transaction(t *testing.T) {
+	server := newTransactionServer(t)
+	c := New(&config.Config{Timeout: 5 * time.Second})
+
+	target := config.Target{
+		URL: server.URL,
+		Steps: []config.Step{
+			{
+				Name:   "login",
+				URL:    "/login",
+				Method: http.MethodPost,
+				Body:   `{"user":"probe"}`,
+				Extract: map[string]config.StepExtract{
+					"token":   {JSON: "data.token"},
+					"session": {Header: "X-Session", Regex: `session=(\w+)`},
+				},
+			},
+			{
+				Name:       "items",
+				URL:        "/api/items",
+				Headers:    map[string]string{"Authorization": "Bearer ${token}", "X-Session-ID": "${session}"},
+				ExpectBody: `"id":42`,
+			},
+		},
+	}
+
+	result, err := c.CheckTarget(context.Background(), target)
+	require.NoError(t, err)
+	require.True(t, result.IsUp(), "%v", result.Error)
+	require.Len(t, result.Steps, 2)
+	assert.Equal(t, "login", result.Steps[0].Name)
+	assert.Equal(t, "items", result.Steps[1].Name)
+	for _, step := range result.Steps {
+		assert.True(t, step.Passed(), "%s: %v", step.Name, step.Error)
+		assert.Positive(t, step.ResponseTime)
+	}
+
+	// A failing status stops the transaction and reports the step's status
+	target.Steps[0].Body = `{"user":"other"}`
+	result, err = c.CheckTarget(context.Background(), target)
+	require.NoError(t, err)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+	assert.False(t, result.IsUp())
+	require.Len(t, result.Steps, 1)
+	assert.False(t, result.Steps[0].Passed())
+
+	// A failed assertion fails the check with an error naming the step
+	target.Steps[0].Body = `{"user":"probe"}`
+	target.Steps[1].ExpectBody = `"id":7`
+	result, err = c.CheckTarget(context.Background(), target)
+	require.NoError(t, err)
+	assert.EqualError(t, result.Error, "step items: body does not match expectBody")
+	require.Len(t, result.Steps, 2)
+	assert.True(t, result.Steps[0].Passed())
+	assert.False(t, result.Steps[1].Passed())
+}
+
+func TestCheckTarget_TransactionInvalidSteps(t *testing.T) {
+	c := New(&config.Config{Timeout: 5 * time.Second})
+
+	_, err := c.CheckTarget(context.Background(), config.Target{
+		URL:   "https://example.com",
+		Steps: []config.Step{{Name: "fetch", Headers: map[string]string{"Authorization": "Bearer ${token}"}}},
+	})
+	assert.ErrorContains(t, err, "${token}")
+}
+
+func TestStepExtract_Value(t *testing.T) {
+	body := []byte(`{"data":{"token":"abc","expires":3600,"scopes":["read","write"]}}`)
+	header := http.Header{"Location": []string{"/orders/17"}}
+
+	tests := []struct {
+		name    string
+		extract config.StepExtract
+		want    string
+		wantErr string
+	}{
+		{name: "json string", extract: config.StepExtract{JSON: "data.token"}, want: "abc"},
+		{name: "json number", extract: config.StepExtract{JSON: "data.expires"}, want: "3600"},
+		{name: "json array index", extract: config.StepExtract{JSON: "data.scopes.1"}, want: "write"},
+		{name: "json object", extract: config.StepExtract{JSON: "data.scopes"}, want: `["read","write"]`},
+		{name: "json missing", extract: config.StepExtract{JSON: "data.user"}, wantErr: "data.user not found"},
+		{name: "body regex group", extract: config.StepExtract{Regex: `"token":"(\w+)"`}, want: "abc"},
+		{name: "header regex match", extract: config.StepExtract{Header: "Location", Regex: `\d+`}, want: "17"},
+		{name: "regex mismatch", extract: config.StepExtract{Header: "Location", Regex: `users`}, wantErr: "regex does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := newTransaction(config.Target{Steps: []config.Step{{Name: "step", Extract: map[string]config.StepExtract{"value": tt.extract}}}})
+			require.NoError(t, err)
+
+			value, err := steps[0].extract["value"].value(header, body)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
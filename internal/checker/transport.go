@@ -0,0 +1,60 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jasoet/pkg/rest"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// freshConnectionKey is the context key that makes an HTTP check open its own connection
+type freshConnectionKey struct{}
+
+func withFreshConnection(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshConnectionKey{}, true)
+}
+
+func freshConnection(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshConnectionKey{}).(bool)
+	return fresh
+}
+
+// poolTransport sends requests over the pooled transport, or over a copy without
+// keep-alives for checks that must not reuse a connection
+type poolTransport struct {
+	pooled *http.Transport
+	fresh  *http.Transport
+}
+
+func (p *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if freshConnection(req.Context()) {
+		return p.fresh.RoundTrip(req)
+	}
+	return p.pooled.RoundTrip(req)
+}
+
+// configureConnections applies the connection pool settings to the client's transport.
+// It must be called after any other change to the transport, such as its TLS options.
+func configureConnections(client *rest.Client, cfg config.ConnectionsConfig) {
+	transport, err := client.GetRestClient().Transport()
+	if err != nil {
+		log.Warn().Err(err).Msg("Connection pool settings not applied to HTTP checks")
+		return
+	}
+
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	fresh := transport.Clone()
+	fresh.DisableKeepAlives = true
+	client.GetRestClient().SetTransport(&poolTransport{pooled: transport, fresh: fresh})
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingServer counts the connections opened to it
+func countingServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var connections atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &connections
+}
+
+func TestConnections_KeepAlive(t *testing.T) {
+	keepAlive := true
+	noKeepAlive := false
+
+	tests := []struct {
+		name        string
+		connections config.ConnectionsConfig
+		keepAlive   *bool
+		expected    int64
+	}{
+		{name: "reused by default", expected: 1},
+		{name: "fresh per check", keepAlive: &noKeepAlive, expected: 3},
+		{name: "fresh by default", connections: config.ConnectionsConfig{DisableKeepAlives: true}, expected: 3},
+		{name: "reused despite the default", connections: config.ConnectionsConfig{DisableKeepAlives: true}, keepAlive: &keepAlive, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, connections := countingServer(t)
+
+			checker := New(&config.Config{
+				Checks:      []config.Target{{URL: server.URL, KeepAlive: tt.keepAlive}},
+				Timeout:     5 * time.Second,
+				Connections: tt.connections,
+			})
+
+			for i := 0; i < 3; i++ {
+				results, err := checker.RunCycle(context.Background())
+				require.NoError(t, err)
+				require.Len(t, results, 1)
+				assert.True(t, results[0].IsUp())
+			}
+			assert.Equal(t, tt.expected, connections.Load())
+		})
+	}
+}
+
+func TestConfigureConnections(t *testing.T) {
+	checker := New(&config.Config{
+		Timeout: 5 * time.Second,
+		Connections: config.ConnectionsConfig{
+			MaxConnsPerHost:     4,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		},
+	})
+
+	transport, ok := checker.restClient.GetRestClient().GetClient().Transport.(*poolTransport)
+	require.True(t, ok)
+	assert.Equal(t, 4, transport.pooled.MaxConnsPerHost)
+	assert.Equal(t, 2, transport.pooled.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, transport.pooled.IdleConnTimeout)
+	assert.False(t, transport.pooled.DisableKeepAlives)
+	assert.True(t, transport.fresh.DisableKeepAlives)
+	assert.Equal(t, 4, transport.fresh.MaxConnsPerHost)
+}
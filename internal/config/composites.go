@@ -0,0 +1,85 @@
+package config
+
+import "fmt"
+
+// Composite modes, how many members of a composite must be up for it to be up
+const (
+	CompositeAll    = "all"
+	CompositeAny    = "any"
+	CompositeQuorum = "quorum"
+)
+
+// Composite rolls the checks of several targets, e.g. all regional replicas of a service,
+// up into a single availability
+type Composite struct {
+	Name string `yaml:"name" json:"name"`
+	// Mode is all (the default), any or quorum
+	Mode string `yaml:"mode" json:"mode,omitempty"`
+	// Quorum is the number of members that must be up in quorum mode
+	Quorum int `yaml:"quorum" json:"quorum,omitempty"`
+	// Members names the member targets, by name or URL
+	Members []string `yaml:"members" json:"members"`
+}
+
+// Up reports whether the composite is up when up of its checked members are
+func (c Composite) Up(up, checked int) bool {
+	switch c.Mode {
+	case CompositeAny:
+		return up > 0
+	case CompositeQuorum:
+		return up >= c.Quorum
+	default:
+		return checked > 0 && up == checked
+	}
+}
+
+// validate checks that the composite has a name, a valid mode and members among the targets
+func (c Composite) validate(targets []Target) error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(c.Members) == 0 {
+		return fmt.Errorf("composite %s has no members", c.Name)
+	}
+
+	switch c.Mode {
+	case "", CompositeAll, CompositeAny:
+		if c.Quorum != 0 {
+			return fmt.Errorf("quorum of composite %s requires mode %q", c.Name, CompositeQuorum)
+		}
+	case CompositeQuorum:
+		if c.Quorum < 1 || c.Quorum > len(c.Members) {
+			return fmt.Errorf("invalid quorum %d for composite %s: must be between 1 and its %d members", c.Quorum, c.Name, len(c.Members))
+		}
+	default:
+		return fmt.Errorf("invalid mode %q for composite %s: must be %q, %q or %q", c.Mode, c.Name, CompositeAll, CompositeAny, CompositeQuorum)
+	}
+
+	seen := make(map[string]bool, len(c.Members))
+	for _, member := range c.Members {
+		target, exists := FindTarget(targets, member)
+		if !exists {
+			return fmt.Errorf("composite %s has unknown member %q", c.Name, member)
+		}
+		if seen[target.URL] {
+			return fmt.Errorf("composite %s lists member %q twice", c.Name, member)
+		}
+		seen[target.URL] = true
+	}
+	return nil
+}
+
+// validateComposites checks the composites, whose names must be unique
+func validateComposites(composites []Composite, targets []Target) error {
+	names := make(map[string]bool, len(composites))
+	for i, composite := range composites {
+		if err := composite.validate(targets); err != nil {
+			return fmt.Errorf("invalid composite %d: %w", i, err)
+		}
+		if names[composite.Name] {
+			return fmt.Errorf("invalid composite %d: duplicate name %q", i, composite.Name)
+		}
+		names[composite.Name] = true
+	}
+	return nil
+}
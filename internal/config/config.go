@@ -1,33 +1,1563 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	_ "embed"
+	"errors"
 	"fmt"
+	"math"
+	"mime"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/jasoet/pkg/config"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Probe modes control when checks are executed
+const (
+	// ProbeModeInterval checks all targets on an internal ticker every CheckInterval
+	ProbeModeInterval = "interval"
+	// ProbeModeScrape checks all targets on demand whenever /metrics is scraped
+	ProbeModeScrape = "scrape"
+)
+
+// Log formats control how log lines are written to stderr
+const (
+	// LogFormatConsole writes human-readable, colored lines
+	LogFormatConsole = "console"
+	// LogFormatJSON writes one JSON object per line for log collectors
+	LogFormatJSON = "json"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Targets       []string      `yaml:"targets"`
-	CheckInterval time.Duration `yaml:"checkInterval"`
-	Timeout       time.Duration `yaml:"timeout"`
-	ListenPort    int           `yaml:"listenPort"`
-	InstanceID    string        `yaml:"instanceId"`
-	Retries       int           `yaml:"retries"`
-	LogLevel      string        `yaml:"logLevel"`
+	Targets       []string            `yaml:"targets"`
+	Checks        []Target            `yaml:"checks"`
+	CheckInterval time.Duration       `yaml:"checkInterval"`
+	Timeout       time.Duration       `yaml:"timeout"`
+	ListenPort    int                 `yaml:"listenPort"`
+	ListenAddress string              `yaml:"listenAddress"`
+	InstanceID    string              `yaml:"instanceId"`
+	Retries       int                 `yaml:"retries"`
+	LogLevel      string              `yaml:"logLevel"`
+	LogFormat     string              `yaml:"logFormat"`
+	DebugTargets  []string            `yaml:"debugTargets"`
+	ShardIndex    int                 `yaml:"shardIndex"`
+	ShardTotal    int                 `yaml:"shardTotal"`
+	ProbeMode     string              `yaml:"probeMode"`
+	ScrapeTimeout time.Duration       `yaml:"scrapeTimeout"`
+	DrainTimeout  time.Duration       `yaml:"drainTimeout"`
+	Warmup        time.Duration       `yaml:"warmup"`
+	DNSCache      DNSCacheConfig      `yaml:"dnsCache"`
+	Connections   ConnectionsConfig   `yaml:"connections"`
+	Source        SourceConfig        `yaml:"source"`
+	Groups        GroupsConfig        `yaml:"groups"`
+	Graphite      GraphiteConfig      `yaml:"graphite"`
+	InfluxDB      InfluxDBConfig      `yaml:"influxdb"`
+	Kafka         KafkaConfig         `yaml:"kafka"`
+	NATS          NATSConfig          `yaml:"nats"`
+	SLO           SLOConfig           `yaml:"slo"`
+	Metrics       MetricsConfig       `yaml:"metrics"`
+	AlertRules    AlertRulesConfig    `yaml:"alertRules"`
+	API           APIConfig           `yaml:"api"`
+	Modules       map[string]Module   `yaml:"modules"`
+	Auth          AuthConfig          `yaml:"auth"`
+	ServerTLS     ServerTLSConfig     `yaml:"serverTls"`
+	TLS           TLSConfig           `yaml:"tls"`
+	Admin         AdminConfig         `yaml:"admin"`
+	Debug         DebugConfig         `yaml:"debug"`
+	AccessLog     AccessLogConfig     `yaml:"accessLog"`
+	AuditLog      AuditLogConfig      `yaml:"auditLog"`
+	Syslog        SyslogConfig        `yaml:"syslog"`
+	History       HistoryConfig       `yaml:"history"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Heartbeat     HeartbeatConfig     `yaml:"heartbeat"`
+	StatusPage    StatusPageConfig    `yaml:"statusPage"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	Events        EventsConfig        `yaml:"events"`
+	Leader        LeaderConfig        `yaml:"leaderElection" mapstructure:"leaderElection"`
+	Controller    ControllerConfig    `yaml:"controller"`
+	Discovery     DiscoveryConfig     `yaml:"discovery"`
+	Metadata      MetadataConfig      `yaml:"instanceMetadata" mapstructure:"instanceMetadata"`
+	Plugins       []string            `yaml:"plugins"`
+	TargetPolicy  TargetPolicyConfig  `yaml:"targetPolicy"`
+	Composites    []Composite         `yaml:"composites"`
+	// Tenants partition the targets between teams, see TenantConfig
+	Tenants map[string]TenantConfig `yaml:"tenants"`
+}
+
+// Target describes a monitored URL together with its optional per-target settings
+type Target struct {
+	URL           string            `yaml:"url" json:"url"`
+	Name          string            `yaml:"name" json:"name,omitempty"`
+	Group         string            `yaml:"group" json:"group,omitempty"`
+	Labels        map[string]string `yaml:"labels" json:"labels,omitempty"`
+	Module        string            `yaml:"module" json:"module,omitempty"`
+	Method        string            `yaml:"method" json:"method,omitempty"`
+	ExpectBody    string            `yaml:"expectBody" json:"expectBody,omitempty"`
+	ExpectHeaders map[string]string `yaml:"expectHeaders" json:"expectHeaders,omitempty"`
+	Objective     float64           `yaml:"objective" json:"objective,omitempty"`
+	Disabled      bool              `yaml:"disabled" json:"disabled,omitempty"`
+	NoDNSCache    bool              `yaml:"noDnsCache" json:"noDnsCache,omitempty"`
+	KeepAlive     *bool             `yaml:"keepAlive" json:"keepAlive,omitempty"`
+	Interval      string            `yaml:"interval" json:"interval,omitempty"`
+	Source        SourceConfig      `yaml:"source" json:"source,omitzero"`
+	// SecurityHeaders audits the response for the headers of SecurityHeaderNames
+	SecurityHeaders bool `yaml:"securityHeaders" json:"securityHeaders,omitempty"`
+	// ExpectContentType is the media type the response must have, e.g. application/json,
+	// ignoring parameters such as charset. It is reported with the header assertions.
+	ExpectContentType string `yaml:"expectContentType" json:"expectContentType,omitempty"`
+	// MinBodyBytes and MaxBodyBytes bound the size of the response body, 0 for no bound.
+	// The outcome is exported as url_size_within_bounds; FailOnBodySize also fails the
+	// check when the size is out of bounds.
+	MinBodyBytes   int64 `yaml:"minBodyBytes" json:"minBodyBytes,omitempty"`
+	MaxBodyBytes   int64 `yaml:"maxBodyBytes" json:"maxBodyBytes,omitempty"`
+	FailOnBodySize bool  `yaml:"failOnBodySize" json:"failOnBodySize,omitempty"`
+	// GraphQL makes the check POST a GraphQL query, see GraphQLConfig
+	GraphQL *GraphQLConfig `yaml:"graphql" json:"graphql,omitempty"`
+	// SOAP makes the check POST a SOAP request, see SOAPConfig
+	SOAP *SOAPConfig `yaml:"soap" json:"soap,omitempty"`
+	// Steps make the check a transaction of several requests, see Step
+	Steps []Step `yaml:"steps" json:"steps,omitempty"`
+	// DependsOn names the targets, by name or URL, the target depends on. Its scheduled
+	// checks are skipped while one of them is not up.
+	DependsOn []string `yaml:"dependsOn" json:"dependsOn,omitempty"`
+	// Description and RunbookURL give on-call responders context: they are shown in the
+	// API, UI and notifications and exported by url_target_info
+	Description string `yaml:"description" json:"description,omitempty"`
+	RunbookURL  string `yaml:"runbookUrl" json:"runbookUrl,omitempty"`
+	// Alerts overrides the thresholds of the generated alerting rules for the target
+	Alerts AlertThresholds `yaml:"alerts" json:"alerts,omitzero"`
+	// Tenant is the tenant owning the target, see TenantConfig
+	Tenant string `yaml:"tenant" json:"tenant,omitempty"`
+}
+
+// DefaultModule is the implicit probe module: the standard check for the target's protocol,
+// up when an HTTP target answers with a 2xx status. It can be redefined under modules.
+const DefaultModule = "http_2xx"
+
+// SecurityHeadersModule is the built-in module auditing the security headers of a
+// response, see Module.SecurityHeaders. It can be redefined under modules.
+const SecurityHeadersModule = "http_security_headers"
+
+// SecurityHeaderNames are the response headers the security header audit looks for
+var SecurityHeaderNames = []string{
+	"Strict-Transport-Security",
+	"Content-Security-Policy",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+}
+
+// Module is a named, reusable probe configuration that targets can select. Its method and
+// assertions apply to targets that do not set their own. SecurityHeaders reports which
+// security headers the responses carry, without affecting whether the target is up.
+// GraphQL and SOAP turn the checks into GraphQL queries and SOAP requests.
+type Module struct {
+	Method            string            `yaml:"method"`
+	ExpectBody        string            `yaml:"expectBody"`
+	ExpectHeaders     map[string]string `yaml:"expectHeaders"`
+	ExpectContentType string            `yaml:"expectContentType"`
+	SecurityHeaders   bool              `yaml:"securityHeaders"`
+	GraphQL           *GraphQLConfig    `yaml:"graphql"`
+	SOAP              *SOAPConfig       `yaml:"soap"`
+	TLS               TLSConfig         `yaml:"tls"`
+}
+
+// TLSConfig holds the TLS options used when checking HTTPS targets. MinVersion and
+// CipherSuites form the TLS policy: connections below the minimum version or with another
+// cipher suite fail, unless ReportOnly is set, which lets them through and only reports
+// them as legacy TLS. The cipher suites apply to TLS 1.2 and below.
+type TLSConfig struct {
+	InsecureSkipVerify bool     `yaml:"insecureSkipVerify"`
+	ServerName         string   `yaml:"serverName"`
+	CAFile             string   `yaml:"caFile"`
+	MinVersion         string   `yaml:"minVersion"`
+	CipherSuites       []string `yaml:"cipherSuites"`
+	ReportOnly         bool     `yaml:"reportOnly"`
+}
+
+// DefaultTLSMinVersion is the TLS version below which connections count as legacy TLS
+// while no minVersion is set, Go's default minimum for clients
+const DefaultTLSMinVersion = "1.2"
+
+// tlsVersions maps the minVersion values to TLS versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// IsZero reports whether no TLS option is set
+func (t TLSConfig) IsZero() bool {
+	return !t.InsecureSkipVerify && t.ServerName == "" && t.CAFile == "" &&
+		t.MinVersion == "" && len(t.CipherSuites) == 0 && !t.ReportOnly
+}
+
+// Merge returns the options of t with those it does not set taken from base, e.g. a
+// module's options over the global ones
+func (t TLSConfig) Merge(base TLSConfig) TLSConfig {
+	t.InsecureSkipVerify = t.InsecureSkipVerify || base.InsecureSkipVerify
+	t.ReportOnly = t.ReportOnly || base.ReportOnly
+	if t.ServerName == "" {
+		t.ServerName = base.ServerName
+	}
+	if t.CAFile == "" {
+		t.CAFile = base.CAFile
+	}
+	if t.MinVersion == "" {
+		t.MinVersion = base.MinVersion
+	}
+	if len(t.CipherSuites) == 0 {
+		t.CipherSuites = base.CipherSuites
+	}
+	return t
+}
+
+// Policy returns the minimum TLS version and the allowed cipher suites, none meaning any
+func (t TLSConfig) Policy() (uint16, []uint16, error) {
+	minVersion := t.MinVersion
+	if minVersion == "" {
+		minVersion = DefaultTLSMinVersion
+	}
+	version, known := tlsVersions[minVersion]
+	if !known {
+		return 0, nil, fmt.Errorf("invalid TLS minVersion %q: must be 1.0, 1.1, 1.2 or 1.3", t.MinVersion)
+	}
+
+	suites := make([]uint16, 0, len(t.CipherSuites))
+	for _, name := range t.CipherSuites {
+		id, known := cipherSuite(name)
+		if !known {
+			return 0, nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return version, suites, nil
+}
+
+// cipherSuite looks up a cipher suite by its standard name, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+func cipherSuite(name string) (uint16, bool) {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// ClientConfig builds the tls.Config for outbound checks, loading the CA file if one is set
+func (t TLSConfig) ClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		ServerName:         t.ServerName,
+	}
+
+	minVersion, suites, err := t.Policy()
+	if err != nil {
+		return nil, err
+	}
+	if t.ReportOnly {
+		// Legacy servers must be reachable to be reported
+		tlsConfig.MinVersion = tls.VersionTLS10
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, suite.ID)
+		}
+	} else {
+		tlsConfig.MinVersion = minVersion
+		if len(suites) > 0 {
+			tlsConfig.CipherSuites = suites
+		}
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", t.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// TLSFor returns the TLS options of the target: those of its module over the global ones
+func (c *Config) TLSFor(target Target) TLSConfig {
+	module, _ := c.Module(target.Module)
+	return module.TLS.Merge(c.TLS)
+}
+
+// Module returns the named module. Lookups are case-insensitive because configuration keys
+// are lower-cased when loaded. The default module always exists.
+func (c *Config) Module(name string) (Module, bool) {
+	if module, exists := c.Modules[strings.ToLower(name)]; exists {
+		return module, true
+	}
+	if strings.EqualFold(name, SecurityHeadersModule) {
+		return Module{SecurityHeaders: true}, true
+	}
+	if strings.EqualFold(name, GraphQLModule) {
+		return Module{GraphQL: &GraphQLConfig{Query: DefaultGraphQLQuery}}, true
+	}
+	return Module{}, strings.EqualFold(name, DefaultModule)
+}
+
+// ResolveModule returns the target with the method and assertions of its module filled in
+// where the target does not set its own
+func (c *Config) ResolveModule(t Target) (Target, error) {
+	if t.Module == "" {
+		return t, nil
+	}
+
+	module, exists := c.Module(t.Module)
+	if !exists {
+		return t, fmt.Errorf("unknown module %q for %s", t.Module, RedactURL(t.URL))
+	}
+
+	if t.Method == "" {
+		t.Method = module.Method
+	}
+	if t.ExpectBody == "" {
+		t.ExpectBody = module.ExpectBody
+	}
+	if len(t.ExpectHeaders) == 0 {
+		t.ExpectHeaders = module.ExpectHeaders
+	}
+	if t.ExpectContentType == "" {
+		t.ExpectContentType = module.ExpectContentType
+	}
+	t.SecurityHeaders = t.SecurityHeaders || module.SecurityHeaders
+	if t.GraphQL == nil && t.SOAP == nil {
+		t.GraphQL = module.GraphQL
+		t.SOAP = module.SOAP
+	}
+
+	return t, nil
+}
+
+// HasAssertions reports whether any response assertion is configured for the target
+func (t Target) HasAssertions() bool {
+	return t.ExpectBody != "" || len(t.ExpectHeaders) > 0 || t.ExpectContentType != "" || t.HasBodySizeBounds()
+}
+
+// HasBodySizeBounds reports whether the size of the target's response body is bounded
+func (t Target) HasBodySizeBounds() bool {
+	return t.MinBodyBytes > 0 || t.MaxBodyBytes > 0
+}
+
+// AllTargets returns the plain targets followed by the structured checks. A check
+// whose URL is also listed in targets replaces the plain entry.
+func (c *Config) AllTargets() []Target {
+	targets := make([]Target, 0, len(c.Targets)+len(c.Checks))
+	index := make(map[string]int, len(c.Targets)+len(c.Checks))
+
+	add := func(target Target) {
+		if i, exists := index[target.URL]; exists {
+			targets[i] = target
+			return
+		}
+		index[target.URL] = len(targets)
+		targets = append(targets, target)
+	}
+
+	for _, url := range c.Targets {
+		add(Target{URL: url})
+	}
+	for _, check := range c.Checks {
+		add(check)
+	}
+
+	return targets
+}
+
+// DebugLogging reports whether debugTargets lists the target's URL, name or group, so
+// its checks are logged at debug level regardless of logLevel
+func (c *Config) DebugLogging(target Target) bool {
+	for _, entry := range c.DebugTargets {
+		if entry == target.URL || (target.Name != "" && entry == target.Name) || (target.Group != "" && entry == target.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Alerting rules served by /api/v1/prometheus/rules
+const (
+	AlertURLDown                = "URLDown"
+	AlertURLSlow                = "URLSlow"
+	AlertURLCertificateExpiring = "URLCertificateExpiring"
+	AlertURLFlapping            = "URLFlapping"
+)
+
+// Default alert thresholds used unless configured otherwise
+const (
+	DefaultAlertFor        = 2 * time.Minute
+	DefaultAlertSlow       = 2 * time.Second
+	DefaultAlertCertExpiry = 14 * 24 * time.Hour
+	DefaultAlertFlapping   = 4
+)
+
+// AlertRulesConfig holds the default thresholds of the alerting rules served by
+// /api/v1/prometheus/rules. Disabled lists the alerts left out.
+type AlertRulesConfig struct {
+	For        time.Duration `yaml:"for"`        // how long a target must be down, or slow, to alert
+	Slow       time.Duration `yaml:"slow"`       // response time above which a target is slow
+	CertExpiry time.Duration `yaml:"certExpiry"` // alert this long before the certificate expires
+	Flapping   int           `yaml:"flapping"`   // state changes within an hour that count as flapping
+	Disabled   []string      `yaml:"disabled"`
+}
+
+// AlertThresholds are a target's overrides of the AlertRulesConfig defaults. Durations are
+// strings like Interval; a slow or certExpiry of "0s" leaves that rule out for the target,
+// Disabled all of them.
+type AlertThresholds struct {
+	For        string `yaml:"for" json:"for,omitempty"`
+	Slow       string `yaml:"slow" json:"slow,omitempty"`
+	CertExpiry string `yaml:"certExpiry" json:"certExpiry,omitempty"`
+	Flapping   int    `yaml:"flapping" json:"flapping,omitempty"`
+	Disabled   bool   `yaml:"disabled" json:"disabled,omitempty"`
+}
+
+// validate checks the overrides, with prefix naming the target in errors
+func (a AlertThresholds) validate(prefix string) error {
+	for _, threshold := range [][2]string{{"for", a.For}, {"slow", a.Slow}, {"certExpiry", a.CertExpiry}} {
+		if threshold[1] == "" {
+			continue
+		}
+		if duration, err := time.ParseDuration(threshold[1]); err != nil || duration < 0 {
+			return fmt.Errorf("invalid alerts.%s %q for %s: must be a duration, e.g. 5m", threshold[0], threshold[1], prefix)
+		}
+	}
+	if a.Flapping < 0 {
+		return fmt.Errorf("invalid alerts.flapping for %s: must not be negative", prefix)
+	}
+	return nil
+}
+
+// prepare validates the thresholds and fills in their defaults
+func (a *AlertRulesConfig) prepare() error {
+	if a.For < 0 || a.Slow < 0 || a.CertExpiry < 0 || a.Flapping < 0 {
+		return fmt.Errorf("alertRules: thresholds must not be negative")
+	}
+	for _, alert := range a.Disabled {
+		switch alert {
+		case AlertURLDown, AlertURLSlow, AlertURLCertificateExpiring, AlertURLFlapping:
+		default:
+			return fmt.Errorf("alertRules: unknown alert %q in disabled: must be %s, %s, %s or %s", alert, AlertURLDown, AlertURLSlow, AlertURLCertificateExpiring, AlertURLFlapping)
+		}
+	}
+
+	if a.For == 0 {
+		a.For = DefaultAlertFor
+	}
+	if a.Slow == 0 {
+		a.Slow = DefaultAlertSlow
+	}
+	if a.CertExpiry == 0 {
+		a.CertExpiry = DefaultAlertCertExpiry
+	}
+	if a.Flapping == 0 {
+		a.Flapping = DefaultAlertFlapping
+	}
+	return nil
+}
+
+// AlertEnabled reports whether the alert is not in Disabled
+func (a AlertRulesConfig) AlertEnabled(alert string) bool {
+	return !slices.Contains(a.Disabled, alert)
+}
+
+// TargetAlertRules returns the alert thresholds of the target: its overrides applied to
+// the defaults
+func (c *Config) TargetAlertRules(target Target) AlertRulesConfig {
+	rules := c.AlertRules
+	for _, override := range []struct {
+		value    string
+		duration *time.Duration
+	}{
+		{target.Alerts.For, &rules.For},
+		{target.Alerts.Slow, &rules.Slow},
+		{target.Alerts.CertExpiry, &rules.CertExpiry},
+	} {
+		if duration, err := time.ParseDuration(override.value); err == nil {
+			*override.duration = duration
+		}
+	}
+	if target.Alerts.Flapping > 0 {
+		rules.Flapping = target.Alerts.Flapping
+	}
+	return rules
+}
+
+// SLOConfig holds the windows used to compute error-budget burn for targets with an objective
+type SLOConfig struct {
+	Windows []time.Duration `yaml:"windows"`
+	Period  time.Duration   `yaml:"period"`
+}
+
+// DefaultSLOPeriod is the period error budgets are computed over unless configured otherwise
+const DefaultSLOPeriod = 30 * 24 * time.Hour
+
+// DefaultSLOWindows returns the burn rate windows used unless configured otherwise
+func DefaultSLOWindows() []time.Duration {
+	return []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour}
+}
+
+// DefaultMaxBodyBytes is the request body limit used when api.maxBodyBytes is not set
+const DefaultMaxBodyBytes = 64 << 10
+
+// APIConfig holds the settings for the runtime target management API. RateLimit (requests
+// per second per client, 0 for unlimited), RateBurst and MaxBodyBytes guard the endpoints
+// that trigger outbound checks or change the target set.
+type APIConfig struct {
+	Token        string  `yaml:"token"`
+	StateFile    string  `yaml:"stateFile"`
+	RateLimit    float64 `yaml:"rateLimit"`
+	RateBurst    int     `yaml:"rateBurst"`
+	MaxBodyBytes int64   `yaml:"maxBodyBytes"`
+}
+
+// AuthConfig protects the HTTP endpoints with basic auth and/or a bearer token
+type AuthConfig struct {
+	Users           []BasicAuthUser `yaml:"users"`
+	BearerToken     string          `yaml:"bearerToken"`
+	BearerTokenFile string          `yaml:"bearerTokenFile"`
+}
+
+// BasicAuthUser is a basic auth credential; Password holds a bcrypt hash
+type BasicAuthUser struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Enabled reports whether any credential is configured
+func (a AuthConfig) Enabled() bool {
+	return len(a.Users) > 0 || a.BearerToken != ""
+}
+
+// ServerTLSConfig enables HTTPS for the exporter's own endpoints. Certificates are reloaded
+// when the files change; ClientCAFile additionally requires scrapers to present a client
+// certificate signed by one of its CAs.
+type ServerTLSConfig struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// Enabled reports whether HTTPS serving is configured
+func (t ServerTLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// UnixSocketPrefix marks a listen address as a unix socket path
+const UnixSocketPrefix = "unix:"
+
+// Address returns the address the exporter listens on: the unix socket address as
+// configured, or ListenAddress (all interfaces when empty) joined with ListenPort
+func (c *Config) Address() string {
+	if strings.HasPrefix(c.ListenAddress, UnixSocketPrefix) {
+		return c.ListenAddress
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(c.ListenAddress, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(c.ListenPort))
+}
+
+// validateListenAddress rejects listen addresses that carry their own port, which is set
+// with listenPort
+func validateListenAddress(address string) error {
+	if address == "" || strings.HasPrefix(address, UnixSocketPrefix) {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return fmt.Errorf("listenAddress %q must not include a port, use listenPort", address)
+	}
+	return nil
+}
+
+// DefaultAdminListenAddress is where the admin listener binds when debug endpoints are
+// enabled without an admin address
+const DefaultAdminListenAddress = "127.0.0.1:8413"
+
+// AdminConfig moves the admin endpoints (reload, target management and debug) off the
+// public listener. ListenAddress is host:port or unix:/path/to/socket.
+type AdminConfig struct {
+	ListenAddress string `yaml:"listenAddress"`
+}
+
+// Enabled reports whether a separate admin listener is configured
+func (a AdminConfig) Enabled() bool {
+	return a.ListenAddress != ""
+}
+
+// DebugConfig enables pprof and runtime stats on the admin listener; they are never
+// exposed on the main port
+type DebugConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// AccessLogConfig enables structured request logging. Include and Exclude hold path.Match
+// patterns (e.g. "/api/*"); with Include set only matching paths are logged, and paths
+// matching Exclude are never logged.
+type AccessLogConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Syslog transport protocols
+const (
+	SyslogProtocolUDP = "udp"
+	SyslogProtocolTCP = "tcp"
+	SyslogProtocolTLS = "tls"
+)
+
+// DefaultSyslogFacility is used when syslog.facility is not set
+const DefaultSyslogFacility = "daemon"
+
+// syslogFacilities maps the facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogConfig additionally sends the logs as RFC 5424 messages to the syslog server at
+// Address (host:port). TLS applies to the tls protocol only.
+type SyslogConfig struct {
+	Address  string    `yaml:"address"`
+	Protocol string    `yaml:"protocol"`
+	Facility string    `yaml:"facility"`
+	TLS      TLSConfig `yaml:"tls"`
+}
+
+// Enabled reports whether a syslog server is configured
+func (s SyslogConfig) Enabled() bool {
+	return s.Address != ""
+}
+
+// FacilityCode returns the RFC 5424 code of the configured facility
+func (s SyslogConfig) FacilityCode() int {
+	return syslogFacilities[s.Facility]
+}
+
+// Defaults for the rotation of the audit log
+const (
+	DefaultAuditLogMaxBytes = 100 << 20
+	DefaultAuditLogMaxAge   = 24 * time.Hour
+)
+
+// AuditLogConfig appends every scheduled check result as a JSON line to File. The file is
+// rotated once it reaches MaxBytes or has been written to for MaxAge; MaxBackups limits
+// the rotated files that are kept, zero keeps all of them.
+type AuditLogConfig struct {
+	File       string        `yaml:"file"`
+	MaxBytes   int64         `yaml:"maxBytes"`
+	MaxAge     time.Duration `yaml:"maxAge"`
+	MaxBackups int           `yaml:"maxBackups"`
+}
+
+// Enabled reports whether an audit log file is configured
+func (a AuditLogConfig) Enabled() bool {
+	return a.File != ""
+}
+
+// DefaultHistorySize is the number of check results kept per target when history.size is not set
+const DefaultHistorySize = 1440
+
+// HistoryConfig sizes the per-target ring buffer of recent check results. When File is set
+// the buffers are saved to it on shutdown and restored on startup.
+type HistoryConfig struct {
+	Size int    `yaml:"size"`
+	File string `yaml:"file"`
+}
+
+// ConnectionsConfig tunes the connection pool of the HTTP checks. Zero values keep the
+// client defaults; MaxConnsPerHost limits the connections to a host including those in
+// use. DisableKeepAlives opens a fresh connection for every check, so response times
+// include the TCP and TLS handshakes; a check's keepAlive overrides it.
+type ConnectionsConfig struct {
+	MaxConnsPerHost     int           `yaml:"maxConnsPerHost"`
+	MaxIdleConnsPerHost int           `yaml:"maxIdleConnsPerHost"`
+	IdleConnTimeout     time.Duration `yaml:"idleConnTimeout"`
+	DisableKeepAlives   bool          `yaml:"disableKeepAlives"`
+}
+
+// TargetInterval returns how often the target is checked in interval mode: its own
+// interval, or checkInterval when it has none
+func (c *Config) TargetInterval(target Target) time.Duration {
+	if target.Interval != "" {
+		if interval, err := time.ParseDuration(target.Interval); err == nil && interval > 0 {
+			return interval
+		}
+	}
+	return c.CheckInterval
+}
+
+// KeepAlive reports whether checks of the target may reuse pooled connections
+func (c *Config) KeepAlive(target Target) bool {
+	if target.KeepAlive != nil {
+		return *target.KeepAlive
+	}
+	return !c.Connections.DisableKeepAlives
+}
+
+// SourceConfig selects where the checks' connections originate on multi-homed hosts:
+// Address is the local IP they are bound to and Interface the network interface or VRF
+// device they are sent through (Linux only)
+type SourceConfig struct {
+	Address   string `yaml:"address" json:"address,omitempty"`
+	Interface string `yaml:"interface" json:"interface,omitempty"`
+}
+
+// IsZero reports whether no source is set, so the system picks it
+func (s SourceConfig) IsZero() bool {
+	return s.Address == "" && s.Interface == ""
+}
+
+func (s SourceConfig) validate() error {
+	if s.Address != "" && net.ParseIP(s.Address) == nil {
+		return fmt.Errorf("invalid source address %q: must be an IP address", s.Address)
+	}
+	return nil
+}
+
+// SourceFor returns the source of the target's connections: its own when set, else the
+// global one
+func (c *Config) SourceFor(target Target) SourceConfig {
+	if !target.Source.IsZero() {
+		return target.Source
+	}
+	return c.Source
+}
+
+// GroupConfig gives the HTTP checks of a target group their own client, so its timeout,
+// retries and proxy do not affect other targets and its connections come from a separate
+// pool. Zero values fall back to the global settings.
+type GroupConfig struct {
+	Timeout         time.Duration `yaml:"timeout"`
+	Retries         *int          `yaml:"retries"`
+	Proxy           string        `yaml:"proxy"`
+	MaxConnsPerHost int           `yaml:"maxConnsPerHost"`
+}
+
+// GroupsConfig maps group names to their client settings
+type GroupsConfig map[string]GroupConfig
+
+// Lookup returns the settings of the named group. Lookups are case-insensitive because
+// configuration keys are lower-cased when loaded.
+func (g GroupsConfig) Lookup(group string) (GroupConfig, bool) {
+	if group == "" {
+		return GroupConfig{}, false
+	}
+	settings, exists := g[strings.ToLower(group)]
+	return settings, exists
+}
+
+func (g GroupConfig) validate() error {
+	if g.Timeout < 0 || g.MaxConnsPerHost < 0 || (g.Retries != nil && *g.Retries < 0) {
+		return fmt.Errorf("timeout, retries and maxConnsPerHost must not be negative")
+	}
+	if g.Proxy != "" {
+		proxy, err := url.Parse(g.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+		}
+		if proxy.Host == "" {
+			return fmt.Errorf("proxy %q has no host", g.Proxy)
+		}
+	}
+	return nil
+}
+
+// DefaultDNSCacheMaxTTL caps how long a DNS answer is cached when dnsCache.maxTTL is not set
+const DefaultDNSCacheMaxTTL = 5 * time.Minute
+
+// DNSCacheConfig enables caching of the targets' DNS answers for the TTL of their records,
+// raised to MinTTL and capped at MaxTTL
+type DNSCacheConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	MinTTL  time.Duration `yaml:"minTTL"`
+	MaxTTL  time.Duration `yaml:"maxTTL"`
+}
+
+// DefaultEventsSize is the number of events kept when events.size is not set
+const DefaultEventsSize = 1000
+
+// EventsConfig sizes the in-memory log of notable events served by /api/v1/events
+type EventsConfig struct {
+	Size int `yaml:"size"`
+}
+
+// Leader election modes
+const (
+	// LeaderElectionKubernetes elects the leader through a coordination.k8s.io Lease
+	LeaderElectionKubernetes = "kubernetes"
+	// LeaderElectionFile elects the instance holding an exclusive lock on a shared file
+	LeaderElectionFile = "file"
+)
+
+// Defaults for leader election
+const (
+	DefaultLeaseName     = "url-exporter"
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderConfig lets replicas elect a leader that runs the checks while the others
+// stand by. Lease and Namespace name the Kubernetes Lease, the namespace defaulting to the
+// pod's own; File is the lock file for the file mode.
+type LeaderConfig struct {
+	Mode          string        `yaml:"mode"`
+	Lease         string        `yaml:"lease"`
+	Namespace     string        `yaml:"namespace"`
+	File          string        `yaml:"file"`
+	LeaseDuration time.Duration `yaml:"leaseDuration"`
+	RetryPeriod   time.Duration `yaml:"retryPeriod"`
+}
+
+// Enabled reports whether leader election is configured
+func (l LeaderConfig) Enabled() bool {
+	return l.Mode != ""
+}
+
+// prepare validates the settings of the configured mode and applies the defaults
+func (l *LeaderConfig) prepare() error {
+	switch l.Mode {
+	case "":
+		return nil
+	case LeaderElectionKubernetes:
+		if l.Lease == "" {
+			l.Lease = DefaultLeaseName
+		}
+	case LeaderElectionFile:
+		if l.File == "" {
+			return fmt.Errorf("file is required in %s mode", LeaderElectionFile)
+		}
+	default:
+		return fmt.Errorf("invalid mode %q: must be %q or %q", l.Mode, LeaderElectionKubernetes, LeaderElectionFile)
+	}
+
+	if l.LeaseDuration < 0 || l.RetryPeriod < 0 {
+		return fmt.Errorf("leaseDuration and retryPeriod must not be negative")
+	}
+	if l.LeaseDuration == 0 {
+		l.LeaseDuration = DefaultLeaseDuration
+	}
+	if l.RetryPeriod == 0 {
+		l.RetryPeriod = DefaultRetryPeriod
+	}
+	if l.RetryPeriod >= l.LeaseDuration {
+		return fmt.Errorf("retryPeriod must be shorter than leaseDuration")
+	}
+	return nil
+}
+
+// DefaultControllerStatusInterval is how often the status of a URLCheck is refreshed while
+// its check result does not change
+const DefaultControllerStatusInterval = time.Minute
+
+// ControllerConfig turns on the controller mode, which checks the URLCheck custom resources
+// of a Kubernetes namespace, by default the pod's own, in addition to the configured
+// targets and writes their results to the resources' status
+type ControllerConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Namespace      string        `yaml:"namespace"`
+	StatusInterval time.Duration `yaml:"statusInterval"`
+}
+
+// Kinds of Kubernetes resources whose hosts can be discovered as targets
+const (
+	DiscoveryKindIngress   = "ingress"
+	DiscoveryKindHTTPRoute = "httproute"
+)
+
+// DiscoveryConfig generates targets from the hosts of the Ingresses and Gateway API
+// HTTPRoutes in the namespaces that opted in by being listed. Scheme is derived from the
+// resource while empty: https for Ingress hosts with TLS and for HTTPRoutes.
+type DiscoveryConfig struct {
+	Namespaces []string `yaml:"namespaces"`
+	Kinds      []string `yaml:"kinds"`
+	Scheme     string   `yaml:"scheme"`
+	Path       string   `yaml:"path"`
+	Group      string   `yaml:"group"`
+}
+
+// Enabled reports whether any namespace opted in to discovery
+func (d DiscoveryConfig) Enabled() bool {
+	return len(d.Namespaces) > 0
+}
+
+// prepare validates the discovery settings and fills in the defaults
+func (d *DiscoveryConfig) prepare() error {
+	if len(d.Kinds) == 0 {
+		d.Kinds = []string{DiscoveryKindIngress}
+	}
+	for _, kind := range d.Kinds {
+		if kind != DiscoveryKindIngress && kind != DiscoveryKindHTTPRoute {
+			return fmt.Errorf("unknown kind %q, expected %s or %s", kind, DiscoveryKindIngress, DiscoveryKindHTTPRoute)
+		}
+	}
+	if d.Scheme != "" && d.Scheme != "http" && d.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if d.Path == "" {
+		d.Path = "/"
+	}
+	if !strings.HasPrefix(d.Path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+	return nil
+}
+
+// TargetPolicyConfig restricts the targets that reach the exporter at runtime: added
+// through the API, probed through /probe or generated from Kubernetes resources. Empty
+// allow lists allow everything the deny lists do not deny. Link-local addresses, which
+// include the cloud metadata services, are denied unless AllowLinkLocal is set.
+// Configured targets are trusted and not restricted.
+type TargetPolicyConfig struct {
+	AllowSchemes   []string `yaml:"allowSchemes"`
+	DenySchemes    []string `yaml:"denySchemes"`
+	AllowPorts     []int    `yaml:"allowPorts"`
+	DenyPorts      []int    `yaml:"denyPorts"`
+	AllowCIDRs     []string `yaml:"allowCidrs"`
+	DenyCIDRs      []string `yaml:"denyCidrs"`
+	AllowLinkLocal bool     `yaml:"allowLinkLocal"`
+}
+
+// validate checks that the ports are valid and the CIDRs parse
+func (p TargetPolicyConfig) validate() error {
+	for _, port := range append(append([]int{}, p.AllowPorts...), p.DenyPorts...) {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %d", port)
+		}
+	}
+	for _, cidr := range append(append([]string{}, p.AllowCIDRs...), p.DenyCIDRs...) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+// NotificationsConfig holds the channels that are notified when a target goes down or
+// comes back up. ExternalURL is the address the exporter is reachable at, used for links
+// in notifications. The channels set directly under notifications form the default
+// receiver; Receivers holds further named sets of channels and Route decides which
+// receivers each target's notifications go to.
+type NotificationsConfig struct {
+	ReceiverConfig `yaml:",inline" mapstructure:",squash"`
+
+	ExternalURL string                    `yaml:"externalUrl"`
+	Receivers   map[string]ReceiverConfig `yaml:"receivers"`
+	Route       RouteConfig               `yaml:"route"`
+	Reports     ReportsConfig             `yaml:"reports"`
+}
+
+// Report schedules
+const (
+	ReportDaily  = "daily"
+	ReportWeekly = "weekly"
+)
+
+// ReportsConfig schedules availability reports per target group, sent to the Telegram and
+// Teams channels of Receivers (the default receiver when empty). Daily reports cover the
+// previous UTC day and weekly ones the previous seven, sent at Time (UTC, "HH:MM") and,
+// for weekly reports, on Weekday. Targets are compared with their objective or, without
+// one, with SLA (a ratio like objective, 0 for none).
+type ReportsConfig struct {
+	Schedule  string   `yaml:"schedule"`
+	Time      string   `yaml:"time"`
+	Weekday   string   `yaml:"weekday"`
+	Receivers []string `yaml:"receivers"`
+	SLA       float64  `yaml:"sla"`
+}
+
+// Enabled reports whether a report schedule is configured
+func (r ReportsConfig) Enabled() bool {
+	return r.Schedule != ""
+}
+
+// Default report delivery settings
+const (
+	DefaultReportTime    = "08:00"
+	DefaultReportWeekday = "monday"
+)
+
+// TimeOfDay returns the configured delivery time as an offset from midnight UTC
+func (r ReportsConfig) TimeOfDay() (time.Duration, error) {
+	t, err := time.Parse("15:04", r.Time)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: must be HH:MM", r.Time)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// DeliveryDay returns the configured delivery day of weekly reports
+func (r ReportsConfig) DeliveryDay() (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), r.Weekday) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", r.Weekday)
+}
+
+// DefaultReceiver is the name of the receiver made of the channels set directly under
+// notifications
+const DefaultReceiver = "default"
+
+// ReceiverConfig is a named set of notification channels
+type ReceiverConfig struct {
+	PagerDuty   PagerDutyConfig   `yaml:"pagerduty"`
+	Telegram    TelegramConfig    `yaml:"telegram"`
+	Teams       TeamsConfig       `yaml:"teams"`
+	CloudEvents CloudEventsConfig `yaml:"cloudEvents"`
+}
+
+// RouteConfig is a node of the notification routing tree, similar to Alertmanager routes.
+// A node matches targets whose labels equal every Match value and fully match every
+// MatchRE expression; the group of a target can be matched as the "group" label. A target
+// is passed on to the first matching child, or to further ones while the matching children
+// have Continue set, and goes to the receiver of the deepest matching node. Receiver
+// defaults to that of the parent node and, at the root, to the default receiver.
+type RouteConfig struct {
+	Receiver string            `yaml:"receiver"`
+	Match    map[string]string `yaml:"match"`
+	MatchRE  map[string]string `yaml:"matchRe"`
+	Continue bool              `yaml:"continue"`
+	Routes   []RouteConfig     `yaml:"routes"`
+}
+
+// ChannelOptions control when a notification channel is notified. A target must stay down
+// for GroupWait before it is reported, so short blips are not; while it stays down the
+// report is repeated every RepeatInterval (0 for never). SendResolved, true by default,
+// reports recoveries of targets whose failure was reported. Template, a Go text/template,
+// replaces the channel's default message text.
+type ChannelOptions struct {
+	GroupWait      time.Duration `yaml:"groupWait"`
+	RepeatInterval time.Duration `yaml:"repeatInterval"`
+	SendResolved   *bool         `yaml:"sendResolved"`
+	Template       string        `yaml:"template"`
+}
+
+// Resolved reports whether recoveries are sent
+func (o ChannelOptions) Resolved() bool {
+	return o.SendResolved == nil || *o.SendResolved
+}
+
+func (o ChannelOptions) validate(channel string) error {
+	if o.GroupWait < 0 || o.RepeatInterval < 0 {
+		return fmt.Errorf("notifications.%s: groupWait and repeatInterval must not be negative", channel)
+	}
+	if _, err := template.New(channel).Parse(o.Template); err != nil {
+		return fmt.Errorf("notifications.%s: invalid template: %w", channel, err)
+	}
+	return nil
+}
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyConfig sends trigger and resolve events to the PagerDuty Events API v2.
+// RoutingKeys maps target groups to integration keys; targets in other groups use
+// RoutingKey, and are not sent when it is empty.
+type PagerDutyConfig struct {
+	ChannelOptions `yaml:",inline" mapstructure:",squash"`
+
+	RoutingKey  string            `yaml:"routingKey"`
+	RoutingKeys map[string]string `yaml:"routingKeys"`
+	Severity    string            `yaml:"severity"`
+	URL         string            `yaml:"url"`
+}
+
+// Enabled reports whether any routing key is configured
+func (p PagerDutyConfig) Enabled() bool {
+	return p.RoutingKey != "" || len(p.RoutingKeys) > 0
+}
+
+// RoutingKeyFor returns the routing key for the group. Group lookups are case-insensitive
+// because configuration keys are lower-cased when loaded.
+func (p PagerDutyConfig) RoutingKeyFor(group string) string {
+	if key, exists := p.RoutingKeys[strings.ToLower(group)]; exists && group != "" {
+		return key
+	}
+	return p.RoutingKey
+}
+
+// DefaultTelegramURL is the Telegram Bot API endpoint
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// TelegramConfig sends state change messages through a Telegram bot to each chat in
+// ChatIDs, given as numeric IDs or @channel names
+type TelegramConfig struct {
+	ChannelOptions `yaml:",inline" mapstructure:",squash"`
+
+	BotToken string   `yaml:"botToken"`
+	ChatIDs  []string `yaml:"chatIds"`
+	URL      string   `yaml:"url"`
+}
+
+// Enabled reports whether a bot token is configured
+func (t TelegramConfig) Enabled() bool {
+	return t.BotToken != ""
+}
+
+// TeamsConfig posts state change cards to a Microsoft Teams incoming webhook
+type TeamsConfig struct {
+	ChannelOptions `yaml:",inline" mapstructure:",squash"`
+
+	WebhookURL string `yaml:"webhookUrl"`
+}
+
+// Enabled reports whether a webhook URL is configured
+func (t TeamsConfig) Enabled() bool {
+	return t.WebhookURL != ""
+}
+
+// CloudEvents HTTP content modes
+const (
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+)
+
+// CloudEventsConfig posts state changes as CloudEvents to an HTTP endpoint, such as a
+// Knative or Argo Events broker. Structured mode sends the whole event as JSON, binary mode
+// its attributes as ce- headers. Source defaults to url-exporter/<instance ID>; Headers
+// are added to every request, e.g. for authentication.
+type CloudEventsConfig struct {
+	ChannelOptions `yaml:",inline" mapstructure:",squash"`
+
+	Endpoint string            `yaml:"endpoint"`
+	Mode     string            `yaml:"mode"`
+	Source   string            `yaml:"source"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+// Enabled reports whether an endpoint is configured
+func (c CloudEventsConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// prepare validates the notification settings and fills in defaults
+func (n *NotificationsConfig) prepare() error {
+	if n.ExternalURL != "" {
+		if u, err := url.Parse(n.ExternalURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notifications.externalUrl: invalid URL %q", n.ExternalURL)
+		}
+	}
+
+	if err := n.ReceiverConfig.prepare("notifications"); err != nil {
+		return err
+	}
+	for name, receiver := range n.Receivers {
+		if name == DefaultReceiver {
+			return fmt.Errorf("notifications.receivers: %q is reserved for the channels set directly under notifications", name)
+		}
+		if err := receiver.prepare("notifications.receivers." + name); err != nil {
+			return err
+		}
+		n.Receivers[name] = receiver
+	}
+
+	if err := n.Reports.prepare(n.Receivers); err != nil {
+		return err
+	}
+
+	if len(n.Route.Match) > 0 || len(n.Route.MatchRE) > 0 {
+		return fmt.Errorf("notifications.route: the root route matches every target and cannot set match or matchRe")
+	}
+	return n.Route.validate("notifications.route", n.Receivers)
+}
+
+// prepare validates the report schedule and fills in its defaults
+func (r *ReportsConfig) prepare(receivers map[string]ReceiverConfig) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	if r.Schedule != ReportDaily && r.Schedule != ReportWeekly {
+		return fmt.Errorf("notifications.reports: invalid schedule %q: must be daily or weekly", r.Schedule)
+	}
+	if r.Time == "" {
+		r.Time = DefaultReportTime
+	}
+	if _, err := r.TimeOfDay(); err != nil {
+		return fmt.Errorf("notifications.reports: %w", err)
+	}
+	if r.Weekday == "" {
+		r.Weekday = DefaultReportWeekday
+	}
+	if _, err := r.DeliveryDay(); err != nil {
+		return fmt.Errorf("notifications.reports: %w", err)
+	}
+	if r.SLA < 0 || r.SLA >= 1 {
+		return fmt.Errorf("notifications.reports: sla must be a ratio between 0 and 1, e.g. 0.999")
+	}
+
+	if len(r.Receivers) == 0 {
+		r.Receivers = []string{DefaultReceiver}
+	}
+	for i, name := range r.Receivers {
+		name = strings.ToLower(name)
+		if _, exists := receivers[name]; !exists && name != DefaultReceiver {
+			return fmt.Errorf("notifications.reports: unknown receiver %q", r.Receivers[i])
+		}
+		r.Receivers[i] = name
+	}
+
+	return nil
+}
+
+// prepare validates the receiver's channels and fills in their defaults. Prefix is the
+// receiver's configuration path used in errors.
+func (r *ReceiverConfig) prepare(prefix string) error {
+	if pagerDuty := &r.PagerDuty; pagerDuty.Enabled() {
+		switch pagerDuty.Severity {
+		case "":
+			pagerDuty.Severity = "critical"
+		case "critical", "error", "warning", "info":
+		default:
+			return fmt.Errorf("%s.pagerduty: invalid severity %q: must be critical, error, warning or info", prefix, pagerDuty.Severity)
+		}
+		if pagerDuty.URL == "" {
+			pagerDuty.URL = DefaultPagerDutyURL
+		}
+	}
+
+	if err := errors.Join(
+		r.PagerDuty.validate(prefix+".pagerduty"),
+		r.Telegram.validate(prefix+".telegram"),
+		r.Teams.validate(prefix+".teams"),
+		r.CloudEvents.validate(prefix+".cloudEvents"),
+	); err != nil {
+		return err
+	}
+
+	if cloudEvents := &r.CloudEvents; cloudEvents.Enabled() {
+		if u, err := url.Parse(cloudEvents.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s.cloudEvents: invalid endpoint %q", prefix, RedactURL(cloudEvents.Endpoint))
+		}
+		switch cloudEvents.Mode {
+		case "":
+			cloudEvents.Mode = CloudEventsStructured
+		case CloudEventsStructured, CloudEventsBinary:
+		default:
+			return fmt.Errorf("%s.cloudEvents: invalid mode %q: must be %s or %s", prefix, cloudEvents.Mode, CloudEventsStructured, CloudEventsBinary)
+		}
+	}
+
+	if telegram := &r.Telegram; telegram.Enabled() {
+		if len(telegram.ChatIDs) == 0 {
+			return fmt.Errorf("%s.telegram: botToken is set but no chatIds", prefix)
+		}
+		if telegram.URL == "" {
+			telegram.URL = DefaultTelegramURL
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the route and its children only refer to known receivers and
+// have valid expressions
+func (r RouteConfig) validate(path string, receivers map[string]ReceiverConfig) error {
+	if r.Receiver != "" && r.Receiver != DefaultReceiver {
+		if _, exists := receivers[strings.ToLower(r.Receiver)]; !exists {
+			return fmt.Errorf("%s: unknown receiver %q", path, r.Receiver)
+		}
+	}
+	for label, expr := range r.MatchRE {
+		if _, err := regexp.Compile("^(?:" + expr + ")$"); err != nil {
+			return fmt.Errorf("%s: invalid matchRe for %s: %w", path, label, err)
+		}
+	}
+	for i, child := range r.Routes {
+		if err := child.validate(fmt.Sprintf("%s.routes[%d]", path, i), receivers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultHeartbeatInterval is how often heartbeats are sent unless configured otherwise
+const DefaultHeartbeatInterval = time.Minute
+
+// Instance metadata providers
+const (
+	MetadataAWS   = "aws"
+	MetadataGCP   = "gcp"
+	MetadataAzure = "azure"
+	// MetadataEnv reads the REGION, ZONE and NODE_NAME environment variables, e.g. set
+	// through the Kubernetes Downward API
+	MetadataEnv = "env"
+)
+
+// DefaultMetadataTimeout bounds the metadata lookup at startup
+const DefaultMetadataTimeout = 2 * time.Second
+
+// MetadataConfig looks up the region, zone and node the exporter runs in at startup and
+// attaches them as labels to all metrics
+type MetadataConfig struct {
+	Provider string        `yaml:"provider"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Enabled reports whether a metadata provider is configured
+func (m MetadataConfig) Enabled() bool {
+	return m.Provider != ""
+}
+
+// HeartbeatConfig sends a request to URL every Interval, for a dead man's switch such as
+// healthchecks.io to notice when the exporter stops running
+type HeartbeatConfig struct {
+	URL      string        `yaml:"url"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Enabled reports whether a heartbeat URL is configured
+func (h HeartbeatConfig) Enabled() bool {
+	return h.URL != ""
+}
+
+// TracingConfig exports a span per check to an OpenTelemetry collector over OTLP/HTTP.
+// Endpoint is the collector's base URL, e.g. http://otel-collector:4318.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"`
+	Headers     map[string]string `yaml:"headers"`
+	SampleRatio float64           `yaml:"sampleRatio"`
+}
+
+// Enabled reports whether an OTLP endpoint is configured
+func (t TracingConfig) Enabled() bool {
+	return t.Endpoint != ""
+}
+
+// DefaultStatusPageTitle is the heading of the status page unless configured otherwise
+const DefaultStatusPageTitle = "Service Status"
+
+// StatusPageConfig serves an HTML status page at /status with the state and daily uptime
+// of every target, grouped by target group. With Public set it is served without
+// authentication, e.g. to be cached by a CDN.
+type StatusPageConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Title   string `yaml:"title"`
+	Public  bool   `yaml:"public"`
+}
+
+// MetricsConfig controls which metric families are exported. The series of a removed
+// target are exported for RemovalGrace longer, so a target that is removed and added back
+// keeps its counters; zero drops them at once.
+type MetricsConfig struct {
+	Disabled     []string      `yaml:"disabled"`
+	RemovalGrace time.Duration `yaml:"removalGrace"`
+}
+
+// GraphiteConfig holds the settings for the optional Graphite plaintext sink
+type GraphiteConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Host          string        `yaml:"host"`
+	Port          int           `yaml:"port"`
+	Prefix        string        `yaml:"prefix"`
+	FlushInterval time.Duration `yaml:"flushInterval"`
+}
+
+// InfluxDBConfig holds the settings for the optional InfluxDB v2 line-protocol sink
+type InfluxDBConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	URL         string `yaml:"url"`
+	Org         string `yaml:"org"`
+	Bucket      string `yaml:"bucket"`
+	Token       string `yaml:"token"`
+	Measurement string `yaml:"measurement"`
+}
+
+// Record formats of the Kafka sink
+const (
+	KafkaFormatJSON = "json"
+	KafkaFormatAvro = "avro"
+)
+
+// SASL mechanisms of the Kafka sink
+const (
+	KafkaSASLPlain       = "PLAIN"
+	KafkaSASLSCRAMSHA256 = "SCRAM-SHA-256"
+	KafkaSASLSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// KafkaConfig holds the settings for the optional Kafka sink, which publishes every
+// scheduled check result as a record keyed by the target's URL
+type KafkaConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+	Format  string   `yaml:"format"`
+	// SchemaID prefixes Avro records with the Confluent wire format header for the schema
+	// registered under this ID; zero writes plain Avro
+	SchemaID int             `yaml:"schemaId"`
+	TLS      KafkaTLSConfig  `yaml:"tls"`
+	SASL     KafkaSASLConfig `yaml:"sasl"`
+}
+
+// KafkaTLSConfig enables TLS for the connections to the brokers
+type KafkaTLSConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	TLSConfig `yaml:",inline" mapstructure:",squash"`
+}
+
+// KafkaSASLConfig authenticates the connections to the brokers, unless Mechanism is empty
+type KafkaSASLConfig struct {
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+// validate checks the Kafka settings and fills in the default format
+func (k *KafkaConfig) validate() error {
+	if len(k.Brokers) == 0 || k.Topic == "" {
+		return fmt.Errorf("kafka sink enabled but brokers or topic not specified")
+	}
+	for _, broker := range k.Brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			return fmt.Errorf("kafka.brokers: %q must be host:port: %w", broker, err)
+		}
+	}
+
+	switch k.Format {
+	case "":
+		k.Format = KafkaFormatJSON
+	case KafkaFormatJSON, KafkaFormatAvro:
+	default:
+		return fmt.Errorf("invalid kafka.format %q: must be %q or %q", k.Format, KafkaFormatJSON, KafkaFormatAvro)
+	}
+	if k.SchemaID < 0 || (k.SchemaID > 0 && k.Format != KafkaFormatAvro) {
+		return fmt.Errorf("kafka.schemaId must be a positive schema ID and requires the %q format", KafkaFormatAvro)
+	}
+
+	switch k.SASL.Mechanism {
+	case "":
+	case KafkaSASLPlain, KafkaSASLSCRAMSHA256, KafkaSASLSCRAMSHA512:
+		if k.SASL.Username == "" {
+			return fmt.Errorf("kafka.sasl.username is required for %s", k.SASL.Mechanism)
+		}
+	default:
+		return fmt.Errorf("invalid kafka.sasl.mechanism %q: must be %q, %q or %q", k.SASL.Mechanism, KafkaSASLPlain, KafkaSASLSCRAMSHA256, KafkaSASLSCRAMSHA512)
+	}
+
+	if k.TLS.Enabled {
+		if _, err := k.TLS.ClientConfig(); err != nil {
+			return fmt.Errorf("invalid kafka.tls: %w", err)
+		}
+	}
+	return nil
+}
+
+// DefaultNATSSubject is the subject template of the NATS sink unless configured otherwise
+const DefaultNATSSubject = "url-exporter.results.{{.Group}}.{{.Status}}"
+
+// NATSConfig holds the settings for the optional NATS sink, which publishes every
+// scheduled check result as a JSON message. Subject is a Go text/template of the subject a
+// result is published to; GroupSubjects overrides it for the targets of a group. With
+// JetStream set each message waits for the acknowledgement of the stream storing it.
+type NATSConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	Servers       []string          `yaml:"servers"`
+	Subject       string            `yaml:"subject"`
+	GroupSubjects map[string]string `yaml:"groupSubjects"`
+	JetStream     bool              `yaml:"jetStream"`
+	Token         string            `yaml:"token"`
+	Username      string            `yaml:"username"`
+	Password      string            `yaml:"password"`
+	TLS           NATSTLSConfig     `yaml:"tls"`
+}
+
+// NATSTLSConfig enables TLS for the connections to the servers
+type NATSTLSConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	TLSConfig `yaml:",inline" mapstructure:",squash"`
+}
+
+// SubjectFor returns the subject template for the targets of the group. Group lookups are
+// case-insensitive because the configuration loader lowercases map keys.
+func (n NATSConfig) SubjectFor(group string) string {
+	if subject, exists := n.GroupSubjects[strings.ToLower(group)]; exists && group != "" {
+		return subject
+	}
+	return n.Subject
+}
+
+// validate checks the NATS settings and fills in the default subject
+func (n *NATSConfig) validate() error {
+	if len(n.Servers) == 0 {
+		return fmt.Errorf("nats sink enabled but no servers specified")
+	}
+	for _, server := range n.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return fmt.Errorf("nats.servers: %q must be host:port: %w", server, err)
+		}
+	}
+
+	if n.Subject == "" {
+		n.Subject = DefaultNATSSubject
+	}
+	if _, err := template.New("subject").Parse(n.Subject); err != nil {
+		return fmt.Errorf("invalid nats.subject: %w", err)
+	}
+	for group, subject := range n.GroupSubjects {
+		if _, err := template.New(group).Parse(subject); err != nil {
+			return fmt.Errorf("invalid nats.groupSubjects.%s: %w", group, err)
+		}
+	}
+
+	if n.Token != "" && n.Username != "" {
+		return fmt.Errorf("nats.token and nats.username are mutually exclusive")
+	}
+
+	if n.TLS.Enabled {
+		if _, err := n.TLS.ClientConfig(); err != nil {
+			return fmt.Errorf("invalid nats.tls: %w", err)
+		}
+	}
+	return nil
 }
 
 //go:embed config.default.yml
 var defaultYAML string
 
 func Load() (*Config, error) {
+	return load(true)
+}
+
+// LoadSettings loads the configuration like Load but does not require targets, for
+// commands that check targets given on the command line
+func LoadSettings() (*Config, error) {
+	return load(false)
+}
+
+func load(requireTargets bool) (*Config, error) {
 	configContent, err := loadConfigFile()
 	if err != nil {
 		configContent = defaultYAML
@@ -60,13 +1590,453 @@ func Load() (*Config, error) {
 		}
 	}
 
-	if len(cfg.Targets) == 0 {
+	if requireTargets && len(cfg.Targets) == 0 && len(cfg.Checks) == 0 {
 		return nil, fmt.Errorf("no targets specified")
 	}
 
+	if err := cfg.ValidateChecks(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.applySharding(); err != nil {
+		return nil, err
+	}
+
+	for i := range cfg.Composites {
+		if cfg.Composites[i].Mode == "" {
+			cfg.Composites[i].Mode = CompositeAll
+		}
+	}
+
+	switch cfg.LogFormat {
+	case "":
+		cfg.LogFormat = LogFormatConsole
+	case LogFormatConsole, LogFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid logFormat %q: must be %q or %q", cfg.LogFormat, LogFormatConsole, LogFormatJSON)
+	}
+
+	if cfg.Syslog.Enabled() {
+		if _, _, err := net.SplitHostPort(cfg.Syslog.Address); err != nil {
+			return nil, fmt.Errorf("syslog.address: must be host:port: %w", err)
+		}
+		switch cfg.Syslog.Protocol {
+		case "":
+			cfg.Syslog.Protocol = SyslogProtocolUDP
+		case SyslogProtocolUDP, SyslogProtocolTCP, SyslogProtocolTLS:
+		default:
+			return nil, fmt.Errorf("invalid syslog.protocol %q: must be %q, %q or %q", cfg.Syslog.Protocol, SyslogProtocolUDP, SyslogProtocolTCP, SyslogProtocolTLS)
+		}
+		if cfg.Syslog.Facility == "" {
+			cfg.Syslog.Facility = DefaultSyslogFacility
+		}
+		if _, ok := syslogFacilities[cfg.Syslog.Facility]; !ok {
+			return nil, fmt.Errorf("invalid syslog.facility %q", cfg.Syslog.Facility)
+		}
+	}
+
+	switch cfg.ProbeMode {
+	case "":
+		cfg.ProbeMode = ProbeModeInterval
+	case ProbeModeInterval, ProbeModeScrape:
+	default:
+		return nil, fmt.Errorf("invalid probeMode %q: must be %q or %q", cfg.ProbeMode, ProbeModeInterval, ProbeModeScrape)
+	}
+	if cfg.ScrapeTimeout <= 0 {
+		cfg.ScrapeTimeout = cfg.Timeout
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = cfg.Timeout
+	}
+	if cfg.Warmup < 0 {
+		return nil, fmt.Errorf("warmup must not be negative")
+	}
+	if cfg.Metrics.RemovalGrace < 0 {
+		return nil, fmt.Errorf("metrics: removalGrace must not be negative")
+	}
+
+	if err := cfg.AlertRules.prepare(); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.SLO.Windows) == 0 {
+		cfg.SLO.Windows = DefaultSLOWindows()
+	}
+	if cfg.SLO.Period <= 0 {
+		cfg.SLO.Period = DefaultSLOPeriod
+	}
+	for _, window := range cfg.SLO.Windows {
+		if window <= 0 || window > cfg.SLO.Period {
+			return nil, fmt.Errorf("invalid slo window %s: must be positive and not exceed the period %s", window, cfg.SLO.Period)
+		}
+	}
+
+	if err := validateListenAddress(cfg.ListenAddress); err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range slices.Concat(cfg.AccessLog.Include, cfg.AccessLog.Exclude) {
+		if _, err := path.Match(pattern, "/"); err != nil {
+			return nil, fmt.Errorf("invalid accessLog path pattern %q: %w", pattern, err)
+		}
+	}
+
+	if err := cfg.Auth.load(); err != nil {
+		return nil, err
+	}
+	if err := cfg.prepareTenants(); err != nil {
+		return nil, err
+	}
+
+	if cfg.API.RateLimit < 0 || cfg.API.RateBurst < 0 || cfg.API.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("api: rateLimit, rateBurst and maxBodyBytes must not be negative")
+	}
+	if cfg.API.RateLimit > 0 && cfg.API.RateBurst == 0 {
+		cfg.API.RateBurst = max(1, int(math.Ceil(cfg.API.RateLimit)))
+	}
+	if cfg.API.MaxBodyBytes == 0 {
+		cfg.API.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	if cfg.History.Size < 0 {
+		return nil, fmt.Errorf("history: size must not be negative")
+	}
+	if cfg.History.Size == 0 {
+		cfg.History.Size = DefaultHistorySize
+	}
+
+	if cfg.AuditLog.MaxBytes < 0 || cfg.AuditLog.MaxAge < 0 || cfg.AuditLog.MaxBackups < 0 {
+		return nil, fmt.Errorf("auditLog: maxBytes, maxAge and maxBackups must not be negative")
+	}
+	if cfg.AuditLog.MaxBytes == 0 {
+		cfg.AuditLog.MaxBytes = DefaultAuditLogMaxBytes
+	}
+	if cfg.AuditLog.MaxAge == 0 {
+		cfg.AuditLog.MaxAge = DefaultAuditLogMaxAge
+	}
+
+	if cfg.Events.Size < 0 {
+		return nil, fmt.Errorf("events: size must not be negative")
+	}
+	if cfg.Events.Size == 0 {
+		cfg.Events.Size = DefaultEventsSize
+	}
+
+	if err := cfg.Source.validate(); err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	if cfg.Connections.MaxConnsPerHost < 0 || cfg.Connections.MaxIdleConnsPerHost < 0 || cfg.Connections.IdleConnTimeout < 0 {
+		return nil, fmt.Errorf("connections: maxConnsPerHost, maxIdleConnsPerHost and idleConnTimeout must not be negative")
+	}
+
+	for name, group := range cfg.Groups {
+		if err := group.validate(); err != nil {
+			return nil, fmt.Errorf("invalid group %s: %w", name, err)
+		}
+	}
+
+	if cfg.DNSCache.MinTTL < 0 || cfg.DNSCache.MaxTTL < 0 {
+		return nil, fmt.Errorf("dnsCache: minTTL and maxTTL must not be negative")
+	}
+	if cfg.DNSCache.MaxTTL == 0 {
+		cfg.DNSCache.MaxTTL = DefaultDNSCacheMaxTTL
+	}
+	if cfg.DNSCache.MinTTL > cfg.DNSCache.MaxTTL {
+		return nil, fmt.Errorf("dnsCache: minTTL must not exceed maxTTL")
+	}
+
+	if err := cfg.Leader.prepare(); err != nil {
+		return nil, fmt.Errorf("leaderElection: %w", err)
+	}
+
+	if cfg.Controller.StatusInterval < 0 {
+		return nil, fmt.Errorf("controller: statusInterval must not be negative")
+	}
+	if cfg.Controller.StatusInterval == 0 {
+		cfg.Controller.StatusInterval = DefaultControllerStatusInterval
+	}
+
+	if err := cfg.Discovery.prepare(); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+
+	switch cfg.Metadata.Provider {
+	case "", MetadataAWS, MetadataGCP, MetadataAzure, MetadataEnv:
+	default:
+		return nil, fmt.Errorf("instanceMetadata: unknown provider %q, expected aws, gcp, azure or env", cfg.Metadata.Provider)
+	}
+	if cfg.Metadata.Timeout < 0 {
+		return nil, fmt.Errorf("instanceMetadata: timeout must not be negative")
+	}
+	if cfg.Metadata.Timeout == 0 {
+		cfg.Metadata.Timeout = DefaultMetadataTimeout
+	}
+
+	for _, path := range cfg.Plugins {
+		if path == "" {
+			return nil, fmt.Errorf("plugins: path must not be empty")
+		}
+	}
+
+	if err := cfg.TargetPolicy.validate(); err != nil {
+		return nil, fmt.Errorf("targetPolicy: %w", err)
+	}
+
+	if _, err := cfg.TLS.ClientConfig(); err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+
+	if (cfg.ServerTLS.CertFile == "") != (cfg.ServerTLS.KeyFile == "") {
+		return nil, fmt.Errorf("serverTls: certFile and keyFile must be set together")
+	}
+	if cfg.ServerTLS.ClientCAFile != "" && !cfg.ServerTLS.Enabled() {
+		return nil, fmt.Errorf("serverTls: clientCAFile requires certFile and keyFile")
+	}
+
+	if cfg.Debug.Enabled && !cfg.Admin.Enabled() {
+		cfg.Admin.ListenAddress = DefaultAdminListenAddress
+	}
+
+	if err := cfg.Notifications.prepare(); err != nil {
+		return nil, err
+	}
+
+	if cfg.StatusPage.Title == "" {
+		cfg.StatusPage.Title = DefaultStatusPageTitle
+	}
+
+	if cfg.Heartbeat.Enabled() {
+		if u, err := url.Parse(cfg.Heartbeat.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("heartbeat.url: must be an absolute http or https URL")
+		}
+		if cfg.Heartbeat.Interval < 0 {
+			return nil, fmt.Errorf("heartbeat.interval must not be negative")
+		}
+		if cfg.Heartbeat.Interval == 0 {
+			cfg.Heartbeat.Interval = DefaultHeartbeatInterval
+		}
+	}
+
+	if cfg.Tracing.Enabled() {
+		if u, err := url.Parse(cfg.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("tracing.endpoint: must be an absolute http or https URL")
+		}
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			return nil, fmt.Errorf("tracing.sampleRatio must be between 0 and 1")
+		}
+		if cfg.Tracing.SampleRatio == 0 {
+			cfg.Tracing.SampleRatio = 1
+		}
+	}
+
+	if cfg.Graphite.Enabled {
+		if cfg.Graphite.Host == "" {
+			return nil, fmt.Errorf("graphite sink enabled but no host specified")
+		}
+		if cfg.Graphite.Port == 0 {
+			cfg.Graphite.Port = 2003
+		}
+		if cfg.Graphite.Prefix == "" {
+			cfg.Graphite.Prefix = "url_exporter"
+		}
+		if cfg.Graphite.FlushInterval <= 0 {
+			cfg.Graphite.FlushInterval = cfg.CheckInterval
+		}
+	}
+
+	if cfg.InfluxDB.Enabled {
+		if cfg.InfluxDB.URL == "" || cfg.InfluxDB.Org == "" || cfg.InfluxDB.Bucket == "" {
+			return nil, fmt.Errorf("influxdb sink enabled but url, org or bucket not specified")
+		}
+		if cfg.InfluxDB.Measurement == "" {
+			cfg.InfluxDB.Measurement = "url_check"
+		}
+	}
+
+	if cfg.Kafka.Enabled {
+		if err := cfg.Kafka.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.NATS.Enabled {
+		if err := cfg.NATS.validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
 
+// load reads the bearer token file, if any, and validates the basic auth users
+func (a *AuthConfig) load() error {
+	if a.BearerTokenFile != "" {
+		if a.BearerToken != "" {
+			return fmt.Errorf("auth: bearerToken and bearerTokenFile are mutually exclusive")
+		}
+		content, err := os.ReadFile(a.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("auth: failed to read bearer token file: %w", err)
+		}
+		a.BearerToken = strings.TrimSpace(string(content))
+		if a.BearerToken == "" {
+			return fmt.Errorf("auth: bearer token file %s is empty", a.BearerTokenFile)
+		}
+	}
+
+	for i, user := range a.Users {
+		if user.Username == "" {
+			return fmt.Errorf("auth: user %d has no username", i)
+		}
+		if _, err := bcrypt.Cost([]byte(user.Password)); err != nil {
+			return fmt.Errorf("auth: password of user %s must be a bcrypt hash: %w", user.Username, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateChecks validates the modules and the checks using them. Check names must be
+// unique.
+func (c *Config) ValidateChecks() error {
+	for name, module := range c.Modules {
+		probe := Target{URL: "module " + name, Method: module.Method, ExpectBody: module.ExpectBody, ExpectHeaders: module.ExpectHeaders, ExpectContentType: module.ExpectContentType}
+		if err := probe.Validate(); err != nil {
+			return fmt.Errorf("invalid module %s: %w", name, err)
+		}
+		if _, err := module.TLS.ClientConfig(); err != nil {
+			return fmt.Errorf("invalid module %s: %w", name, err)
+		}
+		if module.GraphQL != nil {
+			if err := module.GraphQL.validate(); err != nil {
+				return fmt.Errorf("invalid module %s: %w", name, err)
+			}
+		}
+		if module.SOAP != nil {
+			if module.GraphQL != nil {
+				return fmt.Errorf("invalid module %s: soap cannot be combined with graphql", name)
+			}
+			if err := module.SOAP.validate(); err != nil {
+				return fmt.Errorf("invalid module %s: %w", name, err)
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, check := range c.Checks {
+		if check.Name != "" {
+			if names[check.Name] {
+				return fmt.Errorf("invalid check %d: duplicate name %q", i, check.Name)
+			}
+			names[check.Name] = true
+		}
+
+		resolved, err := c.ResolveModule(check)
+		if err == nil {
+			err = resolved.Validate()
+		}
+		if err == nil {
+			err = c.ValidateTenant(check)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid check %d: %w", i, err)
+		}
+	}
+
+	if err := ValidateDependencies(c.AllTargets()); err != nil {
+		return fmt.Errorf("invalid dependsOn: %w", err)
+	}
+	return validateComposites(c.Composites, c.AllTargets())
+}
+
+// Validate checks that the target has a URL, a supported method, valid assertion patterns
+// and a sane objective
+func (t Target) Validate() error {
+	if t.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	switch t.Method {
+	case "", http.MethodHead, http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		return fmt.Errorf("invalid method %q for %s", t.Method, RedactURL(t.URL))
+	}
+
+	if t.ExpectBody != "" && t.Method == http.MethodHead {
+		return fmt.Errorf("expectBody for %s cannot be used with the HEAD method", RedactURL(t.URL))
+	}
+
+	if t.MinBodyBytes < 0 || t.MaxBodyBytes < 0 {
+		return fmt.Errorf("minBodyBytes and maxBodyBytes for %s must not be negative", RedactURL(t.URL))
+	}
+	if t.MaxBodyBytes > 0 && t.MinBodyBytes > t.MaxBodyBytes {
+		return fmt.Errorf("minBodyBytes for %s must not exceed maxBodyBytes", RedactURL(t.URL))
+	}
+	if t.HasBodySizeBounds() && t.Method == http.MethodHead {
+		return fmt.Errorf("minBodyBytes and maxBodyBytes for %s cannot be used with the HEAD method", RedactURL(t.URL))
+	}
+	if t.FailOnBodySize && !t.HasBodySizeBounds() {
+		return fmt.Errorf("failOnBodySize for %s requires minBodyBytes or maxBodyBytes", RedactURL(t.URL))
+	}
+
+	if t.ExpectBody != "" {
+		if _, err := regexp.Compile(t.ExpectBody); err != nil {
+			return fmt.Errorf("invalid expectBody for %s: %w", RedactURL(t.URL), err)
+		}
+	}
+
+	for header, pattern := range t.ExpectHeaders {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid expectHeaders[%s] for %s: %w", header, RedactURL(t.URL), err)
+		}
+	}
+
+	if t.ExpectContentType != "" {
+		if _, _, err := mime.ParseMediaType(t.ExpectContentType); err != nil {
+			return fmt.Errorf("invalid expectContentType %q for %s: %w", t.ExpectContentType, RedactURL(t.URL), err)
+		}
+	}
+
+	if t.RunbookURL != "" {
+		if u, err := url.Parse(t.RunbookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid runbookUrl %q for %s: must be an http or https URL", t.RunbookURL, RedactURL(t.URL))
+		}
+	}
+
+	if t.Objective < 0 || t.Objective >= 1 {
+		return fmt.Errorf("invalid objective for %s: must be between 0 and 1 (e.g. 0.999)", RedactURL(t.URL))
+	}
+
+	if t.Interval != "" {
+		if interval, err := time.ParseDuration(t.Interval); err != nil || interval <= 0 {
+			return fmt.Errorf("invalid interval %q for %s: must be a positive duration, e.g. 5m", t.Interval, RedactURL(t.URL))
+		}
+	}
+
+	if err := t.Source.validate(); err != nil {
+		return fmt.Errorf("%w for %s", err, RedactURL(t.URL))
+	}
+
+	if err := t.Alerts.validate(RedactURL(t.URL)); err != nil {
+		return err
+	}
+
+	if err := t.validateSteps(); err != nil {
+		return err
+	}
+
+	if err := t.validateGraphQL(); err != nil {
+		return err
+	}
+
+	if err := t.validateSOAP(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func loadConfigFile() (string, error) {
 	if configPath := os.Getenv("URL_CONFIG_FILE"); configPath != "" {
 		log.Debug().
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -587,6 +589,2726 @@ func TestConfig_TargetsValidation(t *testing.T) {
 
 // Helper functions
 
+func TestLoad_GraphiteDefaults(t *testing.T) {
+	clearEnv(t)
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+targets:
+  - "https://example.com"
+checkInterval: 45s
+graphite:
+  enabled: true
+  host: "graphite.local"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("URL_CONFIG_FILE", configPath)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Graphite.Host != "graphite.local" {
+		t.Errorf("Graphite.Host: expected %q, got %q", "graphite.local", cfg.Graphite.Host)
+	}
+
+	if cfg.Graphite.Port != 2003 {
+		t.Errorf("Graphite.Port: expected %d, got %d", 2003, cfg.Graphite.Port)
+	}
+
+	if cfg.Graphite.Prefix != "url_exporter" {
+		t.Errorf("Graphite.Prefix: expected %q, got %q", "url_exporter", cfg.Graphite.Prefix)
+	}
+
+	if cfg.Graphite.FlushInterval != 45*time.Second {
+		t.Errorf("Graphite.FlushInterval: expected %v, got %v", 45*time.Second, cfg.Graphite.FlushInterval)
+	}
+}
+
+func TestLoad_GraphiteWithoutHostError(t *testing.T) {
+	clearEnv(t)
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+targets:
+  - "https://example.com"
+graphite:
+  enabled: true
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("URL_CONFIG_FILE", configPath)
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected error when graphite is enabled without host")
+	}
+
+	if !strings.Contains(err.Error(), "graphite sink enabled but no host specified") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoad_InfluxDBValidation(t *testing.T) {
+	clearEnv(t)
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+targets:
+  - "https://example.com"
+influxdb:
+  enabled: true
+  url: "http://influxdb:8086"
+  org: "my-org"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("URL_CONFIG_FILE", configPath)
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected error when influxdb is enabled without bucket")
+	}
+
+	if !strings.Contains(err.Error(), "influxdb sink enabled") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoad_InfluxDBDefaults(t *testing.T) {
+	clearEnv(t)
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+targets:
+  - "https://example.com"
+influxdb:
+  enabled: true
+  url: "http://influxdb:8086"
+  org: "my-org"
+  bucket: "probes"
+  token: "secret"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("URL_CONFIG_FILE", configPath)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.InfluxDB.Measurement != "url_check" {
+		t.Errorf("InfluxDB.Measurement: expected %q, got %q", "url_check", cfg.InfluxDB.Measurement)
+	}
+
+	if cfg.InfluxDB.Token != "secret" {
+		t.Errorf("InfluxDB.Token: expected %q, got %q", "secret", cfg.InfluxDB.Token)
+	}
+}
+
+func TestLoad_WithChecks(t *testing.T) {
+	clearEnv(t)
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+targets:
+  - "https://example.com"
+checks:
+  - url: "https://api.example.com/health"
+    expectBody: '"status":\s*"ok"'
+    expectHeaders:
+      Content-Type: "application/json"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("URL_CONFIG_FILE", configPath)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if len(cfg.Checks) != 1 {
+		t.Fatalf("Expected 1 check, got %d", len(cfg.Checks))
+	}
+
+	check := cfg.Checks[0]
+	if check.URL != "https://api.example.com/health" {
+		t.Errorf("Check URL: expected %q, got %q", "https://api.example.com/health", check.URL)
+	}
+
+	if check.ExpectBody != `"status":\s*"ok"` {
+		t.Errorf("Check ExpectBody: got %q", check.ExpectBody)
+	}
+
+	if check.ExpectHeaders["content-type"] != "application/json" {
+		t.Errorf("Check ExpectHeaders: got %v", check.ExpectHeaders)
+	}
+
+	if !check.HasAssertions() {
+		t.Error("Check should report assertions")
+	}
+
+	if len(cfg.AllTargets()) != 2 {
+		t.Errorf("Expected 2 targets in total, got %d", len(cfg.AllTargets()))
+	}
+}
+
+func TestLoad_ChecksOnly(t *testing.T) {
+	clearEnv(t)
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+targets: []
+checks:
+  - url: "https://api.example.com/health"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("URL_CONFIG_FILE", configPath)
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+}
+
+func TestLoad_InvalidCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name: "missing url",
+			content: `
+checks:
+  - expectBody: "ok"
+`,
+			errMsg: "url is required",
+		},
+		{
+			name: "invalid body pattern",
+			content: `
+checks:
+  - url: "https://example.com"
+    expectBody: "("
+`,
+			errMsg: "invalid expectBody",
+		},
+		{
+			name: "invalid header pattern",
+			content: `
+checks:
+  - url: "https://example.com"
+    expectHeaders:
+      x-test: "["
+`,
+			errMsg: "invalid expectHeaders[x-test]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			t.Setenv("URL_CONFIG_FILE", configPath)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Expected error for invalid check")
+			}
+
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestConfig_AllTargets(t *testing.T) {
+	cfg := &Config{
+		Targets: []string{"https://a.example.com", "https://b.example.com"},
+		Checks: []Target{
+			{URL: "https://b.example.com", ExpectBody: "ok"},
+			{URL: "https://c.example.com"},
+		},
+	}
+
+	targets := cfg.AllTargets()
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, got %d", len(targets))
+	}
+
+	expected := []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"}
+	for i, url := range expected {
+		if targets[i].URL != url {
+			t.Errorf("Target %d: expected %q, got %q", i, url, targets[i].URL)
+		}
+	}
+
+	if targets[1].ExpectBody != "ok" {
+		t.Error("Structured check should replace the plain target with the same URL")
+	}
+}
+
+func TestLoad_SLODefaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	expected := []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour}
+	if len(cfg.SLO.Windows) != len(expected) {
+		t.Fatalf("SLO.Windows: expected %v, got %v", expected, cfg.SLO.Windows)
+	}
+	for i, window := range expected {
+		if cfg.SLO.Windows[i] != window {
+			t.Errorf("SLO.Windows[%d]: expected %v, got %v", i, window, cfg.SLO.Windows[i])
+		}
+	}
+
+	if cfg.SLO.Period != 720*time.Hour {
+		t.Errorf("SLO.Period: expected %v, got %v", 720*time.Hour, cfg.SLO.Period)
+	}
+}
+
+func TestLoad_SLOValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name: "objective out of range",
+			content: `
+checks:
+  - url: "https://example.com"
+    objective: 99.9
+`,
+			errMsg: "invalid objective",
+		},
+		{
+			name: "window longer than period",
+			content: `
+targets: ["https://example.com"]
+slo:
+  windows: [1h, 48h]
+  period: 24h
+`,
+			errMsg: "invalid slo window 48h0m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			t.Setenv("URL_CONFIG_FILE", configPath)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ProbeMode(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.ProbeMode != ProbeModeInterval {
+		t.Errorf("ProbeMode: expected %q, got %q", ProbeModeInterval, cfg.ProbeMode)
+	}
+
+	if cfg.ScrapeTimeout != 10*time.Second {
+		t.Errorf("ScrapeTimeout: expected %v, got %v", 10*time.Second, cfg.ScrapeTimeout)
+	}
+
+	t.Setenv("URL_PROBEMODE", "scrape")
+	t.Setenv("URL_SCRAPETIMEOUT", "5s")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.ProbeMode != ProbeModeScrape {
+		t.Errorf("ProbeMode: expected %q, got %q", ProbeModeScrape, cfg.ProbeMode)
+	}
+
+	if cfg.ScrapeTimeout != 5*time.Second {
+		t.Errorf("ScrapeTimeout: expected %v, got %v", 5*time.Second, cfg.ScrapeTimeout)
+	}
+
+	t.Setenv("URL_PROBEMODE", "push")
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "invalid probeMode") {
+		t.Errorf("Expected invalid probeMode error, got: %v", err)
+	}
+}
+
+func TestLoad_MetricsDisabled(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets:
+  - "https://example.com"
+metrics:
+  disabled:
+    - url_check_total
+    - url_status_code_total
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	expected := []string{"url_check_total", "url_status_code_total"}
+	if !reflect.DeepEqual(cfg.Metrics.Disabled, expected) {
+		t.Errorf("Metrics.Disabled: expected %v, got %v", expected, cfg.Metrics.Disabled)
+	}
+}
+
+func TestLoad_CheckGroupAndLabels(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `checks:
+  - url: "https://api.example.com/health"
+    group: "api"
+    labels:
+      team: "payments"
+      env: "prod"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	check := cfg.Checks[0]
+	if check.Group != "api" {
+		t.Errorf("Group: expected %q, got %q", "api", check.Group)
+	}
+
+	expected := map[string]string{"team": "payments", "env": "prod"}
+	if !reflect.DeepEqual(check.Labels, expected) {
+		t.Errorf("Labels: expected %v, got %v", expected, check.Labels)
+	}
+}
+
+func TestTarget_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{"valid", Target{URL: "https://example.com", Method: "POST", ExpectBody: "ok", Objective: 0.99}, ""},
+		{"missing url", Target{}, "url is required"},
+		{"invalid method", Target{URL: "https://example.com", Method: "TRACE"}, "invalid method"},
+		{"body with head", Target{URL: "https://example.com", Method: "HEAD", ExpectBody: "ok"}, "cannot be used with the HEAD method"},
+		{"invalid body regex", Target{URL: "https://example.com", ExpectBody: "("}, "invalid expectBody"},
+		{"invalid objective", Target{URL: "https://example.com", Objective: 1}, "invalid objective"},
+		{"runbook", Target{URL: "https://example.com", Description: "Checkout API", RunbookURL: "https://wiki.example.com/runbooks/checkout"}, ""},
+		{"invalid runbook", Target{URL: "https://example.com", RunbookURL: "wiki/runbooks/checkout"}, "invalid runbookUrl"},
+		{"content type", Target{URL: "https://example.com", ExpectContentType: "application/json"}, ""},
+		{"invalid content type", Target{URL: "https://example.com", ExpectContentType: "json; charset"}, "invalid expectContentType"},
+		{"body size bounds", Target{URL: "https://example.com", MinBodyBytes: 1024, FailOnBodySize: true}, ""},
+		{"negative body size", Target{URL: "https://example.com", MaxBodyBytes: -1}, "must not be negative"},
+		{"inverted body size bounds", Target{URL: "https://example.com", MinBodyBytes: 2048, MaxBodyBytes: 1024}, "must not exceed maxBodyBytes"},
+		{"body size with head", Target{URL: "https://example.com", Method: "HEAD", MinBodyBytes: 1}, "cannot be used with the HEAD method"},
+		{"fail without bounds", Target{URL: "https://example.com", FailOnBodySize: true}, "requires minBodyBytes or maxBodyBytes"},
+		{"graphql", Target{URL: "https://example.com/graphql", GraphQL: &GraphQLConfig{Query: "{ health }", Variables: `{"deep": true}`, Expect: []GraphQLExpectation{{Path: "data.health", Value: "^OK$"}}}}, ""},
+		{"graphql without query", Target{URL: "https://example.com/graphql", GraphQL: &GraphQLConfig{}}, "graphql query is required"},
+		{"graphql tcp", Target{URL: "tcp://example.com:80", GraphQL: &GraphQLConfig{Query: "{ health }"}}, "graphql needs an http or https url"},
+		{"graphql with get", Target{URL: "https://example.com/graphql", Method: "GET", GraphQL: &GraphQLConfig{Query: "{ health }"}}, "cannot be used with the GET method"},
+		{"graphql variables", Target{URL: "https://example.com/graphql", GraphQL: &GraphQLConfig{Query: "{ health }", Variables: "[1]"}}, "graphql variables must be a JSON object"},
+		{"soap", Target{URL: "https://example.com/ws", SOAP: &SOAPConfig{Version: SOAP12, Action: "urn:Ping", Body: "<Ping/>"}}, ""},
+		{"soap version", Target{URL: "https://example.com/ws", SOAP: &SOAPConfig{Version: "2.0"}}, "invalid soap version"},
+		{"soap body", Target{URL: "https://example.com/ws", SOAP: &SOAPConfig{Body: "<Ping>"}}, "invalid soap body"},
+		{"soap with get", Target{URL: "https://example.com/ws", Method: "GET", SOAP: &SOAPConfig{Body: "<Ping/>"}}, "cannot be used with the GET method"},
+		{"soap with graphql", Target{URL: "https://example.com/ws", SOAP: &SOAPConfig{Body: "<Ping/>"}, GraphQL: &GraphQLConfig{Query: "{ health }"}}, "cannot be combined with steps or graphql"},
+		{"graphql expectation", Target{URL: "https://example.com/graphql", GraphQL: &GraphQLConfig{Query: "{ health }", Expect: []GraphQLExpectation{{Path: "data", Value: "("}}}}, "invalid graphql expectation data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.target.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTarget_ValidateSteps(t *testing.T) {
+	login := Step{Name: "login", Method: "POST", Extract: map[string]StepExtract{"token": {JSON: "data.token"}}}
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantErr string
+	}{
+		{"valid", []Step{login, {Name: "api", URL: "/api?token=${token}", Headers: map[string]string{"Authorization": "Bearer ${token}"}}}, ""},
+		{"missing name", []Step{{URL: "/login"}}, "step 1 of https://example.com has no name"},
+		{"duplicate name", []Step{login, {Name: "login"}}, `duplicate step "login"`},
+		{"invalid method", []Step{{Name: "login", Method: "TRACE"}}, "invalid method"},
+		{"undefined variable", []Step{{Name: "api", Body: `{"token":"${token}"}`}, login}, "uses ${token}, which no earlier step extracts"},
+		{"invalid body regex", []Step{{Name: "api", ExpectBody: "("}}, "invalid expectBody for step api"},
+		{"json with regex", []Step{{Name: "login", Extract: map[string]StepExtract{"token": {JSON: "token", Regex: "."}}}}, "json cannot be combined"},
+		{"missing extraction", []Step{{Name: "login", Extract: map[string]StepExtract{"token": {Header: "X-Token"}}}}, "json or regex is required"},
+		{"invalid variable name", []Step{{Name: "login", Extract: map[string]StepExtract{"the-token": {JSON: "token"}}}}, "invalid variable name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Target{URL: "https://example.com", Steps: tt.steps}.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := (Target{URL: "redis://localhost:6379", Steps: []Step{login}}).Validate(); err == nil {
+		t.Error("Expected an error for steps of a non-HTTP target")
+	}
+}
+
+func TestExpandVariables(t *testing.T) {
+	got := ExpandVariables("Bearer ${token} for ${user}", map[string]string{"token": "abc"})
+	if got != "Bearer abc for ${user}" {
+		t.Errorf("ExpandVariables(): got %q", got)
+	}
+}
+
+func TestLoad_Modules(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `modules:
+  http_json:
+    method: GET
+    expectBody: '"status":"ok"'
+    expectHeaders:
+      Content-Type: "json"
+    expectContentType: application/json
+  HTTPS_Insecure:
+    tls:
+      insecureSkipVerify: true
+      serverName: "internal.example.com"
+checks:
+  - url: "https://api.example.com/health"
+    module: "http_json"
+  - url: "https://internal.example.com"
+    module: "HTTPS_Insecure"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	module, ok := cfg.Module("http_json")
+	if !ok {
+		t.Fatal("Expected module http_json to exist")
+	}
+	if module.Method != "GET" {
+		t.Errorf("Method: expected GET, got %q", module.Method)
+	}
+
+	insecure, ok := cfg.Module("HTTPS_Insecure")
+	if !ok {
+		t.Fatal("Expected module lookup to be case-insensitive")
+	}
+	if !insecure.TLS.InsecureSkipVerify || insecure.TLS.ServerName != "internal.example.com" {
+		t.Errorf("Unexpected TLS options: %+v", insecure.TLS)
+	}
+
+	resolved, err := cfg.ResolveModule(cfg.Checks[0])
+	if err != nil {
+		t.Fatalf("ResolveModule() failed: %v", err)
+	}
+	if resolved.Method != "GET" || resolved.ExpectBody != `"status":"ok"` || resolved.ExpectHeaders["content-type"] != "json" || resolved.ExpectContentType != "application/json" {
+		t.Errorf("Module settings not applied: %+v", resolved)
+	}
+
+	if _, ok := cfg.Module(DefaultModule); !ok {
+		t.Error("Expected the default module to always exist")
+	}
+}
+
+func TestResolveModule_TargetOverrides(t *testing.T) {
+	cfg := &Config{
+		Modules: map[string]Module{
+			"http_post": {Method: "POST", ExpectBody: "accepted"},
+		},
+	}
+
+	resolved, err := cfg.ResolveModule(Target{URL: "https://example.com", Module: "http_post", ExpectBody: "created"})
+	if err != nil {
+		t.Fatalf("ResolveModule() failed: %v", err)
+	}
+	if resolved.Method != "POST" {
+		t.Errorf("Method: expected POST, got %q", resolved.Method)
+	}
+	if resolved.ExpectBody != "created" {
+		t.Errorf("ExpectBody: expected target value, got %q", resolved.ExpectBody)
+	}
+
+	if _, err := cfg.ResolveModule(Target{URL: "https://example.com", Module: "missing"}); err == nil {
+		t.Error("Expected error for unknown module")
+	}
+}
+
+func TestResolveModule_SecurityHeaders(t *testing.T) {
+	cfg := &Config{
+		Modules: map[string]Module{
+			"https_audit": {Method: "GET", SecurityHeaders: true},
+		},
+	}
+
+	for _, module := range []string{"https_audit", "HTTP_Security_Headers"} {
+		resolved, err := cfg.ResolveModule(Target{URL: "https://example.com", Module: module})
+		if err != nil {
+			t.Fatalf("ResolveModule(%s) failed: %v", module, err)
+		}
+		if !resolved.SecurityHeaders {
+			t.Errorf("ResolveModule(%s): expected the security header audit", module)
+		}
+	}
+
+	resolved, err := cfg.ResolveModule(Target{URL: "https://example.com", Module: DefaultModule})
+	if err != nil {
+		t.Fatalf("ResolveModule() failed: %v", err)
+	}
+	if resolved.SecurityHeaders {
+		t.Error("Expected no security header audit for the default module")
+	}
+}
+
+func TestLoad_InvalidModule(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "unknown module",
+			content: `checks:
+  - url: "https://example.com"
+    module: "missing"
+`,
+			wantErr: `unknown module "missing"`,
+		},
+		{
+			name: "invalid module regex",
+			content: `targets: ["https://example.com"]
+modules:
+  broken:
+    expectBody: "("
+`,
+			wantErr: "invalid module broken",
+		},
+		{
+			name: "missing CA file",
+			content: `targets: ["https://example.com"]
+modules:
+  custom_ca:
+    tls:
+      caFile: "/nonexistent/ca.pem"
+`,
+			wantErr: "failed to read CA file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			t.Setenv("URL_CONFIG_FILE", configFile)
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Auth(t *testing.T) {
+	clearEnv(t)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("token-from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	configFile := filepath.Join(dir, "config.yaml")
+	content := `targets: ["https://example.com"]
+auth:
+  bearerTokenFile: "` + tokenFile + `"
+  users:
+    - username: "Prometheus"
+      password: "$2a$04$4GyuNnpabqyfUZbqtpHmCuZJbXm4X7JBfn7lExyb0Nv5fIbfgJB9e"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Auth.BearerToken != "token-from-file" {
+		t.Errorf("BearerToken: expected token from file, got %q", cfg.Auth.BearerToken)
+	}
+	if len(cfg.Auth.Users) != 1 || cfg.Auth.Users[0].Username != "Prometheus" {
+		t.Errorf("Users: unexpected %+v", cfg.Auth.Users)
+	}
+	if !cfg.Auth.Enabled() {
+		t.Error("Expected auth to be enabled")
+	}
+}
+
+func TestLoad_InvalidAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    string
+		wantErr string
+	}{
+		{
+			name: "plain text password",
+			auth: `  users:
+    - username: "prometheus"
+      password: "secret"
+`,
+			wantErr: "must be a bcrypt hash",
+		},
+		{
+			name: "token and token file",
+			auth: `  bearerToken: "abc"
+  bearerTokenFile: "/tmp/token"
+`,
+			wantErr: "mutually exclusive",
+		},
+		{
+			name: "missing token file",
+			auth: `  bearerTokenFile: "/nonexistent/token"
+`,
+			wantErr: "failed to read bearer token file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			content := "targets: [\"https://example.com\"]\nauth:\n" + tt.auth
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			t.Setenv("URL_CONFIG_FILE", configFile)
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidServerTLS(t *testing.T) {
+	tests := []struct {
+		name      string
+		serverTLS string
+		wantErr   string
+	}{
+		{
+			name: "certificate without key",
+			serverTLS: `  certFile: "/etc/tls/server.crt"
+`,
+			wantErr: "certFile and keyFile must be set together",
+		},
+		{
+			name: "client CA without certificate",
+			serverTLS: `  clientCAFile: "/etc/tls/ca.crt"
+`,
+			wantErr: "clientCAFile requires certFile and keyFile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			content := "targets: [\"https://example.com\"]\nserverTls:\n" + tt.serverTLS
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			t.Setenv("URL_CONFIG_FILE", configFile)
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_AdminAndDebug(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Debug.Enabled {
+		t.Error("Debug: expected disabled by default")
+	}
+
+	if cfg.Admin.Enabled() {
+		t.Errorf("Admin: expected no admin listener by default, got %q", cfg.Admin.ListenAddress)
+	}
+
+	t.Setenv("URL_DEBUG_ENABLED", "true")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if !cfg.Debug.Enabled {
+		t.Error("Debug: expected enabled")
+	}
+
+	if cfg.Admin.ListenAddress != DefaultAdminListenAddress {
+		t.Errorf("Admin.ListenAddress: expected %q, got %q", DefaultAdminListenAddress, cfg.Admin.ListenAddress)
+	}
+
+	t.Setenv("URL_ADMIN_LISTENADDRESS", "unix:/run/url-exporter/admin.sock")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Admin.ListenAddress != "unix:/run/url-exporter/admin.sock" {
+		t.Errorf("Admin.ListenAddress: expected the configured socket, got %q", cfg.Admin.ListenAddress)
+	}
+}
+
+func TestConfig_Address(t *testing.T) {
+	tests := []struct {
+		listenAddress string
+		want          string
+	}{
+		{listenAddress: "", want: ":8412"},
+		{listenAddress: "127.0.0.1", want: "127.0.0.1:8412"},
+		{listenAddress: "::1", want: "[::1]:8412"},
+		{listenAddress: "[::1]", want: "[::1]:8412"},
+		{listenAddress: "unix:/run/url-exporter.sock", want: "unix:/run/url-exporter.sock"},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{ListenPort: 8412, ListenAddress: tt.listenAddress}
+		if got := cfg.Address(); got != tt.want {
+			t.Errorf("Address() with listenAddress %q: expected %q, got %q", tt.listenAddress, tt.want, got)
+		}
+	}
+}
+
+func TestLoad_ListenAddress(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("URL_LISTENADDRESS", "127.0.0.1")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Address() != "127.0.0.1:8412" {
+		t.Errorf("Address: expected 127.0.0.1:8412, got %q", cfg.Address())
+	}
+
+	t.Setenv("URL_LISTENADDRESS", "127.0.0.1:9000")
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "must not include a port") {
+		t.Errorf("Expected listenAddress port error, got: %v", err)
+	}
+}
+
+func TestLoad_AccessLog(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+accessLog:
+  enabled: true
+  exclude: ["/metrics", "/-/*"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if !cfg.AccessLog.Enabled {
+		t.Error("AccessLog: expected enabled")
+	}
+	if !reflect.DeepEqual(cfg.AccessLog.Exclude, []string{"/metrics", "/-/*"}) {
+		t.Errorf("AccessLog.Exclude: got %v", cfg.AccessLog.Exclude)
+	}
+
+	content = `targets: ["https://example.com"]
+accessLog:
+  include: ["/api/[v1"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "invalid accessLog path pattern") {
+		t.Errorf("Expected invalid pattern error, got: %v", err)
+	}
+}
+
+func TestLoad_APILimits(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.API.RateLimit != 0 || cfg.API.RateBurst != 0 {
+		t.Errorf("API rate limit: expected disabled, got %v/%d", cfg.API.RateLimit, cfg.API.RateBurst)
+	}
+	if cfg.API.MaxBodyBytes != 65536 {
+		t.Errorf("API.MaxBodyBytes: expected 65536, got %d", cfg.API.MaxBodyBytes)
+	}
+
+	content = `targets: ["https://example.com"]
+api:
+  rateLimit: 2.5
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.API.RateBurst != 3 {
+		t.Errorf("API.RateBurst: expected 3, got %d", cfg.API.RateBurst)
+	}
+
+	content = `targets: ["https://example.com"]
+api:
+  maxBodyBytes: -1
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("Expected negative limit error, got: %v", err)
+	}
+}
+
+func TestLoad_HistoryAndNames(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `checks:
+  - url: "https://example.com"
+    name: "web"
+  - url: "https://api.example.com"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Checks[0].Name != "web" {
+		t.Errorf("Checks[0].Name: expected web, got %q", cfg.Checks[0].Name)
+	}
+	if cfg.History.Size != DefaultHistorySize {
+		t.Errorf("History.Size: expected %d, got %d", DefaultHistorySize, cfg.History.Size)
+	}
+
+	content = `checks:
+  - url: "https://example.com"
+    name: "web"
+  - url: "https://api.example.com"
+    name: "web"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), `duplicate name "web"`) {
+		t.Errorf("Expected duplicate name error, got: %v", err)
+	}
+
+	content = `targets: ["https://example.com"]
+history:
+  size: -1
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "history: size") {
+		t.Errorf("Expected history size error, got: %v", err)
+	}
+}
+
+func TestLoad_PagerDuty(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+notifications:
+  pagerduty:
+    routingKey: "default-key"
+    routingKeys:
+      Payments: "payments-key"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	pagerDuty := cfg.Notifications.PagerDuty
+	if !pagerDuty.Enabled() {
+		t.Fatal("PagerDuty: expected enabled")
+	}
+	if pagerDuty.Severity != "critical" || pagerDuty.URL != DefaultPagerDutyURL {
+		t.Errorf("PagerDuty defaults: got severity %q, url %q", pagerDuty.Severity, pagerDuty.URL)
+	}
+	if got := pagerDuty.RoutingKeyFor("Payments"); got != "payments-key" {
+		t.Errorf("RoutingKeyFor(Payments): expected payments-key, got %q", got)
+	}
+	if got := pagerDuty.RoutingKeyFor("web"); got != "default-key" {
+		t.Errorf("RoutingKeyFor(web): expected default-key, got %q", got)
+	}
+
+	content = `targets: ["https://example.com"]
+notifications:
+  pagerduty:
+    routingKey: "default-key"
+    severity: "high"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "invalid severity") {
+		t.Errorf("Expected invalid severity error, got: %v", err)
+	}
+}
+
+func TestLoad_Telegram(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+notifications:
+  telegram:
+    botToken: "123:abc"
+    chatIds: ["-1001234", "@status"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	telegram := cfg.Notifications.Telegram
+	if !telegram.Enabled() || telegram.URL != DefaultTelegramURL {
+		t.Errorf("Telegram: got %+v", telegram)
+	}
+	if !reflect.DeepEqual(telegram.ChatIDs, []string{"-1001234", "@status"}) {
+		t.Errorf("Telegram.ChatIDs: got %v", telegram.ChatIDs)
+	}
+
+	content = `targets: ["https://example.com"]
+notifications:
+  telegram:
+    botToken: "123:abc"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "no chatIds") {
+		t.Errorf("Expected missing chatIds error, got: %v", err)
+	}
+}
+
+func TestLoad_NotificationsExternalURL(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+notifications:
+  externalUrl: "exporter.example.com:8412"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "notifications.externalUrl") {
+		t.Errorf("Expected invalid externalUrl error, got: %v", err)
+	}
+}
+
+func TestLoad_NotificationChannelOptions(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+notifications:
+  telegram:
+    botToken: "123:abc"
+    chatIds: ["42"]
+    groupWait: 2m
+    repeatInterval: 4h
+    sendResolved: false
+  teams:
+    webhookUrl: "https://example.webhook.office.com/webhookb2/x"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	telegram := cfg.Notifications.Telegram.ChannelOptions
+	if telegram.GroupWait != 2*time.Minute || telegram.RepeatInterval != 4*time.Hour || telegram.Resolved() {
+		t.Errorf("Telegram options: got groupWait %v, repeatInterval %v, resolved %v", telegram.GroupWait, telegram.RepeatInterval, telegram.Resolved())
+	}
+	if teams := cfg.Notifications.Teams.ChannelOptions; teams.GroupWait != 0 || !teams.Resolved() {
+		t.Errorf("Teams options: expected defaults, got %+v", teams)
+	}
+
+	content = `targets: ["https://example.com"]
+notifications:
+  teams:
+    webhookUrl: "https://example.webhook.office.com/webhookb2/x"
+    repeatInterval: -1m
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "notifications.teams: groupWait and repeatInterval") {
+		t.Errorf("Expected negative repeatInterval error, got: %v", err)
+	}
+
+	content = `targets: ["https://example.com"]
+notifications:
+  teams:
+    webhookUrl: "https://example.webhook.office.com/webhookb2/x"
+    template: "{{ .Target.URL "
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "notifications.teams: invalid template") {
+		t.Errorf("Expected invalid template error, got: %v", err)
+	}
+}
+
+func TestLoad_NotificationRoutes(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+notifications:
+  teams:
+    webhookUrl: "https://example.webhook.office.com/webhookb2/ops"
+  receivers:
+    Payments:
+      telegram:
+        botToken: "123:abc"
+        chatIds: ["42"]
+      pagerduty:
+        routingKey: "payments-key"
+  route:
+    routes:
+      - match: {team: payments}
+        receiver: payments
+        continue: true
+        routes:
+          - matchRe: {env: "prod|staging"}
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	payments, exists := cfg.Notifications.Receivers["payments"]
+	if !exists {
+		t.Fatalf("Expected payments receiver, got %v", cfg.Notifications.Receivers)
+	}
+	if payments.Telegram.URL != DefaultTelegramURL || payments.PagerDuty.Severity != "critical" {
+		t.Errorf("Expected receiver channel defaults, got %+v", payments)
+	}
+	if cfg.Notifications.Teams.WebhookURL != "https://example.webhook.office.com/webhookb2/ops" {
+		t.Errorf("Expected default receiver teams webhook, got %q", cfg.Notifications.Teams.WebhookURL)
+	}
+
+	routes := cfg.Notifications.Route.Routes
+	if len(routes) != 1 || routes[0].Receiver != "payments" || routes[0].Match["team"] != "payments" || !routes[0].Continue {
+		t.Fatalf("Unexpected routes: %+v", routes)
+	}
+	if len(routes[0].Routes) != 1 || routes[0].Routes[0].MatchRE["env"] != "prod|staging" {
+		t.Errorf("Unexpected nested routes: %+v", routes[0].Routes)
+	}
+
+	invalid := map[string]string{
+		"unknown receiver": `
+  route:
+    routes:
+      - match: {team: payments}
+        receiver: billing
+`,
+		"invalid matchRe for env": `
+  route:
+    routes:
+      - matchRe: {env: "prod("}
+`,
+		"cannot set match": `
+  route:
+    match: {team: payments}
+`,
+		"is reserved": `
+  receivers:
+    default:
+      teams:
+        webhookUrl: "https://example.webhook.office.com/webhookb2/x"
+`,
+		"notifications.receivers.payments.telegram: botToken is set but no chatIds": `
+  receivers:
+    payments:
+      telegram:
+        botToken: "123:abc"
+`,
+	}
+	for expected, notifications := range invalid {
+		content := "targets: [\"https://example.com\"]\nnotifications:" + notifications
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestLoad_Heartbeat(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+heartbeat:
+  url: "https://hc-ping.com/check-uuid"
+  interval: 0s
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.Heartbeat.Enabled() || cfg.Heartbeat.Interval != DefaultHeartbeatInterval {
+		t.Errorf("Expected enabled heartbeat with default interval, got %+v", cfg.Heartbeat)
+	}
+
+	content = `targets: ["https://example.com"]
+heartbeat:
+  url: "hc-ping.com/check-uuid"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "heartbeat.url") || strings.Contains(err.Error(), "check-uuid") {
+		t.Errorf("Expected invalid heartbeat URL error without the URL, got: %v", err)
+	}
+}
+
+func TestLoad_NotificationReports(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+notifications:
+  reports:
+    schedule: weekly
+    sla: 0.995
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	reports := cfg.Notifications.Reports
+	if reports.Time != DefaultReportTime || reports.Weekday != DefaultReportWeekday || !reflect.DeepEqual(reports.Receivers, []string{DefaultReceiver}) {
+		t.Errorf("Expected report defaults, got %+v", reports)
+	}
+	if offset, _ := reports.TimeOfDay(); offset != 8*time.Hour {
+		t.Errorf("Expected 8h time of day, got %v", offset)
+	}
+	if day, _ := reports.DeliveryDay(); day != time.Monday {
+		t.Errorf("Expected monday, got %v", day)
+	}
+
+	invalid := map[string]string{
+		"invalid schedule": "schedule: hourly",
+		"invalid time":     "schedule: daily\n    time: \"8am\"",
+		"invalid weekday":  "schedule: weekly\n    weekday: someday",
+		"sla must be":      "schedule: daily\n    sla: 99.9",
+		"unknown receiver": "schedule: daily\n    receivers: [payments]",
+	}
+	for expected, reports := range invalid {
+		content := "targets: [\"https://example.com\"]\nnotifications:\n  reports:\n    " + reports + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+tracing:
+  endpoint: "http://otel-collector:4318"
+  headers:
+    api-key: "secret"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.Tracing.Enabled() || cfg.Tracing.SampleRatio != 1 || cfg.Tracing.Headers["api-key"] != "secret" {
+		t.Errorf("Expected enabled tracing with the default sample ratio, got %+v", cfg.Tracing)
+	}
+
+	invalid := map[string]string{
+		"tracing.endpoint":    "tracing:\n  endpoint: \"otel-collector:4318\"\n",
+		"tracing.sampleRatio": "tracing:\n  endpoint: \"http://otel-collector:4318\"\n  sampleRatio: 1.5\n",
+	}
+	for expected, tracing := range invalid {
+		if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\n"+tracing), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %s error, got: %v", expected, err)
+		}
+	}
+}
+
+func TestLoad_LogFormat(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(`targets: ["https://example.com"]`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.LogFormat != LogFormatConsole {
+		t.Errorf("Expected default log format %q, got %q", LogFormatConsole, cfg.LogFormat)
+	}
+
+	content := `targets: ["https://example.com"]
+logFormat: json
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.LogFormat != LogFormatJSON {
+		t.Errorf("Expected log format %q, got %q", LogFormatJSON, cfg.LogFormat)
+	}
+
+	content = `targets: ["https://example.com"]
+logFormat: logfmt
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "logFormat") {
+		t.Errorf("Expected invalid logFormat error, got: %v", err)
+	}
+}
+
+func TestConfig_DebugLogging(t *testing.T) {
+	cfg := &Config{DebugTargets: []string{"https://flaky.example.com", "checkout", "payments"}}
+
+	tests := []struct {
+		target   Target
+		expected bool
+	}{
+		{Target{URL: "https://flaky.example.com"}, true},
+		{Target{URL: "https://shop.example.com", Name: "checkout"}, true},
+		{Target{URL: "https://pay.example.com", Group: "payments"}, true},
+		{Target{URL: "https://example.com", Name: "web", Group: "frontend"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.DebugLogging(tt.target); got != tt.expected {
+			t.Errorf("DebugLogging(%+v) = %v, expected %v", tt.target, got, tt.expected)
+		}
+	}
+
+	if (&Config{}).DebugLogging(Target{URL: "https://flaky.example.com"}) {
+		t.Error("Expected no debug logging without debugTargets")
+	}
+}
+
+func TestLoad_AuditLog(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+auditLog:
+  file: /var/log/url-exporter/audit.jsonl
+  maxBackups: 7
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.AuditLog.Enabled() {
+		t.Error("Expected audit log to be enabled")
+	}
+	if cfg.AuditLog.MaxBytes != DefaultAuditLogMaxBytes {
+		t.Errorf("Expected default max bytes %d, got %d", DefaultAuditLogMaxBytes, cfg.AuditLog.MaxBytes)
+	}
+	if cfg.AuditLog.MaxAge != DefaultAuditLogMaxAge {
+		t.Errorf("Expected default max age %v, got %v", DefaultAuditLogMaxAge, cfg.AuditLog.MaxAge)
+	}
+	if cfg.AuditLog.MaxBackups != 7 {
+		t.Errorf("Expected 7 backups, got %d", cfg.AuditLog.MaxBackups)
+	}
+
+	content = `targets: ["https://example.com"]
+auditLog:
+  file: /var/log/url-exporter/audit.jsonl
+  maxAge: -1h
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "auditLog") {
+		t.Errorf("Expected invalid auditLog error, got: %v", err)
+	}
+}
+
+func TestLoad_Syslog(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `targets: ["https://example.com"]
+syslog:
+  address: syslog.example.com:514
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.Syslog.Enabled() {
+		t.Error("Expected syslog to be enabled")
+	}
+	if cfg.Syslog.Protocol != SyslogProtocolUDP {
+		t.Errorf("Expected default protocol %q, got %q", SyslogProtocolUDP, cfg.Syslog.Protocol)
+	}
+	if cfg.Syslog.FacilityCode() != 3 {
+		t.Errorf("Expected daemon facility code 3, got %d", cfg.Syslog.FacilityCode())
+	}
+
+	invalid := map[string]string{
+		"address":  "address: syslog.example.com",
+		"protocol": "address: syslog.example.com:514\n  protocol: http",
+		"facility": "address: syslog.example.com:514\n  facility: local9",
+	}
+	for name, syslog := range invalid {
+		content := "targets: [\"https://example.com\"]\nsyslog:\n  " + syslog + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "syslog") {
+			t.Errorf("Expected invalid syslog %s error, got: %v", name, err)
+		}
+	}
+}
+
+func TestLoad_Sharding(t *testing.T) {
+	clearEnv(t)
+
+	var targets []string
+	for i := 0; i < 100; i++ {
+		targets = append(targets, fmt.Sprintf("https://service-%d.example.com", i))
+	}
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	load := func(shardIndex, shardTotal int) (*Config, error) {
+		content := fmt.Sprintf("targets: [\"%s\"]\nchecks:\n  - url: https://service-0.example.com\n    name: first\nshardIndex: %d\nshardTotal: %d\n",
+			strings.Join(targets, `", "`), shardIndex, shardTotal)
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load()
+	}
+
+	seen := make(map[string]int)
+	for index := 0; index < 3; index++ {
+		cfg, err := load(index, 3)
+		if err != nil {
+			t.Fatalf("Failed to load shard %d: %v", index, err)
+		}
+		if len(cfg.Targets) < 20 {
+			t.Errorf("Expected shard %d to get about a third of the targets, got %d", index, len(cfg.Targets))
+		}
+		for _, target := range cfg.AllTargets() {
+			seen[target.URL]++
+			if !cfg.InShard(target.URL) {
+				t.Errorf("Shard %d got target %s of another shard", index, target.URL)
+			}
+			if target.URL == "https://service-0.example.com" && target.Name != "first" {
+				t.Errorf("Expected the check to replace its plain target in shard %d", index)
+			}
+		}
+	}
+	if len(seen) != len(targets) {
+		t.Errorf("Expected every target in a shard, got %d of %d", len(seen), len(targets))
+	}
+	for url, count := range seen {
+		if count != 1 {
+			t.Errorf("Expected %s in exactly one shard, got %d", url, count)
+		}
+	}
+
+	cfg, err := load(0, 0)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Sharded() || len(cfg.Targets) != len(targets) {
+		t.Errorf("Expected all %d targets without sharding, got %d", len(targets), len(cfg.Targets))
+	}
+
+	for _, invalid := range [][2]int{{3, 3}, {-2, 3}, {1, 1}, {0, -1}} {
+		if _, err := load(invalid[0], invalid[1]); err == nil || !strings.Contains(err.Error(), "shard") {
+			t.Errorf("Expected shard error for index %d of %d, got: %v", invalid[0], invalid[1], err)
+		}
+	}
+}
+
+func TestShardOf_MinimalMovement(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		url := fmt.Sprintf("https://service-%d.example.com", i)
+		before, after := shardOf(url, 3), shardOf(url, 4)
+		// Adding a shard only moves targets to the new shard
+		if before != after && after != 3 {
+			t.Errorf("Expected %s to stay in shard %d or move to shard 3, got %d", url, before, after)
+		}
+	}
+}
+
+func TestHostnameOrdinal(t *testing.T) {
+	if index, err := hostnameOrdinal("url-exporter-12"); err != nil || index != 12 {
+		t.Errorf("Expected ordinal 12, got %d (%v)", index, err)
+	}
+	if _, err := hostnameOrdinal("url-exporter"); err == nil {
+		t.Error("Expected an error for a hostname without ordinal")
+	}
+}
+
+func TestLoad_LeaderElection(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	load := func(content string) (*Config, error) {
+		if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\n"+content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load()
+	}
+
+	cfg, err := load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Leader.Enabled() {
+		t.Error("Expected leader election to be disabled by default")
+	}
+
+	cfg, err = load("leaderElection:\n  mode: kubernetes\n")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Leader.Lease != DefaultLeaseName || cfg.Leader.LeaseDuration != DefaultLeaseDuration || cfg.Leader.RetryPeriod != DefaultRetryPeriod {
+		t.Errorf("Expected defaults to be applied, got %+v", cfg.Leader)
+	}
+
+	cfg, err = load("leaderElection:\n  mode: file\n  file: /shared/leader.lock\n  leaseDuration: 30s\n  retryPeriod: 5s\n")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Leader.File != "/shared/leader.lock" || cfg.Leader.LeaseDuration != 30*time.Second || cfg.Leader.RetryPeriod != 5*time.Second {
+		t.Errorf("Unexpected leader election config %+v", cfg.Leader)
+	}
+
+	for _, invalid := range []string{
+		"leaderElection:\n  mode: zookeeper\n",
+		"leaderElection:\n  mode: file\n",
+		"leaderElection:\n  mode: kubernetes\n  leaseDuration: 2s\n  retryPeriod: 2s\n",
+		"leaderElection:\n  mode: kubernetes\n  retryPeriod: -1s\n",
+	} {
+		if _, err := load(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestLoad_DNSCache(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	load := func(content string) (*Config, error) {
+		content = "targets: [\"https://example.com\"]\nchecks:\n  - url: https://geo.example.com\n    noDnsCache: true\n" + content
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load()
+	}
+
+	cfg, err := load("dnsCache:\n  enabled: true\n  minTTL: 10s\n")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.DNSCache.Enabled || cfg.DNSCache.MinTTL != 10*time.Second || cfg.DNSCache.MaxTTL != DefaultDNSCacheMaxTTL {
+		t.Errorf("Unexpected DNS cache config %+v", cfg.DNSCache)
+	}
+	if len(cfg.Checks) != 1 || !cfg.Checks[0].NoDNSCache {
+		t.Errorf("Expected noDnsCache to be set on the check, got %+v", cfg.Checks)
+	}
+
+	for _, invalid := range []string{
+		"dnsCache:\n  minTTL: -1s\n",
+		"dnsCache:\n  minTTL: 10m\n  maxTTL: 1m\n",
+	} {
+		if _, err := load(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestLoad_Connections(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := `targets: ["https://example.com"]
+checks:
+  - url: https://fresh.example.com
+    keepAlive: false
+connections:
+  maxConnsPerHost: 8
+  idleConnTimeout: 30s
+  disableKeepAlives: true
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Connections.MaxConnsPerHost != 8 || cfg.Connections.IdleConnTimeout != 30*time.Second || !cfg.Connections.DisableKeepAlives {
+		t.Errorf("Unexpected connections config %+v", cfg.Connections)
+	}
+	if cfg.KeepAlive(Target{URL: "https://example.com"}) {
+		t.Error("Expected disableKeepAlives to apply to targets without keepAlive")
+	}
+	if len(cfg.Checks) != 1 || cfg.Checks[0].KeepAlive == nil || *cfg.Checks[0].KeepAlive {
+		t.Errorf("Expected keepAlive false on the check, got %+v", cfg.Checks)
+	}
+
+	keepAlive := true
+	if !cfg.KeepAlive(Target{URL: "https://example.com", KeepAlive: &keepAlive}) {
+		t.Error("Expected the target's keepAlive to override disableKeepAlives")
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nconnections:\n  maxConnsPerHost: -1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a negative maxConnsPerHost")
+	}
+}
+
+func TestLoad_TargetInterval(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := `targets: ["https://example.com"]
+checkInterval: 30s
+checks:
+  - url: https://batch.example.com
+    interval: 5m
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	intervals := make(map[string]time.Duration)
+	for _, target := range cfg.AllTargets() {
+		intervals[target.URL] = cfg.TargetInterval(target)
+	}
+	if intervals["https://example.com"] != 30*time.Second || intervals["https://batch.example.com"] != 5*time.Minute {
+		t.Errorf("Unexpected target intervals %v", intervals)
+	}
+
+	for _, interval := range []string{"soon", "0s", "-1m"} {
+		if err := (Target{URL: "https://example.com", Interval: interval}).Validate(); err == nil {
+			t.Errorf("Expected an error for interval %q", interval)
+		}
+	}
+}
+
+func TestLoad_Groups(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := `targets: ["https://example.com"]
+groups:
+  Payments:
+    timeout: 2s
+    retries: 0
+    proxy: http://proxy.internal:3128
+    maxConnsPerHost: 4
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	group, exists := cfg.Groups.Lookup("PAYMENTS")
+	if !exists {
+		t.Fatalf("Expected group payments, got %+v", cfg.Groups)
+	}
+	if group.Timeout != 2*time.Second || group.Proxy != "http://proxy.internal:3128" || group.MaxConnsPerHost != 4 {
+		t.Errorf("Unexpected group config %+v", group)
+	}
+	if group.Retries == nil || *group.Retries != 0 {
+		t.Errorf("Expected retries 0 to be kept, got %v", group.Retries)
+	}
+	if _, exists := cfg.Groups.Lookup("other"); exists {
+		t.Error("Expected no settings for an unconfigured group")
+	}
+
+	invalid := []string{
+		"groups:\n  payments:\n    timeout: -1s\n",
+		"groups:\n  payments:\n    retries: -1\n",
+		"groups:\n  payments:\n    proxy: ftp://proxy.internal\n",
+		"groups:\n  payments:\n    proxy: http://\n",
+	}
+	for _, group := range invalid {
+		if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\n"+group), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for %q", group)
+		}
+	}
+}
+
+func TestLoad_DrainTimeout(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ntimeout: 7s\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.DrainTimeout != 7*time.Second {
+		t.Errorf("Expected drainTimeout to default to timeout, got %v", cfg.DrainTimeout)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ndrainTimeout: 45s\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.DrainTimeout != 45*time.Second {
+		t.Errorf("Expected drainTimeout 45s, got %v", cfg.DrainTimeout)
+	}
+}
+
+func TestLoad_Warmup(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nwarmup: 2m\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Warmup != 2*time.Minute {
+		t.Errorf("Expected warmup 2m, got %v", cfg.Warmup)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nwarmup: -1s\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a negative warmup")
+	}
+}
+
+func TestLoad_MetricsRemovalGrace(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nmetrics:\n  removalGrace: 10m\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Metrics.RemovalGrace != 10*time.Minute {
+		t.Errorf("Metrics.RemovalGrace: expected 10m, got %v", cfg.Metrics.RemovalGrace)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nmetrics:\n  removalGrace: -1m\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a negative removalGrace")
+	}
+}
+
+func TestLoad_Controller(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ncontroller:\n  enabled: true\n  namespace: monitoring\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.Controller.Enabled || cfg.Controller.Namespace != "monitoring" {
+		t.Errorf("Controller: expected enabled in namespace monitoring, got %+v", cfg.Controller)
+	}
+	if cfg.Controller.StatusInterval != DefaultControllerStatusInterval {
+		t.Errorf("Controller.StatusInterval: expected default %v, got %v", DefaultControllerStatusInterval, cfg.Controller.StatusInterval)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ncontroller:\n  statusInterval: -1m\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a negative statusInterval")
+	}
+}
+
+func TestLoad_Discovery(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ndiscovery:\n  namespaces: [shop, blog]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.Discovery.Enabled() || len(cfg.Discovery.Namespaces) != 2 {
+		t.Errorf("Discovery: expected namespaces shop and blog, got %v", cfg.Discovery.Namespaces)
+	}
+	if len(cfg.Discovery.Kinds) != 1 || cfg.Discovery.Kinds[0] != DiscoveryKindIngress {
+		t.Errorf("Discovery.Kinds: expected default [ingress], got %v", cfg.Discovery.Kinds)
+	}
+	if cfg.Discovery.Path != "/" {
+		t.Errorf("Discovery.Path: expected default /, got %q", cfg.Discovery.Path)
+	}
+
+	invalid := []string{
+		"discovery:\n  kinds: [service]\n",
+		"discovery:\n  scheme: ftp\n",
+		"discovery:\n  path: health\n",
+	}
+	for _, content := range invalid {
+		if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\n"+content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestLoad_InstanceMetadata(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ninstanceMetadata:\n  provider: gcp\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.Metadata.Enabled() || cfg.Metadata.Provider != MetadataGCP {
+		t.Errorf("Metadata.Provider: expected gcp, got %q", cfg.Metadata.Provider)
+	}
+	if cfg.Metadata.Timeout != DefaultMetadataTimeout {
+		t.Errorf("Metadata.Timeout: expected default %v, got %v", DefaultMetadataTimeout, cfg.Metadata.Timeout)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\ninstanceMetadata:\n  provider: openstack\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
+
+func TestLoad_Plugins(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nplugins: [/opt/plugins/myproto.so]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.Plugins) != 1 || cfg.Plugins[0] != "/opt/plugins/myproto.so" {
+		t.Errorf("Plugins: expected [/opt/plugins/myproto.so], got %v", cfg.Plugins)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nplugins: [\"\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an empty plugin path")
+	}
+}
+
+func TestLoadSettings_NoTargets(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	if err := os.WriteFile(configFile, []byte("timeout: 3s\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected Load() to require targets")
+	}
+	cfg, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() failed: %v", err)
+	}
+	if cfg.Timeout != 3*time.Second {
+		t.Errorf("Timeout: expected 3s, got %v", cfg.Timeout)
+	}
+}
+
+func TestLoad_TargetPolicy(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := "targets: [\"https://example.com\"]\ntargetPolicy:\n  allowSchemes: [http, https]\n  denyPorts: [22]\n  denyCidrs: [10.0.0.0/8]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.TargetPolicy.AllowSchemes) != 2 || len(cfg.TargetPolicy.DenyPorts) != 1 || len(cfg.TargetPolicy.DenyCIDRs) != 1 {
+		t.Errorf("TargetPolicy: unexpected %+v", cfg.TargetPolicy)
+	}
+	if cfg.TargetPolicy.AllowLinkLocal {
+		t.Error("TargetPolicy: link-local addresses should be denied by default")
+	}
+
+	for _, invalid := range []string{"denyCidrs: [10.0.0.0]", "allowPorts: [70000]"} {
+		content := "targets: [\"https://example.com\"]\ntargetPolicy:\n  " + invalid + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestLoad_Source(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := "source:\n  address: 10.0.1.5\nchecks:\n  - url: https://example.com\n  - url: https://partner.example.com\n    source:\n      interface: vrf-partner\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := cfg.SourceFor(cfg.Checks[0]); got.Address != "10.0.1.5" || got.Interface != "" {
+		t.Errorf("SourceFor(example.com): expected the global source, got %+v", got)
+	}
+	if got := cfg.SourceFor(cfg.Checks[1]); got.Address != "" || got.Interface != "vrf-partner" {
+		t.Errorf("SourceFor(partner.example.com): expected the check's source, got %+v", got)
+	}
+
+	for _, invalid := range []string{"source:\n  address: eth0\ntargets: [\"https://example.com\"]\n", "checks:\n  - url: https://example.com\n    source:\n      address: 10.0.1\n"} {
+		if err := os.WriteFile(configFile, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := "tls:\n  minVersion: \"1.3\"\n  reportOnly: true\nmodules:\n  legacy:\n    tls:\n      minVersion: \"1.0\"\nchecks:\n  - url: https://example.com\n  - url: https://old.example.com\n    module: legacy\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := cfg.TLSFor(cfg.Checks[0]); got.MinVersion != "1.3" || !got.ReportOnly {
+		t.Errorf("TLSFor(example.com): expected the global options, got %+v", got)
+	}
+	if got := cfg.TLSFor(cfg.Checks[1]); got.MinVersion != "1.0" || !got.ReportOnly {
+		t.Errorf("TLSFor(old.example.com): expected the module's minVersion over the global options, got %+v", got)
+	}
+
+	for _, invalid := range []string{"tls:\n  minVersion: \"1.4\"\ntargets: [\"https://example.com\"]\n", "tls:\n  cipherSuites: [TLS_MADE_UP]\ntargets: [\"https://example.com\"]\n"} {
+		if err := os.WriteFile(configFile, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	api := Target{URL: "https://api.example.com", Name: "api"}
+	db := Target{URL: "https://db.example.com", Name: "db"}
+	tests := []struct {
+		name    string
+		targets []Target
+		wantErr string
+	}{
+		{"by name and url", []Target{api, db, {URL: "https://web.example.com", DependsOn: []string{"api", "https://db.example.com"}}}, ""},
+		{"unknown target", []Target{api, {URL: "https://web.example.com", DependsOn: []string{"cache"}}}, `depends on unknown target "cache"`},
+		{"self", []Target{{URL: "https://api.example.com", Name: "api", DependsOn: []string{"api"}}}, "dependency cycle"},
+		{"cycle", []Target{{URL: api.URL, Name: "api", DependsOn: []string{"db"}}, {URL: db.URL, Name: "db", DependsOn: []string{"web"}}, {URL: "https://web.example.com", Name: "web", DependsOn: []string{"api"}}}, "dependency cycle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDependencies(tt.targets)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_DependsOn(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := "checks:\n  - url: https://gateway.example.com\n    name: gateway\n  - url: https://api.example.com\n    dependsOn: [gateway]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.Checks[1].DependsOn) != 1 || cfg.Checks[1].DependsOn[0] != "gateway" {
+		t.Errorf("Expected api to depend on gateway, got %v", cfg.Checks[1].DependsOn)
+	}
+
+	invalid := "checks:\n  - url: https://api.example.com\n    dependsOn: [gateway]\n"
+	if err := os.WriteFile(configFile, []byte(invalid), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "invalid dependsOn") {
+		t.Errorf("Expected an invalid dependsOn error, got: %v", err)
+	}
+}
+
+func TestLoad_Composites(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	checks := "checks:\n  - url: https://eu.example.com\n    name: eu\n  - url: https://us.example.com\n    name: us\n"
+	content := checks + "composites:\n  - name: api\n    members: [eu, https://us.example.com]\n  - name: api-quorum\n    mode: quorum\n    quorum: 1\n    members: [eu, us]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.Composites) != 2 || cfg.Composites[0].Mode != CompositeAll || cfg.Composites[1].Quorum != 1 {
+		t.Errorf("Expected an all-of and a quorum composite, got %+v", cfg.Composites)
+	}
+
+	tests := []struct {
+		name       string
+		composites string
+		wantErr    string
+	}{
+		{"missing name", "  - members: [eu]\n", "name is required"},
+		{"no members", "  - name: api\n", "has no members"},
+		{"unknown member", "  - name: api\n    members: [eu, ap]\n", `unknown member "ap"`},
+		{"duplicate member", "  - name: api\n    members: [eu, https://eu.example.com]\n", "twice"},
+		{"invalid mode", "  - name: api\n    mode: most\n    members: [eu]\n", `invalid mode "most"`},
+		{"quorum too large", "  - name: api\n    mode: quorum\n    quorum: 3\n    members: [eu, us]\n", "invalid quorum 3"},
+		{"quorum without mode", "  - name: api\n    quorum: 1\n    members: [eu, us]\n", "requires mode"},
+		{"duplicate name", "  - name: api\n    members: [eu]\n  - name: api\n    members: [us]\n", `duplicate name "api"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configFile, []byte(checks+"composites:\n"+tt.composites), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestComposite_Up(t *testing.T) {
+	tests := []struct {
+		composite   Composite
+		up, checked int
+		want        bool
+	}{
+		{Composite{Mode: CompositeAll}, 3, 3, true},
+		{Composite{Mode: CompositeAll}, 2, 3, false},
+		{Composite{Mode: CompositeAll}, 0, 0, false},
+		{Composite{Mode: CompositeAny}, 1, 3, true},
+		{Composite{Mode: CompositeAny}, 0, 3, false},
+		{Composite{Mode: CompositeQuorum, Quorum: 2}, 2, 3, true},
+		{Composite{Mode: CompositeQuorum, Quorum: 2}, 1, 3, false},
+	}
+	for _, tt := range tests {
+		if got := tt.composite.Up(tt.up, tt.checked); got != tt.want {
+			t.Errorf("%s Up(%d, %d): expected %v, got %v", tt.composite.Mode, tt.up, tt.checked, tt.want, got)
+		}
+	}
+}
+
+func TestLoad_Kafka(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := "targets: [\"https://example.com\"]\nkafka:\n  enabled: true\n  brokers: [\"kafka-1:9093\"]\n  topic: probes\n  tls:\n    enabled: true\n    serverName: kafka.internal\n  sasl:\n    mechanism: SCRAM-SHA-512\n    username: exporter\n    password: secret\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Kafka.Format != KafkaFormatJSON {
+		t.Errorf("Expected the json format by default, got %q", cfg.Kafka.Format)
+	}
+	if !cfg.Kafka.TLS.Enabled || cfg.Kafka.TLS.ServerName != "kafka.internal" {
+		t.Errorf("Expected the TLS options to be loaded, got %+v", cfg.Kafka.TLS)
+	}
+	if cfg.Kafka.SASL.Mechanism != KafkaSASLSCRAMSHA512 || cfg.Kafka.SASL.Username != "exporter" {
+		t.Errorf("Expected the SASL options to be loaded, got %+v", cfg.Kafka.SASL)
+	}
+	if redacted := cfg.Redacted(); redacted.Kafka.SASL.Password != RedactedValue {
+		t.Errorf("Expected the SASL password to be redacted, got %q", redacted.Kafka.SASL.Password)
+	}
+
+	tests := []struct {
+		name    string
+		kafka   string
+		wantErr string
+	}{
+		{"missing topic", "  brokers: [\"kafka-1:9092\"]\n", "brokers or topic not specified"},
+		{"broker without port", "  brokers: [kafka-1]\n  topic: probes\n", "must be host:port"},
+		{"invalid format", "  brokers: [\"kafka-1:9092\"]\n  topic: probes\n  format: protobuf\n", `invalid kafka.format "protobuf"`},
+		{"schema id with json", "  brokers: [\"kafka-1:9092\"]\n  topic: probes\n  schemaId: 3\n", "requires the \"avro\" format"},
+		{"invalid mechanism", "  brokers: [\"kafka-1:9092\"]\n  topic: probes\n  sasl:\n    mechanism: GSSAPI\n", `invalid kafka.sasl.mechanism "GSSAPI"`},
+		{"missing username", "  brokers: [\"kafka-1:9092\"]\n  topic: probes\n  sasl:\n    mechanism: PLAIN\n", "username is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "targets: [\"https://example.com\"]\nkafka:\n  enabled: true\n" + tt.kafka
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_NATS(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := "targets: [\"https://example.com\"]\nnats:\n  enabled: true\n  servers: [\"nats-1:4222\"]\n  groupSubjects:\n    Payments: \"payments.{{.Status}}\"\n  jetStream: true\n  token: secret\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.NATS.Subject != DefaultNATSSubject {
+		t.Errorf("Expected the default subject, got %q", cfg.NATS.Subject)
+	}
+	if subject := cfg.NATS.SubjectFor("payments"); subject != "payments.{{.Status}}" {
+		t.Errorf("Expected the group's subject, got %q", subject)
+	}
+	if subject := cfg.NATS.SubjectFor(""); subject != DefaultNATSSubject {
+		t.Errorf("Expected the default subject for ungrouped targets, got %q", subject)
+	}
+	if !cfg.NATS.JetStream {
+		t.Error("Expected JetStream to be enabled")
+	}
+	if redacted := cfg.Redacted(); redacted.NATS.Token != RedactedValue {
+		t.Errorf("Expected the token to be redacted, got %q", redacted.NATS.Token)
+	}
+
+	tests := []struct {
+		name    string
+		nats    string
+		wantErr string
+	}{
+		{"missing servers", "  subject: probes\n", "no servers specified"},
+		{"server without port", "  servers: [nats-1]\n", "must be host:port"},
+		{"invalid subject", "  servers: [\"nats-1:4222\"]\n  subject: \"probes.{{.Group\"\n", "invalid nats.subject"},
+		{"invalid group subject", "  servers: [\"nats-1:4222\"]\n  groupSubjects:\n    web: \"{{if}}\"\n", "invalid nats.groupSubjects.web"},
+		{"token and username", "  servers: [\"nats-1:4222\"]\n  token: secret\n  username: exporter\n", "mutually exclusive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "targets: [\"https://example.com\"]\nnats:\n  enabled: true\n" + tt.nats
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_CloudEvents(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := `targets: ["https://example.com"]
+notifications:
+  cloudEvents:
+    endpoint: "http://broker-ingress.knative-eventing.svc/default/default"
+    groupWait: 1m
+    headers:
+      Authorization: "Bearer secret"
+  receivers:
+    sre:
+      cloudEvents:
+        endpoint: "https://events.example.com"
+        mode: binary
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	cloudEvents := cfg.Notifications.CloudEvents
+	if !cloudEvents.Enabled() || cloudEvents.Mode != CloudEventsStructured || cloudEvents.GroupWait != time.Minute {
+		t.Errorf("Expected the structured mode by default and the channel options, got %+v", cloudEvents)
+	}
+	if cloudEvents.Headers["authorization"] != "Bearer secret" {
+		t.Errorf("Expected the headers to be loaded, got %v", cloudEvents.Headers)
+	}
+	if mode := cfg.Notifications.Receivers["sre"].CloudEvents.Mode; mode != CloudEventsBinary {
+		t.Errorf("Expected the receiver's binary mode, got %q", mode)
+	}
+	if redacted := cfg.Redacted(); redacted.Notifications.CloudEvents.Headers["authorization"] != RedactedValue {
+		t.Errorf("Expected the headers to be redacted, got %v", redacted.Notifications.CloudEvents.Headers)
+	}
+
+	tests := []struct {
+		name        string
+		cloudEvents string
+		wantErr     string
+	}{
+		{"invalid endpoint", "    endpoint: \"broker:8080\"\n", "notifications.cloudEvents: invalid endpoint"},
+		{"invalid mode", "    endpoint: \"http://broker\"\n    mode: batched\n", `invalid mode "batched"`},
+		{"invalid template", "    endpoint: \"http://broker\"\n    template: \"{{ .Target\"\n", "notifications.cloudEvents: invalid template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "targets: [\"https://example.com\"]\nnotifications:\n  cloudEvents:\n" + tt.cloudEvents
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_AlertRules(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	content := `targets: ["https://example.com"]
+alertRules:
+  slow: 500ms
+  disabled: [URLFlapping]
+checks:
+  - url: "https://api.example.com"
+    alerts:
+      for: 10m
+      certExpiry: 0s
+      flapping: 2
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	expected := AlertRulesConfig{For: 2 * time.Minute, Slow: 500 * time.Millisecond, CertExpiry: 336 * time.Hour, Flapping: 4, Disabled: []string{AlertURLFlapping}}
+	if !reflect.DeepEqual(cfg.AlertRules, expected) {
+		t.Errorf("Expected the defaults with the configured thresholds, got %+v", cfg.AlertRules)
+	}
+	if cfg.AlertRules.AlertEnabled(AlertURLFlapping) || !cfg.AlertRules.AlertEnabled(AlertURLDown) {
+		t.Errorf("Expected only %s to be disabled", AlertURLFlapping)
+	}
+	expected = AlertRulesConfig{For: 10 * time.Minute, Slow: 500 * time.Millisecond, Flapping: 2, Disabled: []string{AlertURLFlapping}}
+	if rules := cfg.TargetAlertRules(cfg.Checks[0]); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected the check's overrides, got %+v", rules)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"negative default", "alertRules:\n  flapping: -1\n", "alertRules: thresholds must not be negative"},
+		{"invalid duration", "checks:\n  - url: \"https://api.example.com\"\n    alerts:\n      slow: fast\n", `invalid alerts.slow "fast"`},
+		{"unknown alert", "alertRules:\n  disabled: [URLGone]\n", `unknown alert "URLGone"`},
+		{"negative flapping", "checks:\n  - url: \"https://api.example.com\"\n    alerts:\n      flapping: -2\n", "invalid alerts.flapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "targets: [\"https://example.com\"]\n" + tt.content
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Tenants(t *testing.T) {
+	clearEnv(t)
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	t.Setenv("URL_CONFIG_FILE", configFile)
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("search-token\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	content := `targets: ["https://example.com"]
+tenants:
+  payments:
+    bearerToken: payments-token
+    metrics: true
+  search:
+    bearerTokenFile: "` + tokenFile + `"
+checks:
+  - url: "https://pay.example.com"
+    tenant: payments
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Tenants["search"].BearerToken != "search-token" {
+		t.Errorf("Expected the token of search to be read from its file, got %q", cfg.Tenants["search"].BearerToken)
+	}
+	if !cfg.Tenants["payments"].Metrics || cfg.Tenants["search"].Metrics {
+		t.Errorf("Expected only payments to have a metrics path, got %+v", cfg.Tenants)
+	}
+	if cfg.Checks[0].Tenant != "payments" {
+		t.Errorf("Expected the check to belong to payments, got %q", cfg.Checks[0].Tenant)
+	}
+	if redacted := cfg.Redacted(); redacted.Tenants["payments"].BearerToken != RedactedValue {
+		t.Errorf("Expected the tenant token to be redacted, got %q", redacted.Tenants["payments"].BearerToken)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown tenant", "checks:\n  - url: \"https://api.example.com\"\n    tenant: search\n", `unknown tenant "search"`},
+		{"invalid name", "tenants:\n  pay/ments:\n    bearerToken: x\n", `invalid name "pay/ments"`},
+		{"shared token", "tenants:\n  a:\n    bearerToken: same\n  b:\n    bearerToken: same\n", "bearer token is already used"},
+		{"general token", "auth:\n  bearerToken: same\ntenants:\n  a:\n    bearerToken: same\n", "bearer token is already used by auth"},
+		{"both tokens", "tenants:\n  a:\n    bearerToken: x\n    bearerTokenFile: /tmp/token\n", "mutually exclusive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "targets: [\"https://example.com\"]\n" + tt.content
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_GraphQLModule(t *testing.T) {
+	clearEnv(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `modules:
+  graphql_health:
+    graphql:
+      query: "query Health($deep: Boolean) { health(deep: $deep) { status } }"
+      variables: '{"deep": true}'
+      operationName: Health
+      expect:
+        - path: data.health.status
+          value: "^OK$"
+checks:
+  - url: "https://api.example.com/graphql"
+    module: "graphql_health"
+  - url: "https://other.example.com/graphql"
+    module: "graphql"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	resolved, err := cfg.ResolveModule(cfg.Checks[0])
+	if err != nil {
+		t.Fatalf("ResolveModule() failed: %v", err)
+	}
+	graphql := resolved.GraphQL
+	if graphql == nil || graphql.Variables != `{"deep": true}` || graphql.OperationName != "Health" {
+		t.Fatalf("GraphQL settings not applied: %+v", graphql)
+	}
+	if len(graphql.Expect) != 1 || graphql.Expect[0].Path != "data.health.status" || graphql.Expect[0].Value != "^OK$" {
+		t.Errorf("Unexpected expectations: %+v", graphql.Expect)
+	}
+
+	builtIn, err := cfg.ResolveModule(cfg.Checks[1])
+	if err != nil {
+		t.Fatalf("ResolveModule() failed: %v", err)
+	}
+	if builtIn.GraphQL == nil || builtIn.GraphQL.Query != DefaultGraphQLQuery {
+		t.Errorf("Expected the built-in graphql module, got %+v", builtIn.GraphQL)
+	}
+
+	if err := os.WriteFile(configFile, []byte("targets: [\"https://example.com\"]\nmodules:\n  broken:\n    graphql:\n      variables: '{}'\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "invalid module broken: graphql query is required") {
+		t.Errorf("Expected module without query to be rejected, got: %v", err)
+	}
+}
+
 func clearEnv(t *testing.T) {
 	envVars := []string{
 		"URL_TARGETS",
@@ -596,6 +3318,7 @@ func clearEnv(t *testing.T) {
 		"URL_INSTANCEID",
 		"URL_RETRIES",
 		"URL_LOGLEVEL",
+		"URL_LOGFORMAT",
 		"URL_CONFIG_FILE",
 	}
 
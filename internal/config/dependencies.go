@@ -0,0 +1,69 @@
+package config
+
+import "fmt"
+
+// FindTarget returns the target with the name or URL
+func FindTarget(targets []Target, selector string) (Target, bool) {
+	for _, target := range targets {
+		if target.URL == selector || (target.Name != "" && target.Name == selector) {
+			return target, true
+		}
+	}
+	return Target{}, false
+}
+
+// ValidateDependencies checks that every dependency of the targets names another of the
+// targets, by name or URL, and that no target depends on itself through its dependencies
+func ValidateDependencies(targets []Target) error {
+	for _, target := range targets {
+		for _, selector := range target.DependsOn {
+			if _, exists := FindTarget(targets, selector); !exists {
+				return fmt.Errorf("%s depends on unknown target %q", RedactURL(target.URL), selector)
+			}
+		}
+	}
+	return DependencyCycle(targets)
+}
+
+// DependencyCycle returns an error when a target depends on itself through its
+// dependencies. Dependencies on unknown targets are ignored.
+func DependencyCycle(targets []Target) error {
+	parents := make(map[string][]string, len(targets))
+	for _, target := range targets {
+		for _, selector := range target.DependsOn {
+			if parent, exists := FindTarget(targets, selector); exists {
+				parents[target.URL] = append(parents[target.URL], parent.URL)
+			}
+		}
+	}
+
+	// Depth-first search for a cycle, visiting every target once
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(targets))
+	var visit func(url string) error
+	visit = func(url string) error {
+		switch state[url] {
+		case visiting:
+			return fmt.Errorf("dependency cycle through %s", RedactURL(url))
+		case visited:
+			return nil
+		}
+		state[url] = visiting
+		for _, parent := range parents[url] {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		state[url] = visited
+		return nil
+	}
+	for _, target := range targets {
+		if err := visit(target.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
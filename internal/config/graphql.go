@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// GraphQLModule is the built-in module checking a GraphQL endpoint with DefaultGraphQLQuery,
+// which every GraphQL server answers. It can be redefined under modules.
+const GraphQLModule = "graphql"
+
+// DefaultGraphQLQuery is the query of the built-in graphql module
+const DefaultGraphQLQuery = "{ __typename }"
+
+// GraphQLConfig makes a check POST a GraphQL query to the target. The check fails when the
+// response has errors or a value of Expect does not match.
+type GraphQLConfig struct {
+	Query string `yaml:"query" json:"query"`
+	// Variables is the JSON object of the query's variables
+	Variables     string `yaml:"variables" json:"variables,omitempty"`
+	OperationName string `yaml:"operationName" json:"operationName,omitempty"`
+	// Expect are the values the response must have, all of them
+	Expect []GraphQLExpectation `yaml:"expect" json:"expect,omitempty"`
+}
+
+// GraphQLExpectation asserts a value of a GraphQL response
+type GraphQLExpectation struct {
+	// Path is the dotted path of the value in the response, e.g. data.health.status
+	Path string `yaml:"path" json:"path"`
+	// Value is a regular expression the value must match. Strings are matched as they
+	// are, other values as JSON.
+	Value string `yaml:"value" json:"value"`
+}
+
+// validateGraphQL checks the GraphQL settings of the target, which only HTTP checks without
+// steps support
+func (t Target) validateGraphQL() error {
+	if t.GraphQL == nil {
+		return nil
+	}
+	if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("graphql needs an http or https url, got %s", RedactURL(t.URL))
+	}
+	if t.Method != "" && t.Method != http.MethodPost {
+		return fmt.Errorf("graphql for %s cannot be used with the %s method", RedactURL(t.URL), t.Method)
+	}
+	if len(t.Steps) > 0 {
+		return fmt.Errorf("graphql for %s cannot be combined with steps", RedactURL(t.URL))
+	}
+	if err := t.GraphQL.validate(); err != nil {
+		return fmt.Errorf("%w for %s", err, RedactURL(t.URL))
+	}
+	return nil
+}
+
+func (g *GraphQLConfig) validate() error {
+	if g.Query == "" {
+		return errors.New("graphql query is required")
+	}
+	if g.Variables != "" {
+		var variables map[string]any
+		if err := json.Unmarshal([]byte(g.Variables), &variables); err != nil {
+			return fmt.Errorf("graphql variables must be a JSON object: %w", err)
+		}
+	}
+	for i, expectation := range g.Expect {
+		if expectation.Path == "" {
+			return fmt.Errorf("graphql expectation %d has no path", i+1)
+		}
+		if _, err := regexp.Compile(expectation.Value); err != nil {
+			return fmt.Errorf("invalid graphql expectation %s: %w", expectation.Path, err)
+		}
+	}
+	return nil
+}
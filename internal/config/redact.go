@@ -0,0 +1,151 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RedactedValue replaces secrets in the output of Redacted
+const RedactedValue = "<redacted>"
+
+// Redacted returns a copy of the configuration that is safe to show: tokens and password
+// hashes are replaced with RedactedValue and passwords embedded in URLs are masked.
+// Secrets that are not set stay empty so it remains visible whether they are configured.
+func (c *Config) Redacted() Config {
+	redacted := *c
+
+	redacted.Targets = make([]string, len(c.Targets))
+	for i, target := range c.Targets {
+		redacted.Targets[i] = RedactURL(target)
+	}
+
+	redacted.Checks = make([]Target, len(c.Checks))
+	for i, check := range c.Checks {
+		check.URL = RedactURL(check.URL)
+		redacted.Checks[i] = check
+	}
+
+	redacted.InfluxDB.URL = RedactURL(c.InfluxDB.URL)
+	redacted.InfluxDB.Token = redactSecret(c.InfluxDB.Token)
+	redacted.Kafka.SASL.Password = redactSecret(c.Kafka.SASL.Password)
+	redacted.NATS.Token = redactSecret(c.NATS.Token)
+	redacted.NATS.Password = redactSecret(c.NATS.Password)
+	redacted.API.Token = redactSecret(c.API.Token)
+	redacted.Auth.BearerToken = redactSecret(c.Auth.BearerToken)
+	if c.Tenants != nil {
+		redacted.Tenants = make(map[string]TenantConfig, len(c.Tenants))
+		for name, tenant := range c.Tenants {
+			tenant.BearerToken = redactSecret(tenant.BearerToken)
+			redacted.Tenants[name] = tenant
+		}
+	}
+
+	redacted.Heartbeat.URL = redactSecret(c.Heartbeat.URL)
+	redacted.Tracing.Endpoint = RedactURL(c.Tracing.Endpoint)
+	if c.Tracing.Headers != nil {
+		redacted.Tracing.Headers = make(map[string]string, len(c.Tracing.Headers))
+		for name, value := range c.Tracing.Headers {
+			redacted.Tracing.Headers[name] = redactSecret(value)
+		}
+	}
+	redacted.Notifications.ReceiverConfig = redactReceiver(c.Notifications.ReceiverConfig)
+	if c.Notifications.Receivers != nil {
+		redacted.Notifications.Receivers = make(map[string]ReceiverConfig, len(c.Notifications.Receivers))
+		for name, receiver := range c.Notifications.Receivers {
+			redacted.Notifications.Receivers[name] = redactReceiver(receiver)
+		}
+	}
+
+	redacted.Auth.Users = make([]BasicAuthUser, len(c.Auth.Users))
+	for i, user := range c.Auth.Users {
+		user.Password = redactSecret(user.Password)
+		redacted.Auth.Users[i] = user
+	}
+
+	return redacted
+}
+
+// redactReceiver redacts the routing keys, bot token, webhook URL and CloudEvents headers
+// of the receiver
+func redactReceiver(receiver ReceiverConfig) ReceiverConfig {
+	receiver.PagerDuty.RoutingKey = redactSecret(receiver.PagerDuty.RoutingKey)
+	if keys := receiver.PagerDuty.RoutingKeys; keys != nil {
+		receiver.PagerDuty.RoutingKeys = make(map[string]string, len(keys))
+		for group, key := range keys {
+			receiver.PagerDuty.RoutingKeys[group] = redactSecret(key)
+		}
+	}
+	receiver.Telegram.BotToken = redactSecret(receiver.Telegram.BotToken)
+	receiver.Teams.WebhookURL = redactSecret(receiver.Teams.WebhookURL)
+	receiver.CloudEvents.Endpoint = RedactURL(receiver.CloudEvents.Endpoint)
+	if headers := receiver.CloudEvents.Headers; headers != nil {
+		receiver.CloudEvents.Headers = make(map[string]string, len(headers))
+		for name, value := range headers {
+			receiver.CloudEvents.Headers[name] = redactSecret(value)
+		}
+	}
+	return receiver
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// redactedURLValue replaces passwords and secret query values in redacted URLs, as
+// url.URL.Redacted does
+const redactedURLValue = "xxxxx"
+
+// RedactURL masks the password of URLs with embedded credentials, e.g.
+// redis://:password@host, and the values of query parameters that look like secrets, e.g.
+// ?token=. Targets keep their real URL as their identity; it is redacted wherever it is
+// shown: logs, metric labels, notifications and the status page.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	query := RedactQuery(u.RawQuery)
+	if _, hasPassword := u.User.Password(); !hasPassword && query == u.RawQuery {
+		return raw
+	}
+	u.RawQuery = query
+	return u.Redacted()
+}
+
+// RedactQuery masks the values of the query parameters that look like secrets, keeping
+// the order and encoding of the others
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, _, hasValue := strings.Cut(param, "=")
+		decoded, err := url.QueryUnescape(name)
+		if err != nil {
+			decoded = name
+		}
+		if hasValue && secretParam(decoded) {
+			params[i] = name + "=" + redactedURLValue
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// secretParam reports whether the query parameter name looks like it carries a secret
+func secretParam(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "key", "pass", "pwd", "sig", "auth", "authorization":
+		return true
+	}
+	for _, secret := range []string{"token", "secret", "password", "passwd", "signature", "credential", "apikey", "api_key", "api-key"} {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// ShardIndexFromHostname is the shardIndex that derives the index from the ordinal at the
+// end of the hostname, e.g. 2 for the StatefulSet pod url-exporter-2
+const ShardIndexFromHostname = -1
+
+// ordinalPattern matches the ordinal suffix of a StatefulSet pod name
+var ordinalPattern = regexp.MustCompile(`-(\d+)$`)
+
+// Sharded reports whether the configured targets are split across several instances
+func (c *Config) Sharded() bool {
+	return c.ShardTotal > 1
+}
+
+// InShard reports whether this instance's shard checks the target. Every target is in
+// the shard when sharding is off.
+func (c *Config) InShard(targetURL string) bool {
+	if !c.Sharded() {
+		return true
+	}
+	return shardOf(targetURL, c.ShardTotal) == c.ShardIndex
+}
+
+// applySharding validates the shard settings, derives the index from the hostname when
+// asked to and keeps only the targets and checks of this instance's shard
+func (c *Config) applySharding() error {
+	if c.ShardTotal < 0 {
+		return fmt.Errorf("shardTotal must not be negative")
+	}
+	if !c.Sharded() {
+		if c.ShardIndex > 0 {
+			return fmt.Errorf("shardIndex %d requires shardTotal greater than 1", c.ShardIndex)
+		}
+		c.ShardIndex = 0
+		return nil
+	}
+
+	if c.ShardIndex == ShardIndexFromHostname {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get hostname for shardIndex: %w", err)
+		}
+		index, err := hostnameOrdinal(hostname)
+		if err != nil {
+			return err
+		}
+		c.ShardIndex = index
+	}
+	if c.ShardIndex < 0 || c.ShardIndex >= c.ShardTotal {
+		return fmt.Errorf("shardIndex %d must be between 0 and shardTotal-1 (%d)", c.ShardIndex, c.ShardTotal-1)
+	}
+
+	targets := make([]string, 0, len(c.Targets))
+	for _, url := range c.Targets {
+		if c.InShard(url) {
+			targets = append(targets, url)
+		}
+	}
+	checks := make([]Target, 0, len(c.Checks))
+	for _, check := range c.Checks {
+		if c.InShard(check.URL) {
+			checks = append(checks, check)
+		}
+	}
+	c.Targets, c.Checks = targets, checks
+
+	return nil
+}
+
+// hostnameOrdinal returns the ordinal at the end of a StatefulSet pod's hostname
+func hostnameOrdinal(hostname string) (int, error) {
+	match := ordinalPattern.FindStringSubmatch(hostname)
+	if match == nil {
+		return 0, fmt.Errorf("shardIndex: hostname %q does not end with a StatefulSet ordinal", hostname)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// shardOf assigns the URL to one of total shards with a jump consistent hash (Lamping and
+// Veach), so changing the number of shards only moves the targets it must move
+func shardOf(targetURL string, total int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(targetURL))
+	key := h.Sum64()
+
+	var b, j int64 = -1, 0
+	for j < int64(total) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SOAP versions
+const (
+	SOAP11 = "1.1"
+	SOAP12 = "1.2"
+)
+
+// SOAPConfig makes a check POST a SOAP request to the target. The check fails when the
+// response is not a SOAP envelope or holds a Fault.
+type SOAPConfig struct {
+	// Version is the SOAP version, SOAP11 (default) or SOAP12
+	Version string `yaml:"version" json:"version,omitempty"`
+	// Action is the SOAPAction of the operation
+	Action string `yaml:"action" json:"action,omitempty"`
+	// Body is the XML content of the request's Body, placed in an Envelope of the version
+	Body string `yaml:"body" json:"body"`
+}
+
+// EnvelopeNamespace returns the namespace of the SOAP Envelope of the version
+func (s *SOAPConfig) EnvelopeNamespace() string {
+	if s.Version == SOAP12 {
+		return "http://www.w3.org/2003/05/soap-envelope"
+	}
+	return "http://schemas.xmlsoap.org/soap/envelope/"
+}
+
+// Envelope returns the request document: the Body wrapped in an Envelope
+func (s *SOAPConfig) Envelope() string {
+	return `<?xml version="1.0" encoding="utf-8"?>` + "\n" +
+		`<soap:Envelope xmlns:soap="` + s.EnvelopeNamespace() + `">` +
+		`<soap:Body>` + s.Body + `</soap:Body></soap:Envelope>`
+}
+
+// validateSOAP checks the SOAP settings of the target, which only HTTP checks without
+// steps or GraphQL support
+func (t Target) validateSOAP() error {
+	if t.SOAP == nil {
+		return nil
+	}
+	if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("soap needs an http or https url, got %s", RedactURL(t.URL))
+	}
+	if t.Method != "" && t.Method != http.MethodPost {
+		return fmt.Errorf("soap for %s cannot be used with the %s method", RedactURL(t.URL), t.Method)
+	}
+	if len(t.Steps) > 0 || t.GraphQL != nil {
+		return fmt.Errorf("soap for %s cannot be combined with steps or graphql", RedactURL(t.URL))
+	}
+	if err := t.SOAP.validate(); err != nil {
+		return fmt.Errorf("%w for %s", err, RedactURL(t.URL))
+	}
+	return nil
+}
+
+func (s *SOAPConfig) validate() error {
+	switch s.Version {
+	case "", SOAP11, SOAP12:
+	default:
+		return fmt.Errorf("invalid soap version %q, must be %s or %s", s.Version, SOAP11, SOAP12)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(s.Envelope()))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid soap body: %w", err)
+		}
+	}
+}
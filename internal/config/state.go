@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// State is the target set persisted by the target management API, as stored in the
+// state file
+type State struct {
+	// Targets are trusted like the configured targets they started out as
+	Targets []Target `json:"targets"`
+	// RuntimeTargets were added through the API and stay subject to the target policy
+	RuntimeTargets []Target `json:"runtimeTargets,omitempty"`
+}
+
+// LoadState reads the target set persisted by the target management API. ok is false
+// when the state file does not exist yet.
+func LoadState(path string) (st State, ok bool, err error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, false, nil
+	}
+	if err != nil {
+		return State{}, false, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, &st); err != nil {
+		return State{}, false, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+
+	for i, target := range st.Targets {
+		if err := target.Validate(); err != nil {
+			return State{}, false, fmt.Errorf("invalid target %d in state file %s: %w", i, path, err)
+		}
+	}
+	for i, target := range st.RuntimeTargets {
+		if err := target.Validate(); err != nil {
+			return State{}, false, fmt.Errorf("invalid runtime target %d in state file %s: %w", i, path, err)
+		}
+	}
+
+	return st, true, nil
+}
+
+// SaveState atomically writes the target set to the state file
+func SaveState(path string, st State) error {
+	content, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	return WriteFileAtomic(path, content)
+}
+
+// WriteFileAtomic replaces the file with the content through a temporary file in the same
+// directory, so readers never see a partial write
+func WriteFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
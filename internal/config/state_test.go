@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+
+	st := State{
+		Targets: []Target{
+			{URL: "https://example.com"},
+			{URL: "https://api.example.com/health", Group: "api", ExpectBody: "ok", Labels: map[string]string{"team": "payments"}},
+		},
+		RuntimeTargets: []Target{{URL: "https://added.example.com", Group: "api"}},
+	}
+
+	if err := SaveState(path, st); err != nil {
+		t.Fatalf("SaveState() failed: %v", err)
+	}
+
+	loaded, ok, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() failed: %v", err)
+	}
+	if !ok {
+		t.Fatal("LoadState() reported missing state file")
+	}
+	if !reflect.DeepEqual(loaded, st) {
+		t.Errorf("Expected %v, got %v", st, loaded)
+	}
+}
+
+func TestLoadState_WithoutRuntimeTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+
+	// State files written before runtime targets were stored apart
+	if err := os.WriteFile(path, []byte(`{"targets":[{"url":"https://example.com"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	st, ok, err := LoadState(path)
+	if err != nil || !ok {
+		t.Fatalf("LoadState() failed: ok=%v err=%v", ok, err)
+	}
+	if len(st.Targets) != 1 || st.RuntimeTargets != nil {
+		t.Errorf("Expected one trusted target, got %+v", st)
+	}
+}
+
+func TestLoadState_Missing(t *testing.T) {
+	st, ok, err := LoadState(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadState() failed: %v", err)
+	}
+	if ok || st.Targets != nil || st.RuntimeTargets != nil {
+		t.Errorf("Expected no state, got ok=%v state=%+v", ok, st)
+	}
+}
+
+func TestLoadState_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+
+	if err := os.WriteFile(path, []byte(`{"targets":[{"url":"https://example.com","expectBody":"("}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	_, _, err := LoadState(path)
+	if err == nil || !strings.Contains(err.Error(), "invalid target 0") {
+		t.Errorf("Expected invalid target error, got: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`not json`), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	_, _, err = LoadState(path)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse state file") {
+		t.Errorf("Expected parse error, got: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"targets":[],"runtimeTargets":[{"url":""}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	_, _, err = LoadState(path)
+	if err == nil || !strings.Contains(err.Error(), "invalid runtime target 0") {
+		t.Errorf("Expected parse error, got: %v", err)
+	}
+}
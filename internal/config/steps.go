@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// Step is a request of a transaction check. The steps of a target run in order; values
+// extracted from a response are available to the later steps as ${name} in their URL,
+// headers and body.
+type Step struct {
+	// Name identifies the step in the metrics, unique within the target
+	Name string `yaml:"name" json:"name"`
+	// URL is resolved against the target's URL, which it defaults to
+	URL        string                 `yaml:"url" json:"url,omitempty"`
+	Method     string                 `yaml:"method" json:"method,omitempty"`
+	Headers    map[string]string      `yaml:"headers" json:"headers,omitempty"`
+	Body       string                 `yaml:"body" json:"body,omitempty"`
+	ExpectBody string                 `yaml:"expectBody" json:"expectBody,omitempty"`
+	Extract    map[string]StepExtract `yaml:"extract" json:"extract,omitempty"`
+}
+
+// StepExtract extracts a variable from a step's response: a field of a JSON body, or the
+// first capture group of a regular expression matched against the body or a header
+type StepExtract struct {
+	// JSON is the dotted path of a field of the JSON body, e.g. data.token or items.0.id
+	JSON string `yaml:"json" json:"json,omitempty"`
+	// Header is the header the regular expression is matched against instead of the body
+	Header string `yaml:"header" json:"header,omitempty"`
+	Regex  string `yaml:"regex" json:"regex,omitempty"`
+}
+
+var (
+	// stepVariable matches the ${name} references to extracted variables
+	stepVariable = regexp.MustCompile(`\$\{(\w+)\}`)
+	variableName = regexp.MustCompile(`^\w+$`)
+)
+
+// ExpandVariables replaces the ${name} references in text with the variables' values.
+// Unknown references are kept.
+func ExpandVariables(text string, variables map[string]string) string {
+	return stepVariable.ReplaceAllStringFunc(text, func(reference string) string {
+		if value, exists := variables[stepVariable.FindStringSubmatch(reference)[1]]; exists {
+			return value
+		}
+		return reference
+	})
+}
+
+// validateSteps checks the steps of the target. Every variable a step uses must be
+// extracted by an earlier step.
+func (t Target) validateSteps() error {
+	if len(t.Steps) == 0 {
+		return nil
+	}
+	if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("steps need an http or https url, got %s", RedactURL(t.URL))
+	}
+
+	names := make(map[string]bool, len(t.Steps))
+	variables := make(map[string]bool)
+	for i, step := range t.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("step %d of %s has no name", i+1, RedactURL(t.URL))
+		}
+		if names[step.Name] {
+			return fmt.Errorf("duplicate step %q for %s", step.Name, RedactURL(t.URL))
+		}
+		names[step.Name] = true
+
+		switch step.Method {
+		case "", http.MethodHead, http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		default:
+			return fmt.Errorf("invalid method %q for step %s of %s", step.Method, step.Name, RedactURL(t.URL))
+		}
+
+		texts := []string{step.URL, step.Body}
+		for _, value := range step.Headers {
+			texts = append(texts, value)
+		}
+		for _, text := range texts {
+			for _, reference := range stepVariable.FindAllStringSubmatch(text, -1) {
+				if !variables[reference[1]] {
+					return fmt.Errorf("step %s of %s uses ${%s}, which no earlier step extracts", step.Name, RedactURL(t.URL), reference[1])
+				}
+			}
+		}
+		if _, err := url.Parse(stepVariable.ReplaceAllString(step.URL, "x")); err != nil {
+			return fmt.Errorf("invalid url for step %s of %s: %w", step.Name, RedactURL(t.URL), err)
+		}
+
+		if step.ExpectBody != "" {
+			if _, err := regexp.Compile(step.ExpectBody); err != nil {
+				return fmt.Errorf("invalid expectBody for step %s of %s: %w", step.Name, RedactURL(t.URL), err)
+			}
+		}
+
+		for name, extract := range step.Extract {
+			if !variableName.MatchString(name) {
+				return fmt.Errorf("invalid variable name %q for step %s of %s", name, step.Name, RedactURL(t.URL))
+			}
+			if err := extract.validate(); err != nil {
+				return fmt.Errorf("invalid extract %s for step %s of %s: %w", name, step.Name, RedactURL(t.URL), err)
+			}
+			variables[name] = true
+		}
+	}
+	return nil
+}
+
+func (e StepExtract) validate() error {
+	switch {
+	case e.JSON != "" && (e.Regex != "" || e.Header != ""):
+		return fmt.Errorf("json cannot be combined with regex or header")
+	case e.JSON != "":
+		return nil
+	case e.Regex == "":
+		return fmt.Errorf("json or regex is required")
+	}
+	if _, err := regexp.Compile(e.Regex); err != nil {
+		return err
+	}
+	return nil
+}
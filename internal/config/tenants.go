@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// tenantName is the format of tenant names, which appear in the /metrics/{tenant} paths
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// TenantConfig configures a tenant, a team whose targets are partitioned from those of the
+// other tenants. Requests with the tenant's bearer token only see the tenant's targets.
+type TenantConfig struct {
+	BearerToken     string `yaml:"bearerToken"`
+	BearerTokenFile string `yaml:"bearerTokenFile"`
+	// Metrics serves the series of the tenant's targets at /metrics/{tenant}
+	Metrics bool `yaml:"metrics"`
+}
+
+// prepareTenants validates the tenant names and reads the bearer token files. Every token
+// must identify a single tenant, so tokens must differ from each other and from the
+// general auth.bearerToken.
+func (c *Config) prepareTenants() error {
+	tokens := make(map[string]string)
+	if c.Auth.BearerToken != "" {
+		tokens[c.Auth.BearerToken] = "auth"
+	}
+
+	for name, tenant := range c.Tenants {
+		if !tenantName.MatchString(name) {
+			return fmt.Errorf("tenants: invalid name %q, must be lowercase letters, digits, '-' and '_'", name)
+		}
+
+		if tenant.BearerTokenFile != "" {
+			if tenant.BearerToken != "" {
+				return fmt.Errorf("tenants: %s: bearerToken and bearerTokenFile are mutually exclusive", name)
+			}
+			content, err := os.ReadFile(tenant.BearerTokenFile)
+			if err != nil {
+				return fmt.Errorf("tenants: %s: failed to read bearer token file: %w", name, err)
+			}
+			tenant.BearerToken = strings.TrimSpace(string(content))
+			if tenant.BearerToken == "" {
+				return fmt.Errorf("tenants: %s: bearer token file %s is empty", name, tenant.BearerTokenFile)
+			}
+			c.Tenants[name] = tenant
+		}
+
+		if tenant.BearerToken == "" {
+			continue
+		}
+		if other, exists := tokens[tenant.BearerToken]; exists {
+			return fmt.Errorf("tenants: %s: bearer token is already used by %s", name, other)
+		}
+		tokens[tenant.BearerToken] = name
+	}
+
+	return nil
+}
+
+// ValidateTenant checks that the tenant of the target, if any, is configured
+func (c *Config) ValidateTenant(target Target) error {
+	if target.Tenant == "" {
+		return nil
+	}
+	if _, exists := c.Tenants[target.Tenant]; !exists {
+		return fmt.Errorf("unknown tenant %q for %s", target.Tenant, RedactURL(target.URL))
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package controller implements the controller mode: it watches the URLCheck custom
+// resources of a Kubernetes namespace, keeps the exporter's targets in line with their
+// specs and writes the latest check results back to their status subresource.
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// Phases reported in the status of a URLCheck
+const (
+	// PhasePending means the target was added and has not been checked yet
+	PhasePending = "Pending"
+	PhaseUp      = "Up"
+	PhaseDown    = "Down"
+	// PhaseInvalid means the spec was rejected; the message tells why
+	PhaseInvalid = "Invalid"
+)
+
+// statusFlushInterval is how often pending status updates are written
+const statusFlushInterval = time.Second
+
+// Targets is the live target set the URLCheck targets are added to
+type Targets interface {
+	AddTarget(target config.Target) error
+	RemoveTarget(url string) bool
+}
+
+// Controller reconciles URLCheck resources into the target set
+type Controller struct {
+	api            *kube.Client
+	namespace      string
+	targets        Targets
+	inShard        func(url string) bool
+	statusInterval time.Duration
+	retryPeriod    time.Duration
+
+	// Active, if set, reports whether this replica writes the status, e.g. only the leader
+	Active func() bool
+
+	mutex   sync.Mutex
+	checks  map[string]*managedCheck // resource name -> state
+	urls    map[string]string        // target URL -> name of the resource it belongs to
+	pending map[string]checkStatus   // resource name -> status not written yet
+}
+
+// managedCheck is the state kept for one URLCheck
+type managedCheck struct {
+	generation int64
+	// target is the target added for the resource; its URL is empty while none is
+	target  config.Target
+	status  checkStatus
+	written time.Time
+}
+
+// New creates a controller for the configured namespace, by default the pod's own
+func New(cfg *config.Config, targets Targets) (*Controller, error) {
+	api, err := kube.InCluster()
+	if err != nil {
+		return nil, fmt.Errorf("controller mode: %w", err)
+	}
+
+	namespace := cfg.Controller.Namespace
+	if namespace == "" {
+		namespace = api.Namespace
+	}
+	return newController(api, namespace, cfg, targets), nil
+}
+
+func newController(api *kube.Client, namespace string, cfg *config.Config, targets Targets) *Controller {
+	return &Controller{
+		api:            api,
+		namespace:      namespace,
+		targets:        targets,
+		inShard:        cfg.InShard,
+		statusInterval: cfg.Controller.StatusInterval,
+		retryPeriod:    5 * time.Second,
+		checks:         make(map[string]*managedCheck),
+		urls:           make(map[string]string),
+		pending:        make(map[string]checkStatus),
+	}
+}
+
+// Manages reports whether the target was added for a URLCheck, and returns its name
+func (c *Controller) Manages(url string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name, exists := c.urls[url]
+	return name, exists
+}
+
+// Run watches the URLCheck resources and writes their status until the context is done
+func (c *Controller) Run(ctx context.Context) {
+	go c.writeStatus(ctx)
+	c.informer().run(ctx)
+}
+
+// resync applies a full listing: every resource is applied and the targets of resources
+// that no longer exist are removed
+func (c *Controller) resync(checks []urlCheck) {
+	seen := make(map[string]bool, len(checks))
+	for _, check := range checks {
+		seen[check.Metadata.Name] = true
+		c.apply(check)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for name := range c.checks {
+		if !seen[name] {
+			c.forget(name)
+		}
+	}
+}
+
+// apply brings the target of a created or updated resource in line with its spec
+func (c *Controller) apply(check urlCheck) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name := check.Metadata.Name
+	managed, exists := c.checks[name]
+	if !exists {
+		managed = &managedCheck{}
+		c.checks[name] = managed
+	}
+	managed.generation = check.Metadata.Generation
+
+	target := check.Spec
+	if target.Name == "" {
+		target.Name = name
+	}
+	if err := target.Validate(); err != nil {
+		c.release(managed)
+		c.reject(name, managed, err.Error())
+		return
+	}
+	if !c.inShard(target.URL) {
+		// The replica of the target's shard checks it and reports its status
+		c.release(managed)
+		return
+	}
+	if owner, exists := c.urls[target.URL]; exists && owner != name {
+		c.release(managed)
+		c.reject(name, managed, "url is already checked for URLCheck "+owner)
+		return
+	}
+	if managed.target.URL != "" && reflect.DeepEqual(managed.target, target) {
+		return
+	}
+
+	c.release(managed)
+	if err := c.targets.AddTarget(target); err != nil {
+		message := err.Error()
+		if errors.Is(err, checker.ErrTargetExists) {
+			message = "url is already a target of the exporter"
+		}
+		c.reject(name, managed, message)
+		return
+	}
+	managed.target = target
+	c.urls[target.URL] = name
+	c.pending[name] = checkStatus{Phase: PhasePending, ObservedGeneration: managed.generation}
+
+	log.Info().Str("urlcheck", name).Str("url", config.RedactURL(target.URL)).Msg("Target added for URLCheck")
+}
+
+// remove drops the target of a deleted resource
+func (c *Controller) remove(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.forget(name)
+}
+
+// forget drops the resource and its target. The caller must hold the lock.
+func (c *Controller) forget(name string) {
+	if managed, exists := c.checks[name]; exists {
+		c.release(managed)
+	}
+	delete(c.checks, name)
+	delete(c.pending, name)
+}
+
+// release removes the target added for the resource, if any. The caller must hold the lock.
+func (c *Controller) release(managed *managedCheck) {
+	if managed.target.URL == "" {
+		return
+	}
+	c.targets.RemoveTarget(managed.target.URL)
+	delete(c.urls, managed.target.URL)
+	log.Info().Str("url", config.RedactURL(managed.target.URL)).Msg("Target removed for URLCheck")
+	managed.target = config.Target{}
+}
+
+// reject reports a spec that cannot be checked. The caller must hold the lock.
+func (c *Controller) reject(name string, managed *managedCheck, message string) {
+	log.Warn().Str("urlcheck", name).Str("reason", message).Msg("Ignoring invalid URLCheck")
+	c.pending[name] = checkStatus{Phase: PhaseInvalid, Message: message, ObservedGeneration: managed.generation}
+}
+
+// HandleCycle is a checker.CycleHandler that queues the status updates of the URLCheck
+// targets in the cycle. An unchanged result is only written again once the status
+// interval has passed, to keep the load on the API server low.
+func (c *Controller) HandleCycle(_ context.Context, results []checker.Result) {
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, result := range results {
+		name, exists := c.urls[result.URL]
+		if !exists {
+			continue
+		}
+		managed := c.checks[name]
+
+		status := resultStatus(result)
+		status.ObservedGeneration = managed.generation
+		if status.sameResult(managed.status) && now.Sub(managed.written) < c.statusInterval {
+			continue
+		}
+		c.pending[name] = status
+	}
+}
+
+// writeStatus writes the pending status updates until the context is done
+func (c *Controller) writeStatus(ctx context.Context) {
+	ticker := time.NewTicker(statusFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.flush(ctx)
+		}
+	}
+}
+
+// flush writes the pending status updates. Failed writes are retried on the next flush
+// unless a newer status has been queued meanwhile.
+func (c *Controller) flush(ctx context.Context) {
+	c.mutex.Lock()
+	pending := c.pending
+	c.pending = make(map[string]checkStatus)
+	c.mutex.Unlock()
+
+	if len(pending) == 0 || (c.Active != nil && !c.Active()) {
+		// A standby leaves the status to the leader
+		return
+	}
+
+	for name, status := range pending {
+		err := c.patchStatus(ctx, name, status)
+
+		c.mutex.Lock()
+		managed, exists := c.checks[name]
+		switch {
+		case !exists:
+		case err != nil:
+			if _, queued := c.pending[name]; !queued {
+				c.pending[name] = status
+			}
+		default:
+			managed.status = status
+			managed.written = time.Now()
+		}
+		c.mutex.Unlock()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn().Err(err).Str("urlcheck", name).Msg("Failed to update URLCheck status")
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const collection = "/apis/urlexporter.jasoet.github.io/v1alpha1/namespaces/monitoring/urlchecks"
+
+// fakeURLCheckAPI serves a URLCheck list, a watch replaying fixed events and records
+// status patches
+type fakeURLCheckAPI struct {
+	mutex   sync.Mutex
+	list    string
+	events  []string
+	patches map[string]checkStatus
+	watchRV string
+}
+
+func (f *fakeURLCheckAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == collection && r.URL.Query().Get("watch") == "true":
+		f.watchRV = r.URL.Query().Get("resourceVersion")
+		for _, event := range f.events {
+			_, _ = fmt.Fprintln(w, event)
+		}
+	case r.Method == http.MethodGet && r.URL.Path == collection:
+		_, _ = w.Write([]byte(f.list))
+	case r.Method == http.MethodPatch && r.Header.Get("Content-Type") == kube.ContentTypeMergePatch:
+		var patch struct {
+			Status checkStatus `json:"status"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&patch)
+		if f.patches == nil {
+			f.patches = make(map[string]checkStatus)
+		}
+		f.patches[r.URL.Path] = patch.Status
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeURLCheckAPI) patch(name string) (checkStatus, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	status, exists := f.patches[collection+"/"+name+"/status"]
+	return status, exists
+}
+
+// fakeTargets is the live target set, rejecting URLs that are already targets
+type fakeTargets struct {
+	targets map[string]config.Target
+}
+
+func (f *fakeTargets) AddTarget(target config.Target) error {
+	if _, exists := f.targets[target.URL]; exists {
+		return fmt.Errorf("%w: %s", checker.ErrTargetExists, target.URL)
+	}
+	f.targets[target.URL] = target
+	return nil
+}
+
+func (f *fakeTargets) RemoveTarget(url string) bool {
+	_, exists := f.targets[url]
+	delete(f.targets, url)
+	return exists
+}
+
+func newTestController(t *testing.T, api *fakeURLCheckAPI) (*Controller, *fakeTargets) {
+	t.Helper()
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client := &kube.Client{
+		BaseURL: server.URL,
+		HTTP:    http.DefaultClient,
+		Token:   func() (string, error) { return "token", nil },
+	}
+	cfg := &config.Config{Controller: config.ControllerConfig{StatusInterval: time.Minute}}
+	targets := &fakeTargets{targets: make(map[string]config.Target)}
+	return newController(client, "monitoring", cfg, targets), targets
+}
+
+func urlCheckJSON(name, url string, generation int) string {
+	return fmt.Sprintf(`{"metadata":{"name":%q,"resourceVersion":"%d","generation":%d},"spec":{"url":%q}}`,
+		name, 10+generation, generation, url)
+}
+
+func TestController_ListAndWatch(t *testing.T) {
+	api := &fakeURLCheckAPI{
+		list: `{"metadata":{"resourceVersion":"10"},"items":[` +
+			urlCheckJSON("shop", "https://shop.example.com", 1) + `,` +
+			urlCheckJSON("broken", "", 1) + `]}`,
+		events: []string{
+			`{"type":"MODIFIED","object":` + urlCheckJSON("shop", "https://shop.example.com/health", 2) + `}`,
+			`{"type":"ADDED","object":` + urlCheckJSON("blog", "https://blog.example.com", 1) + `}`,
+			`{"type":"DELETED","object":` + urlCheckJSON("blog", "https://blog.example.com", 1) + `}`,
+			`{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"20"}}}`,
+		},
+	}
+	ctrl, targets := newTestController(t, api)
+	ctx := context.Background()
+
+	informer := ctrl.informer()
+	resourceVersion, err := informer.list(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "10", resourceVersion)
+	require.Contains(t, targets.targets, "https://shop.example.com")
+	assert.Equal(t, "shop", targets.targets["https://shop.example.com"].Name, "the name defaults to the resource name")
+	assert.Equal(t, PhaseInvalid, ctrl.pending["broken"].Phase)
+	assert.Equal(t, "url is required", ctrl.pending["broken"].Message)
+
+	resourceVersion, err = informer.watch(ctx, resourceVersion)
+	require.NoError(t, err)
+	assert.Equal(t, "10", api.watchRV)
+	assert.Equal(t, "20", resourceVersion)
+
+	assert.Len(t, targets.targets, 1)
+	assert.Contains(t, targets.targets, "https://shop.example.com/health", "an updated spec replaces the target")
+	name, managed := ctrl.Manages("https://shop.example.com/health")
+	assert.True(t, managed)
+	assert.Equal(t, "shop", name)
+	_, managed = ctrl.Manages("https://blog.example.com")
+	assert.False(t, managed)
+}
+
+func TestController_ResyncRemovesDeletedResources(t *testing.T) {
+	api := &fakeURLCheckAPI{}
+	ctrl, targets := newTestController(t, api)
+
+	ctrl.resync([]urlCheck{
+		{Metadata: objectMeta{Name: "shop"}, Spec: config.Target{URL: "https://shop.example.com"}},
+		{Metadata: objectMeta{Name: "blog"}, Spec: config.Target{URL: "https://blog.example.com"}},
+	})
+	require.Len(t, targets.targets, 2)
+
+	ctrl.resync([]urlCheck{
+		{Metadata: objectMeta{Name: "shop"}, Spec: config.Target{URL: "https://shop.example.com"}},
+	})
+	assert.Len(t, targets.targets, 1)
+	assert.Contains(t, targets.targets, "https://shop.example.com")
+}
+
+func TestController_Conflicts(t *testing.T) {
+	api := &fakeURLCheckAPI{}
+	ctrl, targets := newTestController(t, api)
+	targets.targets["https://configured.example.com"] = config.Target{URL: "https://configured.example.com"}
+
+	ctrl.apply(urlCheck{Metadata: objectMeta{Name: "first"}, Spec: config.Target{URL: "https://shop.example.com"}})
+	ctrl.apply(urlCheck{Metadata: objectMeta{Name: "second"}, Spec: config.Target{URL: "https://shop.example.com"}})
+	ctrl.apply(urlCheck{Metadata: objectMeta{Name: "third"}, Spec: config.Target{URL: "https://configured.example.com"}})
+
+	assert.Equal(t, PhasePending, ctrl.pending["first"].Phase)
+	assert.Equal(t, PhaseInvalid, ctrl.pending["second"].Phase)
+	assert.Equal(t, "url is already checked for URLCheck first", ctrl.pending["second"].Message)
+	assert.Equal(t, PhaseInvalid, ctrl.pending["third"].Phase)
+	assert.Equal(t, "url is already a target of the exporter", ctrl.pending["third"].Message)
+
+	name, _ := ctrl.Manages("https://shop.example.com")
+	assert.Equal(t, "first", name)
+	_, managed := ctrl.Manages("https://configured.example.com")
+	assert.False(t, managed)
+}
+
+func TestController_WatchExpired(t *testing.T) {
+	api := &fakeURLCheckAPI{
+		events: []string{`{"type":"ERROR","object":{"kind":"Status","code":410,"message":"too old resource version"}}`},
+	}
+	ctrl, _ := newTestController(t, api)
+
+	resourceVersion, err := ctrl.informer().watch(context.Background(), "5")
+	assert.True(t, errors.Is(err, errExpired))
+	assert.Empty(t, resourceVersion, "an expired watch relists")
+}
+
+func TestController_Status(t *testing.T) {
+	api := &fakeURLCheckAPI{}
+	ctrl, _ := newTestController(t, api)
+	ctx := context.Background()
+
+	ctrl.apply(urlCheck{
+		Metadata: objectMeta{Name: "shop", Generation: 3},
+		Spec:     config.Target{URL: "https://shop.example.com"},
+	})
+	ctrl.flush(ctx)
+
+	status, written := api.patch("shop")
+	require.True(t, written)
+	assert.Equal(t, PhasePending, status.Phase)
+	assert.Equal(t, int64(3), status.ObservedGeneration)
+
+	checkedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	up := checker.Result{URL: "https://shop.example.com", StatusCode: 200, ResponseTime: 42 * time.Millisecond, Timestamp: checkedAt}
+	ctrl.HandleCycle(ctx, []checker.Result{up})
+	ctrl.flush(ctx)
+
+	status, _ = api.patch("shop")
+	assert.Equal(t, PhaseUp, status.Phase)
+	assert.Equal(t, 200, status.StatusCode)
+	assert.Equal(t, int64(42), status.ResponseTimeMilliseconds)
+	assert.Equal(t, "2025-03-01T12:00:00Z", status.LastCheckTime)
+
+	// An unchanged result is not written again within the status interval
+	ctrl.HandleCycle(ctx, []checker.Result{up})
+	assert.Empty(t, ctrl.pending)
+
+	mismatch := false
+	down := checker.Result{URL: "https://shop.example.com", StatusCode: 200, BodyMatch: &mismatch, Timestamp: checkedAt}
+	ctrl.HandleCycle(ctx, []checker.Result{down})
+	ctrl.flush(ctx)
+
+	status, _ = api.patch("shop")
+	assert.Equal(t, PhaseDown, status.Phase)
+	assert.Equal(t, "response body does not match expectBody", status.Message)
+}
+
+func TestController_StandbyDoesNotWriteStatus(t *testing.T) {
+	api := &fakeURLCheckAPI{}
+	ctrl, _ := newTestController(t, api)
+	ctrl.Active = func() bool { return false }
+
+	ctrl.apply(urlCheck{Metadata: objectMeta{Name: "shop"}, Spec: config.Target{URL: "https://shop.example.com"}})
+	ctrl.flush(context.Background())
+
+	_, written := api.patch("shop")
+	assert.False(t, written)
+}
@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// Annotations read from discovered Ingresses and HTTPRoutes
+const (
+	// AnnotationIgnore set to "true" keeps the hosts of the resource from being checked
+	AnnotationIgnore = Group + "/ignore"
+	// AnnotationPath overrides the path checked on the hosts of the resource
+	AnnotationPath = Group + "/path"
+)
+
+// ingress is the part of a networking.k8s.io/v1 Ingress that publishes hosts
+type ingress struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Rules []struct {
+			Host string `json:"host"`
+		} `json:"rules"`
+		TLS []struct {
+			Hosts []string `json:"hosts"`
+		} `json:"tls"`
+	} `json:"spec"`
+}
+
+// httpRoute is the part of a gateway.networking.k8s.io/v1 HTTPRoute that publishes hosts
+type httpRoute struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Hostnames []string `json:"hostnames"`
+	} `json:"spec"`
+}
+
+// Discovery checks the hosts published by the Ingresses and HTTPRoutes of the namespaces
+// that opted in. A host published by several resources is checked once, until the last of
+// them goes away.
+type Discovery struct {
+	api         *kube.Client
+	config      config.DiscoveryConfig
+	targets     Targets
+	inShard     func(url string) bool
+	retryPeriod time.Duration
+
+	mutex   sync.Mutex
+	sources map[string][]config.Target // source, e.g. "Ingress shop/web" -> targets it publishes
+	owners  map[string][]string        // target URL -> sources publishing it, sorted
+	added   map[string]bool            // target URLs added; the others were targets already
+}
+
+// NewDiscovery creates the discovery of the configured namespaces
+func NewDiscovery(cfg *config.Config, targets Targets) (*Discovery, error) {
+	api, err := kube.InCluster()
+	if err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	return newDiscovery(api, cfg, targets), nil
+}
+
+func newDiscovery(api *kube.Client, cfg *config.Config, targets Targets) *Discovery {
+	return &Discovery{
+		api:         api,
+		config:      cfg.Discovery,
+		targets:     targets,
+		inShard:     cfg.InShard,
+		retryPeriod: 5 * time.Second,
+		sources:     make(map[string][]config.Target),
+		owners:      make(map[string][]string),
+		added:       make(map[string]bool),
+	}
+}
+
+// Manages reports whether the target was added for a discovered resource, and returns the
+// resource, e.g. "Ingress shop/web"
+func (d *Discovery) Manages(url string) (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.added[url] {
+		return "", false
+	}
+	return d.owners[url][0], true
+}
+
+// Run watches the resources of every namespace until the context is done
+func (d *Discovery) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, namespace := range d.config.Namespaces {
+		for _, kind := range d.config.Kinds {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.informer(namespace, kind).run(ctx)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// informer watches the resources of one kind in the namespace
+func (d *Discovery) informer(namespace, kind string) *informer {
+	path := fmt.Sprintf("/apis/networking.k8s.io/v1/namespaces/%s/ingresses", namespace)
+	resourceKind := "Ingress"
+	if kind == config.DiscoveryKindHTTPRoute {
+		path = fmt.Sprintf("/apis/gateway.networking.k8s.io/v1/namespaces/%s/httproutes", namespace)
+		resourceKind = "HTTPRoute"
+	}
+
+	decode := func(object json.RawMessage) (string, []config.Target, bool) {
+		source, targets, err := d.decode(resourceKind, object)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Ignoring %s that cannot be decoded", resourceKind)
+			return "", nil, false
+		}
+		return source, targets, true
+	}
+
+	return &informer{
+		api:         d.api,
+		path:        path,
+		kind:        resourceKind,
+		retryPeriod: d.retryPeriod,
+		resync: func(objects []json.RawMessage) {
+			listed := make(map[string][]config.Target, len(objects))
+			for _, object := range objects {
+				if source, targets, ok := decode(object); ok {
+					listed[source] = targets
+				}
+			}
+			d.resync(resourceKind+" "+namespace+"/", listed)
+		},
+		apply: func(object json.RawMessage) {
+			if source, targets, ok := decode(object); ok {
+				d.update(source, targets)
+			}
+		},
+		remove: func(object json.RawMessage) {
+			if source, _, ok := decode(object); ok {
+				d.update(source, nil)
+			}
+		},
+	}
+}
+
+// decode returns the source name of the resource and the targets for its hosts
+func (d *Discovery) decode(kind string, object json.RawMessage) (string, []config.Target, error) {
+	var (
+		meta  objectMeta
+		hosts []string
+		tls   = make(map[string]bool)
+	)
+	switch kind {
+	case "Ingress":
+		var resource ingress
+		if err := json.Unmarshal(object, &resource); err != nil {
+			return "", nil, err
+		}
+		meta = resource.Metadata
+		for _, rule := range resource.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+		for _, entry := range resource.Spec.TLS {
+			for _, host := range entry.Hosts {
+				tls[host] = true
+			}
+		}
+	default:
+		var resource httpRoute
+		if err := json.Unmarshal(object, &resource); err != nil {
+			return "", nil, err
+		}
+		meta = resource.Metadata
+		hosts = resource.Spec.Hostnames
+		// The listener of the Gateway decides; HTTPS is the safe assumption
+		for _, host := range hosts {
+			tls[host] = true
+		}
+	}
+
+	source := kind + " " + meta.Namespace + "/" + meta.Name
+	if meta.Annotations[AnnotationIgnore] == "true" {
+		return source, nil, nil
+	}
+
+	path := d.config.Path
+	if annotated := meta.Annotations[AnnotationPath]; strings.HasPrefix(annotated, "/") {
+		path = annotated
+	}
+
+	var targets []config.Target
+	for _, host := range hosts {
+		// Rules without a host and wildcard hosts do not name a single address to check
+		if host == "" || strings.Contains(host, "*") {
+			continue
+		}
+		scheme := d.config.Scheme
+		if scheme == "" {
+			scheme = "http"
+			if tls[host] {
+				scheme = "https"
+			}
+		}
+		target := config.Target{
+			URL:   scheme + "://" + host + path,
+			Name:  host,
+			Group: d.config.Group,
+			Labels: map[string]string{
+				"namespace":           meta.Namespace,
+				strings.ToLower(kind): meta.Name,
+			},
+		}
+		if !slices.ContainsFunc(targets, func(t config.Target) bool { return t.URL == target.URL }) {
+			targets = append(targets, target)
+		}
+	}
+	return source, targets, nil
+}
+
+// resync applies a full listing of the sources with the prefix: the sources that no
+// longer exist are dropped
+func (d *Discovery) resync(prefix string, listed map[string][]config.Target) {
+	d.mutex.Lock()
+	var gone []string
+	for source := range d.sources {
+		if _, exists := listed[source]; !exists && strings.HasPrefix(source, prefix) {
+			gone = append(gone, source)
+		}
+	}
+	d.mutex.Unlock()
+
+	for _, source := range gone {
+		d.update(source, nil)
+	}
+	for source, targets := range listed {
+		d.update(source, targets)
+	}
+}
+
+// update replaces the targets published by the source; nil drops the source
+func (d *Discovery) update(source string, targets []config.Target) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	previous := d.sources[source]
+	if targets == nil {
+		delete(d.sources, source)
+	} else {
+		d.sources[source] = targets
+	}
+
+	for _, target := range previous {
+		if !slices.ContainsFunc(targets, func(t config.Target) bool { return t.URL == target.URL }) {
+			d.release(source, target.URL)
+		}
+	}
+	for _, target := range targets {
+		if !slices.ContainsFunc(previous, func(t config.Target) bool { return t.URL == target.URL }) {
+			d.claim(source, target)
+		}
+	}
+}
+
+// claim records the source as publishing the target and adds the target if it is the
+// first to. The caller must hold the lock.
+func (d *Discovery) claim(source string, target config.Target) {
+	owners := append(d.owners[target.URL], source)
+	sort.Strings(owners)
+	d.owners[target.URL] = owners
+	if len(owners) > 1 || !d.inShard(target.URL) {
+		return
+	}
+
+	if err := d.targets.AddTarget(target); err != nil {
+		if errors.Is(err, checker.ErrTargetExists) {
+			log.Debug().Str("source", source).Str("url", config.RedactURL(target.URL)).Msg("Discovered host is already a target")
+		} else {
+			log.Warn().Err(err).Str("source", source).Str("url", config.RedactURL(target.URL)).Msg("Failed to add discovered host")
+		}
+		return
+	}
+	d.added[target.URL] = true
+	log.Info().Str("source", source).Str("url", config.RedactURL(target.URL)).Msg("Target added for discovered host")
+}
+
+// release drops the source from the publishers of the URL and removes the target once
+// none is left. The caller must hold the lock.
+func (d *Discovery) release(source, url string) {
+	owners := slices.DeleteFunc(d.owners[url], func(owner string) bool { return owner == source })
+	if len(owners) > 0 {
+		d.owners[url] = owners
+		return
+	}
+
+	delete(d.owners, url)
+	if d.added[url] {
+		d.targets.RemoveTarget(url)
+		delete(d.added, url)
+		log.Info().Str("source", source).Str("url", config.RedactURL(url)).Msg("Target removed for discovered host")
+	}
+}
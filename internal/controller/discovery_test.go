@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiscovery(t *testing.T, handler http.Handler, discovery config.DiscoveryConfig) (*Discovery, *fakeTargets) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := &kube.Client{
+		BaseURL: server.URL,
+		HTTP:    http.DefaultClient,
+		Token:   func() (string, error) { return "token", nil },
+	}
+	if discovery.Path == "" {
+		discovery.Path = "/"
+	}
+	targets := &fakeTargets{targets: make(map[string]config.Target)}
+	return newDiscovery(client, &config.Config{Discovery: discovery}, targets), targets
+}
+
+func TestDiscovery_Ingresses(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/networking.k8s.io/v1/namespaces/shop/ingresses" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"metadata":{"resourceVersion":"7"},"items":[
+			{"metadata":{"name":"web","namespace":"shop"},"spec":{
+				"rules":[{"host":"shop.example.com"},{"host":"api.example.com"},{"host":"*.example.com"},{}],
+				"tls":[{"hosts":["shop.example.com"]}]}},
+			{"metadata":{"name":"internal","namespace":"shop","annotations":{"urlexporter.jasoet.github.io/ignore":"true"}},
+				"spec":{"rules":[{"host":"internal.example.com"}]}}
+		]}`))
+	})
+	discovery, targets := newTestDiscovery(t, api, config.DiscoveryConfig{Namespaces: []string{"shop"}, Group: "ingress"})
+
+	resourceVersion, err := discovery.informer("shop", config.DiscoveryKindIngress).list(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "7", resourceVersion)
+
+	require.Len(t, targets.targets, 2, "wildcard and empty hosts and ignored resources are skipped")
+	shop := targets.targets["https://shop.example.com/"]
+	assert.Equal(t, "shop.example.com", shop.Name)
+	assert.Equal(t, "ingress", shop.Group)
+	assert.Equal(t, map[string]string{"namespace": "shop", "ingress": "web"}, shop.Labels)
+	assert.Contains(t, targets.targets, "http://api.example.com/", "hosts without TLS are checked over http")
+
+	source, managed := discovery.Manages("https://shop.example.com/")
+	assert.True(t, managed)
+	assert.Equal(t, "Ingress shop/web", source)
+}
+
+func TestDiscovery_HTTPRoutePath(t *testing.T) {
+	discovery, _ := newTestDiscovery(t, http.NotFoundHandler(), config.DiscoveryConfig{Path: "/health"})
+
+	source, targets, err := discovery.decode("HTTPRoute", []byte(`{"metadata":{"name":"api","namespace":"shop"},
+		"spec":{"hostnames":["api.example.com"]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "HTTPRoute shop/api", source)
+	require.Len(t, targets, 1)
+	assert.Equal(t, "https://api.example.com/health", targets[0].URL)
+
+	_, targets, err = discovery.decode("HTTPRoute", []byte(`{"metadata":{"name":"api","namespace":"shop",
+		"annotations":{"urlexporter.jasoet.github.io/path":"/ready"}},"spec":{"hostnames":["api.example.com"]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/ready", targets[0].URL, "the path annotation overrides the configured path")
+}
+
+func TestDiscovery_SharedHosts(t *testing.T) {
+	discovery, targets := newTestDiscovery(t, http.NotFoundHandler(), config.DiscoveryConfig{})
+	shop := []config.Target{{URL: "https://shop.example.com/", Name: "shop.example.com"}}
+
+	discovery.update("Ingress shop/web", shop)
+	discovery.update("HTTPRoute shop/web", shop)
+	require.Len(t, targets.targets, 1)
+
+	discovery.update("Ingress shop/web", nil)
+	assert.Len(t, targets.targets, 1, "the host is checked while a resource still publishes it")
+
+	discovery.resync("HTTPRoute shop/", map[string][]config.Target{})
+	assert.Empty(t, targets.targets)
+	_, managed := discovery.Manages("https://shop.example.com/")
+	assert.False(t, managed)
+}
+
+func TestDiscovery_ExistingTarget(t *testing.T) {
+	discovery, targets := newTestDiscovery(t, http.NotFoundHandler(), config.DiscoveryConfig{})
+	configured := config.Target{URL: "https://shop.example.com/", Group: "configured"}
+	targets.targets[configured.URL] = configured
+
+	discovery.update("Ingress shop/web", []config.Target{{URL: "https://shop.example.com/", Name: "shop.example.com"}})
+	_, managed := discovery.Manages("https://shop.example.com/")
+	assert.False(t, managed)
+
+	discovery.update("Ingress shop/web", nil)
+	assert.Equal(t, configured, targets.targets[configured.URL], "a target that was configured is left alone")
+}
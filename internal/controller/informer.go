@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// watchTimeout makes the API server end a watch after a while, so a watch that silently
+// broke is noticed
+const watchTimeout = 5 * time.Minute
+
+// errExpired means the resource version to watch from is too old and a relist is needed
+var errExpired = errors.New("resource version expired")
+
+// informer lists and watches a collection of the API server and hands its objects to
+// the callbacks
+type informer struct {
+	api         *kube.Client
+	path        string // collection path, e.g. /apis/<group>/<version>/namespaces/<ns>/<resource>
+	kind        string // kind of the objects, for messages
+	retryPeriod time.Duration
+
+	// resync receives a full listing, apply an added or updated object and remove a deleted one
+	resync func(objects []json.RawMessage)
+	apply  func(object json.RawMessage)
+	remove func(object json.RawMessage)
+}
+
+// objectMeta is the part of the object metadata the controller uses
+type objectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Generation      int64             `json:"generation,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type objectList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// apiStatus is the Status object the API server sends with a watch ERROR event
+type apiStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// run lists and watches the collection until the context is done. Lost watches resume
+// where they left off; when that is no longer possible everything is relisted.
+func (i *informer) run(ctx context.Context) {
+	resourceVersion := ""
+	for {
+		var err error
+		if resourceVersion == "" {
+			resourceVersion, err = i.list(ctx)
+		}
+		if err == nil {
+			resourceVersion, err = i.watch(ctx, resourceVersion)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil || errors.Is(err, errExpired) {
+			continue
+		}
+
+		log.Warn().Err(err).Str("path", i.path).Msgf("Failed to watch %s resources, retrying", i.kind)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(i.retryPeriod):
+		}
+	}
+}
+
+// list resyncs the current objects and returns the resource version to watch from
+func (i *informer) list(ctx context.Context) (string, error) {
+	resp, err := i.api.Do(ctx, http.MethodGet, i.path, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%s resources not found at %s, is the resource installed?", i.kind, i.path)
+	default:
+		return "", fmt.Errorf("unexpected status %d listing %s resources", resp.StatusCode, i.kind)
+	}
+
+	var list objectList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return "", fmt.Errorf("failed to decode %s list: %w", i.kind, err)
+	}
+	i.resync(list.Items)
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch applies the changes after the resource version until the watch ends and returns
+// the last resource version seen
+func (i *informer) watch(ctx context.Context, resourceVersion string) (string, error) {
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("allowWatchBookmarks", "true")
+	query.Set("resourceVersion", resourceVersion)
+	query.Set("timeoutSeconds", fmt.Sprint(int(watchTimeout.Seconds())))
+
+	resp, err := i.api.Stream(ctx, i.path+"?"+query.Encode())
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusGone:
+		return "", errExpired
+	default:
+		return resourceVersion, fmt.Errorf("unexpected status %d watching %s resources", resp.StatusCode, i.kind)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return resourceVersion, nil
+			}
+			return resourceVersion, fmt.Errorf("failed to decode watch event: %w", err)
+		}
+
+		if event.Type == "ERROR" {
+			var status apiStatus
+			_ = json.Unmarshal(event.Object, &status)
+			if status.Code == http.StatusGone {
+				return "", errExpired
+			}
+			return resourceVersion, fmt.Errorf("watch error %d: %s", status.Code, status.Message)
+		}
+
+		var object struct {
+			Metadata objectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(event.Object, &object); err != nil {
+			return resourceVersion, fmt.Errorf("failed to decode %s: %w", i.kind, err)
+		}
+		resourceVersion = object.Metadata.ResourceVersion
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			i.apply(event.Object)
+		case "DELETED":
+			i.remove(event.Object)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// API group, version and resource of the URLCheck custom resource
+const (
+	Group    = "urlexporter.jasoet.github.io"
+	Version  = "v1alpha1"
+	Resource = "urlchecks"
+)
+
+// urlCheck is a URLCheck resource. Its spec is a target as accepted by the API.
+type urlCheck struct {
+	Metadata objectMeta    `json:"metadata"`
+	Spec     config.Target `json:"spec"`
+}
+
+// checkStatus is the status subresource of a URLCheck
+type checkStatus struct {
+	Phase                    string `json:"phase"`
+	Message                  string `json:"message,omitempty"`
+	StatusCode               int    `json:"statusCode,omitempty"`
+	ResponseTimeMilliseconds int64  `json:"responseTimeMilliseconds,omitempty"`
+	LastCheckTime            string `json:"lastCheckTime,omitempty"`
+	ObservedGeneration       int64  `json:"observedGeneration,omitempty"`
+}
+
+// resultStatus describes a check result as URLCheck status
+func resultStatus(result checker.Result) checkStatus {
+	status := checkStatus{
+		Phase:                    PhaseDown,
+		StatusCode:               result.StatusCode,
+		ResponseTimeMilliseconds: result.ResponseTime.Milliseconds(),
+		LastCheckTime:            result.Timestamp.UTC().Format(time.RFC3339),
+	}
+
+	switch {
+	case result.IsSkipped():
+		status.Message = "skipped, dependency " + config.RedactURL(result.SkippedBy) + " is down"
+	case result.Error != nil:
+		status.StatusCode = 0
+		status.ResponseTimeMilliseconds = 0
+		status.Message = result.Error.Error()
+	case result.BodyMatch != nil && !*result.BodyMatch:
+		status.Message = "response body does not match expectBody"
+	case result.SizeWithinBounds != nil && !*result.SizeWithinBounds:
+		status.Message = "response body size out of minBodyBytes and maxBodyBytes"
+	case result.HeaderMatch != nil && !*result.HeaderMatch:
+		status.Message = "response headers do not match expectHeaders or expectContentType"
+	case result.IsUp():
+		status.Phase = PhaseUp
+	}
+	return status
+}
+
+// sameResult reports whether both statuses describe the same outcome, ignoring when it
+// was checked and how long it took
+func (s checkStatus) sameResult(other checkStatus) bool {
+	return s.Phase == other.Phase && s.Message == other.Message && s.StatusCode == other.StatusCode &&
+		s.ObservedGeneration == other.ObservedGeneration
+}
+
+func (c *Controller) collectionPath() string {
+	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", Group, Version, c.namespace, Resource)
+}
+
+// informer watches the URLCheck resources of the namespace
+func (c *Controller) informer() *informer {
+	return &informer{
+		api:         c.api,
+		path:        c.collectionPath(),
+		kind:        "URLCheck",
+		retryPeriod: c.retryPeriod,
+		resync: func(objects []json.RawMessage) {
+			checks := make([]urlCheck, 0, len(objects))
+			for _, object := range objects {
+				if check, ok := decodeURLCheck(object); ok {
+					checks = append(checks, check)
+				}
+			}
+			c.resync(checks)
+		},
+		apply: func(object json.RawMessage) {
+			if check, ok := decodeURLCheck(object); ok {
+				c.apply(check)
+			}
+		},
+		remove: func(object json.RawMessage) {
+			if check, ok := decodeURLCheck(object); ok {
+				c.remove(check.Metadata.Name)
+			}
+		},
+	}
+}
+
+// decodeURLCheck decodes a URLCheck. The CRD schema makes a malformed one unlikely; it is
+// skipped when it happens.
+func decodeURLCheck(object json.RawMessage) (urlCheck, bool) {
+	var check urlCheck
+	if err := json.Unmarshal(object, &check); err != nil {
+		log.Warn().Err(err).Msg("Ignoring URLCheck that cannot be decoded")
+		return check, false
+	}
+	return check, true
+}
+
+// patchStatus writes the status subresource of the named resource. A resource deleted
+// meanwhile is not an error.
+func (c *Controller) patchStatus(ctx context.Context, name string, status checkStatus) error {
+	body, err := json.Marshal(map[string]checkStatus{"status": status})
+	if err != nil {
+		return fmt.Errorf("failed to encode URLCheck status: %w", err)
+	}
+
+	resp, err := c.api.Do(ctx, http.MethodPatch, c.collectionPath()+"/"+name+"/status", body, kube.ContentTypeMergePatch)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound || (resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return nil
+	}
+	return fmt.Errorf("unexpected status %d updating URLCheck %s/%s", resp.StatusCode, c.namespace, name)
+}
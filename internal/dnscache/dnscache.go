@@ -0,0 +1,368 @@
+// Package dnscache resolves the hostnames of checked targets through a cache that keeps
+// each answer for the TTL of its DNS records, so frequent checks of few hosts do not send
+// a DNS query each.
+package dnscache
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fallbackTTL is how long answers of the system resolver are cached, which does not
+// report TTLs
+const fallbackTTL = 30 * time.Second
+
+// queryTimeout bounds a single query to a nameserver
+const queryTimeout = 5 * time.Second
+
+// errNoAnswer is returned when the nameservers have no usable answer and the system
+// resolver is asked instead
+var errNoAnswer = errors.New("no answer")
+
+// DialFunc dials a network address, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// bypassKey is the context key that makes a dial skip the cache
+type bypassKey struct{}
+
+// WithoutCache returns a context whose dials resolve the host without the cache
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+type entry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// call is an in-flight lookup that concurrent lookups of the same host wait for
+type call struct {
+	done  chan struct{}
+	addrs []string
+	err   error
+}
+
+// Resolver is a caching resolver, safe for concurrent use
+type Resolver struct {
+	minTTL  time.Duration
+	maxTTL  time.Duration
+	servers []string
+	// hosts are the names in the hosts file, which the system resolver answers
+	hosts map[string]bool
+	// system resolves names the nameservers do not answer, e.g. Kubernetes short names
+	// that need the search domains
+	system func(ctx context.Context, host string) ([]string, error)
+	now    func() time.Time
+
+	mutex    sync.Mutex
+	entries  map[string]entry
+	inflight map[string]*call
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// New creates a resolver that queries the nameservers of /etc/resolv.conf
+func New(cfg config.DNSCacheConfig) *Resolver {
+	return &Resolver{
+		minTTL:   cfg.MinTTL,
+		maxTTL:   cfg.MaxTTL,
+		servers:  readNameservers("/etc/resolv.conf"),
+		hosts:    readHosts("/etc/hosts"),
+		system:   net.DefaultResolver.LookupHost,
+		now:      time.Now,
+		entries:  make(map[string]entry),
+		inflight: make(map[string]*call),
+	}
+}
+
+// Hits returns the number of lookups answered from the cache or by a concurrent lookup
+// of the same host
+func (r *Resolver) Hits() uint64 {
+	return r.hits.Load()
+}
+
+// Misses returns the number of lookups that had to query DNS
+func (r *Resolver) Misses() uint64 {
+	return r.misses.Load()
+}
+
+// Wrap returns a dial function that resolves the host through the cache and dials its
+// addresses with dial in turn until one connects. IP addresses and dials with a context
+// from WithoutCache are passed to dial unchanged.
+func (r *Resolver) Wrap(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil || bypassed(ctx) {
+			return dial(ctx, network, address)
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var dialErr error
+		for _, addr := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, dialErr
+	}
+}
+
+// LookupHost returns the addresses of the host, from the cache while its TTL lasts
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	now := r.now()
+
+	r.mutex.Lock()
+	if cached, ok := r.entries[host]; ok && now.Before(cached.expires) {
+		r.mutex.Unlock()
+		r.hits.Add(1)
+		return cached.addrs, nil
+	}
+	if pending, ok := r.inflight[host]; ok {
+		r.mutex.Unlock()
+		r.hits.Add(1)
+		select {
+		case <-pending.done:
+			return pending.addrs, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := &call{done: make(chan struct{})}
+	r.inflight[host] = pending
+	r.mutex.Unlock()
+	r.misses.Add(1)
+
+	addrs, ttl, err := r.resolve(ctx, host)
+	pending.addrs, pending.err = addrs, err
+
+	r.mutex.Lock()
+	delete(r.inflight, host)
+	if err == nil {
+		if ttl = r.clamp(ttl); ttl > 0 {
+			r.prune(now)
+			r.entries[host] = entry{addrs: addrs, expires: now.Add(ttl)}
+		}
+	}
+	r.mutex.Unlock()
+	close(pending.done)
+
+	return addrs, err
+}
+
+// resolve queries the nameservers, leaving names they cannot answer to the system resolver
+func (r *Resolver) resolve(ctx context.Context, host string) ([]string, time.Duration, error) {
+	if !r.hosts[host] && strings.Contains(host, ".") {
+		addrs, ttl, err := r.query(ctx, host)
+		if err == nil {
+			return addrs, ttl, nil
+		}
+		if ctx.Err() != nil {
+			return nil, 0, err
+		}
+	}
+
+	addrs, err := r.system(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	return addrs, fallbackTTL, nil
+}
+
+// clamp applies the configured TTL bounds
+func (r *Resolver) clamp(ttl time.Duration) time.Duration {
+	if ttl < r.minTTL {
+		ttl = r.minTTL
+	}
+	if r.maxTTL > 0 && ttl > r.maxTTL {
+		ttl = r.maxTTL
+	}
+	return ttl
+}
+
+// prune drops expired entries, e.g. of hosts no longer checked. The mutex must be held.
+func (r *Resolver) prune(now time.Time) {
+	for host, cached := range r.entries {
+		if !now.Before(cached.expires) {
+			delete(r.entries, host)
+		}
+	}
+}
+
+// query asks the nameservers for the A and AAAA records of the host, returning the
+// addresses and the lowest TTL of the answers
+func (r *Resolver) query(ctx context.Context, host string) ([]string, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid hostname %q: %w", host, err)
+	}
+
+	var lastErr error = errNoAnswer
+	for _, server := range r.servers {
+		var addrs []string
+		var ttl uint32
+		answered := true
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			found, foundTTL, err := exchange(ctx, server, name, qtype)
+			if err != nil {
+				lastErr = err
+				answered = false
+				break
+			}
+			if len(found) > 0 && (len(addrs) == 0 || foundTTL < ttl) {
+				ttl = foundTTL
+			}
+			addrs = append(addrs, found...)
+		}
+		if !answered {
+			if ctx.Err() != nil {
+				return nil, 0, ctx.Err()
+			}
+			continue
+		}
+		if len(addrs) == 0 {
+			return nil, 0, errNoAnswer
+		}
+		return addrs, time.Duration(ttl) * time.Second, nil
+	}
+	return nil, 0, lastErr
+}
+
+// exchange sends a single query over UDP. Truncated and failed answers are reported as
+// errNoAnswer, so the system resolver retries them.
+func exchange(ctx context.Context, server string, name dnsmessage.Name, qtype dnsmessage.Type) ([]string, uint32, error) {
+	var idBytes [2]byte
+	_, _ = rand.Read(idBytes[:])
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := query.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to pack DNS query: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to reach nameserver %s: %w", server, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(queryTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(packet); err != nil {
+		return nil, 0, fmt.Errorf("failed to query nameserver %s: %w", server, err)
+	}
+
+	buffer := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, 0, fmt.Errorf("no response from nameserver %s: %w", server, err)
+		}
+
+		var response dnsmessage.Message
+		if err := response.Unpack(buffer[:n]); err != nil || response.ID != id || !response.Response {
+			// Ignore stray or malformed packets until the deadline
+			continue
+		}
+		if response.Truncated || response.RCode != dnsmessage.RCodeSuccess {
+			return nil, 0, errNoAnswer
+		}
+
+		var addrs []string
+		var ttl uint32
+		for _, answer := range response.Answers {
+			var addr net.IP
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				addr = body.A[:]
+			case *dnsmessage.AAAAResource:
+				addr = body.AAAA[:]
+			default:
+				continue
+			}
+			if len(addrs) == 0 || answer.Header.TTL < ttl {
+				ttl = answer.Header.TTL
+			}
+			addrs = append(addrs, addr.String())
+		}
+		return addrs, ttl, nil
+	}
+}
+
+// readNameservers returns the nameservers of a resolv.conf file, defaulting to the local
+// resolver like the Go resolver does
+func readNameservers(path string) []string {
+	var servers []string
+	file, err := os.Open(path)
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+				servers = append(servers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1:53", "[::1]:53"}
+	}
+	return servers
+}
+
+// readHosts returns the names listed in a hosts file
+func readHosts(path string) map[string]bool {
+	hosts := make(map[string]bool)
+	file, err := os.Open(path)
+	if err != nil {
+		return hosts
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		for _, name := range fields[min(1, len(fields)):] {
+			hosts[strings.ToLower(strings.TrimSuffix(name, "."))] = true
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,243 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeNameserver answers A queries for its records over UDP and counts the queries
+type fakeNameserver struct {
+	conn    net.PacketConn
+	records map[string][4]byte
+	ttl     uint32
+	queries atomic.Int64
+}
+
+func startNameserver(t *testing.T, ttl uint32, records map[string][4]byte) *fakeNameserver {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ns := &fakeNameserver{conn: conn, records: records, ttl: ttl}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buffer[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			ns.queries.Add(1)
+
+			question := query.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			a, ok := ns.records[question.Name.String()]
+			if !ok {
+				response.RCode = dnsmessage.RCodeNameError
+			} else if question.Type == dnsmessage.TypeA {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ns.ttl},
+					Body:   &dnsmessage.AResource{A: a},
+				}}
+			}
+			packet, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packet, addr)
+		}
+	}()
+
+	return ns
+}
+
+func newTestResolver(cfg config.DNSCacheConfig, servers ...string) *Resolver {
+	r := New(cfg)
+	r.servers = servers
+	r.hosts = map[string]bool{}
+	r.system = func(ctx context.Context, host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	return r
+}
+
+func TestResolver_CachesForTTL(t *testing.T) {
+	ns := startNameserver(t, 60, map[string][4]byte{"app.example.test.": {10, 0, 0, 1}})
+
+	now := time.Now()
+	r := newTestResolver(config.DNSCacheConfig{MaxTTL: time.Hour}, ns.conn.LocalAddr().String())
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		addrs, err := r.LookupHost(context.Background(), "App.Example.Test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	}
+	assert.Equal(t, uint64(1), r.Misses())
+	assert.Equal(t, uint64(2), r.Hits())
+	assert.Equal(t, int64(2), ns.queries.Load(), "one A and one AAAA query")
+
+	// The answer expires with its TTL
+	now = now.Add(61 * time.Second)
+	_, err := r.LookupHost(context.Background(), "app.example.test")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), r.Misses())
+	assert.Equal(t, int64(4), ns.queries.Load())
+}
+
+func TestResolver_ClampsTTL(t *testing.T) {
+	ns := startNameserver(t, 3600, map[string][4]byte{"app.example.test.": {10, 0, 0, 1}})
+
+	now := time.Now()
+	r := newTestResolver(config.DNSCacheConfig{MaxTTL: time.Minute}, ns.conn.LocalAddr().String())
+	r.now = func() time.Time { return now }
+
+	_, err := r.LookupHost(context.Background(), "app.example.test")
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = r.LookupHost(context.Background(), "app.example.test")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), r.Misses(), "maxTTL caps the record TTL")
+
+	// A zero TTL is not cached unless minTTL is set
+	zero := startNameserver(t, 0, map[string][4]byte{"app.example.test.": {10, 0, 0, 1}})
+	r = newTestResolver(config.DNSCacheConfig{MaxTTL: time.Minute}, zero.conn.LocalAddr().String())
+	_, _ = r.LookupHost(context.Background(), "app.example.test")
+	_, _ = r.LookupHost(context.Background(), "app.example.test")
+	assert.Equal(t, uint64(2), r.Misses())
+
+	r = newTestResolver(config.DNSCacheConfig{MinTTL: time.Minute, MaxTTL: time.Minute}, zero.conn.LocalAddr().String())
+	_, _ = r.LookupHost(context.Background(), "app.example.test")
+	_, _ = r.LookupHost(context.Background(), "app.example.test")
+	assert.Equal(t, uint64(1), r.Misses())
+}
+
+func TestResolver_FallsBackToSystemResolver(t *testing.T) {
+	ns := startNameserver(t, 60, map[string][4]byte{})
+
+	r := newTestResolver(config.DNSCacheConfig{MaxTTL: time.Hour}, ns.conn.LocalAddr().String())
+	r.hosts = map[string]bool{"db.example.test": true}
+	var systemLookups []string
+	r.system = func(ctx context.Context, host string) ([]string, error) {
+		systemLookups = append(systemLookups, host)
+		return []string{"10.0.0.2"}, nil
+	}
+
+	// Unknown names, short names and hosts file entries are left to the system resolver
+	for _, host := range []string{"missing.example.test", "my-service", "db.example.test"} {
+		addrs, err := r.LookupHost(context.Background(), host)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.2"}, addrs)
+	}
+	assert.Equal(t, []string{"missing.example.test", "my-service", "db.example.test"}, systemLookups)
+	assert.Equal(t, int64(1), ns.queries.Load(), "only the unknown dotted name is queried")
+
+	// Their answers are cached too
+	_, err := r.LookupHost(context.Background(), "my-service")
+	require.NoError(t, err)
+	assert.Len(t, systemLookups, 3)
+
+	r.system = func(ctx context.Context, host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	_, err = r.LookupHost(context.Background(), "other-service")
+	assert.Error(t, err)
+}
+
+func TestResolver_CoalescesConcurrentLookups(t *testing.T) {
+	release := make(chan struct{})
+	var lookups atomic.Int64
+	r := newTestResolver(config.DNSCacheConfig{MaxTTL: time.Hour})
+	r.system = func(ctx context.Context, host string) ([]string, error) {
+		lookups.Add(1)
+		<-release
+		return []string{"10.0.0.3"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := r.LookupHost(context.Background(), "my-service")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"10.0.0.3"}, addrs)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), lookups.Load())
+	assert.Equal(t, uint64(1), r.Misses())
+	assert.Equal(t, uint64(9), r.Hits())
+}
+
+func TestResolver_Wrap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	ns := startNameserver(t, 60, map[string][4]byte{"app.example.test.": {127, 0, 0, 1}})
+	r := newTestResolver(config.DNSCacheConfig{MaxTTL: time.Hour}, ns.conn.LocalAddr().String())
+
+	var dialed []string
+	var dialer net.Dialer
+	dial := r.Wrap(func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		return dialer.DialContext(ctx, network, address)
+	})
+
+	conn, err := dial(context.Background(), "tcp", net.JoinHostPort("app.example.test", port))
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, []string{net.JoinHostPort("127.0.0.1", port)}, dialed)
+
+	// IP addresses and bypassed dials are passed through
+	conn, err = dial(context.Background(), "tcp", net.JoinHostPort("127.0.0.1", port))
+	require.NoError(t, err)
+	_ = conn.Close()
+	_, err = dial(WithoutCache(context.Background()), "tcp", net.JoinHostPort("app.example.test", port))
+	assert.Error(t, err)
+	assert.Equal(t, net.JoinHostPort("app.example.test", port), dialed[2])
+	assert.Equal(t, uint64(1), r.Misses())
+	assert.Equal(t, uint64(0), r.Hits())
+}
+
+func TestReadNameservers(t *testing.T) {
+	path := t.TempDir() + "/resolv.conf"
+	require.NoError(t, os.WriteFile(path, []byte("# comment\nsearch svc.cluster.local\nnameserver 10.96.0.10\nnameserver 2001:db8::53\noptions ndots:5\n"), 0644))
+	assert.Equal(t, []string{"10.96.0.10:53", "[2001:db8::53]:53"}, readNameservers(path))
+
+	assert.Equal(t, []string{"127.0.0.1:53", "[::1]:53"}, readNameservers(t.TempDir()+"/missing"))
+}
+
+func TestReadHosts(t *testing.T) {
+	path := t.TempDir() + "/hosts"
+	require.NoError(t, os.WriteFile(path, []byte("127.0.0.1 localhost # loopback\n10.0.0.5 DB.example.test db\n\n# 10.0.0.6 old.example.test\n"), 0644))
+	assert.Equal(t, map[string]bool{"localhost": true, "db.example.test": true, "db": true}, readHosts(path))
+}
@@ -0,0 +1,115 @@
+// Package events keeps a bounded log of notable exporter events, such as target state
+// changes and configuration reloads, for operators to see what happened recently.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Event types
+const (
+	// TypeStateChange is recorded when a target goes down or recovers
+	TypeStateChange = "state_change"
+	// TypeCheckError is recorded when a check fails with an error different from the
+	// previous check of the target
+	TypeCheckError = "check_error"
+	// TypeReload is recorded when the configuration is reloaded
+	TypeReload = "reload"
+	// TypeTargetAdded is recorded when a target is added at runtime
+	TypeTargetAdded = "target_added"
+	// TypeTargetRemoved is recorded when a target is removed at runtime
+	TypeTargetRemoved = "target_removed"
+	// TypeLeadership is recorded when this replica becomes leader or loses leadership
+	TypeLeadership = "leadership"
+)
+
+// Event is a single entry of the event log. Target is the redacted URL of its target.
+type Event struct {
+	ID      uint64    `json:"id"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Target  string    `json:"target,omitempty"`
+	CycleID string    `json:"cycle_id,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Filter selects events from the log. Empty fields match every event; Target matches
+// events by the redacted target URL.
+type Filter struct {
+	Type    string
+	Target  string
+	CycleID string
+	Since   time.Time
+	// Limit keeps only the most recent matching events, zero keeps all
+	Limit int
+}
+
+// Log is a ring buffer of the most recent events, safe for concurrent use
+type Log struct {
+	mutex  sync.RWMutex
+	events []Event
+	size   int
+	start  int
+	lastID uint64
+}
+
+// New creates a log that keeps the last size events
+func New(size int) *Log {
+	return &Log{size: size, events: make([]Event, 0, size)}
+}
+
+// Add records an event, dropping the oldest one when the log is full. The event gets the
+// next ID, and the current time unless it has one. The target URL is redacted since the
+// log is served to API clients.
+func (l *Log) Add(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Target = config.RedactURL(event.Target)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.lastID++
+	event.ID = l.lastID
+
+	if len(l.events) < l.size {
+		l.events = append(l.events, event)
+		return
+	}
+	l.events[l.start] = event
+	l.start = (l.start + 1) % l.size
+}
+
+// Events returns the events matching the filter, oldest first
+func (l *Log) Events(filter Filter) []Event {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	target := config.RedactURL(filter.Target)
+	events := make([]Event, 0)
+	for i := range l.events {
+		event := l.events[(l.start+i)%len(l.events)]
+		if filter.Type != "" && event.Type != filter.Type {
+			continue
+		}
+		if target != "" && event.Target != target {
+			continue
+		}
+		if filter.CycleID != "" && event.CycleID != filter.CycleID {
+			continue
+		}
+		if event.Time.Before(filter.Since) {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_KeepsMostRecent(t *testing.T) {
+	log := New(3)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		log.Add(Event{Time: start.Add(time.Duration(i) * time.Minute), Type: TypeCheckError, Message: "error"})
+	}
+
+	events := log.Events(Filter{})
+	require.Len(t, events, 3)
+	assert.Equal(t, []uint64{3, 4, 5}, []uint64{events[0].ID, events[1].ID, events[2].ID})
+	assert.Equal(t, start.Add(2*time.Minute), events[0].Time)
+}
+
+func TestLog_Events(t *testing.T) {
+	log := New(10)
+	start := time.Now().Add(-time.Hour)
+
+	log.Add(Event{Time: start, Type: TypeStateChange, Target: "https://a.example.com", Message: "Target is down: status code 503"})
+	log.Add(Event{Time: start.Add(10 * time.Minute), Type: TypeReload, Message: "Configuration reloaded"})
+	log.Add(Event{Time: start.Add(20 * time.Minute), Type: TypeStateChange, Target: "https://b.example.com", CycleID: "3f2a", Message: "Target recovered"})
+	log.Add(Event{Type: TypeTargetAdded, Target: "https://a.example.com", Message: "Target added via API"})
+
+	events := log.Events(Filter{Type: TypeStateChange})
+	require.Len(t, events, 2)
+	assert.Equal(t, "https://a.example.com", events[0].Target)
+
+	events = log.Events(Filter{Target: "https://a.example.com"})
+	require.Len(t, events, 2)
+	assert.Equal(t, TypeTargetAdded, events[1].Type)
+	assert.False(t, events[1].Time.IsZero(), "events without a time get the current time")
+
+	events = log.Events(Filter{CycleID: "3f2a"})
+	require.Len(t, events, 1)
+	assert.Equal(t, "https://b.example.com", events[0].Target)
+
+	events = log.Events(Filter{Since: start.Add(15 * time.Minute)})
+	require.Len(t, events, 2)
+	assert.Equal(t, "Target recovered", events[0].Message)
+
+	events = log.Events(Filter{Limit: 1})
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(4), events[0].ID)
+
+	assert.NotNil(t, New(1).Events(Filter{}))
+}
@@ -0,0 +1,76 @@
+// Package heartbeat pings an external dead man's switch while the exporter is running.
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Heartbeat periodically requests the configured URL. Services such as healthchecks.io
+// alert when the requests stop, which happens when the exporter dies, even if nothing
+// scrapes it. It uses a plain HTTP client so the URL, which usually identifies the check
+// and acts as its secret, never appears in logs.
+type Heartbeat struct {
+	config config.HeartbeatConfig
+	client *http.Client
+}
+
+func New(cfg config.HeartbeatConfig, timeout time.Duration) *Heartbeat {
+	return &Heartbeat{
+		config: cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Start sends a heartbeat right away and then every interval until the context is done
+func (h *Heartbeat) Start(ctx context.Context) {
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.Ping(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to send heartbeat")
+		} else {
+			log.Debug().Msg("Heartbeat sent")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ping sends a single heartbeat and fails on non-2xx responses
+func (h *Heartbeat) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.config.URL, nil)
+	if err != nil {
+		return errors.New("invalid heartbeat url")
+	}
+	req.Header.Set("User-Agent", "url-exporter/1.0")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("heartbeat request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("heartbeat request failed: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat_Start(t *testing.T) {
+	var pings atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ping/check-uuid", r.URL.Path)
+		assert.Equal(t, "url-exporter/1.0", r.Header.Get("User-Agent"))
+		pings.Add(1)
+	}))
+	defer server.Close()
+
+	heartbeat := New(config.HeartbeatConfig{URL: server.URL + "/ping/check-uuid", Interval: 20 * time.Millisecond}, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		heartbeat.Start(ctx)
+		close(done)
+	}()
+
+	// The first heartbeat is sent right away, then one per interval
+	assert.Eventually(t, func() bool { return pings.Load() >= 3 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat did not stop when the context was cancelled")
+	}
+}
+
+func TestHeartbeat_Ping_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	heartbeat := New(config.HeartbeatConfig{URL: server.URL + "/ping/secret-uuid", Interval: time.Minute}, time.Second)
+	err := heartbeat.Ping(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "heartbeat request failed: HTTP 404", err.Error())
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	endpoint := closed.URL + "/ping/secret-uuid"
+	closed.Close()
+
+	heartbeat = New(config.HeartbeatConfig{URL: endpoint, Interval: time.Minute}, time.Second)
+	err = heartbeat.Ping(context.Background())
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-uuid")
+}
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// clientID identifies the exporter to the brokers, e.g. in their quotas and request logs
+const clientID = "url-exporter"
+
+// maxResponseSize bounds the responses read, which are small for the requests sent
+const maxResponseSize = 16 << 20
+
+// brokerConn is a connection to a broker. Requests are sent one at a time.
+type brokerConn struct {
+	conn          net.Conn
+	timeout       time.Duration
+	correlationID int32
+}
+
+// roundTrip sends a request with the body and returns a decoder of the response body
+func (b *brokerConn) roundTrip(ctx context.Context, apiKey, version int16, body []byte) (*decoder, error) {
+	deadline := time.Now().Add(b.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := b.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// Canceling the context interrupts the request
+	stop := context.AfterFunc(ctx, func() { _ = b.conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	b.correlationID++
+	var request encoder
+	request.int32(0) // size, set below
+	request.int16(apiKey)
+	request.int16(version)
+	request.int32(b.correlationID)
+	request.string(clientID)
+	request.buf = append(request.buf, body...)
+	binary.BigEndian.PutUint32(request.buf, uint32(len(request.buf)-4))
+	if _, err := b.conn.Write(request.buf); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(b.conn, size[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length < 4 || length > maxResponseSize {
+		return nil, fmt.Errorf("invalid response size %d", length)
+	}
+	response := make([]byte, length)
+	if _, err := io.ReadFull(b.conn, response); err != nil {
+		return nil, err
+	}
+
+	d := &decoder{buf: response}
+	if correlationID := d.int32(); correlationID != b.correlationID {
+		return nil, fmt.Errorf("response to request %d received for request %d", correlationID, b.correlationID)
+	}
+	return d, nil
+}
+
+func (b *brokerConn) close() error {
+	return b.conn.Close()
+}
+
+// brokerMetadata is the cluster's view of a topic: the brokers and the leader of each
+// partition
+type brokerMetadata struct {
+	addresses map[int32]string // node ID -> host:port
+	leaders   []int32          // partition -> node ID of the leader, -1 if none
+}
+
+// metadata requests the metadata of the topic, letting the broker create it if the
+// cluster auto-creates topics
+func (b *brokerConn) metadata(ctx context.Context, topic string) (brokerMetadata, error) {
+	var request encoder
+	request.int32(1)
+	request.string(topic)
+	request.bool(true) // allow auto topic creation
+
+	d, err := b.roundTrip(ctx, apiMetadata, metadataVersion, request.buf)
+	if err != nil {
+		return brokerMetadata{}, err
+	}
+
+	metadata := brokerMetadata{addresses: make(map[int32]string)}
+	d.int32() // throttle time
+	for range d.arrayLength() {
+		nodeID := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		metadata.addresses[nodeID] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster ID
+	d.int32()  // controller ID
+
+	var topicErr error
+	found := false
+	for range d.arrayLength() {
+		code := d.int16()
+		name := d.string()
+		d.bool() // internal
+		partitions := d.arrayLength()
+		leaders := make([]int32, partitions)
+		for range partitions {
+			d.int16() // partition error, e.g. an unavailable leader reported as -1
+			index := d.int32()
+			leader := d.int32()
+			for range d.arrayLength() { // replicas
+				d.int32()
+			}
+			for range d.arrayLength() { // in-sync replicas
+				d.int32()
+			}
+			if index >= 0 && int(index) < partitions {
+				leaders[index] = leader
+			}
+		}
+		if name == topic {
+			found = true
+			topicErr = errorCode(code)
+			metadata.leaders = leaders
+		}
+	}
+	if d.err != nil {
+		return brokerMetadata{}, fmt.Errorf("invalid metadata response: %w", d.err)
+	}
+	if topicErr != nil {
+		return brokerMetadata{}, fmt.Errorf("topic %s: %w", topic, topicErr)
+	}
+	if !found || len(metadata.leaders) == 0 {
+		return brokerMetadata{}, fmt.Errorf("topic %s has no partitions", topic)
+	}
+	return metadata, nil
+}
+
+// produce writes a batch of records to each partition and waits until all in-sync
+// replicas have them
+func (b *brokerConn) produce(ctx context.Context, topic string, batches map[int32][]byte) error {
+	var request encoder
+	request.nullString() // transactional ID
+	request.int16(-1)    // acks: all in-sync replicas
+	request.int32(int32(b.timeout.Milliseconds()))
+	request.int32(1)
+	request.string(topic)
+	request.int32(int32(len(batches)))
+	for partition, batch := range batches {
+		request.int32(partition)
+		request.bytes(batch)
+	}
+
+	d, err := b.roundTrip(ctx, apiProduce, produceVersion, request.buf)
+	if err != nil {
+		return err
+	}
+
+	var produceErr error
+	for range d.arrayLength() {
+		d.string() // topic
+		for range d.arrayLength() {
+			partition := d.int32()
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if err := errorCode(code); err != nil && produceErr == nil {
+				produceErr = fmt.Errorf("partition %d: %w", partition, err)
+			}
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("invalid produce response: %w", d.err)
+	}
+	return produceErr
+}
@@ -0,0 +1,201 @@
+// Package kafka is a minimal Kafka producer: it publishes records to a topic, partitioned
+// by key like Kafka's own clients, over plain or TLS connections with optional SASL
+// PLAIN or SCRAM authentication. It speaks the protocol versions of Kafka 0.11 and later.
+//
+// The sink only produces small batches, so this covers the metadata, produce and SASL
+// requests instead of pulling in a full client library with its consumer groups,
+// transactions and admin APIs. Setups that need more, e.g. idempotent producers,
+// OAUTHBEARER or compression, are out of scope; moving to a maintained client then
+// means replacing Producer behind the sink.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Producer publishes records to a topic. It keeps the topic's metadata and a connection
+// to each partition leader, and starts over from the bootstrap brokers after a failure.
+type Producer struct {
+	brokers   []string
+	topic     string
+	tlsConfig *tls.Config
+	sasl      config.KafkaSASLConfig
+	timeout   time.Duration
+
+	mutex    sync.Mutex
+	metadata *brokerMetadata
+	conns    map[int32]*brokerConn // node ID -> connection to the broker
+}
+
+// NewProducer creates a producer for the configured brokers and topic, with timeout
+// bounding each request
+func NewProducer(cfg config.KafkaConfig, timeout time.Duration) (*Producer, error) {
+	p := &Producer{
+		brokers: cfg.Brokers,
+		topic:   cfg.Topic,
+		sasl:    cfg.SASL,
+		timeout: timeout,
+		conns:   make(map[int32]*brokerConn),
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := cfg.TLS.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid kafka TLS configuration: %w", err)
+		}
+		p.tlsConfig = tlsConfig
+	}
+
+	return p, nil
+}
+
+// Produce publishes the records and waits until all in-sync replicas of their partitions
+// have them. A failed attempt is retried once with fresh metadata and connections.
+func (p *Producer) Produce(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	err := p.produce(ctx, records)
+	if err != nil && ctx.Err() == nil {
+		p.reset()
+		err = p.produce(ctx, records)
+	}
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("failed to produce to kafka topic %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// Close closes the connections to the brokers
+func (p *Producer) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.reset()
+	return nil
+}
+
+// produce sends the records to the leaders of their partitions. The caller must hold the
+// mutex.
+func (p *Producer) produce(ctx context.Context, records []Record) error {
+	if p.metadata == nil {
+		metadata, err := p.bootstrap(ctx)
+		if err != nil {
+			return err
+		}
+		p.metadata = &metadata
+	}
+
+	byPartition := make(map[int32][]Record)
+	for _, record := range records {
+		partition := partitionFor(record.Key, len(p.metadata.leaders))
+		byPartition[partition] = append(byPartition[partition], record)
+	}
+
+	now := time.Now()
+	byLeader := make(map[int32]map[int32][]byte)
+	for partition, partitionRecords := range byPartition {
+		leader := p.metadata.leaders[partition]
+		if _, known := p.metadata.addresses[leader]; !known {
+			return fmt.Errorf("partition %d has no leader", partition)
+		}
+		if byLeader[leader] == nil {
+			byLeader[leader] = make(map[int32][]byte)
+		}
+		byLeader[leader][partition] = recordBatch(partitionRecords, now)
+	}
+
+	for leader, batches := range byLeader {
+		conn, err := p.conn(ctx, leader)
+		if err != nil {
+			return err
+		}
+		if err := conn.produce(ctx, p.topic, batches); err != nil {
+			return fmt.Errorf("broker %s: %w", p.metadata.addresses[leader], err)
+		}
+	}
+	return nil
+}
+
+// bootstrap fetches the topic's metadata from the first bootstrap broker that answers
+func (p *Producer) bootstrap(ctx context.Context) (brokerMetadata, error) {
+	var errs []error
+	for _, address := range p.brokers {
+		conn, err := p.dial(ctx, address)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		metadata, err := conn.metadata(ctx, p.topic)
+		_ = conn.close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("broker %s: %w", address, err))
+			continue
+		}
+		return metadata, nil
+	}
+	return brokerMetadata{}, fmt.Errorf("no bootstrap broker available: %w", errors.Join(errs...))
+}
+
+// conn returns the connection to the broker, connecting first if needed. The caller must
+// hold the mutex.
+func (p *Producer) conn(ctx context.Context, nodeID int32) (*brokerConn, error) {
+	if conn, exists := p.conns[nodeID]; exists {
+		return conn, nil
+	}
+	conn, err := p.dial(ctx, p.metadata.addresses[nodeID])
+	if err != nil {
+		return nil, err
+	}
+	p.conns[nodeID] = conn
+	return conn, nil
+}
+
+// dial connects and authenticates to the broker at the address
+func (p *Producer) dial(ctx context.Context, address string) (*brokerConn, error) {
+	dialer := &net.Dialer{Timeout: p.timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if p.tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: p.tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker %s: %w", address, err)
+	}
+
+	broker := &brokerConn{conn: conn, timeout: p.timeout}
+	if p.sasl.Mechanism != "" {
+		if err := broker.authenticate(ctx, p.sasl); err != nil {
+			_ = broker.close()
+			return nil, fmt.Errorf("SASL %s authentication with broker %s failed: %w", strings.ToUpper(p.sasl.Mechanism), address, err)
+		}
+	}
+	return broker, nil
+}
+
+// reset closes the connections and forgets the metadata. The caller must hold the mutex.
+func (p *Producer) reset() {
+	for nodeID, conn := range p.conns {
+		_ = conn.close()
+		delete(p.conns, nodeID)
+	}
+	p.metadata = nil
+}
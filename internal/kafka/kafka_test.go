@@ -0,0 +1,344 @@
+package kafka
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker is a single-node cluster hosting one topic with two partitions. It accepts
+// the user "probe" with the password "secret" over SASL PLAIN and SCRAM-SHA-256.
+type fakeBroker struct {
+	listener net.Listener
+	topic    string
+
+	mutex   sync.Mutex
+	records map[int32][]Record // partition -> records produced
+	errCode int16              // error code of the next produce responses
+}
+
+func newFakeBroker(t *testing.T, topic string) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &fakeBroker{listener: listener, topic: topic, records: make(map[int32][]Record)}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) address() string {
+	return b.listener.Addr().String()
+}
+
+func (b *fakeBroker) produced() map[int32][]Record {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	produced := make(map[int32][]Record, len(b.records))
+	for partition, records := range b.records {
+		produced[partition] = append([]Record(nil), records...)
+	}
+	return produced
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	var scram scramServer
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		d := &decoder{buf: request}
+		apiKey, _, correlationID := d.int16(), d.int16(), d.int32()
+		d.string() // client ID
+
+		var response encoder
+		response.int32(0)
+		response.int32(correlationID)
+		switch apiKey {
+		case apiMetadata:
+			b.metadata(&response)
+		case apiSaslHandshake:
+			mechanism := d.string()
+			if mechanism == config.KafkaSASLPlain || mechanism == config.KafkaSASLSCRAMSHA256 {
+				response.int16(0)
+			} else {
+				response.int16(33)
+			}
+			response.int32(2)
+			response.string(config.KafkaSASLPlain)
+			response.string(config.KafkaSASLSCRAMSHA256)
+		case apiSaslAuthenticate:
+			reply, ok := scram.step(d.bytes())
+			if ok {
+				response.int16(0)
+			} else {
+				response.int16(58)
+			}
+			response.nullString()
+			response.bytes(reply)
+		case apiProduce:
+			b.produce(d, &response)
+		default:
+			return
+		}
+		binary.BigEndian.PutUint32(response.buf, uint32(len(response.buf)-4))
+		if _, err := conn.Write(response.buf); err != nil {
+			return
+		}
+	}
+}
+
+func (b *fakeBroker) metadata(response *encoder) {
+	host, port, _ := net.SplitHostPort(b.address())
+	portNumber, _ := strconv.Atoi(port)
+	response.int32(0) // throttle time
+	response.int32(1)
+	response.int32(1) // node ID
+	response.string(host)
+	response.int32(int32(portNumber))
+	response.nullString()
+	response.nullString() // cluster ID
+	response.int32(1)     // controller ID
+	response.int32(1)
+	response.int16(0)
+	response.string(b.topic)
+	response.bool(false)
+	response.int32(2)
+	for partition := range int32(2) {
+		response.int16(0)
+		response.int32(partition)
+		response.int32(1) // leader
+		response.int32(1)
+		response.int32(1) // replicas
+		response.int32(1)
+		response.int32(1) // in-sync replicas
+	}
+}
+
+func (b *fakeBroker) produce(d *decoder, response *encoder) {
+	d.string() // transactional ID
+	d.int16()  // acks
+	d.int32()  // timeout
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	topics := d.arrayLength()
+	response.int32(int32(topics))
+	for range topics {
+		response.string(d.string())
+		partitions := d.arrayLength()
+		response.int32(int32(partitions))
+		for range partitions {
+			partition := d.int32()
+			records, ok := decodeBatch(d.bytes())
+			code := b.errCode
+			if !ok {
+				code = 2 // corrupt message
+			} else if code == 0 {
+				b.records[partition] = append(b.records[partition], records...)
+			}
+			response.int32(partition)
+			response.int16(code)
+			response.int64(0)
+			response.int64(-1)
+		}
+	}
+	response.int32(0) // throttle time
+}
+
+// decodeBatch decodes a record batch, verifying its length and checksum
+func decodeBatch(batch []byte) ([]Record, bool) {
+	d := &decoder{buf: batch}
+	d.int64() // base offset
+	if int(d.int32()) != len(d.buf) || d.int32() != -1 || d.int8() != 2 {
+		return nil, false
+	}
+	if uint32(d.int32()) != crc32.Checksum(d.buf, crc32.MakeTable(crc32.Castagnoli)) {
+		return nil, false
+	}
+	d.read(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	count := d.int32()
+	records := make([]Record, 0, count)
+	rest := d.buf
+	for range count {
+		_, n := binary.Varint(rest) // length
+		rest = rest[n+1:]           // attributes
+		_, n = binary.Varint(rest)  // timestamp delta
+		rest = rest[n:]
+		_, n = binary.Varint(rest) // offset delta
+		rest = rest[n:]
+		var record Record
+		for _, field := range []*[]byte{&record.Key, &record.Value} {
+			length, n := binary.Varint(rest)
+			rest = rest[n:]
+			*field = rest[:length]
+			rest = rest[length:]
+		}
+		_, n = binary.Varint(rest) // headers
+		rest = rest[n:]
+		records = append(records, record)
+	}
+	return records, d.err == nil && len(rest) == 0
+}
+
+// scramServer is the broker's side of the SASL exchanges
+type scramServer struct {
+	clientFirstBare, serverFirst string
+	salted                       []byte
+}
+
+func (s *scramServer) step(message []byte) ([]byte, bool) {
+	text := string(message)
+	switch {
+	case text == "\x00probe\x00secret":
+		return nil, true
+	case strings.HasPrefix(text, "n,,"):
+		s.clientFirstBare = strings.TrimPrefix(text, "n,,")
+		attributes := scramAttributes(s.clientFirstBare)
+		if attributes["n"] != "probe" {
+			return nil, false
+		}
+		salt := []byte("salt")
+		s.salted, _ = pbkdf2.Key(sha256.New, "secret", salt, 4096, sha256.Size)
+		s.serverFirst = "r=" + attributes["r"] + "server,s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+		return []byte(s.serverFirst), true
+	case strings.HasPrefix(text, "c=biws,"):
+		withoutProof, proof, _ := strings.Cut(text, ",p=")
+		authMessage := s.clientFirstBare + "," + s.serverFirst + "," + withoutProof
+		clientKey := hmacSum(sha256.New, s.salted, "Client Key")
+		storedKey := sha256.Sum256(clientKey)
+		expected := hmacSum(sha256.New, storedKey[:], authMessage)
+		for i := range expected {
+			expected[i] ^= clientKey[i]
+		}
+		if !hmac.Equal([]byte(proof), []byte(base64.StdEncoding.EncodeToString(expected))) {
+			return []byte("e=invalid-proof"), false
+		}
+		serverKey := hmacSum(sha256.New, s.salted, "Server Key")
+		return []byte("v=" + base64.StdEncoding.EncodeToString(hmacSum(sha256.New, serverKey, authMessage))), true
+	}
+	return nil, false
+}
+
+func TestProducer_Produce(t *testing.T) {
+	tests := []struct {
+		name string
+		sasl config.KafkaSASLConfig
+	}{
+		{name: "plaintext"},
+		{name: "sasl plain", sasl: config.KafkaSASLConfig{Mechanism: config.KafkaSASLPlain, Username: "probe", Password: "secret"}},
+		{name: "sasl scram", sasl: config.KafkaSASLConfig{Mechanism: config.KafkaSASLSCRAMSHA256, Username: "probe", Password: "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := newFakeBroker(t, "results")
+			producer, err := NewProducer(config.KafkaConfig{
+				Brokers: []string{"127.0.0.1:1", broker.address()},
+				Topic:   "results",
+				SASL:    tt.sasl,
+			}, 5*time.Second)
+			require.NoError(t, err)
+			defer producer.Close()
+
+			records := []Record{
+				{Key: []byte("https://a.example.com"), Value: []byte(`{"up":true}`)},
+				{Key: []byte("https://b.example.com"), Value: []byte(`{"up":false}`)},
+				{Key: []byte("https://a.example.com"), Value: []byte(`{"up":false}`)},
+			}
+			require.NoError(t, producer.Produce(context.Background(), records))
+			require.NoError(t, producer.Produce(context.Background(), records[:1]))
+
+			produced := broker.produced()
+			total := 0
+			for partition, partitionRecords := range produced {
+				total += len(partitionRecords)
+				for _, record := range partitionRecords {
+					assert.Equal(t, partitionFor(record.Key, 2), partition, "records are partitioned by key")
+				}
+			}
+			assert.Equal(t, 4, total)
+			keyA := partitionFor([]byte("https://a.example.com"), 2)
+			var values []string
+			for _, record := range produced[keyA] {
+				if string(record.Key) == "https://a.example.com" {
+					values = append(values, string(record.Value))
+				}
+			}
+			assert.Equal(t, []string{`{"up":true}`, `{"up":false}`, `{"up":true}`}, values, "records of a key keep their order")
+		})
+	}
+}
+
+func TestProducer_Errors(t *testing.T) {
+	broker := newFakeBroker(t, "results")
+
+	producer, err := NewProducer(config.KafkaConfig{
+		Brokers: []string{broker.address()},
+		Topic:   "results",
+		SASL:    config.KafkaSASLConfig{Mechanism: config.KafkaSASLPlain, Username: "probe", Password: "wrong"},
+	}, 5*time.Second)
+	require.NoError(t, err)
+	err = producer.Produce(context.Background(), []Record{{Key: []byte("k"), Value: []byte("v")}})
+	assert.ErrorContains(t, err, "SASL PLAIN authentication")
+	assert.ErrorContains(t, err, "SASL_AUTHENTICATION_FAILED")
+
+	producer, err = NewProducer(config.KafkaConfig{Brokers: []string{broker.address()}, Topic: "other"}, 5*time.Second)
+	require.NoError(t, err)
+	err = producer.Produce(context.Background(), []Record{{Key: []byte("k"), Value: []byte("v")}})
+	assert.ErrorContains(t, err, "topic other has no partitions")
+
+	broker.mutex.Lock()
+	broker.errCode = 19
+	broker.mutex.Unlock()
+	producer, err = NewProducer(config.KafkaConfig{Brokers: []string{broker.address()}, Topic: "results"}, 5*time.Second)
+	require.NoError(t, err)
+	defer producer.Close()
+	err = producer.Produce(context.Background(), []Record{{Key: []byte("k"), Value: []byte("v")}})
+	var kafkaErr Error
+	require.ErrorAs(t, err, &kafkaErr)
+	assert.Equal(t, Error(19), kafkaErr)
+	assert.ErrorContains(t, err, "NOT_ENOUGH_REPLICAS")
+}
+
+func TestMurmur2(t *testing.T) {
+	// Values of Kafka's Utils.murmur2
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, want := range tests {
+		assert.Equal(t, want, murmur2([]byte(key)), key)
+	}
+}
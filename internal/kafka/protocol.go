@@ -0,0 +1,291 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// API keys and the versions of the requests used, supported by Kafka 0.11 and later
+const (
+	apiProduce          int16 = 0
+	apiMetadata         int16 = 3
+	apiSaslHandshake    int16 = 17
+	apiSaslAuthenticate int16 = 36
+
+	produceVersion          int16 = 3
+	metadataVersion         int16 = 4
+	saslHandshakeVersion    int16 = 1
+	saslAuthenticateVersion int16 = 0
+)
+
+// errorNames names the error codes a producer commonly sees
+var errorNames = map[int16]string{
+	1:  "OFFSET_OUT_OF_RANGE",
+	2:  "CORRUPT_MESSAGE",
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	10: "MESSAGE_TOO_LARGE",
+	17: "INVALID_TOPIC_EXCEPTION",
+	19: "NOT_ENOUGH_REPLICAS",
+	20: "NOT_ENOUGH_REPLICAS_AFTER_APPEND",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	31: "CLUSTER_AUTHORIZATION_FAILED",
+	33: "UNSUPPORTED_SASL_MECHANISM",
+	34: "ILLEGAL_SASL_STATE",
+	35: "UNSUPPORTED_VERSION",
+	58: "SASL_AUTHENTICATION_FAILED",
+}
+
+// Error is an error code returned by a broker
+type Error int16
+
+func (e Error) Error() string {
+	if name, known := errorNames[int16(e)]; known {
+		return fmt.Sprintf("kafka error %d (%s)", int16(e), name)
+	}
+	return fmt.Sprintf("kafka error %d", int16(e))
+}
+
+// errorCode returns the error of a non-zero code
+func errorCode(code int16) error {
+	if code == 0 {
+		return nil
+	}
+	return Error(code)
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// encoder appends the fields of a request in the Kafka protocol's big-endian encoding
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8) {
+	e.buf = append(e.buf, byte(v))
+}
+
+func (e *encoder) int16(v int16) {
+	e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v))
+}
+
+func (e *encoder) int32(v int32) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v))
+}
+
+func (e *encoder) int64(v int64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v))
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.int8(1)
+	} else {
+		e.int8(0)
+	}
+}
+
+func (e *encoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// nullString encodes null, as used for an absent transactional ID
+func (e *encoder) nullString() {
+	e.int16(-1)
+}
+
+func (e *encoder) bytes(v []byte) {
+	e.int32(int32(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// varint encodes a zigzag variable-length integer, as in record batches
+func (e *encoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *encoder) varBytes(v []byte) {
+	if v == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+var errTruncated = errors.New("truncated response")
+
+// decoder reads the fields of a response. After the first error every read returns zero
+// values and err keeps that error.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errTruncated
+		return nil
+	}
+	value := d.buf[:n]
+	d.buf = d.buf[n:]
+	return value
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.read(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.read(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.read(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.read(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *decoder) bool() bool {
+	return d.int8() != 0
+}
+
+// string reads a string, empty when null
+func (d *decoder) string() string {
+	length := d.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(d.read(int(length)))
+}
+
+// bytes reads a byte array, nil when null
+func (d *decoder) bytes() []byte {
+	length := d.int32()
+	if length < 0 {
+		return nil
+	}
+	return d.read(int(length))
+}
+
+// arrayLength reads the length of an array, zero when null
+func (d *decoder) arrayLength() int {
+	length := d.int32()
+	if length < 0 {
+		return 0
+	}
+	if int(length) > len(d.buf) {
+		// Every element takes at least a byte
+		d.err = errTruncated
+		return 0
+	}
+	return int(length)
+}
+
+// Record is a message to publish. Records with the same key go to the same partition.
+type Record struct {
+	Key   []byte
+	Value []byte
+}
+
+// recordBatch encodes the records as a record batch (message format v2) created at now
+func recordBatch(records []Record, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+
+	var body encoder
+	body.int16(0)                       // attributes: no compression, create time
+	body.int32(int32(len(records) - 1)) // last offset delta
+	body.int64(timestamp)               // base timestamp
+	body.int64(timestamp)               // max timestamp
+	body.int64(-1)                      // producer ID
+	body.int16(-1)                      // producer epoch
+	body.int32(-1)                      // base sequence
+	body.int32(int32(len(records)))
+	for i, record := range records {
+		var r encoder
+		r.int8(0)          // attributes
+		r.varint(0)        // timestamp delta
+		r.varint(int64(i)) // offset delta
+		r.varBytes(record.Key)
+		r.varBytes(record.Value)
+		r.varint(0) // headers
+		body.varint(int64(len(r.buf)))
+		body.buf = append(body.buf, r.buf...)
+	}
+
+	var batch encoder
+	batch.int64(0)                                // base offset, assigned by the broker
+	batch.int32(int32(4 + 1 + 4 + len(body.buf))) // length of the rest of the batch
+	batch.int32(-1)                               // partition leader epoch
+	batch.int8(2)                                 // magic
+	batch.int32(int32(crc32.Checksum(body.buf, crc32c)))
+	batch.buf = append(batch.buf, body.buf...)
+	return batch.buf
+}
+
+// murmur2 is the hash of Kafka's default partitioner, so records are partitioned by key
+// like Kafka's own clients do
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// partitionFor returns the partition of a key among count partitions
+func partitionFor(key []byte, count int) int32 {
+	return int32(int(uint32(murmur2(key))&0x7fffffff) % count)
+}
@@ -0,0 +1,151 @@
+package kafka
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// authenticate runs the SASL exchange of the mechanism on the connection
+func (b *brokerConn) authenticate(ctx context.Context, sasl config.KafkaSASLConfig) error {
+	var request encoder
+	request.string(sasl.Mechanism)
+	d, err := b.roundTrip(ctx, apiSaslHandshake, saslHandshakeVersion, request.buf)
+	if err != nil {
+		return err
+	}
+	code := d.int16()
+	var enabled []string
+	for range d.arrayLength() {
+		enabled = append(enabled, d.string())
+	}
+	if d.err != nil {
+		return fmt.Errorf("invalid SASL handshake response: %w", d.err)
+	}
+	if err := errorCode(code); err != nil {
+		return fmt.Errorf("mechanism %s not enabled, the broker supports %s: %w", sasl.Mechanism, strings.Join(enabled, ", "), err)
+	}
+
+	switch sasl.Mechanism {
+	case config.KafkaSASLPlain:
+		_, err = b.saslExchange(ctx, []byte("\x00"+sasl.Username+"\x00"+sasl.Password))
+		return err
+	case config.KafkaSASLSCRAMSHA256:
+		return b.scram(ctx, sha256.New, sasl.Username, sasl.Password)
+	case config.KafkaSASLSCRAMSHA512:
+		return b.scram(ctx, sha512.New, sasl.Username, sasl.Password)
+	default:
+		return fmt.Errorf("unsupported SASL mechanism %q", sasl.Mechanism)
+	}
+}
+
+// saslExchange sends a SASL message and returns the broker's reply
+func (b *brokerConn) saslExchange(ctx context.Context, message []byte) ([]byte, error) {
+	var request encoder
+	request.bytes(message)
+	d, err := b.roundTrip(ctx, apiSaslAuthenticate, saslAuthenticateVersion, request.buf)
+	if err != nil {
+		return nil, err
+	}
+	code := d.int16()
+	errorMessage := d.string()
+	reply := d.bytes()
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid SASL authenticate response: %w", d.err)
+	}
+	if err := errorCode(code); err != nil {
+		if errorMessage != "" {
+			return nil, fmt.Errorf("%s: %w", errorMessage, err)
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// scram authenticates with SCRAM (RFC 5802) and verifies the broker's signature
+func (b *brokerConn) scram(ctx context.Context, newHash func() hash.Hash, username, password string) error {
+	nonceBytes := make([]byte, 18)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return err
+	}
+	nonce := base64.RawStdEncoding.EncodeToString(nonceBytes)
+
+	name := strings.NewReplacer("=", "=3D", ",", "=2C").Replace(username)
+	clientFirstBare := "n=" + name + ",r=" + nonce
+	serverFirst, err := b.saslExchange(ctx, []byte("n,,"+clientFirstBare))
+	if err != nil {
+		return err
+	}
+
+	attributes := scramAttributes(string(serverFirst))
+	serverNonce, encodedSalt, iterations := attributes["r"], attributes["s"], attributes["i"]
+	if !strings.HasPrefix(serverNonce, nonce) || len(serverNonce) == len(nonce) {
+		return errors.New("invalid SCRAM server nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+	iterationCount, err := strconv.Atoi(iterations)
+	if err != nil || iterationCount < 1 {
+		return fmt.Errorf("invalid SCRAM iteration count %q", iterations)
+	}
+
+	saltedPassword, err := pbkdf2.Key(newHash, password, salt, iterationCount, newHash().Size())
+	if err != nil {
+		return err
+	}
+	clientKey := hmacSum(newHash, saltedPassword, "Client Key")
+	storedKey := newHash()
+	storedKey.Write(clientKey)
+
+	clientFinalBare := "c=biws,r=" + serverNonce
+	authMessage := clientFirstBare + "," + string(serverFirst) + "," + clientFinalBare
+	proof := hmacSum(newHash, storedKey.Sum(nil), authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+
+	serverFinal, err := b.saslExchange(ctx, []byte(clientFinalBare+",p="+base64.StdEncoding.EncodeToString(proof)))
+	if err != nil {
+		return err
+	}
+	attributes = scramAttributes(string(serverFinal))
+	if message, failed := attributes["e"]; failed {
+		return fmt.Errorf("SCRAM authentication failed: %s", message)
+	}
+	serverKey := hmacSum(newHash, saltedPassword, "Server Key")
+	signature := base64.StdEncoding.EncodeToString(hmacSum(newHash, serverKey, authMessage))
+	if !hmac.Equal([]byte(attributes["v"]), []byte(signature)) {
+		return errors.New("invalid SCRAM server signature")
+	}
+	return nil
+}
+
+func hmacSum(newHash func() hash.Hash, key []byte, message string) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// scramAttributes parses the comma-separated key=value attributes of a SCRAM message
+func scramAttributes(message string) map[string]string {
+	attributes := make(map[string]string)
+	for _, attribute := range strings.Split(message, ",") {
+		if key, value, found := strings.Cut(attribute, "="); found {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,117 @@
+// Package kube is a minimal client for the Kubernetes API server, authenticating with the
+// pod's service account. It covers the few calls the exporter makes without client-go.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Content types of JSON request bodies
+const (
+	ContentTypeJSON       = "application/json"
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
+// Client calls the API server. Requests time out after the HTTP client's timeout;
+// watches use Stream, which has none.
+type Client struct {
+	BaseURL string
+	// Namespace is the pod's own namespace
+	Namespace string
+	HTTP      *http.Client
+	Token     func() (string, error)
+}
+
+// InCluster returns a client for the API server of the cluster the pod runs in
+func InCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster")
+	}
+
+	namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod namespace: %w", err)
+	}
+
+	caData, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in cluster CA")
+	}
+
+	return &Client{
+		BaseURL:   "https://" + net.JoinHostPort(host, port),
+		Namespace: strings.TrimSpace(string(namespace)),
+		HTTP: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			},
+		},
+		// The token is read on every request because the kubelet rotates it
+		Token: func() (string, error) {
+			data, err := os.ReadFile(serviceAccountDir + "/token")
+			if err != nil {
+				return "", fmt.Errorf("failed to read service account token: %w", err)
+			}
+			return strings.TrimSpace(string(data)), nil
+		},
+	}, nil
+}
+
+// Do sends a request to the API path, e.g. /apis/coordination.k8s.io/v1/..., with an
+// optional body of the given content type
+func (c *Client) Do(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	return c.do(ctx, c.HTTP, method, path, body, contentType)
+}
+
+// Stream sends a GET request whose response is read for as long as the context allows,
+// such as a watch
+func (c *Client) Stream(ctx context.Context, path string) (*http.Response, error) {
+	streaming := *c.HTTP
+	streaming.Timeout = 0
+	return c.do(ctx, &streaming, http.MethodGet, path, nil, "")
+}
+
+func (c *Client) do(ctx context.Context, client *http.Client, method, path string, body []byte, contentType string) (*http.Response, error) {
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", ContentTypeJSON)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request %s %s failed: %w", method, path, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileLock is held by the process with an exclusive flock on the file, e.g. on a volume
+// shared by both replicas. The lock disappears with the process, so a crashed leader is
+// replaced as soon as a standby retries.
+type fileLock struct {
+	path     string
+	identity string
+	file     *os.File
+}
+
+func newFileLock(path, identity string) *fileLock {
+	return &fileLock{path: path, identity: identity}
+}
+
+// TryAcquire keeps a held lock and otherwise tries to lock the file without blocking. The
+// holder's identity is written to the file for operators.
+func (l *fileLock) TryAcquire(_ context.Context) (bool, error) {
+	if l.file != nil {
+		return true, nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file %s: %w", l.path, err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock %s: %w", l.path, err)
+	}
+
+	l.file = file
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(l.identity+"\n"), 0)
+	}
+	return true, nil
+}
+
+// Release unlocks and closes the file
+func (l *fileLock) Release(_ context.Context) error {
+	if l.file == nil {
+		return nil
+	}
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,51 @@
+package leader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	ctx := context.Background()
+
+	first := newFileLock(path, "replica-a")
+	second := newFileLock(path, "replica-b")
+
+	acquired, err := first.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Renewing a held lock succeeds
+	acquired, err = first.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "replica-a\n", string(content))
+
+	acquired, err = second.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	require.NoError(t, first.Release(ctx))
+	require.NoError(t, first.Release(ctx))
+
+	acquired, err = second.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	require.NoError(t, second.Release(ctx))
+}
+
+func TestFileLock_MissingDirectory(t *testing.T) {
+	lock := newFileLock(filepath.Join(t.TempDir(), "missing", "leader.lock"), "replica-a")
+
+	_, err := lock.TryAcquire(context.Background())
+	assert.Error(t, err)
+}
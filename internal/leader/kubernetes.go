@@ -0,0 +1,224 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kube"
+)
+
+// microTimeFormat is the RFC 3339 format with microseconds used by Lease times
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// kubernetesLock is a coordination.k8s.io/v1 Lease, the lock client-go's leader election
+// uses. The API is called directly with the pod's service account.
+type kubernetesLock struct {
+	api       *kube.Client
+	namespace string
+	name      string
+	identity  string
+	duration  time.Duration
+}
+
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       *string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds *int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *string `json:"acquireTime,omitempty"`
+	RenewTime            *string `json:"renewTime,omitempty"`
+	LeaseTransitions     *int    `json:"leaseTransitions,omitempty"`
+}
+
+// newKubernetesLock uses the in-cluster API server and service account. The namespace
+// defaults to the pod's own.
+func newKubernetesLock(cfg config.LeaderConfig, identity string) (*kubernetesLock, error) {
+	api, err := kube.InCluster()
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes leader election: %w", err)
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = api.Namespace
+	}
+
+	return &kubernetesLock{
+		api:       api,
+		namespace: namespace,
+		name:      cfg.Lease,
+		identity:  identity,
+		duration:  cfg.LeaseDuration,
+	}, nil
+}
+
+// TryAcquire creates the lease, renews it while held, or takes it over once the previous
+// holder let it expire or released it. Conflicting writes by another replica lose.
+func (l *kubernetesLock) TryAcquire(ctx context.Context) (bool, error) {
+	current, status, err := l.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if status == http.StatusNotFound {
+		created := l.newLease(now)
+		status, err = l.write(ctx, http.MethodPost, l.collectionURL(), created)
+		if err != nil {
+			return false, err
+		}
+		return l.written(status)
+	}
+
+	holder := ""
+	if current.Spec.HolderIdentity != nil {
+		holder = *current.Spec.HolderIdentity
+	}
+	if holder != "" && holder != l.identity && !expired(current.Spec, now) {
+		return false, nil
+	}
+
+	updated := l.newLease(now)
+	updated.Metadata.ResourceVersion = current.Metadata.ResourceVersion
+	transitions := 0
+	if current.Spec.LeaseTransitions != nil {
+		transitions = *current.Spec.LeaseTransitions
+	}
+	if holder == l.identity && current.Spec.AcquireTime != nil {
+		updated.Spec.AcquireTime = current.Spec.AcquireTime
+	} else if holder != "" {
+		transitions++
+	}
+	updated.Spec.LeaseTransitions = &transitions
+
+	status, err = l.write(ctx, http.MethodPut, l.leaseURL(), updated)
+	if err != nil {
+		return false, err
+	}
+	return l.written(status)
+}
+
+// Release clears the holder so a standby takes over on its next retry
+func (l *kubernetesLock) Release(ctx context.Context) error {
+	current, status, err := l.get(ctx)
+	if err != nil || status == http.StatusNotFound {
+		return err
+	}
+	if current.Spec.HolderIdentity == nil || *current.Spec.HolderIdentity != l.identity {
+		return nil
+	}
+
+	current.Spec.HolderIdentity = nil
+	current.Spec.AcquireTime = nil
+	current.Spec.RenewTime = nil
+	status, err = l.write(ctx, http.MethodPut, l.leaseURL(), current)
+	if err != nil {
+		return err
+	}
+	if _, err := l.written(status); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *kubernetesLock) newLease(now time.Time) *lease {
+	holder := l.identity
+	seconds := int(l.duration.Seconds())
+	timestamp := now.UTC().Format(microTimeFormat)
+	return &lease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata:   leaseMetadata{Name: l.name, Namespace: l.namespace},
+		Spec: leaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &seconds,
+			AcquireTime:          &timestamp,
+			RenewTime:            &timestamp,
+		},
+	}
+}
+
+// expired reports whether the holder failed to renew the lease in time
+func expired(spec leaseSpec, now time.Time) bool {
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	renewed, err := time.Parse(time.RFC3339Nano, *spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second))
+}
+
+// written maps the status of a create or update to whether the lease is now held
+func (l *kubernetesLock) written(status int) (bool, error) {
+	switch {
+	case status >= 200 && status < 300:
+		return true, nil
+	case status == http.StatusConflict:
+		// Another replica updated or created the lease first
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d writing lease %s/%s", status, l.namespace, l.name)
+	}
+}
+
+func (l *kubernetesLock) collectionURL() string {
+	return fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.namespace)
+}
+
+func (l *kubernetesLock) leaseURL() string {
+	return l.collectionURL() + "/" + l.name
+}
+
+func (l *kubernetesLock) get(ctx context.Context) (*lease, int, error) {
+	resp, err := l.api.Do(ctx, http.MethodGet, l.leaseURL(), nil, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, resp.StatusCode, nil
+	default:
+		return nil, 0, fmt.Errorf("unexpected status %d reading lease %s/%s", resp.StatusCode, l.namespace, l.name)
+	}
+
+	var current lease
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode lease: %w", err)
+	}
+	return &current, resp.StatusCode, nil
+}
+
+func (l *kubernetesLock) write(ctx context.Context, method, url string, body *lease) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode lease: %w", err)
+	}
+	resp, err := l.api.Do(ctx, method, url, data, kube.ContentTypeJSON)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
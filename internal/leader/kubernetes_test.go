@@ -0,0 +1,167 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeaseAPI serves a single Lease with optimistic concurrency like the API server
+type fakeLeaseAPI struct {
+	mutex   sync.Mutex
+	lease   *lease
+	version int
+}
+
+func (f *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const collection = "/apis/coordination.k8s.io/v1/namespaces/monitoring/leases"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == collection+"/url-exporter":
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.lease)
+	case r.Method == http.MethodPost && r.URL.Path == collection:
+		if f.lease != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.store(w, r)
+	case r.Method == http.MethodPut && r.URL.Path == collection+"/url-exporter":
+		var update lease
+		_ = json.NewDecoder(r.Body).Decode(&update)
+		if f.lease == nil || update.Metadata.ResourceVersion != f.lease.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.lease = &update
+		f.version++
+		f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		_ = json.NewEncoder(w).Encode(f.lease)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeLeaseAPI) store(w http.ResponseWriter, r *http.Request) {
+	var created lease
+	_ = json.NewDecoder(r.Body).Decode(&created)
+	f.lease = &created
+	f.version++
+	f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(f.lease)
+}
+
+func (f *fakeLeaseAPI) holder() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.lease == nil || f.lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *f.lease.Spec.HolderIdentity
+}
+
+func newTestKubernetesLock(baseURL, identity string) *kubernetesLock {
+	return &kubernetesLock{
+		api: &kube.Client{
+			BaseURL: baseURL,
+			HTTP:    http.DefaultClient,
+			Token:   func() (string, error) { return "token", nil },
+		},
+		namespace: "monitoring",
+		name:      "url-exporter",
+		identity:  identity,
+		duration:  15 * time.Second,
+	}
+}
+
+func TestKubernetesLock(t *testing.T) {
+	api := &fakeLeaseAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx := context.Background()
+	first := newTestKubernetesLock(server.URL, "replica-a")
+	second := newTestKubernetesLock(server.URL, "replica-b")
+
+	// The first replica creates the lease
+	acquired, err := first.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "replica-a", api.holder())
+
+	acquired, err = second.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Renewals keep the acquire time
+	acquireTime := *api.lease.Spec.AcquireTime
+	acquired, err = first.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, acquireTime, *api.lease.Spec.AcquireTime)
+
+	// A released lease is taken over right away
+	require.NoError(t, first.Release(ctx))
+	assert.Equal(t, "", api.holder())
+	require.NoError(t, second.Release(ctx))
+
+	acquired, err = second.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "replica-b", api.holder())
+}
+
+func TestKubernetesLock_TakesOverExpiredLease(t *testing.T) {
+	holder := "replica-a"
+	seconds := 15
+	transitions := 2
+	renewed := time.Now().Add(-time.Minute).UTC().Format(microTimeFormat)
+	api := &fakeLeaseAPI{lease: &lease{
+		Metadata: leaseMetadata{Name: "url-exporter", Namespace: "monitoring", ResourceVersion: "7"},
+		Spec: leaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &seconds,
+			AcquireTime:          &renewed,
+			RenewTime:            &renewed,
+			LeaseTransitions:     &transitions,
+		},
+	}, version: 7}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	acquired, err := newTestKubernetesLock(server.URL, "replica-b").TryAcquire(context.Background())
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "replica-b", api.holder())
+	assert.Equal(t, 3, *api.lease.Spec.LeaseTransitions)
+}
+
+func TestKubernetesLock_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := newTestKubernetesLock(server.URL, "replica-a").TryAcquire(context.Background())
+	assert.ErrorContains(t, err, "403")
+}
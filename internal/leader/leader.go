@@ -0,0 +1,151 @@
+// Package leader elects one of several exporter replicas as the leader that runs the
+// checks, so an HA pair does not send every probe and notification twice.
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Lock is the shared lock the replicas compete for
+type Lock interface {
+	// TryAcquire acquires the lock, or renews it when already held, and reports whether
+	// this replica holds it
+	TryAcquire(ctx context.Context) (bool, error)
+	// Release gives the lock up so a standby can take over without waiting for it to expire
+	Release(ctx context.Context) error
+}
+
+// Elector keeps trying to acquire the lock and runs the leader's work while it holds it
+type Elector struct {
+	lock          Lock
+	identity      string
+	retryPeriod   time.Duration
+	renewDeadline time.Duration
+	leading       atomic.Bool
+
+	// OnChange, if set, is called whenever this replica becomes leader or loses leadership
+	OnChange func(leading bool)
+}
+
+// New creates an elector for the configured mode. The identity is unique per process, so
+// replicas sharing an instanceId are still told apart.
+func New(cfg config.LeaderConfig) (*Elector, error) {
+	identity, err := newIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	var lock Lock
+	switch cfg.Mode {
+	case config.LeaderElectionKubernetes:
+		lock, err = newKubernetesLock(cfg, identity)
+	case config.LeaderElectionFile:
+		lock = newFileLock(cfg.File, identity)
+	default:
+		err = fmt.Errorf("unsupported leader election mode %q", cfg.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newElector(lock, identity, cfg), nil
+}
+
+func newElector(lock Lock, identity string, cfg config.LeaderConfig) *Elector {
+	return &Elector{
+		lock:        lock,
+		identity:    identity,
+		retryPeriod: cfg.RetryPeriod,
+		// Leadership ends well before the lease expires, so two replicas never both lead
+		renewDeadline: cfg.LeaseDuration * 2 / 3,
+	}
+}
+
+// Identity returns the name this replica holds the lock under
+func (e *Elector) Identity() string {
+	return e.identity
+}
+
+// IsLeader reports whether this replica currently leads
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run competes for the lock until the context is done. Each time this replica becomes
+// leader, lead is called with a context that is canceled when leadership is lost; it
+// must start its work and return. On shutdown a held lock is released.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.retryPeriod)
+	defer ticker.Stop()
+
+	var cancel context.CancelFunc
+	var renewed time.Time
+
+	for {
+		acquired, err := e.lock.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("Failed to acquire or renew the leader lock")
+		}
+
+		switch {
+		case acquired:
+			renewed = time.Now()
+			if cancel == nil {
+				var leaderCtx context.Context
+				leaderCtx, cancel = context.WithCancel(ctx)
+				log.Info().Str("identity", e.identity).Msg("Became leader, starting checks")
+				e.setLeading(true)
+				lead(leaderCtx)
+			}
+		case cancel != nil && (err == nil || time.Since(renewed) >= e.renewDeadline):
+			// Transient errors are tolerated until the renew deadline
+			log.Warn().Str("identity", e.identity).Msg("Lost leadership, standing by")
+			e.setLeading(false)
+			cancel()
+			cancel = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if cancel != nil {
+				e.setLeading(false)
+				cancel()
+
+				releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := e.lock.Release(releaseCtx); err != nil {
+					log.Warn().Err(err).Msg("Failed to release the leader lock")
+				}
+				releaseCancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) setLeading(leading bool) {
+	e.leading.Store(leading)
+	if e.OnChange != nil {
+		e.OnChange(leading)
+	}
+}
+
+// newIdentity combines the hostname, e.g. the pod name, with a random suffix
+func newIdentity() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %w", err)
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hostname + "_" + hex.EncodeToString(b), nil
+}
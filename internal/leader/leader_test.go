@@ -0,0 +1,161 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLock is acquired while held is true and fails with err when set
+type fakeLock struct {
+	mutex    sync.Mutex
+	held     bool
+	err      error
+	released bool
+}
+
+func (l *fakeLock) TryAcquire(_ context.Context) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.held && l.err == nil, l.err
+}
+
+func (l *fakeLock) Release(_ context.Context) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.released = true
+	return nil
+}
+
+func (l *fakeLock) set(held bool, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.held, l.err = held, err
+}
+
+func testConfig() config.LeaderConfig {
+	return config.LeaderConfig{LeaseDuration: 300 * time.Millisecond, RetryPeriod: 10 * time.Millisecond}
+}
+
+func TestElector_LeadsWhileHoldingTheLock(t *testing.T) {
+	lock := &fakeLock{}
+	elector := newElector(lock, "replica-a", testConfig())
+
+	var mutex sync.Mutex
+	var changes []bool
+	elector.OnChange = func(leading bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		changes = append(changes, leading)
+	}
+
+	leadCtxs := make(chan context.Context, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.Run(ctx, func(ctx context.Context) { leadCtxs <- ctx })
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, elector.IsLeader())
+	assert.Empty(t, leadCtxs)
+
+	lock.set(true, nil)
+	var leadCtx context.Context
+	select {
+	case leadCtx = <-leadCtxs:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for leadership")
+	}
+	assert.True(t, elector.IsLeader())
+
+	// Renewals do not start the work again
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, leadCtxs)
+
+	lock.set(false, nil)
+	select {
+	case <-leadCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for leadership to end")
+	}
+	assert.False(t, elector.IsLeader())
+
+	cancel()
+	<-done
+	assert.False(t, lock.released, "a lock that is not held is not released")
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []bool{true, false}, changes)
+}
+
+func TestElector_ToleratesErrorsUntilRenewDeadline(t *testing.T) {
+	lock := &fakeLock{held: true}
+	elector := newElector(lock, "replica-a", testConfig())
+
+	leadCtxs := make(chan context.Context, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx, func(ctx context.Context) { leadCtxs <- ctx })
+
+	leadCtx := <-leadCtxs
+	lock.set(true, errors.New("api server unavailable"))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, elector.IsLeader(), "a transient error keeps leadership")
+
+	select {
+	case <-leadCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the renew deadline")
+	}
+	assert.False(t, elector.IsLeader())
+}
+
+func TestElector_ReleasesOnShutdown(t *testing.T) {
+	lock := &fakeLock{held: true}
+	elector := newElector(lock, "replica-a", testConfig())
+
+	leadCtxs := make(chan context.Context, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.Run(ctx, func(ctx context.Context) { leadCtxs <- ctx })
+	}()
+
+	leadCtx := <-leadCtxs
+	cancel()
+	<-done
+
+	assert.Error(t, leadCtx.Err())
+	assert.False(t, elector.IsLeader())
+	assert.True(t, lock.released)
+}
+
+func TestNew(t *testing.T) {
+	cfg := testConfig()
+	cfg.Mode = config.LeaderElectionFile
+	cfg.File = t.TempDir() + "/leader.lock"
+
+	elector, err := New(cfg)
+	require.NoError(t, err)
+	assert.NotEmpty(t, elector.Identity())
+
+	other, err := New(cfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, elector.Identity(), other.Identity())
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	cfg.Mode = config.LeaderElectionKubernetes
+	_, err = New(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+// Package metadata looks up where the exporter runs, from the cloud provider's instance
+// metadata service or from the environment, so probes from several regions can be told
+// apart by their labels.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Label names attached to the metrics
+const (
+	LabelRegion = "region"
+	LabelZone   = "zone"
+	LabelNode   = "node"
+)
+
+// Endpoints of the metadata services, variables so tests can point them elsewhere
+var (
+	awsEndpoint   = "http://169.254.169.254"
+	gcpEndpoint   = "http://metadata.google.internal"
+	azureEndpoint = "http://169.254.169.254"
+)
+
+// Lookup returns the region, zone and node labels of the configured provider. Labels the
+// provider does not know are left out. NODE_NAME, as set through the Downward API, names
+// the node with every provider, since on Kubernetes it means more than the instance.
+func Lookup(ctx context.Context, cfg config.MetadataConfig) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	var (
+		labels map[string]string
+		err    error
+	)
+	switch cfg.Provider {
+	case config.MetadataAWS:
+		labels, err = lookupAWS(ctx)
+	case config.MetadataGCP:
+		labels, err = lookupGCP(ctx)
+	case config.MetadataAzure:
+		labels, err = lookupAzure(ctx)
+	default:
+		labels = map[string]string{LabelRegion: os.Getenv("REGION"), LabelZone: os.Getenv("ZONE")}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s instance metadata: %w", cfg.Provider, err)
+	}
+
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		labels[LabelNode] = node
+	}
+	for name, value := range labels {
+		if value == "" {
+			delete(labels, name)
+		}
+	}
+	return labels, nil
+}
+
+// lookupAWS reads the EC2 placement using an IMDSv2 session token
+func lookupAWS(ctx context.Context) (map[string]string, error) {
+	token, err := fetch(ctx, http.MethodPut, awsEndpoint+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return nil, err
+	}
+
+	header := map[string]string{"X-aws-ec2-metadata-token": token}
+	labels := make(map[string]string, 3)
+	for label, path := range map[string]string{
+		LabelRegion: "/latest/meta-data/placement/region",
+		LabelZone:   "/latest/meta-data/placement/availability-zone",
+		LabelNode:   "/latest/meta-data/instance-id",
+	} {
+		if labels[label], err = fetch(ctx, http.MethodGet, awsEndpoint+path, header); err != nil {
+			return nil, err
+		}
+	}
+	return labels, nil
+}
+
+// lookupGCP reads the zone and instance name of a GCE instance. The region is the zone
+// without its last part, e.g. us-central1 for us-central1-a.
+func lookupGCP(ctx context.Context) (map[string]string, error) {
+	header := map[string]string{"Metadata-Flavor": "Google"}
+	zone, err := fetch(ctx, http.MethodGet, gcpEndpoint+"/computeMetadata/v1/instance/zone", header)
+	if err != nil {
+		return nil, err
+	}
+	name, err := fetch(ctx, http.MethodGet, gcpEndpoint+"/computeMetadata/v1/instance/name", header)
+	if err != nil {
+		return nil, err
+	}
+
+	// The zone comes as projects/<number>/zones/<zone>
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return map[string]string{LabelRegion: region, LabelZone: zone, LabelNode: name}, nil
+}
+
+// lookupAzure reads the location, availability zone and name of an Azure VM
+func lookupAzure(ctx context.Context) (map[string]string, error) {
+	body, err := fetch(ctx, http.MethodGet, azureEndpoint+"/metadata/instance/compute?api-version=2021-02-01&format=json",
+		map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var compute struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		Name     string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return nil, fmt.Errorf("failed to decode compute metadata: %w", err)
+	}
+	return map[string]string{LabelRegion: compute.Location, LabelZone: compute.Zone, LabelNode: compute.Name}, nil
+}
+
+// fetch requests a metadata value and returns the response body
+func fetch(ctx context.Context, method, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+
+	// The metadata services are link-local; a proxy from the environment must not be used
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveMetadata(t *testing.T, endpoint *string, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	previous := *endpoint
+	*endpoint = server.URL
+	t.Cleanup(func() { *endpoint = previous })
+}
+
+func lookup(t *testing.T, provider string) (map[string]string, error) {
+	t.Helper()
+	t.Setenv("NODE_NAME", "")
+	return Lookup(context.Background(), config.MetadataConfig{Provider: provider, Timeout: time.Second})
+}
+
+func TestLookup_AWS(t *testing.T) {
+	serveMetadata(t, &awsEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			assert.Equal(t, http.MethodPut, r.Method)
+			_, _ = w.Write([]byte("session"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		values := map[string]string{
+			"/latest/meta-data/placement/region":            "eu-west-1",
+			"/latest/meta-data/placement/availability-zone": "eu-west-1b",
+			"/latest/meta-data/instance-id":                 "i-0abc",
+		}
+		_, _ = w.Write([]byte(values[r.URL.Path]))
+	})
+
+	labels, err := lookup(t, config.MetadataAWS)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu-west-1", "zone": "eu-west-1b", "node": "i-0abc"}, labels)
+}
+
+func TestLookup_GCP(t *testing.T) {
+	serveMetadata(t, &gcpEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/zone":
+			_, _ = w.Write([]byte("projects/123/zones/us-central1-a"))
+		case "/computeMetadata/v1/instance/name":
+			_, _ = w.Write([]byte("probe-1"))
+		}
+	})
+
+	labels, err := lookup(t, config.MetadataGCP)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "us-central1", "zone": "us-central1-a", "node": "probe-1"}, labels)
+}
+
+func TestLookup_Azure(t *testing.T) {
+	serveMetadata(t, &azureEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"location":"westeurope","zone":"","name":"probe-vm"}`))
+	})
+
+	labels, err := lookup(t, config.MetadataAzure)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "westeurope", "node": "probe-vm"}, labels, "an empty zone is left out")
+}
+
+func TestLookup_Env(t *testing.T) {
+	t.Setenv("REGION", "ap-southeast-1")
+	t.Setenv("ZONE", "")
+	t.Setenv("NODE_NAME", "worker-3")
+
+	labels, err := Lookup(context.Background(), config.MetadataConfig{Provider: config.MetadataEnv, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "ap-southeast-1", "node": "worker-3"}, labels)
+}
+
+func TestLookup_NodeNameOverridesInstance(t *testing.T) {
+	serveMetadata(t, &azureEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"location":"westeurope","zone":"2","name":"probe-vm"}`))
+	})
+	t.Setenv("NODE_NAME", "aks-node-7")
+
+	labels, err := Lookup(context.Background(), config.MetadataConfig{Provider: config.MetadataAzure, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, "aks-node-7", labels["node"])
+}
+
+func TestLookup_Unavailable(t *testing.T) {
+	serveMetadata(t, &awsEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := lookup(t, config.MetadataAWS)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "aws instance metadata")
+}
@@ -1,18 +1,24 @@
 package metrics
 
 import (
-	"context"
 	"fmt"
 	neturl "net/url"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/jasoet/url-exporter/internal/checker"
 	"github.com/jasoet/url-exporter/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/rs/zerolog/log"
 )
 
+// SkippedValue is the url_up value of targets whose check was skipped because a dependency
+// is down, telling them apart from targets that are down themselves
+const SkippedValue = -1
+
 // Collector implements the Prometheus collector interface
 type Collector struct {
 	config      *config.Config
@@ -20,6 +26,17 @@ type Collector struct {
 	mutex       sync.RWMutex
 	lastResults map[string]*checker.Result
 	counters    map[string]map[string]int // URL -> status_code -> count
+	slo         map[string]*sloTracker    // URL -> availability tracker, for targets with an objective
+	lastSuccess map[string]time.Time      // URL -> timestamp of the last successful check
+	disabled    map[*prometheus.Desc]bool // metric families turned off in the configuration
+	history     map[string][]HistoryPoint // URL -> recent check outcomes, oldest first
+	daily       map[string][]DailyUptime  // URL -> check counts per day, oldest first
+	incidents   map[string][]Incident     // URL -> downtime episodes, oldest first; the last may be open
+	labels      map[string]*targetLabels  // URL -> label values of the target's series
+	removed     map[string]time.Time      // URL -> removal time of targets kept for the removal grace period
+
+	incidentStats map[string]*incidentSummary // URL -> durations of the incidents closed since startup
+	composites    []compositeRollup
 
 	urlUp              *prometheus.Desc
 	urlError           *prometheus.Desc
@@ -27,86 +44,312 @@ type Collector struct {
 	urlHTTPStatusCode  *prometheus.Desc
 	urlCheckTotal      *prometheus.Desc
 	urlStatusCodeTotal *prometheus.Desc
+	urlContentMatch    *prometheus.Desc
+	urlHeaderMatch     *prometheus.Desc
+	urlSizeInBounds    *prometheus.Desc
+	urlHTTPVersion     *prometheus.Desc
+	urlTLSVersionInfo  *prometheus.Desc
+	urlTLSCertExpiry   *prometheus.Desc
+	urlSecurityHeader  *prometheus.Desc
+	urlCacheHit        *prometheus.Desc
+	urlCacheAge        *prometheus.Desc
+	urlCacheMaxAge     *prometheus.Desc
+	urlClockSkew       *prometheus.Desc
+	urlStepDuration    *prometheus.Desc
+	urlStepSuccess     *prometheus.Desc
+
+	urlLastSuccess *prometheus.Desc
+
+	urlSLOObjective      *prometheus.Desc
+	urlSLOBurnRate       *prometheus.Desc
+	urlSLOBudgetConsumed *prometheus.Desc
+
+	urlIncidentDuration *prometheus.Desc
+
+	urlCompositeUp *prometheus.Desc
+
+	urlTargetInfo *prometheus.Desc
 }
 
 func NewCollector(cfg *config.Config, chk *checker.Checker) *Collector {
-	return &Collector{
+	slo := make(map[string]*sloTracker)
+	for _, target := range cfg.AllTargets() {
+		if target.Objective > 0 {
+			slo[target.URL] = newSLOTracker(target.Objective, cfg.SLO.Windows)
+		}
+	}
+
+	c := &Collector{
 		config:      cfg,
 		checker:     chk,
 		lastResults: make(map[string]*checker.Result),
 		counters:    make(map[string]map[string]int),
+		slo:         slo,
+		lastSuccess: make(map[string]time.Time),
+		history:     make(map[string][]HistoryPoint),
+		daily:       make(map[string][]DailyUptime),
+		incidents:   make(map[string][]Incident),
+		labels:      make(map[string]*targetLabels),
+		removed:     make(map[string]time.Time),
+
+		incidentStats: make(map[string]*incidentSummary),
+		composites:    newCompositeRollups(cfg),
 
 		urlUp: prometheus.NewDesc(
 			"url_up",
-			"URL is up (1 if URL returns 2xx status, 0 otherwise)",
-			[]string{"url", "host", "path", "protocol", "instance"},
+			"URL is up (1 if URL returns 2xx status, 0 otherwise, -1 if skipped because a dependency is down)",
+			targetLabelNames,
 			nil,
 		),
 		urlResponseTime: prometheus.NewDesc(
 			"url_response_time_milliseconds",
 			"Response time in milliseconds",
-			[]string{"url", "host", "path", "protocol", "instance"},
+			targetLabelNames,
 			nil,
 		),
 		urlHTTPStatusCode: prometheus.NewDesc(
 			"url_http_status_code",
 			"HTTP status code returned",
-			[]string{"url", "host", "path", "protocol", "instance"},
+			targetLabelNames,
 			nil,
 		),
 		urlCheckTotal: prometheus.NewDesc(
 			"url_check_total",
 			"Total number of checks by status code",
-			[]string{"url", "host", "path", "protocol", "status_code", "instance"},
+			statusLabelNames,
 			nil,
 		),
 		urlError: prometheus.NewDesc(
 			"url_error",
 			"URL error (1 if URL returns network/connection error, 0 otherwise)",
-			[]string{"url", "host", "path", "protocol", "instance"},
+			targetLabelNames,
 			nil,
 		),
 		urlStatusCodeTotal: prometheus.NewDesc(
 			"url_status_code_total",
 			"Counter for each specific HTTP status code encountered",
-			[]string{"url", "host", "path", "protocol", "status_code", "instance"},
+			statusLabelNames,
+			nil,
+		),
+		urlContentMatch: prometheus.NewDesc(
+			"url_content_match",
+			"Response body matches the configured body assertion (1 if matched, 0 otherwise)",
+			targetLabelNames,
+			nil,
+		),
+		urlHeaderMatch: prometheus.NewDesc(
+			"url_header_match",
+			"Response headers match all configured header assertions (1 if matched, 0 otherwise)",
+			targetLabelNames,
+			nil,
+		),
+		urlSizeInBounds: prometheus.NewDesc(
+			"url_size_within_bounds",
+			"Response body size within minBodyBytes and maxBodyBytes (1 if within, 0 otherwise), for targets with size bounds",
+			targetLabelNames,
+			nil,
+		),
+		urlHTTPVersion: prometheus.NewDesc(
+			"url_http_version",
+			"Negotiated HTTP protocol version (1.0, 1.1, 2 or 3)",
+			targetLabelNames,
+			nil,
+		),
+		urlTLSVersionInfo: prometheus.NewDesc(
+			"url_tls_version_info",
+			"Negotiated TLS version and cipher suite of HTTPS checks, always 1; legacy is true below the TLS policy",
+			tlsLabelNames,
+			nil,
+		),
+		urlTLSCertExpiry: prometheus.NewDesc(
+			"url_tls_cert_expiry_timestamp_seconds",
+			"Unix timestamp at which the certificate presented to HTTPS checks expires",
+			targetLabelNames,
+			nil,
+		),
+		urlSecurityHeader: prometheus.NewDesc(
+			"url_security_header_present",
+			"Security header present in the response (1 if present, 0 otherwise), for targets whose module audits them",
+			headerLabelNames,
+			nil,
+		),
+		urlCacheHit: prometheus.NewDesc(
+			"url_http_cache_hit",
+			"Response served from the CDN cache according to X-Cache or CF-Cache-Status (1 on a hit, 0 on a miss)",
+			targetLabelNames,
+			nil,
+		),
+		urlCacheAge: prometheus.NewDesc(
+			"url_http_cache_age_seconds",
+			"Age header of the response, the seconds it spent in caches",
+			targetLabelNames,
+			nil,
+		),
+		urlCacheMaxAge: prometheus.NewDesc(
+			"url_http_cache_max_age_seconds",
+			"Freshness lifetime of the response in seconds, from the s-maxage or max-age directive of Cache-Control",
+			targetLabelNames,
+			nil,
+		),
+		urlClockSkew: prometheus.NewDesc(
+			"url_server_clock_skew_seconds",
+			"Offset of the server clock from the exporter clock according to the Date header, positive when the server is ahead",
+			targetLabelNames,
+			nil,
+		),
+		urlStepDuration: prometheus.NewDesc(
+			"url_step_duration_milliseconds",
+			"Response time of a transaction step in milliseconds",
+			stepLabelNames,
+			nil,
+		),
+		urlStepSuccess: prometheus.NewDesc(
+			"url_step_success",
+			"Transaction step passed (1 if it returned 2xx and its assertion and extractions succeeded, 0 otherwise)",
+			stepLabelNames,
+			nil,
+		),
+		urlLastSuccess: prometheus.NewDesc(
+			"url_last_success_timestamp_seconds",
+			"Unix timestamp of the last successful (2xx) check, 0 if the target has never succeeded",
+			targetLabelNames,
 			nil,
 		),
+		urlSLOObjective: prometheus.NewDesc(
+			"url_slo_objective",
+			"Configured availability objective for the target (ratio, e.g. 0.999)",
+			targetLabelNames,
+			nil,
+		),
+		urlSLOBurnRate: prometheus.NewDesc(
+			"url_slo_burn_rate",
+			"Error budget burn rate over the window (1 means the budget lasts exactly the SLO period)",
+			windowLabelNames,
+			nil,
+		),
+		urlSLOBudgetConsumed: prometheus.NewDesc(
+			"url_slo_error_budget_consumed_ratio",
+			"Fraction of the SLO period's error budget consumed within the window",
+			windowLabelNames,
+			nil,
+		),
+		urlIncidentDuration: prometheus.NewDesc(
+			"url_incident_duration_seconds",
+			"Duration of the downtime incidents that ended since startup",
+			targetLabelNames,
+			nil,
+		),
+		urlCompositeUp: prometheus.NewDesc(
+			"url_composite_up",
+			"Composite is up (1 if enough of its checked members are up for its mode, 0 otherwise)",
+			compositeLabelNames,
+			nil,
+		),
+		urlTargetInfo: prometheus.NewDesc(
+			"url_target_info",
+			"Description and runbook URL of targets that set them, always 1",
+			infoLabelNames,
+			nil,
+		),
+	}
+
+	families := c.families()
+	c.disabled = make(map[*prometheus.Desc]bool)
+	for _, name := range cfg.Metrics.Disabled {
+		desc, exists := families[name]
+		if !exists {
+			log.Warn().Str("metric", name).Msg("Ignoring unknown metric family in metrics.disabled")
+			continue
+		}
+		c.disabled[desc] = true
 	}
+
+	return c
+}
+
+// families returns every metric family the collector can export, keyed by metric name
+func (c *Collector) families() map[string]*prometheus.Desc {
+	return map[string]*prometheus.Desc{
+		"url_up":                                c.urlUp,
+		"url_error":                             c.urlError,
+		"url_response_time_milliseconds":        c.urlResponseTime,
+		"url_http_status_code":                  c.urlHTTPStatusCode,
+		"url_check_total":                       c.urlCheckTotal,
+		"url_status_code_total":                 c.urlStatusCodeTotal,
+		"url_content_match":                     c.urlContentMatch,
+		"url_header_match":                      c.urlHeaderMatch,
+		"url_size_within_bounds":                c.urlSizeInBounds,
+		"url_http_version":                      c.urlHTTPVersion,
+		"url_tls_version_info":                  c.urlTLSVersionInfo,
+		"url_tls_cert_expiry_timestamp_seconds": c.urlTLSCertExpiry,
+		"url_security_header_present":           c.urlSecurityHeader,
+		"url_http_cache_hit":                    c.urlCacheHit,
+		"url_http_cache_age_seconds":            c.urlCacheAge,
+		"url_http_cache_max_age_seconds":        c.urlCacheMaxAge,
+		"url_server_clock_skew_seconds":         c.urlClockSkew,
+		"url_step_duration_milliseconds":        c.urlStepDuration,
+		"url_step_success":                      c.urlStepSuccess,
+		"url_last_success_timestamp_seconds":    c.urlLastSuccess,
+		"url_slo_objective":                     c.urlSLOObjective,
+		"url_slo_burn_rate":                     c.urlSLOBurnRate,
+		"url_slo_error_budget_consumed_ratio":   c.urlSLOBudgetConsumed,
+		"url_incident_duration_seconds":         c.urlIncidentDuration,
+		"url_composite_up":                      c.urlCompositeUp,
+		"url_target_info":                       c.urlTargetInfo,
+	}
+}
+
+// Enabled reports whether the collector exports the metric family with the name
+func (c *Collector) Enabled(name string) bool {
+	desc, exists := c.families()[name]
+	return exists && !c.disabled[desc]
 }
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- c.urlUp
-	ch <- c.urlError
-	ch <- c.urlResponseTime
-	ch <- c.urlHTTPStatusCode
-	ch <- c.urlCheckTotal
-	ch <- c.urlStatusCodeTotal
+	for _, desc := range c.families() {
+		if !c.disabled[desc] {
+			ch <- desc
+		}
+	}
 }
 
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.evictRemoved(time.Now())
+
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
 	for _, result := range c.lastResults {
-		// Extract protocol from URL
-		protocol := "unknown"
-		if u, err := neturl.Parse(result.URL); err == nil {
-			protocol = u.Scheme
-		}
-		
-		labels := []string{result.URL, result.Host, result.Path, protocol, c.config.InstanceID}
+		target := c.labelsFor(result)
+		labels := target.base
 
 		up := float64(0)
-		if result.Error == nil && result.StatusCode >= 200 && result.StatusCode < 300 {
+		switch {
+		case result.IsUp():
 			up = 1
+		case result.IsSkipped():
+			up = SkippedValue
 		}
 
-		ch <- prometheus.MustNewConstMetric(
+		c.send(
+			ch,
 			c.urlUp,
 			prometheus.GaugeValue,
 			up,
-			labels...,
+			labels,
+		)
+
+		lastSuccess := float64(0)
+		if ts, exists := c.lastSuccess[result.URL]; exists {
+			lastSuccess = float64(ts.UnixNano()) / 1e9
+		}
+
+		c.send(
+			ch,
+			c.urlLastSuccess,
+			prometheus.GaugeValue,
+			lastSuccess,
+			labels,
 		)
 
 		errorValue := float64(0)
@@ -114,104 +357,490 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 			errorValue = 1
 		}
 
-		ch <- prometheus.MustNewConstMetric(
+		c.send(
+			ch,
 			c.urlError,
 			prometheus.GaugeValue,
 			errorValue,
-			labels...,
+			labels,
 		)
 
-		if result.Error == nil {
-			ch <- prometheus.MustNewConstMetric(
+		for _, step := range result.Steps {
+			stepLabels := target.stepLabels(step.Name)
+			c.send(
+				ch,
+				c.urlStepDuration,
+				prometheus.GaugeValue,
+				float64(step.ResponseTime.Milliseconds()),
+				stepLabels,
+			)
+			c.send(
+				ch,
+				c.urlStepSuccess,
+				prometheus.GaugeValue,
+				boolToFloat(step.Passed()),
+				stepLabels,
+			)
+		}
+
+		// Reported even when an out of bounds size failed the check
+		if result.SizeWithinBounds != nil {
+			c.send(
+				ch,
+				c.urlSizeInBounds,
+				prometheus.GaugeValue,
+				boolToFloat(*result.SizeWithinBounds),
+				labels,
+			)
+		}
+
+		if result.Error == nil && !result.IsSkipped() {
+			c.send(
+				ch,
 				c.urlResponseTime,
 				prometheus.GaugeValue,
 				float64(result.ResponseTime.Milliseconds()),
-				labels...,
+				labels,
 			)
 
-			ch <- prometheus.MustNewConstMetric(
+			c.send(
+				ch,
 				c.urlHTTPStatusCode,
 				prometheus.GaugeValue,
 				float64(result.StatusCode),
-				labels...,
+				labels,
 			)
+
+			if result.HTTPVersion > 0 {
+				c.send(
+					ch,
+					c.urlHTTPVersion,
+					prometheus.GaugeValue,
+					result.HTTPVersion,
+					labels,
+				)
+			}
+
+			if result.TLSVersion != "" {
+				c.send(
+					ch,
+					c.urlTLSVersionInfo,
+					prometheus.GaugeValue,
+					1,
+					target.tlsLabels(resultTLS(result)),
+				)
+			}
+
+			if !result.TLSCertExpiry.IsZero() {
+				c.send(
+					ch,
+					c.urlTLSCertExpiry,
+					prometheus.GaugeValue,
+					float64(result.TLSCertExpiry.Unix()),
+					labels,
+				)
+			}
+
+			if result.BodyMatch != nil {
+				c.send(
+					ch,
+					c.urlContentMatch,
+					prometheus.GaugeValue,
+					boolToFloat(*result.BodyMatch),
+					labels,
+				)
+			}
+
+			if result.HeaderMatch != nil {
+				c.send(
+					ch,
+					c.urlHeaderMatch,
+					prometheus.GaugeValue,
+					boolToFloat(*result.HeaderMatch),
+					labels,
+				)
+			}
+
+			for header, present := range result.SecurityHeaders {
+				c.send(
+					ch,
+					c.urlSecurityHeader,
+					prometheus.GaugeValue,
+					boolToFloat(present),
+					target.headerLabels(header),
+				)
+			}
+
+			if cache := result.Cache; cache != nil {
+				if cache.Hit != nil {
+					c.send(
+						ch,
+						c.urlCacheHit,
+						prometheus.GaugeValue,
+						boolToFloat(*cache.Hit),
+						labels,
+					)
+				}
+				if cache.Age != nil {
+					c.send(
+						ch,
+						c.urlCacheAge,
+						prometheus.GaugeValue,
+						cache.Age.Seconds(),
+						labels,
+					)
+				}
+				if cache.MaxAge != nil {
+					c.send(
+						ch,
+						c.urlCacheMaxAge,
+						prometheus.GaugeValue,
+						cache.MaxAge.Seconds(),
+						labels,
+					)
+				}
+			}
+
+			if result.ClockSkew != nil {
+				c.send(
+					ch,
+					c.urlClockSkew,
+					prometheus.GaugeValue,
+					result.ClockSkew.Seconds(),
+					labels,
+				)
+			}
 		}
 	}
 
+	c.collectSLO(ch)
+	c.collectIncidents(ch)
+	c.collectComposites(ch)
+	c.collectTargetInfo(ch)
+
 	for url, statusCounts := range c.counters {
 		result, exists := c.lastResults[url]
 		if !exists {
 			continue
 		}
 
-		// Extract protocol from URL for counter metrics
-		protocol := "unknown"
-		if u, err := neturl.Parse(url); err == nil {
-			protocol = u.Scheme
-		}
-		
-		baseLabels := []string{url, result.Host, result.Path, protocol}
+		labels := c.labelsFor(result)
 
 		for statusCode, count := range statusCounts {
-			checkLabels := append(baseLabels, statusCode, c.config.InstanceID)
-			ch <- prometheus.MustNewConstMetric(
+			statusLabels := labels.statusLabels(statusCode)
+			c.send(
+				ch,
 				c.urlCheckTotal,
 				prometheus.CounterValue,
 				float64(count),
-				checkLabels...,
+				statusLabels,
 			)
 
-			statusLabels := append(baseLabels, statusCode, c.config.InstanceID)
-			ch <- prometheus.MustNewConstMetric(
+			c.send(
+				ch,
 				c.urlStatusCodeTotal,
 				prometheus.CounterValue,
 				float64(count),
-				statusLabels...,
+				statusLabels,
 			)
 		}
 	}
 }
 
-func (c *Collector) Start(ctx context.Context) {
+// Record updates the collector state with a single check result. It makes the collector
+// a checker.ResultSink.
+func (c *Collector) Record(result checker.Result) {
 	c.mutex.Lock()
-	for _, url := range c.config.Targets {
-		c.counters[url] = make(map[string]int)
+	c.lastResults[result.URL] = &result
+
+	statusCode := "error"
+	switch {
+	case result.IsSkipped():
+		statusCode = "skipped"
+	case result.Error == nil:
+		statusCode = strconv.Itoa(result.StatusCode)
+	}
+
+	if _, exists := c.counters[result.URL]; !exists {
+		c.counters[result.URL] = make(map[string]int)
+	}
+	c.counters[result.URL][statusCode]++
+
+	labels, exists := c.labels[result.URL]
+	if !exists || !labels.matches(&result) {
+		labels = c.newTargetLabels(&result)
+		c.labels[result.URL] = labels
 	}
+	if _, exists := labels.status[statusCode]; !exists {
+		labels.status[statusCode] = labels.statusLabels(statusCode)
+	}
+	if result.TLSVersion != "" {
+		if _, exists := labels.tls[resultTLS(&result)]; !exists {
+			labels.tls[resultTLS(&result)] = labels.tlsLabels(resultTLS(&result))
+		}
+	}
+	for header := range result.SecurityHeaders {
+		if _, exists := labels.headers[header]; !exists {
+			labels.headers[header] = labels.headerLabels(header)
+		}
+	}
+	for _, step := range result.Steps {
+		if _, exists := labels.steps[step.Name]; !exists {
+			labels.steps[step.Name] = labels.stepLabels(step.Name)
+		}
+	}
+
+	if result.IsUp() {
+		c.lastSuccess[result.URL] = result.Timestamp
+	}
+
+	// Skipped checks say nothing about the target's availability
+	if result.IsSkipped() {
+		c.mutex.Unlock()
+		log.Debug().
+			Str("url", config.RedactURL(result.URL)).
+			Str("dependency", config.RedactURL(result.SkippedBy)).
+			Msg("Processed skipped check result")
+		return
+	}
+
+	if tracker, exists := c.slo[result.URL]; exists {
+		tracker.record(result.Timestamp, result.IsUp())
+	}
+
+	point := HistoryPoint{
+		Timestamp:    result.Timestamp,
+		ResponseTime: result.ResponseTime,
+		Up:           result.IsUp(),
+		StatusCode:   result.StatusCode,
+	}
+	if result.Error != nil {
+		point.Error = result.Error.Error()
+	}
+	c.history[result.URL] = appendHistory(c.history[result.URL], point, c.historySize())
+	if !result.Timestamp.IsZero() {
+		c.daily[result.URL] = appendDaily(c.daily[result.URL], result.Timestamp, point.Up)
+	}
+	c.recordIncident(result)
 	c.mutex.Unlock()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case result, ok := <-c.checker.Results():
+	log.Debug().
+		Str("url", config.RedactURL(result.URL)).
+		Str("status", statusCode).
+		Msg("Processed check result")
+}
+
+// AddTarget prepares the collector for a target registered at runtime. For a target that
+// is already known only the SLO tracking is adjusted; a changed objective starts a new tracker.
+func (c *Collector) AddTarget(target config.Target) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.removed, target.URL)
+	if _, exists := c.counters[target.URL]; !exists {
+		c.counters[target.URL] = make(map[string]int)
+	}
+	if target.Objective <= 0 {
+		delete(c.slo, target.URL)
+		return
+	}
+	if tracker, exists := c.slo[target.URL]; !exists || tracker.objective != target.Objective {
+		c.slo[target.URL] = newSLOTracker(target.Objective, c.config.SLO.Windows)
+	}
+}
+
+// RemoveTarget drops all state kept for a target so its series disappear from the next
+// scrape, or once metrics.removalGrace has passed
+func (c *Collector) RemoveTarget(url string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.config.Metrics.RemovalGrace > 0 {
+		if _, exists := c.removed[url]; !exists {
+			c.removed[url] = time.Now()
+		}
+		return
+	}
+	c.deleteTarget(url)
+}
+
+// evictRemoved drops the state of the removed targets whose grace period has passed
+func (c *Collector) evictRemoved(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for url, removedAt := range c.removed {
+		if now.Sub(removedAt) >= c.config.Metrics.RemovalGrace {
+			c.deleteTarget(url)
+			log.Debug().Str("url", config.RedactURL(url)).Msg("Evicted the series of a removed target")
+		}
+	}
+}
+
+// deleteTarget drops all state kept for a target. The caller must hold the lock.
+func (c *Collector) deleteTarget(url string) {
+	delete(c.removed, url)
+	delete(c.lastResults, url)
+	delete(c.counters, url)
+	delete(c.slo, url)
+	delete(c.lastSuccess, url)
+	delete(c.history, url)
+	delete(c.daily, url)
+	delete(c.incidents, url)
+	delete(c.incidentStats, url)
+	delete(c.labels, url)
+}
+
+// collectSLO emits objective, burn rate and budget consumption for every target with an
+// objective. The caller must hold the read lock.
+func (c *Collector) collectSLO(ch chan<- prometheus.Metric) {
+	now := time.Now()
+
+	for url, tracker := range c.slo {
+		result, exists := c.lastResults[url]
+		if !exists {
+			continue
+		}
+
+		labels := c.labelsFor(result)
+
+		c.send(
+			ch,
+			c.urlSLOObjective,
+			prometheus.GaugeValue,
+			tracker.objective,
+			labels.base,
+		)
+
+		for i, window := range c.config.SLO.Windows {
+			errorRatio, ok := tracker.errorRatio(now, window)
 			if !ok {
-				return
+				continue
 			}
 
-			c.mutex.Lock()
-			c.lastResults[result.URL] = &result
+			burnRate := tracker.burnRate(errorRatio)
+			windowLabels := labels.windows[i]
 
-			statusCode := "error"
-			if result.Error == nil {
-				statusCode = strconv.Itoa(result.StatusCode)
-			}
+			c.send(
+				ch,
+				c.urlSLOBurnRate,
+				prometheus.GaugeValue,
+				burnRate,
+				windowLabels,
+			)
 
-			if _, exists := c.counters[result.URL]; !exists {
-				c.counters[result.URL] = make(map[string]int)
-			}
-			c.counters[result.URL][statusCode]++
-			c.mutex.Unlock()
+			c.send(
+				ch,
+				c.urlSLOBudgetConsumed,
+				prometheus.GaugeValue,
+				burnRate*window.Seconds()/c.config.SLO.Period.Seconds(),
+				windowLabels,
+			)
+		}
+	}
+}
 
-			log.Debug().
-				Str("url", result.URL).
-				Str("status", statusCode).
-				Msg("Processed check result")
+// collectIncidents emits the duration summary of the incidents closed since startup. The
+// caller must hold the read lock.
+func (c *Collector) collectIncidents(ch chan<- prometheus.Metric) {
+	if c.disabled[c.urlIncidentDuration] {
+		return
+	}
+
+	for url, summary := range c.incidentStats {
+		result, exists := c.lastResults[url]
+		if !exists {
+			continue
 		}
+
+		ch <- prometheus.MustNewConstSummary(c.urlIncidentDuration, summary.count, summary.sum, nil, c.labelsFor(result).values...)
 	}
 }
 
-func (c *Collector) Register() error {
-	if err := prometheus.Register(c); err != nil {
+// send emits a metric unless its family has been disabled
+func (c *Collector) send(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labels []*dto.LabelPair) {
+	if c.disabled[desc] {
+		return
+	}
+	ch <- &labeledMetric{desc: desc, valueType: valueType, value: value, labels: labels}
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// resultProtocol returns the protocol label for a result, deriving it from the URL
+// for results that were recorded without one
+func resultProtocol(result *checker.Result) string {
+	if result.Protocol != "" {
+		return result.Protocol
+	}
+	if u, err := neturl.Parse(result.URL); err == nil && u.Scheme != "" {
+		return u.Scheme
+	}
+	return "unknown"
+}
+
+// Snapshot returns a copy of the latest result for each target, ordered by URL
+func (c *Collector) Snapshot() []checker.Result {
+	c.evictRemoved(time.Now())
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	results := make([]checker.Result, 0, len(c.lastResults))
+	for _, result := range c.lastResults {
+		results = append(results, *result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].URL < results[j].URL
+	})
+
+	return results
+}
+
+// Counters returns a copy of the per-status check counts of every target, keyed by URL
+// and then by status code ("error" for failed checks)
+func (c *Collector) Counters() map[string]map[string]int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	counters := make(map[string]map[string]int, len(c.counters))
+	for url, statusCounts := range c.counters {
+		counts := make(map[string]int, len(statusCounts))
+		for statusCode, count := range statusCounts {
+			counts[statusCode] = count
+		}
+		counters[url] = counts
+	}
+
+	return counters
+}
+
+// CheckStats returns the number of checks of the target since start and how many of them
+// succeeded with a 2xx status
+func (c *Collector) CheckStats(url string) (total, successful int) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for statusCode, count := range c.counters[url] {
+		total += count
+		if code, err := strconv.Atoi(statusCode); err == nil && code >= 200 && code < 300 {
+			successful += count
+		}
+	}
+
+	return total, successful
+}
+
+// Register registers the collector with the given registerer, typically a dedicated
+// prometheus.Registry owned by the server
+func (c *Collector) Register(registerer prometheus.Registerer) error {
+	if err := registerer.Register(c); err != nil {
 		return fmt.Errorf("failed to register collector: %w", err)
 	}
 	return nil
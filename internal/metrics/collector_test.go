@@ -3,6 +3,9 @@ package metrics
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -78,7 +81,7 @@ func TestCollector_Describe(t *testing.T) {
 	chk := checker.New(cfg)
 	collector := NewCollector(cfg, chk)
 	
-	ch := make(chan *prometheus.Desc, 10)
+	ch := make(chan *prometheus.Desc, 26)
 	collector.Describe(ch)
 	close(ch)
 	
@@ -87,7 +90,7 @@ func TestCollector_Describe(t *testing.T) {
 		descriptors = append(descriptors, desc)
 	}
 	
-	assert.Equal(t, 6, len(descriptors))
+	assert.Equal(t, 26, len(descriptors))
 	
 	// Verify all expected descriptors are present
 	expectedDescs := []*prometheus.Desc{
@@ -97,6 +100,26 @@ func TestCollector_Describe(t *testing.T) {
 		collector.urlHTTPStatusCode,
 		collector.urlCheckTotal,
 		collector.urlStatusCodeTotal,
+		collector.urlContentMatch,
+		collector.urlHeaderMatch,
+		collector.urlSizeInBounds,
+		collector.urlHTTPVersion,
+		collector.urlTLSVersionInfo,
+		collector.urlTLSCertExpiry,
+		collector.urlSecurityHeader,
+		collector.urlCacheHit,
+		collector.urlCacheAge,
+		collector.urlCacheMaxAge,
+		collector.urlClockSkew,
+		collector.urlStepDuration,
+		collector.urlStepSuccess,
+		collector.urlLastSuccess,
+		collector.urlSLOObjective,
+		collector.urlSLOBurnRate,
+		collector.urlSLOBudgetConsumed,
+		collector.urlIncidentDuration,
+		collector.urlCompositeUp,
+		collector.urlTargetInfo,
 	}
 	
 	for _, expected := range expectedDescs {
@@ -167,8 +190,8 @@ func TestCollector_Collect_SuccessfulResult(t *testing.T) {
 		metrics = append(metrics, metric)
 	}
 	
-	// Should have 6 metrics: url_up, url_error, url_response_time, url_http_status_code, url_check_total, url_status_code_total
-	assert.Equal(t, 6, len(metrics))
+	// Should have 7 metrics: url_up, url_last_success_timestamp_seconds, url_error, url_response_time, url_http_status_code, url_check_total, url_status_code_total
+	assert.Equal(t, 7, len(metrics))
 	
 	// Verify metrics values
 	for _, metric := range metrics {
@@ -255,8 +278,8 @@ func TestCollector_Collect_ErrorResult(t *testing.T) {
 		metrics = append(metrics, metric)
 	}
 	
-	// Should have 4 metrics: url_up, url_error (gauges) + url_check_total, url_status_code_total (counters)
-	assert.Equal(t, 4, len(metrics))
+	// Should have 5 metrics: url_up, url_last_success_timestamp_seconds, url_error (gauges) + url_check_total, url_status_code_total (counters)
+	assert.Equal(t, 5, len(metrics))
 	
 	// Verify metrics values
 	for _, metric := range metrics {
@@ -320,8 +343,8 @@ func TestCollector_Collect_HTTPErrorResult(t *testing.T) {
 		metrics = append(metrics, metric)
 	}
 	
-	// Should have 6 metrics: url_up, url_error, url_response_time, url_http_status_code + counters
-	assert.Equal(t, 6, len(metrics))
+	// Should have 7 metrics: url_up, url_last_success_timestamp_seconds, url_error, url_response_time, url_http_status_code + counters
+	assert.Equal(t, 7, len(metrics))
 	
 	// Verify metrics values
 	for _, metric := range metrics {
@@ -393,10 +416,10 @@ func TestCollector_Collect_MultipleResults(t *testing.T) {
 		metrics = append(metrics, metric)
 	}
 	
-	// Should have 10 metrics total: 
-	// - example.com: 4 gauges + 2 counters = 6
-	// - test.com: 2 gauges + 2 counters = 4
-	assert.Equal(t, 10, len(metrics))
+	// Should have 12 metrics total: 
+	// - example.com: 5 gauges + 2 counters = 7
+	// - test.com: 3 gauges + 2 counters = 5
+	assert.Equal(t, 12, len(metrics))
 	
 	// Count metrics by URL
 	urlMetrics := make(map[string]int)
@@ -414,8 +437,8 @@ func TestCollector_Collect_MultipleResults(t *testing.T) {
 		}
 	}
 	
-	assert.Equal(t, 6, urlMetrics["https://example.com"]) // Success: 4 gauges + 2 counters
-	assert.Equal(t, 4, urlMetrics["https://test.com"])    // Error: 2 gauges + 2 counters
+	assert.Equal(t, 7, urlMetrics["https://example.com"]) // Success: 5 gauges + 2 counters
+	assert.Equal(t, 5, urlMetrics["https://test.com"])    // Error: 3 gauges + 2 counters
 }
 
 func TestCollector_Register_Success(t *testing.T) {
@@ -440,81 +463,23 @@ func TestCollector_Register_Success(t *testing.T) {
 	assert.NotNil(t, gathered)
 }
 
-func TestCollector_Register_GlobalRegistry(t *testing.T) {
-	cfg := &config.Config{
-		Targets:    []string{"https://example.com"},
-		InstanceID: "test-instance-global",
-	}
-	
-	chk := checker.New(cfg)
-	collector := NewCollector(cfg, chk)
-	
-	// This will use the global registry
-	err := collector.Register()
-	if err != nil {
-		// Registration might fail if collector is already registered in global registry
-		// This is expected in test environments
-		assert.Contains(t, err.Error(), "failed to register collector")
-	}
-}
+func TestCollector_Register_DuplicateRegistration(t *testing.T) {
+	registry := prometheus.NewRegistry()
 
-func TestCollector_Start_ContextCancellation(t *testing.T) {
 	cfg := &config.Config{
 		Targets:    []string{"https://example.com"},
 		InstanceID: "test-instance",
 	}
-	
-	chk := checker.New(cfg)
-	collector := NewCollector(cfg, chk)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-	defer cancel()
-	
-	// Start should return when context is cancelled
-	start := time.Now()
-	collector.Start(ctx)
-	elapsed := time.Since(start)
-	
-	// Should return reasonably quickly after context timeout
-	assert.Less(t, elapsed, 200*time.Millisecond)
-}
 
-func TestCollector_Start_ProcessResults(t *testing.T) {
-	cfg := &config.Config{
-		Targets:    []string{"https://example.com"},
-		InstanceID: "test-instance",
-	}
-	
 	chk := checker.New(cfg)
 	collector := NewCollector(cfg, chk)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-	defer cancel()
-	
-	// Start collector in background
-	go collector.Start(ctx)
-	
-	// Give some time for Start to initialize counters
-	time.Sleep(50 * time.Millisecond)
-	
-	// Verify counters are initialized for configured targets
-	collector.mutex.RLock()
-	_, exists := collector.counters["https://example.com"]
-	collector.mutex.RUnlock()
-	assert.True(t, exists, "Counters should be initialized for configured targets")
-	
-	// Wait for context to timeout
-	<-ctx.Done()
-	
-	// Verify the lastResults map is still accessible (no race conditions)
-	collector.mutex.RLock()
-	resultsCount := len(collector.lastResults)
-	countersCount := len(collector.counters)
-	collector.mutex.RUnlock()
-	
-	// Should be 0 results since we couldn't inject results, but counters should be initialized
-	assert.Equal(t, 0, resultsCount)
-	assert.Equal(t, 1, countersCount)
+
+	require.NoError(t, collector.Register(registry))
+
+	// Registering the same collector twice on one registry must fail
+	err := collector.Register(registry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to register collector")
 }
 
 func TestCollector_ThreadSafety(t *testing.T) {
@@ -829,4 +794,1006 @@ func TestCollector_MultipleURLsWithCounters(t *testing.T) {
 	// Counter metrics: example.com has 2 statuses, test.com has 2, api.com has 2
 	assert.Equal(t, 6, metricCounts["url_check_total"])
 	assert.Equal(t, 6, metricCounts["url_status_code_total"])
-}
\ No newline at end of file
+}
+func TestCollector_Snapshot(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://b.example.com", "https://a.example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	collector.mutex.Lock()
+	collector.lastResults["https://b.example.com"] = &checker.Result{URL: "https://b.example.com", StatusCode: 200}
+	collector.lastResults["https://a.example.com"] = &checker.Result{URL: "https://a.example.com", StatusCode: 500}
+	collector.mutex.Unlock()
+
+	snapshot := collector.Snapshot()
+
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, "https://a.example.com", snapshot[0].URL)
+	assert.Equal(t, "https://b.example.com", snapshot[1].URL)
+
+	// Mutating the snapshot must not affect collector state
+	snapshot[0].StatusCode = 0
+	assert.Equal(t, 500, collector.lastResults["https://a.example.com"].StatusCode)
+}
+
+func TestCollector_ProtocolLabelOnGauges(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"tcp://db.internal:5432"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	collector.mutex.Lock()
+	collector.lastResults["tcp://db.internal:5432"] = &checker.Result{
+		URL:          "tcp://db.internal:5432",
+		Host:         "tcp://db.internal:5432",
+		Path:         "/",
+		Protocol:     "tcp",
+		StatusCode:   200,
+		ResponseTime: 5 * time.Millisecond,
+	}
+	collector.mutex.Unlock()
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	close(ch)
+
+	count := 0
+	for metric := range ch {
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+
+		protocol := ""
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "protocol" {
+				protocol = label.GetValue()
+			}
+		}
+		assert.Equal(t, "tcp", protocol, metric.Desc().String())
+		count++
+	}
+
+	// url_up, url_last_success_timestamp_seconds, url_error, url_response_time_milliseconds, url_http_status_code
+	assert.Equal(t, 5, count)
+}
+
+func TestResultProtocol_FallsBackToURLScheme(t *testing.T) {
+	assert.Equal(t, "redis", resultProtocol(&checker.Result{URL: "redis://localhost:6379"}))
+	assert.Equal(t, "ssh", resultProtocol(&checker.Result{URL: "https://example.com", Protocol: "ssh"}))
+	assert.Equal(t, "unknown", resultProtocol(&checker.Result{URL: "example.com"}))
+}
+
+func TestCollector_AssertionMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://plain.example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	bodyMatch := false
+	headerMatch := true
+
+	collector.mutex.Lock()
+	collector.lastResults["https://example.com"] = &checker.Result{
+		URL:         "https://example.com",
+		Host:        "https://example.com",
+		Path:        "/",
+		Protocol:    "https",
+		StatusCode:  200,
+		BodyMatch:   &bodyMatch,
+		HeaderMatch: &headerMatch,
+	}
+	collector.lastResults["https://plain.example.com"] = &checker.Result{
+		URL:        "https://plain.example.com",
+		Host:       "https://plain.example.com",
+		Path:       "/",
+		Protocol:   "https",
+		StatusCode: 200,
+	}
+	collector.mutex.Unlock()
+
+	ch := make(chan prometheus.Metric, 20)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+
+		var url string
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "url" {
+				url = label.GetValue()
+			}
+		}
+
+		desc := metric.Desc().String()
+		for _, name := range []string{"url_content_match", "url_header_match"} {
+			if strings.Contains(desc, `"`+name+`"`) {
+				if values[name] == nil {
+					values[name] = map[string]float64{}
+				}
+				values[name][url] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"https://example.com": 0}, values["url_content_match"])
+	assert.Equal(t, map[string]float64{"https://example.com": 1}, values["url_header_match"])
+}
+
+func TestCollector_HTTPVersionMetric(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "redis://localhost:6379"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	collector.mutex.Lock()
+	collector.lastResults["https://example.com"] = &checker.Result{
+		URL:         "https://example.com",
+		Host:        "https://example.com",
+		Path:        "/",
+		Protocol:    "https",
+		StatusCode:  200,
+		HTTPVersion: 2,
+	}
+	collector.lastResults["redis://localhost:6379"] = &checker.Result{
+		URL:        "redis://localhost:6379",
+		Host:       "redis://localhost:6379",
+		Path:       "/",
+		Protocol:   "redis",
+		StatusCode: 200,
+	}
+	collector.mutex.Unlock()
+
+	ch := make(chan prometheus.Metric, 20)
+	collector.Collect(ch)
+	close(ch)
+
+	versions := map[string]float64{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_http_version"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "url" {
+				versions[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	// Only HTTP targets report a protocol version
+	assert.Equal(t, map[string]float64{"https://example.com": 2}, versions)
+}
+
+func TestCollector_TLSVersionInfoMetric(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "http://plain.example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:            "https://example.com",
+		Host:           "https://example.com",
+		Path:           "/",
+		Protocol:       "https",
+		StatusCode:     200,
+		Timestamp:      time.Now(),
+		TLSVersion:     "1.1",
+		TLSCipherSuite: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		TLSLegacy:      true,
+		TLSCertExpiry:  time.Unix(1900000000, 0),
+	})
+	collector.Record(checker.Result{
+		URL:        "http://plain.example.com",
+		Host:       "http://plain.example.com",
+		Path:       "/",
+		Protocol:   "http",
+		StatusCode: 200,
+		Timestamp:  time.Now(),
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	var infos, expiries []*dto.Metric
+	for metric := range ch {
+		m := &dto.Metric{}
+		switch {
+		case strings.Contains(metric.Desc().String(), `"url_tls_version_info"`):
+			infos = append(infos, m)
+		case strings.Contains(metric.Desc().String(), `"url_tls_cert_expiry_timestamp_seconds"`):
+			expiries = append(expiries, m)
+		default:
+			continue
+		}
+		require.NoError(t, metric.Write(m))
+	}
+
+	require.Len(t, expiries, 1)
+	assert.Equal(t, 1900000000.0, expiries[0].GetGauge().GetValue())
+
+	// Only the TLS connection reports its version
+	require.Len(t, infos, 1)
+	assert.Equal(t, 1.0, infos[0].GetGauge().GetValue())
+	labels := map[string]string{}
+	for _, label := range infos[0].GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	assert.Equal(t, "https://example.com", labels["url"])
+	assert.Equal(t, "1.1", labels["version"])
+	assert.Equal(t, "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", labels["cipher_suite"])
+	assert.Equal(t, "true", labels["legacy"])
+	assert.Equal(t, "test-instance", labels["instance"])
+}
+
+func TestCollector_SkippedResult(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://api.example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:       "https://api.example.com",
+		Host:      "https://api.example.com",
+		Path:      "/",
+		Protocol:  "https",
+		Timestamp: time.Now(),
+		SkippedBy: "gateway",
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		desc := metric.Desc().String()
+		switch {
+		case strings.Contains(desc, `"url_up"`):
+			values["up"] = m.GetGauge().GetValue()
+		case strings.Contains(desc, `"url_response_time_milliseconds"`):
+			values["response_time"] = m.GetGauge().GetValue()
+		case strings.Contains(desc, `"url_check_total"`):
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "status_code" {
+					values["check_total_"+label.GetValue()] = m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, float64(SkippedValue), values["up"])
+	assert.Equal(t, 1.0, values["check_total_skipped"])
+	assert.NotContains(t, values, "response_time", "skipped checks have no response time")
+}
+
+func TestCollector_SecurityHeaderMetric(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:        "https://example.com",
+		Host:       "https://example.com",
+		Path:       "/",
+		Protocol:   "https",
+		StatusCode: 200,
+		Timestamp:  time.Now(),
+		SecurityHeaders: map[string]bool{
+			"Strict-Transport-Security": true,
+			"X-Frame-Options":           false,
+		},
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	present := map[string]float64{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_security_header_present"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "header" {
+				present[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"strict-transport-security": 1, "x-frame-options": 0}, present)
+}
+
+func TestCollector_CacheMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://cdn.example.com"},
+		InstanceID: "test-instance",
+	}
+
+	hit := true
+	age, maxAge := 90*time.Second, time.Hour
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:        "https://example.com",
+		Host:       "https://example.com",
+		Path:       "/",
+		Protocol:   "https",
+		StatusCode: 200,
+		Timestamp:  time.Now(),
+	})
+	collector.Record(checker.Result{
+		URL:        "https://cdn.example.com",
+		Host:       "https://cdn.example.com",
+		Path:       "/",
+		Protocol:   "https",
+		StatusCode: 200,
+		Timestamp:  time.Now(),
+		Cache:      &checker.CacheStatus{Hit: &hit, Age: &age, MaxAge: &maxAge},
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		var name string
+		switch desc := metric.Desc().String(); {
+		case strings.Contains(desc, `"url_http_cache_hit"`):
+			name = "hit"
+		case strings.Contains(desc, `"url_http_cache_age_seconds"`):
+			name = "age"
+		case strings.Contains(desc, `"url_http_cache_max_age_seconds"`):
+			name = "max_age"
+		default:
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "url" {
+				values[name+" "+label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	// Only the response with cache headers exports them
+	assert.Equal(t, map[string]float64{
+		"hit https://cdn.example.com":     1,
+		"age https://cdn.example.com":     90,
+		"max_age https://cdn.example.com": 3600,
+	}, values)
+}
+
+func TestCollector_ClockSkewMetric(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	skew := -90 * time.Second
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:        "https://example.com",
+		Host:       "https://example.com",
+		Path:       "/",
+		Protocol:   "https",
+		StatusCode: 200,
+		Timestamp:  time.Now(),
+		ClockSkew:  &skew,
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	var skews []float64
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_server_clock_skew_seconds"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		skews = append(skews, m.GetGauge().GetValue())
+	}
+
+	assert.Equal(t, []float64{-90}, skews)
+}
+
+func TestCollector_SizeWithinBoundsMetric(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://blank.example.com"},
+		InstanceID: "test-instance",
+	}
+
+	within, outside := true, false
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:              "https://example.com",
+		Host:             "https://example.com",
+		Path:             "/",
+		Protocol:         "https",
+		StatusCode:       200,
+		Timestamp:        time.Now(),
+		SizeWithinBounds: &within,
+	})
+	// The size failed the check, it is still reported
+	collector.Record(checker.Result{
+		URL:              "https://blank.example.com",
+		Host:             "https://blank.example.com",
+		Path:             "/",
+		Protocol:         "https",
+		StatusCode:       200,
+		Timestamp:        time.Now(),
+		Failure:          "response body of 0 bytes is below minBodyBytes 100",
+		SizeWithinBounds: &outside,
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_size_within_bounds"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "url" {
+				values[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"https://example.com": 1, "https://blank.example.com": 0}, values)
+}
+
+func TestCollector_BodySizeFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets:    []string{server.URL},
+		InstanceID: "test-instance",
+		Timeout:    time.Second,
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	result, err := chk.CheckTarget(context.Background(), config.Target{URL: server.URL, MinBodyBytes: 100, FailOnBodySize: true})
+	require.NoError(t, err)
+	collector.Record(result)
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, name := range []string{"url_up", "url_error", "url_http_status_code", "url_size_within_bounds"} {
+			if strings.Contains(metric.Desc().String(), `"`+name+`"`) {
+				values[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	// The request succeeded, so the failed size is down without being an error
+	assert.Equal(t, map[string]float64{
+		"url_up":                 0,
+		"url_error":              0,
+		"url_http_status_code":   200,
+		"url_size_within_bounds": 0,
+	}, values)
+}
+
+func TestCollector_StepMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:       "https://example.com",
+		Host:      "https://example.com",
+		Path:      "/",
+		Protocol:  "https",
+		Error:     errors.New("step fetch: body does not match expectBody"),
+		Timestamp: time.Now(),
+		Steps: []checker.StepResult{
+			{Name: "login", StatusCode: 200, ResponseTime: 120 * time.Millisecond},
+			{Name: "fetch", StatusCode: 200, ResponseTime: 80 * time.Millisecond, Error: errors.New("body does not match expectBody")},
+		},
+	})
+
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	durations := map[string]float64{}
+	success := map[string]float64{}
+	for metric := range ch {
+		desc := metric.Desc().String()
+		if !strings.Contains(desc, `"url_step_`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() != "step" {
+				continue
+			}
+			if strings.Contains(desc, `"url_step_duration_milliseconds"`) {
+				durations[label.GetValue()] = m.GetGauge().GetValue()
+			} else {
+				success[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"login": 120, "fetch": 80}, durations)
+	assert.Equal(t, map[string]float64{"login": 1, "fetch": 0}, success)
+}
+
+func TestCollector_SLOMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://example.com", Objective: 0.99},
+		},
+		Targets:    []string{"https://no-slo.example.com"},
+		InstanceID: "test-instance",
+		SLO: config.SLOConfig{
+			Windows: []time.Duration{time.Hour},
+			Period:  30 * 24 * time.Hour,
+		},
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	require.Contains(t, collector.slo, "https://example.com")
+	require.NotContains(t, collector.slo, "https://no-slo.example.com")
+
+	now := time.Now()
+	collector.mutex.Lock()
+	for i := 0; i < 10; i++ {
+		collector.slo["https://example.com"].record(now.Add(-time.Duration(i)*time.Minute), i != 0)
+	}
+	collector.lastResults["https://example.com"] = &checker.Result{
+		URL: "https://example.com", Host: "https://example.com", Path: "/", Protocol: "https", StatusCode: 500,
+	}
+	collector.lastResults["https://no-slo.example.com"] = &checker.Result{
+		URL: "https://no-slo.example.com", Host: "https://no-slo.example.com", Path: "/", Protocol: "https", StatusCode: 200,
+	}
+	collector.mutex.Unlock()
+
+	ch := make(chan prometheus.Metric, 30)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		desc := metric.Desc().String()
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+
+		for _, name := range []string{"url_slo_objective", "url_slo_burn_rate", "url_slo_error_budget_consumed_ratio"} {
+			if strings.Contains(desc, `"`+name+`"`) {
+				for _, label := range m.GetLabel() {
+					if label.GetName() == "url" {
+						assert.Equal(t, "https://example.com", label.GetValue())
+					}
+					if label.GetName() == "window" {
+						assert.Equal(t, "1h", label.GetValue())
+					}
+				}
+				values[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	require.Len(t, values, 3)
+	assert.InDelta(t, 0.99, values["url_slo_objective"], 1e-9)
+	// 1 failure out of 10 checks against a 1% budget burns at 10x
+	assert.InDelta(t, 10.0, values["url_slo_burn_rate"], 1e-9)
+	assert.InDelta(t, 10.0/720.0, values["url_slo_error_budget_consumed_ratio"], 1e-9)
+}
+
+func TestCollector_LastSuccessTimestamp(t *testing.T) {
+	cfg := &config.Config{
+		Targets:       []string{"https://example.com", "https://never.example.com"},
+		InstanceID:    "test-instance",
+		CheckInterval: time.Hour,
+		Timeout:       time.Second,
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	succeededAt := time.Unix(1700000000, 0)
+
+	collector.mutex.Lock()
+	collector.lastSuccess["https://example.com"] = succeededAt
+	collector.lastResults["https://example.com"] = &checker.Result{
+		URL: "https://example.com", Host: "https://example.com", Path: "/", Protocol: "https",
+		Error: errors.New("connection refused"),
+	}
+	collector.lastResults["https://never.example.com"] = &checker.Result{
+		URL: "https://never.example.com", Host: "https://never.example.com", Path: "/", Protocol: "https",
+		StatusCode: 503,
+	}
+	collector.mutex.Unlock()
+
+	ch := make(chan prometheus.Metric, 20)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_last_success_timestamp_seconds"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "url" {
+				values[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	// The last success is kept even though the latest check errored
+	assert.Equal(t, float64(1700000000), values["https://example.com"])
+	assert.Equal(t, float64(0), values["https://never.example.com"])
+}
+
+func TestCollector_RecordsCheckerResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Targets:       []string{server.URL},
+		InstanceID:    "test-instance",
+		CheckInterval: time.Hour,
+		Timeout:       time.Second,
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	chk.AddSink(collector)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go chk.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		collector.mutex.RLock()
+		defer collector.mutex.RUnlock()
+		_, exists := collector.lastSuccess[server.URL]
+		return exists
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestCollector_Record(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	now := time.Now()
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://example.com", Error: errors.New("timeout"), Timestamp: now.Add(time.Second)})
+
+	collector.mutex.RLock()
+	defer collector.mutex.RUnlock()
+
+	assert.Equal(t, map[string]int{"200": 2, "error": 1}, collector.counters["https://example.com"])
+	assert.Error(t, collector.lastResults["https://example.com"].Error)
+	assert.Equal(t, now, collector.lastSuccess["https://example.com"])
+}
+
+func TestCollector_DisabledFamilies(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+		Metrics: config.MetricsConfig{
+			Disabled: []string{"url_check_total", "url_status_code_total", "url_unknown"},
+		},
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	collector.Record(checker.Result{
+		URL:          "https://example.com",
+		Host:         "https://example.com",
+		Path:         "/",
+		StatusCode:   200,
+		ResponseTime: 100 * time.Millisecond,
+		Timestamp:    time.Now(),
+	})
+
+	descCh := make(chan *prometheus.Desc, 26)
+	collector.Describe(descCh)
+	close(descCh)
+
+	var descriptors []*prometheus.Desc
+	for desc := range descCh {
+		descriptors = append(descriptors, desc)
+	}
+	assert.Len(t, descriptors, 24)
+	assert.NotContains(t, descriptors, collector.urlCheckTotal)
+	assert.NotContains(t, descriptors, collector.urlStatusCodeTotal)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, collector.Register(registry))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "url_up")
+	assert.NotContains(t, names, "url_check_total")
+	assert.NotContains(t, names, "url_status_code_total")
+}
+
+func TestCollector_Counters(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: time.Now()})
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 503, Timestamp: time.Now()})
+
+	counters := collector.Counters()
+	assert.Equal(t, map[string]int{"200": 1, "503": 1}, counters["https://example.com"])
+
+	// The returned map is a copy
+	counters["https://example.com"]["200"] = 10
+	assert.Equal(t, 1, collector.Counters()["https://example.com"]["200"])
+
+	collector.Record(checker.Result{URL: "https://example.com", Error: errors.New("timeout"), Timestamp: time.Now()})
+	total, successful := collector.CheckStats("https://example.com")
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 1, successful)
+}
+
+func TestCollector_AddRemoveTarget(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+		SLO:        config.SLOConfig{Windows: []time.Duration{time.Hour}, Period: 24 * time.Hour},
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	collector.AddTarget(config.Target{URL: "https://new.example.com", Objective: 0.99})
+	collector.Record(checker.Result{URL: "https://new.example.com", StatusCode: 200, Timestamp: time.Now()})
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: time.Now()})
+
+	collector.mutex.RLock()
+	assert.Contains(t, collector.slo, "https://new.example.com")
+	collector.mutex.RUnlock()
+
+	collector.RemoveTarget("https://new.example.com")
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, collector.Register(registry))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "url" {
+					assert.NotEqual(t, "https://new.example.com", label.GetValue(), family.GetName())
+				}
+			}
+		}
+	}
+
+	assert.Len(t, collector.Snapshot(), 1)
+	assert.NotContains(t, collector.Counters(), "https://new.example.com")
+}
+
+func TestCollector_TargetLabels(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+		SLO:        config.SLOConfig{Windows: []time.Duration{time.Hour}, Period: 24 * time.Hour},
+	}
+
+	collector := NewCollector(cfg, checker.New(cfg))
+
+	result := checker.Result{URL: "https://example.com/health", Host: "example.com", Path: "/health", Protocol: "https", StatusCode: 200, Timestamp: time.Now()}
+	collector.Record(result)
+	labels := collector.labels[result.URL]
+	require.NotNil(t, labels)
+	assert.Equal(t, []string{result.URL, "example.com", "/health", "https", "test-instance"}, labels.values)
+
+	pairs := func(labels []*dto.LabelPair) map[string]string {
+		values := make(map[string]string, len(labels))
+		for i, label := range labels {
+			if i > 0 {
+				assert.Less(t, labels[i-1].GetName(), label.GetName(), "label pairs are sorted by name")
+			}
+			values[label.GetName()] = label.GetValue()
+		}
+		return values
+	}
+	assert.Equal(t, map[string]string{"url": result.URL, "host": "example.com", "path": "/health", "protocol": "https", "instance": "test-instance"}, pairs(labels.base))
+	require.Len(t, labels.windows, 1)
+	assert.Equal(t, "1h", pairs(labels.windows[0])["window"])
+	assert.Equal(t, "200", pairs(labels.statusLabels("200"))["status_code"])
+	assert.Equal(t, "404", pairs(labels.statusLabels("404"))["status_code"], "labels of unseen status codes are built on demand")
+
+	collector.Record(result)
+	assert.Same(t, labels, collector.labels[result.URL], "labels are reused while they do not change")
+
+	result.Protocol = "http"
+	collector.Record(result)
+	assert.Equal(t, "http", collector.labels[result.URL].values[3], "labels are rebuilt when the protocol changes")
+
+	collector.RemoveTarget(result.URL)
+	assert.NotContains(t, collector.labels, result.URL)
+}
+
+// benchmarkCollector returns a collector holding results for the given number of targets
+func benchmarkCollector(b *testing.B, targets int) *Collector {
+	b.Helper()
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "bench-instance",
+		SLO:        config.SLOConfig{Windows: []time.Duration{time.Hour, 24 * time.Hour}, Period: 30 * 24 * time.Hour},
+	}
+	collector := NewCollector(cfg, checker.New(cfg))
+
+	now := time.Now()
+	for i := 0; i < targets; i++ {
+		url := fmt.Sprintf("https://host-%d.example.com/health", i)
+		collector.AddTarget(config.Target{URL: url, Objective: 0.999})
+		collector.Record(checker.Result{URL: url, Host: fmt.Sprintf("host-%d.example.com", i), Path: "/health", Protocol: "https", StatusCode: 200, ResponseTime: 20 * time.Millisecond, HTTPVersion: 2, Timestamp: now})
+		collector.Record(checker.Result{URL: url, Host: fmt.Sprintf("host-%d.example.com", i), Path: "/health", Protocol: "https", StatusCode: 503, ResponseTime: 20 * time.Millisecond, HTTPVersion: 2, Timestamp: now})
+	}
+	return collector
+}
+
+func BenchmarkCollector_Collect(b *testing.B) {
+	collector := benchmarkCollector(b, 5000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ch := make(chan prometheus.Metric, 1024)
+		done := make(chan struct{})
+		go func() {
+			for range ch {
+			}
+			close(done)
+		}()
+		collector.Collect(ch)
+		close(ch)
+		<-done
+	}
+}
+
+func BenchmarkCollector_Record(b *testing.B) {
+	collector := benchmarkCollector(b, 5000)
+	result := checker.Result{URL: "https://host-1.example.com/health", Host: "host-1.example.com", Path: "/health", Protocol: "https", StatusCode: 200, Timestamp: time.Now()}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collector.Record(result)
+	}
+}
+
+func TestCollector_RemovalGrace(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+		Metrics:    config.MetricsConfig{RemovalGrace: time.Minute},
+	}
+
+	collector := NewCollector(cfg, checker.New(cfg))
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: time.Now()})
+	collector.Record(checker.Result{URL: "https://other.example.com", StatusCode: 200, Timestamp: time.Now()})
+
+	collector.RemoveTarget("https://example.com")
+	collector.RemoveTarget("https://other.example.com")
+
+	// Within the grace period the series are still exported
+	assert.Len(t, collector.Snapshot(), 2)
+
+	// A target added back keeps its state
+	collector.AddTarget(config.Target{URL: "https://other.example.com"})
+
+	collector.evictRemoved(time.Now().Add(time.Minute))
+	snapshot := collector.Snapshot()
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, "https://other.example.com", snapshot[0].URL)
+	assert.NotContains(t, collector.Counters(), "https://example.com")
+	assert.Equal(t, 1, collector.Counters()["https://other.example.com"]["200"])
+}
+
+func TestCollector_Collect_RedactsCredentials(t *testing.T) {
+	cfg := &config.Config{InstanceID: "test-instance"}
+	collector := NewCollector(cfg, checker.New(cfg))
+
+	collector.Record(checker.Result{
+		URL:        "redis://:s3cret@cache:6379/?token=t0k3n",
+		Host:       "redis://cache:6379",
+		Path:       "/?token=xxxxx",
+		Protocol:   "redis",
+		StatusCode: 200,
+		Timestamp:  time.Now(),
+	})
+
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+
+	for metric := range ch {
+		out := &dto.Metric{}
+		require.NoError(t, metric.Write(out))
+		for _, label := range out.GetLabel() {
+			assert.NotContains(t, label.GetValue(), "s3cret")
+			assert.NotContains(t, label.GetValue(), "t0k3n")
+			if label.GetName() == "url" {
+				assert.Equal(t, "redis://:xxxxx@cache:6379/?token=xxxxx", label.GetValue())
+			}
+		}
+	}
+}
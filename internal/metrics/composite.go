@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// compositeRollup is a configured composite with the labels of its series
+type compositeRollup struct {
+	config.Composite
+	labels []*dto.LabelPair // pairs of compositeLabelNames
+}
+
+func newCompositeRollups(cfg *config.Config) []compositeRollup {
+	rollups := make([]compositeRollup, 0, len(cfg.Composites))
+	for _, composite := range cfg.Composites {
+		if composite.Mode == "" {
+			composite.Mode = config.CompositeAll
+		}
+		rollups = append(rollups, compositeRollup{
+			Composite: composite,
+			labels:    labelPairs(compositeLabelNames, []string{composite.Name, composite.Mode, cfg.InstanceID}),
+		})
+	}
+	return rollups
+}
+
+// collectComposites emits the availability of every composite with a checked member.
+// Members are resolved against the current targets, so targets added by a reload or at
+// runtime count once they exist. Members without a result, e.g. disabled or checked by
+// another shard, are left out, and so are skipped members: the dependency that is down
+// already counts where it is a member. The caller must hold the read lock.
+func (c *Collector) collectComposites(ch chan<- prometheus.Metric) {
+	for _, rollup := range c.composites {
+		up, checked := 0, 0
+		for _, member := range rollup.Members {
+			target, exists := c.checker.Lookup(member)
+			if !exists {
+				continue
+			}
+			result, exists := c.lastResults[target.URL]
+			if !exists || result.IsSkipped() {
+				continue
+			}
+			checked++
+			if result.IsUp() {
+				up++
+			}
+		}
+		if checked == 0 {
+			continue
+		}
+
+		c.send(
+			ch,
+			c.urlCompositeUp,
+			prometheus.GaugeValue,
+			boolToFloat(rollup.Up(up, checked)),
+			rollup.labels,
+		)
+	}
+}
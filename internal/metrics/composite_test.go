@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector_CompositeUp(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://eu.example.com", Name: "eu"},
+			{URL: "https://us.example.com", Name: "us"},
+			{URL: "https://ap.example.com", Name: "ap"},
+			{URL: "https://sa.example.com", Name: "sa"},
+		},
+		Composites: []config.Composite{
+			{Name: "all-regions", Members: []string{"eu", "us", "ap"}},
+			{Name: "any-region", Mode: config.CompositeAny, Members: []string{"eu", "us", "ap"}},
+			{Name: "majority", Mode: config.CompositeQuorum, Quorum: 2, Members: []string{"eu", "us", "https://ap.example.com"}},
+			{Name: "unchecked", Members: []string{"sa"}},
+			{Name: "pair", Members: []string{"eu", "us"}},
+			{Name: "later", Members: []string{"na"}},
+		},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	now := time.Now()
+	collector.Record(checker.Result{URL: "https://eu.example.com", StatusCode: 200, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://us.example.com", StatusCode: 200, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://ap.example.com", StatusCode: 503, Timestamp: now})
+
+	assert.Equal(t, map[string]float64{"all-regions/all": 0, "any-region/any": 1, "majority/quorum": 1, "pair/all": 1}, compositeValues(t, collector))
+
+	collector.Record(checker.Result{URL: "https://us.example.com", SkippedBy: "eu", Timestamp: now})
+	assert.Equal(t, map[string]float64{"all-regions/all": 0, "any-region/any": 1, "majority/quorum": 0, "pair/all": 1}, compositeValues(t, collector),
+		"skipped members are neither up nor down")
+
+	// Members added after the collector was created count once they are checked
+	require.NoError(t, chk.AddTarget(config.Target{URL: "https://na.example.com", Name: "na"}))
+	collector.Record(checker.Result{URL: "https://na.example.com", StatusCode: 503, Timestamp: now})
+	assert.Equal(t, float64(0), compositeValues(t, collector)["later/all"])
+}
+
+// compositeValues returns the url_composite_up values by composite/mode
+func compositeValues(t *testing.T, collector *Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 40)
+	collector.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_composite_up"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		labels := map[string]string{}
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		assert.Equal(t, "test-instance", labels["instance"])
+		values[labels["composite"]+"/"+labels["mode"]] = m.GetGauge().GetValue()
+	}
+	return values
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import "time"
+
+// DailyHistoryDays is the number of days of daily check counts kept per target
+const DailyHistoryDays = 90
+
+// DailyUptime counts the checks of a target during a UTC day
+type DailyUptime struct {
+	Day        time.Time `json:"day"`
+	Checks     int       `json:"checks"`
+	Successful int       `json:"successful"`
+}
+
+// Uptime returns the percentage of successful checks of the day, 0 without checks
+func (d DailyUptime) Uptime() float64 {
+	if d.Checks == 0 {
+		return 0
+	}
+	return float64(d.Successful) / float64(d.Checks) * 100
+}
+
+// day returns the UTC day of the timestamp
+func day(timestamp time.Time) time.Time {
+	return timestamp.UTC().Truncate(24 * time.Hour)
+}
+
+// appendDaily counts a check in the daily history, dropping days older than
+// DailyHistoryDays. Checks of days before the last recorded one are ignored.
+func appendDaily(days []DailyUptime, timestamp time.Time, up bool) []DailyUptime {
+	today := day(timestamp)
+
+	n := len(days)
+	switch {
+	case n > 0 && days[n-1].Day.Equal(today):
+	case n > 0 && today.Before(days[n-1].Day):
+		return days
+	default:
+		days = append(days, DailyUptime{Day: today})
+		n++
+	}
+
+	days[n-1].Checks++
+	if up {
+		days[n-1].Successful++
+	}
+
+	oldest := today.AddDate(0, 0, -(DailyHistoryDays - 1))
+	start := 0
+	for start < len(days) && days[start].Day.Before(oldest) {
+		start++
+	}
+	return days[start:]
+}
+
+// DailySeries returns the counts of the last days up to and including the day of now,
+// oldest first, with empty entries for days without checks
+func DailySeries(recorded []DailyUptime, days int, now time.Time) []DailyUptime {
+	today := day(now)
+
+	byDay := make(map[time.Time]DailyUptime, len(recorded))
+	for _, entry := range recorded {
+		byDay[entry.Day.UTC()] = entry
+	}
+
+	series := make([]DailyUptime, days)
+	for i := range series {
+		d := today.AddDate(0, 0, i-days+1)
+		series[i] = byDay[d]
+		series[i].Day = d
+	}
+	return series
+}
+
+// DailyUptime returns the daily check counts of the target for the last days, see
+// DailySeries
+func (c *Collector) DailyUptime(url string, days int, now time.Time) []DailyUptime {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return DailySeries(c.daily[url], days, now)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendDaily(t *testing.T) {
+	start := time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC)
+
+	var days []DailyUptime
+	days = appendDaily(days, start, true)
+	days = appendDaily(days, start.Add(30*time.Second), false)
+	days = appendDaily(days, start.Add(2*time.Minute), true)
+	// Late results of a day already passed are ignored
+	days = appendDaily(days, start, true)
+
+	require.Len(t, days, 2)
+	assert.Equal(t, DailyUptime{Day: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Checks: 2, Successful: 1}, days[0])
+	assert.Equal(t, DailyUptime{Day: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Checks: 1, Successful: 1}, days[1])
+	assert.Equal(t, 50.0, days[0].Uptime())
+	assert.Zero(t, DailyUptime{}.Uptime())
+
+	// Days beyond DailyHistoryDays are dropped
+	days = appendDaily(days, start.AddDate(0, 0, DailyHistoryDays), true)
+	require.Len(t, days, 2)
+	assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), days[0].Day)
+}
+
+func TestDailySeries(t *testing.T) {
+	recorded := []DailyUptime{
+		{Day: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Checks: 10, Successful: 9},
+		{Day: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), Checks: 4, Successful: 4},
+	}
+
+	series := DailySeries(recorded, 4, time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, []DailyUptime{
+		{Day: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{Day: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Checks: 10, Successful: 9},
+		{Day: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Day: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), Checks: 4, Successful: 4},
+	}, series)
+}
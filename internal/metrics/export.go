@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats supported by WriteHistoryReport
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// HistoryReport is an availability report of the recorded check history of targets
+type HistoryReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Since       *time.Time     `json:"since,omitempty"`
+	Targets     []TargetReport `json:"targets"`
+}
+
+// TargetReport summarizes the recorded checks of a target. Availability is the percentage
+// of successful checks, From and To the times of the first and last one.
+type TargetReport struct {
+	URL          string         `json:"url"`
+	Name         string         `json:"name,omitempty"`
+	Checks       int            `json:"checks"`
+	Successful   int            `json:"successful"`
+	Availability float64        `json:"availability_percent"`
+	From         *time.Time     `json:"from,omitempty"`
+	To           *time.Time     `json:"to,omitempty"`
+	Results      []ReportResult `json:"results"`
+}
+
+// ReportResult is a single check in a report
+type ReportResult struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Up             bool      `json:"up"`
+	StatusCode     int       `json:"status_code,omitempty"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// NewTargetReport summarizes the history points of a target
+func NewTargetReport(url, name string, points []HistoryPoint) TargetReport {
+	report := TargetReport{
+		URL:     url,
+		Name:    name,
+		Checks:  len(points),
+		Results: make([]ReportResult, 0, len(points)),
+	}
+
+	for _, point := range points {
+		if point.Up {
+			report.Successful++
+		}
+		report.Results = append(report.Results, ReportResult{
+			Timestamp:      point.Timestamp,
+			Up:             point.Up,
+			StatusCode:     point.StatusCode,
+			ResponseTimeMs: point.ResponseTime.Milliseconds(),
+			Error:          point.Error,
+		})
+	}
+
+	if len(points) > 0 {
+		report.Availability = float64(report.Successful) / float64(report.Checks) * 100
+		from, to := points[0].Timestamp, points[len(points)-1].Timestamp
+		report.From, report.To = &from, &to
+	}
+
+	return report
+}
+
+// WriteHistoryReport writes the report as CSV, one row per check, or as JSON
+func WriteHistoryReport(w io.Writer, format string, report HistoryReport) error {
+	switch format {
+	case ExportFormatCSV:
+		return writeReportCSV(w, report)
+	case ExportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	default:
+		return fmt.Errorf("unsupported export format %q: must be csv or json", format)
+	}
+}
+
+func writeReportCSV(w io.Writer, report HistoryReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"url", "name", "timestamp", "up", "status_code", "response_time_ms", "error"}); err != nil {
+		return err
+	}
+
+	for _, target := range report.Targets {
+		for _, result := range target.Results {
+			statusCode := ""
+			if result.StatusCode != 0 {
+				statusCode = strconv.Itoa(result.StatusCode)
+			}
+
+			record := []string{
+				target.URL,
+				target.Name,
+				result.Timestamp.UTC().Format(time.RFC3339),
+				strconv.FormatBool(result.Up),
+				statusCode,
+				strconv.FormatInt(result.ResponseTimeMs, 10),
+				result.Error,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
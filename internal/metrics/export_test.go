@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHistoryReport(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	points := []HistoryPoint{
+		{Timestamp: start, ResponseTime: 42 * time.Millisecond, Up: true, StatusCode: 200},
+		{Timestamp: start.Add(time.Minute), ResponseTime: 3 * time.Millisecond, Error: "connection refused, reset"},
+		{Timestamp: start.Add(2 * time.Minute), ResponseTime: 40 * time.Millisecond, Up: true, StatusCode: 200},
+		{Timestamp: start.Add(3 * time.Minute), ResponseTime: 41 * time.Millisecond, Up: true, StatusCode: 204},
+	}
+
+	target := NewTargetReport("https://example.com", "web", points)
+	assert.Equal(t, 4, target.Checks)
+	assert.Equal(t, 3, target.Successful)
+	assert.Equal(t, 75.0, target.Availability)
+	assert.Equal(t, start, *target.From)
+	assert.Equal(t, start.Add(3*time.Minute), *target.To)
+
+	empty := NewTargetReport("https://empty.example.com", "", nil)
+	assert.Zero(t, empty.Availability)
+	assert.Nil(t, empty.From)
+
+	report := HistoryReport{GeneratedAt: start.Add(time.Hour), Targets: []TargetReport{target, empty}}
+
+	var csv bytes.Buffer
+	require.NoError(t, WriteHistoryReport(&csv, ExportFormatCSV, report))
+	assert.Equal(t, `url,name,timestamp,up,status_code,response_time_ms,error
+https://example.com,web,2026-01-02T03:04:05Z,true,200,42,
+https://example.com,web,2026-01-02T03:05:05Z,false,,3,"connection refused, reset"
+https://example.com,web,2026-01-02T03:06:05Z,true,200,40,
+https://example.com,web,2026-01-02T03:07:05Z,true,204,41,
+`, csv.String())
+
+	var encoded bytes.Buffer
+	require.NoError(t, WriteHistoryReport(&encoded, ExportFormatJSON, report))
+	var decoded HistoryReport
+	require.NoError(t, json.Unmarshal(encoded.Bytes(), &decoded))
+	require.Len(t, decoded.Targets, 2)
+	assert.Equal(t, 75.0, decoded.Targets[0].Availability)
+	assert.Equal(t, int64(42), decoded.Targets[0].Results[0].ResponseTimeMs)
+	assert.Empty(t, decoded.Targets[1].Results)
+
+	assert.Error(t, WriteHistoryReport(&encoded, "xml", report))
+}
+
+func TestHistorySince(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	points := []HistoryPoint{{Timestamp: start}, {Timestamp: start.Add(time.Minute)}, {Timestamp: start.Add(2 * time.Minute)}}
+
+	assert.Len(t, HistorySince(points, time.Time{}), 3)
+	assert.Equal(t, points[1:], HistorySince(points, start.Add(time.Minute)))
+	assert.Empty(t, HistorySince(points, start.Add(time.Hour)))
+}
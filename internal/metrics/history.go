@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// HistoryPoint is the outcome of a single check kept in a target's recent history
+type HistoryPoint struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	ResponseTime time.Duration `json:"response_time"`
+	Up           bool          `json:"up"`
+	StatusCode   int           `json:"status_code,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// SavedHistory is the on-disk format of the saved history: the recent check outcomes, the
+// daily check counts and the incidents of each target URL
+type SavedHistory struct {
+	Targets   map[string][]HistoryPoint `json:"targets"`
+	Daily     map[string][]DailyUptime  `json:"daily,omitempty"`
+	Incidents map[string][]Incident     `json:"incidents,omitempty"`
+}
+
+// appendHistory adds the point to the history, dropping the oldest entries beyond size
+func appendHistory(history []HistoryPoint, point HistoryPoint, size int) []HistoryPoint {
+	history = append(history, point)
+	if len(history) > size {
+		history = history[len(history)-size:]
+	}
+	return history
+}
+
+// historySize is the number of points kept per target
+func (c *Collector) historySize() int {
+	if c.config.History.Size > 0 {
+		return c.config.History.Size
+	}
+	return config.DefaultHistorySize
+}
+
+// History returns a copy of the last limit check outcomes of every target, oldest first.
+// A limit of zero returns the whole history.
+func (c *Collector) History(limit int) map[string][]HistoryPoint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	history := make(map[string][]HistoryPoint, len(c.history))
+	for url, points := range c.history {
+		if limit > 0 && len(points) > limit {
+			points = points[len(points)-limit:]
+		}
+		history[url] = append([]HistoryPoint(nil), points...)
+	}
+
+	return history
+}
+
+// TargetHistory returns a copy of the check outcomes of the target recorded at or after
+// since, oldest first
+func (c *Collector) TargetHistory(url string, since time.Time) []HistoryPoint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]HistoryPoint{}, HistorySince(c.history[url], since)...)
+}
+
+// HistorySince returns the tail of the points, oldest first, recorded at or after since
+func HistorySince(points []HistoryPoint, since time.Time) []HistoryPoint {
+	start := len(points)
+	for start > 0 && !points[start-1].Timestamp.Before(since) {
+		start--
+	}
+	return points[start:]
+}
+
+// SaveHistory atomically writes the history of every target to the file
+func (c *Collector) SaveHistory(path string) error {
+	c.mutex.RLock()
+	content, err := json.Marshal(SavedHistory{Targets: c.history, Daily: c.daily, Incidents: c.incidents})
+	c.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	return config.WriteFileAtomic(path, content)
+}
+
+// ReadHistoryFile returns the history saved by SaveHistory
+func ReadHistoryFile(path string) (*SavedHistory, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %w", path, err)
+	}
+
+	var saved SavedHistory
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse history file %s: %w", path, err)
+	}
+
+	return &saved, nil
+}
+
+// LoadHistory restores the history saved by SaveHistory for the registered targets. A
+// missing file is not an error.
+func (c *Collector) LoadHistory(path string) error {
+	saved, err := ReadHistoryFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	size := c.historySize()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, target := range c.checker.Targets() {
+		points := saved.Targets[target.URL]
+		if len(points) > size {
+			points = points[len(points)-size:]
+		}
+		if len(points) > 0 {
+			c.history[target.URL] = points
+		}
+		if days := saved.Daily[target.URL]; len(days) > 0 {
+			c.daily[target.URL] = days
+		}
+		if incidents := saved.Incidents[target.URL]; len(incidents) > 0 {
+			c.incidents[target.URL] = incidents
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendHistory_KeepsMostRecent(t *testing.T) {
+	const size = 60
+
+	var history []HistoryPoint
+	for i := 0; i < size+5; i++ {
+		history = appendHistory(history, HistoryPoint{ResponseTime: time.Duration(i) * time.Millisecond}, size)
+	}
+
+	require.Len(t, history, size)
+	assert.Equal(t, 5*time.Millisecond, history[0].ResponseTime)
+	assert.Equal(t, time.Duration(size+4)*time.Millisecond, history[size-1].ResponseTime)
+}
+
+func TestCollector_History(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+
+	now := time.Now()
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, ResponseTime: 20 * time.Millisecond, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://example.com", Error: errors.New("timeout"), ResponseTime: time.Second, Timestamp: now.Add(time.Second)})
+
+	history := collector.History(0)
+	assert.Equal(t, []HistoryPoint{
+		{Timestamp: now, ResponseTime: 20 * time.Millisecond, Up: true, StatusCode: 200},
+		{Timestamp: now.Add(time.Second), ResponseTime: time.Second, Up: false, Error: "timeout"},
+	}, history["https://example.com"])
+
+	// The returned history is a copy
+	history["https://example.com"][0].Up = false
+	assert.True(t, collector.History(0)["https://example.com"][0].Up)
+
+	assert.Equal(t, []HistoryPoint{
+		{Timestamp: now.Add(time.Second), ResponseTime: time.Second, Up: false, Error: "timeout"},
+	}, collector.History(1)["https://example.com"])
+
+	assert.Len(t, collector.TargetHistory("https://example.com", now), 2)
+	assert.Len(t, collector.TargetHistory("https://example.com", now.Add(time.Millisecond)), 1)
+	assert.Empty(t, collector.TargetHistory("https://example.com", now.Add(time.Minute)))
+	assert.Empty(t, collector.TargetHistory("https://unknown.example.com", time.Time{}))
+
+	collector.RemoveTarget("https://example.com")
+	assert.NotContains(t, collector.History(0), "https://example.com")
+}
+
+func TestCollector_SaveAndLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://other.example.com"},
+		InstanceID: "test-instance",
+		History:    config.HistoryConfig{Size: 2},
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	collector := NewCollector(cfg, checker.New(cfg))
+	for i := 0; i < 3; i++ {
+		collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, ResponseTime: time.Duration(i) * time.Millisecond, Timestamp: now.Add(time.Duration(i) * time.Second)})
+	}
+	collector.Record(checker.Result{URL: "https://other.example.com", Error: errors.New("refused"), Timestamp: now})
+	require.NoError(t, collector.SaveHistory(path))
+
+	// Only the history of targets that are still registered is restored
+	cfg = &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+		History:    config.HistoryConfig{Size: 1},
+	}
+	restored := NewCollector(cfg, checker.New(cfg))
+	require.NoError(t, restored.LoadHistory(path))
+
+	history := restored.History(0)
+	assert.Equal(t, map[string][]HistoryPoint{
+		"https://example.com": {{Timestamp: now.Add(2 * time.Second), ResponseTime: 2 * time.Millisecond, Up: true, StatusCode: 200}},
+	}, history)
+
+	// Daily counts are restored in full, independent of the history size
+	checks := 0
+	for _, day := range restored.DailyUptime("https://example.com", 2, now.Add(2*time.Second)) {
+		checks += day.Checks
+	}
+	assert.Equal(t, 3, checks)
+	assert.Zero(t, restored.DailyUptime("https://other.example.com", 1, now)[0].Checks)
+
+	require.NoError(t, restored.LoadHistory(filepath.Join(t.TempDir(), "missing.json")))
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	assert.Error(t, restored.LoadHistory(path))
+}
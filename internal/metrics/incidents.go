@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+)
+
+// MaxIncidents is the number of incidents kept per target; older incidents are also
+// dropped once they ended more than DailyHistoryDays ago
+const MaxIncidents = 500
+
+// Error types counted in incidents
+const (
+	ErrorTypeTimeout           = "timeout"
+	ErrorTypeDNS               = "dns"
+	ErrorTypeConnectionRefused = "connection_refused"
+	ErrorTypeTLS               = "tls"
+	ErrorTypeOther             = "other"
+	ErrorTypeAssertion         = "assertion"
+)
+
+// Incident is a downtime episode of a target: consecutive failing checks, ended by the
+// next successful check
+type Incident struct {
+	URL   string     `json:"url"`
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+	// Checks is the number of failing checks during the incident
+	Checks int `json:"checks"`
+	// Errors counts the failing checks by error type, e.g. timeout or http_503
+	Errors    map[string]int `json:"errors"`
+	LastError string         `json:"last_error,omitempty"`
+}
+
+// Open reports whether the target is still down
+func (i Incident) Open() bool {
+	return i.End == nil
+}
+
+// Duration returns the length of the incident, up to now while it is open
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.End != nil {
+		return i.End.Sub(i.Start)
+	}
+	return now.Sub(i.Start)
+}
+
+// incidentSummary accumulates the durations of the incidents closed since startup
+type incidentSummary struct {
+	count uint64
+	sum   float64
+}
+
+// ErrorType classifies a failing check result: the network error kind, assertion for
+// checks failed by an assertion, or http_<code> for checks that completed with a non-2xx
+// status
+func ErrorType(result checker.Result) string {
+	err := result.Error
+	if err == nil {
+		if result.Failure != "" {
+			return ErrorTypeAssertion
+		}
+		return "http_" + strconv.Itoa(result.StatusCode)
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrorTypeDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTypeTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorTypeConnectionRefused
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ErrorTypeTLS
+	default:
+		return ErrorTypeOther
+	}
+}
+
+// recordIncident opens, extends or closes the incident of the result's target. The
+// caller must hold the write lock.
+func (c *Collector) recordIncident(result checker.Result) {
+	if result.Timestamp.IsZero() {
+		return
+	}
+
+	incidents := c.incidents[result.URL]
+	n := len(incidents)
+	open := n > 0 && incidents[n-1].Open()
+
+	switch {
+	case result.IsUp() && open:
+		end := result.Timestamp
+		incidents[n-1].End = &end
+
+		summary, exists := c.incidentStats[result.URL]
+		if !exists {
+			summary = &incidentSummary{}
+			c.incidentStats[result.URL] = summary
+		}
+		summary.count++
+		summary.sum += incidents[n-1].Duration(end).Seconds()
+	case result.IsUp():
+		return
+	case !open:
+		incidents = append(incidents, Incident{
+			URL:    result.URL,
+			Start:  result.Timestamp,
+			Errors: make(map[string]int),
+		})
+		n++
+	}
+
+	if !result.IsUp() {
+		incident := &incidents[n-1]
+		if incident.Errors == nil {
+			incident.Errors = make(map[string]int)
+		}
+		incident.Checks++
+		incident.Errors[ErrorType(result)]++
+		if result.Error != nil {
+			incident.LastError = result.Error.Error()
+		} else {
+			incident.LastError = result.Failure
+		}
+	}
+
+	c.incidents[result.URL] = pruneIncidents(incidents, result.Timestamp)
+}
+
+// pruneIncidents drops the incidents beyond MaxIncidents and those that ended more than
+// DailyHistoryDays before now
+func pruneIncidents(incidents []Incident, now time.Time) []Incident {
+	if len(incidents) > MaxIncidents {
+		incidents = incidents[len(incidents)-MaxIncidents:]
+	}
+
+	oldest := now.AddDate(0, 0, -DailyHistoryDays)
+	start := 0
+	for start < len(incidents) && incidents[start].End != nil && incidents[start].End.Before(oldest) {
+		start++
+	}
+	return incidents[start:]
+}
+
+// Incidents returns a copy of the incidents of every target that were ongoing at or after
+// since, oldest first
+func (c *Collector) Incidents(since time.Time) []Incident {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	incidents := make([]Incident, 0)
+	for _, recorded := range c.incidents {
+		for _, incident := range recorded {
+			if incident.End != nil && incident.End.Before(since) {
+				continue
+			}
+			incidents = append(incidents, copyIncident(incident))
+		}
+	}
+
+	sort.Slice(incidents, func(i, j int) bool {
+		if incidents[i].Start.Equal(incidents[j].Start) {
+			return incidents[i].URL < incidents[j].URL
+		}
+		return incidents[i].Start.Before(incidents[j].Start)
+	})
+
+	return incidents
+}
+
+func copyIncident(incident Incident) Incident {
+	if incident.End != nil {
+		end := *incident.End
+		incident.End = &end
+	}
+	errorCounts := make(map[string]int, len(incident.Errors))
+	for errorType, count := range incident.Errors {
+		errorCounts[errorType] = count
+	}
+	incident.Errors = errorCounts
+	return incident
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   checker.Result
+		expected string
+	}{
+		{"http status", checker.Result{StatusCode: 503}, "http_503"},
+		{"assertion", checker.Result{StatusCode: 200, Failure: "response body of 0 bytes is below minBodyBytes 100"}, ErrorTypeAssertion},
+		{"dns", checker.Result{Error: fmt.Errorf("network error: %w", &net.DNSError{Err: "no such host", Name: "example.invalid"})}, ErrorTypeDNS},
+		{"deadline", checker.Result{Error: fmt.Errorf("request failed: %w", context.DeadlineExceeded)}, ErrorTypeTimeout},
+		{"dns timeout", checker.Result{Error: &net.OpError{Op: "dial", Err: &net.DNSError{IsTimeout: true}}}, ErrorTypeDNS},
+		{"refused", checker.Result{Error: fmt.Errorf("connection failed: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})}, ErrorTypeConnectionRefused},
+		{"other", checker.Result{Error: errors.New("unsupported protocol: ftp")}, ErrorTypeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ErrorType(tt.result))
+		})
+	}
+}
+
+func TestCollector_Incidents(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://other.example.com"},
+		InstanceID: "test-instance",
+	}
+	collector := NewCollector(cfg, checker.New(cfg))
+
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	record := func(url string, offset time.Duration, statusCode int, err error) {
+		collector.Record(checker.Result{URL: url, Host: url, Path: "/", Protocol: "https", StatusCode: statusCode, Error: err, Timestamp: start.Add(offset)})
+	}
+
+	record("https://example.com", 0, 200, nil)
+	record("https://example.com", time.Minute, 503, nil)
+	record("https://example.com", 2*time.Minute, 0, context.DeadlineExceeded)
+	record("https://example.com", 3*time.Minute, 503, nil)
+	record("https://example.com", 5*time.Minute, 200, nil)
+	record("https://other.example.com", 10*time.Minute, 0, errors.New("refused"))
+
+	incidents := collector.Incidents(time.Time{})
+	require.Len(t, incidents, 2)
+
+	resolved := incidents[0]
+	assert.Equal(t, "https://example.com", resolved.URL)
+	assert.Equal(t, start.Add(time.Minute), resolved.Start)
+	require.NotNil(t, resolved.End)
+	assert.Equal(t, start.Add(5*time.Minute), *resolved.End)
+	assert.False(t, resolved.Open())
+	assert.Equal(t, 4*time.Minute, resolved.Duration(time.Now()))
+	assert.Equal(t, 3, resolved.Checks)
+	assert.Equal(t, map[string]int{"http_503": 2, ErrorTypeTimeout: 1}, resolved.Errors)
+
+	ongoing := incidents[1]
+	assert.Equal(t, "https://other.example.com", ongoing.URL)
+	assert.True(t, ongoing.Open())
+	assert.Equal(t, "refused", ongoing.LastError)
+	assert.Equal(t, time.Minute, ongoing.Duration(start.Add(11*time.Minute)))
+
+	// Ongoing incidents are always returned, resolved ones only when they overlap the period
+	incidents = collector.Incidents(start.Add(6 * time.Minute))
+	require.Len(t, incidents, 1)
+	assert.Equal(t, "https://other.example.com", incidents[0].URL)
+
+	// Returned incidents are copies
+	incidents[0].Errors["other"] = 100
+	assert.Equal(t, 1, collector.Incidents(time.Time{})[1].Errors[ErrorTypeOther])
+
+	ch := make(chan prometheus.Metric, 50)
+	collector.Collect(ch)
+	close(ch)
+
+	summaries := map[string]*dto.Summary{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_incident_duration_seconds"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "url" {
+				summaries[label.GetValue()] = m.GetSummary()
+			}
+		}
+	}
+
+	// Only resolved incidents are observed
+	require.Len(t, summaries, 1)
+	assert.Equal(t, uint64(1), summaries["https://example.com"].GetSampleCount())
+	assert.Equal(t, 240.0, summaries["https://example.com"].GetSampleSum())
+
+	collector.RemoveTarget("https://example.com")
+	assert.Len(t, collector.Incidents(time.Time{}), 1)
+}
+
+func TestPruneIncidents(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -DailyHistoryDays-1)
+	recent := now.Add(-time.Hour)
+
+	incidents := []Incident{
+		{URL: "a", Start: old.Add(-time.Minute), End: &old},
+		{URL: "b", Start: recent.Add(-time.Minute), End: &recent},
+		{URL: "c", Start: recent},
+	}
+	assert.Equal(t, incidents[1:], pruneIncidents(incidents, now))
+
+	many := make([]Incident, MaxIncidents+10)
+	for i := range many {
+		many[i] = Incident{Start: recent, End: &recent, Checks: i}
+	}
+	pruned := pruneIncidents(many, now)
+	require.Len(t, pruned, MaxIncidents)
+	assert.Equal(t, 10, pruned[0].Checks)
+}
+
+func TestCollector_SaveAndLoadIncidents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	collector := NewCollector(cfg, checker.New(cfg))
+	collector.Record(checker.Result{URL: "https://example.com", StatusCode: 500, Timestamp: now})
+	require.NoError(t, collector.SaveHistory(path))
+
+	restored := NewCollector(cfg, checker.New(cfg))
+	require.NoError(t, restored.LoadHistory(path))
+
+	// The restored open incident continues until the target recovers
+	restored.Record(checker.Result{URL: "https://example.com", StatusCode: 500, Timestamp: now.Add(time.Minute)})
+	restored.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: now.Add(2 * time.Minute)})
+
+	incidents := restored.Incidents(time.Time{})
+	require.Len(t, incidents, 1)
+	assert.Equal(t, now, incidents[0].Start)
+	assert.Equal(t, 2, incidents[0].Checks)
+	assert.Equal(t, 2*time.Minute, incidents[0].Duration(time.Now()))
+}
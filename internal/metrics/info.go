@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectTargetInfo emits url_target_info for the checked targets with a description or
+// runbook URL. The caller must hold the read lock.
+func (c *Collector) collectTargetInfo(ch chan<- prometheus.Metric) {
+	if c.disabled[c.urlTargetInfo] {
+		return
+	}
+
+	for _, target := range c.checker.Targets() {
+		if target.Description == "" && target.RunbookURL == "" {
+			continue
+		}
+		result, exists := c.lastResults[target.URL]
+		if !exists {
+			continue
+		}
+
+		c.send(
+			ch,
+			c.urlTargetInfo,
+			prometheus.GaugeValue,
+			1,
+			c.labelsFor(result).infoLabels(target.Name, target.Group, target.Description, target.RunbookURL),
+		)
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector_TargetInfo(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://api.example.com/health", Name: "api", Group: "shop", Description: "Checkout API", RunbookURL: "https://runbooks.example.com/api"},
+			{URL: "https://web.example.com", Description: "Storefront"},
+			{URL: "https://plain.example.com"},
+			{URL: "https://unchecked.example.com", Description: "Not checked yet"},
+		},
+		InstanceID: "test-instance",
+	}
+
+	chk := checker.New(cfg)
+	collector := NewCollector(cfg, chk)
+	now := time.Now()
+	collector.Record(checker.Result{URL: "https://api.example.com/health", Host: "https://api.example.com", Path: "/health", Protocol: "https", StatusCode: 200, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://web.example.com", Host: "https://web.example.com", Protocol: "https", StatusCode: 503, Timestamp: now})
+	collector.Record(checker.Result{URL: "https://plain.example.com", Host: "https://plain.example.com", Protocol: "https", StatusCode: 200, Timestamp: now})
+
+	infos := targetInfos(t, collector)
+	require.Len(t, infos, 2, "only checked targets with a description or runbook have info")
+	assert.Equal(t, map[string]string{
+		"url":         "https://api.example.com/health",
+		"host":        "https://api.example.com",
+		"path":        "/health",
+		"protocol":    "https",
+		"name":        "api",
+		"group":       "shop",
+		"description": "Checkout API",
+		"runbook_url": "https://runbooks.example.com/api",
+		"instance":    "test-instance",
+	}, infos["https://api.example.com/health"])
+	assert.Equal(t, "Storefront", infos["https://web.example.com"]["description"])
+	assert.Empty(t, infos["https://web.example.com"]["runbook_url"])
+
+	cfg.Metrics.Disabled = []string{"url_target_info"}
+	collector = NewCollector(cfg, chk)
+	collector.Record(checker.Result{URL: "https://web.example.com", StatusCode: 200, Timestamp: now})
+	assert.Empty(t, targetInfos(t, collector))
+}
+
+// targetInfos returns the labels of the url_target_info series by URL
+func targetInfos(t *testing.T, collector *Collector) map[string]map[string]string {
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+
+	infos := map[string]map[string]string{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), `"url_target_info"`) {
+			continue
+		}
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		assert.Equal(t, 1.0, m.GetGauge().GetValue())
+		labels := map[string]string{}
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		infos[labels["url"]] = labels
+	}
+	return infos
+}
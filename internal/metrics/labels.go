@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Label names of the per-target metric families
+var (
+	targetLabelNames = []string{"url", "host", "path", "protocol", "instance"}
+	statusLabelNames = []string{"url", "host", "path", "protocol", "status_code", "instance"}
+	windowLabelNames = []string{"url", "host", "path", "protocol", "window", "instance"}
+	tlsLabelNames    = []string{"url", "host", "path", "protocol", "version", "cipher_suite", "legacy", "instance"}
+	headerLabelNames = []string{"url", "host", "path", "protocol", "header", "instance"}
+	stepLabelNames   = []string{"url", "host", "path", "protocol", "step", "instance"}
+	infoLabelNames   = []string{"url", "host", "path", "protocol", "name", "group", "description", "runbook_url", "instance"}
+
+	compositeLabelNames = []string{"composite", "mode", "instance"}
+)
+
+// targetLabels holds the label pairs of a target's series. They are built when a result
+// is recorded, so scrapes do not allocate them again for every metric.
+type targetLabels struct {
+	// host, path and protocol are the values recorded, to notice when they change
+	host, path, protocol string
+
+	values  []string                    // url, host, path, protocol, instance
+	base    []*dto.LabelPair            // pairs of targetLabelNames
+	windows [][]*dto.LabelPair          // pairs of windowLabelNames for each SLO window, in configuration order
+	status  map[string][]*dto.LabelPair // pairs of statusLabelNames for each status code seen
+	tls     map[tlsKey][]*dto.LabelPair // pairs of tlsLabelNames for each TLS connection seen
+	headers map[string][]*dto.LabelPair // pairs of headerLabelNames for each audited security header
+	steps   map[string][]*dto.LabelPair // pairs of stepLabelNames for each transaction step run
+}
+
+// tlsKey identifies the label values of a TLS connection
+type tlsKey struct {
+	version, cipherSuite string
+	legacy               bool
+}
+
+func resultTLS(result *checker.Result) tlsKey {
+	return tlsKey{version: result.TLSVersion, cipherSuite: result.TLSCipherSuite, legacy: result.TLSLegacy}
+}
+
+func (c *Collector) newTargetLabels(result *checker.Result) *targetLabels {
+	protocol := resultProtocol(result)
+	values := []string{config.RedactURL(result.URL), result.Host, result.Path, protocol, c.config.InstanceID}
+
+	labels := &targetLabels{
+		host:     result.Host,
+		path:     result.Path,
+		protocol: result.Protocol,
+		values:   values,
+		base:     labelPairs(targetLabelNames, values),
+		windows:  make([][]*dto.LabelPair, len(c.config.SLO.Windows)),
+		status:   make(map[string][]*dto.LabelPair),
+		tls:      make(map[tlsKey][]*dto.LabelPair),
+		headers:  make(map[string][]*dto.LabelPair),
+		steps:    make(map[string][]*dto.LabelPair),
+	}
+	for i, window := range c.config.SLO.Windows {
+		labels.windows[i] = labelPairs(windowLabelNames, []string{values[0], values[1], values[2], values[3], formatWindow(window), values[4]})
+	}
+	return labels
+}
+
+// labelsFor returns the labels of the result's target. Results stored without being
+// recorded get labels that are built for this scrape only. The caller must hold the read lock.
+func (c *Collector) labelsFor(result *checker.Result) *targetLabels {
+	if labels, exists := c.labels[result.URL]; exists && labels.matches(result) {
+		return labels
+	}
+	return c.newTargetLabels(result)
+}
+
+func (l *targetLabels) matches(result *checker.Result) bool {
+	return l.host == result.Host && l.path == result.Path && l.protocol == result.Protocol
+}
+
+// statusLabels returns the label pairs of the per-status counters for the status code
+func (l *targetLabels) statusLabels(statusCode string) []*dto.LabelPair {
+	if pairs, exists := l.status[statusCode]; exists {
+		return pairs
+	}
+	return labelPairs(statusLabelNames, []string{l.values[0], l.values[1], l.values[2], l.values[3], statusCode, l.values[4]})
+}
+
+// tlsLabels returns the label pairs of url_tls_version_info for the TLS connection
+func (l *targetLabels) tlsLabels(key tlsKey) []*dto.LabelPair {
+	if pairs, exists := l.tls[key]; exists {
+		return pairs
+	}
+	return labelPairs(tlsLabelNames, []string{l.values[0], l.values[1], l.values[2], l.values[3], key.version, key.cipherSuite, strconv.FormatBool(key.legacy), l.values[4]})
+}
+
+// headerLabels returns the label pairs of url_security_header_present for the header,
+// named in lower case as in HTTP/2
+func (l *targetLabels) headerLabels(header string) []*dto.LabelPair {
+	if pairs, exists := l.headers[header]; exists {
+		return pairs
+	}
+	return labelPairs(headerLabelNames, []string{l.values[0], l.values[1], l.values[2], l.values[3], strings.ToLower(header), l.values[4]})
+}
+
+// stepLabels returns the label pairs of the step metrics for the transaction step
+func (l *targetLabels) stepLabels(step string) []*dto.LabelPair {
+	if pairs, exists := l.steps[step]; exists {
+		return pairs
+	}
+	return labelPairs(stepLabelNames, []string{l.values[0], l.values[1], l.values[2], l.values[3], step, l.values[4]})
+}
+
+// infoLabels returns the label pairs of url_target_info, built for each scrape since the
+// target's settings can change at runtime
+func (l *targetLabels) infoLabels(name, group, description, runbookURL string) []*dto.LabelPair {
+	return labelPairs(infoLabelNames, []string{l.values[0], l.values[1], l.values[2], l.values[3], name, group, description, runbookURL, l.values[4]})
+}
+
+// labelPairs pairs the names with the values, sorted by name as the exposition expects
+func labelPairs(names, values []string) []*dto.LabelPair {
+	pairs := make([]*dto.LabelPair, len(names))
+	for i := range names {
+		pairs[i] = &dto.LabelPair{Name: &names[i], Value: &values[i]}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].GetName() < pairs[j].GetName()
+	})
+	return pairs
+}
+
+// labeledMetric is a constant gauge or counter with precomputed label pairs. Unlike
+// prometheus.MustNewConstMetric it does not build the pairs again for every metric.
+type labeledMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     float64
+	labels    []*dto.LabelPair
+}
+
+func (m *labeledMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+func (m *labeledMetric) Write(out *dto.Metric) error {
+	out.Label = m.labels
+	switch m.valueType {
+	case prometheus.CounterValue:
+		out.Counter = &dto.Counter{Value: &m.value}
+	default:
+		out.Gauge = &dto.Gauge{Value: &m.value}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// sloBucketSize is the resolution at which check outcomes are aggregated for SLO windows
+const sloBucketSize = time.Minute
+
+type sloBucket struct {
+	start time.Time
+	total int
+	good  int
+}
+
+// sloTracker keeps per-minute check outcomes for a target so that availability can be
+// computed over sliding windows without storing every individual result
+type sloTracker struct {
+	objective float64
+	retention time.Duration
+	buckets   []sloBucket
+}
+
+func newSLOTracker(objective float64, windows []time.Duration) *sloTracker {
+	retention := time.Duration(0)
+	for _, window := range windows {
+		if window > retention {
+			retention = window
+		}
+	}
+
+	return &sloTracker{
+		objective: objective,
+		retention: retention,
+	}
+}
+
+func (t *sloTracker) record(timestamp time.Time, good bool) {
+	start := timestamp.Truncate(sloBucketSize)
+
+	if n := len(t.buckets); n == 0 || t.buckets[n-1].start.Before(start) {
+		t.buckets = append(t.buckets, sloBucket{start: start})
+	}
+
+	bucket := &t.buckets[len(t.buckets)-1]
+	bucket.total++
+	if good {
+		bucket.good++
+	}
+
+	t.evict(timestamp)
+}
+
+// evict drops buckets that fall entirely outside the longest window
+func (t *sloTracker) evict(now time.Time) {
+	cutoff := now.Add(-t.retention - sloBucketSize)
+
+	drop := 0
+	for drop < len(t.buckets) && t.buckets[drop].start.Before(cutoff) {
+		drop++
+	}
+
+	if drop > 0 {
+		t.buckets = append(t.buckets[:0], t.buckets[drop:]...)
+	}
+}
+
+// errorRatio returns the fraction of failed checks within the window ending at now.
+// ok is false when no checks were recorded in the window.
+func (t *sloTracker) errorRatio(now time.Time, window time.Duration) (ratio float64, ok bool) {
+	cutoff := now.Add(-window)
+
+	total, good := 0, 0
+	for i := len(t.buckets) - 1; i >= 0; i-- {
+		bucket := t.buckets[i]
+		if !bucket.start.Add(sloBucketSize).After(cutoff) {
+			break
+		}
+		total += bucket.total
+		good += bucket.good
+	}
+
+	if total == 0 {
+		return 0, false
+	}
+
+	return float64(total-good) / float64(total), true
+}
+
+// burnRate returns how fast the error budget is being spent relative to the objective:
+// 1 means the budget would be exactly exhausted at the end of the SLO period
+func (t *sloTracker) burnRate(errorRatio float64) float64 {
+	return errorRatio / (1 - t.objective)
+}
+
+// formatWindow renders a window duration as a compact label value such as 5m, 6h or 3d
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return strconv.FormatInt(int64(window/(24*time.Hour)), 10) + "d"
+	case window%time.Hour == 0:
+		return strconv.FormatInt(int64(window/time.Hour), 10) + "h"
+	case window%time.Minute == 0:
+		return strconv.FormatInt(int64(window/time.Minute), 10) + "m"
+	default:
+		return window.String()
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLOTracker_ErrorRatio(t *testing.T) {
+	tracker := newSLOTracker(0.99, []time.Duration{5 * time.Minute, time.Hour})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// 50 minutes of healthy checks, then 4 minutes of failures
+	for i := 60; i > 10; i-- {
+		tracker.record(now.Add(-time.Duration(i)*time.Minute), true)
+	}
+	for i := 4; i > 0; i-- {
+		tracker.record(now.Add(-time.Duration(i)*time.Minute), false)
+	}
+
+	ratio, ok := tracker.errorRatio(now, 5*time.Minute)
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, ratio, 1e-9)
+
+	ratio, ok = tracker.errorRatio(now, time.Hour)
+	assert.True(t, ok)
+	assert.InDelta(t, 4.0/54.0, ratio, 1e-9)
+
+	assert.InDelta(t, 100.0, tracker.burnRate(1.0), 1e-9)
+}
+
+func TestSLOTracker_NoData(t *testing.T) {
+	tracker := newSLOTracker(0.999, []time.Duration{time.Hour})
+	now := time.Now()
+
+	_, ok := tracker.errorRatio(now, time.Hour)
+	assert.False(t, ok)
+
+	tracker.record(now.Add(-2*time.Hour), true)
+	_, ok = tracker.errorRatio(now, time.Hour)
+	assert.False(t, ok)
+}
+
+func TestSLOTracker_AggregatesPerMinute(t *testing.T) {
+	tracker := newSLOTracker(0.999, []time.Duration{time.Hour})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker.record(now.Add(5*time.Second), true)
+	tracker.record(now.Add(35*time.Second), false)
+	tracker.record(now.Add(65*time.Second), true)
+
+	assert.Len(t, tracker.buckets, 2)
+	assert.Equal(t, 2, tracker.buckets[0].total)
+	assert.Equal(t, 1, tracker.buckets[0].good)
+}
+
+func TestSLOTracker_EvictsOldBuckets(t *testing.T) {
+	tracker := newSLOTracker(0.999, []time.Duration{10 * time.Minute})
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 120; i++ {
+		tracker.record(start.Add(time.Duration(i)*time.Minute), true)
+	}
+
+	assert.LessOrEqual(t, len(tracker.buckets), 12)
+}
+
+func TestFormatWindow(t *testing.T) {
+	assert.Equal(t, "5m", formatWindow(5*time.Minute))
+	assert.Equal(t, "6h", formatWindow(6*time.Hour))
+	assert.Equal(t, "3d", formatWindow(72*time.Hour))
+	assert.Equal(t, "90m", formatWindow(90*time.Minute))
+	assert.Equal(t, "30s", formatWindow(30*time.Second))
+}
@@ -0,0 +1,280 @@
+package nats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientName identifies the exporter to the servers, e.g. in their connection listings
+const clientName = "url-exporter"
+
+// maxMessageSize bounds the messages read, which are small acknowledgements
+const maxMessageSize = 1 << 20
+
+// maxLineSize bounds the protocol lines read, the longest being the INFO of a server
+// listing the URLs of its cluster
+const maxLineSize = 64 << 10
+
+// serverInfo is the part of the INFO a server sends on connect that the client uses
+type serverInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+	MaxPayload  int  `json:"max_payload"`
+}
+
+// connectOptions is the CONNECT message
+type connectOptions struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	TLSRequired  bool   `json:"tls_required"`
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	AuthToken    string `json:"auth_token,omitempty"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+}
+
+// serverConn is a connection to a server. Messages are published one batch at a time.
+type serverConn struct {
+	conn    net.Conn
+	address string
+	reader  *bufio.Reader
+	timeout time.Duration
+	info    serverInfo
+	// inbox is the prefix of the reply subjects of JetStream acknowledgements, subscribed
+	// on first use
+	inbox string
+}
+
+// setDeadline bounds the next reads and writes by the timeout and the context's deadline,
+// and interrupts them when the context is canceled until stop is called
+func (s *serverConn) setDeadline(ctx context.Context) (stop func() bool, err error) {
+	deadline := time.Now().Add(s.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	// The connection may be upgraded to TLS meanwhile, whose deadlines are those of conn
+	conn := s.conn
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	return context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) }), nil
+}
+
+// readInfo reads the INFO the server sends on connect
+func (s *serverConn) readInfo() error {
+	line, err := s.readLine()
+	if err != nil {
+		return err
+	}
+	payload, isInfo := strings.CutPrefix(line, "INFO ")
+	if !isInfo {
+		return fmt.Errorf("expected INFO from server, got %q", line)
+	}
+	if err := json.Unmarshal([]byte(payload), &s.info); err != nil {
+		return fmt.Errorf("invalid INFO from server: %w", err)
+	}
+	return nil
+}
+
+// connect sends the CONNECT message and waits for the server to accept it
+func (s *serverConn) connect(options connectOptions) error {
+	message, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.conn, "CONNECT %s\r\nPING\r\n", message); err != nil {
+		return err
+	}
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return serverError(line)
+		case line == "+OK", strings.HasPrefix(line, "INFO "):
+		default:
+			return fmt.Errorf("unexpected %q from server", line)
+		}
+	}
+}
+
+// publish sends the messages and waits until the server processed them. With jetStream
+// it also waits for the acknowledgement of the stream storing each message.
+func (s *serverConn) publish(ctx context.Context, messages []Message, jetStream bool) error {
+	stop, err := s.setDeadline(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	var request bytes.Buffer
+	if jetStream && s.inbox == "" {
+		s.inbox = newInbox()
+		fmt.Fprintf(&request, "SUB %s.* 1\r\n", s.inbox)
+	}
+	for i, message := range messages {
+		if s.info.MaxPayload > 0 && len(message.Data) > s.info.MaxPayload {
+			return fmt.Errorf("message to %s exceeds the maximum payload of %d bytes", message.Subject, s.info.MaxPayload)
+		}
+		if jetStream {
+			fmt.Fprintf(&request, "PUB %s %s.%d %d\r\n", message.Subject, s.inbox, i, len(message.Data))
+		} else {
+			fmt.Fprintf(&request, "PUB %s %d\r\n", message.Subject, len(message.Data))
+		}
+		request.Write(message.Data)
+		request.WriteString("\r\n")
+	}
+	request.WriteString("PING\r\n")
+	if _, err := s.conn.Write(request.Bytes()); err != nil {
+		return err
+	}
+
+	// The PONG confirms the server processed the messages; acknowledgements of JetStream
+	// may arrive before or after it
+	pending := 0
+	if jetStream {
+		pending = len(messages)
+	}
+	acknowledged := make([]bool, len(messages))
+	var errs []error
+	for pong := false; !pong || pending > 0; {
+		line, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			pong = true
+		case line == "PING":
+			if _, err := io.WriteString(s.conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return serverError(line)
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			subject, header, payload, err := s.readMessage(line)
+			if err != nil {
+				return err
+			}
+			i, err := strconv.Atoi(strings.TrimPrefix(subject, s.inbox+"."))
+			if err != nil || i < 0 || i >= len(messages) || acknowledged[i] {
+				continue
+			}
+			acknowledged[i] = true
+			pending--
+			if err := ackError(header, payload); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", messages[i].Subject, err))
+			}
+		case line == "+OK", strings.HasPrefix(line, "INFO "):
+		default:
+			return fmt.Errorf("unexpected %q from server", line)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readMessage reads the payload of the MSG or HMSG with the protocol line, returning its
+// subject, header and payload
+func (s *serverConn) readMessage(line string) (string, []byte, []byte, error) {
+	fields := strings.Fields(line)
+	headerSize, totalSize := 0, 0
+	var err error
+	switch {
+	case fields[0] == "MSG" && (len(fields) == 4 || len(fields) == 5):
+		totalSize, err = strconv.Atoi(fields[len(fields)-1])
+	case fields[0] == "HMSG" && (len(fields) == 5 || len(fields) == 6):
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+		if err == nil {
+			totalSize, err = strconv.Atoi(fields[len(fields)-1])
+		}
+	default:
+		return "", nil, nil, fmt.Errorf("invalid message %q from server", line)
+	}
+	if err != nil || headerSize < 0 || totalSize < headerSize || totalSize > maxMessageSize {
+		return "", nil, nil, fmt.Errorf("invalid message %q from server", line)
+	}
+
+	message := make([]byte, totalSize+2)
+	if _, err := io.ReadFull(s.reader, message); err != nil {
+		return "", nil, nil, err
+	}
+	return fields[1], message[:headerSize], message[headerSize:totalSize], nil
+}
+
+// readLine reads a protocol line without its line ending, failing for lines longer than
+// maxLineSize
+func (s *serverConn) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := s.reader.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLineSize {
+			return "", fmt.Errorf("line from server exceeds %d bytes", maxLineSize)
+		}
+		line = append(line, chunk...)
+		if err == nil {
+			return strings.TrimRight(string(line), "\r\n"), nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return "", err
+		}
+	}
+}
+
+func (s *serverConn) close() error {
+	return s.conn.Close()
+}
+
+// serverError returns the error of an -ERR line, e.g. -ERR 'Authorization Violation'
+func serverError(line string) error {
+	message := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+	return fmt.Errorf("server error: %s", message)
+}
+
+// pubAck is the acknowledgement of a message published to a JetStream stream
+type pubAck struct {
+	Stream string `json:"stream"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// ackError returns the error of a JetStream acknowledgement, if any. A 503 status header
+// means no stream stores the subject.
+func ackError(header, payload []byte) error {
+	if status, _, _ := bytes.Cut(header, []byte("\r\n")); len(status) > 0 {
+		if fields := strings.Fields(string(status)); len(fields) > 1 && fields[1] == "503" {
+			return errors.New("no JetStream stream stores the subject")
+		}
+	}
+
+	var ack pubAck
+	if err := json.Unmarshal(payload, &ack); err != nil {
+		return fmt.Errorf("invalid JetStream acknowledgement: %w", err)
+	}
+	if ack.Error != nil {
+		return fmt.Errorf("JetStream error %d: %s", ack.Error.Code, ack.Error.Description)
+	}
+	if ack.Stream == "" {
+		return errors.New("invalid JetStream acknowledgement without stream")
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package nats is a minimal NATS publisher: it publishes messages over plain or TLS
+// connections with token or user and password authentication, optionally waiting for
+// the acknowledgements of the JetStream streams storing them.
+//
+// The sink only publishes, which takes a handful of text protocol messages, so this
+// stands in for the official client and its subscriptions, reconnect buffering and
+// key-value and object stores. NKey and JWT credentials are out of scope; moving to the
+// official client then means replacing Client behind the sink.
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Message is a message to publish
+type Message struct {
+	Subject string
+	Data    []byte
+}
+
+// Client publishes messages to the first server that accepts a connection, and connects
+// again after a failure
+type Client struct {
+	servers   []string
+	tlsConfig *tls.Config
+	options   connectOptions
+	jetStream bool
+	timeout   time.Duration
+
+	mutex sync.Mutex
+	conn  *serverConn
+}
+
+// NewClient creates a client for the configured servers, with timeout bounding each
+// connection attempt and publish
+func NewClient(cfg config.NATSConfig, timeout time.Duration) (*Client, error) {
+	c := &Client{
+		servers:   cfg.Servers,
+		jetStream: cfg.JetStream,
+		timeout:   timeout,
+		options: connectOptions{
+			Name:      clientName,
+			Lang:      "go",
+			Protocol:  1,
+			AuthToken: cfg.Token,
+			User:      cfg.Username,
+			Pass:      cfg.Password,
+		},
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := cfg.TLS.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid nats TLS configuration: %w", err)
+		}
+		c.tlsConfig = tlsConfig
+	}
+
+	return c, nil
+}
+
+// Publish publishes the messages and waits until the server processed them or, with
+// JetStream, until their streams stored them. A failed attempt is retried once over a
+// new connection.
+func (c *Client) Publish(ctx context.Context, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	for _, message := range messages {
+		if !validSubject(message.Subject) {
+			return fmt.Errorf("invalid nats subject %q", message.Subject)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.publish(ctx, messages)
+	if err != nil && ctx.Err() == nil {
+		c.reset()
+		err = c.publish(ctx, messages)
+	}
+	if err != nil {
+		c.reset()
+		return fmt.Errorf("failed to publish to nats: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection to the server
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.reset()
+	return nil
+}
+
+// publish publishes the messages over the current connection, connecting first if
+// needed. The caller must hold the mutex.
+func (c *Client) publish(ctx context.Context, messages []Message) error {
+	if c.conn == nil {
+		conn, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+	if err := c.conn.publish(ctx, messages, c.jetStream); err != nil {
+		return fmt.Errorf("server %s: %w", c.conn.address, err)
+	}
+	return nil
+}
+
+// connect connects to the first server that accepts the connection
+func (c *Client) connect(ctx context.Context) (*serverConn, error) {
+	var errs []error
+	for _, address := range c.servers {
+		conn, err := c.dial(ctx, address)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return conn, nil
+	}
+	return nil, fmt.Errorf("no server available: %w", errors.Join(errs...))
+}
+
+// dial connects and authenticates to the server at the address
+func (c *Client) dial(ctx context.Context, address string) (*serverConn, error) {
+	netConn, err := (&net.Dialer{Timeout: c.timeout}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", address, err)
+	}
+
+	conn := &serverConn{conn: netConn, address: address, timeout: c.timeout}
+	if err := c.handshake(ctx, conn); err != nil {
+		_ = conn.close()
+		return nil, fmt.Errorf("failed to connect to server %s: %w", address, err)
+	}
+	return conn, nil
+}
+
+// handshake reads the server's INFO, upgrades the connection to TLS if configured and
+// sends the CONNECT message
+func (c *Client) handshake(ctx context.Context, conn *serverConn) error {
+	stop, err := conn.setDeadline(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	conn.reader = bufio.NewReader(conn.conn)
+	if err := conn.readInfo(); err != nil {
+		return err
+	}
+
+	options := c.options
+	switch {
+	case c.tlsConfig != nil:
+		tlsConfig := c.tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(conn.address)
+		}
+		tlsConn := tls.Client(conn.conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		conn.conn = tlsConn
+		conn.reader = bufio.NewReader(tlsConn)
+		options.TLSRequired = true
+	case conn.info.TLSRequired:
+		return errors.New("server requires TLS")
+	}
+
+	// No responders tell a publish to JetStream that no stream stores its subject
+	options.Headers = conn.info.Headers
+	options.NoResponders = conn.info.Headers
+	return conn.connect(options)
+}
+
+// reset closes the connection. The caller must hold the mutex.
+func (c *Client) reset() {
+	if c.conn != nil {
+		_ = c.conn.close()
+		c.conn = nil
+	}
+}
+
+// validSubject reports whether the subject can be published to: dot-separated non-empty
+// tokens without whitespace or wildcards
+func validSubject(subject string) bool {
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" || token == "*" || token == ">" || strings.ContainsAny(token, " \t\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// newInbox returns a unique prefix for reply subjects
+func newInbox() string {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	return "_INBOX." + hex.EncodeToString(id)
+}
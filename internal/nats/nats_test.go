@@ -0,0 +1,204 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is a NATS server with JetStream that accepts the token "secret". Messages to
+// "orders.>" are not stored by any stream and the stream of "full.>" rejects messages.
+type fakeServer struct {
+	listener net.Listener
+
+	mutex       sync.Mutex
+	connections int
+	published   []Message
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) messages() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Message(nil), s.published...)
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	s.mutex.Lock()
+	s.connections++
+	s.mutex.Unlock()
+
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"headers\":true,\"max_payload\":64}\r\n")
+	reader := bufio.NewReader(conn)
+	var inbox, sid string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			var options connectOptions
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &options) != nil || options.AuthToken != "secret" || !options.NoResponders {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "SUB":
+			inbox, sid = strings.TrimSuffix(fields[1], ".*"), fields[2]
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			subject := fields[1]
+			s.mutex.Lock()
+			s.published = append(s.published, Message{Subject: subject, Data: payload[:size]})
+			seq := len(s.published)
+			s.mutex.Unlock()
+			if len(fields) != 4 || !strings.HasPrefix(fields[2], inbox+".") {
+				continue
+			}
+			reply := fields[2]
+			switch {
+			case strings.HasPrefix(subject, "orders."):
+				header := "NATS/1.0 503\r\n\r\n"
+				fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", reply, sid, len(header), len(header), header)
+			case strings.HasPrefix(subject, "full."):
+				ack := `{"error":{"code":503,"description":"maximum messages exceeded"}}`
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+			default:
+				ack := fmt.Sprintf(`{"stream":"RESULTS","seq":%d}`, seq)
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+			}
+		}
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("jetStream=%t", jetStream), func(t *testing.T) {
+			server := newFakeServer(t)
+			client, err := NewClient(config.NATSConfig{
+				Servers:   []string{"127.0.0.1:1", server.address()},
+				JetStream: jetStream,
+				Token:     "secret",
+			}, 5*time.Second)
+			require.NoError(t, err)
+			defer client.Close()
+
+			messages := []Message{
+				{Subject: "results.web.up", Data: []byte(`{"up":true}`)},
+				{Subject: "results.db.down", Data: []byte(`{"up":false}`)},
+			}
+			require.NoError(t, client.Publish(context.Background(), messages))
+			require.NoError(t, client.Publish(context.Background(), messages[:1]))
+
+			assert.Equal(t, append(messages, messages[0]), server.messages())
+			server.mutex.Lock()
+			assert.Equal(t, 1, server.connections, "the connection is reused")
+			server.mutex.Unlock()
+		})
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := newFakeServer(t)
+	publish := func(cfg config.NATSConfig, subject, data string) error {
+		cfg.Servers = []string{server.address()}
+		client, err := NewClient(cfg, 5*time.Second)
+		require.NoError(t, err)
+		defer client.Close()
+		return client.Publish(context.Background(), []Message{{Subject: subject, Data: []byte(data)}})
+	}
+
+	err := publish(config.NATSConfig{Token: "wrong"}, "results.web.up", "{}")
+	assert.ErrorContains(t, err, "Authorization Violation")
+
+	jetStream := config.NATSConfig{Token: "secret", JetStream: true}
+	assert.ErrorContains(t, publish(jetStream, "orders.web.up", "{}"), "no JetStream stream stores the subject")
+	assert.ErrorContains(t, publish(jetStream, "full.web.up", "{}"), "JetStream error 503: maximum messages exceeded")
+	assert.ErrorContains(t, publish(jetStream, "results.web.up", strings.Repeat("x", 65)), "exceeds the maximum payload of 64 bytes")
+
+	for _, subject := range []string{"results..up", "results.web up", "results.>", ""} {
+		assert.ErrorContains(t, publish(jetStream, subject, "{}"), "invalid nats subject", subject)
+	}
+
+	client, err := NewClient(config.NATSConfig{Servers: []string{"127.0.0.1:1"}}, time.Second)
+	require.NoError(t, err)
+	err = client.Publish(context.Background(), []Message{{Subject: "results", Data: []byte("{}")}})
+	assert.ErrorContains(t, err, "no server available")
+}
+
+func TestClient_LongLine(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// An INFO longer than any line the client reads
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "INFO {\"server_id\":\"")
+				_, _ = io.Copy(conn, io.LimitReader(infiniteReader{}, 2*maxLineSize))
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	client, err := NewClient(config.NATSConfig{Servers: []string{listener.Addr().String()}}, 5*time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+	err = client.Publish(context.Background(), []Message{{Subject: "results", Data: []byte("{}")}})
+	assert.ErrorContains(t, err, fmt.Sprintf("line from server exceeds %d bytes", maxLineSize))
+}
+
+// infiniteReader reads endless x
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
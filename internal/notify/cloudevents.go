@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// cloudEventsTypePrefix is followed by the new state in the type of the events, e.g.
+// io.github.jasoet.urlexporter.target.down
+const cloudEventsTypePrefix = "io.github.jasoet.urlexporter.target."
+
+// CloudEvents posts state changes as CloudEvents 1.0 over HTTP, so event-driven workflows
+// can subscribe to them through a broker
+type CloudEvents struct {
+	config config.CloudEventsConfig
+	source string
+	client *http.Client
+}
+
+// cloudEvent is an event in the CloudEvents JSON format
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            stateChange `json:"data"`
+}
+
+// stateChange is the data of an event. Since is when the target went down, also for the
+// event of its recovery.
+type stateChange struct {
+	Target         stateChangeTarget `json:"target"`
+	State          string            `json:"state"`
+	PreviousState  string            `json:"previousState"`
+	Repeat         bool              `json:"repeat,omitempty"`
+	Since          time.Time         `json:"since"`
+	Instance       string            `json:"instance"`
+	StatusCode     int               `json:"statusCode,omitempty"`
+	ResponseTimeMs float64           `json:"responseTimeMs"`
+	Error          string            `json:"error,omitempty"`
+	HistoryURL     string            `json:"historyUrl,omitempty"`
+	Message        string            `json:"message,omitempty"`
+}
+
+type stateChangeTarget struct {
+	URL         string            `json:"url"`
+	Name        string            `json:"name,omitempty"`
+	Group       string            `json:"group,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Description string            `json:"description,omitempty"`
+	RunbookURL  string            `json:"runbookUrl,omitempty"`
+}
+
+func NewCloudEvents(cfg config.CloudEventsConfig, instance string, timeout time.Duration) *CloudEvents {
+	source := cfg.Source
+	if source == "" {
+		source = "url-exporter/" + instance
+	}
+	return &CloudEvents{
+		config: cfg,
+		source: source,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *CloudEvents) Name() string {
+	return "cloudevents"
+}
+
+// Notify posts the event to the endpoint in the configured content mode
+func (c *CloudEvents) Notify(ctx context.Context, event Event) error {
+	ce := c.event(event)
+
+	header := make(http.Header)
+	for name, value := range c.config.Headers {
+		header.Set(name, value)
+	}
+
+	var (
+		content []byte
+		err     error
+	)
+	if c.config.Mode == config.CloudEventsBinary {
+		content, err = json.Marshal(ce.Data)
+		header.Set("Content-Type", ce.DataContentType)
+		header.Set("ce-specversion", ce.SpecVersion)
+		header.Set("ce-id", ce.ID)
+		header.Set("ce-source", ce.Source)
+		header.Set("ce-type", ce.Type)
+		header.Set("ce-subject", ce.Subject)
+		header.Set("ce-time", ce.Time)
+	} else {
+		content, err = json.Marshal(ce)
+		header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := post(ctx, c.client, c.config.Endpoint, header, content); err != nil {
+		return fmt.Errorf("failed to post cloudevent for %s: %w", config.RedactURL(event.Target.URL), err)
+	}
+	return nil
+}
+
+func (c *CloudEvents) event(event Event) cloudEvent {
+	data := stateChange{
+		Target: stateChangeTarget{
+			URL:         config.RedactURL(event.Target.URL),
+			Name:        event.Target.Name,
+			Group:       event.Target.Group,
+			Labels:      event.Target.Labels,
+			Description: event.Target.Description,
+			RunbookURL:  event.Target.RunbookURL,
+		},
+		State:          "down",
+		PreviousState:  "up",
+		Repeat:         event.Repeat,
+		Since:          event.Since.UTC(),
+		Instance:       event.Instance,
+		StatusCode:     event.Result.StatusCode,
+		ResponseTimeMs: float64(event.Result.ResponseTime) / float64(time.Millisecond),
+		HistoryURL:     event.HistoryURL,
+		Message:        event.Message,
+	}
+	switch {
+	case event.Up:
+		data.State, data.PreviousState = "up", "down"
+	case event.Repeat:
+		data.PreviousState = "down"
+	}
+	if !event.Up {
+		data.Error = failure(event.Result)
+	}
+
+	subject := event.Target.Name
+	if subject == "" {
+		subject = data.Target.URL
+	}
+	timestamp := event.Result.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              eventID(event, timestamp),
+		Source:          c.source,
+		Type:            cloudEventsTypePrefix + data.State,
+		Subject:         subject,
+		Time:            timestamp.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// eventID identifies the event by its target, state and check time, so a broker can
+// drop events delivered twice
+func eventID(event Event, timestamp time.Time) string {
+	sum := sha256.Sum256([]byte(event.Target.URL + "\x00" + strconv.FormatBool(event.Up) + "\x00" + strconv.FormatInt(timestamp.UnixNano(), 10)))
+	return hex.EncodeToString(sum[:16])
+}
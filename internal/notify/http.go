@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// postJSON posts the body as JSON and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body interface{}) ([]byte, error) {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return post(ctx, client, endpoint, http.Header{"Content-Type": {"application/json"}}, content)
+}
+
+// post posts the content with the headers and fails on non-2xx responses. Errors never
+// contain the endpoint, since webhook URLs and bot tokens in them are secrets.
+func post(ctx context.Context, client *http.Client, endpoint string, header http.Header, content []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.New("invalid endpoint url")
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return reply, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	return reply, nil
+}
+
+// historyLink returns the URL of the target's history endpoint on the exporter, or an
+// empty string when no external URL is configured
+func historyLink(externalURL string, event Event) string {
+	if externalURL == "" {
+		return ""
+	}
+
+	name := event.Target.Name
+	if name == "" {
+		name = config.RedactURL(event.Target.URL)
+	}
+	return strings.TrimRight(externalURL, "/") + "/api/v1/targets/" + url.PathEscape(name) + "/history"
+}
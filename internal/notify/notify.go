@@ -0,0 +1,323 @@
+// Package notify sends notifications when a target goes down or comes back up.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Event is a change of a target's state between up and down, or a repeated report of a
+// target that is still down. Since is when the target went down. Message is the channel's
+// rendered template, empty when it has none, and replaces the notifier's default text.
+type Event struct {
+	Target     config.Target
+	Result     checker.Result
+	Up         bool
+	Repeat     bool
+	Since      time.Time
+	Instance   string
+	HistoryURL string
+	Stats      Stats
+	Message    string
+}
+
+// Stats are the check counts of a target since the exporter started. Uptime is the
+// percentage of successful checks.
+type Stats struct {
+	Checks     int
+	Successful int
+	Uptime     float64
+}
+
+// StatsSource provides the check counts of a target, such as the metrics collector
+type StatsSource interface {
+	CheckStats(url string) (total, successful int)
+}
+
+// Notifier delivers state change events to an external service
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// Channel is a notifier together with the options controlling when it is notified and
+// the receiver it belongs to, config.DefaultReceiver when empty
+type Channel struct {
+	Receiver string
+	Notifier Notifier
+	Options  config.ChannelOptions
+}
+
+// alert tracks a down target on a channel
+type alert struct {
+	since    time.Time // when the target went down
+	notified time.Time // when it was last reported, zero until the first report
+}
+
+type channelState struct {
+	Channel
+	template *template.Template
+	alerts   map[string]*alert // URL -> alert of a down target
+}
+
+type delivery struct {
+	receiver string
+	notifier Notifier
+	event    Event
+}
+
+// Dispatcher detects up/down transitions in the results of each check cycle and hands
+// them to the channels according to their options. A target that is down when it is first
+// checked is reported; one that is up is not. Options are evaluated after every cycle, so
+// waits and repeats are effectively rounded up to the check interval.
+type Dispatcher struct {
+	checker     *checker.Checker
+	instance    string
+	externalURL string
+	stats       StatsSource
+	route       *route
+	now         func() time.Time
+
+	mutex    sync.Mutex
+	channels []*channelState
+}
+
+// New returns a dispatcher for the notification channels enabled in the configuration,
+// or nil when none is. Stats, when not nil, provides the uptime figures of events.
+func New(cfg *config.Config, chk *checker.Checker, stats StatsSource) (*Dispatcher, error) {
+	notifications := cfg.Notifications
+
+	channels := receiverChannels(config.DefaultReceiver, notifications.ReceiverConfig, cfg)
+	names := make([]string, 0, len(notifications.Receivers))
+	for name := range notifications.Receivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		channels = append(channels, receiverChannels(name, notifications.Receivers[name], cfg)...)
+	}
+
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	d, err := NewDispatcher(chk, cfg.InstanceID, channels...)
+	if err != nil {
+		return nil, err
+	}
+	if d.route, err = newRoute(notifications.Route, config.DefaultReceiver); err != nil {
+		return nil, fmt.Errorf("invalid notification route: %w", err)
+	}
+	d.externalURL = notifications.ExternalURL
+	d.stats = stats
+	return d, nil
+}
+
+// receiverChannels returns the enabled channels of the receiver
+func receiverChannels(name string, receiver config.ReceiverConfig, cfg *config.Config) []Channel {
+	var channels []Channel
+	if receiver.PagerDuty.Enabled() {
+		channels = append(channels, Channel{name, NewPagerDuty(receiver.PagerDuty, cfg.InstanceID, cfg.Timeout), receiver.PagerDuty.ChannelOptions})
+	}
+	if receiver.Telegram.Enabled() {
+		channels = append(channels, Channel{name, NewTelegram(receiver.Telegram, cfg.Timeout), receiver.Telegram.ChannelOptions})
+	}
+	if receiver.Teams.Enabled() {
+		channels = append(channels, Channel{name, NewTeams(receiver.Teams, cfg.Timeout), receiver.Teams.ChannelOptions})
+	}
+	if receiver.CloudEvents.Enabled() {
+		channels = append(channels, Channel{name, NewCloudEvents(receiver.CloudEvents, cfg.InstanceID, cfg.Timeout), receiver.CloudEvents.ChannelOptions})
+	}
+	return channels
+}
+
+func NewDispatcher(chk *checker.Checker, instance string, channels ...Channel) (*Dispatcher, error) {
+	d := &Dispatcher{
+		checker:  chk,
+		instance: instance,
+		now:      time.Now,
+	}
+	for _, channel := range channels {
+		if channel.Receiver == "" {
+			channel.Receiver = config.DefaultReceiver
+		}
+		tmpl, err := parseTemplate(channel.Notifier.Name(), channel.Options.Template)
+		if err != nil {
+			return nil, err
+		}
+		d.channels = append(d.channels, &channelState{Channel: channel, template: tmpl, alerts: make(map[string]*alert)})
+	}
+	return d, nil
+}
+
+// HandleCycle is a checker.CycleHandler that sends the notifications due after the cycle
+// to the channels of the receivers each target is routed to
+func (d *Dispatcher) HandleCycle(ctx context.Context, results []checker.Result) {
+	targets := make(map[string]config.Target)
+	receivers := make(map[string][]string)
+	for _, target := range d.checker.Targets() {
+		targets[target.URL] = target
+		receivers[target.URL] = d.receivers(target)
+	}
+
+	now := d.now()
+	var deliveries []delivery
+
+	d.mutex.Lock()
+	for _, channel := range d.channels {
+		for url := range channel.alerts {
+			if _, exists := targets[url]; !exists {
+				delete(channel.alerts, url)
+			}
+		}
+
+		for _, result := range results {
+			target, exists := targets[result.URL]
+			// Skipped checks neither raise nor resolve alerts: the dependency is reported
+			if !exists || result.IsSkipped() {
+				continue
+			}
+			if !slices.Contains(receivers[target.URL], channel.Receiver) {
+				// the target's labels may have changed since it was reported
+				delete(channel.alerts, target.URL)
+				continue
+			}
+
+			event, due := channel.evaluate(target, result, now)
+			if due {
+				d.complete(&event)
+				channel.render(&event)
+				deliveries = append(deliveries, delivery{receiver: channel.Receiver, notifier: channel.Notifier, event: event})
+			}
+		}
+	}
+	d.mutex.Unlock()
+
+	for _, delivery := range deliveries {
+		send(ctx, delivery.receiver, delivery.notifier, delivery.event)
+	}
+}
+
+// receivers returns the receivers the target's notifications go to
+func (d *Dispatcher) receivers(target config.Target) []string {
+	if d.route == nil {
+		return []string{config.DefaultReceiver}
+	}
+	return d.route.receivers(routeLabels(target))
+}
+
+// complete fills in the event fields that do not depend on the channel
+func (d *Dispatcher) complete(event *Event) {
+	event.Instance = d.instance
+	event.HistoryURL = historyLink(d.externalURL, *event)
+	if d.stats != nil {
+		total, successful := d.stats.CheckStats(event.Target.URL)
+		event.Stats = Stats{Checks: total, Successful: successful}
+		if total > 0 {
+			event.Stats.Uptime = float64(successful) / float64(total) * 100
+		}
+	}
+}
+
+// render sets the event message from the channel's template. A template that fails to
+// execute is logged and the notifier's default text is used instead.
+func (c *channelState) render(event *Event) {
+	if c.template == nil {
+		return
+	}
+
+	message, err := render(c.template, *event)
+	if err != nil {
+		log.Warn().Err(err).Str("notifier", c.Notifier.Name()).Str("url", config.RedactURL(event.Target.URL)).Msg("Failed to render notification template")
+		return
+	}
+	event.Message = message
+}
+
+// evaluate updates the channel's alert for the target and returns the event to send, if any
+func (c *channelState) evaluate(target config.Target, result checker.Result, now time.Time) (Event, bool) {
+	event := Event{Target: target, Result: result, Up: result.IsUp()}
+	current, down := c.alerts[target.URL]
+
+	if event.Up {
+		if !down {
+			return event, false
+		}
+		delete(c.alerts, target.URL)
+		event.Since = current.since
+		// Recoveries within the group wait were never reported
+		return event, !current.notified.IsZero() && c.Options.Resolved()
+	}
+
+	if !down {
+		current = &alert{since: now}
+		c.alerts[target.URL] = current
+	}
+	event.Since = current.since
+
+	switch {
+	case current.notified.IsZero():
+		if now.Sub(current.since) < c.Options.GroupWait {
+			return event, false
+		}
+	case c.Options.RepeatInterval > 0 && now.Sub(current.notified) >= c.Options.RepeatInterval:
+		event.Repeat = true
+	default:
+		return event, false
+	}
+
+	current.notified = now
+	return event, true
+}
+
+func send(ctx context.Context, receiver string, notifier Notifier, event Event) {
+	start := time.Now()
+	if err := notifier.Notify(ctx, event); err != nil {
+		log.Error().Err(err).
+			Str("receiver", receiver).
+			Str("notifier", notifier.Name()).
+			Str("url", config.RedactURL(event.Target.URL)).
+			Bool("up", event.Up).
+			Msg("Failed to send notification")
+		return
+	}
+
+	log.Info().
+		Str("receiver", receiver).
+		Str("notifier", notifier.Name()).
+		Str("url", config.RedactURL(event.Target.URL)).
+		Bool("up", event.Up).
+		Bool("repeat", event.Repeat).
+		Dur("duration", time.Since(start)).
+		Msg("Notification sent")
+}
+
+// summary is a one-line description of the event
+func summary(event Event) string {
+	switch {
+	case event.Up:
+		return config.RedactURL(event.Target.URL) + " is up"
+	case event.Repeat:
+		return config.RedactURL(event.Target.URL) + " is still down: " + failure(event.Result)
+	default:
+		return config.RedactURL(event.Target.URL) + " is down: " + failure(event.Result)
+	}
+}
+
+// failure describes why a check failed
+func failure(result checker.Result) string {
+	if result.Error != nil {
+		return result.Error.Error()
+	}
+	return "HTTP " + strconv.Itoa(result.StatusCode)
+}
@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	mutex  sync.Mutex
+	events []Event
+	err    error
+}
+
+func (r *recordingNotifier) Name() string {
+	return "recording"
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event Event) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestDispatcher_HandleCycle(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://a.example.com", Group: "web"},
+			{URL: "https://b.example.com"},
+		},
+		InstanceID: "test-instance",
+	}
+
+	notifier := &recordingNotifier{}
+	failing := &recordingNotifier{err: errors.New("unavailable")}
+	dispatcher, err := NewDispatcher(checker.New(cfg), "test-instance", Channel{Notifier: failing}, Channel{Notifier: notifier})
+	require.NoError(t, err)
+
+	up := func(url string) checker.Result {
+		return checker.Result{URL: url, StatusCode: 200, Timestamp: time.Now()}
+	}
+	down := func(url string) checker.Result {
+		return checker.Result{URL: url, Error: errors.New("refused"), Timestamp: time.Now()}
+	}
+
+	// Targets that are up when first checked are not reported, ones that are down are
+	dispatcher.HandleCycle(context.Background(), []checker.Result{up("https://a.example.com"), down("https://b.example.com")})
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, "https://b.example.com", notifier.events[0].Target.URL)
+	assert.False(t, notifier.events[0].Up)
+	assert.Equal(t, "test-instance", notifier.events[0].Instance)
+
+	// Unchanged states are not reported again
+	dispatcher.HandleCycle(context.Background(), []checker.Result{up("https://a.example.com"), down("https://b.example.com")})
+	require.Len(t, notifier.events, 1)
+
+	dispatcher.HandleCycle(context.Background(), []checker.Result{down("https://a.example.com"), up("https://b.example.com")})
+	require.Len(t, notifier.events, 3)
+	assert.Equal(t, "https://a.example.com", notifier.events[1].Target.URL)
+	assert.Equal(t, "web", notifier.events[1].Target.Group)
+	assert.False(t, notifier.events[1].Up)
+	assert.Equal(t, "https://b.example.com", notifier.events[2].Target.URL)
+	assert.True(t, notifier.events[2].Up)
+
+	// A failing notifier does not keep the others from being notified
+	assert.Len(t, failing.events, 3)
+
+	// Results of unregistered targets are ignored
+	dispatcher.HandleCycle(context.Background(), []checker.Result{down("https://unknown.example.com")})
+	assert.Len(t, notifier.events, 3)
+}
+
+func TestNew(t *testing.T) {
+	cfg := &config.Config{Targets: []string{"https://example.com"}, InstanceID: "test-instance"}
+	dispatcher, err := New(cfg, checker.New(cfg), nil)
+	require.NoError(t, err)
+	assert.Nil(t, dispatcher)
+
+	cfg.Notifications.PagerDuty = config.PagerDutyConfig{RoutingKey: "key", Severity: "critical", URL: config.DefaultPagerDutyURL}
+	dispatcher, err = New(cfg, checker.New(cfg), nil)
+	require.NoError(t, err)
+	require.NotNil(t, dispatcher)
+	require.Len(t, dispatcher.channels, 1)
+	assert.Equal(t, "pagerduty", dispatcher.channels[0].Notifier.Name())
+}
+
+func TestDispatcher_ChannelOptions(t *testing.T) {
+	cfg := &config.Config{Targets: []string{"https://example.com"}, InstanceID: "test-instance"}
+
+	noResolve := false
+	delayed := &recordingNotifier{}
+	repeating := &recordingNotifier{}
+	dispatcher, err := NewDispatcher(checker.New(cfg), "test-instance",
+		Channel{Notifier: delayed, Options: config.ChannelOptions{GroupWait: time.Minute}},
+		Channel{Notifier: repeating, Options: config.ChannelOptions{RepeatInterval: 10 * time.Minute, SendResolved: &noResolve}},
+	)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+
+	cycle := func(offset time.Duration, up bool) {
+		now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(offset)
+		result := checker.Result{URL: "https://example.com", StatusCode: 200}
+		if !up {
+			result.StatusCode = 503
+		}
+		dispatcher.HandleCycle(context.Background(), []checker.Result{result})
+	}
+
+	// A blip shorter than the group wait is not reported, nor is its recovery
+	cycle(0, false)
+	cycle(30*time.Second, true)
+	assert.Empty(t, delayed.events)
+	require.Len(t, repeating.events, 1)
+
+	cycle(time.Minute, false)
+	cycle(90*time.Second, false)
+	assert.Empty(t, delayed.events)
+
+	cycle(2*time.Minute, false)
+	require.Len(t, delayed.events, 1)
+	assert.False(t, delayed.events[0].Up)
+	assert.Equal(t, now.Add(-time.Minute), delayed.events[0].Since)
+
+	// Reports are repeated while the target stays down
+	require.Len(t, repeating.events, 2)
+	cycle(10*time.Minute, false)
+	assert.Len(t, repeating.events, 2)
+	cycle(11*time.Minute, false)
+	require.Len(t, repeating.events, 3)
+	assert.True(t, repeating.events[2].Repeat)
+	assert.Len(t, delayed.events, 1)
+
+	// Recoveries are only sent where enabled
+	cycle(12*time.Minute, true)
+	require.Len(t, delayed.events, 2)
+	assert.True(t, delayed.events[1].Up)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 1, 0, 0, time.UTC), delayed.events[1].Since)
+	assert.Len(t, repeating.events, 3)
+}
+
+type fixedStats struct{}
+
+func (fixedStats) CheckStats(url string) (int, int) {
+	return 200, 199
+}
+
+func TestDispatcher_Template(t *testing.T) {
+	cfg := &config.Config{
+		Checks:     []config.Target{{URL: "https://example.com", Name: "web", Labels: map[string]string{"team": "payments"}}},
+		InstanceID: "test-instance",
+	}
+	cfg.Notifications.ExternalURL = "https://exporter.example.com"
+
+	templated := &recordingNotifier{}
+	plain := &recordingNotifier{}
+	failing := &recordingNotifier{}
+	dispatcher, err := NewDispatcher(checker.New(cfg), "test-instance",
+		Channel{Notifier: templated, Options: config.ChannelOptions{Template: `
+{{ .Target.Name }} ({{ .Target.Labels.team }}) is {{ .Status }}{{ if .Error }}: {{ .Error }}{{ end }}
+uptime {{ printf "%.1f" .Stats.Uptime }}% over {{ .Stats.Checks }} checks, down for {{ .Downtime }}
+{{ .HistoryURL }}`}},
+		Channel{Notifier: plain},
+		Channel{Notifier: failing, Options: config.ChannelOptions{Template: `{{ .Target.URL.Missing }}`}},
+	)
+	require.NoError(t, err)
+	dispatcher.externalURL = cfg.Notifications.ExternalURL
+	dispatcher.stats = fixedStats{}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return start }
+	dispatcher.HandleCycle(context.Background(), []checker.Result{{URL: "https://example.com", StatusCode: 503, Timestamp: start}})
+
+	dispatcher.now = func() time.Time { return start.Add(90 * time.Second) }
+	dispatcher.HandleCycle(context.Background(), []checker.Result{{URL: "https://example.com", StatusCode: 200, Timestamp: start.Add(90 * time.Second)}})
+
+	require.Len(t, templated.events, 2)
+	assert.Equal(t, "web (payments) is down: HTTP 503\nuptime 99.5% over 200 checks, down for 0s\nhttps://exporter.example.com/api/v1/targets/web/history", templated.events[0].Message)
+	assert.Equal(t, "web (payments) is up\nuptime 99.5% over 200 checks, down for 1m30s\nhttps://exporter.example.com/api/v1/targets/web/history", templated.events[1].Message)
+
+	// Channels without a template, or whose template fails, use their default text
+	require.Len(t, plain.events, 2)
+	assert.Empty(t, plain.events[0].Message)
+	assert.Equal(t, Stats{Checks: 200, Successful: 199, Uptime: 99.5}, plain.events[0].Stats)
+	require.Len(t, failing.events, 2)
+	assert.Empty(t, failing.events[0].Message)
+
+	_, err = NewDispatcher(checker.New(cfg), "test-instance", Channel{Notifier: plain, Options: config.ChannelOptions{Template: "{{ .Status "}})
+	assert.Error(t, err)
+}
+
+func TestDispatcher_Routes(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://pay.example.com", Labels: map[string]string{"team": "payments"}},
+			{URL: "https://web.example.com"},
+		},
+		InstanceID: "test-instance",
+	}
+
+	ops := &recordingNotifier{}
+	payments := &recordingNotifier{}
+	dispatcher, err := NewDispatcher(checker.New(cfg), "test-instance",
+		Channel{Notifier: ops},
+		Channel{Receiver: "payments", Notifier: payments},
+	)
+	require.NoError(t, err)
+	dispatcher.route, err = newRoute(config.RouteConfig{
+		Routes: []config.RouteConfig{{Match: map[string]string{"team": "payments"}, Receiver: "payments"}},
+	}, config.DefaultReceiver)
+	require.NoError(t, err)
+
+	dispatcher.HandleCycle(context.Background(), []checker.Result{
+		{URL: "https://pay.example.com", StatusCode: 503},
+		{URL: "https://web.example.com", StatusCode: 503},
+	})
+
+	require.Len(t, payments.events, 1)
+	assert.Equal(t, "https://pay.example.com", payments.events[0].Target.URL)
+	require.Len(t, ops.events, 1)
+	assert.Equal(t, "https://web.example.com", ops.events[0].Target.URL)
+}
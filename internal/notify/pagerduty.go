@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jasoet/pkg/rest"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// maxPagerDutySummary is the longest summary the Events API accepts
+const maxPagerDutySummary = 1024
+
+// PagerDuty sends trigger events to the PagerDuty Events API v2 when a target goes down
+// and resolves them when it comes back up. Incidents are deduplicated per instance and URL.
+type PagerDuty struct {
+	config     config.PagerDutyConfig
+	instance   string
+	restClient *rest.Client
+}
+
+// pagerDutyEvent is the body of an Events API v2 request
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Client      string            `json:"client,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func NewPagerDuty(cfg config.PagerDutyConfig, instance string, timeout time.Duration) *PagerDuty {
+	restClient := rest.NewClient(rest.WithRestConfig(rest.Config{
+		RetryCount:    2,
+		RetryWaitTime: time.Second,
+		Timeout:       timeout,
+	}))
+
+	return &PagerDuty{
+		config:     cfg,
+		instance:   instance,
+		restClient: restClient,
+	}
+}
+
+func (p *PagerDuty) Name() string {
+	return "pagerduty"
+}
+
+// Notify sends the event with the routing key of the target's group. Targets without a
+// routing key are skipped.
+func (p *PagerDuty) Notify(ctx context.Context, event Event) error {
+	routingKey := p.config.RoutingKeyFor(event.Target.Group)
+	if routingKey == "" {
+		log.Debug().Str("url", config.RedactURL(event.Target.URL)).Str("group", event.Target.Group).Msg("No PagerDuty routing key for target")
+		return nil
+	}
+
+	body, err := json.Marshal(p.event(routingKey, event))
+	if err != nil {
+		return fmt.Errorf("failed to encode pagerduty event: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if _, err := p.restClient.MakeRequest(ctx, http.MethodPost, p.config.URL, string(body), headers); err != nil {
+		return fmt.Errorf("failed to send pagerduty event for %s: %w", config.RedactURL(event.Target.URL), err)
+	}
+
+	return nil
+}
+
+func (p *PagerDuty) event(routingKey string, event Event) pagerDutyEvent {
+	pdEvent := pagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: "resolve",
+		DedupKey:    "url-exporter/" + p.instance + "/" + config.RedactURL(event.Target.URL),
+		Client:      "url-exporter",
+	}
+	if event.Up {
+		return pdEvent
+	}
+
+	details := make(map[string]string, len(event.Target.Labels)+4)
+	for name, value := range event.Target.Labels {
+		details[name] = value
+	}
+	details["error"] = failure(event.Result)
+	details["status_code"] = strconv.Itoa(event.Result.StatusCode)
+	details["response_time_ms"] = strconv.FormatInt(event.Result.ResponseTime.Milliseconds(), 10)
+	if event.Target.Description != "" {
+		details["description"] = event.Target.Description
+	}
+
+	pdEvent.EventAction = "trigger"
+	pdEvent.Payload = &pagerDutyPayload{
+		Summary:       pagerDutySummary(event),
+		Source:        p.instance,
+		Severity:      p.config.Severity,
+		Component:     config.RedactURL(event.Target.URL),
+		Group:         event.Target.Group,
+		Class:         "url_check",
+		CustomDetails: details,
+	}
+	if !event.Result.Timestamp.IsZero() {
+		pdEvent.Payload.Timestamp = event.Result.Timestamp.UTC().Format(time.RFC3339)
+	}
+	if event.Target.RunbookURL != "" {
+		pdEvent.Links = []pagerDutyLink{{Href: event.Target.RunbookURL, Text: "Runbook"}}
+	}
+
+	return pdEvent
+}
+
+// pagerDutySummary is the templated message or the default summary, cut to the 1024
+// characters the Events API accepts
+func pagerDutySummary(event Event) string {
+	text := event.Message
+	if text == "" {
+		text = summary(event)
+	}
+
+	if runes := []rune(text); len(runes) > maxPagerDutySummary {
+		return string(runes[:maxPagerDutySummary])
+	}
+	return text
+}
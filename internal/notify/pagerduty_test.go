@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerDuty_Notify(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var event pagerDutyEvent
+		require.NoError(t, json.Unmarshal(body, &event))
+		events = append(events, event)
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pagerDuty := NewPagerDuty(config.PagerDutyConfig{
+		RoutingKey:  "default-key",
+		RoutingKeys: map[string]string{"payments": "payments-key"},
+		Severity:    "error",
+		URL:         server.URL,
+	}, "vm-01", time.Second)
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	target := config.Target{URL: "https://pay.example.com", Group: "Payments", Labels: map[string]string{"team": "payments"}}
+
+	down := Event{
+		Target:   target,
+		Result:   checker.Result{URL: target.URL, StatusCode: 503, ResponseTime: 120 * time.Millisecond, Timestamp: ts},
+		Instance: "vm-01",
+	}
+	require.NoError(t, pagerDuty.Notify(context.Background(), down))
+
+	up := Event{Target: target, Result: checker.Result{URL: target.URL, StatusCode: 200, Timestamp: ts}, Up: true, Instance: "vm-01"}
+	require.NoError(t, pagerDuty.Notify(context.Background(), up))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, pagerDutyEvent{
+		RoutingKey:  "payments-key",
+		EventAction: "trigger",
+		DedupKey:    "url-exporter/vm-01/https://pay.example.com",
+		Client:      "url-exporter",
+		Payload: &pagerDutyPayload{
+			Summary:   "https://pay.example.com is down: HTTP 503",
+			Source:    "vm-01",
+			Severity:  "error",
+			Timestamp: "2026-01-02T03:04:05Z",
+			Component: "https://pay.example.com",
+			Group:     "Payments",
+			Class:     "url_check",
+			CustomDetails: map[string]string{
+				"team":             "payments",
+				"error":            "HTTP 503",
+				"status_code":      "503",
+				"response_time_ms": "120",
+			},
+		},
+	}, events[0])
+	assert.Equal(t, pagerDutyEvent{
+		RoutingKey:  "payments-key",
+		EventAction: "resolve",
+		DedupKey:    "url-exporter/vm-01/https://pay.example.com",
+		Client:      "url-exporter",
+	}, events[1])
+
+	// Other groups use the default routing key
+	other := Event{Target: config.Target{URL: "https://web.example.com"}, Result: checker.Result{Error: errors.New("refused")}}
+	require.NoError(t, pagerDuty.Notify(context.Background(), other))
+	require.Len(t, events, 3)
+	assert.Equal(t, "default-key", events[2].RoutingKey)
+	assert.Equal(t, "https://web.example.com is down: refused", events[2].Payload.Summary)
+	assert.Empty(t, events[2].Payload.Timestamp)
+	assert.Empty(t, events[2].Links)
+
+	// The runbook is linked from the incident
+	documented := other
+	documented.Target.Description = "Public website"
+	documented.Target.RunbookURL = "https://wiki.example.com/runbooks/web"
+	require.NoError(t, pagerDuty.Notify(context.Background(), documented))
+	require.Len(t, events, 4)
+	assert.Equal(t, []pagerDutyLink{{Href: "https://wiki.example.com/runbooks/web", Text: "Runbook"}}, events[3].Links)
+	assert.Equal(t, "Public website", events[3].Payload.CustomDetails["description"])
+}
+
+func TestPagerDutySummary(t *testing.T) {
+	event := Event{Target: config.Target{URL: "https://example.com"}, Result: checker.Result{StatusCode: 503}}
+	assert.Equal(t, "https://example.com is down: HTTP 503", pagerDutySummary(event))
+
+	event.Message = strings.Repeat("ä", 2000)
+	assert.Equal(t, strings.Repeat("ä", maxPagerDutySummary), pagerDutySummary(event))
+}
+
+func TestPagerDuty_Notify_NoRoutingKey(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pagerDuty := NewPagerDuty(config.PagerDutyConfig{
+		RoutingKeys: map[string]string{"payments": "payments-key"},
+		Severity:    "critical",
+		URL:         server.URL,
+	}, "vm-01", time.Second)
+
+	event := Event{Target: config.Target{URL: "https://web.example.com", Group: "web"}}
+	require.NoError(t, pagerDuty.Notify(context.Background(), event))
+	assert.Zero(t, requests)
+}
+
+func TestPagerDuty_Notify_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	pagerDuty := NewPagerDuty(config.PagerDutyConfig{RoutingKey: "key", Severity: "critical", URL: server.URL}, "vm-01", time.Second)
+
+	err := pagerDuty.Notify(context.Background(), Event{Target: config.Target{URL: "https://example.com"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to send pagerduty event for https://example.com")
+}
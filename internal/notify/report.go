@@ -0,0 +1,283 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// Report is an availability summary of the targets over a period, grouped by target group
+type Report struct {
+	Schedule string // daily or weekly
+	From     time.Time
+	To       time.Time // exclusive
+	Instance string
+	Groups   []GroupReport
+}
+
+// GroupReport is the availability of a target group. Availability is the percentage of
+// successful checks of all its targets; Met and Breached count the targets that reached
+// or missed their objective.
+type GroupReport struct {
+	Name         string
+	Checks       int
+	Successful   int
+	Availability float64
+	Met          int
+	Breached     int
+	Targets      []TargetAvailability
+}
+
+// TargetAvailability is the availability of a target over the report period. Objective is
+// the ratio it is compared with, 0 for none.
+type TargetAvailability struct {
+	URL          string
+	Name         string
+	Checks       int
+	Successful   int
+	Availability float64
+	Objective    float64
+}
+
+// Breached reports whether the target missed its objective
+func (t TargetAvailability) Breached() bool {
+	return t.Objective > 0 && t.Checks > 0 && t.Availability < t.Objective*100
+}
+
+// Reporter is implemented by notifiers that can deliver availability reports
+type Reporter interface {
+	Notifier
+	Report(ctx context.Context, report Report) error
+}
+
+// DailySource provides the daily check counts of targets, such as the metrics collector
+type DailySource interface {
+	DailyUptime(url string, days int, now time.Time) []metrics.DailyUptime
+}
+
+// Reports sends the scheduled availability reports
+type Reports struct {
+	config    config.ReportsConfig
+	checker   *checker.Checker
+	source    DailySource
+	instance  string
+	reporters []Reporter
+	now       func() time.Time
+}
+
+// NewReports returns the report scheduler of the configuration, or nil when reports are
+// disabled or none of their receivers has a channel that can deliver them
+func NewReports(cfg *config.Config, chk *checker.Checker, source DailySource) *Reports {
+	reports := cfg.Notifications.Reports
+	if !reports.Enabled() {
+		return nil
+	}
+
+	var reporters []Reporter
+	for _, name := range reports.Receivers {
+		receiver := cfg.Notifications.ReceiverConfig
+		if name != config.DefaultReceiver {
+			receiver = cfg.Notifications.Receivers[name]
+		}
+		for _, channel := range receiverChannels(name, receiver, cfg) {
+			if reporter, ok := channel.Notifier.(Reporter); ok {
+				reporters = append(reporters, reporter)
+			}
+		}
+	}
+
+	if len(reporters) == 0 {
+		log.Warn().Strs("receivers", reports.Receivers).Msg("Reports are scheduled but no receiver has a Telegram or Teams channel")
+		return nil
+	}
+
+	return &Reports{
+		config:    reports,
+		checker:   chk,
+		source:    source,
+		instance:  cfg.InstanceID,
+		reporters: reporters,
+		now:       time.Now,
+	}
+}
+
+// Start sends the reports on schedule until the context is done
+func (r *Reports) Start(ctx context.Context) {
+	for {
+		next := r.next(r.now())
+		log.Debug().Time("next", next).Str("schedule", r.config.Schedule).Msg("Next availability report scheduled")
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		r.Send(ctx, r.Build(r.now()))
+	}
+}
+
+// next returns the first delivery time after now
+func (r *Reports) next(now time.Time) time.Time {
+	offset, _ := r.config.TimeOfDay()
+	weekday, _ := r.config.DeliveryDay()
+
+	next := now.UTC().Truncate(24 * time.Hour).Add(offset)
+	for !next.After(now) || (r.config.Schedule == config.ReportWeekly && next.Weekday() != weekday) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Build summarizes the full UTC days of the report period before now
+func (r *Reports) Build(now time.Time) Report {
+	days := 1
+	if r.config.Schedule == config.ReportWeekly {
+		days = 7
+	}
+
+	to := now.UTC().Truncate(24 * time.Hour)
+	report := Report{
+		Schedule: r.config.Schedule,
+		From:     to.AddDate(0, 0, -days),
+		To:       to,
+		Instance: r.instance,
+	}
+
+	groups := make(map[string]*GroupReport)
+	for _, target := range r.checker.Targets() {
+		if target.Disabled {
+			continue
+		}
+
+		availability := TargetAvailability{URL: target.URL, Name: target.Name, Objective: target.Objective}
+		if availability.Objective == 0 {
+			availability.Objective = r.config.SLA
+		}
+		// the series ends with today, which is not part of the period
+		series := r.source.DailyUptime(target.URL, days+1, now)
+		for _, day := range series[:days] {
+			availability.Checks += day.Checks
+			availability.Successful += day.Successful
+		}
+		if availability.Checks > 0 {
+			availability.Availability = float64(availability.Successful) / float64(availability.Checks) * 100
+		}
+
+		group, exists := groups[target.Group]
+		if !exists {
+			group = &GroupReport{Name: target.Group}
+			groups[target.Group] = group
+		}
+		group.Checks += availability.Checks
+		group.Successful += availability.Successful
+		if availability.Objective > 0 && availability.Checks > 0 {
+			if availability.Breached() {
+				group.Breached++
+			} else {
+				group.Met++
+			}
+		}
+		group.Targets = append(group.Targets, availability)
+	}
+
+	for _, group := range groups {
+		if group.Checks > 0 {
+			group.Availability = float64(group.Successful) / float64(group.Checks) * 100
+		}
+		sort.Slice(group.Targets, func(i, j int) bool { return group.Targets[i].URL < group.Targets[j].URL })
+		report.Groups = append(report.Groups, *group)
+	}
+	// Ungrouped targets come last
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if (report.Groups[i].Name == "") != (report.Groups[j].Name == "") {
+			return report.Groups[j].Name == ""
+		}
+		return report.Groups[i].Name < report.Groups[j].Name
+	})
+
+	return report
+}
+
+// Send delivers the report to every reporter, logging failures
+func (r *Reports) Send(ctx context.Context, report Report) {
+	for _, reporter := range r.reporters {
+		if err := reporter.Report(ctx, report); err != nil {
+			log.Error().Err(err).Str("notifier", reporter.Name()).Str("schedule", report.Schedule).Msg("Failed to send availability report")
+			continue
+		}
+		log.Info().Str("notifier", reporter.Name()).Str("schedule", report.Schedule).Msg("Availability report sent")
+	}
+}
+
+// reportTitle is the heading of the report, e.g. "Weekly availability report"
+func reportTitle(report Report) string {
+	if report.Schedule == config.ReportWeekly {
+		return "Weekly availability report"
+	}
+	return "Daily availability report"
+}
+
+// reportPeriod formats the days covered by the report
+func reportPeriod(report Report) string {
+	last := report.To.AddDate(0, 0, -1)
+	if !last.After(report.From) {
+		return report.From.Format("2006-01-02")
+	}
+	return report.From.Format("2006-01-02") + " – " + last.Format("2006-01-02")
+}
+
+// groupName is the displayed name of a report group
+func groupName(group GroupReport) string {
+	if group.Name == "" {
+		return "Ungrouped"
+	}
+	return group.Name
+}
+
+// targetName is the displayed name of a target in reports
+func targetName(target TargetAvailability) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	return config.RedactURL(target.URL)
+}
+
+// availabilityText formats the target's availability and how it compares with its
+// objective, e.g. "99.95% ✅ objective 99.90%"
+func availabilityText(target TargetAvailability) string {
+	if target.Checks == 0 {
+		return "no data"
+	}
+
+	text := fmt.Sprintf("%.2f%%", target.Availability)
+	if target.Objective == 0 {
+		return text
+	}
+	mark := "✅"
+	if target.Breached() {
+		mark = "❌"
+	}
+	return fmt.Sprintf("%s %s objective %.2f%%", text, mark, target.Objective*100)
+}
+
+// groupText formats the group's availability and objective counts
+func groupText(group GroupReport) string {
+	if group.Checks == 0 {
+		return "no data"
+	}
+
+	text := fmt.Sprintf("%.2f%%", group.Availability)
+	if group.Met+group.Breached > 0 {
+		text += fmt.Sprintf(", %d of %d objectives met", group.Met, group.Met+group.Breached)
+	}
+	return text
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedDaily map[string][]metrics.DailyUptime
+
+func (f fixedDaily) DailyUptime(url string, days int, now time.Time) []metrics.DailyUptime {
+	return metrics.DailySeries(f[url], days, now)
+}
+
+type recordingReporter struct {
+	recordingNotifier
+	reports []Report
+}
+
+func (r *recordingReporter) Report(_ context.Context, report Report) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestReports_Build(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://api.example.com", Name: "api", Group: "payments", Objective: 0.998},
+			{URL: "https://web.example.com", Group: "payments"},
+			{URL: "https://blog.example.com"},
+			{URL: "https://paused.example.com", Disabled: true},
+		},
+		InstanceID: "vm-01",
+	}
+
+	day := func(offset int) time.Time {
+		return time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC).AddDate(0, 0, offset)
+	}
+	source := fixedDaily{
+		"https://api.example.com": {
+			{Day: day(-8), Checks: 1000, Successful: 0}, // before the period
+			{Day: day(-7), Checks: 1000, Successful: 1000},
+			{Day: day(-1), Checks: 1000, Successful: 997},
+			{Day: day(0), Checks: 10, Successful: 0}, // today, after the period
+		},
+		"https://web.example.com": {{Day: day(-3), Checks: 1000, Successful: 990}},
+	}
+
+	reports := &Reports{
+		config:   config.ReportsConfig{Schedule: config.ReportWeekly, SLA: 0.995},
+		checker:  checker.New(cfg),
+		source:   source,
+		instance: "vm-01",
+	}
+
+	report := reports.Build(day(0).Add(8 * time.Hour))
+	assert.Equal(t, day(-7), report.From)
+	assert.Equal(t, day(0), report.To)
+	assert.Equal(t, "2026-03-02 – 2026-03-08", reportPeriod(report))
+
+	require.Len(t, report.Groups, 2)
+	payments := report.Groups[0]
+	assert.Equal(t, "payments", payments.Name)
+	assert.Equal(t, 3000, payments.Checks)
+	assert.InDelta(t, 99.57, payments.Availability, 0.01)
+	assert.Equal(t, 1, payments.Met)
+	assert.Equal(t, 1, payments.Breached)
+
+	api := payments.Targets[0]
+	assert.Equal(t, 2000, api.Checks)
+	assert.InDelta(t, 99.85, api.Availability, 0.001)
+	assert.Equal(t, 0.998, api.Objective)
+	assert.False(t, api.Breached())
+	assert.Equal(t, "99.85% ✅ objective 99.80%", availabilityText(api))
+
+	web := payments.Targets[1]
+	assert.Equal(t, 0.995, web.Objective)
+	assert.True(t, web.Breached())
+	assert.Equal(t, "99.00% ❌ objective 99.50%", availabilityText(web))
+
+	ungrouped := report.Groups[1]
+	assert.Equal(t, "Ungrouped", groupName(ungrouped))
+	require.Len(t, ungrouped.Targets, 1)
+	assert.Equal(t, "no data", availabilityText(ungrouped.Targets[0]))
+	assert.Equal(t, "no data", groupText(ungrouped))
+
+	daily := &Reports{config: config.ReportsConfig{Schedule: config.ReportDaily}, checker: checker.New(cfg), source: source}
+	report = daily.Build(day(0).Add(8 * time.Hour))
+	assert.Equal(t, "2026-03-08", reportPeriod(report))
+	assert.Equal(t, 1000, report.Groups[0].Targets[0].Checks)
+	assert.Equal(t, "99.70%", availabilityText(TargetAvailability{Checks: 1000, Availability: 99.7}))
+}
+
+func TestReports_Next(t *testing.T) {
+	weekly := &Reports{config: config.ReportsConfig{Schedule: config.ReportWeekly, Time: "08:30", Weekday: "monday"}}
+	daily := &Reports{config: config.ReportsConfig{Schedule: config.ReportDaily, Time: "08:30", Weekday: "monday"}}
+
+	// Wednesday
+	now := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 12, 8, 30, 0, 0, time.UTC), daily.next(now))
+	assert.Equal(t, time.Date(2026, 3, 16, 8, 30, 0, 0, time.UTC), weekly.next(now))
+
+	now = time.Date(2026, 3, 16, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 16, 8, 30, 0, 0, time.UTC), daily.next(now))
+	assert.Equal(t, time.Date(2026, 3, 16, 8, 30, 0, 0, time.UTC), weekly.next(now))
+
+	now = time.Date(2026, 3, 16, 8, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 23, 8, 30, 0, 0, time.UTC), weekly.next(now))
+}
+
+func TestNewReports(t *testing.T) {
+	cfg := &config.Config{Targets: []string{"https://example.com"}, InstanceID: "vm-01"}
+	assert.Nil(t, NewReports(cfg, checker.New(cfg), fixedDaily{}))
+
+	// PagerDuty cannot deliver reports
+	cfg.Notifications.PagerDuty = config.PagerDutyConfig{RoutingKey: "key", Severity: "critical", URL: config.DefaultPagerDutyURL}
+	cfg.Notifications.Reports = config.ReportsConfig{Schedule: config.ReportDaily, Time: "08:00", Weekday: "monday", Receivers: []string{config.DefaultReceiver}}
+	assert.Nil(t, NewReports(cfg, checker.New(cfg), fixedDaily{}))
+
+	cfg.Notifications.Receivers = map[string]config.ReceiverConfig{
+		"payments": {Teams: config.TeamsConfig{WebhookURL: "https://example.webhook.office.com/webhookb2/x"}},
+	}
+	cfg.Notifications.Reports.Receivers = []string{config.DefaultReceiver, "payments"}
+	reports := NewReports(cfg, checker.New(cfg), fixedDaily{})
+	require.NotNil(t, reports)
+	require.Len(t, reports.reporters, 1)
+	assert.Equal(t, "teams", reports.reporters[0].Name())
+
+	recorder := &recordingReporter{}
+	reports.reporters = []Reporter{recorder}
+	reports.Send(context.Background(), Report{Schedule: config.ReportDaily})
+	assert.Len(t, recorder.reports, 1)
+}
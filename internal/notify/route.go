@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// route is a compiled node of the notification routing tree
+type route struct {
+	receiver string
+	match    map[string]string
+	matchRE  map[string]*regexp.Regexp
+	cont     bool
+	routes   []*route
+}
+
+// newRoute compiles the routing tree. Receivers left empty are inherited from the parent.
+func newRoute(cfg config.RouteConfig, parent string) (*route, error) {
+	r := &route{
+		receiver: strings.ToLower(cfg.Receiver),
+		match:    make(map[string]string, len(cfg.Match)),
+		matchRE:  make(map[string]*regexp.Regexp, len(cfg.MatchRE)),
+		cont:     cfg.Continue,
+	}
+	if r.receiver == "" {
+		r.receiver = parent
+	}
+
+	for label, value := range cfg.Match {
+		r.match[strings.ToLower(label)] = value
+	}
+	for label, expr := range cfg.MatchRE {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid matchRe for %s: %w", label, err)
+		}
+		r.matchRE[strings.ToLower(label)] = re
+	}
+
+	for _, child := range cfg.Routes {
+		compiled, err := newRoute(child, r.receiver)
+		if err != nil {
+			return nil, err
+		}
+		r.routes = append(r.routes, compiled)
+	}
+
+	return r, nil
+}
+
+// matches reports whether the labels satisfy all of the node's matchers
+func (r *route) matches(labels map[string]string) bool {
+	for label, value := range r.match {
+		if labels[label] != value {
+			return false
+		}
+	}
+	for label, re := range r.matchRE {
+		if !re.MatchString(labels[label]) {
+			return false
+		}
+	}
+	return true
+}
+
+// receivers returns the receivers of the deepest matching nodes, assuming the node
+// itself matches
+func (r *route) receivers(labels map[string]string) []string {
+	var receivers []string
+	for _, child := range r.routes {
+		if !child.matches(labels) {
+			continue
+		}
+		for _, receiver := range child.receivers(labels) {
+			if !slices.Contains(receivers, receiver) {
+				receivers = append(receivers, receiver)
+			}
+		}
+		if !child.cont {
+			break
+		}
+	}
+
+	if receivers == nil {
+		return []string{r.receiver}
+	}
+	return receivers
+}
+
+// routeLabels are the labels a target is routed by: its labels, with names lower-cased
+// like those of the configuration, and its group as "group"
+func routeLabels(target config.Target) map[string]string {
+	labels := make(map[string]string, len(target.Labels)+1)
+	for name, value := range target.Labels {
+		labels[strings.ToLower(name)] = value
+	}
+	if target.Group != "" {
+		labels["group"] = target.Group
+	}
+	return labels
+}
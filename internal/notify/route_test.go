@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute_Receivers(t *testing.T) {
+	tree, err := newRoute(config.RouteConfig{
+		Routes: []config.RouteConfig{
+			{
+				Match:    map[string]string{"team": "payments"},
+				Receiver: "Payments",
+				Continue: true,
+				Routes: []config.RouteConfig{
+					{MatchRE: map[string]string{"env": "prod|staging"}, Receiver: "payments-oncall"},
+				},
+			},
+			{Match: map[string]string{"group": "edge"}, Receiver: "edge"},
+			{Match: map[string]string{"team": "payments"}, Receiver: "finance"},
+			{MatchRE: map[string]string{"env": ".+"}, Receiver: "unreached"},
+		},
+	}, config.DefaultReceiver)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		target   config.Target
+		expected []string
+	}{
+		{"unmatched targets use the root receiver", config.Target{URL: "https://a.example.com"}, []string{"default"}},
+		{"nested matches win over their parent", config.Target{URL: "https://b.example.com", Labels: map[string]string{"Team": "payments", "env": "prod"}}, []string{"payments-oncall", "finance"}},
+		{"regular expressions are anchored", config.Target{URL: "https://c.example.com", Labels: map[string]string{"team": "payments", "env": "production"}}, []string{"payments", "finance"}},
+		{"groups match as the group label", config.Target{URL: "https://d.example.com", Group: "edge", Labels: map[string]string{"env": "dev"}}, []string{"edge"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tree.receivers(routeLabels(tt.target)))
+		})
+	}
+
+	_, err = newRoute(config.RouteConfig{Routes: []config.RouteConfig{{MatchRE: map[string]string{"env": "("}}}}, config.DefaultReceiver)
+	assert.Error(t, err)
+}
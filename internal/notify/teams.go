@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Card colors of the down and up states
+const (
+	teamsColorDown = "D13438"
+	teamsColorUp   = "2EB886"
+)
+
+// Teams posts state change cards to a Microsoft Teams incoming webhook, colored by state
+// and linking to the target's history when the exporter's external URL is known
+type Teams struct {
+	config config.TeamsConfig
+	client *http.Client
+}
+
+// teamsCard is a connector MessageCard
+type teamsCard struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	ThemeColor      string         `json:"themeColor"`
+	Summary         string         `json:"summary"`
+	Title           string         `json:"title"`
+	Text            string         `json:"text,omitempty"`
+	Sections        []teamsSection `json:"sections,omitempty"`
+	PotentialAction []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+type teamsSection struct {
+	Title string      `json:"title,omitempty"`
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+func NewTeams(cfg config.TeamsConfig, timeout time.Duration) *Teams {
+	return &Teams{
+		config: cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *Teams) Name() string {
+	return "teams"
+}
+
+// Notify posts a card for the event to the webhook
+func (t *Teams) Notify(ctx context.Context, event Event) error {
+	if _, err := postJSON(ctx, t.client, t.config.WebhookURL, t.card(event)); err != nil {
+		return fmt.Errorf("failed to post teams card for %s: %w", config.RedactURL(event.Target.URL), err)
+	}
+	return nil
+}
+
+// Report posts the availability report as a card with a section per target group, red
+// when a target missed its objective
+func (t *Teams) Report(ctx context.Context, report Report) error {
+	if _, err := postJSON(ctx, t.client, t.config.WebhookURL, teamsReportCard(report)); err != nil {
+		return fmt.Errorf("failed to post teams report: %w", err)
+	}
+	return nil
+}
+
+func (t *Teams) card(event Event) teamsCard {
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: teamsColorUp,
+		Summary:    summary(event),
+		Title:      "UP: " + config.RedactURL(event.Target.URL),
+	}
+
+	if event.Target.RunbookURL != "" {
+		card.PotentialAction = append(card.PotentialAction, teamsAction{
+			Type:    "OpenUri",
+			Name:    "Open runbook",
+			Targets: []teamsTarget{{OS: "default", URI: event.Target.RunbookURL}},
+		})
+	}
+	if event.HistoryURL != "" {
+		card.PotentialAction = append(card.PotentialAction, teamsAction{
+			Type:    "OpenUri",
+			Name:    "View history",
+			Targets: []teamsTarget{{OS: "default", URI: event.HistoryURL}},
+		})
+	}
+
+	var facts []teamsFact
+	if !event.Up {
+		card.ThemeColor = teamsColorDown
+		card.Title = "DOWN: " + config.RedactURL(event.Target.URL)
+		facts = append(facts, teamsFact{Name: "Error", Value: failure(event.Result)})
+	}
+
+	// A templated message replaces the facts
+	if event.Message != "" {
+		card.Text = event.Message
+		return card
+	}
+
+	if event.Result.StatusCode != 0 {
+		facts = append(facts, teamsFact{Name: "Status code", Value: strconv.Itoa(event.Result.StatusCode)})
+	}
+	if event.Target.Group != "" {
+		facts = append(facts, teamsFact{Name: "Group", Value: event.Target.Group})
+	}
+	if event.Target.Description != "" {
+		facts = append(facts, teamsFact{Name: "Description", Value: event.Target.Description})
+	}
+
+	labels := make([]string, 0, len(event.Target.Labels))
+	for name := range event.Target.Labels {
+		labels = append(labels, name)
+	}
+	sort.Strings(labels)
+	for _, name := range labels {
+		facts = append(facts, teamsFact{Name: name, Value: event.Target.Labels[name]})
+	}
+
+	facts = append(facts, teamsFact{Name: "Instance", Value: event.Instance})
+	if !event.Result.Timestamp.IsZero() {
+		facts = append(facts, teamsFact{Name: "Checked at", Value: event.Result.Timestamp.UTC().Format(time.RFC3339)})
+	}
+	card.Sections = []teamsSection{{Facts: facts}}
+
+	return card
+}
+
+func teamsReportCard(report Report) teamsCard {
+	title := reportTitle(report)
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: teamsColorUp,
+		Summary:    title + " " + reportPeriod(report),
+		Title:      title + ": " + reportPeriod(report),
+		Text:       "Instance: " + report.Instance,
+	}
+
+	for _, group := range report.Groups {
+		if group.Breached > 0 {
+			card.ThemeColor = teamsColorDown
+		}
+
+		section := teamsSection{Title: groupName(group) + ": " + groupText(group)}
+		for _, target := range group.Targets {
+			section.Facts = append(section.Facts, teamsFact{Name: targetName(target), Value: availabilityText(target)})
+		}
+		card.Sections = append(card.Sections, section)
+	}
+
+	return card
+}
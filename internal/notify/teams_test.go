@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeams_Notify(t *testing.T) {
+	var cards []teamsCard
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/webhookb2/secret", r.URL.Path)
+
+		var card teamsCard
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&card))
+		cards = append(cards, card)
+
+		_, _ = w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	teams := NewTeams(config.TeamsConfig{WebhookURL: server.URL + "/webhookb2/secret"}, time.Second)
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	target := config.Target{URL: "https://example.com/health", Group: "web", Labels: map[string]string{"team": "payments", "env": "prod"}}
+
+	down := Event{Target: target, Result: checker.Result{StatusCode: 503, Timestamp: ts}, Instance: "vm-01"}
+	down.HistoryURL = historyLink("https://exporter.example.com/", down)
+	require.NoError(t, teams.Notify(context.Background(), down))
+
+	require.Len(t, cards, 1)
+	assert.Equal(t, teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: teamsColorDown,
+		Summary:    "https://example.com/health is down: HTTP 503",
+		Title:      "DOWN: https://example.com/health",
+		Sections: []teamsSection{{Facts: []teamsFact{
+			{Name: "Error", Value: "HTTP 503"},
+			{Name: "Status code", Value: "503"},
+			{Name: "Group", Value: "web"},
+			{Name: "env", Value: "prod"},
+			{Name: "team", Value: "payments"},
+			{Name: "Instance", Value: "vm-01"},
+			{Name: "Checked at", Value: "2026-01-02T03:04:05Z"},
+		}}},
+		PotentialAction: []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "View history",
+			Targets: []teamsTarget{{OS: "default", URI: "https://exporter.example.com/api/v1/targets/https:%2F%2Fexample.com%2Fhealth/history"}},
+		}},
+	}, cards[0])
+
+	// Named targets are linked by name; without an external URL there is no link
+	up := Event{Target: config.Target{URL: "https://example.com", Name: "web"}, Result: checker.Result{StatusCode: 200}, Up: true, Instance: "vm-01"}
+	require.NoError(t, teams.Notify(context.Background(), up))
+
+	require.Len(t, cards, 2)
+	assert.Equal(t, teamsColorUp, cards[1].ThemeColor)
+	assert.Equal(t, "UP: https://example.com", cards[1].Title)
+	assert.Empty(t, cards[1].PotentialAction)
+	assert.Equal(t, "https://exporter.example.com/api/v1/targets/web/history", historyLink("https://exporter.example.com", up))
+	assert.Empty(t, historyLink("", up))
+
+	// A templated message replaces the facts
+	down.Message = "payments: example.com is down"
+	require.NoError(t, teams.Notify(context.Background(), down))
+
+	require.Len(t, cards, 3)
+	assert.Equal(t, "payments: example.com is down", cards[2].Text)
+	assert.Empty(t, cards[2].Sections)
+	assert.Equal(t, teamsColorDown, cards[2].ThemeColor)
+	assert.Len(t, cards[2].PotentialAction, 1)
+}
+
+func TestTeams_Notify_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	teams := NewTeams(config.TeamsConfig{WebhookURL: server.URL + "/webhookb2/secret"}, time.Second)
+
+	err := teams.Notify(context.Background(), Event{Target: config.Target{URL: "https://example.com"}, Result: checker.Result{Error: errors.New("refused")}})
+	require.Error(t, err)
+	assert.Equal(t, "failed to post teams card for https://example.com: HTTP 400", err.Error())
+}
+
+func TestTeamsReportCard(t *testing.T) {
+	report := Report{
+		Schedule: config.ReportWeekly,
+		From:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		Instance: "vm-01",
+		Groups: []GroupReport{
+			{Name: "payments", Checks: 2000, Successful: 1990, Availability: 99.5, Met: 1, Breached: 1, Targets: []TargetAvailability{
+				{URL: "https://api.example.com", Name: "api", Checks: 1000, Successful: 1000, Availability: 100, Objective: 0.999},
+				{URL: "https://web.example.com", Checks: 1000, Successful: 990, Availability: 99, Objective: 0.995},
+			}},
+			{Targets: []TargetAvailability{{URL: "https://blog.example.com"}}},
+		},
+	}
+
+	card := teamsReportCard(report)
+	assert.Equal(t, "Weekly availability report: 2026-03-02 – 2026-03-08", card.Title)
+	assert.Equal(t, "Instance: vm-01", card.Text)
+	assert.Equal(t, teamsColorDown, card.ThemeColor)
+	assert.Equal(t, []teamsSection{
+		{Title: "payments: 99.50%, 1 of 2 objectives met", Facts: []teamsFact{
+			{Name: "api", Value: "100.00% ✅ objective 99.90%"},
+			{Name: "https://web.example.com", Value: "99.00% ❌ objective 99.50%"},
+		}},
+		{Title: "Ungrouped: no data", Facts: []teamsFact{{Name: "https://blog.example.com", Value: "no data"}}},
+	}, card.Sections)
+
+	report.Groups[0].Breached = 0
+	assert.Equal(t, teamsColorUp, teamsReportCard(report).ThemeColor)
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Telegram sends state change messages through the Telegram Bot API. It uses a plain HTTP
+// client instead of the rest client so the bot token, which is part of the request URL,
+// never appears in logs.
+type Telegram struct {
+	config config.TelegramConfig
+	client *http.Client
+}
+
+// telegramMessage is the body of a sendMessage request
+type telegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+func NewTelegram(cfg config.TelegramConfig, timeout time.Duration) *Telegram {
+	return &Telegram{
+		config: cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+// Notify sends the event to every configured chat
+func (t *Telegram) Notify(ctx context.Context, event Event) error {
+	text := telegramText(event)
+
+	var errs []error
+	for _, chatID := range t.config.ChatIDs {
+		if err := t.send(ctx, telegramMessage{ChatID: chatID, Text: text, DisableWebPagePreview: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send telegram message to chat %s: %w", chatID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Report sends the availability report to every configured chat
+func (t *Telegram) Report(ctx context.Context, report Report) error {
+	text := telegramReportText(report)
+
+	var errs []error
+	for _, chatID := range t.config.ChatIDs {
+		if err := t.send(ctx, telegramMessage{ChatID: chatID, Text: text, DisableWebPagePreview: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send telegram report to chat %s: %w", chatID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *Telegram) send(ctx context.Context, message telegramMessage) error {
+	endpoint := strings.TrimRight(t.config.URL, "/") + "/bot" + t.config.BotToken + "/sendMessage"
+
+	reply, err := postJSON(ctx, t.client, endpoint, message)
+	if err != nil && reply != nil {
+		var failure struct {
+			Description string `json:"description"`
+		}
+		if json.Unmarshal(reply, &failure) == nil && failure.Description != "" {
+			return fmt.Errorf("telegram returned %w: %s", err, failure.Description)
+		}
+	}
+	return err
+}
+
+// telegramText formats the event as a plain-text message, unless a template rendered one
+func telegramText(event Event) string {
+	if event.Message != "" {
+		return event.Message
+	}
+
+	var b strings.Builder
+	switch {
+	case event.Up:
+		b.WriteString("🟢 UP " + config.RedactURL(event.Target.URL))
+	case event.Repeat:
+		b.WriteString("🔴 STILL DOWN " + config.RedactURL(event.Target.URL))
+		b.WriteString("\nError: " + failure(event.Result))
+	default:
+		b.WriteString("🔴 DOWN " + config.RedactURL(event.Target.URL))
+		b.WriteString("\nError: " + failure(event.Result))
+	}
+	if event.Target.Group != "" {
+		b.WriteString("\nGroup: " + event.Target.Group)
+	}
+	if event.Target.Description != "" {
+		b.WriteString("\nDescription: " + event.Target.Description)
+	}
+	if event.Target.RunbookURL != "" {
+		b.WriteString("\nRunbook: " + event.Target.RunbookURL)
+	}
+	b.WriteString("\nInstance: " + event.Instance)
+	return b.String()
+}
+
+// telegramReportText formats the availability report as a plain-text message
+func telegramReportText(report Report) string {
+	var b strings.Builder
+	b.WriteString("📊 " + reportTitle(report))
+	b.WriteString("\n" + reportPeriod(report) + " · Instance: " + report.Instance)
+	for _, group := range report.Groups {
+		b.WriteString("\n\n" + groupName(group) + ": " + groupText(group))
+		for _, target := range group.Targets {
+			b.WriteString("\n• " + targetName(target) + ": " + availabilityText(target))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegram_Notify(t *testing.T) {
+	var paths []string
+	var messages []telegramMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+
+		var message telegramMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		messages = append(messages, message)
+
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	telegram := NewTelegram(config.TelegramConfig{BotToken: "123:abc", ChatIDs: []string{"42", "@status"}, URL: server.URL + "/"}, time.Second)
+
+	event := Event{
+		Target:   config.Target{URL: "https://example.com", Group: "web"},
+		Result:   checker.Result{URL: "https://example.com", Error: errors.New("connection refused")},
+		Instance: "vm-01",
+	}
+	require.NoError(t, telegram.Notify(context.Background(), event))
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, []string{"/bot123:abc/sendMessage", "/bot123:abc/sendMessage"}, paths)
+	assert.Equal(t, "42", messages[0].ChatID)
+	assert.Equal(t, "@status", messages[1].ChatID)
+	assert.Equal(t, "🔴 DOWN https://example.com\nError: connection refused\nGroup: web\nInstance: vm-01", messages[0].Text)
+	assert.True(t, messages[0].DisableWebPagePreview)
+
+	event.Repeat = true
+	assert.Equal(t, "🔴 STILL DOWN https://example.com\nError: connection refused\nGroup: web\nInstance: vm-01", telegramText(event))
+
+	event.Up = true
+	event.Target.Group = ""
+	assert.Equal(t, "🟢 UP https://example.com\nInstance: vm-01", telegramText(event))
+
+	event.Message = "example.com is back"
+	assert.Equal(t, "example.com is back", telegramText(event))
+}
+
+func TestTelegram_Notify_Error(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	url := closed.URL
+	closed.Close()
+
+	telegram := NewTelegram(config.TelegramConfig{BotToken: "123:secret", ChatIDs: []string{"42"}, URL: url}, time.Second)
+
+	// Transport errors do not reveal the bot token
+	err := telegram.Notify(context.Background(), Event{Target: config.Target{URL: "https://example.com"}})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+	}))
+	defer server.Close()
+
+	telegram = NewTelegram(config.TelegramConfig{BotToken: "123:secret", ChatIDs: []string{"42"}, URL: server.URL}, time.Second)
+	err = telegram.Notify(context.Background(), Event{Target: config.Target{URL: "https://example.com"}})
+	require.Error(t, err)
+	assert.Equal(t, "failed to send telegram message to chat 42: telegram returned HTTP 400: Bad Request: chat not found", err.Error())
+}
+
+func TestTelegramReportText(t *testing.T) {
+	report := Report{
+		Schedule: config.ReportWeekly,
+		From:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		Instance: "vm-01",
+		Groups: []GroupReport{
+			{Name: "payments", Checks: 2000, Successful: 1990, Availability: 99.5, Met: 1, Breached: 1, Targets: []TargetAvailability{
+				{URL: "https://api.example.com", Name: "api", Checks: 1000, Successful: 1000, Availability: 100, Objective: 0.999},
+				{URL: "https://web.example.com", Checks: 1000, Successful: 990, Availability: 99, Objective: 0.995},
+			}},
+			{Targets: []TargetAvailability{{URL: "https://blog.example.com"}}},
+		},
+	}
+
+	assert.Equal(t, "📊 Weekly availability report\n2026-03-02 – 2026-03-08 · Instance: vm-01"+
+		"\n\npayments: 99.50%, 1 of 2 objectives met"+
+		"\n• api: 100.00% ✅ objective 99.90%"+
+		"\n• https://web.example.com: 99.00% ❌ objective 99.50%"+
+		"\n\nUngrouped: no data"+
+		"\n• https://blog.example.com: no data", telegramReportText(report))
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is what a channel's message template is executed with. Besides the event
+// fields, such as .Target.URL, .Target.Labels, .Result.StatusCode and .Stats.Uptime, it
+// offers the state as "up" or "down", the check error and how long the target was down.
+type templateData struct {
+	Event
+	Status   string
+	Error    string
+	Downtime time.Duration
+}
+
+// parseTemplate parses a channel's message template, returning nil for an empty one
+func parseTemplate(name, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// render executes the template for the event
+func render(tmpl *template.Template, event Event) (string, error) {
+	data := templateData{Event: event, Status: "up"}
+	if !event.Up {
+		data.Status = "down"
+		data.Error = failure(event.Result)
+	}
+	if !event.Since.IsZero() && !event.Result.Timestamp.IsZero() {
+		data.Downtime = event.Result.Timestamp.Sub(event.Since).Round(time.Second)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
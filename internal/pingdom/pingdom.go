@@ -0,0 +1,221 @@
+// Package pingdom reads the uptime checks of Pingdom through its API 3.1 and converts them
+// into exporter checks, to consolidate the monitoring onto the exporter.
+package pingdom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// DefaultEndpoint is the base URL of the Pingdom API
+const DefaultEndpoint = "https://api.pingdom.com/api/3.1"
+
+// IDLabel is the label of the imported checks holding the ID of their Pingdom check
+const IDLabel = "pingdom_check_id"
+
+// statusPaused is the status of paused checks
+const statusPaused = "paused"
+
+// Check is the definition of a Pingdom check. Type holds the settings of the check's type
+// under the type's name, e.g. http or tcp.
+type Check struct {
+	ID         int                        `json:"id"`
+	Name       string                     `json:"name"`
+	Hostname   string                     `json:"hostname"`
+	Resolution int                        `json:"resolution"` // minutes between checks
+	Status     string                     `json:"status"`
+	Type       map[string]json.RawMessage `json:"type"`
+}
+
+// httpSettings are the settings of http checks
+type httpSettings struct {
+	URL              string `json:"url"`
+	Encryption       bool   `json:"encryption"`
+	Port             int    `json:"port"`
+	ShouldContain    string `json:"shouldcontain"`
+	ShouldNotContain string `json:"shouldnotcontain"`
+	PostData         string `json:"postdata"`
+}
+
+// tcpSettings are the settings of tcp checks
+type tcpSettings struct {
+	Port           int    `json:"port"`
+	StringToSend   string `json:"stringtosend"`
+	StringToExpect string `json:"stringtoexpect"`
+}
+
+type listResponse struct {
+	Checks []struct {
+		ID int `json:"id"`
+	} `json:"checks"`
+}
+
+type detailResponse struct {
+	Check Check `json:"check"`
+}
+
+type errorResponse struct {
+	Error struct {
+		StatusCode   int    `json:"statuscode"`
+		StatusDesc   string `json:"statusdesc"`
+		ErrorMessage string `json:"errormessage"`
+	} `json:"error"`
+}
+
+// Client reads checks from the Pingdom API with an API token
+type Client struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewClient creates a client of the API at the endpoint, DefaultEndpoint outside tests
+func NewClient(endpoint, token string, timeout time.Duration) *Client {
+	return &Client{
+		endpoint: endpoint,
+		token:    token,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Checks lists the definitions of every check of the account
+func (c *Client) Checks(ctx context.Context) ([]Check, error) {
+	var list listResponse
+	if err := c.get(ctx, "/checks?limit=25000", &list); err != nil {
+		return nil, fmt.Errorf("failed to list checks: %w", err)
+	}
+
+	checks := make([]Check, 0, len(list.Checks))
+	for _, item := range list.Checks {
+		var detail detailResponse
+		if err := c.get(ctx, "/checks/"+strconv.Itoa(item.ID), &detail); err != nil {
+			return nil, fmt.Errorf("failed to read check %d: %w", item.ID, err)
+		}
+		checks = append(checks, detail.Check)
+	}
+	return checks, nil
+}
+
+// get decodes the JSON response to the path into out
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		if json.Unmarshal(content, &apiErr) == nil && apiErr.Error.ErrorMessage != "" {
+			return fmt.Errorf("%s: %s", apiErr.Error.StatusDesc, apiErr.Error.ErrorMessage)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// Convert converts the http and tcp checks into checks of the same URL and interval.
+// Paused checks are imported disabled. The returned warnings explain the checks that were
+// skipped or imported with differences.
+func Convert(pingdomChecks []Check) ([]config.Target, []string) {
+	var (
+		checks   []config.Target
+		warnings []string
+	)
+	warn := func(check Check, format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf("check %d (%s): ", check.ID, check.Name)+fmt.Sprintf(format, args...))
+	}
+	taken := make(map[string]bool)
+
+	for _, check := range pingdomChecks {
+		var target config.Target
+		switch {
+		case check.Type["http"] != nil:
+			var settings httpSettings
+			if err := json.Unmarshal(check.Type["http"], &settings); err != nil {
+				warn(check, "invalid http settings, skipped")
+				continue
+			}
+			target = httpTarget(check.Hostname, settings)
+			if settings.ShouldNotContain != "" {
+				warn(check, "shouldnotcontain has no equivalent and was not imported")
+			}
+			if settings.PostData != "" {
+				warn(check, "postdata was not imported, the check uses GET")
+			}
+		case check.Type["tcp"] != nil:
+			var settings tcpSettings
+			if err := json.Unmarshal(check.Type["tcp"], &settings); err != nil || settings.Port == 0 {
+				warn(check, "invalid tcp settings, skipped")
+				continue
+			}
+			target.URL = "tcp://" + net.JoinHostPort(check.Hostname, strconv.Itoa(settings.Port))
+			if settings.StringToSend != "" || settings.StringToExpect != "" {
+				warn(check, "stringtosend and stringtoexpect have no equivalent and were not imported")
+			}
+		default:
+			warn(check, "type %s has no equivalent, skipped", strings.Join(slices.Sorted(maps.Keys(check.Type)), ","))
+			continue
+		}
+
+		target.Name = check.Name
+		if taken[target.Name] {
+			target.Name = fmt.Sprintf("%s-%d", check.Name, check.ID)
+		}
+		if target.Name != "" {
+			taken[target.Name] = true
+		}
+		target.Labels = map[string]string{IDLabel: strconv.Itoa(check.ID)}
+		target.Disabled = check.Status == statusPaused
+		if check.Resolution > 0 {
+			target.Interval = strconv.Itoa(check.Resolution) + "m"
+		}
+		checks = append(checks, target)
+	}
+
+	return checks, warnings
+}
+
+// httpTarget builds the target of an http check
+func httpTarget(hostname string, settings httpSettings) config.Target {
+	endpoint := url.URL{Scheme: "http", Host: hostname}
+	defaultPort := 80
+	if settings.Encryption {
+		endpoint.Scheme, defaultPort = "https", 443
+	}
+	if settings.Port != 0 && settings.Port != defaultPort {
+		endpoint.Host = net.JoinHostPort(hostname, strconv.Itoa(settings.Port))
+	}
+	target := config.Target{URL: endpoint.String() + settings.URL}
+	if settings.ShouldContain != "" {
+		target.Method = http.MethodGet
+		target.ExpectBody = regexp.QuoteMeta(settings.ShouldContain)
+	}
+	return target
+}
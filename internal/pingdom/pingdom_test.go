@@ -0,0 +1,78 @@
+package pingdom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Checks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":{"statuscode":403,"statusdesc":"Forbidden","errormessage":"Invalid token"}}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/checks":
+			fmt.Fprint(w, `{"checks":[{"id":1,"name":"web","type":"http"},{"id":2,"name":"db","type":"tcp"}]}`)
+		case "/checks/1":
+			fmt.Fprint(w, `{"check":{"id":1,"name":"web","hostname":"example.com","resolution":5,"status":"up","type":{"http":{"url":"/health","encryption":true,"port":443}}}}`)
+		case "/checks/2":
+			fmt.Fprint(w, `{"check":{"id":2,"name":"db","hostname":"db.example.com","resolution":1,"status":"paused","type":{"tcp":{"port":5432}}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checks, err := NewClient(server.URL, "token", 5*time.Second).Checks(context.Background())
+	require.NoError(t, err)
+	require.Len(t, checks, 2)
+	assert.Equal(t, "example.com", checks[0].Hostname)
+	assert.Equal(t, 5, checks[0].Resolution)
+	assert.JSONEq(t, `{"port":5432}`, string(checks[1].Type["tcp"]))
+
+	_, err = NewClient(server.URL, "wrong", 5*time.Second).Checks(context.Background())
+	assert.ErrorContains(t, err, "Forbidden: Invalid token")
+}
+
+func TestConvert(t *testing.T) {
+	check := func(id int, name, hostname, status string, resolution int, kind, settings string) Check {
+		return Check{ID: id, Name: name, Hostname: hostname, Status: status, Resolution: resolution, Type: map[string]json.RawMessage{kind: json.RawMessage(settings)}}
+	}
+	checks := []Check{
+		check(1, "web", "example.com", "up", 5, "http", `{"url":"/health?deep=1","encryption":true,"port":443,"shouldcontain":"ok (v1)"}`),
+		check(2, "web", "api.example.com", "paused", 1, "http", `{"url":"/","encryption":false,"port":8080,"shouldnotcontain":"error","postdata":"a=b"}`),
+		check(3, "db", "db.example.com", "up", 1, "tcp", `{"port":5432,"stringtoexpect":"ready"}`),
+		check(4, "ping", "example.com", "up", 1, "ping", `{}`),
+		check(5, "broken", "example.com", "up", 1, "tcp", `{}`),
+	}
+
+	targets, warnings := Convert(checks)
+
+	assert.Equal(t, []config.Target{
+		{URL: "https://example.com/health?deep=1", Name: "web", Labels: map[string]string{IDLabel: "1"}, Method: "GET", ExpectBody: `ok \(v1\)`, Interval: "5m"},
+		{URL: "http://api.example.com:8080/", Name: "web-2", Labels: map[string]string{IDLabel: "2"}, Interval: "1m", Disabled: true},
+		{URL: "tcp://db.example.com:5432", Name: "db", Labels: map[string]string{IDLabel: "3"}, Interval: "1m"},
+	}, targets)
+	assert.Equal(t, []string{
+		"check 2 (web): shouldnotcontain has no equivalent and was not imported",
+		"check 2 (web): postdata was not imported, the check uses GET",
+		"check 3 (db): stringtosend and stringtoexpect have no equivalent and were not imported",
+		"check 4 (ping): type ping has no equivalent, skipped",
+		"check 5 (broken): invalid tcp settings, skipped",
+	}, warnings)
+
+	for _, target := range targets {
+		assert.NoError(t, target.Validate(), target.URL)
+	}
+}
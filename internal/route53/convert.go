@@ -0,0 +1,163 @@
+package route53
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// IDLabel is the label of the imported checks holding the ID of their health check
+const IDLabel = "route53_health_check_id"
+
+// Route53 health check types
+const (
+	typeHTTP            = "HTTP"
+	typeHTTPS           = "HTTPS"
+	typeHTTPStrMatch    = "HTTP_STR_MATCH"
+	typeHTTPSStrMatch   = "HTTPS_STR_MATCH"
+	typeTCP             = "TCP"
+	typeCalculated      = "CALCULATED"
+	typeCloudWatch      = "CLOUDWATCH_METRIC"
+	typeRecoveryControl = "RECOVERY_CONTROL"
+)
+
+// Import is the result of converting health checks
+type Import struct {
+	Checks     []config.Target
+	Composites []config.Composite
+	// Warnings explain the health checks that were skipped or imported with differences
+	Warnings []string
+}
+
+// Convert converts the health checks into checks and, for calculated health checks,
+// composites of them. The checks are named by the Name tag of their health check, or its
+// ID, and carry the ID in IDLabel.
+func Convert(healthChecks []HealthCheck) Import {
+	var result Import
+	names := make(map[string]string) // health check ID -> check name
+	taken := make(map[string]bool)
+
+	name := func(check HealthCheck) string {
+		name := check.Tags["Name"]
+		if name == "" || taken[name] {
+			name = check.ID
+		}
+		taken[name] = true
+		return name
+	}
+
+	var calculated []HealthCheck
+	for _, check := range healthChecks {
+		cfg := check.Config
+		switch cfg.Type {
+		case typeCalculated:
+			calculated = append(calculated, check)
+			continue
+		case typeCloudWatch, typeRecoveryControl:
+			result.warn(check, "type %s has no equivalent, skipped", cfg.Type)
+			continue
+		}
+		if cfg.Inverted {
+			result.warn(check, "inverted health checks have no equivalent, skipped")
+			continue
+		}
+
+		target, err := convertEndpoint(cfg)
+		if err != nil {
+			result.warn(check, "%v, skipped", err)
+			continue
+		}
+		if cfg.IPAddress != "" && cfg.FullyQualifiedDomainName != "" {
+			result.warn(check, "checks %s instead of the IP address %s", cfg.FullyQualifiedDomainName, cfg.IPAddress)
+		}
+
+		target.Name = name(check)
+		target.Labels = map[string]string{IDLabel: check.ID}
+		target.Disabled = cfg.Disabled
+		if cfg.RequestInterval > 0 {
+			target.Interval = (time.Duration(cfg.RequestInterval) * time.Second).String()
+		}
+		names[check.ID] = target.Name
+		result.Checks = append(result.Checks, target)
+	}
+
+	for _, check := range calculated {
+		composite := config.Composite{Name: name(check), Mode: config.CompositeQuorum, Quorum: check.Config.HealthThreshold}
+		for _, child := range check.Config.ChildHealthChecks {
+			if member, exists := names[child]; exists {
+				composite.Members = append(composite.Members, member)
+			} else {
+				result.warn(check, "child health check %s was not imported", child)
+			}
+		}
+		if len(composite.Members) == 0 {
+			result.warn(check, "no child health check was imported, skipped")
+			continue
+		}
+		if composite.Quorum < 1 || composite.Quorum > len(composite.Members) {
+			result.warn(check, "health threshold %d does not fit its %d imported children, skipped", composite.Quorum, len(composite.Members))
+			continue
+		}
+		result.Composites = append(result.Composites, composite)
+	}
+
+	return result
+}
+
+func (i *Import) warn(check HealthCheck, format string, args ...any) {
+	i.Warnings = append(i.Warnings, fmt.Sprintf("health check %s: ", check.ID)+fmt.Sprintf(format, args...))
+}
+
+// convertEndpoint converts the endpoint of an HTTP, HTTPS or TCP health check into a
+// check of the same URL
+func convertEndpoint(cfg HealthCheckConfig) (config.Target, error) {
+	host := cfg.FullyQualifiedDomainName
+	if host == "" {
+		host = cfg.IPAddress
+	}
+	if host == "" {
+		return config.Target{}, fmt.Errorf("no domain name or IP address")
+	}
+
+	var scheme string
+	defaultPort := 0
+	switch cfg.Type {
+	case typeHTTP, typeHTTPStrMatch:
+		scheme, defaultPort = "http", 80
+	case typeHTTPS, typeHTTPSStrMatch:
+		scheme, defaultPort = "https", 443
+	case typeTCP:
+		scheme = "tcp"
+		if cfg.Port == 0 {
+			return config.Target{}, fmt.Errorf("TCP health check without port")
+		}
+	default:
+		return config.Target{}, fmt.Errorf("unknown type %q", cfg.Type)
+	}
+
+	endpoint := url.URL{Scheme: scheme, Host: host}
+	switch {
+	case cfg.Port != 0 && cfg.Port != defaultPort:
+		endpoint.Host = net.JoinHostPort(host, strconv.Itoa(cfg.Port))
+	case strings.Contains(host, ":"):
+		endpoint.Host = "[" + host + "]"
+	}
+	if scheme != "tcp" {
+		endpoint.Path, endpoint.RawQuery, _ = strings.Cut(cfg.ResourcePath, "?")
+	}
+
+	target := config.Target{URL: endpoint.String()}
+	if cfg.Type == typeHTTPStrMatch || cfg.Type == typeHTTPSStrMatch {
+		// Route53 matches the string in the body of a GET response
+		target.Method = http.MethodGet
+		target.ExpectBody = regexp.QuoteMeta(cfg.SearchString)
+	}
+	return target, nil
+}
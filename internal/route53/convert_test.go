@@ -0,0 +1,45 @@
+package route53
+
+import (
+	"testing"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	checks := []HealthCheck{
+		{ID: "hc-web", Tags: map[string]string{"Name": "web"}, Config: HealthCheckConfig{Type: "HTTPS", FullyQualifiedDomainName: "example.com", ResourcePath: "/health?deep=1", RequestInterval: 30}},
+		{ID: "hc-api", Tags: map[string]string{"Name": "web"}, Config: HealthCheckConfig{Type: "HTTP_STR_MATCH", IPAddress: "192.0.2.1", FullyQualifiedDomainName: "api.example.com", Port: 8080, SearchString: "status: ok (v1)", RequestInterval: 10, Disabled: true}},
+		{ID: "hc-db", Config: HealthCheckConfig{Type: "TCP", IPAddress: "2001:db8::1", Port: 5432}},
+		{ID: "hc-v6", Config: HealthCheckConfig{Type: "HTTP", IPAddress: "2001:db8::2"}},
+		{ID: "hc-inverted", Config: HealthCheckConfig{Type: "HTTP", FullyQualifiedDomainName: "maintenance.example.com", Inverted: true}},
+		{ID: "hc-alarm", Config: HealthCheckConfig{Type: "CLOUDWATCH_METRIC"}},
+		{ID: "hc-all", Tags: map[string]string{"Name": "shop"}, Config: HealthCheckConfig{Type: "CALCULATED", ChildHealthChecks: []string{"hc-web", "hc-api", "hc-alarm"}, HealthThreshold: 2}},
+		{ID: "hc-none", Config: HealthCheckConfig{Type: "CALCULATED", ChildHealthChecks: []string{"hc-alarm"}, HealthThreshold: 1}},
+	}
+
+	imported := Convert(checks)
+
+	assert.Equal(t, []config.Target{
+		{URL: "https://example.com/health?deep=1", Name: "web", Labels: map[string]string{IDLabel: "hc-web"}, Interval: "30s"},
+		{URL: "http://api.example.com:8080", Name: "hc-api", Labels: map[string]string{IDLabel: "hc-api"}, Method: "GET", ExpectBody: `status: ok \(v1\)`, Interval: "10s", Disabled: true},
+		{URL: "tcp://[2001:db8::1]:5432", Name: "hc-db", Labels: map[string]string{IDLabel: "hc-db"}},
+		{URL: "http://[2001:db8::2]", Name: "hc-v6", Labels: map[string]string{IDLabel: "hc-v6"}},
+	}, imported.Checks)
+	assert.Equal(t, []config.Composite{
+		{Name: "shop", Mode: config.CompositeQuorum, Quorum: 2, Members: []string{"web", "hc-api"}},
+	}, imported.Composites)
+	assert.Equal(t, []string{
+		"health check hc-api: checks api.example.com instead of the IP address 192.0.2.1",
+		"health check hc-inverted: inverted health checks have no equivalent, skipped",
+		"health check hc-alarm: type CLOUDWATCH_METRIC has no equivalent, skipped",
+		"health check hc-all: child health check hc-alarm was not imported",
+		"health check hc-none: child health check hc-alarm was not imported",
+		"health check hc-none: no child health check was imported, skipped",
+	}, imported.Warnings)
+
+	for _, check := range imported.Checks {
+		assert.NoError(t, check.Validate(), check.URL)
+	}
+}
@@ -0,0 +1,214 @@
+// Package route53 reads the health checks of AWS Route53 and converts them into exporter
+// checks, to migrate the monitoring off Route53. It talks to the Route53 API directly,
+// signing the requests with AWS Signature Version 4.
+package route53
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultEndpoint is the global endpoint of the Route53 API
+const DefaultEndpoint = "https://route53.amazonaws.com"
+
+const (
+	apiVersion = "2013-04-01"
+	// signingRegion is the region requests to the global Route53 endpoint are signed for
+	signingRegion = "us-east-1"
+	// maxTagResources is the most health checks whose tags one request lists
+	maxTagResources = 10
+)
+
+// Credentials are the AWS credentials signing the requests
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// CredentialsFromEnv reads the credentials from the standard AWS environment variables
+func CredentialsFromEnv() (Credentials, error) {
+	credentials := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return Credentials{}, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return credentials, nil
+}
+
+// HealthCheck is a Route53 health check together with its tags
+type HealthCheck struct {
+	ID     string            `xml:"Id"`
+	Config HealthCheckConfig `xml:"HealthCheckConfig"`
+	Tags   map[string]string `xml:"-"`
+}
+
+// HealthCheckConfig is the definition of a health check, see the HealthCheckConfig type of
+// the Route53 API
+type HealthCheckConfig struct {
+	Type                     string   `xml:"Type"`
+	IPAddress                string   `xml:"IPAddress"`
+	Port                     int      `xml:"Port"`
+	ResourcePath             string   `xml:"ResourcePath"`
+	FullyQualifiedDomainName string   `xml:"FullyQualifiedDomainName"`
+	SearchString             string   `xml:"SearchString"`
+	RequestInterval          int      `xml:"RequestInterval"`
+	FailureThreshold         int      `xml:"FailureThreshold"`
+	Inverted                 bool     `xml:"Inverted"`
+	Disabled                 bool     `xml:"Disabled"`
+	ChildHealthChecks        []string `xml:"ChildHealthChecks>ChildHealthCheck"`
+	HealthThreshold          int      `xml:"HealthThreshold"`
+}
+
+type listHealthChecksResponse struct {
+	HealthChecks []HealthCheck `xml:"HealthChecks>HealthCheck"`
+	IsTruncated  bool          `xml:"IsTruncated"`
+	NextMarker   string        `xml:"NextMarker"`
+}
+
+type listTagsRequest struct {
+	XMLName     xml.Name `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ListTagsForResourcesRequest"`
+	ResourceIDs []string `xml:"ResourceIds>ResourceId"`
+}
+
+type listTagsResponse struct {
+	ResourceTagSets []struct {
+		ResourceID string `xml:"ResourceId"`
+		Tags       []struct {
+			Key   string `xml:"Key"`
+			Value string `xml:"Value"`
+		} `xml:"Tags>Tag"`
+	} `xml:"ResourceTagSets>ResourceTagSet"`
+}
+
+type errorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// Client reads health checks from the Route53 API
+type Client struct {
+	endpoint    string
+	credentials Credentials
+	client      *http.Client
+	// now is the signing time, replaced in tests
+	now func() time.Time
+}
+
+// NewClient creates a client of the API at the endpoint, DefaultEndpoint outside tests
+func NewClient(endpoint string, credentials Credentials, timeout time.Duration) *Client {
+	return &Client{
+		endpoint:    endpoint,
+		credentials: credentials,
+		client:      &http.Client{Timeout: timeout},
+		now:         time.Now,
+	}
+}
+
+// HealthChecks lists every health check of the account with its tags
+func (c *Client) HealthChecks(ctx context.Context) ([]HealthCheck, error) {
+	var checks []HealthCheck
+	marker := ""
+	for {
+		query := url.Values{"maxitems": {"100"}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		var page listHealthChecksResponse
+		if err := c.call(ctx, http.MethodGet, "/"+apiVersion+"/healthcheck", query, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list health checks: %w", err)
+		}
+		checks = append(checks, page.HealthChecks...)
+		if !page.IsTruncated || page.NextMarker == "" {
+			break
+		}
+		marker = page.NextMarker
+	}
+
+	for start := 0; start < len(checks); start += maxTagResources {
+		batch := checks[start:min(start+maxTagResources, len(checks))]
+		if err := c.addTags(ctx, batch); err != nil {
+			return nil, fmt.Errorf("failed to list health check tags: %w", err)
+		}
+	}
+	return checks, nil
+}
+
+// addTags sets the tags of the health checks
+func (c *Client) addTags(ctx context.Context, checks []HealthCheck) error {
+	request := listTagsRequest{}
+	index := make(map[string]int, len(checks))
+	for i, check := range checks {
+		request.ResourceIDs = append(request.ResourceIDs, check.ID)
+		index[check.ID] = i
+	}
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var response listTagsResponse
+	if err := c.call(ctx, http.MethodPost, "/"+apiVersion+"/tags/healthcheck", nil, body, &response); err != nil {
+		return err
+	}
+	for _, set := range response.ResourceTagSets {
+		i, exists := index[set.ResourceID]
+		if !exists {
+			continue
+		}
+		checks[i].Tags = make(map[string]string, len(set.Tags))
+		for _, tag := range set.Tags {
+			checks[i].Tags[tag.Key] = tag.Value
+		}
+	}
+	return nil
+}
+
+// call sends the signed request and decodes the XML response into out
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body []byte, out any) error {
+	target := c.endpoint + path
+	if len(query) > 0 {
+		target += "?" + canonicalQuery(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	sign(req, body, c.credentials, signingRegion, "route53", c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		if xml.Unmarshal(content, &apiErr) == nil && apiErr.Code != "" {
+			return fmt.Errorf("%s: %s", apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := xml.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
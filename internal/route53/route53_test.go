@@ -0,0 +1,91 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	credentials := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	sign(req, nil, credentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+// fakeRoute53 serves two pages of health checks and the tags of the first one
+func fakeRoute53(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>The security token included in the request is invalid.</Message></Error></ErrorResponse>`)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/healthcheck" && r.URL.Query().Get("marker") == "":
+			fmt.Fprint(w, `<ListHealthChecksResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+<HealthChecks><HealthCheck><Id>hc-1</Id><HealthCheckConfig><Type>HTTPS</Type><FullyQualifiedDomainName>example.com</FullyQualifiedDomainName><ResourcePath>/health</ResourcePath><RequestInterval>30</RequestInterval></HealthCheckConfig></HealthCheck></HealthChecks>
+<IsTruncated>true</IsTruncated><NextMarker>hc-2</NextMarker></ListHealthChecksResponse>`)
+		case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/healthcheck" && r.URL.Query().Get("marker") == "hc-2":
+			fmt.Fprint(w, `<ListHealthChecksResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+<HealthChecks><HealthCheck><Id>hc-2</Id><HealthCheckConfig><Type>TCP</Type><IPAddress>192.0.2.1</IPAddress><Port>5432</Port><RequestInterval>10</RequestInterval></HealthCheckConfig></HealthCheck></HealthChecks>
+<IsTruncated>false</IsTruncated></ListHealthChecksResponse>`)
+		case r.Method == http.MethodPost && r.URL.Path == "/2013-04-01/tags/healthcheck":
+			body, _ := io.ReadAll(r.Body)
+			assert.Contains(t, string(body), "<ResourceId>hc-1</ResourceId><ResourceId>hc-2</ResourceId>")
+			fmt.Fprint(w, `<ListTagsForResourcesResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+<ResourceTagSets><ResourceTagSet><ResourceType>healthcheck</ResourceType><ResourceId>hc-1</ResourceId><Tags><Tag><Key>Name</Key><Value>web</Value></Tag></Tags></ResourceTagSet></ResourceTagSets>
+</ListTagsForResourcesResponse>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_HealthChecks(t *testing.T) {
+	server := fakeRoute53(t)
+
+	client := NewClient(server.URL, Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, 5*time.Second)
+	checks, err := client.HealthChecks(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, checks, 2)
+	assert.Equal(t, "hc-1", checks[0].ID)
+	assert.Equal(t, HealthCheckConfig{Type: "HTTPS", FullyQualifiedDomainName: "example.com", ResourcePath: "/health", RequestInterval: 30}, checks[0].Config)
+	assert.Equal(t, map[string]string{"Name": "web"}, checks[0].Tags)
+	assert.Equal(t, 5432, checks[1].Config.Port)
+	assert.Nil(t, checks[1].Tags)
+
+	client = NewClient(server.URL, Credentials{AccessKeyID: "other", SecretAccessKey: "secret"}, 5*time.Second)
+	_, err = client.HealthChecks(context.Background())
+	assert.ErrorContains(t, err, "InvalidClientTokenId: The security token included in the request is invalid.")
+}
+
+func TestCredentialsFromEnv(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err := CredentialsFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	credentials, err := CredentialsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, credentials)
+}
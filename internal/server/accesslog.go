@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// builtinRequestLogMessage is the message of the per-request line logged by the
+// jasoet/pkg server, which the access log replaces when enabled
+const builtinRequestLogMessage = "request"
+
+// accessLogger returns middleware that logs method, path, status, latency and remote
+// address of every request whose path passes the configured include/exclude filters
+func accessLogger(cfg config.AccessLogConfig) echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		Skipper: func(c echo.Context) bool {
+			return !logPath(cfg, c.Request().URL.Path)
+		},
+		LogMethod:       true,
+		LogURIPath:      true,
+		LogStatus:       true,
+		LogLatency:      true,
+		LogRemoteIP:     true,
+		LogResponseSize: true,
+		LogError:        true,
+		LogValuesFunc: func(_ echo.Context, v middleware.RequestLoggerValues) error {
+			event := log.Info()
+			if v.Status >= http.StatusInternalServerError {
+				event = log.Error().Err(v.Error)
+			}
+			event.
+				Str("method", v.Method).
+				Str("path", v.URIPath).
+				Int("status", v.Status).
+				Float64("latency_ms", float64(v.Latency.Microseconds())/1000).
+				Str("remote_addr", v.RemoteIP).
+				Int64("bytes_out", v.ResponseSize).
+				Msg("access")
+			return nil
+		},
+	})
+}
+
+// logPath reports whether requests for the path are logged
+func logPath(cfg config.AccessLogConfig, requestPath string) bool {
+	if len(cfg.Include) > 0 && !matchesAny(cfg.Include, requestPath) {
+		return false
+	}
+	return !matchesAny(cfg.Exclude, requestPath)
+}
+
+func matchesAny(patterns []string, requestPath string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, requestPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinRequestLogFilter drops the jasoet/pkg server's own request lines so the access
+// log is the only request log and its filters take effect
+type builtinRequestLogFilter struct{}
+
+func (builtinRequestLogFilter) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.InfoLevel && msg == builtinRequestLogMessage {
+		e.Discard()
+	}
+}
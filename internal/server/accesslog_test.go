@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog redirects the global logger into a buffer for the duration of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { log.Logger = previous })
+	return &buf
+}
+
+func TestAccessLogger(t *testing.T) {
+	buf := captureLog(t)
+
+	e := echo.New()
+	e.Use(accessLogger(config.AccessLogConfig{Exclude: []string{"/metrics"}}))
+	e.GET("/metrics", func(c echo.Context) error { return c.String(http.StatusOK, "metrics") })
+	e.GET("/api/v1/targets", func(c echo.Context) error { return c.String(http.StatusOK, "targets") })
+
+	for _, path := range []string{"/metrics", "/api/v1/targets", "/missing"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.10:51234"
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "access", entry["message"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/v1/targets", entry["path"])
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Equal(t, "192.0.2.10", entry["remote_addr"])
+	assert.Contains(t, entry, "latency_ms")
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "/missing", entry["path"])
+	assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+}
+
+func TestLogPath(t *testing.T) {
+	cfg := config.AccessLogConfig{
+		Include: []string{"/api/v1/*", "/probe"},
+		Exclude: []string{"/api/v1/stream"},
+	}
+
+	assert.True(t, logPath(cfg, "/api/v1/targets"))
+	assert.True(t, logPath(cfg, "/probe"))
+	assert.False(t, logPath(cfg, "/api/v1/stream"))
+	assert.False(t, logPath(cfg, "/metrics"))
+	assert.True(t, logPath(config.AccessLogConfig{}, "/metrics"))
+}
+
+func TestBuiltinRequestLogFilter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(builtinRequestLogFilter{})
+
+	logger.Info().Str("URI", "/metrics").Msg(builtinRequestLogMessage)
+	logger.Info().Msg("access")
+	logger.Error().Msg(builtinRequestLogMessage)
+
+	assert.NotContains(t, buf.String(), "/metrics")
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/jasoet/pkg/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// setupManagementRoutes registers the endpoints that change the running target set. They
+// are subject to the request limits and require the API token when one is set.
+func (s *URLExporterServer) setupManagementRoutes(e *echo.Echo) {
+	middleware := slices.Clone(s.limits)
+	if s.config.API.Token != "" {
+		middleware = append(middleware, s.requireToken)
+	}
+
+	e.POST("/api/v1/targets", s.handleAddTarget, middleware...)
+	e.DELETE("/api/v1/targets", s.handleRemoveTarget, middleware...)
+	e.POST("/-/reload", s.handleReload, middleware...)
+}
+
+// setupAdminRoutes registers the routes served by the separate admin listener
+func (s *URLExporterServer) setupAdminRoutes(e *echo.Echo) {
+	s.setupManagementRoutes(e)
+
+	if s.config.Debug.Enabled {
+		e.Any("/debug/*", echo.WrapHandler(s.debugHandler()))
+	}
+}
+
+// startAdminServer serves the admin routes on the configured admin address with the same
+// server helper as the main listener, which also stops it on shutdown. The listener is
+// opened synchronously so a bad address fails startup.
+func (s *URLExporterServer) startAdminServer() error {
+	listener, err := listen(s.config.Admin.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin address %s: %w", s.config.Admin.ListenAddress, err)
+	}
+
+	go server.StartWithConfig(s.adminServerConfig(listener))
+	return nil
+}
+
+// adminServerConfig configures the server helper to serve the admin routes on the listener,
+// without the helper's metrics endpoint
+func (s *URLExporterServer) adminServerConfig(listener net.Listener) server.Config {
+	port := 0
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		port = addr.Port
+	}
+
+	serverConfig := server.DefaultConfig(
+		port,
+		func(e *echo.Echo) {
+			log.Info().
+				Str("address", s.config.Admin.ListenAddress).
+				Bool("debug", s.config.Debug.Enabled).
+				Msg("Admin endpoints enabled")
+		},
+		func(e *echo.Echo) {
+			log.Info().Msg("Shutting down admin endpoints")
+		},
+	)
+	serverConfig.EnableMetrics = false
+	if s.config.AccessLog.Enabled {
+		serverConfig.Middleware = append(serverConfig.Middleware, accessLogger(s.config.AccessLog))
+	}
+	// The routes are set up before the helper starts serving on the preset listener
+	serverConfig.EchoConfigurer = func(e *echo.Echo) {
+		e.Listener = listener
+		e.HidePort = true
+		s.setupAdminRoutes(e)
+	}
+	return serverConfig
+}
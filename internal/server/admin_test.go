@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminListener_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	// A stale socket file from a previous run must not block startup
+	require.NoError(t, os.WriteFile(socket, nil, 0600))
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		Admin:      config.AdminConfig{ListenAddress: "unix:" + socket},
+		Debug:      config.DebugConfig{Enabled: true},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, server.startAdminServer())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	// Without an API token the admin listener serves target management unauthenticated
+	resp, err := client.Post("http://admin/api/v1/targets", echo.MIMEApplicationJSON, strings.NewReader(`{"url": "https://new.example.com"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Len(t, server.checker.Targets(), 2)
+
+	resp, err = client.Get("http://admin/debug/runtime")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Get("http://admin/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAdminListener_RemovesManagementFromMainListener(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		API:        config.APIConfig{Token: "secret"},
+		Admin:      config.AdminConfig{ListenAddress: "127.0.0.1:0"},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	main := echo.New()
+	server.setupRoutes(main)
+
+	rec := doRequest(main, http.MethodPost, "/api/v1/targets", "secret", `{"url": "https://new.example.com"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	rec = doRequest(main, http.MethodPost, "/-/reload", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The admin listener still enforces the token when one is configured
+	admin := echo.New()
+	server.setupAdminRoutes(admin)
+
+	rec = doRequest(admin, http.MethodPost, "/api/v1/targets", "", `{"url": "https://new.example.com"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(admin, http.MethodPost, "/api/v1/targets", "secret", `{"url": "https://new.example.com"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// Debug endpoints are off unless enabled
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestStartAdminServer_InvalidAddress(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		Admin:      config.AdminConfig{ListenAddress: "256.0.0.1:0"},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	assert.Error(t, server.startAdminServer())
+}
@@ -0,0 +1,465 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/events"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// targetSchedule describes when a target is checked
+type targetSchedule struct {
+	Mode     string `json:"mode"`
+	Interval string `json:"interval,omitempty"`
+}
+
+// resultSummary is the JSON view of the latest check result of a target
+type resultSummary struct {
+	Up             bool      `json:"up"`
+	StatusCode     int       `json:"status_code"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	Error          string    `json:"error,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	// SkippedBy is the dependency whose failure skipped the check
+	SkippedBy string `json:"skipped_by,omitempty"`
+}
+
+// stepDetail is the JSON view of the outcome of a transaction step
+type stepDetail struct {
+	Name           string `json:"name"`
+	Passed         bool   `json:"passed"`
+	StatusCode     int    `json:"status_code"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	Error          string `json:"error,omitempty"`
+}
+
+// cacheDetail is the JSON view of the cache headers of a result
+type cacheDetail struct {
+	Hit           *bool  `json:"hit,omitempty"`
+	AgeSeconds    *int64 `json:"age_seconds,omitempty"`
+	MaxAgeSeconds *int64 `json:"max_age_seconds,omitempty"`
+}
+
+// targetInfo is the JSON view of a configured target returned by /api/v1/targets
+type targetInfo struct {
+	URL string `json:"url"`
+	// RawURL is the URL with its credentials and secret query parameters, only returned
+	// to authenticated requests for targets whose URL holds any
+	RawURL      string            `json:"rawUrl,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Group       string            `json:"group,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Description string            `json:"description,omitempty"`
+	RunbookURL  string            `json:"runbookUrl,omitempty"`
+	Tenant      string            `json:"tenant,omitempty"`
+	Disabled    bool              `json:"disabled,omitempty"`
+	Schedule    targetSchedule    `json:"schedule"`
+	Status      *resultSummary    `json:"status"`
+}
+
+// resultDetail is the JSON view of a target's latest result returned by /api/v1/results
+type resultDetail struct {
+	URL            string  `json:"url"`
+	Host           string  `json:"host"`
+	Path           string  `json:"path"`
+	Protocol       string  `json:"protocol"`
+	Group          string  `json:"group,omitempty"`
+	HTTPVersion    float64 `json:"http_version,omitempty"`
+	TLSVersion     string  `json:"tls_version,omitempty"`
+	TLSCipherSuite string  `json:"tls_cipher_suite,omitempty"`
+	TLSLegacy      bool    `json:"tls_legacy,omitempty"`
+	BodyMatch      *bool   `json:"body_match,omitempty"`
+	HeaderMatch    *bool   `json:"header_match,omitempty"`
+	CycleID        string  `json:"cycle_id,omitempty"`
+	// SecurityHeaders reports the audited security headers by name
+	SecurityHeaders map[string]bool `json:"security_headers,omitempty"`
+	Cache           *cacheDetail    `json:"cache,omitempty"`
+	// ClockSkewSeconds is how far the server clock is ahead of the exporter's
+	ClockSkewSeconds *int64       `json:"clock_skew_seconds,omitempty"`
+	SizeWithinBounds *bool        `json:"size_within_bounds,omitempty"`
+	Steps            []stepDetail `json:"steps,omitempty"`
+	resultSummary
+	Counters map[string]int `json:"counters,omitempty"`
+}
+
+// targetsResponse is the body returned by GET /api/v1/targets
+type targetsResponse struct {
+	Targets []targetInfo `json:"targets"`
+}
+
+// resultsResponse is the body returned by GET /api/v1/results
+type resultsResponse struct {
+	Results []resultDetail `json:"results"`
+}
+
+// errorResponse is the body of every API error
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// checkRequest is the body of POST /api/v1/check: a target plus an optional timeout that
+// shortens the configured one
+type checkRequest struct {
+	config.Target
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// validate checks the target and returns the requested timeout, zero when none is given
+func (r checkRequest) validate() (time.Duration, error) {
+	if err := r.Target.Validate(); err != nil {
+		return 0, err
+	}
+	if r.Timeout == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(r.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout: %s", r.Timeout)
+	}
+	return timeout, nil
+}
+
+func respondError(c echo.Context, status int, message string) error {
+	return c.JSON(status, errorResponse{Error: message})
+}
+
+// decodeJSON strictly decodes the JSON request body into v, rejecting unknown fields so
+// that misspelled settings are reported instead of silently ignored
+func decodeJSON(c echo.Context, v interface{}) error {
+	decoder := json.NewDecoder(c.Request().Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after JSON body")
+	}
+	return nil
+}
+
+// decodeErrorStatus is the response status for a decodeJSON error: 413 when the body
+// exceeded the size limit, 400 otherwise
+func decodeErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func newResultSummary(result checker.Result) *resultSummary {
+	summary := &resultSummary{
+		Up:             result.IsUp(),
+		StatusCode:     result.StatusCode,
+		ResponseTimeMs: result.ResponseTime.Milliseconds(),
+		Timestamp:      result.Timestamp,
+		SkippedBy:      config.RedactURL(result.SkippedBy),
+	}
+	if result.Error != nil {
+		summary.Error = result.Error.Error()
+	}
+	return summary
+}
+
+// handleTargets lists every configured target with its metadata and the summary of its
+// latest result; status is null for targets that have not been checked yet. Targets can be
+// narrowed with the selector and status query parameters. URLs are redacted, the raw URL is
+// only returned to authenticated requests.
+func (s *URLExporterServer) handleTargets(c echo.Context) error {
+	selector, reqErr := parseSelector(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+	status, reqErr := parseStatusFilter(c.QueryParams())
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	latest := make(map[string]checker.Result)
+	for _, result := range s.collector.Snapshot() {
+		latest[result.URL] = result
+	}
+
+	configured := s.checker.Targets()
+	targets := make([]targetInfo, 0, len(configured))
+	for _, target := range configured {
+		result, checked := latest[target.URL]
+		if !selector.matches(target) || !status.matches(checked, result.IsUp()) {
+			continue
+		}
+
+		info := targetInfo{
+			URL:         config.RedactURL(target.URL),
+			Name:        target.Name,
+			Group:       target.Group,
+			Labels:      target.Labels,
+			Description: target.Description,
+			RunbookURL:  target.RunbookURL,
+			Tenant:      target.Tenant,
+			Disabled:    target.Disabled,
+			Schedule:    targetSchedule{Mode: s.config.ProbeMode},
+		}
+		if info.URL != target.URL && s.authenticated(c) {
+			info.RawURL = target.URL
+		}
+		if s.config.ProbeMode != config.ProbeModeScrape {
+			info.Schedule.Interval = s.config.TargetInterval(target).String()
+		}
+		if checked {
+			info.Status = newResultSummary(result)
+		}
+		targets = append(targets, info)
+	}
+
+	return c.JSON(http.StatusOK, targetsResponse{Targets: targets})
+}
+
+// handleResults returns the latest result of every checked target together with its
+// per-status check counters. Results can be narrowed with the selector and status query
+// parameters.
+func (s *URLExporterServer) handleResults(c echo.Context) error {
+	selector, reqErr := parseSelector(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+	status, reqErr := parseStatusFilter(c.QueryParams())
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	targets := make(map[string]config.Target)
+	for _, target := range s.checker.Targets() {
+		targets[target.URL] = target
+	}
+
+	counters := s.collector.Counters()
+
+	results := make([]resultDetail, 0)
+	for _, result := range s.collector.Snapshot() {
+		target, exists := targets[result.URL]
+		if !exists {
+			target = config.Target{URL: result.URL}
+		}
+		if !selector.matches(target) || !status.matches(true, result.IsUp()) {
+			continue
+		}
+
+		results = append(results, newResultDetail(result, target.Group, counters[result.URL]))
+	}
+
+	return c.JSON(http.StatusOK, resultsResponse{Results: results})
+}
+
+// newResultDetail builds the JSON view of a result
+func newResultDetail(result checker.Result, group string, counters map[string]int) resultDetail {
+	return resultDetail{
+		URL:              config.RedactURL(result.URL),
+		Host:             result.Host,
+		Path:             result.Path,
+		Protocol:         result.Protocol,
+		Group:            group,
+		HTTPVersion:      result.HTTPVersion,
+		TLSVersion:       result.TLSVersion,
+		TLSCipherSuite:   result.TLSCipherSuite,
+		TLSLegacy:        result.TLSLegacy,
+		BodyMatch:        result.BodyMatch,
+		HeaderMatch:      result.HeaderMatch,
+		CycleID:          result.CycleID,
+		SecurityHeaders:  result.SecurityHeaders,
+		Cache:            newCacheDetail(result.Cache),
+		ClockSkewSeconds: seconds(result.ClockSkew),
+		SizeWithinBounds: result.SizeWithinBounds,
+		Steps:            newStepDetails(result.Steps),
+		resultSummary:    *newResultSummary(result),
+		Counters:         counters,
+	}
+}
+
+func newCacheDetail(cache *checker.CacheStatus) *cacheDetail {
+	if cache == nil {
+		return nil
+	}
+	return &cacheDetail{
+		Hit:           cache.Hit,
+		AgeSeconds:    seconds(cache.Age),
+		MaxAgeSeconds: seconds(cache.MaxAge),
+	}
+}
+
+// seconds converts an optional duration into whole seconds
+func seconds(duration *time.Duration) *int64 {
+	if duration == nil {
+		return nil
+	}
+	value := int64(duration.Seconds())
+	return &value
+}
+
+func newStepDetails(steps []checker.StepResult) []stepDetail {
+	details := make([]stepDetail, 0, len(steps))
+	for _, step := range steps {
+		detail := stepDetail{
+			Name:           step.Name,
+			Passed:         step.Passed(),
+			StatusCode:     step.StatusCode,
+			ResponseTimeMs: step.ResponseTime.Milliseconds(),
+		}
+		if step.Error != nil {
+			detail.Error = step.Error.Error()
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// handleCheck immediately checks the target in the request body and returns the result,
+// without recording it in the collector
+func (s *URLExporterServer) handleCheck(c echo.Context) error {
+	var request checkRequest
+	if err := decodeJSON(c, &request); err != nil {
+		return respondError(c, decodeErrorStatus(err), "invalid check request: "+err.Error())
+	}
+
+	timeout, err := request.validate()
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	if timeout == 0 {
+		timeout = s.config.Timeout
+	}
+
+	ctx := c.Request().Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result, err := s.checker.CheckRuntimeTarget(ctx, request.Target)
+	if errors.Is(err, checker.ErrTargetNotAllowed) {
+		return respondError(c, http.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, newResultDetail(result, request.Group, nil))
+}
+
+// handleConfig returns the effective configuration with secrets redacted. It is rendered
+// through its YAML form so keys and durations read the same as in the config file.
+func (s *URLExporterServer) handleConfig(c echo.Context) error {
+	redacted := s.config.Redacted()
+
+	content, err := yaml.Marshal(&redacted)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, "failed to encode config: "+err.Error())
+	}
+
+	var effective map[string]interface{}
+	if err := yaml.Unmarshal(content, &effective); err != nil {
+		return respondError(c, http.StatusInternalServerError, "failed to encode config: "+err.Error())
+	}
+
+	return c.JSON(http.StatusOK, effective)
+}
+
+// requireToken rejects requests that do not carry the configured API token as a bearer token
+func (s *URLExporterServer) requireToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, found := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.API.Token)) != 1 {
+			return respondError(c, http.StatusUnauthorized, "missing or invalid bearer token")
+		}
+		return next(c)
+	}
+}
+
+// handleAddTarget registers the target in the request body with the checker and collector
+func (s *URLExporterServer) handleAddTarget(c echo.Context) error {
+	var target config.Target
+	if err := decodeJSON(c, &target); err != nil {
+		return respondError(c, decodeErrorStatus(err), "invalid target: "+err.Error())
+	}
+
+	if err := s.checker.AddRuntimeTarget(target); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, checker.ErrTargetExists) {
+			status = http.StatusConflict
+		}
+		if errors.Is(err, checker.ErrTargetNotAllowed) {
+			status = http.StatusForbidden
+		}
+		return respondError(c, status, err.Error())
+	}
+	s.collector.AddTarget(target)
+
+	log.Info().Str("url", config.RedactURL(target.URL)).Msg("Target added via API")
+	s.events.Add(events.Event{Type: events.TypeTargetAdded, Target: target.URL, Message: "Target added via API"})
+
+	if err := s.persistTargets(); err != nil {
+		return respondError(c, http.StatusInternalServerError, "target added but not persisted: "+err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, target)
+}
+
+// handleRemoveTarget deregisters the target given by the url query parameter and drops its series
+func (s *URLExporterServer) handleRemoveTarget(c echo.Context) error {
+	targetURL := c.QueryParam("url")
+	if targetURL == "" {
+		return respondError(c, http.StatusBadRequest, "url parameter is missing")
+	}
+	if resource, managed := s.managedBy(targetURL); managed {
+		return respondError(c, http.StatusConflict, "target belongs to "+resource+", change the resource instead")
+	}
+
+	if !s.checker.RemoveTarget(targetURL) {
+		return respondError(c, http.StatusNotFound, "target not found: "+config.RedactURL(targetURL))
+	}
+	s.collector.RemoveTarget(targetURL)
+
+	log.Info().Str("url", config.RedactURL(targetURL)).Msg("Target removed via API")
+	s.events.Add(events.Event{Type: events.TypeTargetRemoved, Target: targetURL, Message: "Target removed via API"})
+
+	if err := s.persistTargets(); err != nil {
+		return respondError(c, http.StatusInternalServerError, "target removed but not persisted: "+err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// persistTargets writes the current target set to the state file, if one is configured.
+// Targets of Kubernetes resources are left out; they come back from the cluster.
+func (s *URLExporterServer) persistTargets() error {
+	if s.config.API.StateFile == "" {
+		return nil
+	}
+
+	var st config.State
+	for _, target := range s.ownTargets() {
+		if s.checker.IsRuntimeTarget(target.URL) {
+			st.RuntimeTargets = append(st.RuntimeTargets, target)
+		} else {
+			st.Targets = append(st.Targets, target)
+		}
+	}
+	if err := config.SaveState(s.config.API.StateFile, st); err != nil {
+		log.Error().Err(err).Str("state_file", s.config.API.StateFile).Msg("Failed to persist targets")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// protected returns the middleware applied to every endpoint except the health probes,
+// which stay open for container orchestrators
+func (s *URLExporterServer) protected() []echo.MiddlewareFunc {
+	if !s.config.Auth.Enabled() {
+		return nil
+	}
+	return []echo.MiddlewareFunc{s.authenticate}
+}
+
+// tenantProtected returns the middleware of the endpoints tenants may use. Besides the
+// general credentials, it accepts the bearer tokens of the tenants, whose requests only
+// see the tenant's targets.
+func (s *URLExporterServer) tenantProtected() []echo.MiddlewareFunc {
+	if len(s.config.Tenants) == 0 {
+		return s.protected()
+	}
+	return []echo.MiddlewareFunc{s.authenticateTenant}
+}
+
+// authenticateTenant records the tenant of a request carrying a tenant's bearer token and
+// authenticates any other request like authenticate
+func (s *URLExporterServer) authenticateTenant(next echo.HandlerFunc) echo.HandlerFunc {
+	authenticated := next
+	if s.config.Auth.Enabled() {
+		authenticated = s.authenticate(next)
+	}
+	return func(c echo.Context) error {
+		if tenant, ok := s.tenantOf(c.Request()); ok {
+			c.Set(tenantContextKey, tenant)
+			return next(c)
+		}
+		return authenticated(c)
+	}
+}
+
+// tenantOf returns the tenant whose bearer token the request carries
+func (s *URLExporterServer) tenantOf(r *http.Request) (string, bool) {
+	token, found := strings.CutPrefix(r.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !found {
+		return "", false
+	}
+	for name, tenant := range s.config.Tenants {
+		if tenant.BearerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(tenant.BearerToken)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// authenticated reports whether the request was authenticated, with the general credentials
+// or a tenant's bearer token
+func (s *URLExporterServer) authenticated(c echo.Context) bool {
+	return s.config.Auth.Enabled() || requestTenant(c) != ""
+}
+
+// authenticate accepts requests carrying either the configured bearer token or the
+// credentials of a configured basic auth user
+func (s *URLExporterServer) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.authorized(c.Request()) {
+			return next(c)
+		}
+
+		if len(s.config.Auth.Users) > 0 {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="url-exporter"`)
+		}
+		return respondError(c, http.StatusUnauthorized, "unauthorized")
+	}
+}
+
+func (s *URLExporterServer) authorized(r *http.Request) bool {
+	auth := s.config.Auth
+
+	if token, found := strings.CutPrefix(r.Header.Get(echo.HeaderAuthorization), "Bearer "); found {
+		return auth.BearerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(auth.BearerToken)) == 1
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	for _, user := range auth.Users {
+		if subtle.ConstantTimeCompare([]byte(username), []byte(user.Username)) == 1 {
+			return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+		}
+	}
+
+	return false
+}
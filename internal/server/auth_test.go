@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAuthTestEcho(t *testing.T, auth config.AuthConfig) *echo.Echo {
+	t.Helper()
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		ProbeMode:  config.ProbeModeInterval,
+		Auth:       auth,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+	return e
+}
+
+func TestAuthentication(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	e := newAuthTestEcho(t, config.AuthConfig{
+		Users:       []config.BasicAuthUser{{Username: "prometheus", Password: string(hash)}},
+		BearerToken: "token-123",
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		setup    func(r *http.Request)
+		expected int
+	}{
+		{"metrics without credentials", "/metrics", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"metrics with basic auth", "/metrics", func(r *http.Request) { r.SetBasicAuth("prometheus", "s3cret") }, http.StatusOK},
+		{"metrics with wrong password", "/metrics", func(r *http.Request) { r.SetBasicAuth("prometheus", "wrong") }, http.StatusUnauthorized},
+		{"metrics with unknown user", "/metrics", func(r *http.Request) { r.SetBasicAuth("admin", "s3cret") }, http.StatusUnauthorized},
+		{"metrics with bearer token", "/metrics", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token-123") }, http.StatusOK},
+		{"metrics with wrong token", "/metrics", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"api without credentials", "/api/v1/results", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"api with bearer token", "/api/v1/results", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token-123") }, http.StatusOK},
+		{"root without credentials", "/", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"liveness stays open", "/-/healthy", func(r *http.Request) {}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Code)
+			if tt.expected == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="url-exporter"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthentication_Disabled(t *testing.T) {
+	e := newAuthTestEcho(t, config.AuthConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
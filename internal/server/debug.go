@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runtimeStats is the JSON view returned by /debug/runtime
+type runtimeStats struct {
+	Goroutines    int     `json:"goroutines"`
+	CPUs          int     `json:"cpus"`
+	GoVersion     string  `json:"go_version"`
+	HeapAllocMB   float64 `json:"heap_alloc_mb"`
+	HeapInuseMB   float64 `json:"heap_inuse_mb"`
+	HeapObjects   uint64  `json:"heap_objects"`
+	SysMB         float64 `json:"sys_mb"`
+	NumGC         uint32  `json:"num_gc"`
+	LastGCPauseMs float64 `json:"last_gc_pause_ms"`
+	Targets       int     `json:"targets"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// debugHandler serves pprof under /debug/pprof/, expvar under /debug/vars and a runtime
+// summary under /debug/runtime
+func (s *URLExporterServer) debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/runtime", s.handleRuntime)
+	return mux
+}
+
+func (s *URLExporterServer) handleRuntime(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := runtimeStats{
+		Goroutines:    runtime.NumGoroutine(),
+		CPUs:          runtime.NumCPU(),
+		GoVersion:     runtime.Version(),
+		HeapAllocMB:   float64(mem.HeapAlloc) / (1 << 20),
+		HeapInuseMB:   float64(mem.HeapInuse) / (1 << 20),
+		HeapObjects:   mem.HeapObjects,
+		SysMB:         float64(mem.Sys) / (1 << 20),
+		NumGC:         mem.NumGC,
+		LastGCPauseMs: float64(mem.PauseNs[(mem.NumGC+255)%256]) / float64(time.Millisecond),
+		Targets:       len(s.checker.Targets()),
+	}
+	if !s.startedAt.IsZero() {
+		stats.UptimeSeconds = time.Since(s.startedAt).Seconds()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Error().Err(err).Msg("Failed to write runtime stats")
+	}
+}
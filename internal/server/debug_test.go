@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHandler(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://test.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+	handler := server.debugHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var stats runtimeStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.Positive(t, stats.HeapAllocMB)
+	assert.Equal(t, 2, stats.Targets)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "memstats")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+}
+
+func TestDebugEndpoints_NotOnMainListener(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		Admin:      config.AdminConfig{ListenAddress: "127.0.0.1:0"},
+		Debug:      config.DebugConfig{Enabled: true},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars", "/debug/runtime"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
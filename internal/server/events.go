@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/events"
+	"github.com/labstack/echo/v4"
+)
+
+// eventsResponse is the body returned by GET /api/v1/events
+type eventsResponse struct {
+	Events []events.Event `json:"events"`
+}
+
+// handleEvents returns the recent events, oldest first. They can be filtered by type,
+// target URL, check cycle ID and the since period (e.g. 1h); limit keeps only the most
+// recent ones.
+func (s *URLExporterServer) handleEvents(c echo.Context) error {
+	filter := events.Filter{
+		Type:    c.QueryParam("type"),
+		Target:  c.QueryParam("target"),
+		CycleID: c.QueryParam("cycle"),
+	}
+
+	if param := c.QueryParam("since"); param != "" {
+		period, err := time.ParseDuration(param)
+		if err != nil || period <= 0 {
+			return respondError(c, http.StatusBadRequest, "since must be a positive duration, e.g. 1h")
+		}
+		filter.Since = time.Now().Add(-period)
+	}
+
+	if param := c.QueryParam("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit <= 0 {
+			return respondError(c, http.StatusBadRequest, "limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	return c.JSON(http.StatusOK, eventsResponse{Events: s.events.Events(filter)})
+}
+
+// recordResultEvents adds the state changes and new check errors of the scheduled checks
+// to the event log until the context is done or the subscription ends
+func (s *URLExporterServer) recordResultEvents(ctx context.Context, results <-chan checker.Result, unsubscribe func()) {
+	defer unsubscribe()
+
+	previous := make(map[string]checker.Result)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			// A skipped check is no state change of the target
+			if result.IsSkipped() {
+				continue
+			}
+			last, exists := previous[result.URL]
+			previous[result.URL] = result
+			s.recordResult(last, exists, result)
+		}
+	}
+}
+
+// recordResult records a state change when the target went down or recovered, including
+// a target that is down on its first check, and a check error unless the previous check
+// failed with the same error
+func (s *URLExporterServer) recordResult(previous checker.Result, exists bool, result checker.Result) {
+	switch {
+	case !result.IsUp() && (!exists || previous.IsUp()):
+		s.events.Add(events.Event{
+			Time:    result.Timestamp,
+			Type:    events.TypeStateChange,
+			Target:  result.URL,
+			CycleID: result.CycleID,
+			Message: "Target is down: " + downReason(result),
+		})
+	case result.IsUp() && exists && !previous.IsUp():
+		s.events.Add(events.Event{
+			Time:    result.Timestamp,
+			Type:    events.TypeStateChange,
+			Target:  result.URL,
+			CycleID: result.CycleID,
+			Message: "Target recovered",
+		})
+	}
+
+	if result.Error != nil && (previous.Error == nil || previous.Error.Error() != result.Error.Error()) {
+		s.events.Add(events.Event{
+			Time:    result.Timestamp,
+			Type:    events.TypeCheckError,
+			Target:  result.URL,
+			CycleID: result.CycleID,
+			Message: result.Error.Error(),
+		})
+	}
+}
+
+// downReason describes why a check failed
+func downReason(result checker.Result) string {
+	if result.Error != nil {
+		return result.Error.Error()
+	}
+	return fmt.Sprintf("status code %d", result.StatusCode)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordResultEvents(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com", "https://api.example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour)
+	results := make(chan checker.Result, 10)
+	results <- checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: start}
+	results <- checker.Result{URL: "https://api.example.com", Error: errors.New("connection refused"), Timestamp: start}
+	results <- checker.Result{URL: "https://example.com", StatusCode: 503, Timestamp: start.Add(time.Minute)}
+	results <- checker.Result{URL: "https://example.com", StatusCode: 503, Timestamp: start.Add(2 * time.Minute)}
+	results <- checker.Result{URL: "https://api.example.com", Error: errors.New("connection refused"), Timestamp: start.Add(time.Minute)}
+	results <- checker.Result{URL: "https://api.example.com", Error: errors.New("timeout"), Timestamp: start.Add(2 * time.Minute), CycleID: "cycle-3"}
+	results <- checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: start.Add(3 * time.Minute)}
+	close(results)
+
+	unsubscribed := false
+	server.recordResultEvents(context.Background(), results, func() { unsubscribed = true })
+	assert.True(t, unsubscribed)
+
+	var response eventsResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/events", &response))
+
+	type entry struct{ eventType, target, message string }
+	var got []entry
+	for _, event := range response.Events {
+		got = append(got, entry{event.Type, event.Target, event.Message})
+	}
+	assert.Equal(t, []entry{
+		{events.TypeStateChange, "https://api.example.com", "Target is down: connection refused"},
+		{events.TypeCheckError, "https://api.example.com", "connection refused"},
+		{events.TypeStateChange, "https://example.com", "Target is down: status code 503"},
+		{events.TypeCheckError, "https://api.example.com", "timeout"},
+		{events.TypeStateChange, "https://example.com", "Target recovered"},
+	}, got)
+
+	response = eventsResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/events?type=check_error&limit=1", &response))
+	require.Len(t, response.Events, 1)
+	assert.Equal(t, "timeout", response.Events[0].Message)
+
+	response = eventsResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/events?cycle=cycle-3", &response))
+	require.Len(t, response.Events, 1)
+	assert.Equal(t, "timeout", response.Events[0].Message)
+	assert.Equal(t, "cycle-3", response.Events[0].CycleID)
+
+	response = eventsResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/events?target=https://example.com&since=30m", &response))
+	assert.Empty(t, response.Events)
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/events?since=soon", &failure))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/events?limit=0", &failure))
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// grafanaDashboard is the subset of the Grafana dashboard model the generated dashboard uses
+type grafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	Timezone      string            `json:"timezone"`
+	Refresh       string            `json:"refresh"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Time          grafanaTimeRange  `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name       string          `json:"name"`
+	Label      string          `json:"label"`
+	Type       string          `json:"type"`
+	Query      string          `json:"query"`
+	Datasource *grafanaRef     `json:"datasource,omitempty"`
+	Current    *grafanaCurrent `json:"current,omitempty"`
+	Refresh    int             `json:"refresh,omitempty"`
+	Multi      bool            `json:"multi,omitempty"`
+	IncludeAll bool            `json:"includeAll,omitempty"`
+	AllValue   string          `json:"allValue,omitempty"`
+}
+
+type grafanaCurrent struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+type grafanaRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaPanel struct {
+	ID          int              `json:"id"`
+	Type        string           `json:"type"`
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	Datasource  grafanaRef       `json:"datasource"`
+	GridPos     grafanaGridPos   `json:"gridPos"`
+	FieldConfig grafanaFieldConf `json:"fieldConfig"`
+	Targets     []grafanaQuery   `json:"targets"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaFieldConf struct {
+	Defaults grafanaFieldDefaults `json:"defaults"`
+}
+
+type grafanaFieldDefaults struct {
+	Unit string   `json:"unit,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+type grafanaQuery struct {
+	RefID        string     `json:"refId"`
+	Datasource   grafanaRef `json:"datasource"`
+	Expr         string     `json:"expr"`
+	LegendFormat string     `json:"legendFormat,omitempty"`
+	Instant      bool       `json:"instant,omitempty"`
+}
+
+// dashboardPanel describes a generated panel. Panels of disabled metric families are left
+// out; %s in the expression is replaced by the target label matchers.
+type dashboardPanel struct {
+	family      string
+	kind        string // Grafana panel type
+	title       string
+	description string
+	expr        string
+	legend      string
+	unit        string
+	width       int
+	percent     bool // the values are ratios, shown between 0 and 100%
+	untargeted  bool // the family has no url label
+}
+
+// dashboardPanels are the panels of the dashboard in layout order, filling rows of 24 columns
+var dashboardPanels = []dashboardPanel{
+	{family: "url_up", kind: "stat", title: "Targets up", expr: "count(url_up{%s} == 1) or vector(0)", width: 6},
+	{family: "url_up", kind: "stat", title: "Targets down", expr: "count(url_up{%s} == 0) or vector(0)", width: 6},
+	{family: "url_up", kind: "stat", title: "Targets skipped", description: "Not checked while a dependency is down", expr: "count(url_up{%s} == -1) or vector(0)", width: 6},
+	{family: "url_up", kind: "stat", title: "Availability", expr: "avg(avg_over_time((url_up{%s} >= 0)[$__range:]))", unit: "percentunit", width: 6, percent: true},
+	{family: "url_up", kind: "timeseries", title: "Up", expr: "url_up{%s}", legend: "{{url}}", width: 12},
+	{family: "url_response_time_milliseconds", kind: "timeseries", title: "Response time", expr: "url_response_time_milliseconds{%s}", legend: "{{url}}", unit: "ms", width: 12},
+	{family: "url_http_status_code", kind: "timeseries", title: "HTTP status code", expr: "url_http_status_code{%s}", legend: "{{url}}", width: 12},
+	{family: "url_check_total", kind: "timeseries", title: "Checks by status", expr: "sum by (url, status_code) (rate(url_check_total{%s}[$__rate_interval]))", legend: "{{url}} {{status_code}}", unit: "ops", width: 12},
+	{family: "url_last_success_timestamp_seconds", kind: "timeseries", title: "Time since last success", expr: "time() - url_last_success_timestamp_seconds{%s}", legend: "{{url}}", unit: "s", width: 12},
+	{family: "url_tls_version_info", kind: "table", title: "TLS", expr: "url_tls_version_info{%s}", width: 12},
+	{family: "url_tls_cert_expiry_timestamp_seconds", kind: "timeseries", title: "Certificate expires in", expr: "url_tls_cert_expiry_timestamp_seconds{%s} - time()", legend: "{{url}}", unit: "s", width: 12},
+	{family: "url_slo_burn_rate", kind: "timeseries", title: "SLO burn rate", expr: "url_slo_burn_rate{%s}", legend: "{{url}} {{window}}", width: 12},
+	{family: "url_slo_error_budget_consumed_ratio", kind: "timeseries", title: "Error budget consumed", expr: "url_slo_error_budget_consumed_ratio{%s}", legend: "{{url}} {{window}}", unit: "percentunit", width: 12},
+	{family: "url_step_duration_milliseconds", kind: "timeseries", title: "Transaction step duration", expr: "url_step_duration_milliseconds{%s}", legend: "{{url}} {{step}}", unit: "ms", width: 12},
+	{family: "url_composite_up", kind: "timeseries", title: "Composite up", expr: "url_composite_up{%s}", legend: "{{composite}}", width: 12, untargeted: true},
+	{family: "url_target_info", kind: "table", title: "Target descriptions and runbooks", expr: "url_target_info{%s}", width: 24},
+}
+
+// labelName matches valid Prometheus label names
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// handleGrafanaDashboard returns a Grafana dashboard for the metrics this exporter
+// exports, to import or provision. Its instance variable defaults to this instance and
+// the panels of families turned off in metrics.disabled are left out. The instanceLabel
+// query parameter names the label of the instance ID in Prometheus, exported_instance
+// when the scrape config does not set honor_labels.
+func (s *URLExporterServer) handleGrafanaDashboard(c echo.Context) error {
+	instanceLabel, reqErr := instanceLabelParam(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+	return c.JSON(http.StatusOK, s.grafanaDashboard(instanceLabel))
+}
+
+// instanceLabelParam reads the instanceLabel query parameter, instance by default
+func instanceLabelParam(c echo.Context) (string, *requestError) {
+	instanceLabel := c.QueryParam("instanceLabel")
+	if instanceLabel == "" {
+		return "instance", nil
+	}
+	if !labelName.MatchString(instanceLabel) {
+		return "", &requestError{http.StatusBadRequest, "instanceLabel must be a Prometheus label name"}
+	}
+	return instanceLabel, nil
+}
+
+func (s *URLExporterServer) grafanaDashboard(instanceLabel string) grafanaDashboard {
+	datasource := grafanaRef{Type: "prometheus", UID: "${datasource}"}
+	instance := s.config.InstanceID
+	sum := sha256.Sum256([]byte(instance))
+
+	dashboard := grafanaDashboard{
+		UID:           "url-exporter-" + hex.EncodeToString(sum[:6]),
+		Title:         "url-exporter (" + instance + ")",
+		Tags:          []string{"url-exporter"},
+		Timezone:      "browser",
+		Refresh:       "30s",
+		SchemaVersion: 39,
+		Time:          grafanaTimeRange{From: "now-6h", To: "now"},
+		Templating: grafanaTemplating{List: []grafanaVariable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			{
+				Name:       "instance",
+				Label:      "Instance",
+				Type:       "query",
+				Query:      "label_values(url_up, " + instanceLabel + ")",
+				Datasource: &datasource,
+				Current:    &grafanaCurrent{Text: instance, Value: instance},
+				Refresh:    2,
+				Multi:      true,
+				IncludeAll: true,
+				AllValue:   ".*",
+			},
+			{
+				Name:       "url",
+				Label:      "URL",
+				Type:       "query",
+				Query:      "label_values(url_up{" + instanceLabel + `=~"$instance"}, url)`,
+				Datasource: &datasource,
+				Current:    &grafanaCurrent{Text: "All", Value: "$__all"},
+				Refresh:    2,
+				Multi:      true,
+				IncludeAll: true,
+				AllValue:   ".*",
+			},
+		}},
+		Panels: []grafanaPanel{},
+	}
+
+	x, y, rowHeight := 0, 0, 0
+	for _, spec := range dashboardPanels {
+		if !s.collector.Enabled(spec.family) {
+			continue
+		}
+		matchers := instanceLabel + `=~"$instance"`
+		if !spec.untargeted {
+			matchers += `, url=~"$url"`
+		}
+		height := 8
+		if spec.kind == "stat" {
+			height = 4
+		}
+		if x+spec.width > 24 {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+
+		panel := grafanaPanel{
+			ID:          len(dashboard.Panels) + 1,
+			Type:        spec.kind,
+			Title:       spec.title,
+			Description: spec.description,
+			Datasource:  datasource,
+			GridPos:     grafanaGridPos{H: height, W: spec.width, X: x, Y: y},
+			FieldConfig: grafanaFieldConf{Defaults: grafanaFieldDefaults{Unit: spec.unit}},
+			Targets: []grafanaQuery{{
+				RefID:        "A",
+				Datasource:   datasource,
+				Expr:         strings.Replace(spec.expr, "%s", matchers, 1),
+				LegendFormat: spec.legend,
+				Instant:      spec.kind != "timeseries",
+			}},
+		}
+		if spec.percent {
+			lower, upper := 0.0, 1.0
+			panel.FieldConfig.Defaults.Min, panel.FieldConfig.Defaults.Max = &lower, &upper
+		}
+		dashboard.Panels = append(dashboard.Panels, panel)
+
+		x += spec.width
+		rowHeight = max(rowHeight, height)
+		if x >= 24 {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+	}
+
+	return dashboard
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGrafanaDashboard(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "vm-01",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	var dashboard grafanaDashboard
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/grafana/dashboard", &dashboard))
+	assert.Equal(t, "url-exporter (vm-01)", dashboard.Title)
+	assert.LessOrEqual(t, len(dashboard.UID), 40)
+
+	require.Len(t, dashboard.Templating.List, 3)
+	instance := dashboard.Templating.List[1]
+	assert.Equal(t, "instance", instance.Name)
+	assert.Equal(t, &grafanaCurrent{Text: "vm-01", Value: "vm-01"}, instance.Current)
+
+	titles := make(map[string]string)
+	for i, panel := range dashboard.Panels {
+		assert.Equal(t, i+1, panel.ID)
+		assert.LessOrEqual(t, panel.GridPos.X+panel.GridPos.W, 24, panel.Title)
+		require.Len(t, panel.Targets, 1)
+		titles[panel.Title] = panel.Targets[0].Expr
+	}
+	assert.Len(t, titles, len(dashboardPanels))
+	assert.Equal(t, `url_response_time_milliseconds{instance=~"$instance", url=~"$url"}`, titles["Response time"])
+
+	dashboard = grafanaDashboard{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/grafana/dashboard?instanceLabel=exported_instance", &dashboard))
+	assert.Equal(t, "label_values(url_up, exported_instance)", dashboard.Templating.List[1].Query)
+	for _, panel := range dashboard.Panels {
+		assert.Contains(t, panel.Targets[0].Expr, `exported_instance=~"$instance"`, panel.Title)
+	}
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/grafana/dashboard?instanceLabel=exported-instance", &failure))
+
+	// Panels of disabled families are left out
+	cfg.Metrics.Disabled = []string{"url_response_time_milliseconds", "url_target_info"}
+	server, err = createTestServer(cfg)
+	require.NoError(t, err)
+
+	dashboard = grafanaDashboard{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/grafana/dashboard", &dashboard))
+	assert.Len(t, dashboard.Panels, len(dashboardPanels)-2)
+	for _, panel := range dashboard.Panels {
+		assert.NotEqual(t, "Response time", panel.Title)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// staleCycleFactor is how many check intervals may pass without a completed cycle
+// before the exporter reports itself unhealthy
+const staleCycleFactor = 3
+
+// handleHealth reports exporter health for container health checks: 200 while checks
+// are running and recent, 503 when the check loop has stopped or cycles have stalled
+func (s *URLExporterServer) handleHealth(c echo.Context) error {
+	now := time.Now()
+	lastCycle := s.checker.LastCycle()
+	scrapeMode := s.config.ProbeMode == config.ProbeModeScrape
+	running := s.checker.Running()
+
+	info := map[string]interface{}{
+		"config_loaded":   true,
+		"probe_mode":      s.config.ProbeMode,
+		"checker_running": running,
+	}
+	if s.elector != nil {
+		info["leader"] = s.elector.IsLeader()
+	}
+
+	if !lastCycle.IsZero() {
+		info["last_cycle"] = lastCycle.UTC().Format(time.RFC3339)
+		info["last_cycle_age_seconds"] = now.Sub(lastCycle).Seconds()
+	}
+
+	problem := ""
+	switch {
+	case scrapeMode:
+		// checks only run when scraped, so neither the loop nor cycle age says anything about health
+	case !s.isLeader():
+		// a standby does not check until it becomes leader
+	case !running:
+		problem = "checker is not running"
+	case lastCycle.IsZero():
+		if !s.startedAt.IsZero() && now.Sub(s.startedAt) > s.staleAfter() {
+			problem = "no check cycle completed since start"
+		}
+	case now.Sub(lastCycle) > s.staleAfter():
+		problem = "last check cycle is stale"
+	}
+
+	if problem != "" {
+		info["status"] = "DOWN"
+		info["reason"] = problem
+		return c.JSON(http.StatusServiceUnavailable, info)
+	}
+
+	info["status"] = "UP"
+	return c.JSON(http.StatusOK, info)
+}
+
+// staleAfter returns the maximum acceptable age of the last completed check cycle. Some
+// target is due at least once per shortest interval, so cycles are never further apart.
+func (s *URLExporterServer) staleAfter() time.Duration {
+	var interval time.Duration
+	for _, target := range s.checker.Targets() {
+		if target.Disabled {
+			continue
+		}
+		if targetInterval := s.config.TargetInterval(target); interval == 0 || targetInterval < interval {
+			interval = targetInterval
+		}
+	}
+	if interval == 0 {
+		interval = s.config.CheckInterval
+	}
+	return staleCycleFactor*interval + s.config.Timeout
+}
+
+// handleHealthy is the liveness probe: it answers as long as the process can serve requests
+func (s *URLExporterServer) handleHealthy(c echo.Context) error {
+	return c.String(http.StatusOK, "URL Exporter is Healthy.\n")
+}
+
+// handleReady is the readiness probe: it fails until every enabled target has been checked
+// once and its result reached the collector, so scrapes are not routed to an exporter
+// that is empty or only partly filled after a restart. Once ready it stays ready; targets
+// added later do not take the replica out of rotation. A standby is ready, so rollouts of
+// an HA pair do not wait for it to become leader.
+func (s *URLExporterServer) handleReady(c echo.Context) error {
+	if !s.isLeader() {
+		return c.String(http.StatusOK, "URL Exporter is Ready (standby).\n")
+	}
+	if pending := s.pendingFirstChecks(); pending > 0 {
+		return c.String(http.StatusServiceUnavailable,
+			fmt.Sprintf("URL Exporter is not ready: %d targets not checked yet.\n", pending))
+	}
+
+	return c.String(http.StatusOK, "URL Exporter is Ready.\n")
+}
+
+// handleStarted is the startup probe: it fails like the readiness probe until the first
+// check of every target, so a startupProbe can hold off the liveness probe during a long
+// warmup. A standby has started once it serves requests.
+func (s *URLExporterServer) handleStarted(c echo.Context) error {
+	if s.isLeader() {
+		if pending := s.pendingFirstChecks(); pending > 0 {
+			return c.String(http.StatusServiceUnavailable,
+				fmt.Sprintf("URL Exporter has not started: %d targets not checked yet.\n", pending))
+		}
+	}
+
+	return c.String(http.StatusOK, "URL Exporter has Started.\n")
+}
+
+// pendingFirstChecks returns how many enabled targets have no result yet, or 0 once all
+// of them had one. Scrape mode checks on demand and never waits.
+func (s *URLExporterServer) pendingFirstChecks() int {
+	if s.config.ProbeMode == config.ProbeModeScrape || s.checkedAll.Load() {
+		return 0
+	}
+
+	checked := make(map[string]bool)
+	for _, result := range s.collector.Snapshot() {
+		checked[result.URL] = true
+	}
+
+	pending := 0
+	for _, target := range s.checker.Targets() {
+		if !target.Disabled && !checked[target.URL] {
+			pending++
+		}
+	}
+	if pending == 0 {
+		s.checkedAll.Store(true)
+	}
+	return pending
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getHealth(t *testing.T, server *URLExporterServer) (int, map[string]interface{}) {
+	t.Helper()
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestHandleHealth_CheckerNotRunning(t *testing.T) {
+	cfg := &config.Config{
+		Targets:       []string{"https://example.com"},
+		CheckInterval: 30 * time.Second,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	code, body := getHealth(t, server)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "DOWN", body["status"])
+	assert.Equal(t, "checker is not running", body["reason"])
+	assert.Equal(t, true, body["config_loaded"])
+	assert.Equal(t, false, body["checker_running"])
+}
+
+func TestHandleHealth_Running(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:       []string{target.URL},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.startBackgroundWorkers(ctx)
+
+	require.Eventually(t, func() bool {
+		return !server.checker.LastCycle().IsZero()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	code, body := getHealth(t, server)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", body["status"])
+	assert.Equal(t, true, body["checker_running"])
+	assert.Contains(t, body, "last_cycle")
+	assert.Less(t, body["last_cycle_age_seconds"], float64(60))
+}
+
+func TestHandleHealth_ScrapeMode(t *testing.T) {
+	cfg := &config.Config{
+		Targets:       []string{"https://example.com"},
+		CheckInterval: 30 * time.Second,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeScrape,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	code, body := getHealth(t, server)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", body["status"])
+	assert.Equal(t, "scrape", body["probe_mode"])
+	assert.NotContains(t, body, "last_cycle")
+}
+
+func TestStaleAfter(t *testing.T) {
+	cfg := &config.Config{
+		Targets:       []string{"https://example.com"},
+		CheckInterval: 30 * time.Second,
+		Timeout:       10 * time.Second,
+	}
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100*time.Second, server.staleAfter())
+
+	// Per-target intervals count, whether shorter or longer than checkInterval
+	cfg.Targets = nil
+	cfg.Checks = []config.Target{{URL: "https://a.example.com", Interval: "5m"}, {URL: "https://b.example.com", Interval: "2m"}}
+	server, err = createTestServer(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, 370*time.Second, server.staleAfter())
+}
+
+func TestHandleHealthyAndReady(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:       []string{target.URL},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	get := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("/-/healthy"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/-/ready"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.startBackgroundWorkers(ctx)
+
+	require.Eventually(t, func() bool {
+		return get("/-/ready") == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, get("/-/healthy"))
+}
+
+func TestHandleReady_WaitsForEveryTarget(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://a.example.com"},
+			{URL: "https://b.example.com"},
+			{URL: "https://disabled.example.com", Disabled: true},
+		},
+		CheckInterval: time.Hour,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	server.collector.Record(checker.Result{URL: "https://a.example.com", StatusCode: 200, Timestamp: time.Now()})
+	rec := get("/-/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "1 targets not checked yet")
+	assert.Equal(t, http.StatusServiceUnavailable, get("/-/started").Code)
+
+	server.collector.Record(checker.Result{URL: "https://b.example.com", StatusCode: 200, Timestamp: time.Now()})
+	assert.Equal(t, http.StatusOK, get("/-/ready").Code, "disabled targets are not waited for")
+	assert.Equal(t, http.StatusOK, get("/-/started").Code)
+
+	require.NoError(t, server.checker.AddTarget(config.Target{URL: "https://c.example.com"}))
+	assert.Equal(t, http.StatusOK, get("/-/ready").Code, "targets added later do not make the exporter unready")
+}
+
+func TestHandleReady_ScrapeMode(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		ProbeMode:  config.ProbeModeScrape,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/-/ready", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLeaderElection_Standby(t *testing.T) {
+	requests := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:    []string{target.URL},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		ProbeMode:  config.ProbeModeScrape,
+		Leader: config.LeaderConfig{
+			Mode:          config.LeaderElectionFile,
+			File:          filepath.Join(t.TempDir(), "leader.lock"),
+			LeaseDuration: 15 * time.Second,
+			RetryPeriod:   2 * time.Second,
+		},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	// Without running the election the replica stands by
+	req := httptest.NewRequest(http.MethodGet, "/-/ready", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "standby")
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Contains(t, rec.Body.String(), "url_exporter_leader 0")
+	assert.Zero(t, requests, "a standby does not probe on scrapes")
+
+	// Its checker is not running, which is healthy for a standby
+	server.config.ProbeMode = config.ProbeModeInterval
+	code, body := getHealth(t, server)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, body["leader"])
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// historyResponse is the body returned by GET /api/v1/targets/{name}/history
+type historyResponse struct {
+	URL     string          `json:"url"`
+	Name    string          `json:"name,omitempty"`
+	Results []resultSummary `json:"results"`
+}
+
+// requestError is a client error found while parsing a request
+type requestError struct {
+	status  int
+	message string
+}
+
+// handleTargetHistory returns the recent check results of the target identified by its
+// name or URL-escaped URL, oldest first. The since query parameter (e.g. 1h) limits the
+// results to that period and status (up or down) to the results in that state.
+func (s *URLExporterServer) handleTargetHistory(c echo.Context) error {
+	target, since, reqErr := s.historyQuery(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+	status, reqErr := parseStatusFilter(c.QueryParams())
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	points := s.collector.TargetHistory(target.URL, since)
+	response := historyResponse{
+		URL:     config.RedactURL(target.URL),
+		Name:    target.Name,
+		Results: make([]resultSummary, 0, len(points)),
+	}
+	for _, point := range points {
+		if status.matches(true, point.Up) {
+			response.Results = append(response.Results, newHistorySummary(point))
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// handleTargetHistoryExport returns the target's history as an availability report to
+// download, in the CSV (default) or JSON format given by the format query parameter
+func (s *URLExporterServer) handleTargetHistoryExport(c echo.Context) error {
+	target, since, reqErr := s.historyQuery(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = metrics.ExportFormatCSV
+	}
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case metrics.ExportFormatCSV:
+	case metrics.ExportFormatJSON:
+		contentType = echo.MIMEApplicationJSONCharsetUTF8
+	default:
+		return respondError(c, http.StatusBadRequest, "format must be csv or json")
+	}
+
+	report := metrics.HistoryReport{
+		GeneratedAt: time.Now().UTC(),
+		Targets:     []metrics.TargetReport{metrics.NewTargetReport(config.RedactURL(target.URL), target.Name, s.collector.TargetHistory(target.URL, since))},
+	}
+	if !since.IsZero() {
+		report.Since = &since
+	}
+
+	var body bytes.Buffer
+	if err := metrics.WriteHistoryReport(&body, format, report); err != nil {
+		return respondError(c, http.StatusInternalServerError, "failed to export history: "+err.Error())
+	}
+
+	name := target.Name
+	if name == "" {
+		name = "target"
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+url.PathEscape(name)+`-history.`+format+`"`)
+	return c.Blob(http.StatusOK, contentType, body.Bytes())
+}
+
+// historyQuery resolves the target of a history request from its name parameter and the
+// start of the requested period from the optional since parameter
+func (s *URLExporterServer) historyQuery(c echo.Context) (config.Target, time.Time, *requestError) {
+	name, err := url.PathUnescape(c.Param("name"))
+	if err != nil {
+		return config.Target{}, time.Time{}, &requestError{http.StatusBadRequest, "invalid target name: " + err.Error()}
+	}
+
+	var since time.Time
+	if param := c.QueryParam("since"); param != "" {
+		period, err := time.ParseDuration(param)
+		if err != nil || period <= 0 {
+			return config.Target{}, time.Time{}, &requestError{http.StatusBadRequest, "since must be a positive duration, e.g. 1h"}
+		}
+		since = time.Now().Add(-period)
+	}
+
+	target, exists := s.checker.Lookup(name)
+	if !exists || !visible(c, target) {
+		return config.Target{}, time.Time{}, &requestError{http.StatusNotFound, "target not found: " + config.RedactURL(name)}
+	}
+
+	return target, since, nil
+}
+
+func newHistorySummary(point metrics.HistoryPoint) resultSummary {
+	return resultSummary{
+		Up:             point.Up,
+		StatusCode:     point.StatusCode,
+		ResponseTimeMs: point.ResponseTime.Milliseconds(),
+		Error:          point.Error,
+		Timestamp:      point.Timestamp,
+	}
+}
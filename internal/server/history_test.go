@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleTargetHistory(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://example.com/health", Name: "web"},
+			{URL: "https://api.example.com"},
+		},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	server.collector.Record(checker.Result{URL: "https://example.com/health", StatusCode: 200, ResponseTime: 40 * time.Millisecond, Timestamp: now.Add(-2 * time.Hour)})
+	server.collector.Record(checker.Result{URL: "https://example.com/health", Error: errors.New("connection refused"), ResponseTime: 3 * time.Millisecond, Timestamp: now.Add(-time.Minute)})
+
+	var history historyResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/targets/web/history", &history))
+	assert.Equal(t, "https://example.com/health", history.URL)
+	assert.Equal(t, "web", history.Name)
+	require.Len(t, history.Results, 2)
+	assert.True(t, history.Results[0].Up)
+	assert.Equal(t, 200, history.Results[0].StatusCode)
+	assert.Equal(t, int64(40), history.Results[0].ResponseTimeMs)
+	assert.False(t, history.Results[1].Up)
+	assert.Equal(t, "connection refused", history.Results[1].Error)
+
+	history = historyResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/targets/web/history?since=1h", &history))
+	require.Len(t, history.Results, 1)
+	assert.Equal(t, "connection refused", history.Results[0].Error)
+
+	history = historyResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/targets/web/history?status=up", &history))
+	require.Len(t, history.Results, 1)
+	assert.Equal(t, 200, history.Results[0].StatusCode)
+
+	// Unnamed targets are addressed by their escaped URL
+	history = historyResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/targets/"+url.PathEscape("https://example.com/health")+"/history", &history))
+	assert.Len(t, history.Results, 2)
+
+	history = historyResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/targets/"+url.PathEscape("https://api.example.com")+"/history", &history))
+	assert.NotNil(t, history.Results)
+	assert.Empty(t, history.Results)
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusNotFound, getJSON(t, server, "/api/v1/targets/unknown/history", &failure))
+	assert.Equal(t, "target not found: unknown", failure.Error)
+
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/targets/web/history?since=yesterday", &failure))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/targets/web/history?since=-1h", &failure))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/targets/web/history?status=ongoing", &failure))
+}
+
+func TestHandleTargetHistoryExport(t *testing.T) {
+	cfg := &config.Config{
+		Checks:     []config.Target{{URL: "https://example.com/health", Name: "web"}},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	ts := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	server.collector.Record(checker.Result{URL: "https://example.com/health", StatusCode: 200, ResponseTime: 40 * time.Millisecond, Timestamp: ts.Add(-2 * time.Hour)})
+	server.collector.Record(checker.Result{URL: "https://example.com/health", StatusCode: 503, ResponseTime: 3 * time.Millisecond, Timestamp: ts})
+
+	e := echo.New()
+	server.setupRoutes(e)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/targets/web/history/export?since=1h")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `attachment; filename="web-history.csv"`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "url,name,timestamp,up,status_code,response_time_ms,error\n"+
+		"https://example.com/health,web,"+ts.Format(time.RFC3339)+",false,503,3,\n", rec.Body.String())
+
+	rec = get("/api/v1/targets/web/history/export?format=json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="web-history.json"`, rec.Header().Get(echo.HeaderContentDisposition))
+	var report metrics.HistoryReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Nil(t, report.Since)
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, 2, report.Targets[0].Checks)
+	assert.Equal(t, 50.0, report.Targets[0].Availability)
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/targets/web/history/export?format=xml").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/targets/unknown/history/export").Code)
+}
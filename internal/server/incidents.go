@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// incidentInfo is the JSON view of a downtime incident returned by /api/v1/incidents
+type incidentInfo struct {
+	URL             string         `json:"url"`
+	Name            string         `json:"name,omitempty"`
+	Group           string         `json:"group,omitempty"`
+	Start           time.Time      `json:"start"`
+	End             *time.Time     `json:"end,omitempty"`
+	Ongoing         bool           `json:"ongoing"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Checks          int            `json:"checks"`
+	Errors          map[string]int `json:"errors"`
+	LastError       string         `json:"last_error,omitempty"`
+}
+
+// incidentsResponse is the body returned by GET /api/v1/incidents
+type incidentsResponse struct {
+	Incidents []incidentInfo `json:"incidents"`
+}
+
+// handleIncidents returns the downtime incidents of the targets, oldest first. They can
+// be filtered by target (name or URL-escaped URL), the selector query parameters, status
+// (ongoing or resolved) and the since period (e.g. 24h) they overlap.
+func (s *URLExporterServer) handleIncidents(c echo.Context) error {
+	selector, reqErr := parseSelector(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	status := c.QueryParam("status")
+	if status != "" && status != "ongoing" && status != "resolved" {
+		return respondError(c, http.StatusBadRequest, "status must be ongoing or resolved")
+	}
+
+	var since time.Time
+	if param := c.QueryParam("since"); param != "" {
+		period, err := time.ParseDuration(param)
+		if err != nil || period <= 0 {
+			return respondError(c, http.StatusBadRequest, "since must be a positive duration, e.g. 24h")
+		}
+		since = time.Now().Add(-period)
+	}
+
+	targetURL := ""
+	if param := c.QueryParam("target"); param != "" {
+		name, err := url.PathUnescape(param)
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "invalid target: "+err.Error())
+		}
+		target, exists := s.checker.Lookup(name)
+		if !exists || !visible(c, target) {
+			return respondError(c, http.StatusNotFound, "target not found: "+config.RedactURL(name))
+		}
+		targetURL = target.URL
+	}
+
+	targets := make(map[string]config.Target)
+	for _, target := range s.checker.Targets() {
+		targets[target.URL] = target
+	}
+
+	now := time.Now()
+	incidents := make([]incidentInfo, 0)
+	for _, incident := range s.collector.Incidents(since) {
+		if targetURL != "" && incident.URL != targetURL {
+			continue
+		}
+		target, exists := targets[incident.URL]
+		if !exists {
+			target = config.Target{URL: incident.URL}
+		}
+		if !selector.matches(target) {
+			continue
+		}
+		if status != "" && incident.Open() != (status == "ongoing") {
+			continue
+		}
+
+		incidents = append(incidents, incidentInfo{
+			URL:             config.RedactURL(incident.URL),
+			Name:            target.Name,
+			Group:           target.Group,
+			Start:           incident.Start,
+			End:             incident.End,
+			Ongoing:         incident.Open(),
+			DurationSeconds: incident.Duration(now).Seconds(),
+			Checks:          incident.Checks,
+			Errors:          incident.Errors,
+			LastError:       incident.LastError,
+		})
+	}
+
+	return c.JSON(http.StatusOK, incidentsResponse{Incidents: incidents})
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleIncidents(t *testing.T) {
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: "https://example.com/health", Name: "web", Group: "frontend"},
+			{URL: "https://api.example.com", Labels: map[string]string{"team": "payments"}},
+		},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	start := time.Now().Add(-3 * time.Hour).UTC().Truncate(time.Second)
+	server.collector.Record(checker.Result{URL: "https://example.com/health", StatusCode: 502, Timestamp: start})
+	server.collector.Record(checker.Result{URL: "https://example.com/health", StatusCode: 200, Timestamp: start.Add(10 * time.Minute)})
+	server.collector.Record(checker.Result{URL: "https://api.example.com", Error: errors.New("connection refused"), Timestamp: start.Add(2 * time.Hour)})
+
+	var response incidentsResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/v1/incidents", &response))
+	require.Len(t, response.Incidents, 2)
+
+	resolved := response.Incidents[0]
+	assert.Equal(t, "https://example.com/health", resolved.URL)
+	assert.Equal(t, "web", resolved.Name)
+	assert.Equal(t, "frontend", resolved.Group)
+	assert.False(t, resolved.Ongoing)
+	assert.Equal(t, 600.0, resolved.DurationSeconds)
+	assert.Equal(t, map[string]int{"http_502": 1}, resolved.Errors)
+
+	ongoing := response.Incidents[1]
+	assert.True(t, ongoing.Ongoing)
+	assert.Nil(t, ongoing.End)
+	assert.Equal(t, "connection refused", ongoing.LastError)
+	assert.Greater(t, ongoing.DurationSeconds, 3000.0)
+
+	filters := map[string]string{
+		"/api/v1/incidents?status=ongoing":           "https://api.example.com",
+		"/api/v1/incidents?status=resolved":          "https://example.com/health",
+		"/api/v1/incidents?group=frontend":           "https://example.com/health",
+		"/api/v1/incidents?target=web":               "https://example.com/health",
+		"/api/v1/incidents?since=1h":                 "https://api.example.com",
+		"/api/v1/incidents?label.team=payments":      "https://api.example.com",
+		"/api/v1/incidents?host=https://example.com": "https://example.com/health",
+		"/api/v1/incidents?target=" + url.QueryEscape(url.PathEscape("https://api.example.com")): "https://api.example.com",
+	}
+	for path, expected := range filters {
+		response = incidentsResponse{}
+		require.Equal(t, http.StatusOK, getJSON(t, server, path, &response), path)
+		require.Len(t, response.Incidents, 1, path)
+		assert.Equal(t, expected, response.Incidents[0].URL, path)
+	}
+
+	var failure errorResponse
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/incidents?status=down", &failure))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server, "/api/v1/incidents?since=yesterday", &failure))
+	assert.Equal(t, http.StatusNotFound, getJSON(t, server, "/api/v1/incidents?target=unknown", &failure))
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/controller"
+	"github.com/jasoet/url-exporter/internal/events"
+)
+
+// kubeTargets adds and removes the targets of Kubernetes resources like the API does.
+// Source names the resources in the event log, e.g. URLCheck.
+type kubeTargets struct {
+	server *URLExporterServer
+	source string
+}
+
+func (t kubeTargets) AddTarget(target config.Target) error {
+	if err := t.server.checker.AddRuntimeTarget(target); err != nil {
+		return err
+	}
+	t.server.collector.AddTarget(target)
+	t.server.events.Add(events.Event{Type: events.TypeTargetAdded, Target: target.URL, Message: "Target added by " + t.source})
+	return nil
+}
+
+func (t kubeTargets) RemoveTarget(url string) bool {
+	if !t.server.checker.RemoveTarget(url) {
+		return false
+	}
+	t.server.collector.RemoveTarget(url)
+	t.server.events.Add(events.Event{Type: events.TypeTargetRemoved, Target: url, Message: "Target removed by " + t.source})
+	return true
+}
+
+// setupController makes the targets of URLCheck resources part of the target set
+func (s *URLExporterServer) setupController() error {
+	ctrl, err := controller.New(s.config, kubeTargets{server: s, source: "URLCheck"})
+	if err != nil {
+		return err
+	}
+	// Every replica watches the resources, but only the leader reports results
+	ctrl.Active = s.isLeader
+	s.checker.OnCycle(ctrl.HandleCycle)
+	s.urlChecks = ctrl
+	return nil
+}
+
+// setupDiscovery makes the hosts of Ingresses and HTTPRoutes part of the target set
+func (s *URLExporterServer) setupDiscovery() error {
+	discovery, err := controller.NewDiscovery(s.config, kubeTargets{server: s, source: "discovery"})
+	if err != nil {
+		return err
+	}
+	s.discovery = discovery
+	return nil
+}
+
+// managedBy returns the Kubernetes resource the target belongs to, e.g. "URLCheck shop",
+// if it does not come from the configuration or the API
+func (s *URLExporterServer) managedBy(url string) (string, bool) {
+	if s.urlChecks != nil {
+		if name, managed := s.urlChecks.Manages(url); managed {
+			return "URLCheck " + name, true
+		}
+	}
+	if s.discovery != nil {
+		if source, managed := s.discovery.Manages(url); managed {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// ownTargets returns the targets that do not belong to a Kubernetes resource
+func (s *URLExporterServer) ownTargets() []config.Target {
+	targets := s.checker.Targets()
+	if s.urlChecks == nil && s.discovery == nil {
+		return targets
+	}
+
+	own := make([]config.Target, 0, len(targets))
+	for _, target := range targets {
+		if _, managed := s.managedBy(target.URL); !managed {
+			own = append(own, target)
+		}
+	}
+	return own
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitExpiry is how long an idle client's rate limit state is kept
+const rateLimitExpiry = 10 * time.Minute
+
+// newRequestLimits returns the middleware guarding the endpoints that trigger outbound
+// checks or change the target set: a per-client rate limit and a request body size limit.
+// The limiter state is shared, so a client's budget covers all of those endpoints.
+func (s *URLExporterServer) newRequestLimits() []echo.MiddlewareFunc {
+	var limits []echo.MiddlewareFunc
+
+	if s.config.API.RateLimit > 0 {
+		store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(s.config.API.RateLimit),
+			Burst:     s.config.API.RateBurst,
+			ExpiresIn: rateLimitExpiry,
+		})
+		limits = append(limits, middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+			Store:               store,
+			IdentifierExtractor: clientAddress,
+			ErrorHandler: func(c echo.Context, err error) error {
+				return respondError(c, http.StatusForbidden, "unable to identify client")
+			},
+			DenyHandler: func(c echo.Context, identifier string, err error) error {
+				log.Debug().Str("client", identifier).Str("path", c.Path()).Msg("Request rate limited")
+				c.Response().Header().Set("Retry-After", "1")
+				return respondError(c, http.StatusTooManyRequests, "rate limit exceeded")
+			},
+		}))
+	}
+
+	if s.config.API.MaxBodyBytes > 0 {
+		limits = append(limits, limitBody(s.config.API.MaxBodyBytes))
+	}
+
+	return limits
+}
+
+// clientAddress identifies a client by the IP of the connection. Forwarding headers are
+// ignored because clients could set them to dodge the limit.
+func clientAddress(c echo.Context) (string, error) {
+	remoteAddr := c.Request().RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host, nil
+	}
+	return remoteAddr, nil
+}
+
+// limitBody rejects request bodies larger than limit bytes with 413
+func limitBody(limit int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength > limit {
+				return respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit))
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, limit)
+			return next(c)
+		}
+	}
+}
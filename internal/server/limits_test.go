@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimits_RateLimit(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		API:        config.APIConfig{Token: "secret", RateLimit: 0.001, RateBurst: 2},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	request := func(method, path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "192.0.2.99")
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The burst is shared by the limited endpoints
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/probe", "192.0.2.1:1234").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v1/check", "192.0.2.1:1235").Code)
+
+	rec := request(http.MethodDelete, "/api/v1/targets?url=x", "192.0.2.1:1236")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "rate limit exceeded")
+
+	// Other clients have their own budget and read-only endpoints are not limited
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/probe", "192.0.2.2:1234").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/targets", "192.0.2.1:1237").Code)
+}
+
+func TestRequestLimits_BodySize(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		API:        config.APIConfig{Token: "secret", MaxBodyBytes: 64},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	large := `{"url":"https://example.com/` + strings.Repeat("a", 64) + `"}`
+
+	rec := doRequest(e, http.MethodPost, "/api/v1/check", "", large)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/v1/targets", "secret", large)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Len(t, server.checker.Targets(), 1)
+
+	// Bodies without a declared length are cut off while decoding
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/targets", strings.NewReader(large))
+	req.ContentLength = -1
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/v1/targets", "secret", `{"url":"https://new.example.com"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// listen opens a listener on a TCP address or, with the unix: prefix, on a unix socket.
+// A stale socket file left behind by a previous run is removed first.
+func listen(address string) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(address, config.UnixSocketPrefix)
+	if !isUnix {
+		return net.Listen("tcp", address)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pathParam matches OpenAPI path parameters, which echo routes write as :name
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestHandleOpenAPI(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		API:        config.APIConfig{Token: "secret"},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	registered := make(map[string]bool)
+	for _, route := range e.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	// Every documented operation is served
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			route := strings.ToUpper(method) + " " + pathParam.ReplaceAllString(path, ":$1")
+			documented[route] = true
+			assert.True(t, registered[route], "documented route %s is not registered", route)
+		}
+	}
+
+	// Every versioned API route is documented
+	for route := range registered {
+		if strings.Contains(route, " /api/v1/") || strings.HasSuffix(route, " /-/reload") {
+			assert.True(t, documented[route], "route %s is not documented", route)
+		}
+	}
+}
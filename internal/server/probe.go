@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// handleProbe runs a single on-demand check of the target given in the query string and
+// serves only that target's metrics from a registry created for the request, following
+// the blackbox_exporter multi-target pattern
+func (s *URLExporterServer) handleProbe(c echo.Context) error {
+	targetURL := c.QueryParam("target")
+	if targetURL == "" {
+		return c.String(http.StatusBadRequest, "target parameter is missing")
+	}
+
+	target, configured := s.probeTarget(targetURL)
+
+	// An explicit module replaces the target's configured module and check settings
+	if module := c.QueryParam("module"); module != "" {
+		if _, exists := s.config.Module(module); !exists {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("unknown module %q", module))
+		}
+		target.Module = module
+		target.Method = ""
+		target.ExpectBody = ""
+		target.ExpectHeaders = nil
+		target.ExpectContentType = ""
+		target.MinBodyBytes = 0
+		target.MaxBodyBytes = 0
+		target.FailOnBodySize = false
+		target.GraphQL = nil
+		target.SOAP = nil
+		target.SecurityHeaders = false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), s.scrapeTimeout(c.Request()))
+	defer cancel()
+
+	check := s.checker.CheckTarget
+	if !configured {
+		check = s.checker.CheckRuntimeTarget
+	}
+	result, err := check(ctx, target)
+	if errors.Is(err, checker.ErrTargetNotAllowed) {
+		return c.String(http.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	probeConfig := *s.config
+	probeConfig.Targets = nil
+	probeConfig.Checks = []config.Target{target}
+
+	collector := metrics.NewCollector(&probeConfig, s.checker)
+	collector.Record(result)
+
+	registry := prometheus.NewRegistry()
+	if err := collector.Register(prometheus.WrapRegistererWith(s.instanceLabels, registry)); err != nil {
+		log.Error().Err(err).Str("target", config.RedactURL(targetURL)).Msg("Failed to register probe collector")
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// probeTarget returns the registered check for the URL so its assertions apply, or a
+// plain target for URLs that are not registered, which the target policy restricts. SLO
+// objectives are dropped since a single probe carries no history.
+func (s *URLExporterServer) probeTarget(targetURL string) (config.Target, bool) {
+	for _, target := range s.checker.Targets() {
+		if target.URL == targetURL {
+			target.Objective = 0
+			return target, true
+		}
+	}
+	return config.Target{URL: targetURL}, false
+}
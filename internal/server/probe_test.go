@@ -0,0 +1,157 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProbeTestEcho(t *testing.T, cfg *config.Config) *echo.Echo {
+	t.Helper()
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+	return e
+}
+
+func probe(e *echo.Echo, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/probe?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleProbe(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:    []string{"https://configured.example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "probe-instance",
+	}
+	e := newProbeTestEcho(t, cfg)
+
+	rec := probe(e, url.Values{"target": {target.URL + "/health"}, "module": {"http_2xx"}})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `url_up{host="`+target.URL+`",instance="probe-instance",path="/health",protocol="http",url="`+target.URL+`/health"} 1`)
+	assert.Contains(t, body, "url_response_time_milliseconds{")
+	assert.NotContains(t, body, "configured.example.com")
+	assert.NotContains(t, body, "go_goroutines")
+}
+
+func TestHandleProbe_Down(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:    []string{"https://configured.example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "probe-instance",
+	}
+	e := newProbeTestEcho(t, cfg)
+
+	rec := probe(e, url.Values{"target": {target.URL}})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `protocol="http",url="`+target.URL+`"} 0`)
+}
+
+func TestHandleProbe_UsesConfiguredAssertions(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("status: ok"))
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Checks:     []config.Target{{URL: target.URL, ExpectBody: "ok", Objective: 0.99}},
+		Timeout:    5 * time.Second,
+		InstanceID: "probe-instance",
+	}
+	e := newProbeTestEcho(t, cfg)
+
+	rec := probe(e, url.Values{"target": {target.URL}})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "url_content_match{")
+	assert.NotContains(t, rec.Body.String(), "url_slo_objective")
+}
+
+func TestHandleProbe_Module(t *testing.T) {
+	var methods []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "probe-instance",
+		Modules: map[string]config.Module{
+			"http_json": {Method: http.MethodGet, ExpectBody: `"status":"ok"`},
+		},
+	}
+	e := newProbeTestEcho(t, cfg)
+
+	rec := probe(e, url.Values{"target": {target.URL}, "module": {"http_json"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `url_content_match{host="`+target.URL+`",instance="probe-instance",path="/",protocol="http",url="`+target.URL+`"} 1`)
+
+	rec = probe(e, url.Values{"target": {target.URL}, "module": {"http_2xx"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "url_content_match")
+
+	assert.Equal(t, []string{http.MethodGet, http.MethodHead}, methods)
+}
+
+func TestHandleProbe_BadRequest(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "probe-instance",
+	}
+	e := newProbeTestEcho(t, cfg)
+
+	rec := probe(e, url.Values{})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "target parameter is missing")
+
+	rec = probe(e, url.Values{"target": {"https://example.com"}, "module": {"icmp"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `unknown module "icmp"`)
+}
+
+func TestHandleProbe_TargetPolicy(t *testing.T) {
+	cfg := &config.Config{
+		Targets:      []string{"https://example.com"},
+		Timeout:      5 * time.Second,
+		InstanceID:   "probe-instance",
+		TargetPolicy: config.TargetPolicyConfig{AllowSchemes: []string{"https"}},
+	}
+	e := newProbeTestEcho(t, cfg)
+
+	rec := probe(e, url.Values{"target": {"http://169.254.169.254/latest/meta-data/"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "target not allowed")
+
+	rec = probe(e, url.Values{"target": {"redis://cache:6379"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
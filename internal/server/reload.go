@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/events"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// reloadDiff lists the target URLs affected by a configuration reload, redacted
+type reloadDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// handleReload re-reads the configuration and applies its target changes without a
+// restart, following the Prometheus /-/reload convention. Targets come from the state
+// file when one exists, as on startup. Other settings only take effect after a restart.
+func (s *URLExporterServer) handleReload(c echo.Context) error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	var runtimeTargets []config.Target
+	cfg, err := config.Load()
+	if err == nil {
+		runtimeTargets, err = applyState(cfg)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reload configuration")
+		s.events.Add(events.Event{Type: events.TypeReload, Message: "Failed to reload configuration: " + err.Error()})
+		return respondError(c, http.StatusInternalServerError, "failed to reload configuration: "+err.Error())
+	}
+
+	diff := s.applyTargets(cfg.AllTargets(), runtimeTargets)
+
+	log.Info().
+		Int("added", len(diff.Added)).
+		Int("removed", len(diff.Removed)).
+		Int("changed", len(diff.Changed)).
+		Int("errors", len(diff.Errors)).
+		Msg("Configuration reloaded")
+	s.events.Add(events.Event{
+		Type: events.TypeReload,
+		Message: fmt.Sprintf("Configuration reloaded: %d added, %d removed, %d changed, %d errors",
+			len(diff.Added), len(diff.Removed), len(diff.Changed), len(diff.Errors)),
+	})
+
+	if len(diff.Errors) > 0 {
+		return c.JSON(http.StatusInternalServerError, diff)
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+// applyTargets brings the checker and collector in line with the given target set, the
+// trusted targets and those added at runtime. The targets of Kubernetes resources are
+// left alone.
+func (s *URLExporterServer) applyTargets(targets, runtimeTargets []config.Target) reloadDiff {
+	diff := reloadDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	current := make(map[string]config.Target)
+	for _, target := range s.ownTargets() {
+		current[target.URL] = target
+	}
+
+	// runtime tells for every wanted target whether it is a runtime target
+	runtime := make(map[string]bool, len(targets)+len(runtimeTargets))
+	for _, target := range targets {
+		runtime[target.URL] = false
+	}
+	for _, target := range runtimeTargets {
+		runtime[target.URL] = true
+	}
+
+	for url := range current {
+		if _, wanted := runtime[url]; !wanted {
+			s.checker.RemoveTarget(url)
+			s.collector.RemoveTarget(url)
+			diff.Removed = append(diff.Removed, config.RedactURL(url))
+			s.events.Add(events.Event{Type: events.TypeTargetRemoved, Target: url, Message: "Target removed by reload"})
+		}
+	}
+
+	for _, target := range append(targets[:len(targets):len(targets)], runtimeTargets...) {
+		existing, exists := current[target.URL]
+		wasRuntime := exists && s.checker.IsRuntimeTarget(target.URL)
+		if exists && reflect.DeepEqual(existing, target) && wasRuntime == runtime[target.URL] {
+			continue
+		}
+
+		if exists {
+			s.checker.RemoveTarget(target.URL)
+		}
+		if err := s.addTarget(target, runtime[target.URL]); err != nil {
+			diff.Errors = append(diff.Errors, err.Error())
+			if exists {
+				// keep checking the previous settings rather than dropping the target
+				_ = s.addTarget(existing, wasRuntime)
+			}
+			continue
+		}
+		s.collector.AddTarget(target)
+
+		if exists {
+			diff.Changed = append(diff.Changed, config.RedactURL(target.URL))
+		} else {
+			diff.Added = append(diff.Added, config.RedactURL(target.URL))
+			s.events.Add(events.Event{Type: events.TypeTargetAdded, Target: target.URL, Message: "Target added by reload"})
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+// addTarget registers the target with the checker, subject to the target policy when it
+// is a runtime target
+func (s *URLExporterServer) addTarget(target config.Target, runtime bool) error {
+	if runtime {
+		return s.checker.AddRuntimeTarget(target)
+	}
+	return s.checker.AddTarget(target)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// flappingWindow is the period state changes are counted over by the flapping rule
+const flappingWindow = "1h"
+
+// ruleFile is a Prometheus rule file
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []alertRule `yaml:"rules"`
+}
+
+type alertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// handleAlertRules returns Prometheus alerting rules for the targets this exporter checks,
+// built from their alert thresholds: down, slow, certificate expiring and flapping. Rules
+// of metric families turned off in metrics.disabled are left out. Like the Grafana
+// dashboard, the instanceLabel query parameter names the label of the instance ID.
+func (s *URLExporterServer) handleAlertRules(c echo.Context) error {
+	instanceLabel, reqErr := instanceLabelParam(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	content, err := yaml.Marshal(s.alertRules(instanceLabel))
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, "failed to encode rules: "+err.Error())
+	}
+	return c.Blob(http.StatusOK, "application/yaml; charset=utf-8", content)
+}
+
+func (s *URLExporterServer) alertRules(instanceLabel string) ruleFile {
+	group := ruleGroup{Name: "url-exporter", Rules: []alertRule{}}
+	for _, target := range s.checker.Targets() {
+		if target.Disabled || target.Alerts.Disabled {
+			continue
+		}
+		group.Rules = append(group.Rules, s.targetAlertRules(target, instanceLabel)...)
+	}
+	return ruleFile{Groups: []ruleGroup{group}}
+}
+
+// targetAlertRules returns the rules of the target
+func (s *URLExporterServer) targetAlertRules(target config.Target, instanceLabel string) []alertRule {
+	thresholds := s.config.TargetAlertRules(target)
+	targetURL := config.RedactURL(target.URL)
+	selector := fmt.Sprintf("{url=%s, %s=%s}", strconv.Quote(targetURL), instanceLabel, strconv.Quote(s.config.InstanceID))
+
+	display := target.Name
+	if display == "" {
+		display = targetURL
+	}
+	rule := func(alert, severity, expr string, period time.Duration, summary string) alertRule {
+		r := alertRule{
+			Alert:       alert,
+			Expr:        expr,
+			Labels:      map[string]string{"severity": severity},
+			Annotations: map[string]string{"summary": display + " " + summary},
+		}
+		if period > 0 {
+			r.For = promDuration(period)
+		}
+		if target.Group != "" {
+			r.Labels["group"] = target.Group
+		}
+		if target.Description != "" {
+			r.Annotations["description"] = target.Description
+		}
+		if target.RunbookURL != "" {
+			r.Annotations["runbook_url"] = target.RunbookURL
+		}
+		return r
+	}
+
+	// enabled reports whether the alert applies to the target and its metric is exported
+	enabled := func(alert, family string, threshold bool) bool {
+		return threshold && thresholds.AlertEnabled(alert) && s.collector.Enabled(family)
+	}
+
+	var rules []alertRule
+	if enabled(config.AlertURLDown, "url_up", true) {
+		rules = append(rules, rule(config.AlertURLDown, "critical", "url_up"+selector+" == 0", thresholds.For, "is down"))
+	}
+	if enabled(config.AlertURLSlow, "url_response_time_milliseconds", thresholds.Slow > 0) {
+		expr := fmt.Sprintf("url_response_time_milliseconds%s > %d", selector, thresholds.Slow.Milliseconds())
+		rules = append(rules, rule(config.AlertURLSlow, "warning", expr, thresholds.For, "responds slower than "+promDuration(thresholds.Slow)))
+	}
+	if enabled(config.AlertURLCertificateExpiring, "url_tls_cert_expiry_timestamp_seconds", thresholds.CertExpiry > 0 && strings.HasPrefix(target.URL, "https://")) {
+		expr := fmt.Sprintf("url_tls_cert_expiry_timestamp_seconds%s - time() < %d", selector, int64(thresholds.CertExpiry.Seconds()))
+		rules = append(rules, rule(config.AlertURLCertificateExpiring, "warning", expr, 0, "has a certificate expiring within "+promDuration(thresholds.CertExpiry)))
+	}
+	if enabled(config.AlertURLFlapping, "url_up", thresholds.Flapping > 0) {
+		expr := fmt.Sprintf("changes(url_up%s[%s]) >= %d", selector, flappingWindow, thresholds.Flapping)
+		rules = append(rules, rule(config.AlertURLFlapping, "warning", expr, 0, fmt.Sprintf("changed state %d times or more within %s", thresholds.Flapping, flappingWindow)))
+	}
+	return rules
+}
+
+// promDuration formats the duration for Prometheus, which accepts no fractions
+func promDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	case d%time.Second == 0:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	default:
+		return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+	}
+}
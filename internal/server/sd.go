@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/labstack/echo/v4"
+)
+
+// invalidLabelChars matches characters that are not allowed in Prometheus label names
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// sdTargetGroup is a single entry of the Prometheus http_sd_config format
+type sdTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// handleSDTargets lists the probe targets in Prometheus http_sd_config format, one group
+// per target carrying its group and labels. A configured module is passed as
+// __param_module so discovered targets can be scraped through /probe unchanged.
+// Disabled targets are left out.
+func (s *URLExporterServer) handleSDTargets(c echo.Context) error {
+	groups := make([]sdTargetGroup, 0)
+	for _, target := range s.checker.Targets() {
+		if target.Disabled {
+			continue
+		}
+
+		labels := map[string]string{
+			"__meta_url_exporter_instance": s.config.InstanceID,
+		}
+		for name, value := range target.Labels {
+			labels[sanitizeLabelName(name)] = value
+		}
+		if target.Group != "" {
+			labels["group"] = target.Group
+		}
+		if target.Module != "" {
+			labels["__param_module"] = target.Module
+		}
+
+		groups = append(groups, sdTargetGroup{
+			Targets: []string{target.URL},
+			Labels:  labels,
+		})
+	}
+
+	return c.JSON(http.StatusOK, groups)
+}
+
+// sanitizeLabelName replaces characters that are invalid in label names with underscores
+func sanitizeLabelName(name string) string {
+	name = invalidLabelChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
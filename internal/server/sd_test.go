@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSDTargets(t *testing.T) {
+	cfg := &config.Config{
+		Targets: []string{"https://example.com"},
+		Checks: []config.Target{
+			{
+				URL:    "https://api.example.com/health",
+				Group:  "api",
+				Module: "http_json",
+				Labels: map[string]string{"team": "payments", "cost-center": "42"},
+			},
+			{URL: "https://paused.example.com", Disabled: true},
+		},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		Modules:    map[string]config.Module{"http_json": {Method: http.MethodGet}},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	var groups []sdTargetGroup
+	code := getJSON(t, server, "/sd/targets", &groups)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []sdTargetGroup{
+		{
+			Targets: []string{"https://example.com"},
+			Labels:  map[string]string{"__meta_url_exporter_instance": "test-instance"},
+		},
+		{
+			Targets: []string{"https://api.example.com/health"},
+			Labels: map[string]string{
+				"__meta_url_exporter_instance": "test-instance",
+				"group":                        "api",
+				"team":                         "payments",
+				"cost_center":                  "42",
+				"__param_module":               "http_json",
+			},
+		},
+	}, groups)
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	assert.Equal(t, "team", sanitizeLabelName("team"))
+	assert.Equal(t, "cost_center", sanitizeLabelName("cost-center"))
+	assert.Equal(t, "_1st", sanitizeLabelName("1st"))
+}
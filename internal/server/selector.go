@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// labelParamPrefix prefixes the query parameters selecting targets by label, e.g.
+// label.team=payments
+const labelParamPrefix = "label."
+
+// selector selects targets by the group, host, tenant and label.<name> query parameters
+// shared by the JSON APIs. Each parameter lists alternatives separated by commas and a target must
+// match every parameter given; an empty label value matches targets without the label.
+type selector struct {
+	groups  []string
+	hosts   []string
+	tenants []string
+	labels  map[string][]string
+}
+
+// parseSelector reads the selector from the query parameters. The requests of a tenant
+// only select the tenant's targets.
+func parseSelector(c echo.Context) (selector, *requestError) {
+	params := c.QueryParams()
+	s := selector{
+		groups:  splitParam(params, "group"),
+		hosts:   splitParam(params, "host"),
+		tenants: splitParam(params, "tenant"),
+		labels:  make(map[string][]string),
+	}
+	if tenant := requestTenant(c); tenant != "" {
+		s.tenants = []string{tenant}
+	}
+	for param := range params {
+		name, isLabel := strings.CutPrefix(param, labelParamPrefix)
+		if !isLabel {
+			continue
+		}
+		if name == "" {
+			return selector{}, &requestError{http.StatusBadRequest, "label parameters must name the label, e.g. label.team=payments"}
+		}
+		s.labels[name] = splitParam(params, param)
+	}
+	return s, nil
+}
+
+// splitParam returns the comma-separated alternatives of all values of the parameter
+func splitParam(params url.Values, name string) []string {
+	var values []string
+	for _, value := range params[name] {
+		values = append(values, strings.Split(value, ",")...)
+	}
+	return values
+}
+
+// matches reports whether the selector selects the target
+func (s selector) matches(target config.Target) bool {
+	if len(s.groups) > 0 && !slices.Contains(s.groups, target.Group) {
+		return false
+	}
+	if len(s.hosts) > 0 && !slices.Contains(s.hosts, checker.TargetHost(target.URL)) {
+		return false
+	}
+	if len(s.tenants) > 0 && !slices.Contains(s.tenants, target.Tenant) {
+		return false
+	}
+	for name, values := range s.labels {
+		if !slices.Contains(values, target.Labels[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// statusFilter selects results by the status query parameter: up, down (checked but not
+// up) or empty for both
+type statusFilter string
+
+// parseStatusFilter reads the status query parameter
+func parseStatusFilter(params url.Values) (statusFilter, *requestError) {
+	status := statusFilter(params.Get("status"))
+	if status != "" && status != stateUp && status != stateDown {
+		return "", &requestError{http.StatusBadRequest, "status must be up or down"}
+	}
+	return status, nil
+}
+
+// matches reports whether the filter selects a target that was checked or not and whose
+// latest result is up or not
+func (f statusFilter) matches(checked, up bool) bool {
+	return f == "" || checked && up == (f == stateUp)
+}
@@ -3,16 +3,32 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jasoet/pkg/server"
 	"github.com/jasoet/url-exporter/internal/checker"
 	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/controller"
+	"github.com/jasoet/url-exporter/internal/events"
+	"github.com/jasoet/url-exporter/internal/heartbeat"
+	"github.com/jasoet/url-exporter/internal/leader"
+	"github.com/jasoet/url-exporter/internal/metadata"
 	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/jasoet/url-exporter/internal/notify"
+	"github.com/jasoet/url-exporter/internal/sink"
+	"github.com/jasoet/url-exporter/internal/tracing"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // VersionInfo holds version information injected at build time
@@ -28,30 +44,383 @@ type URLExporterServer struct {
 	config    *config.Config
 	checker   *checker.Checker
 	collector *metrics.Collector
+	registry  *prometheus.Registry
+	graphite  *sink.GraphiteSink
+	influxdb  *sink.InfluxDBSink
+	audit     *sink.AuditSink
+	kafka     *sink.KafkaSink
+	nats      *sink.NATSSink
+	heartbeat *heartbeat.Heartbeat
+	tracer    *sdktrace.TracerProvider
+	reports   *notify.Reports
+	events    *events.Log
+	elector   *leader.Elector
+	urlChecks *controller.Controller
+	discovery *controller.Discovery
 	version   *VersionInfo
+	startedAt time.Time
+	limits    []echo.MiddlewareFunc
+
+	// registerer registers with the registry, adding the instance metadata labels
+	registerer     prometheus.Registerer
+	instanceLabels prometheus.Labels
+
+	// checkedAll is set once every target has been checked, see pendingFirstChecks
+	checkedAll atomic.Bool
+
+	reloadMutex sync.Mutex
+	// stopElection releases the leader lock on shutdown
+	stopElection func()
 }
 
 func New(cfg *config.Config, version *VersionInfo) (*URLExporterServer, error) {
+	runtimeTargets, err := applyState(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checker.LoadPlugins(cfg.Plugins); err != nil {
+		return nil, err
+	}
+
 	chk := checker.New(cfg)
 	col := metrics.NewCollector(cfg, chk)
+	chk.AddSink(col)
+	for _, target := range runtimeTargets {
+		if err := chk.AddRuntimeTarget(target); err != nil {
+			log.Error().Err(err).Str("url", config.RedactURL(target.URL)).Msg("Dropping target from state file")
+			continue
+		}
+		col.AddTarget(target)
+	}
 
-	if err := col.Register(); err != nil {
+	var instanceLabels prometheus.Labels
+	if cfg.Metadata.Enabled() {
+		labels, err := metadata.Lookup(context.Background(), cfg.Metadata)
+		if err != nil {
+			// Monitoring without the labels beats not monitoring
+			log.Warn().Err(err).Msg("Failed to look up instance metadata, metrics go without its labels")
+		} else {
+			log.Info().Interface("labels", labels).Msg("Instance metadata looked up")
+		}
+		instanceLabels = labels
+	}
+
+	// Everything registered through registerer carries the instance metadata labels
+	registry := prometheus.NewRegistry()
+	registerer := prometheus.WrapRegistererWith(instanceLabels, registry)
+	if err := registerer.Register(collectors.NewGoCollector()); err != nil {
+		return nil, fmt.Errorf("failed to register go collector: %w", err)
+	}
+	if err := registerer.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, fmt.Errorf("failed to register process collector: %w", err)
+	}
+	if err := col.Register(registerer); err != nil {
 		return nil, fmt.Errorf("failed to register metrics collector: %w", err)
 	}
+	droppedResults := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "url_exporter_results_dropped_total",
+		Help: "Check results dropped for result stream subscribers that did not keep up",
+	}, func() float64 {
+		return float64(chk.DroppedResults())
+	})
+	if err := registerer.Register(droppedResults); err != nil {
+		return nil, fmt.Errorf("failed to register dropped results counter: %w", err)
+	}
+	if resolver := chk.DNSCache(); resolver != nil {
+		hits := prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "url_exporter_dns_cache_hits_total",
+			Help: "DNS lookups of check targets answered from the DNS cache",
+		}, func() float64 {
+			return float64(resolver.Hits())
+		})
+		misses := prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "url_exporter_dns_cache_misses_total",
+			Help: "DNS lookups of check targets that queried DNS",
+		}, func() float64 {
+			return float64(resolver.Misses())
+		})
+		if err := registerer.Register(hits); err != nil {
+			return nil, fmt.Errorf("failed to register DNS cache hits counter: %w", err)
+		}
+		if err := registerer.Register(misses); err != nil {
+			return nil, fmt.Errorf("failed to register DNS cache misses counter: %w", err)
+		}
+	}
 
 	s := &URLExporterServer{
 		config:    cfg,
 		checker:   chk,
 		collector: col,
+		registry:  registry,
 		version:   version,
+
+		registerer:     registerer,
+		instanceLabels: instanceLabels,
+	}
+	s.limits = s.newRequestLimits()
+
+	eventsSize := cfg.Events.Size
+	if eventsSize <= 0 {
+		eventsSize = config.DefaultEventsSize
+	}
+	s.events = events.New(eventsSize)
+
+	if cfg.History.File != "" {
+		// A lost history must not keep the exporter from monitoring
+		if err := col.LoadHistory(cfg.History.File); err != nil {
+			log.Warn().Err(err).Msg("Failed to restore check history")
+		}
+	}
+
+	if cfg.Graphite.Enabled {
+		s.graphite = sink.NewGraphiteSink(cfg.Graphite, cfg.InstanceID, col)
+	}
+
+	if cfg.InfluxDB.Enabled {
+		s.influxdb = sink.NewInfluxDBSink(cfg.InfluxDB, cfg.InstanceID, cfg.Timeout)
+		chk.OnCycle(s.influxdb.HandleCycle)
+	}
+
+	if cfg.Kafka.Enabled {
+		kafka, err := sink.NewKafkaSink(cfg.Kafka, cfg.InstanceID, cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up the kafka sink: %w", err)
+		}
+		s.kafka = kafka
+		chk.OnCycle(kafka.HandleCycle)
+		if err := registerSinkDrops(registerer, "kafka", kafka.DroppedResults); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.NATS.Enabled {
+		nats, err := sink.NewNATSSink(cfg.NATS, cfg.InstanceID, cfg.Timeout, chk.Lookup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up the nats sink: %w", err)
+		}
+		s.nats = nats
+		chk.OnCycle(nats.HandleCycle)
+		if err := registerSinkDrops(registerer, "nats", nats.DroppedResults); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.AuditLog.Enabled() {
+		audit, err := sink.NewAuditSink(cfg.AuditLog, cfg.InstanceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		s.audit = audit
+		chk.OnCycle(audit.HandleCycle)
+	}
+
+	if cfg.Heartbeat.Enabled() {
+		s.heartbeat = heartbeat.New(cfg.Heartbeat, cfg.Timeout)
+	}
+
+	if cfg.Tracing.Enabled() {
+		provider, err := tracing.New(cfg.Tracing, cfg.InstanceID, version.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		s.tracer = provider
+	}
+
+	dispatcher, err := notify.New(cfg, chk, col)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up notifications: %w", err)
+	}
+	if dispatcher != nil {
+		chk.OnCycle(dispatcher.HandleCycle)
+	}
+	s.reports = notify.NewReports(cfg, chk, col)
+
+	if cfg.Leader.Enabled() {
+		if err := s.setupLeaderElection(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Controller.Enabled {
+		if err := s.setupController(); err != nil {
+			return nil, fmt.Errorf("failed to set up controller mode: %w", err)
+		}
+	}
+
+	if cfg.Discovery.Enabled() {
+		if err := s.setupDiscovery(); err != nil {
+			return nil, fmt.Errorf("failed to set up discovery: %w", err)
+		}
 	}
 
 	return s, nil
 }
 
+// registerSinkDrops exports the number of results a broker sink dropped because the broker
+// did not keep up
+func registerSinkDrops(registerer prometheus.Registerer, sink string, dropped func() uint64) error {
+	counter := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name:        "url_exporter_sink_results_dropped_total",
+		Help:        "Check results dropped for broker sinks that did not keep up",
+		ConstLabels: prometheus.Labels{"sink": sink},
+	}, func() float64 {
+		return float64(dropped())
+	})
+	if err := registerer.Register(counter); err != nil {
+		return fmt.Errorf("failed to register %s dropped results counter: %w", sink, err)
+	}
+	return nil
+}
+
+// setupLeaderElection makes the checks run only while this replica is the leader
+func (s *URLExporterServer) setupLeaderElection() error {
+	elector, err := leader.New(s.config.Leader)
+	if err != nil {
+		return fmt.Errorf("failed to set up leader election: %w", err)
+	}
+	elector.OnChange = func(leading bool) {
+		message := "Became leader as " + elector.Identity()
+		if !leading {
+			message = "Stopped leading as " + elector.Identity()
+		}
+		s.events.Add(events.Event{Type: events.TypeLeadership, Message: message})
+	}
+	s.elector = elector
+
+	leaderGauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "url_exporter_leader",
+		Help: "Whether this replica is the elected leader running the checks (1) or a standby (0)",
+	}, func() float64 {
+		if elector.IsLeader() {
+			return 1
+		}
+		return 0
+	})
+	if err := s.registerer.Register(leaderGauge); err != nil {
+		return fmt.Errorf("failed to register leader gauge: %w", err)
+	}
+	return nil
+}
+
+// isLeader reports whether this replica runs the checks, which it always does without
+// leader election
+func (s *URLExporterServer) isLeader() bool {
+	return s.elector == nil || s.elector.IsLeader()
+}
+
+// applyState replaces the configured targets with those persisted in the state file, if
+// one is configured and exists. It returns the persisted targets that were added at
+// runtime, to be added with AddRuntimeTarget.
+func applyState(cfg *config.Config) ([]config.Target, error) {
+	if cfg.API.StateFile == "" {
+		return nil, nil
+	}
+
+	st, ok, err := config.LoadState(cfg.API.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target state: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	log.Info().
+		Str("state_file", cfg.API.StateFile).
+		Int("targets", len(st.Targets)).
+		Int("runtime_targets", len(st.RuntimeTargets)).
+		Msg("Using targets from state file")
+	cfg.Targets = nil
+	cfg.Checks = st.Targets
+	return st.RuntimeTargets, nil
+}
+
 func (s *URLExporterServer) setupRoutes(e *echo.Echo) {
-	e.GET("/", s.handleRoot)
-	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+	e.GET("/health", s.handleHealth)
+	e.GET("/-/healthy", s.handleHealthy)
+	e.GET("/-/ready", s.handleReady)
+	e.GET("/-/started", s.handleStarted)
+
+	protected := s.protected()
+	e.GET("/", s.handleRoot, protected...)
+	e.GET("/metrics", s.handleMetrics(promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{})), protected...)
+	e.GET("/probe", s.handleProbe, slices.Concat(s.limits, protected)...)
+	e.GET("/ui", s.handleUI, protected...)
+	if s.config.StatusPage.Enabled {
+		statusPage := protected
+		if s.config.StatusPage.Public {
+			statusPage = nil
+		}
+		e.GET("/status", s.handleStatusPage, statusPage...)
+	}
+	e.GET("/sd/targets", s.handleSDTargets, protected...)
+	e.GET("/api/openapi.json", s.handleOpenAPI, protected...)
+	e.GET("/api/v1/events", s.handleEvents, protected...)
+
+	// Tenants can read the metrics, results and history of their own targets
+	tenantProtected := s.tenantProtected()
+	e.GET("/metrics/:tenant", s.handleTenantMetrics, tenantProtected...)
+	e.GET("/api/v1/targets", s.handleTargets, tenantProtected...)
+	e.GET("/api/v1/targets/:name/history", s.handleTargetHistory, tenantProtected...)
+	e.GET("/api/v1/targets/:name/history/export", s.handleTargetHistoryExport, tenantProtected...)
+	e.GET("/api/v1/incidents", s.handleIncidents, tenantProtected...)
+	e.GET("/api/v1/results", s.handleResults, tenantProtected...)
+	e.GET("/api/v1/status", s.handleStatus, tenantProtected...)
+	e.GET("/api/v1/stream", s.handleStream, tenantProtected...)
+	e.POST("/api/v1/check", s.handleCheck, slices.Concat(s.limits, protected)...)
+	e.GET("/api/v1/config", s.handleConfig, protected...)
+	e.GET("/api/v1/grafana/dashboard", s.handleGrafanaDashboard, protected...)
+	e.GET("/api/v1/prometheus/rules", s.handleAlertRules, protected...)
+
+	// Target management and reloads have their own token and do not accept the general
+	// credentials. With a separate admin listener they are only served there.
+	if !s.config.Admin.Enabled() && s.config.API.Token != "" {
+		s.setupManagementRoutes(e)
+	}
+}
+
+// handleMetrics serves the registry, running a full check cycle first when the exporter
+// is in scrape-triggered probe mode
+func (s *URLExporterServer) handleMetrics(metricsHandler http.Handler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.config.ProbeMode == config.ProbeModeScrape && s.isLeader() {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), s.scrapeTimeout(c.Request()))
+			defer cancel()
+
+			// The collector records the results as a sink of the checker
+			if _, err := s.checker.RunCycle(ctx); err != nil {
+				log.Error().Err(err).Msg("Scrape-triggered check cycle failed")
+			}
+		}
+
+		metricsHandler.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// scrapeTimeout returns the configured per-scrape timeout, shortened to the timeout
+// Prometheus advertises in X-Prometheus-Scrape-Timeout-Seconds when that is lower
+func (s *URLExporterServer) scrapeTimeout(r *http.Request) time.Duration {
+	timeout := s.config.ScrapeTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	if header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); header != "" {
+		if seconds, err := strconv.ParseFloat(header, 64); err == nil && seconds > 0 {
+			if advertised := time.Duration(seconds * float64(time.Second)); advertised < timeout {
+				timeout = advertised
+			}
+		}
+	}
+
+	return timeout
+}
+
+// Registry returns the server's dedicated Prometheus registry, allowing embedders to
+// register additional collectors or serve the metrics from their own handler
+func (s *URLExporterServer) Registry() *prometheus.Registry {
+	return s.registry
 }
 
 func (s *URLExporterServer) handleRoot(c echo.Context) error {
@@ -62,22 +431,74 @@ func (s *URLExporterServer) handleRoot(c echo.Context) error {
 		"date":      s.version.Date,
 		"built_by":  s.version.BuiltBy,
 		"instance":  s.config.InstanceID,
-		"targets":   len(s.config.Targets),
+		"targets":   len(s.checker.Targets()),
 		"status":    "running",
-		"endpoints": []string{"/", "/health", "/metrics"},
+		"endpoints": []string{"/", "/health", "/-/healthy", "/-/ready", "/-/started", "/metrics", "/metrics/{tenant}", "/probe", "/ui", "/sd/targets", "/api/openapi.json", "/api/v1/targets", "/api/v1/targets/{name}/history", "/api/v1/targets/{name}/history/export", "/api/v1/incidents", "/api/v1/events", "/api/v1/results", "/api/v1/status", "/api/v1/stream", "/api/v1/check", "/api/v1/config", "/api/v1/grafana/dashboard", "/api/v1/prometheus/rules"},
 	}
 	return c.JSON(http.StatusOK, info)
 }
 
 func (s *URLExporterServer) startBackgroundWorkers(ctx context.Context) {
-	go s.checker.Start(ctx)
-	go s.collector.Start(ctx)
+	s.startedAt = time.Now()
+
+	// Subscribe before the first check so no state change is missed
+	results, unsubscribe := s.checker.Subscribe()
+	go s.recordResultEvents(ctx, results, unsubscribe)
+
+	// Standbys watch the Kubernetes resources too, to have their targets when they take over
+	if s.urlChecks != nil {
+		go s.urlChecks.Run(ctx)
+	}
+	if s.discovery != nil {
+		go s.discovery.Run(ctx)
+	}
+
+	if s.elector == nil {
+		s.startActiveWorkers(ctx)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.elector.Run(ctx, s.startActiveWorkers)
+	}()
+	s.stopElection = func() {
+		cancel()
+		<-done
+	}
+}
+
+// startActiveWorkers starts the work that probes targets or sends data out, which only
+// the leader does when leader election is enabled. The workers stop when ctx is done.
+func (s *URLExporterServer) startActiveWorkers(ctx context.Context) {
+	if s.config.ProbeMode != config.ProbeModeScrape {
+		go s.checker.Start(ctx)
+	}
+
+	if s.graphite != nil {
+		go s.graphite.Start(ctx)
+	}
+
+	if s.heartbeat != nil {
+		go s.heartbeat.Start(ctx)
+	}
+
+	if s.reports != nil {
+		go s.reports.Start(ctx)
+	}
 }
 
 func (s *URLExporterServer) Start() error {
-	log.Info().Int("port", s.config.ListenPort).Msg("Starting URL Exporter server")
+	address := s.config.Address()
+
+	log.Info().
+		Str("address", address).
+		Bool("tls", s.config.ServerTLS.Enabled()).
+		Msg("Starting URL Exporter server")
 
-	server.Start(
+	serverConfig := server.DefaultConfig(
 		s.config.ListenPort,
 		func(e *echo.Echo) {
 			s.setupRoutes(e)
@@ -90,16 +511,92 @@ func (s *URLExporterServer) Start() error {
 		func(e *echo.Echo) {
 			log.Info().Msg("Shutting down URL Exporter server")
 
+			// The checks in flight finish and are recorded before anything is flushed, and
+			// before leadership is given up so a standby does not repeat them
+			drainCtx, drainCancel := context.WithTimeout(context.Background(), s.config.DrainTimeout)
+			if err := s.checker.Shutdown(drainCtx); err != nil {
+				log.Error().Err(err).Msg("Failed to shutdown checker")
+			}
+			drainCancel()
+
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			if err := s.checker.Shutdown(ctx); err != nil {
-				log.Error().Err(err).Msg("Failed to shutdown checker")
+			leading := s.isLeader()
+			if s.stopElection != nil {
+				s.stopElection()
+			}
+			if s.graphite != nil && leading {
+				if err := s.graphite.Flush(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to flush metrics to Graphite")
+				}
+			}
+
+			if s.config.History.File != "" {
+				if err := s.collector.SaveHistory(s.config.History.File); err != nil {
+					log.Error().Err(err).Msg("Failed to save check history")
+				}
+			}
+
+			if s.tracer != nil {
+				if err := s.tracer.Shutdown(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to flush traces")
+				}
+			}
+
+			if s.audit != nil {
+				if err := s.audit.Close(); err != nil {
+					log.Error().Err(err).Msg("Failed to close audit log")
+				}
+			}
+
+			if s.kafka != nil {
+				if err := s.kafka.Close(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to close Kafka connections")
+				}
+			}
+
+			if s.nats != nil {
+				if err := s.nats.Close(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to close NATS connection")
+				}
 			}
 
 			log.Info().Msg("URL Exporter server shutdown complete")
 		},
 	)
 
+	if s.config.AccessLog.Enabled {
+		log.Logger = log.Logger.Hook(builtinRequestLogFilter{})
+		serverConfig.Middleware = append(serverConfig.Middleware, accessLogger(s.config.AccessLog))
+	}
+
+	var listener net.Listener
+	var err error
+	if s.config.ServerTLS.Enabled() {
+		listener, err = newTLSListener(address, s.config.ServerTLS)
+		if err != nil {
+			return fmt.Errorf("failed to set up TLS: %w", err)
+		}
+	} else {
+		listener, err = listen(address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+	}
+	// echo serves on the preset listener instead of opening its own on all interfaces
+	serverConfig.EchoConfigurer = func(e *echo.Echo) {
+		e.Listener = listener
+	}
+
+	if s.config.Admin.Enabled() {
+		if err := s.startAdminServer(); err != nil {
+			_ = listener.Close()
+			return err
+		}
+	}
+
+	server.StartWithConfig(serverConfig)
+
 	return nil
 }
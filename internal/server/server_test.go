@@ -5,12 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/jasoet/url-exporter/internal/checker"
 	"github.com/jasoet/url-exporter/internal/config"
-	"github.com/jasoet/url-exporter/internal/metrics"
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
@@ -27,25 +27,9 @@ func testVersionInfo() *VersionInfo {
 	}
 }
 
-// createTestServer creates a server with isolated Prometheus registry to avoid conflicts
+// createTestServer creates a server for testing; every server owns its own Prometheus registry
 func createTestServer(cfg *config.Config) (*URLExporterServer, error) {
-	chk := checker.New(cfg)
-	col := metrics.NewCollector(cfg, chk)
-
-	// Create a separate registry for each test to avoid conflicts
-	registry := prometheus.NewRegistry()
-	if err := registry.Register(col); err != nil {
-		return nil, err
-	}
-
-	s := &URLExporterServer{
-		config:    cfg,
-		checker:   chk,
-		collector: col,
-		version:   testVersionInfo(),
-	}
-
-	return s, nil
+	return New(cfg, testVersionInfo())
 }
 
 func TestNew(t *testing.T) {
@@ -68,7 +52,54 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, server.collector)
 }
 
-func TestNew_WithRegistrationFailure(t *testing.T) {
+func TestNew_ControllerOutsideCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    10 * time.Second,
+		InstanceID: "test-instance",
+		Controller: config.ControllerConfig{Enabled: true},
+	}
+
+	_, err := createTestServer(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not running in a Kubernetes cluster")
+}
+
+func TestNew_InstanceMetadataLabels(t *testing.T) {
+	t.Setenv("REGION", "eu-west-1")
+	t.Setenv("ZONE", "eu-west-1b")
+	t.Setenv("NODE_NAME", "")
+
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    10 * time.Second,
+		InstanceID: "test-instance",
+		Metadata:   config.MetadataConfig{Provider: config.MetadataEnv, Timeout: time.Second},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+	server.collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, Timestamp: time.Now()})
+
+	families, err := server.registry.Gather()
+	require.NoError(t, err)
+	require.NotEmpty(t, families)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			assert.Equal(t, "eu-west-1", labels["region"], family.GetName())
+			assert.Equal(t, "eu-west-1b", labels["zone"], family.GetName())
+			assert.NotContains(t, labels, "node", family.GetName())
+		}
+	}
+}
+
+func TestNew_MultipleServers(t *testing.T) {
 	cfg := &config.Config{
 		Targets:       []string{"https://example.com"},
 		CheckInterval: 30 * time.Second,
@@ -79,18 +110,33 @@ func TestNew_WithRegistrationFailure(t *testing.T) {
 		LogLevel:      "info",
 	}
 
-	// Test actual New function which uses global registry
+	// Each server uses a dedicated registry, so creating several must not fail
+	// with a duplicate collector registration
 	version := testVersionInfo()
 	server1, err := New(cfg, version)
-	assert.NoError(t, err)
-	assert.NotNil(t, server1)
+	require.NoError(t, err)
+
+	server2, err := New(cfg, version)
+	require.NoError(t, err)
+
+	assert.NotSame(t, server1.Registry(), server2.Registry())
+}
 
-	// Second server creation might fail due to duplicate collector registration
-	// This is expected behavior with global registry
-	_, err2 := New(cfg, version)
-	if err2 != nil {
-		assert.Contains(t, err2.Error(), "failed to register metrics collector")
+func TestNew_RegistryDoesNotUseGlobalRegistry(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		InstanceID: "test-instance",
 	}
+
+	server, err := New(cfg, testVersionInfo())
+	require.NoError(t, err)
+
+	// The collector must be registered on the server registry only
+	assert.False(t, prometheus.DefaultRegisterer.Unregister(server.collector))
+
+	err = server.Registry().Register(server.collector)
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	assert.ErrorAs(t, err, &alreadyRegistered)
 }
 
 func TestURLExporterServer_HandleRoot(t *testing.T) {
@@ -501,3 +547,87 @@ func TestURLExporterServer_Start_ConfigValidation(t *testing.T) {
 		assert.NotEmpty(t, server.config.InstanceID)
 	})
 }
+
+func TestURLExporterServer_ScrapeMode_ChecksOnScrape(t *testing.T) {
+	hits := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:       []string{target.URL},
+		CheckInterval: 30 * time.Second,
+		Timeout:       5 * time.Second,
+		InstanceID:    "scrape-instance",
+		ProbeMode:     config.ProbeModeScrape,
+		ScrapeTimeout: 5 * time.Second,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	for i := 1; i <= 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `url_up{host="`+target.URL+`"`)
+		assert.Equal(t, i, hits, "each scrape should trigger exactly one check")
+		// Each result is recorded once, through the collector's sink registration
+		assert.Regexp(t, `url_check_total\{[^}]*\} `+strconv.Itoa(i)+`\n`, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), "url_exporter_results_dropped_total 0")
+	}
+}
+
+func TestURLExporterServer_IntervalMode_DoesNotCheckOnScrape(t *testing.T) {
+	hits := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Targets:    []string{target.URL},
+		Timeout:    5 * time.Second,
+		InstanceID: "interval-instance",
+		ProbeMode:  config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 0, hits)
+	assert.NotContains(t, rec.Body.String(), "url_up{")
+}
+
+func TestURLExporterServer_ScrapeTimeout(t *testing.T) {
+	server := &URLExporterServer{config: &config.Config{ScrapeTimeout: 10 * time.Second}}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	assert.Equal(t, 10*time.Second, server.scrapeTimeout(req))
+
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "4.5")
+	assert.Equal(t, 4500*time.Millisecond, server.scrapeTimeout(req))
+
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "30")
+	assert.Equal(t, 10*time.Second, server.scrapeTimeout(req))
+
+	server.config.ScrapeTimeout = 0
+	req.Header.Del("X-Prometheus-Scrape-Timeout-Seconds")
+	assert.Equal(t, 10*time.Second, server.scrapeTimeout(req))
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// Target states reported by /api/v1/status and the status UI
+const (
+	stateUp       = "up"
+	stateDown     = "down"
+	stateError    = "error"
+	statePending  = "pending"
+	stateDisabled = "disabled"
+	stateSkipped  = "skipped"
+)
+
+// statusCounts is the number of targets in each state
+type statusCounts struct {
+	Total    int `json:"total"`
+	Up       int `json:"up"`
+	Down     int `json:"down"`
+	Error    int `json:"error"`
+	Pending  int `json:"pending"`
+	Disabled int `json:"disabled"`
+	Skipped  int `json:"skipped"`
+}
+
+// statusSummary is the JSON body of /api/v1/status
+type statusSummary struct {
+	Instance            string       `json:"instance"`
+	Version             string       `json:"version"`
+	ProbeMode           string       `json:"probe_mode"`
+	Targets             statusCounts `json:"targets"`
+	LastCycle           *time.Time   `json:"last_cycle"`
+	LastCycleAgeSeconds *float64     `json:"last_cycle_age_seconds,omitempty"`
+	StartedAt           *time.Time   `json:"started_at"`
+	UptimeSeconds       float64      `json:"uptime_seconds"`
+}
+
+// targetState classifies a target: disabled, pending until its first result, up on a 2xx,
+// skipped while a dependency is down, error when the check itself failed and down otherwise
+func targetState(target config.Target, result checker.Result, checked bool) string {
+	switch {
+	case target.Disabled:
+		return stateDisabled
+	case !checked:
+		return statePending
+	case result.IsUp():
+		return stateUp
+	case result.IsSkipped():
+		return stateSkipped
+	case result.Error != nil:
+		return stateError
+	default:
+		return stateDown
+	}
+}
+
+func (c *statusCounts) add(state string) {
+	c.Total++
+	switch state {
+	case stateUp:
+		c.Up++
+	case stateDown:
+		c.Down++
+	case stateError:
+		c.Error++
+	case statePending:
+		c.Pending++
+	case stateDisabled:
+		c.Disabled++
+	case stateSkipped:
+		c.Skipped++
+	}
+}
+
+// handleStatus returns target counts by state together with the time of the last check
+// cycle and the exporter uptime, for polling by external status pages. Tenants only
+// count their own targets.
+func (s *URLExporterServer) handleStatus(c echo.Context) error {
+	latest := make(map[string]checker.Result)
+	for _, result := range s.collector.Snapshot() {
+		latest[result.URL] = result
+	}
+
+	now := time.Now()
+	summary := statusSummary{
+		Instance:  s.config.InstanceID,
+		Version:   s.version.Version,
+		ProbeMode: s.config.ProbeMode,
+	}
+
+	for _, target := range s.checker.Targets() {
+		if !visible(c, target) {
+			continue
+		}
+		result, checked := latest[target.URL]
+		summary.Targets.add(targetState(target, result, checked))
+	}
+
+	if lastCycle := s.checker.LastCycle(); !lastCycle.IsZero() {
+		age := now.Sub(lastCycle).Seconds()
+		summary.LastCycle = &lastCycle
+		summary.LastCycleAgeSeconds = &age
+	}
+	if !s.startedAt.IsZero() {
+		startedAt := s.startedAt
+		summary.StartedAt = &startedAt
+		summary.UptimeSeconds = now.Sub(startedAt).Seconds()
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
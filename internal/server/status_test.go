@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStatus(t *testing.T) {
+	cfg := &config.Config{
+		Targets: []string{"https://up.example.com", "https://down.example.com", "https://error.example.com", "https://pending.example.com"},
+		Checks: []config.Target{
+			{URL: "https://paused.example.com", Disabled: true},
+		},
+		CheckInterval: 30 * time.Second,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	var response statusSummary
+	code := getJSON(t, server, "/api/v1/status", &response)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, response.LastCycle)
+	assert.Nil(t, response.StartedAt)
+	assert.Equal(t, statusCounts{Total: 5, Pending: 4, Disabled: 1}, response.Targets)
+
+	server.startedAt = time.Now().Add(-time.Minute)
+	now := time.Now()
+	server.collector.Record(checker.Result{URL: "https://up.example.com", StatusCode: 200, Timestamp: now})
+	server.collector.Record(checker.Result{URL: "https://down.example.com", StatusCode: 500, Timestamp: now})
+	server.collector.Record(checker.Result{URL: "https://error.example.com", Error: errors.New("timeout"), Timestamp: now})
+
+	response = statusSummary{}
+	code = getJSON(t, server, "/api/v1/status", &response)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "test-instance", response.Instance)
+	assert.Equal(t, "test-1.0.0", response.Version)
+	assert.Equal(t, config.ProbeModeInterval, response.ProbeMode)
+	assert.Equal(t, statusCounts{Total: 5, Up: 1, Down: 1, Error: 1, Pending: 1, Disabled: 1}, response.Targets)
+	require.NotNil(t, response.StartedAt)
+	assert.GreaterOrEqual(t, response.UptimeSeconds, 60.0)
+
+	server.collector.Record(checker.Result{URL: "https://pending.example.com", SkippedBy: "https://down.example.com", Timestamp: now})
+	response = statusSummary{}
+	getJSON(t, server, "/api/v1/status", &response)
+	assert.Equal(t, statusCounts{Total: 5, Up: 1, Down: 1, Error: 1, Disabled: 1, Skipped: 1}, response.Targets)
+}
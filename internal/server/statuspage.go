@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/jasoet/url-exporter/internal/statuspage"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// statusPageMaxAge is how long caches such as a CDN may serve a copy of the status page
+const statusPageMaxAge = "60"
+
+// statusPageSource serves the status page from the collector's state
+type statusPageSource struct {
+	latest    map[string]checker.Result
+	collector *metrics.Collector
+	now       time.Time
+}
+
+func (s statusPageSource) Current(url string) (bool, bool) {
+	result, checked := s.latest[url]
+	return result.IsUp(), checked
+}
+
+func (s statusPageSource) Daily(url string, days int) []metrics.DailyUptime {
+	return s.collector.DailyUptime(url, days, s.now)
+}
+
+// handleStatusPage renders the status page with the current state and daily uptime of
+// every target
+func (s *URLExporterServer) handleStatusPage(c echo.Context) error {
+	source := statusPageSource{
+		latest:    make(map[string]checker.Result),
+		collector: s.collector,
+		now:       time.Now(),
+	}
+	for _, result := range s.collector.Snapshot() {
+		source.latest[result.URL] = result
+	}
+
+	page := statuspage.Build(s.config.StatusPage.Title, s.checker.Targets(), source, source.now)
+
+	var body strings.Builder
+	if err := statuspage.Render(&body, page); err != nil {
+		log.Error().Err(err).Msg("Failed to render status page")
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	cacheControl := "private, max-age=" + statusPageMaxAge
+	if s.config.StatusPage.Public {
+		cacheControl = "public, max-age=" + statusPageMaxAge
+	}
+	c.Response().Header().Set("Cache-Control", cacheControl)
+	return c.HTML(http.StatusOK, body.String())
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStatusPage(t *testing.T) {
+	cfg := &config.Config{
+		Checks:     []config.Target{{URL: "https://example.com/health", Name: "Website", Group: "public"}},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		Auth:       config.AuthConfig{BearerToken: "token-123"},
+		StatusPage: config.StatusPageConfig{Enabled: true, Title: "Acme Status"},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+	server.collector.Record(checker.Result{URL: "https://example.com/health", StatusCode: 200, Timestamp: time.Now()})
+
+	get := func() *httptest.ResponseRecorder {
+		e := echo.New()
+		server.setupRoutes(e)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		return rec
+	}
+
+	// The page requires the general credentials unless it is public
+	assert.Equal(t, http.StatusUnauthorized, get().Code)
+
+	cfg.StatusPage.Public = true
+	rec := get()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), "<title>Acme Status</title>")
+	assert.Contains(t, rec.Body.String(), "All systems operational")
+	assert.Contains(t, rec.Body.String(), "Website")
+	assert.Contains(t, rec.Body.String(), "100.00% uptime")
+
+	cfg.StatusPage.Enabled = false
+	assert.Equal(t, http.StatusNotFound, get().Code)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// streamKeepAlive is how often a comment is sent on idle streams so proxies keep the connection open
+const streamKeepAlive = 15 * time.Second
+
+// handleStream pushes check results to the client as Server-Sent Events as soon as each
+// check completes. Like /api/v1/results, the stream can be narrowed with the selector and
+// status query parameters.
+func (s *URLExporterServer) handleStream(c echo.Context) error {
+	selector, reqErr := parseSelector(c)
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+	status, reqErr := parseStatusFilter(c.QueryParams())
+	if reqErr != nil {
+		return respondError(c, reqErr.status, reqErr.message)
+	}
+
+	results, unsubscribe := s.checker.Subscribe()
+	defer unsubscribe()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			response.Flush()
+		case result, ok := <-results:
+			if !ok {
+				return nil
+			}
+			target, exists := s.checker.Lookup(result.URL)
+			if !exists {
+				target = config.Target{URL: result.URL}
+			}
+			if !selector.matches(target) || !status.matches(true, result.IsUp()) {
+				continue
+			}
+
+			data, err := json.Marshal(newResultDetail(result, target.Group, nil))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(response, "event: result\ndata: %s\n\n", data); err != nil {
+				return nil
+			}
+			response.Flush()
+		}
+	}
+}
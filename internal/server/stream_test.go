@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStream(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		Checks: []config.Target{
+			{URL: target.URL + "/api", Group: "api"},
+			{URL: target.URL + "/web", Group: "web"},
+		},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+	exporter := httptest.NewServer(e)
+	defer exporter.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, exporter.URL+"/api/v1/stream?group=web", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+	// The response headers are only sent once the handler has subscribed
+	_, err = server.checker.RunCycle(ctx)
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(resp.Body)
+	event, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: result\n", event)
+
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var result struct {
+		URL        string `json:"url"`
+		Group      string `json:"group"`
+		Up         bool   `json:"up"`
+		StatusCode int    `json:"status_code"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &result))
+	assert.Equal(t, target.URL+"/web", result.URL)
+	assert.Equal(t, "web", result.Group)
+	assert.True(t, result.Up)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+
+	// Shutting the checker down ends the stream
+	require.NoError(t, server.checker.Shutdown(ctx))
+	_, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+}
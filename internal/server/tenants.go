@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// tenantContextKey is the key of the tenant a request authenticated as in the echo context
+const tenantContextKey = "tenant"
+
+// requestTenant returns the tenant the request authenticated as. It is empty for requests
+// with the general credentials, which see the targets of every tenant.
+func requestTenant(c echo.Context) string {
+	tenant, _ := c.Get(tenantContextKey).(string)
+	return tenant
+}
+
+// visible reports whether the request may see the target: tenants only see their own
+func visible(c echo.Context, target config.Target) bool {
+	tenant := requestTenant(c)
+	return tenant == "" || target.Tenant == tenant
+}
+
+// handleTenantMetrics serves the series of the targets of the tenant in the path, if the
+// tenant enabled its metrics path. Tenants can only scrape their own path.
+func (s *URLExporterServer) handleTenantMetrics(c echo.Context) error {
+	tenant := c.Param("tenant")
+	if !s.config.Tenants[tenant].Metrics {
+		return respondError(c, http.StatusNotFound, "no metrics path for tenant: "+tenant)
+	}
+	if authenticated := requestTenant(c); authenticated != "" && authenticated != tenant {
+		return respondError(c, http.StatusForbidden, "forbidden")
+	}
+
+	urls := make(map[string]bool)
+	for _, target := range s.checker.Targets() {
+		if target.Tenant == tenant {
+			urls[config.RedactURL(target.URL)] = true
+		}
+	}
+	gatherer := tenantGatherer{gatherer: s.registry, urls: urls}
+	return s.handleMetrics(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))(c)
+}
+
+// tenantGatherer keeps the series of the gathered metrics whose url label is one of urls,
+// dropping the exporter's own metrics and the series of other tenants
+type tenantGatherer struct {
+	gatherer prometheus.Gatherer
+	urls     map[string]bool
+}
+
+func (g tenantGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+
+	filtered := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.GetMetric() {
+			if g.urls[urlLabel(metric)] {
+				metrics = append(metrics, metric)
+			}
+		}
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			filtered = append(filtered, family)
+		}
+	}
+	return filtered, err
+}
+
+// urlLabel returns the value of the url label of the series, empty if it has none
+func urlLabel(metric *dto.Metric) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == "url" {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTenantTestEcho(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	cfg := &config.Config{
+		Targets: []string{"https://example.com"},
+		Checks: []config.Target{
+			{URL: "https://pay.example.com", Name: "pay", Tenant: "payments"},
+			{URL: "https://search.example.com", Name: "search", Tenant: "search"},
+		},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		ProbeMode:  config.ProbeModeInterval,
+		Auth:       config.AuthConfig{BearerToken: "admin-token"},
+		Tenants: map[string]config.TenantConfig{
+			"payments": {BearerToken: "payments-token", Metrics: true},
+			"search":   {BearerToken: "search-token"},
+		},
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	for _, target := range []string{"https://example.com", "https://pay.example.com", "https://search.example.com"} {
+		server.collector.Record(checker.Result{URL: target, StatusCode: 200, Timestamp: now})
+	}
+
+	e := echo.New()
+	server.setupRoutes(e)
+	return e
+}
+
+func serveWithToken(e *echo.Echo, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTenants_API(t *testing.T) {
+	e := newTenantTestEcho(t)
+
+	targetURLs := func(token, path string) []string {
+		rec := serveWithToken(e, path, token)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var response struct {
+			Targets []struct {
+				URL string `json:"url"`
+			} `json:"targets"`
+			Results []struct {
+				URL string `json:"url"`
+			} `json:"results"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		var urls []string
+		for _, target := range response.Targets {
+			urls = append(urls, target.URL)
+		}
+		for _, result := range response.Results {
+			urls = append(urls, result.URL)
+		}
+		return urls
+	}
+
+	assert.Equal(t, []string{"https://pay.example.com"}, targetURLs("payments-token", "/api/v1/targets"))
+	assert.Equal(t, []string{"https://pay.example.com"}, targetURLs("payments-token", "/api/v1/targets?tenant=search"), "tenants cannot select other tenants")
+	assert.Equal(t, []string{"https://search.example.com"}, targetURLs("search-token", "/api/v1/results"))
+	assert.Len(t, targetURLs("admin-token", "/api/v1/targets"), 3)
+	assert.Equal(t, []string{"https://search.example.com"}, targetURLs("admin-token", "/api/v1/targets?tenant=search"))
+
+	var status statusSummary
+	rec := serveWithToken(e, "/api/v1/status", "payments-token")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, 1, status.Targets.Total)
+
+	assert.Equal(t, http.StatusOK, serveWithToken(e, "/api/v1/targets/pay/history", "payments-token").Code)
+	assert.Equal(t, http.StatusNotFound, serveWithToken(e, "/api/v1/targets/search/history", "payments-token").Code)
+	assert.Equal(t, http.StatusNotFound, serveWithToken(e, "/api/v1/incidents?target=search", "payments-token").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(e, "/api/v1/targets", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(e, "/api/v1/targets", "wrong-token").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(e, "/api/v1/config", "payments-token").Code, "tenants only use the tenant endpoints")
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(e, "/metrics", "payments-token").Code)
+}
+
+func TestTenants_Metrics(t *testing.T) {
+	e := newTenantTestEcho(t)
+
+	rec := serveWithToken(e, "/metrics/payments", "payments-token")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `url="https://pay.example.com"`)
+	assert.NotContains(t, body, `url="https://search.example.com"`)
+	assert.NotContains(t, body, `url="https://example.com"`)
+	assert.NotContains(t, body, "go_goroutines", "the exporter's own metrics are not the tenant's")
+	for _, line := range strings.Split(body, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			assert.Contains(t, line, `url="https://pay.example.com"`)
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, serveWithToken(e, "/metrics/payments", "admin-token").Code)
+	assert.Equal(t, http.StatusForbidden, serveWithToken(e, "/metrics/payments", "search-token").Code)
+	assert.Equal(t, http.StatusNotFound, serveWithToken(e, "/metrics/search", "search-token").Code, "search has no metrics path")
+	assert.Equal(t, http.StatusNotFound, serveWithToken(e, "/metrics/unknown", "admin-token").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(e, "/metrics/payments", "").Code)
+}
+
+func TestHandleAddTarget_UnknownTenant(t *testing.T) {
+	cfg := &config.Config{
+		Targets:    []string{"https://example.com"},
+		Timeout:    5 * time.Second,
+		InstanceID: "test-instance",
+		ProbeMode:  config.ProbeModeInterval,
+		API:        config.APIConfig{Token: "secret"},
+		Tenants:    map[string]config.TenantConfig{"payments": {}},
+	}
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	add := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/targets", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, add(`{"url": "https://search.example.com", "tenant": "search"}`))
+	assert.Equal(t, http.StatusCreated, add(`{"url": "https://pay.example.com", "tenant": "payments"}`))
+}
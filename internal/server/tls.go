@@ -0,0 +1,138 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// certReloader serves the certificate from disk and reloads it whenever the certificate or
+// key file changes, so rotated certificates are picked up without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. If a changed certificate cannot be
+// loaded the previous one keeps being served.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.changed() {
+		if err := r.reload(); err != nil {
+			log.Error().Err(err).Str("cert_file", r.certFile).Msg("Failed to reload TLS certificate, keeping the current one")
+		} else {
+			log.Info().Str("cert_file", r.certFile).Msg("Reloaded TLS certificate")
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) changed() bool {
+	certTime, keyTime, err := r.modTimes()
+	if err != nil {
+		return false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return !certTime.Equal(r.certTime) || !keyTime.Equal(r.keyTime)
+}
+
+func (r *certReloader) reload() error {
+	certTime, keyTime, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Remember the attempted versions so a broken pair is retried only once the files change again
+	r.certTime = certTime
+	r.keyTime = keyTime
+
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	r.cert = &cert
+	return nil
+}
+
+func (r *certReloader) modTimes() (certTime, keyTime time.Time, err error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat key: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// newServerTLSConfig builds the tls.Config for serving the exporter over HTTPS
+func newServerTLSConfig(cfg config.ServerTLSConfig) (*tls.Config, error) {
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// newTLSListener listens on the address and wraps the listener with the server TLS config
+func newTLSListener(address string, cfg config.ServerTLSConfig) (net.Listener, error) {
+	tlsConfig, err := newServerTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := listen(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	return tls.NewListener(listener, tlsConfig), nil
+}
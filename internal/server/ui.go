@@ -0,0 +1,164 @@
+package server
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+	sparklinePoints = 60
+
+	// uiRefreshScrape is the page refresh interval in scrape mode, where there is no check interval to follow
+	uiRefreshScrape = 30 * time.Second
+)
+
+//go:embed ui.html
+var uiHTML string
+
+var uiTemplate = template.Must(template.New("ui").Parse(uiHTML))
+
+// uiPage is the data rendered by the status dashboard
+type uiPage struct {
+	Instance        string
+	Version         string
+	RefreshSeconds  int
+	SparklineWidth  int
+	SparklineHeight int
+	Counts          statusCounts
+	Targets         []uiTarget
+}
+
+// uiTarget is a single row of the status dashboard
+type uiTarget struct {
+	URL            string
+	Description    string
+	RunbookURL     string
+	Group          string
+	Status         string
+	StatusCode     int
+	ResponseTimeMs int64
+	LastCheck      string
+	LastError      string
+	Uptime         string
+	Sparkline      string
+}
+
+// handleUI renders an HTML dashboard with the current status, recent latency, last error
+// and uptime of every target, built from the collector's state
+func (s *URLExporterServer) handleUI(c echo.Context) error {
+	latest := make(map[string]checker.Result)
+	for _, result := range s.collector.Snapshot() {
+		latest[result.URL] = result
+	}
+	counters := s.collector.Counters()
+	history := s.collector.History(sparklinePoints)
+
+	page := uiPage{
+		Instance:        s.config.InstanceID,
+		Version:         s.version.Version,
+		RefreshSeconds:  int(s.uiRefresh().Seconds()),
+		SparklineWidth:  sparklineWidth,
+		SparklineHeight: sparklineHeight,
+	}
+
+	for _, target := range s.checker.Targets() {
+		result, checked := latest[target.URL]
+		row := uiTarget{
+			URL:         config.RedactURL(target.URL),
+			Description: target.Description,
+			RunbookURL:  target.RunbookURL,
+			Group:       target.Group,
+			Status:      targetState(target, result, checked),
+			Uptime:      uptime(counters[target.URL]),
+			Sparkline:   sparkline(history[target.URL]),
+		}
+
+		if checked {
+			row.StatusCode = result.StatusCode
+			row.ResponseTimeMs = result.ResponseTime.Milliseconds()
+			row.LastCheck = result.Timestamp.UTC().Format(time.RFC3339)
+			if result.Error != nil {
+				row.LastError = result.Error.Error()
+			}
+		}
+
+		page.Counts.add(row.Status)
+		page.Targets = append(page.Targets, row)
+	}
+
+	sort.SliceStable(page.Targets, func(i, j int) bool {
+		if page.Targets[i].Group != page.Targets[j].Group {
+			return page.Targets[i].Group < page.Targets[j].Group
+		}
+		return page.Targets[i].URL < page.Targets[j].URL
+	})
+
+	var body strings.Builder
+	if err := uiTemplate.Execute(&body, page); err != nil {
+		log.Error().Err(err).Msg("Failed to render status UI")
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.HTML(http.StatusOK, body.String())
+}
+
+// uiRefresh returns how often the dashboard reloads itself: once per check interval,
+// bounded to keep the page neither hammering the server nor going stale
+func (s *URLExporterServer) uiRefresh() time.Duration {
+	if s.config.ProbeMode == config.ProbeModeScrape {
+		return uiRefreshScrape
+	}
+	return min(max(s.config.CheckInterval, 5*time.Second), time.Minute)
+}
+
+// uptime returns the share of successful (2xx) checks since start as a percentage
+func uptime(counts map[string]int) string {
+	total, good := 0, 0
+	for statusCode, count := range counts {
+		total += count
+		if code, err := strconv.Atoi(statusCode); err == nil && code >= 200 && code < 300 {
+			good += count
+		}
+	}
+
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", float64(good)*100/float64(total))
+}
+
+// sparkline returns SVG polyline points plotting the response times of the history,
+// scaled to the slowest check
+func sparkline(history []metrics.HistoryPoint) string {
+	if len(history) < 2 {
+		return ""
+	}
+
+	slowest := time.Duration(1)
+	for _, point := range history {
+		slowest = max(slowest, point.ResponseTime)
+	}
+
+	points := make([]string, 0, len(history))
+	step := float64(sparklineWidth) / float64(len(history)-1)
+	for i, point := range history {
+		y := sparklineHeight - float64(point.ResponseTime)/float64(slowest)*sparklineHeight
+		points = append(points, fmt.Sprintf("%.1f,%.1f", float64(i)*step, y))
+	}
+
+	return strings.Join(points, " ")
+}
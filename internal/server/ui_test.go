@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleUI(t *testing.T) {
+	cfg := &config.Config{
+		Targets: []string{"https://example.com", "https://down.example.com", "https://pending.example.com"},
+		Checks: []config.Target{
+			{URL: "https://api.example.com/health", Group: "api"},
+			{URL: "https://paused.example.com", Disabled: true},
+		},
+		CheckInterval: 30 * time.Second,
+		Timeout:       5 * time.Second,
+		InstanceID:    "test-instance",
+		ProbeMode:     config.ProbeModeInterval,
+	}
+
+	server, err := createTestServer(cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	server.collector.Record(checker.Result{URL: "https://example.com", StatusCode: 200, ResponseTime: 120 * time.Millisecond, Timestamp: now})
+	server.collector.Record(checker.Result{URL: "https://example.com", StatusCode: 503, ResponseTime: 80 * time.Millisecond, Timestamp: now})
+	server.collector.Record(checker.Result{URL: "https://down.example.com", Error: errors.New("connection <refused>"), Timestamp: now})
+
+	e := echo.New()
+	server.setupRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/ui", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/html")
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `content="30"`)
+	assert.Contains(t, body, "0 up")
+	assert.Contains(t, body, "1 down")
+	assert.Contains(t, body, "1 error")
+	assert.Contains(t, body, "2 pending")
+	assert.Contains(t, body, "1 disabled")
+	assert.Contains(t, body, "https://pending.example.com")
+	assert.Contains(t, body, "50.00%")
+	assert.Contains(t, body, "<polyline points=")
+	assert.Contains(t, body, "connection &lt;refused&gt;")
+}
+
+func TestUptime(t *testing.T) {
+	assert.Equal(t, "-", uptime(nil))
+	assert.Equal(t, "75.00%", uptime(map[string]int{"200": 2, "204": 1, "error": 1}))
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Empty(t, sparkline([]metrics.HistoryPoint{{ResponseTime: time.Second}}))
+
+	points := sparkline([]metrics.HistoryPoint{
+		{ResponseTime: 100 * time.Millisecond},
+		{ResponseTime: 50 * time.Millisecond},
+		{ResponseTime: 0},
+	})
+	assert.Equal(t, "0.0,0.0 60.0,12.0 120.0,24.0", points)
+}
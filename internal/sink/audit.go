@@ -0,0 +1,181 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// auditTimeFormat names rotated audit files after the time of rotation
+const auditTimeFormat = "20060102T150405.000Z"
+
+// AuditSink appends every result of each check cycle to a file as JSON lines, rotating
+// the file by size and age
+type AuditSink struct {
+	config   config.AuditLogConfig
+	instance string
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewAuditSink opens the audit log for appending, creating it if needed
+func NewAuditSink(cfg config.AuditLogConfig, instance string) (*AuditSink, error) {
+	s := &AuditSink{
+		config:   cfg,
+		instance: instance,
+	}
+	if err := s.open(time.Now()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// HandleCycle is a checker.CycleHandler that appends the cycle's results and logs failures
+func (s *AuditSink) HandleCycle(_ context.Context, results []checker.Result) {
+	if err := s.Write(results); err != nil {
+		log.Error().Err(err).Msg("Failed to write results to the audit log")
+	}
+}
+
+// Write appends the results to the audit log, rotating it first when it is due
+func (s *AuditSink) Write(results []checker.Result) error {
+	return s.write(results, time.Now())
+}
+
+func (s *AuditSink) write(results []checker.Result, now time.Time) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, result := range results {
+		if err := encoder.Encode(newResultRecord(result, s.instance)); err != nil {
+			return fmt.Errorf("failed to encode audit record: %w", err)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("audit log %s is closed", s.config.File)
+	}
+
+	if s.rotationDue(int64(lines.Len()), now) {
+		if err := s.rotate(now); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(lines.Bytes())
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log %s: %w", s.config.File, err)
+	}
+	return nil
+}
+
+// rotationDue reports whether the file must be rotated before writing n more bytes. An
+// empty file is never rotated, and a zero limit disables that kind of rotation.
+func (s *AuditSink) rotationDue(n int64, now time.Time) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.config.MaxBytes > 0 && s.size+n > s.config.MaxBytes {
+		return true
+	}
+	return s.config.MaxAge > 0 && now.Sub(s.opened) >= s.config.MaxAge
+}
+
+// Close closes the audit log file
+func (s *AuditSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open opens the audit log for appending. The caller must hold the mutex or own s.
+func (s *AuditSink) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(s.config.File), 0750); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(s.config.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", s.config.File, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat audit log %s: %w", s.config.File, err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+	s.opened = now
+	return nil
+}
+
+// rotate renames the current file after the time of rotation, opens a new one and
+// removes the oldest rotated files beyond MaxBackups. The caller must hold the mutex.
+func (s *AuditSink) rotate(now time.Time) error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log %s: %w", s.config.File, err)
+	}
+	s.file = nil
+
+	ext := filepath.Ext(s.config.File)
+	base := strings.TrimSuffix(s.config.File, ext)
+	rotated := base + "-" + now.UTC().Format(auditTimeFormat) + ext
+	if err := os.Rename(s.config.File, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log %s: %w", s.config.File, err)
+	}
+
+	if err := s.open(now); err != nil {
+		return err
+	}
+
+	log.Info().Str("file", rotated).Msg("Rotated audit log")
+
+	if s.config.MaxBackups > 0 {
+		s.removeBackups(base, ext)
+	}
+	return nil
+}
+
+// removeBackups deletes the oldest rotated files beyond MaxBackups. Their names sort by
+// rotation time.
+func (s *AuditSink) removeBackups(base, ext string) {
+	backups, err := filepath.Glob(base + "-*" + ext)
+	if err != nil || len(backups) <= s.config.MaxBackups {
+		return
+	}
+
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-s.config.MaxBackups] {
+		if err := os.Remove(backup); err != nil {
+			log.Warn().Err(err).Str("file", backup).Msg("Failed to remove rotated audit log")
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ResultSource provides the latest check result for each target
+type ResultSource interface {
+	Snapshot() []checker.Result
+}
+
+// GraphiteSink periodically writes the per-target gauges to Graphite using the plaintext protocol
+type GraphiteSink struct {
+	config   config.GraphiteConfig
+	instance string
+	source   ResultSource
+	timeout  time.Duration
+}
+
+func NewGraphiteSink(cfg config.GraphiteConfig, instance string, source ResultSource) *GraphiteSink {
+	return &GraphiteSink{
+		config:   cfg,
+		instance: instance,
+		source:   source,
+		timeout:  10 * time.Second,
+	}
+}
+
+func (g *GraphiteSink) Start(ctx context.Context) {
+	ticker := time.NewTicker(g.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := g.Flush(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to flush metrics to Graphite")
+			}
+		}
+	}
+}
+
+// Flush sends the current snapshot to Graphite over a fresh TCP connection
+func (g *GraphiteSink) Flush(ctx context.Context) error {
+	results := g.source.Snapshot()
+	if len(results) == 0 {
+		return nil
+	}
+
+	payload := g.format(results, time.Now())
+
+	dialer := net.Dialer{Timeout: g.timeout}
+	address := net.JoinHostPort(g.config.Host, strconv.Itoa(g.config.Port))
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to graphite %s: %w", address, err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(g.timeout)); err != nil {
+		return fmt.Errorf("failed to set write deadline: %w", err)
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return fmt.Errorf("failed to write to graphite %s: %w", address, err)
+	}
+
+	log.Debug().
+		Str("address", address).
+		Int("targets", len(results)).
+		Msg("Flushed metrics to Graphite")
+
+	return nil
+}
+
+func (g *GraphiteSink) format(results []checker.Result, now time.Time) []byte {
+	var buf bytes.Buffer
+	timestamp := now.Unix()
+
+	for _, result := range results {
+		base := strings.Join([]string{
+			g.config.Prefix,
+			sanitizeGraphiteNode(g.instance),
+			sanitizeGraphiteNode(result.Host + result.Path),
+		}, ".")
+
+		up := 0
+		switch {
+		case result.IsUp():
+			up = 1
+		case result.IsSkipped():
+			up = -1
+		}
+
+		errorValue := 0
+		if result.Error != nil {
+			errorValue = 1
+		}
+
+		fmt.Fprintf(&buf, "%s.up %d %d\n", base, up, timestamp)
+		fmt.Fprintf(&buf, "%s.error %d %d\n", base, errorValue, timestamp)
+
+		if result.Error == nil && !result.IsSkipped() {
+			fmt.Fprintf(&buf, "%s.response_time_milliseconds %d %d\n", base, result.ResponseTime.Milliseconds(), timestamp)
+			fmt.Fprintf(&buf, "%s.http_status_code %d %d\n", base, result.StatusCode, timestamp)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// sanitizeGraphiteNode turns an arbitrary string into a single Graphite path node
+func sanitizeGraphiteNode(value string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range value {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+			lastUnderscore = false
+			continue
+		}
+		if !lastUnderscore {
+			b.WriteByte('_')
+			lastUnderscore = true
+		}
+	}
+
+	return strings.Trim(b.String(), "_")
+}
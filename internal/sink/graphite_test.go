@@ -0,0 +1,146 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSource []checker.Result
+
+func (s staticSource) Snapshot() []checker.Result {
+	return s
+}
+
+func testResults() staticSource {
+	return staticSource{
+		{
+			URL:          "https://example.com/health",
+			Host:         "https://example.com",
+			Path:         "/health",
+			StatusCode:   200,
+			ResponseTime: 150 * time.Millisecond,
+		},
+		{
+			URL:   "https://down.example.com",
+			Host:  "https://down.example.com",
+			Path:  "/",
+			Error: errors.New("connection refused"),
+		},
+	}
+}
+
+func TestGraphiteSink_Format(t *testing.T) {
+	cfg := config.GraphiteConfig{Prefix: "url_exporter"}
+	g := NewGraphiteSink(cfg, "vm-01.prod", testResults())
+
+	now := time.Unix(1700000000, 0)
+	payload := string(g.format(testResults(), now))
+
+	assert.Contains(t, payload, "url_exporter.vm-01_prod.https_example_com_health.up 1 1700000000\n")
+	assert.Contains(t, payload, "url_exporter.vm-01_prod.https_example_com_health.error 0 1700000000\n")
+	assert.Contains(t, payload, "url_exporter.vm-01_prod.https_example_com_health.response_time_milliseconds 150 1700000000\n")
+	assert.Contains(t, payload, "url_exporter.vm-01_prod.https_example_com_health.http_status_code 200 1700000000\n")
+
+	assert.Contains(t, payload, "url_exporter.vm-01_prod.https_down_example_com.up 0 1700000000\n")
+	assert.Contains(t, payload, "url_exporter.vm-01_prod.https_down_example_com.error 1 1700000000\n")
+	assert.NotContains(t, payload, "https_down_example_com.response_time_milliseconds")
+	assert.NotContains(t, payload, "https_down_example_com.http_status_code")
+}
+
+func TestSanitizeGraphiteNode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/", "https_example_com"},
+		{"https://example.com/api?x=1", "https_example_com_api_x_1"},
+		{"redis://localhost:6379/", "redis_localhost_6379"},
+		{"my-host", "my-host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sanitizeGraphiteNode(tt.input))
+		})
+	}
+}
+
+func TestGraphiteSink_Flush(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	cfg := config.GraphiteConfig{
+		Host:   "127.0.0.1",
+		Port:   addr.Port,
+		Prefix: "test",
+	}
+	g := NewGraphiteSink(cfg, "instance", testResults())
+
+	require.NoError(t, g.Flush(context.Background()))
+
+	select {
+	case data := <-received:
+		lines := strings.Split(strings.TrimSpace(data), "\n")
+		assert.Len(t, lines, 6)
+		assert.True(t, strings.HasPrefix(lines[0], "test.instance."))
+	case <-time.After(2 * time.Second):
+		t.Fatal("graphite server did not receive data")
+	}
+}
+
+func TestGraphiteSink_Flush_EmptySnapshot(t *testing.T) {
+	cfg := config.GraphiteConfig{Host: "127.0.0.1", Port: 1, Prefix: "test"}
+	g := NewGraphiteSink(cfg, "instance", staticSource{})
+
+	assert.NoError(t, g.Flush(context.Background()))
+}
+
+func TestGraphiteSink_Flush_ConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	cfg := config.GraphiteConfig{Host: "127.0.0.1", Port: port, Prefix: "test"}
+	g := NewGraphiteSink(cfg, "instance", testResults())
+
+	err = g.Flush(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to graphite 127.0.0.1:"+strconv.Itoa(port))
+}
+
+func TestGraphiteSink_Start_ContextCancellation(t *testing.T) {
+	cfg := config.GraphiteConfig{Host: "127.0.0.1", Port: 1, Prefix: "test", FlushInterval: time.Hour}
+	g := NewGraphiteSink(cfg, "instance", testResults())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	g.Start(ctx)
+	assert.Less(t, time.Since(start), time.Second)
+}
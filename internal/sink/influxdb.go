@@ -0,0 +1,135 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/pkg/rest"
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	influxTagEscaper   = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxFieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+)
+
+// InfluxDBSink writes the results of each check cycle to InfluxDB v2 as a single line-protocol batch
+type InfluxDBSink struct {
+	config     config.InfluxDBConfig
+	instance   string
+	restClient *rest.Client
+}
+
+func NewInfluxDBSink(cfg config.InfluxDBConfig, instance string, timeout time.Duration) *InfluxDBSink {
+	restClient := rest.NewClient(rest.WithRestConfig(rest.Config{
+		RetryCount:    1,
+		RetryWaitTime: time.Second,
+		Timeout:       timeout,
+	}))
+
+	return &InfluxDBSink{
+		config:     cfg,
+		instance:   instance,
+		restClient: restClient,
+	}
+}
+
+// HandleCycle is a checker.CycleHandler that writes the cycle's results and logs failures
+func (s *InfluxDBSink) HandleCycle(ctx context.Context, results []checker.Result) {
+	if err := s.Write(ctx, results); err != nil {
+		log.Error().Err(err).Msg("Failed to write results to InfluxDB")
+	}
+}
+
+// Write sends the given results to the InfluxDB v2 write API
+func (s *InfluxDBSink) Write(ctx context.Context, results []checker.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	query := url.Values{}
+	query.Set("org", s.config.Org)
+	query.Set("bucket", s.config.Bucket)
+	query.Set("precision", "ns")
+	writeURL := strings.TrimRight(s.config.URL, "/") + "/api/v2/write?" + query.Encode()
+
+	headers := map[string]string{
+		"Content-Type": "text/plain; charset=utf-8",
+	}
+	if s.config.Token != "" {
+		headers["Authorization"] = "Token " + s.config.Token
+	}
+
+	if _, err := s.restClient.MakeRequest(ctx, http.MethodPost, writeURL, s.format(results), headers); err != nil {
+		return fmt.Errorf("failed to write %d results to influxdb: %w", len(results), err)
+	}
+
+	log.Debug().
+		Str("bucket", s.config.Bucket).
+		Int("targets", len(results)).
+		Msg("Wrote results to InfluxDB")
+
+	return nil
+}
+
+func (s *InfluxDBSink) format(results []checker.Result) string {
+	var b strings.Builder
+
+	for _, result := range results {
+		b.WriteString(influxTagEscaper.Replace(s.config.Measurement))
+		writeInfluxTag(&b, "url", config.RedactURL(result.URL))
+		writeInfluxTag(&b, "host", result.Host)
+		writeInfluxTag(&b, "path", result.Path)
+		writeInfluxTag(&b, "protocol", result.Protocol)
+		writeInfluxTag(&b, "instance", s.instance)
+
+		up := 0
+		switch {
+		case result.IsUp():
+			up = 1
+		case result.IsSkipped():
+			up = -1
+		}
+
+		fields := []string{"up=" + strconv.Itoa(up) + "i"}
+		if result.Error != nil {
+			fields = append(fields,
+				"error=1i",
+				`error_message="`+influxFieldEscaper.Replace(result.Error.Error())+`"`,
+			)
+		} else if result.IsSkipped() {
+			fields = append(fields, "error=0i")
+		} else {
+			fields = append(fields,
+				"error=0i",
+				"response_time_milliseconds="+strconv.FormatInt(result.ResponseTime.Milliseconds(), 10)+"i",
+				"http_status_code="+strconv.Itoa(result.StatusCode)+"i",
+			)
+		}
+
+		b.WriteByte(' ')
+		b.WriteString(strings.Join(fields, ","))
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(result.Timestamp.UnixNano(), 10))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+func writeInfluxTag(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteByte(',')
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(influxTagEscaper.Replace(value))
+}
@@ -0,0 +1,125 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInfluxConfig(url string) config.InfluxDBConfig {
+	return config.InfluxDBConfig{
+		Enabled:     true,
+		URL:         url,
+		Org:         "my-org",
+		Bucket:      "probes",
+		Token:       "secret-token",
+		Measurement: "url_check",
+	}
+}
+
+func TestInfluxDBSink_Format(t *testing.T) {
+	s := NewInfluxDBSink(testInfluxConfig("http://influx"), "vm 01", time.Second)
+
+	ts := time.Unix(1700000000, 0)
+	results := []checker.Result{
+		{
+			URL:          "https://example.com/health",
+			Host:         "https://example.com",
+			Path:         "/health",
+			Protocol:     "https",
+			StatusCode:   200,
+			ResponseTime: 150 * time.Millisecond,
+			Timestamp:    ts,
+		},
+		{
+			URL:       "https://down.example.com",
+			Host:      "https://down.example.com",
+			Path:      "/",
+			Protocol:  "https",
+			Error:     errors.New(`dial "tcp", refused`),
+			Timestamp: ts,
+		},
+	}
+
+	lines := strings.Split(strings.TrimSpace(s.format(results)), "\n")
+	require.Len(t, lines, 2)
+
+	assert.Equal(t,
+		`url_check,url=https://example.com/health,host=https://example.com,path=/health,protocol=https,instance=vm\ 01 `+
+			`up=1i,error=0i,response_time_milliseconds=150i,http_status_code=200i 1700000000000000000`,
+		lines[0])
+	assert.Equal(t,
+		`url_check,url=https://down.example.com,host=https://down.example.com,path=/,protocol=https,instance=vm\ 01 `+
+			`up=0i,error=1i,error_message="dial \"tcp\", refused" 1700000000000000000`,
+		lines[1])
+}
+
+func TestInfluxDBSink_Format_EscapesTags(t *testing.T) {
+	s := NewInfluxDBSink(testInfluxConfig("http://influx"), "instance", time.Second)
+
+	results := []checker.Result{
+		{URL: "https://example.com/a b?x=1,2", Host: "https://example.com", Path: "/a b?x=1,2", StatusCode: 200},
+	}
+
+	line := s.format(results)
+	assert.Contains(t, line, `url=https://example.com/a\ b?x\=1\,2`)
+	assert.Contains(t, line, `path=/a\ b?x\=1\,2`)
+}
+
+func TestInfluxDBSink_Write(t *testing.T) {
+	var gotPath, gotQuery, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := NewInfluxDBSink(testInfluxConfig(server.URL+"/"), "instance", time.Second)
+	results := []checker.Result{
+		{URL: "https://example.com", Host: "https://example.com", Path: "/", StatusCode: 200, Timestamp: time.Now()},
+		{URL: "https://test.com", Host: "https://test.com", Path: "/", StatusCode: 503, Timestamp: time.Now()},
+	}
+
+	require.NoError(t, s.Write(context.Background(), results))
+
+	assert.Equal(t, "/api/v2/write", gotPath)
+	assert.Contains(t, gotQuery, "org=my-org")
+	assert.Contains(t, gotQuery, "bucket=probes")
+	assert.Contains(t, gotQuery, "precision=ns")
+	assert.Equal(t, "Token secret-token", gotAuth)
+	assert.Len(t, strings.Split(strings.TrimSpace(gotBody), "\n"), 2)
+}
+
+func TestInfluxDBSink_Write_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	s := NewInfluxDBSink(testInfluxConfig(server.URL), "instance", time.Second)
+	results := []checker.Result{{URL: "https://example.com", StatusCode: 200, Timestamp: time.Now()}}
+
+	err := s.Write(context.Background(), results)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to write 1 results to influxdb")
+}
+
+func TestInfluxDBSink_Write_Empty(t *testing.T) {
+	s := NewInfluxDBSink(testInfluxConfig("http://127.0.0.1:1"), "instance", time.Second)
+
+	assert.NoError(t, s.Write(context.Background(), nil))
+}
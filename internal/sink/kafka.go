@@ -0,0 +1,178 @@
+package sink
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/kafka"
+	"github.com/rs/zerolog/log"
+)
+
+// ResultAvroSchema is the Avro schema of the records the Kafka sink writes with the avro
+// format, the fields of the JSON records with optional fields as unions with null
+const ResultAvroSchema = `{
+  "type": "record",
+  "name": "CheckResult",
+  "namespace": "io.github.jasoet.urlexporter",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "instance", "type": "string"},
+    {"name": "cycle_id", "type": ["null", "string"], "default": null},
+    {"name": "url", "type": "string"},
+    {"name": "host", "type": "string"},
+    {"name": "path", "type": "string"},
+    {"name": "protocol", "type": "string"},
+    {"name": "up", "type": "boolean"},
+    {"name": "status_code", "type": "int"},
+    {"name": "response_time_ms", "type": "double"},
+    {"name": "http_version", "type": ["null", "double"], "default": null},
+    {"name": "body_match", "type": ["null", "boolean"], "default": null},
+    {"name": "header_match", "type": ["null", "boolean"], "default": null},
+    {"name": "error", "type": ["null", "string"], "default": null},
+    {"name": "skipped_by", "type": ["null", "string"], "default": null}
+  ]
+}`
+
+// KafkaSink publishes the results of each check cycle to a Kafka topic, one record per
+// result keyed by the target's URL so the results of a target stay in order. Cycles are
+// published in the background, see publishQueue.
+type KafkaSink struct {
+	config   config.KafkaConfig
+	instance string
+	producer *kafka.Producer
+	queue    *publishQueue
+}
+
+func NewKafkaSink(cfg config.KafkaConfig, instance string, timeout time.Duration) (*KafkaSink, error) {
+	producer, err := kafka.NewProducer(cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &KafkaSink{
+		config:   cfg,
+		instance: instance,
+		producer: producer,
+	}
+	s.queue = newPublishQueue("kafka", s.Write)
+	return s, nil
+}
+
+// HandleCycle is a checker.CycleHandler that queues the cycle's results for publishing
+// without waiting for the brokers; failures are logged
+func (s *KafkaSink) HandleCycle(_ context.Context, results []checker.Result) {
+	s.queue.enqueue(results)
+}
+
+// DroppedResults returns the number of results dropped because the brokers did not keep up
+func (s *KafkaSink) DroppedResults() uint64 {
+	return s.queue.dropped.Load()
+}
+
+// Write publishes the given results
+func (s *KafkaSink) Write(ctx context.Context, results []checker.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	records := make([]kafka.Record, 0, len(results))
+	for _, result := range results {
+		record := newResultRecord(result, s.instance)
+		value, err := s.encode(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode result of %s: %w", record.URL, err)
+		}
+		records = append(records, kafka.Record{Key: []byte(record.URL), Value: value})
+	}
+
+	if err := s.producer.Produce(ctx, records); err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("topic", s.config.Topic).
+		Int("targets", len(results)).
+		Msg("Published results to Kafka")
+
+	return nil
+}
+
+// Close publishes the queued results, dropping those left when ctx is done, and closes the
+// connections to the brokers
+func (s *KafkaSink) Close(ctx context.Context) error {
+	s.queue.close(ctx)
+	return s.producer.Close()
+}
+
+func (s *KafkaSink) encode(record resultRecord) ([]byte, error) {
+	if s.config.Format != config.KafkaFormatAvro {
+		return json.Marshal(record)
+	}
+
+	var value []byte
+	if s.config.SchemaID > 0 {
+		// Confluent wire format: magic byte and the registry's schema ID
+		value = append(value, 0)
+		value = binary.BigEndian.AppendUint32(value, uint32(s.config.SchemaID))
+	}
+	return appendAvroRecord(value, record), nil
+}
+
+// appendAvroRecord appends the Avro binary encoding of the record per ResultAvroSchema
+func appendAvroRecord(buf []byte, record resultRecord) []byte {
+	buf = binary.AppendVarint(buf, record.Timestamp.UnixMilli())
+	buf = appendAvroString(buf, record.Instance)
+	buf = appendAvroOptionalString(buf, record.CycleID)
+	buf = appendAvroString(buf, record.URL)
+	buf = appendAvroString(buf, record.Host)
+	buf = appendAvroString(buf, record.Path)
+	buf = appendAvroString(buf, record.Protocol)
+	buf = appendAvroBool(buf, record.Up)
+	buf = binary.AppendVarint(buf, int64(record.StatusCode))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(record.ResponseTimeMs))
+	if record.HTTPVersion > 0 {
+		buf = binary.AppendVarint(buf, 1)
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(record.HTTPVersion))
+	} else {
+		buf = binary.AppendVarint(buf, 0)
+	}
+	buf = appendAvroOptionalBool(buf, record.BodyMatch)
+	buf = appendAvroOptionalBool(buf, record.HeaderMatch)
+	buf = appendAvroOptionalString(buf, record.Error)
+	buf = appendAvroOptionalString(buf, record.SkippedBy)
+	return buf
+}
+
+func appendAvroString(buf []byte, value string) []byte {
+	buf = binary.AppendVarint(buf, int64(len(value)))
+	return append(buf, value...)
+}
+
+func appendAvroBool(buf []byte, value bool) []byte {
+	if value {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+// appendAvroOptionalString encodes a ["null", "string"] union, null when empty
+func appendAvroOptionalString(buf []byte, value string) []byte {
+	if value == "" {
+		return binary.AppendVarint(buf, 0)
+	}
+	return appendAvroString(binary.AppendVarint(buf, 1), value)
+}
+
+// appendAvroOptionalBool encodes a ["null", "boolean"] union
+func appendAvroOptionalBool(buf []byte, value *bool) []byte {
+	if value == nil {
+		return binary.AppendVarint(buf, 0)
+	}
+	return appendAvroBool(binary.AppendVarint(buf, 1), *value)
+}
@@ -0,0 +1,172 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/nats"
+	"github.com/rs/zerolog/log"
+)
+
+// TargetLookup returns the registered target with the name or URL
+type TargetLookup func(nameOrURL string) (config.Target, bool)
+
+// subjectData is the data of the NATS subject templates. Dots, whitespace and wildcards in
+// the values are replaced by underscores so each value is a single subject token.
+type subjectData struct {
+	Group    string // the target's group, "none" for ungrouped targets
+	Name     string // the target's name, its hostname if unnamed
+	Host     string // the hostname of the target's URL
+	Protocol string
+	Status   string // up, down or skipped
+	Instance string
+}
+
+// NATSSink publishes the results of each check cycle to NATS, one JSON message per result
+// on the subject rendered from the subject template of the target's group. Cycles are
+// published in the background, see publishQueue.
+type NATSSink struct {
+	config    config.NATSConfig
+	instance  string
+	lookup    TargetLookup
+	client    *nats.Client
+	templates map[string]*template.Template // template text -> parsed template
+	queue     *publishQueue
+}
+
+func NewNATSSink(cfg config.NATSConfig, instance string, timeout time.Duration, lookup TargetLookup) (*NATSSink, error) {
+	client, err := nats.NewClient(cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &NATSSink{
+		config:    cfg,
+		instance:  instance,
+		lookup:    lookup,
+		client:    client,
+		templates: make(map[string]*template.Template),
+	}
+	for _, text := range slices.AppendSeq([]string{cfg.Subject}, maps.Values(cfg.GroupSubjects)) {
+		tmpl, err := template.New("subject").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nats subject template %q: %w", text, err)
+		}
+		s.templates[text] = tmpl
+	}
+	s.queue = newPublishQueue("nats", s.Write)
+	return s, nil
+}
+
+// HandleCycle is a checker.CycleHandler that queues the cycle's results for publishing
+// without waiting for the server; failures are logged
+func (s *NATSSink) HandleCycle(_ context.Context, results []checker.Result) {
+	s.queue.enqueue(results)
+}
+
+// DroppedResults returns the number of results dropped because the server did not keep up
+func (s *NATSSink) DroppedResults() uint64 {
+	return s.queue.dropped.Load()
+}
+
+// Write publishes the given results
+func (s *NATSSink) Write(ctx context.Context, results []checker.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	messages := make([]nats.Message, 0, len(results))
+	for _, result := range results {
+		record := newResultRecord(result, s.instance)
+		subject, err := s.subject(result)
+		if err != nil {
+			return fmt.Errorf("failed to render the subject of %s: %w", record.URL, err)
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode result of %s: %w", record.URL, err)
+		}
+		messages = append(messages, nats.Message{Subject: subject, Data: data})
+	}
+
+	if err := s.client.Publish(ctx, messages); err != nil {
+		return err
+	}
+
+	log.Debug().
+		Bool("jetStream", s.config.JetStream).
+		Int("targets", len(results)).
+		Msg("Published results to NATS")
+
+	return nil
+}
+
+// Close publishes the queued results, dropping those left when ctx is done, and closes the
+// connection to the server
+func (s *NATSSink) Close(ctx context.Context) error {
+	s.queue.close(ctx)
+	return s.client.Close()
+}
+
+// subject renders the subject of the result from the template of the target's group
+func (s *NATSSink) subject(result checker.Result) (string, error) {
+	host := result.URL
+	if parsed, err := url.Parse(result.URL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	var group string
+	data := subjectData{
+		Group:    "none",
+		Name:     host,
+		Host:     host,
+		Protocol: result.Protocol,
+		Status:   "down",
+		Instance: s.instance,
+	}
+	if target, exists := s.lookup(result.URL); exists {
+		group = target.Group
+		if target.Group != "" {
+			data.Group = target.Group
+		}
+		if target.Name != "" {
+			data.Name = target.Name
+		}
+	}
+	switch {
+	case result.IsUp():
+		data.Status = "up"
+	case result.IsSkipped():
+		data.Status = "skipped"
+	}
+
+	for _, value := range []*string{&data.Group, &data.Name, &data.Host, &data.Protocol, &data.Instance} {
+		*value = subjectToken(*value)
+	}
+
+	var subject strings.Builder
+	if err := s.templates[s.config.SubjectFor(group)].Execute(&subject, data); err != nil {
+		return "", err
+	}
+	return subject.String(), nil
+}
+
+// subjectToken replaces the characters that separate or match subject tokens
+func subjectToken(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '*' || r == '>' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
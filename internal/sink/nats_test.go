@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSSink_Subject(t *testing.T) {
+	targets := map[string]config.Target{
+		"https://api.example.com/health": {URL: "https://api.example.com/health", Name: "Orders API", Group: "Payments"},
+		"tcp://db.internal:5432":         {URL: "tcp://db.internal:5432", Group: "storage"},
+	}
+	s, err := NewNATSSink(config.NATSConfig{
+		Servers:       []string{"127.0.0.1:1"},
+		Subject:       config.DefaultNATSSubject,
+		GroupSubjects: map[string]string{"payments": "payments.{{.Name}}.{{.Status}}"},
+	}, "vm-01", time.Second, func(nameOrURL string) (config.Target, bool) {
+		target, exists := targets[nameOrURL]
+		return target, exists
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		result checker.Result
+		want   string
+	}{
+		{
+			name:   "group subject",
+			result: checker.Result{URL: "https://api.example.com/health", StatusCode: 200},
+			want:   "payments.Orders_API.up",
+		},
+		{
+			name:   "default subject",
+			result: checker.Result{URL: "tcp://db.internal:5432", Error: errors.New("connection refused")},
+			want:   "url-exporter.results.storage.down",
+		},
+		{
+			name:   "ungrouped target",
+			result: checker.Result{URL: "https://unknown.example.com", SkippedBy: "db"},
+			want:   "url-exporter.results.none.skipped",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, err := s.subject(tt.result)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, subject)
+		})
+	}
+
+	s, err = NewNATSSink(config.NATSConfig{Servers: []string{"127.0.0.1:1"}, Subject: "{{.Host}}.{{.Protocol}}"}, "vm-01", time.Second, s.lookup)
+	require.NoError(t, err)
+	subject, err := s.subject(checker.Result{URL: "tcp://db.internal:5432", Protocol: "tcp"})
+	require.NoError(t, err)
+	assert.Equal(t, "db_internal.tcp", subject, "dots in values do not split tokens")
+}
+
+func TestNATSSink_WriteUnavailable(t *testing.T) {
+	s, err := NewNATSSink(config.NATSConfig{Servers: []string{"127.0.0.1:1"}, Subject: config.DefaultNATSSubject}, "vm-01", time.Second,
+		func(string) (config.Target, bool) { return config.Target{}, false })
+	require.NoError(t, err)
+	defer s.Close(context.Background())
+
+	err = s.Write(context.Background(), []checker.Result{{URL: "https://example.com", StatusCode: 200}})
+	assert.ErrorContains(t, err, "no server available")
+	assert.NoError(t, s.Write(context.Background(), nil))
+}
@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/rs/zerolog/log"
+)
+
+// publishQueueSize is the number of cycles a broker sink may fall behind before the
+// results of new cycles are dropped for it
+const publishQueueSize = 16
+
+// publishQueue publishes the results of each cycle from its own goroutine, so a slow or
+// unreachable broker does not hold up the check cycles. Like the result stream subscribers
+// it is bounded: the results of cycles that do not fit are dropped and counted.
+type publishQueue struct {
+	name    string
+	publish func(ctx context.Context, results []checker.Result) error
+	cycles  chan []checker.Result
+	done    chan struct{}
+	dropped atomic.Uint64
+	// ctx is the context of the publishing, canceled when close stops waiting for it
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// newPublishQueue starts publishing the queued cycles with publish
+func newPublishQueue(name string, publish func(ctx context.Context, results []checker.Result) error) *publishQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &publishQueue{
+		name:    name,
+		publish: publish,
+		cycles:  make(chan []checker.Result, publishQueueSize),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues the results of a cycle without blocking, dropping them when the queue
+// is full or closed
+func (q *publishQueue) enqueue(results []checker.Result) {
+	if len(results) == 0 {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if !q.closed {
+		select {
+		case q.cycles <- slices.Clone(results):
+			return
+		default:
+		}
+	}
+	q.dropped.Add(uint64(len(results)))
+	log.Warn().
+		Str("sink", q.name).
+		Int("dropped", len(results)).
+		Msg("Dropped check results for a sink that did not keep up")
+}
+
+// run publishes the queued cycles. Once the publishing is canceled, the cycles still
+// queued and the one being published are dropped.
+func (q *publishQueue) run() {
+	defer close(q.done)
+	for results := range q.cycles {
+		if q.ctx.Err() != nil {
+			q.dropped.Add(uint64(len(results)))
+			continue
+		}
+		if err := q.publish(q.ctx, results); err != nil {
+			if q.ctx.Err() != nil {
+				q.dropped.Add(uint64(len(results)))
+				continue
+			}
+			log.Error().Err(err).Str("sink", q.name).Msg("Failed to publish results")
+		}
+	}
+}
+
+// close stops queueing results and waits until the queued ones are published. When ctx
+// is done first, the publishing is canceled and the results not published yet are
+// dropped.
+func (q *publishQueue) close(ctx context.Context) {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.cycles)
+	}
+	q.mutex.Unlock()
+	defer q.cancel()
+
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+		dropped := q.dropped.Load()
+		q.cancel()
+		<-q.done
+		log.Warn().
+			Str("sink", q.name).
+			Uint64("dropped", q.dropped.Load()-dropped).
+			Msg("Dropped queued check results on shutdown")
+	}
+}
@@ -0,0 +1,139 @@
+package sink
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishQueue(t *testing.T) {
+	release := make(chan struct{})
+	published := make(chan []checker.Result, publishQueueSize+1)
+	q := newPublishQueue("test", func(ctx context.Context, results []checker.Result) error {
+		<-release
+		published <- results
+		return nil
+	})
+
+	results := []checker.Result{{URL: "https://a.example.com"}, {URL: "https://b.example.com"}}
+	// One cycle is being published, the queue holds the next ones
+	for i := 0; i < publishQueueSize+2; i++ {
+		q.enqueue(results)
+	}
+	q.enqueue(nil)
+	assert.Positive(t, q.dropped.Load())
+	assert.LessOrEqual(t, q.dropped.Load(), uint64(2*len(results)))
+
+	// The queue holds copies, a handler reusing the slice does not change them
+	results[0].URL = "https://changed.example.com"
+
+	close(release)
+	q.close(context.Background())
+	close(published)
+	count := 0
+	for cycle := range published {
+		assert.Equal(t, "https://a.example.com", cycle[0].URL)
+		count++
+	}
+	assert.Equal(t, uint64(len(results)*(publishQueueSize+2-count)), q.dropped.Load())
+
+	// Results of cycles completing after the close are dropped
+	q.enqueue(results)
+	assert.Equal(t, uint64(len(results)*(publishQueueSize+3-count)), q.dropped.Load())
+}
+
+func TestPublishQueue_CloseDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	q := newPublishQueue("test", func(ctx context.Context, results []checker.Result) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	results := []checker.Result{{URL: "https://a.example.com"}, {URL: "https://b.example.com"}}
+	for i := 0; i < 3; i++ {
+		q.enqueue(results)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	q.close(ctx)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, uint64(3*len(results)), q.dropped.Load(), "the interrupted and the queued cycles are dropped")
+}
+
+// silentListener accepts connections and never answers, like a broker that hangs
+func silentListener(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestBrokerSinks_CloseDeadline(t *testing.T) {
+	address := silentListener(t)
+	kafkaSink, err := NewKafkaSink(config.KafkaConfig{Enabled: true, Brokers: []string{address}, Topic: "probes"}, "vm-01", time.Minute)
+	require.NoError(t, err)
+	natsSink, err := NewNATSSink(config.NATSConfig{Servers: []string{address}, Subject: config.DefaultNATSSubject}, "vm-01", time.Minute,
+		func(string) (config.Target, bool) { return config.Target{}, false })
+	require.NoError(t, err)
+
+	for name, s := range map[string]interface {
+		HandleCycle(ctx context.Context, results []checker.Result)
+		Close(ctx context.Context) error
+		DroppedResults() uint64
+	}{"kafka": kafkaSink, "nats": natsSink} {
+		for i := 0; i < 3; i++ {
+			s.HandleCycle(context.Background(), []checker.Result{kafkaTestResult()})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		require.NoError(t, s.Close(ctx), name)
+		cancel()
+		assert.Less(t, time.Since(start), 5*time.Second, "%s waits for the shutdown deadline, not the timeout", name)
+		assert.Equal(t, uint64(3), s.DroppedResults(), name)
+	}
+}
+
+func TestKafkaSink_HandleCycleDoesNotWait(t *testing.T) {
+	s := testKafkaSink(t, config.KafkaConfig{Format: config.KafkaFormatJSON})
+
+	start := time.Now()
+	for i := 0; i < publishQueueSize+1; i++ {
+		s.HandleCycle(context.Background(), []checker.Result{kafkaTestResult()})
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	require.NoError(t, s.Close(context.Background()))
+}
+
+func TestNATSSink_HandleCycleDoesNotWait(t *testing.T) {
+	s, err := NewNATSSink(config.NATSConfig{Servers: []string{"127.0.0.1:1"}, Subject: config.DefaultNATSSubject}, "vm-01", time.Second,
+		func(string) (config.Target, bool) { return config.Target{}, false })
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < publishQueueSize+1; i++ {
+		s.HandleCycle(context.Background(), []checker.Result{{URL: "https://example.com", StatusCode: 200}})
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	require.NoError(t, s.Close(context.Background()))
+}
@@ -0,0 +1,51 @@
+package sink
+
+import (
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// resultRecord is the JSON form of a check result written by the audit log and the Kafka
+// sink
+type resultRecord struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Instance       string    `json:"instance"`
+	CycleID        string    `json:"cycle_id,omitempty"`
+	URL            string    `json:"url"`
+	Host           string    `json:"host"`
+	Path           string    `json:"path"`
+	Protocol       string    `json:"protocol"`
+	Up             bool      `json:"up"`
+	StatusCode     int       `json:"status_code"`
+	ResponseTimeMs float64   `json:"response_time_ms"`
+	HTTPVersion    float64   `json:"http_version,omitempty"`
+	BodyMatch      *bool     `json:"body_match,omitempty"`
+	HeaderMatch    *bool     `json:"header_match,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	SkippedBy      string    `json:"skipped_by,omitempty"`
+}
+
+func newResultRecord(result checker.Result, instance string) resultRecord {
+	record := resultRecord{
+		Timestamp:      result.Timestamp.UTC(),
+		Instance:       instance,
+		CycleID:        result.CycleID,
+		URL:            config.RedactURL(result.URL),
+		Host:           result.Host,
+		Path:           result.Path,
+		Protocol:       result.Protocol,
+		Up:             result.IsUp(),
+		SkippedBy:      config.RedactURL(result.SkippedBy),
+		StatusCode:     result.StatusCode,
+		ResponseTimeMs: float64(result.ResponseTime) / float64(time.Millisecond),
+		HTTPVersion:    result.HTTPVersion,
+		BodyMatch:      result.BodyMatch,
+		HeaderMatch:    result.HeaderMatch,
+	}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	return record
+}
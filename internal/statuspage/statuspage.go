@@ -0,0 +1,154 @@
+// Package statuspage renders a public-friendly HTML status page from the check history.
+package statuspage
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+)
+
+// Days is the number of daily uptime bars shown per target
+const Days = metrics.DailyHistoryDays
+
+// Thresholds of the daily uptime bar colors
+const (
+	minorUptime = 99.0
+	fullUptime  = 100.0
+)
+
+//go:embed statuspage.html
+var pageHTML string
+
+var pageTemplate = template.Must(template.New("statuspage").Parse(pageHTML))
+
+// Source provides the state of the targets shown on the page
+type Source interface {
+	// Current returns whether the target was up on its last check, and false for known
+	// when it was not checked yet
+	Current(url string) (up, known bool)
+	// Daily returns the target's check counts of the last days, oldest first
+	Daily(url string, days int) []metrics.DailyUptime
+}
+
+// Page is the data the status page is rendered from
+type Page struct {
+	Title   string
+	Updated string
+	Status  string // operational, outage or unknown
+	Days    int
+	Groups  []Group
+}
+
+// Group is a section of the page listing the targets of a target group
+type Group struct {
+	Name    string
+	Targets []Target
+}
+
+// Target is a row of the page with the current state and daily uptime bars
+type Target struct {
+	Name   string
+	Status string // up, down or unknown
+	Uptime string
+	Days   []Day
+}
+
+// Day is a single uptime bar, colored by Level: up, minor, major or none without checks
+type Day struct {
+	Level string
+	Title string
+}
+
+// Build collects the page data of the enabled targets. Targets are shown by name, or by
+// URL when they have none; those without a group are listed under "Services".
+func Build(title string, targets []config.Target, source Source, now time.Time) Page {
+	page := Page{
+		Title:   title,
+		Updated: now.UTC().Format("2006-01-02 15:04 MST"),
+		Status:  "operational",
+		Days:    Days,
+	}
+
+	groups := make(map[string]*Group)
+	known := 0
+	for _, target := range targets {
+		if target.Disabled {
+			continue
+		}
+
+		row := Target{Name: target.Name, Status: "unknown"}
+		if row.Name == "" {
+			row.Name = config.RedactURL(target.URL)
+		}
+		if up, checked := source.Current(target.URL); checked {
+			known++
+			row.Status = "up"
+			if !up {
+				row.Status = "down"
+				page.Status = "outage"
+			}
+		}
+
+		checks, successful := 0, 0
+		for _, day := range source.Daily(target.URL, Days) {
+			checks += day.Checks
+			successful += day.Successful
+			row.Days = append(row.Days, newDay(day))
+		}
+		row.Uptime = "-"
+		if checks > 0 {
+			row.Uptime = fmt.Sprintf("%.2f%%", float64(successful)*100/float64(checks))
+		}
+
+		name := target.Group
+		if name == "" {
+			name = "Services"
+		}
+		group, exists := groups[name]
+		if !exists {
+			group = &Group{Name: name}
+			groups[name] = group
+		}
+		group.Targets = append(group.Targets, row)
+	}
+
+	if known == 0 {
+		page.Status = "unknown"
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.Targets, func(i, j int) bool { return group.Targets[i].Name < group.Targets[j].Name })
+		page.Groups = append(page.Groups, *group)
+	}
+	sort.Slice(page.Groups, func(i, j int) bool { return page.Groups[i].Name < page.Groups[j].Name })
+
+	return page
+}
+
+func newDay(day metrics.DailyUptime) Day {
+	date := day.Day.Format("Jan 2, 2006")
+	if day.Checks == 0 {
+		return Day{Level: "none", Title: date + ": no data"}
+	}
+
+	uptime := day.Uptime()
+	level := "major"
+	switch {
+	case uptime >= fullUptime:
+		level = "up"
+	case uptime >= minorUptime:
+		level = "minor"
+	}
+	return Day{Level: level, Title: fmt.Sprintf("%s: %.2f%% uptime", date, uptime)}
+}
+
+// Render writes the page as a self-contained HTML document
+func Render(w io.Writer, page Page) error {
+	return pageTemplate.Execute(w, page)
+}
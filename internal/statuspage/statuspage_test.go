@@ -0,0 +1,86 @@
+package statuspage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	current map[string]bool
+	daily   map[string][]metrics.DailyUptime
+	now     time.Time
+}
+
+func (f fakeSource) Current(url string) (bool, bool) {
+	up, known := f.current[url]
+	return up, known
+}
+
+func (f fakeSource) Daily(url string, days int) []metrics.DailyUptime {
+	return metrics.DailySeries(f.daily[url], days, f.now)
+}
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	today := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	targets := []config.Target{
+		{URL: "https://web.example.com", Name: "Website", Group: "public"},
+		{URL: "https://api.example.com/health", Group: "public"},
+		{URL: "https://db.internal:5432"},
+		{URL: "https://paused.example.com", Disabled: true},
+	}
+	source := fakeSource{
+		current: map[string]bool{"https://web.example.com": true, "https://api.example.com/health": false},
+		daily: map[string][]metrics.DailyUptime{
+			"https://web.example.com": {
+				{Day: today.AddDate(0, 0, -2), Checks: 100, Successful: 100},
+				{Day: today.AddDate(0, 0, -1), Checks: 100, Successful: 99},
+				{Day: today, Checks: 100, Successful: 50},
+			},
+		},
+		now: now,
+	}
+
+	page := Build("Acme Status", targets, source, now)
+	assert.Equal(t, "Acme Status", page.Title)
+	assert.Equal(t, "outage", page.Status)
+	assert.Equal(t, "2026-03-01 12:00 UTC", page.Updated)
+
+	require.Len(t, page.Groups, 2)
+	assert.Equal(t, "Services", page.Groups[0].Name)
+	assert.Equal(t, "public", page.Groups[1].Name)
+
+	public := page.Groups[1].Targets
+	require.Len(t, public, 2)
+	assert.Equal(t, "Website", public[0].Name)
+	assert.Equal(t, "up", public[0].Status)
+	assert.Equal(t, "83.00%", public[0].Uptime)
+	require.Len(t, public[0].Days, Days)
+	assert.Equal(t, Day{Level: "none", Title: "Dec 2, 2025: no data"}, public[0].Days[0])
+	assert.Equal(t, Day{Level: "up", Title: "Feb 27, 2026: 100.00% uptime"}, public[0].Days[Days-3])
+	assert.Equal(t, "minor", public[0].Days[Days-2].Level)
+	assert.Equal(t, "major", public[0].Days[Days-1].Level)
+
+	assert.Equal(t, "https://api.example.com/health", public[1].Name)
+	assert.Equal(t, "down", public[1].Status)
+	assert.Equal(t, "-", public[1].Uptime)
+	assert.Equal(t, "unknown", page.Groups[0].Targets[0].Status)
+
+	var html strings.Builder
+	require.NoError(t, Render(&html, page))
+	assert.Contains(t, html.String(), "<title>Acme Status</title>")
+	assert.Contains(t, html.String(), "Some systems are down")
+	assert.Contains(t, html.String(), `<span class="minor" title="Feb 28, 2026: 99.00% uptime"></span>`)
+	assert.NotContains(t, html.String(), "paused.example.com")
+
+	assert.Equal(t, "unknown", Build("Status", targets, fakeSource{now: now}, now).Status)
+	source.current["https://api.example.com/health"] = true
+	assert.Equal(t, "operational", Build("Status", targets, source, now).Status)
+}
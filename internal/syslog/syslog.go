@@ -0,0 +1,180 @@
+// Package syslog sends log lines to a syslog server as RFC 5424 messages over UDP, TCP or
+// TLS, for hosts where the exporter's stderr is not collected.
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// AppName identifies the exporter in the messages
+const AppName = "url-exporter"
+
+// writeTimeout bounds how long logging waits for a slow or unreachable syslog server
+const writeTimeout = 5 * time.Second
+
+// Writer is a zerolog.LevelWriter that sends each log line as a syslog message. It
+// connects on the first write and reconnects after a failed one.
+type Writer struct {
+	protocol  string
+	address   string
+	tlsConfig *tls.Config
+	facility  int
+	hostname  string
+	procID    string
+
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// New creates a writer for the configured syslog server, with hostname as the HOSTNAME
+// of the messages
+func New(cfg config.SyslogConfig, hostname string) (*Writer, error) {
+	w := &Writer{
+		protocol: cfg.Protocol,
+		address:  cfg.Address,
+		facility: cfg.FacilityCode(),
+		hostname: headerField(hostname),
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+
+	if cfg.Protocol == config.SyslogProtocolTLS {
+		tlsConfig, err := cfg.TLS.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog TLS configuration: %w", err)
+		}
+		w.tlsConfig = tlsConfig
+	}
+
+	return w, nil
+}
+
+// Write sends a line without a level, as a notice
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel sends a line with the severity matching the level. A failed write is
+// retried once on a new connection.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	message := w.format(level, time.Now(), p)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	err := w.send(message)
+	if err != nil {
+		w.closeConn()
+		err = w.send(message)
+	}
+	if err != nil {
+		w.closeConn()
+		return 0, fmt.Errorf("failed to write to syslog %s: %w", w.address, err)
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog server
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.closeConn()
+}
+
+// format builds the RFC 5424 message for a log line
+func (w *Writer) format(level zerolog.Level, now time.Time, p []byte) []byte {
+	priority := w.facility*8 + severity(level)
+	header := fmt.Sprintf("<%d>1 %s %s %s %s - - ",
+		priority, now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, AppName, w.procID)
+
+	message := append([]byte(header), strings.TrimRight(string(p), "\n")...)
+
+	// Stream transports frame each message with its length (RFC 6587 octet counting)
+	if w.protocol != config.SyslogProtocolUDP {
+		message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	return message
+}
+
+// send writes a message, connecting first if needed. The caller must hold the mutex.
+func (w *Writer) send(message []byte) error {
+	if w.conn == nil {
+		conn, err := w.dial()
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(message)
+	return err
+}
+
+func (w *Writer) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: writeTimeout}
+	switch w.protocol {
+	case config.SyslogProtocolTLS:
+		return tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	case config.SyslogProtocolTCP:
+		return dialer.Dial("tcp", w.address)
+	default:
+		return dialer.Dial("udp", w.address)
+	}
+}
+
+// closeConn closes the current connection, if any. The caller must hold the mutex.
+func (w *Writer) closeConn() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// severity maps a zerolog level to the syslog severity
+func severity(level zerolog.Level) int {
+	switch level {
+	case zerolog.PanicLevel:
+		return 1 // alert
+	case zerolog.FatalLevel:
+		return 2 // critical
+	case zerolog.ErrorLevel:
+		return 3 // error
+	case zerolog.WarnLevel:
+		return 4 // warning
+	case zerolog.InfoLevel:
+		return 6 // informational
+	case zerolog.DebugLevel, zerolog.TraceLevel:
+		return 7 // debug
+	default:
+		return 5 // notice
+	}
+}
+
+// headerField makes a value usable as a header field, which must be printable ASCII
+// without spaces, or "-" when empty
+func headerField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+}
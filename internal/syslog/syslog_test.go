@@ -0,0 +1,136 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter_Format(t *testing.T) {
+	w, err := New(config.SyslogConfig{Address: "127.0.0.1:514", Protocol: config.SyslogProtocolUDP, Facility: "local0"}, "vm 01")
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 678000000, time.UTC)
+	message := string(w.format(zerolog.WarnLevel, now, []byte(`{"level":"warn","message":"slow"}`+"\n")))
+
+	// local0 (16) * 8 + warning (4)
+	expected := "<132>1 2026-01-02T03:04:05.678000Z vm_01 url-exporter " + strconv.Itoa(os.Getpid()) +
+		` - - {"level":"warn","message":"slow"}`
+	assert.Equal(t, expected, message)
+
+	w.protocol = config.SyslogProtocolTCP
+	framed := string(w.format(zerolog.WarnLevel, now, []byte(`{"level":"warn","message":"slow"}`)))
+	assert.Equal(t, strconv.Itoa(len(expected))+" "+expected, framed)
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, 3, severity(zerolog.ErrorLevel))
+	assert.Equal(t, 6, severity(zerolog.InfoLevel))
+	assert.Equal(t, 7, severity(zerolog.DebugLevel))
+	assert.Equal(t, 5, severity(zerolog.NoLevel))
+}
+
+func TestWriter_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	w, err := New(config.SyslogConfig{Address: conn.LocalAddr().String(), Protocol: config.SyslogProtocolUDP, Facility: "daemon"}, "host")
+	require.NoError(t, err)
+	defer func() { _ = w.Close() }()
+
+	logger := zerolog.New(w)
+	logger.Error().Msg("check failed")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	// daemon (3) * 8 + error (3)
+	assert.Regexp(t, regexp.MustCompile(`^<27>1 \S+ host url-exporter \d+ - - \{"level":"error","message":"check failed"\}$`), string(buf[:n]))
+}
+
+func TestWriter_TCPReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				reader := bufio.NewReader(conn)
+				for {
+					length, err := reader.ReadString(' ')
+					if err != nil {
+						return
+					}
+					size, err := strconv.Atoi(length[:len(length)-1])
+					if err != nil {
+						return
+					}
+					message := make([]byte, size)
+					if _, err := io.ReadFull(reader, message); err != nil {
+						return
+					}
+					lines <- string(message)
+				}
+			}()
+		}
+	}()
+
+	w, err := New(config.SyslogConfig{Address: listener.Addr().String(), Protocol: config.SyslogProtocolTCP, Facility: "daemon"}, "host")
+	require.NoError(t, err)
+	defer func() { _ = w.Close() }()
+
+	_, err = w.WriteLevel(zerolog.InfoLevel, []byte("first\n"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^<30>1 .* - - first$`, receive(t, lines))
+
+	// A dropped connection is replaced on the next write
+	require.NoError(t, w.conn.Close())
+	_, err = w.WriteLevel(zerolog.InfoLevel, []byte("second\n"))
+	require.NoError(t, err)
+	assert.Regexp(t, `- - second$`, receive(t, lines))
+}
+
+func TestWriter_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	w, err := New(config.SyslogConfig{Address: address, Protocol: config.SyslogProtocolTCP, Facility: "daemon"}, "host")
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("lost"))
+	assert.Error(t, err)
+}
+
+func receive(t *testing.T, lines <-chan string) string {
+	t.Helper()
+
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a syslog message")
+		return ""
+	}
+}
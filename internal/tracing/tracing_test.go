@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestTracesURL(t *testing.T) {
+	tests := map[string]string{
+		"http://otel-collector:4318":        "http://otel-collector:4318/v1/traces",
+		"https://otel.example.com/":         "https://otel.example.com/v1/traces",
+		"https://gateway.example.com/otlp/": "https://gateway.example.com/otlp/v1/traces",
+	}
+
+	for endpoint, expected := range tests {
+		got, err := tracesURL(endpoint)
+		require.NoError(t, err)
+		assert.Equal(t, expected, got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	provider, err := New(config.TracingConfig{Endpoint: "http://127.0.0.1:4318", SampleRatio: 1}, "test-instance", "1.0.0")
+	require.NoError(t, err)
+
+	assert.Same(t, provider, otel.GetTracerProvider())
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+}
@@ -0,0 +1,225 @@
+// Package uptimerobot reads the monitors of UptimeRobot through its API v2 and converts
+// them into exporter checks, to consolidate the monitoring onto the exporter.
+package uptimerobot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// DefaultEndpoint is the base URL of the UptimeRobot API
+const DefaultEndpoint = "https://api.uptimerobot.com/v2"
+
+// IDLabel is the label of the imported checks holding the ID of their monitor
+const IDLabel = "uptimerobot_monitor_id"
+
+// pageSize is the number of monitors read per request, the most the API returns
+const pageSize = 50
+
+// Monitor types
+const (
+	typeHTTP      = 1
+	typeKeyword   = 2
+	typePing      = 3
+	typePort      = 4
+	typeHeartbeat = 5
+)
+
+// keywordNotExists is the keyword_type of monitors that are down when the keyword is
+// missing from the response
+const keywordNotExists = 2
+
+// statusPaused is the status of paused monitors
+const statusPaused = 0
+
+// subTypePorts are the ports of the sub_type of port monitors other than custom ports:
+// HTTP, HTTPS, FTP, SMTP, POP3 and IMAP
+var subTypePorts = map[int]int{1: 80, 2: 443, 3: 21, 4: 25, 5: 110, 6: 143}
+
+// Monitor is the definition of an UptimeRobot monitor
+type Monitor struct {
+	ID           int    `json:"id"`
+	FriendlyName string `json:"friendly_name"`
+	URL          string `json:"url"`
+	Type         int    `json:"type"`
+	SubType      any    `json:"sub_type"`
+	KeywordType  any    `json:"keyword_type"`
+	KeywordValue string `json:"keyword_value"`
+	HTTPUsername string `json:"http_username"`
+	Port         any    `json:"port"`
+	Interval     int    `json:"interval"` // seconds between checks
+	Status       int    `json:"status"`
+}
+
+type monitorsResponse struct {
+	Stat       string `json:"stat"`
+	Pagination struct {
+		Offset int `json:"offset"`
+		Limit  int `json:"limit"`
+		Total  int `json:"total"`
+	} `json:"pagination"`
+	Monitors []Monitor `json:"monitors"`
+	Error    struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Client reads monitors from the UptimeRobot API with an API key
+type Client struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewClient creates a client of the API at the endpoint, DefaultEndpoint outside tests
+func NewClient(endpoint, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Monitors lists every monitor of the account
+func (c *Client) Monitors(ctx context.Context) ([]Monitor, error) {
+	var monitors []Monitor
+	for offset := 0; ; offset += pageSize {
+		page, err := c.getMonitors(ctx, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list monitors: %w", err)
+		}
+		monitors = append(monitors, page.Monitors...)
+		if len(page.Monitors) == 0 || offset+len(page.Monitors) >= page.Pagination.Total {
+			return monitors, nil
+		}
+	}
+}
+
+// getMonitors reads the page of monitors at the offset
+func (c *Client) getMonitors(ctx context.Context, offset int) (monitorsResponse, error) {
+	form := url.Values{
+		"api_key": {c.apiKey},
+		"format":  {"json"},
+		"offset":  {strconv.Itoa(offset)},
+		"limit":   {strconv.Itoa(pageSize)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/getMonitors", strings.NewReader(form.Encode()))
+	if err != nil {
+		return monitorsResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return monitorsResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return monitorsResponse{}, err
+	}
+	var page monitorsResponse
+	if err := json.Unmarshal(content, &page); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return monitorsResponse{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return monitorsResponse{}, fmt.Errorf("invalid response: %w", err)
+	}
+	if page.Stat != "ok" {
+		return monitorsResponse{}, fmt.Errorf("%s: %s", page.Error.Type, page.Error.Message)
+	}
+	return page, nil
+}
+
+// Convert converts the HTTP, keyword and port monitors into checks of the same URL and
+// interval. Paused monitors are imported disabled. The returned warnings explain the
+// monitors that were skipped or imported with differences.
+func Convert(monitors []Monitor) ([]config.Target, []string) {
+	var (
+		checks   []config.Target
+		warnings []string
+	)
+	warn := func(monitor Monitor, format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf("monitor %d (%s): ", monitor.ID, monitor.FriendlyName)+fmt.Sprintf(format, args...))
+	}
+	taken := make(map[string]bool)
+
+	for _, monitor := range monitors {
+		target := config.Target{URL: monitor.URL}
+		switch monitor.Type {
+		case typeHTTP:
+		case typeKeyword:
+			if number(monitor.KeywordType) == keywordNotExists {
+				target.Method = http.MethodGet
+				target.ExpectBody = regexp.QuoteMeta(monitor.KeywordValue)
+			} else {
+				warn(monitor, "alerting when the keyword exists has no equivalent, the keyword was not imported")
+			}
+		case typePort:
+			port := number(monitor.Port)
+			if port == 0 {
+				port = subTypePorts[number(monitor.SubType)]
+			}
+			if port == 0 {
+				warn(monitor, "port monitor without port, skipped")
+				continue
+			}
+			target.URL = "tcp://" + net.JoinHostPort(monitor.URL, strconv.Itoa(port))
+		case typePing:
+			warn(monitor, "ping monitors have no equivalent, skipped")
+			continue
+		case typeHeartbeat:
+			warn(monitor, "heartbeat monitors have no equivalent, skipped")
+			continue
+		default:
+			warn(monitor, "type %d has no equivalent, skipped", monitor.Type)
+			continue
+		}
+		if monitor.HTTPUsername != "" {
+			warn(monitor, "HTTP authentication was not imported")
+		}
+
+		target.Name = monitor.FriendlyName
+		if taken[target.Name] {
+			target.Name = fmt.Sprintf("%s-%d", monitor.FriendlyName, monitor.ID)
+		}
+		if target.Name != "" {
+			taken[target.Name] = true
+		}
+		target.Labels = map[string]string{IDLabel: strconv.Itoa(monitor.ID)}
+		target.Disabled = monitor.Status == statusPaused
+		if monitor.Interval > 0 {
+			target.Interval = strconv.Itoa(monitor.Interval) + "s"
+		}
+		checks = append(checks, target)
+	}
+
+	return checks, warnings
+}
+
+// number returns the value of a field the API returns as a number, a numeric string or an
+// empty string, 0 if it is not a number
+func number(value any) int {
+	switch value := value.(type) {
+	case float64:
+		return int(value)
+	case string:
+		n, _ := strconv.Atoi(value)
+		return n
+	default:
+		return 0
+	}
+}
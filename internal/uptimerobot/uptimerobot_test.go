@@ -0,0 +1,81 @@
+package uptimerobot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Monitors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.URL.Path != "/getMonitors" || r.PostForm.Get("api_key") != "key" {
+			fmt.Fprint(w, `{"stat":"fail","error":{"type":"invalid_parameter","parameter_name":"api_key","message":"api_key is invalid."}}`)
+			return
+		}
+		// 51 monitors: a full page and one more
+		if r.PostForm.Get("offset") == "0" {
+			fmt.Fprint(w, `{"stat":"ok","pagination":{"offset":0,"limit":50,"total":51},"monitors":[`)
+			for i := 1; i <= 50; i++ {
+				if i > 1 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"id":%d,"friendly_name":"m%d","url":"https://example.com/%d","type":1,"sub_type":"","port":"","interval":300,"status":2}`, i, i, i)
+			}
+			fmt.Fprint(w, `]}`)
+			return
+		}
+		fmt.Fprint(w, `{"stat":"ok","pagination":{"offset":50,"limit":50,"total":51},"monitors":[{"id":51,"friendly_name":"db","url":"db.example.com","type":4,"sub_type":99,"port":5432,"interval":60,"status":0}]}`)
+	}))
+	defer server.Close()
+
+	monitors, err := NewClient(server.URL, "key", 5*time.Second).Monitors(context.Background())
+	require.NoError(t, err)
+	require.Len(t, monitors, 51)
+	assert.Equal(t, "m1", monitors[0].FriendlyName)
+	assert.Equal(t, 5432, number(monitors[50].Port))
+
+	_, err = NewClient(server.URL, "wrong", 5*time.Second).Monitors(context.Background())
+	assert.ErrorContains(t, err, "invalid_parameter: api_key is invalid.")
+}
+
+func TestConvert(t *testing.T) {
+	monitors := []Monitor{
+		{ID: 1, FriendlyName: "web", URL: "https://example.com/health", Type: typeHTTP, Interval: 300, Status: 2},
+		{ID: 2, FriendlyName: "web", URL: "https://example.com/", Type: typeKeyword, KeywordType: float64(2), KeywordValue: "Welcome!", Interval: 60, Status: 0},
+		{ID: 3, FriendlyName: "errors", URL: "https://example.com/status", Type: typeKeyword, KeywordType: "1", KeywordValue: "error", HTTPUsername: "admin", Status: 2},
+		{ID: 4, FriendlyName: "db", URL: "db.example.com", Type: typePort, SubType: float64(99), Port: "5432", Status: 2},
+		{ID: 5, FriendlyName: "smtp", URL: "mail.example.com", Type: typePort, SubType: "4", Port: "", Status: 2},
+		{ID: 6, FriendlyName: "ping", URL: "example.com", Type: typePing},
+		{ID: 7, FriendlyName: "cron", Type: typeHeartbeat},
+		{ID: 8, FriendlyName: "custom", URL: "example.com", Type: typePort, SubType: float64(99)},
+	}
+
+	targets, warnings := Convert(monitors)
+
+	assert.Equal(t, []config.Target{
+		{URL: "https://example.com/health", Name: "web", Labels: map[string]string{IDLabel: "1"}, Interval: "300s"},
+		{URL: "https://example.com/", Name: "web-2", Labels: map[string]string{IDLabel: "2"}, Method: "GET", ExpectBody: "Welcome!", Interval: "60s", Disabled: true},
+		{URL: "https://example.com/status", Name: "errors", Labels: map[string]string{IDLabel: "3"}},
+		{URL: "tcp://db.example.com:5432", Name: "db", Labels: map[string]string{IDLabel: "4"}},
+		{URL: "tcp://mail.example.com:25", Name: "smtp", Labels: map[string]string{IDLabel: "5"}},
+	}, targets)
+	assert.Equal(t, []string{
+		"monitor 3 (errors): alerting when the keyword exists has no equivalent, the keyword was not imported",
+		"monitor 3 (errors): HTTP authentication was not imported",
+		"monitor 6 (ping): ping monitors have no equivalent, skipped",
+		"monitor 7 (cron): heartbeat monitors have no equivalent, skipped",
+		"monitor 8 (custom): port monitor without port, skipped",
+	}, warnings)
+
+	for _, target := range targets {
+		assert.NoError(t, target.Validate(), target.URL)
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/jasoet/url-exporter/internal/config"
 	"github.com/jasoet/url-exporter/internal/server"
+	"github.com/jasoet/url-exporter/internal/syslog"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"io"
 	"os"
 )
 
@@ -15,33 +20,59 @@ var (
 	builtBy = "unknown"
 )
 
+// commands are the subcommands run instead of the exporter
+var commands = map[string]func(args []string, stdout io.Writer) error{
+	"check":      runCheck,
+	"debug":      runDebug,
+	"export":     runExport,
+	"import":     runImport,
+	"statuspage": runStatusPage,
+	"top":        runTop,
+}
+
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
+	if len(os.Args) > 1 {
+		if command, exists := commands[os.Args[1]]; exists {
+			if err := command(os.Args[2:], os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				code := 1
+				var exit *exitError
+				if errors.As(err, &exit) {
+					code = exit.code
+				}
+				os.Exit(code)
+			}
+			return
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
-	level, err := zerolog.ParseLevel(cfg.LogLevel)
-	if err != nil {
-		log.Warn().Str("level", cfg.LogLevel).Msg("Invalid log level, using info")
-		level = zerolog.InfoLevel
+	if err := configureLogging(cfg); err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure logging")
 	}
-	zerolog.SetGlobalLevel(level)
 
-	log.Info().
+	event := log.Info().
 		Str("version", version).
 		Str("commit", commit).
 		Str("date", date).
 		Str("built_by", builtBy).
 		Str("instance", cfg.InstanceID).
-		Int("port", cfg.ListenPort).
-		Int("targets", len(cfg.Targets)).
+		Str("address", cfg.Address()).
+		Int("targets", len(cfg.AllTargets())).
+		Str("probe_mode", cfg.ProbeMode).
 		Str("check_interval", cfg.CheckInterval.String()).
-		Str("timeout", cfg.Timeout.String()).
-		Msg("Starting URL Exporter")
+		Str("timeout", cfg.Timeout.String())
+	if cfg.Sharded() {
+		event = event.Int("shard_index", cfg.ShardIndex).Int("shard_total", cfg.ShardTotal)
+	}
+	event.Msg("Starting URL Exporter")
 
 	versionInfo := &server.VersionInfo{
 		Version: version,
@@ -59,3 +90,37 @@ func main() {
 		log.Fatal().Err(err).Msg("Server failed to start")
 	}
 }
+
+// configureLogging applies the configured log format, syslog output and level to the
+// global logger
+func configureLogging(cfg *config.Config) error {
+	var output io.Writer = zerolog.ConsoleWriter{Out: os.Stderr}
+	if cfg.LogFormat == config.LogFormatJSON {
+		output = os.Stderr
+	}
+
+	if cfg.Syslog.Enabled() {
+		writer, err := syslog.New(cfg.Syslog, cfg.InstanceID)
+		if err != nil {
+			return err
+		}
+		output = zerolog.MultiLevelWriter(output, writer)
+	}
+
+	log.Logger = zerolog.New(output).With().Timestamp().Logger()
+
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Warn().Str("level", cfg.LogLevel).Msg("Invalid log level, using info")
+		level = zerolog.InfoLevel
+	}
+
+	// The configured level moves to the logger so the global level lets the debug logs of
+	// the debugTargets through
+	if len(cfg.DebugTargets) > 0 && level > zerolog.DebugLevel {
+		log.Logger = log.Logger.Level(level)
+		level = zerolog.DebugLevel
+	}
+	zerolog.SetGlobalLevel(level)
+	return nil
+}
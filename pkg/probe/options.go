@@ -0,0 +1,138 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+)
+
+// Option configures a Prober
+type Option func(s *settings) error
+
+// settings is the configuration the options build, kept unexported so the options stay
+// the only way to change it
+type settings struct {
+	config        *config.Config
+	sinks         []ResultSink
+	cycleHandlers []CycleHandler
+	hooks         []Hook
+}
+
+// WithURLs adds targets checked with the default settings
+func WithURLs(urls ...string) Option {
+	return func(s *settings) error {
+		s.config.Targets = append(s.config.Targets, urls...)
+		return nil
+	}
+}
+
+// WithTargets adds targets with their own settings
+func WithTargets(targets ...Target) Option {
+	return func(s *settings) error {
+		s.config.Checks = append(s.config.Checks, targets...)
+		return nil
+	}
+}
+
+// WithModule defines a module that targets can select. Module names are case-insensitive.
+func WithModule(name string, module Module) Option {
+	return func(s *settings) error {
+		if name == "" {
+			return fmt.Errorf("module name must not be empty")
+		}
+		if s.config.Modules == nil {
+			s.config.Modules = make(map[string]config.Module)
+		}
+		s.config.Modules[strings.ToLower(name)] = module
+		return nil
+	}
+}
+
+// WithInterval sets how often Run checks each target that has no interval of its own
+func WithInterval(interval time.Duration) Option {
+	return func(s *settings) error {
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+		s.config.CheckInterval = interval
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of a single check attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *settings) error {
+		if timeout <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+		s.config.Timeout = timeout
+		return nil
+	}
+}
+
+// WithRetries sets how often a failed check is retried before it counts as down
+func WithRetries(retries int) Option {
+	return func(s *settings) error {
+		if retries < 0 {
+			return fmt.Errorf("retries must not be negative")
+		}
+		s.config.Retries = retries
+		return nil
+	}
+}
+
+// WithInstance sets the instance label of the metrics, by default the hostname
+func WithInstance(instance string) Option {
+	return func(s *settings) error {
+		s.config.InstanceID = instance
+		return nil
+	}
+}
+
+// WithWarmup spreads the first checks of Run across the period instead of the interval
+func WithWarmup(warmup time.Duration) Option {
+	return func(s *settings) error {
+		if warmup < 0 {
+			return fmt.Errorf("warmup must not be negative")
+		}
+		s.config.Warmup = warmup
+		return nil
+	}
+}
+
+// WithHook runs the hook around every check of the prober. Hooks run in the order they
+// are given, see Hook.
+func WithHook(hook Hook) Option {
+	return func(s *settings) error {
+		if hook == nil {
+			return fmt.Errorf("hook must not be nil")
+		}
+		s.hooks = append(s.hooks, hook)
+		return nil
+	}
+}
+
+// WithSink passes every result of the prober's cycles to the sink, after the url_* metrics
+// have recorded it
+func WithSink(sink ResultSink) Option {
+	return func(s *settings) error {
+		if sink == nil {
+			return fmt.Errorf("sink must not be nil")
+		}
+		s.sinks = append(s.sinks, sink)
+		return nil
+	}
+}
+
+// WithCycleHandler passes the results of each of the prober's cycles to the handler
+func WithCycleHandler(handler CycleHandler) Option {
+	return func(s *settings) error {
+		if handler == nil {
+			return fmt.Errorf("cycle handler must not be nil")
+		}
+		s.cycleHandlers = append(s.cycleHandlers, handler)
+		return nil
+	}
+}
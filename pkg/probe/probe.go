@@ -0,0 +1,217 @@
+// Package probe embeds the URL checks of url-exporter in other services. A Prober checks
+// targets with the same semantics as the exporter: the same protocols, retries, modules,
+// response assertions and url_* Prometheus metrics.
+//
+//	prober, err := probe.New(
+//		probe.WithURLs("https://example.com"),
+//		probe.WithInterval(time.Minute),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	registry.MustRegister(prober.Collector())
+//	go prober.Run(ctx)
+//
+// Single checks do not need Run:
+//
+//	result, err := prober.CheckURL(ctx, "https://example.com/health")
+//
+// The package is the stable API of the module; everything under internal/ may change
+// between releases. Logs go to the global zerolog logger.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Target is a URL to check with its optional settings, as under checks in the exporter's
+// configuration
+type Target = config.Target
+
+// Module is a named, reusable set of check settings that targets select with Module
+type Module = config.Module
+
+// Result is the outcome of a single check
+type Result = checker.Result
+
+// ResultSink records the result of every scheduled check and RunOnce, e.g. to forward the
+// results to a queue or database. Sinks are called synchronously once all checks of a cycle
+// have completed, sorted by URL, so a slow sink delays the next cycle instead of losing
+// results.
+type ResultSink = checker.ResultSink
+
+// ResultSinkFunc adapts a function to a ResultSink
+type ResultSinkFunc = checker.ResultSinkFunc
+
+// CycleHandler receives all results of a cycle at once, after the sinks, e.g. to write
+// them as a batch
+type CycleHandler = checker.CycleHandler
+
+// Hook runs around every check: BeforeCheck may alter the context the target is checked
+// with or fail the check, AfterCheck may change the result before it is recorded
+type Hook = checker.Hook
+
+// WithRequestHeader returns a context whose HTTP checks send the header, for use in a
+// hook's BeforeCheck, e.g. to inject a short-lived token
+func WithRequestHeader(ctx context.Context, name, value string) context.Context {
+	return checker.WithRequestHeader(ctx, name, value)
+}
+
+// ProtocolChecker checks targets of a URL scheme. A check returning a 2xx status is up.
+type ProtocolChecker = checker.ProtocolChecker
+
+// ProtocolOptions are the settings a registered protocol's checker is created with
+type ProtocolOptions = checker.ProtocolOptions
+
+// ProtocolFactory creates the checker of a registered protocol for each Prober
+type ProtocolFactory = checker.ProtocolFactory
+
+// RegisterProtocol adds a URL scheme that targets can use, for every Prober and the
+// exporter. Call it from an init function; it panics if the scheme is empty, built in or
+// already registered.
+func RegisterProtocol(scheme string, factory ProtocolFactory) {
+	checker.RegisterProtocol(scheme, factory)
+}
+
+// ErrTargetExists is returned when adding a target whose URL or name is already used
+var ErrTargetExists = checker.ErrTargetExists
+
+// Defaults of the settings that have no option given
+const (
+	DefaultInterval = 30 * time.Second
+	DefaultTimeout  = 10 * time.Second
+	DefaultRetries  = 3
+)
+
+// Prober checks a set of targets, once on demand or on a schedule, and exports the results
+// as Prometheus metrics
+type Prober struct {
+	config    *config.Config
+	checker   *checker.Checker
+	collector *metrics.Collector
+}
+
+// New creates a prober. Without options it has no targets and uses the defaults above.
+func New(options ...Option) (*Prober, error) {
+	cfg := &config.Config{
+		CheckInterval: DefaultInterval,
+		Timeout:       DefaultTimeout,
+		Retries:       DefaultRetries,
+		ProbeMode:     config.ProbeModeInterval,
+		History:       config.HistoryConfig{Size: config.DefaultHistorySize},
+		SLO:           config.SLOConfig{Windows: config.DefaultSLOWindows(), Period: config.DefaultSLOPeriod},
+		DNSCache:      config.DNSCacheConfig{MaxTTL: config.DefaultDNSCacheMaxTTL},
+	}
+	s := &settings{config: cfg}
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.InstanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname for the instance label: %w", err)
+		}
+		cfg.InstanceID = hostname
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = cfg.Timeout
+	}
+	if err := cfg.ValidateChecks(); err != nil {
+		return nil, err
+	}
+	for _, url := range cfg.Targets {
+		if err := (Target{URL: url}).Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	chk := checker.New(cfg)
+	col := metrics.NewCollector(cfg, chk)
+	chk.AddSink(col)
+	for _, sink := range s.sinks {
+		chk.AddSink(sink)
+	}
+	for _, handler := range s.cycleHandlers {
+		chk.OnCycle(handler)
+	}
+	for _, hook := range s.hooks {
+		chk.AddHook(hook)
+	}
+
+	return &Prober{config: cfg, checker: chk, collector: col}, nil
+}
+
+// Run checks every target once per interval, spread across the interval, until the
+// context is done or Shutdown is called
+func (p *Prober) Run(ctx context.Context) {
+	p.checker.Start(ctx)
+}
+
+// Shutdown stops Run, waiting for the checks in flight until the context is done. Result
+// channels from Subscribe are closed.
+func (p *Prober) Shutdown(ctx context.Context) error {
+	return p.checker.Shutdown(ctx)
+}
+
+// Check checks the target once. It does not need to be one of the prober's targets, and
+// its result is not recorded in the metrics.
+func (p *Prober) Check(ctx context.Context, target Target) (Result, error) {
+	return p.checker.CheckTarget(ctx, target)
+}
+
+// CheckURL checks the URL once with the default settings
+func (p *Prober) CheckURL(ctx context.Context, url string) (Result, error) {
+	return p.Check(ctx, Target{URL: url})
+}
+
+// RunOnce checks all targets once, records the results in the metrics and returns them
+func (p *Prober) RunOnce(ctx context.Context) ([]Result, error) {
+	return p.checker.RunCycle(ctx)
+}
+
+// Subscribe returns a channel receiving the result of every scheduled check and a function
+// to unsubscribe. Results are dropped for a subscriber that does not keep up.
+func (p *Prober) Subscribe() (<-chan Result, func()) {
+	return p.checker.Subscribe()
+}
+
+// AddTarget adds a target, which Run checks from its next tick
+func (p *Prober) AddTarget(target Target) error {
+	if err := p.checker.AddTarget(target); err != nil {
+		return err
+	}
+	p.collector.AddTarget(target)
+	return nil
+}
+
+// RemoveTarget removes the target with the URL and its series, returning false if there
+// is none
+func (p *Prober) RemoveTarget(url string) bool {
+	if !p.checker.RemoveTarget(url) {
+		return false
+	}
+	p.collector.RemoveTarget(url)
+	return true
+}
+
+// Targets returns the targets being checked
+func (p *Prober) Targets() []Target {
+	return p.checker.Targets()
+}
+
+// Collector returns the collector of the url_* metrics, to register with a Prometheus
+// registry
+func (p *Prober) Collector() prometheus.Collector {
+	return p.collector
+}
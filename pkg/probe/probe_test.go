@@ -0,0 +1,211 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTarget(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNew_Options(t *testing.T) {
+	prober, err := New(
+		WithURLs("https://example.com"),
+		WithTargets(Target{URL: "https://api.example.com", Module: "json"}),
+		WithModule("JSON", Module{ExpectBody: `"status":"ok"`}),
+		WithInterval(time.Minute),
+		WithTimeout(3*time.Second),
+		WithRetries(0),
+		WithInstance("embedded"),
+	)
+	require.NoError(t, err)
+	assert.Len(t, prober.Targets(), 2)
+	assert.Equal(t, time.Minute, prober.config.CheckInterval)
+	assert.Equal(t, 3*time.Second, prober.config.Timeout)
+	assert.Equal(t, 0, prober.config.Retries)
+	assert.Equal(t, "embedded", prober.config.InstanceID)
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	invalid := map[string][]Option{
+		"interval":       {WithInterval(0)},
+		"timeout":        {WithTimeout(-time.Second)},
+		"retries":        {WithRetries(-1)},
+		"unknown module": {WithTargets(Target{URL: "https://example.com", Module: "missing"})},
+		"target":         {WithURLs("")},
+		"duplicate name": {WithTargets(Target{URL: "https://a.example.com", Name: "a"}, Target{URL: "https://b.example.com", Name: "a"})},
+	}
+	for name, options := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(options...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProber_Check(t *testing.T) {
+	target := newTestTarget(t, http.StatusOK, `{"status":"ok"}`)
+	prober, err := New(WithRetries(0), WithModule("json", Module{ExpectBody: `"status":"ok"`}))
+	require.NoError(t, err)
+
+	result, err := prober.CheckURL(context.Background(), target.URL)
+	require.NoError(t, err)
+	assert.True(t, result.IsUp())
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+
+	result, err = prober.Check(context.Background(), Target{URL: target.URL, Module: "json"})
+	require.NoError(t, err)
+	require.NotNil(t, result.BodyMatch)
+	assert.True(t, *result.BodyMatch)
+
+	_, err = prober.Check(context.Background(), Target{URL: target.URL, Method: "BREW"})
+	assert.Error(t, err)
+}
+
+func TestProber_RunOnceRecordsMetrics(t *testing.T) {
+	up := newTestTarget(t, http.StatusOK, "")
+	down := newTestTarget(t, http.StatusServiceUnavailable, "")
+
+	prober, err := New(WithURLs(up.URL, down.URL), WithRetries(0), WithInstance("embedded"))
+	require.NoError(t, err)
+
+	results, err := prober.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(prober.Collector()))
+
+	expected := `
+# HELP url_up URL is up (1 if URL returns 2xx status, 0 otherwise, -1 if skipped because a dependency is down)
+# TYPE url_up gauge
+url_up{host="` + down.URL + `",instance="embedded",path="/",protocol="http",url="` + down.URL + `"} 0
+url_up{host="` + up.URL + `",instance="embedded",path="/",protocol="http",url="` + up.URL + `"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "url_up"))
+}
+
+func TestProber_Targets(t *testing.T) {
+	prober, err := New(WithURLs("https://example.com"))
+	require.NoError(t, err)
+
+	require.NoError(t, prober.AddTarget(Target{URL: "https://api.example.com"}))
+	err = prober.AddTarget(Target{URL: "https://api.example.com"})
+	assert.True(t, errors.Is(err, ErrTargetExists))
+	assert.Len(t, prober.Targets(), 2)
+
+	assert.True(t, prober.RemoveTarget("https://api.example.com"))
+	assert.False(t, prober.RemoveTarget("https://api.example.com"))
+	assert.Len(t, prober.Targets(), 1)
+}
+
+func TestProber_RunAndShutdown(t *testing.T) {
+	target := newTestTarget(t, http.StatusOK, "")
+	prober, err := New(WithURLs(target.URL), WithInterval(time.Hour), WithRetries(0))
+	require.NoError(t, err)
+
+	results, unsubscribe := prober.Subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		prober.Run(context.Background())
+	}()
+
+	select {
+	case result := <-results:
+		assert.Equal(t, target.URL, result.URL)
+		assert.True(t, result.IsUp())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result from the scheduled check")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, prober.Shutdown(ctx))
+	<-done
+}
+
+// tokenHook sends a bearer token with every check and counts the results
+type tokenHook struct {
+	token   string
+	results int
+}
+
+func (h *tokenHook) BeforeCheck(ctx context.Context, target Target) (context.Context, error) {
+	return WithRequestHeader(ctx, "Authorization", "Bearer "+h.token), nil
+}
+
+func (h *tokenHook) AfterCheck(ctx context.Context, target Target, result *Result) {
+	h.results++
+}
+
+func TestProber_WithHook(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer target.Close()
+
+	hook := &tokenHook{token: "secret"}
+	prober, err := New(WithURLs(target.URL), WithRetries(0), WithHook(hook))
+	require.NoError(t, err)
+
+	results, err := prober.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].IsUp())
+	assert.Equal(t, 1, hook.results)
+
+	_, err = New(WithHook(nil))
+	assert.Error(t, err)
+}
+
+func TestProber_WithSink(t *testing.T) {
+	up := newTestTarget(t, http.StatusOK, "")
+	down := newTestTarget(t, http.StatusBadGateway, "")
+
+	var recorded []string
+	var cycles [][]Result
+	prober, err := New(
+		WithURLs(up.URL, down.URL),
+		WithRetries(0),
+		WithSink(ResultSinkFunc(func(result Result) {
+			recorded = append(recorded, result.URL)
+		})),
+		WithCycleHandler(func(ctx context.Context, results []Result) {
+			cycles = append(cycles, results)
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = prober.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{up.URL, down.URL}, recorded)
+	require.Len(t, cycles, 1)
+	assert.Len(t, cycles[0], 2)
+
+	_, err = New(WithSink(nil))
+	assert.Error(t, err)
+	_, err = New(WithCycleHandler(nil))
+	assert.Error(t, err)
+}
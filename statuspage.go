@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/jasoet/url-exporter/internal/statuspage"
+)
+
+// runStatusPage implements the statuspage subcommand: it renders the status page from the
+// saved history (history.file) as a static HTML file, e.g. to publish on a CDN
+func runStatusPage(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("statuspage", flag.ContinueOnError)
+	file := flags.String("file", "", "history file to read (default history.file from the configuration)")
+	output := flags.String("output", "", "file to write the page to (default stdout)")
+	title := flags.String("title", "", "page title (default statusPage.title from the configuration)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	cfg, targets, saved, err := loadSavedHistory(*file)
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		*title = cfg.StatusPage.Title
+	}
+
+	now := time.Now()
+	page := statuspage.Build(*title, targets, savedSource{saved: saved, now: now}, now)
+
+	return writeOutput(*output, stdout, func(w io.Writer) error {
+		if err := statuspage.Render(w, page); err != nil {
+			return fmt.Errorf("failed to render status page: %w", err)
+		}
+		return nil
+	})
+}
+
+// savedSource serves the status page from a saved history, taking the last recorded check
+// of each target as its current state
+type savedSource struct {
+	saved *metrics.SavedHistory
+	now   time.Time
+}
+
+func (s savedSource) Current(url string) (bool, bool) {
+	points := s.saved.Targets[url]
+	if len(points) == 0 {
+		return false, false
+	}
+	return points[len(points)-1].Up, true
+}
+
+func (s savedSource) Daily(url string, days int) []metrics.DailyUptime {
+	return metrics.DailySeries(s.saved.Daily[url], days, s.now)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStatusPage(t *testing.T) {
+	dir := t.TempDir()
+	historyFile := filepath.Join(dir, "history.json")
+	configFile := filepath.Join(dir, "config.yaml")
+
+	config := `checks:
+  - url: "https://example.com/health"
+    name: "Website"
+statusPage:
+  title: "Acme Status"
+history:
+  file: "` + historyFile + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+	t.Setenv("URL_CONFIG_FILE", configFile)
+
+	now := time.Now().UTC()
+	saved := metrics.SavedHistory{
+		Targets: map[string][]metrics.HistoryPoint{
+			"https://example.com/health": {{Timestamp: now, Up: false, Error: "timeout"}},
+		},
+		Daily: map[string][]metrics.DailyUptime{
+			"https://example.com/health": {{Day: now.Truncate(24 * time.Hour), Checks: 200, Successful: 190}},
+		},
+	}
+	content, err := json.Marshal(saved)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(historyFile, content, 0644))
+
+	output := filepath.Join(dir, "index.html")
+	require.NoError(t, runStatusPage([]string{"-output", output}, nil))
+
+	content, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "<title>Acme Status</title>")
+	assert.Contains(t, string(content), "Some systems are down")
+	assert.Contains(t, string(content), "95.00% uptime")
+
+	assert.Error(t, runStatusPage([]string{"extra"}, nil))
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "os"
+
+// enableKeyInput is not supported: only the terminals of Linux, macOS and the BSDs are
+// switched to key input
+func enableKeyInput(_ *os.File) (func(), error) {
+	return nil, errKeyInputUnsupported
+}
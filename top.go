@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jasoet/url-exporter/internal/checker"
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// clearScreen moves the cursor home and clears the terminal before each frame
+const clearScreen = "\x1b[H\x1b[2J"
+
+// errKeyInputUnsupported is returned by enableKeyInput on systems whose terminals cannot be
+// switched to key input
+var errKeyInputUnsupported = errors.New("key input is not supported on " + runtime.GOOS)
+
+// topResult is a target's latest result as shown by the top subcommand, decoded from
+// /api/v1/results of a running exporter or converted from a local check
+type topResult struct {
+	URL            string    `json:"url"`
+	Up             bool      `json:"up"`
+	StatusCode     int       `json:"status_code"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	Error          string    `json:"error,omitempty"`
+	SkippedBy      string    `json:"skipped_by,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// topSource provides the latest result of every target
+type topSource interface {
+	Results(ctx context.Context) ([]topResult, error)
+}
+
+// runTop implements the top subcommand: an interactive terminal view of the live status,
+// latency and failure streak of every target, refreshed until quit. It watches a running
+// exporter through its API, or checks the configured targets itself without -url. When
+// stdin is no terminal the view is only refreshed, without key bindings; on systems
+// without key input the subcommand fails.
+func runTop(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("top", flag.ContinueOnError)
+	address := flags.String("url", "", "base URL of a running exporter, e.g. http://localhost:8412 (default check locally)")
+	token := flags.String("token", "", "bearer token for an exporter with auth enabled")
+	refresh := flags.Duration("refresh", 2*time.Second, "how often the view is refreshed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if *refresh <= 0 {
+		return errors.New("refresh must be positive")
+	}
+
+	restore, err := enableKeyInput(os.Stdin)
+	if errors.Is(err, errKeyInputUnsupported) {
+		return fmt.Errorf("top cannot run here: %w", err)
+	}
+	if err == nil {
+		defer restore()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var source topSource
+	title := *address
+	if *address != "" {
+		source = &remoteSource{baseURL: strings.TrimSuffix(*address, "/"), token: *token, client: &http.Client{Timeout: *refresh}}
+	} else {
+		local, err := startLocalSource(ctx)
+		if err != nil {
+			return err
+		}
+		source = local
+		title = "local checks"
+	}
+
+	view := newTopView(title)
+	var keys <-chan topKey
+	if restore != nil {
+		view.interactive = true
+		keys = readKeys(os.Stdin)
+	}
+
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	fetch := true
+	for {
+		if fetch {
+			results, err := source.Results(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			view.update(results, err)
+		}
+		fmt.Fprint(stdout, clearScreen)
+		if err := view.render(stdout, time.Now()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			fetch = true
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+			} else if view.handleKey(key) {
+				return nil
+			}
+			fetch = false
+		}
+	}
+}
+
+// topKey is a key pressed in the view: a character or one of the named keys below
+type topKey string
+
+const (
+	keyUp        topKey = "up"
+	keyDown      topKey = "down"
+	keyEnter     topKey = "enter"
+	keyEscape    topKey = "esc"
+	keyBackspace topKey = "backspace"
+)
+
+// readKeys sends the keys read from r until it fails
+func readKeys(r io.Reader) <-chan topKey {
+	keys := make(chan topKey)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+// parseKeys splits the bytes of a terminal read into keys. The arrow keys arrive as escape
+// sequences, a lone escape byte is the Escape key.
+func parseKeys(input []byte) []topKey {
+	var keys []topKey
+	for text := string(input); text != ""; {
+		switch {
+		case strings.HasPrefix(text, "\x1b[A"), strings.HasPrefix(text, "\x1bOA"):
+			keys, text = append(keys, keyUp), text[3:]
+			continue
+		case strings.HasPrefix(text, "\x1b[B"), strings.HasPrefix(text, "\x1bOB"):
+			keys, text = append(keys, keyDown), text[3:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+		switch {
+		case r == '\x1b':
+			keys = append(keys, keyEscape)
+		case r == '\r', r == '\n':
+			keys = append(keys, keyEnter)
+		case r == 0x7f, r == '\b':
+			keys = append(keys, keyBackspace)
+		case unicode.IsPrint(r):
+			keys = append(keys, topKey(r))
+		}
+	}
+	return keys
+}
+
+// remoteSource reads the results of a running exporter from /api/v1/results
+type remoteSource struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (r *remoteSource) Results(ctx context.Context) ([]topResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/api/v1/results", nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exporter URL: %w", err)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	response, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach exporter: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exporter answered %s", response.Status)
+	}
+
+	var body struct {
+		Results []topResult `json:"results"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+	return body.Results, nil
+}
+
+// localSource checks the configured targets on their intervals, as the exporter does, and
+// keeps the latest result of each
+type localSource struct {
+	mutex  sync.Mutex
+	latest map[string]topResult
+}
+
+func startLocalSource(ctx context.Context) (*localSource, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := checker.LoadPlugins(cfg.Plugins); err != nil {
+		return nil, err
+	}
+
+	// Check logs would scroll the view away
+	log.Logger = zerolog.Nop()
+	return newLocalSource(ctx, cfg), nil
+}
+
+// newLocalSource starts checking the targets of the configuration until ctx is done
+func newLocalSource(ctx context.Context, cfg *config.Config) *localSource {
+	source := &localSource{latest: make(map[string]topResult)}
+	chk := checker.New(cfg)
+	chk.AddSink(checker.ResultSinkFunc(source.record))
+	go chk.Start(ctx)
+	return source
+}
+
+func (l *localSource) record(result checker.Result) {
+	converted := topResult{
+		URL:            result.URL,
+		Up:             result.IsUp(),
+		StatusCode:     result.StatusCode,
+		ResponseTimeMs: result.ResponseTime.Milliseconds(),
+		SkippedBy:      result.SkippedBy,
+		Timestamp:      result.Timestamp,
+	}
+	if result.Error != nil {
+		converted.Error = result.Error.Error()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.latest[result.URL] = converted
+}
+
+func (l *localSource) Results(context.Context) ([]topResult, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	results := make([]topResult, 0, len(l.latest))
+	for _, result := range l.latest {
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// topView holds what the top subcommand shows: the latest results and, per target, the
+// number of consecutive failed checks it has seen. In interactive mode a target can be
+// selected to see its full error, and the targets filtered.
+type topView struct {
+	title   string
+	results []topResult
+	err     error
+	// seen is the timestamp of the last result counted in a target's streak
+	seen    map[string]time.Time
+	streaks map[string]int
+
+	interactive bool
+	// selected is the URL of the selected target, the first shown when empty
+	selected string
+	// filter keeps the targets whose URL or error contain it, ignoring case
+	filter string
+	// editing reports whether keys are typed into the filter
+	editing bool
+}
+
+func newTopView(title string) *topView {
+	return &topView{title: title, seen: make(map[string]time.Time), streaks: make(map[string]int)}
+}
+
+// update replaces the results, counting every result not seen before in the failure
+// streaks. An error keeps the previous results on screen.
+func (v *topView) update(results []topResult, err error) {
+	v.err = err
+	if err != nil {
+		return
+	}
+
+	for _, result := range results {
+		if result.Timestamp.IsZero() || result.Timestamp.Equal(v.seen[result.URL]) {
+			continue
+		}
+		v.seen[result.URL] = result.Timestamp
+		switch {
+		case result.Up:
+			v.streaks[result.URL] = 0
+		case result.SkippedBy == "":
+			// A skipped check says nothing about the target itself
+			v.streaks[result.URL]++
+		}
+	}
+
+	// Down targets first, the longest streaks on top, then skipped ones
+	sort.Slice(results, func(i, j int) bool {
+		if topRank(results[i]) != topRank(results[j]) {
+			return topRank(results[i]) < topRank(results[j])
+		}
+		if v.streaks[results[i].URL] != v.streaks[results[j].URL] {
+			return v.streaks[results[i].URL] > v.streaks[results[j].URL]
+		}
+		return results[i].URL < results[j].URL
+	})
+	v.results = results
+}
+
+// topRank orders the results by status: down, skipped, up
+func topRank(result topResult) int {
+	switch {
+	case result.Up:
+		return 2
+	case result.SkippedBy != "":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// topStatus is the status shown for the result
+func topStatus(result topResult) string {
+	switch {
+	case result.Up:
+		return "UP"
+	case result.SkippedBy != "":
+		return "SKIPPED"
+	default:
+		return "DOWN"
+	}
+}
+
+// topError is the error shown for the result, the down dependency of a skipped check
+func topError(result topResult) string {
+	if result.SkippedBy != "" {
+		return "dependency " + config.RedactURL(result.SkippedBy) + " is down"
+	}
+	return result.Error
+}
+
+// handleKey applies a key pressed in interactive mode and reports whether it quits the view
+func (v *topView) handleKey(key topKey) bool {
+	if v.editing {
+		switch key {
+		case keyEnter:
+			v.editing = false
+		case keyEscape:
+			v.filter, v.editing = "", false
+		case keyBackspace:
+			if filter := []rune(v.filter); len(filter) > 0 {
+				v.filter = string(filter[:len(filter)-1])
+			}
+		case keyUp, keyDown:
+		default:
+			v.filter += string(key)
+		}
+		return false
+	}
+
+	switch key {
+	case "q":
+		return true
+	case keyUp, "k":
+		v.move(-1)
+	case keyDown, "j":
+		v.move(1)
+	case "/":
+		v.editing = true
+	case keyEscape:
+		v.filter = ""
+	}
+	return false
+}
+
+// move selects the target shown delta rows below the selected one
+func (v *topView) move(delta int) {
+	shown := v.shown()
+	if len(shown) == 0 {
+		return
+	}
+	i := max(0, min(len(shown)-1, v.selectedIndex(shown)+delta))
+	v.selected = shown[i].URL
+}
+
+// shown returns the results matching the filter
+func (v *topView) shown() []topResult {
+	if v.filter == "" {
+		return v.results
+	}
+	filter := strings.ToLower(v.filter)
+	var shown []topResult
+	for _, result := range v.results {
+		if strings.Contains(strings.ToLower(config.RedactURL(result.URL)), filter) || strings.Contains(strings.ToLower(topError(result)), filter) {
+			shown = append(shown, result)
+		}
+	}
+	return shown
+}
+
+// selectedIndex returns the row of the selected target, the first when it is not shown
+func (v *topView) selectedIndex(shown []topResult) int {
+	for i, result := range shown {
+		if result.URL == v.selected {
+			return i
+		}
+	}
+	return 0
+}
+
+func (v *topView) render(w io.Writer, now time.Time) error {
+	down, skipped := 0, 0
+	for _, result := range v.results {
+		switch topRank(result) {
+		case 0:
+			down++
+		case 1:
+			skipped++
+		}
+	}
+	quit := "Ctrl-C to quit"
+	if v.interactive {
+		quit = "q to quit"
+	}
+	fmt.Fprintf(w, "url-exporter top - %s - %d targets, %d down, %d skipped - %s (%s)\n", v.title, len(v.results), down, skipped, now.Format(time.TimeOnly), quit)
+	if v.err != nil {
+		fmt.Fprintf(w, "error: %v\n", v.err)
+	}
+	if v.interactive {
+		switch {
+		case v.editing:
+			fmt.Fprintf(w, "filter: %s_ (enter to apply, esc to clear)\n", v.filter)
+		case v.filter != "":
+			fmt.Fprintf(w, "filter: %s (/ to edit, esc to clear) - up/down or j/k to select\n", v.filter)
+		default:
+			fmt.Fprintln(w, "up/down or j/k to select, / to filter")
+		}
+	}
+	fmt.Fprintln(w)
+
+	shown := v.shown()
+	selected := v.selectedIndex(shown)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "STATUS\tCODE\tLATENCY\tFAILS\tCHECKED\tURL\tERROR\n"
+	if v.interactive {
+		header = " \t" + header
+	}
+	fmt.Fprint(table, header)
+	for i, result := range shown {
+		if v.interactive {
+			marker := " "
+			if i == selected {
+				marker = ">"
+			}
+			fmt.Fprint(table, marker+"\t")
+		}
+		checked := "-"
+		if !result.Timestamp.IsZero() {
+			checked = now.Sub(result.Timestamp).Truncate(time.Second).String() + " ago"
+		}
+		fmt.Fprintf(table, "%s\t%d\t%dms\t%d\t%s\t%s\t%s\n", topStatus(result), result.StatusCode, result.ResponseTimeMs, v.streaks[result.URL], checked, config.RedactURL(result.URL), topError(result))
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("failed to render view: %w", err)
+	}
+
+	// The full error of the selected target, which the table shows on one line
+	if v.interactive && len(shown) > 0 {
+		result := shown[selected]
+		fmt.Fprintf(w, "\n%s\n", config.RedactURL(result.URL))
+		if message := topError(result); message != "" {
+			fmt.Fprintf(w, "  %s\n", message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoet/url-exporter/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/api/v1/results", r.URL.Path)
+		_, _ = w.Write([]byte(`{"results":[{"url":"https://example.com","up":false,"status_code":503,"response_time_ms":12,"error":"","timestamp":"2026-01-01T00:00:00Z"},` +
+			`{"url":"https://api.example.com","up":false,"status_code":0,"response_time_ms":0,"skipped_by":"https://example.com","timestamp":"2026-01-01T00:00:00Z"}]}`))
+	}))
+	defer server.Close()
+
+	source := &remoteSource{baseURL: server.URL, token: "secret", client: server.Client()}
+	results, err := source.Results(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "https://example.com", results[0].URL)
+	assert.False(t, results[0].Up)
+	assert.Equal(t, 503, results[0].StatusCode)
+	assert.Equal(t, int64(12), results[0].ResponseTimeMs)
+	assert.Empty(t, results[0].SkippedBy)
+	assert.Equal(t, "https://example.com", results[1].SkippedBy)
+
+	source.token = ""
+	_, err = source.Results(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestLocalSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gateway" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := newLocalSource(ctx, &config.Config{
+		Timeout:       5 * time.Second,
+		CheckInterval: 50 * time.Millisecond,
+		Checks: []config.Target{
+			{URL: server.URL + "/web"},
+			{URL: server.URL + "/gateway", Name: "gateway"},
+			{URL: server.URL + "/api", DependsOn: []string{"gateway"}},
+		},
+	})
+
+	// The first checks are spread over the interval, and the dependent one is skipped once
+	// the gateway has been seen down
+	byURL := make(map[string]topResult)
+	require.Eventually(t, func() bool {
+		results, err := source.Results(ctx)
+		require.NoError(t, err)
+		for _, result := range results {
+			byURL[result.URL] = result
+		}
+		return len(byURL) == 3 && byURL[server.URL+"/api"].SkippedBy != ""
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, byURL[server.URL+"/web"].Up)
+	assert.Equal(t, http.StatusOK, byURL[server.URL+"/web"].StatusCode)
+	assert.False(t, byURL[server.URL+"/gateway"].Up)
+	assert.Equal(t, http.StatusBadGateway, byURL[server.URL+"/gateway"].StatusCode)
+	assert.Empty(t, byURL[server.URL+"/gateway"].SkippedBy)
+	assert.False(t, byURL[server.URL+"/api"].Up)
+	assert.Equal(t, "gateway", byURL[server.URL+"/api"].SkippedBy)
+	assert.False(t, byURL[server.URL+"/api"].Timestamp.IsZero())
+}
+
+func TestTopView(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	view := newTopView("http://localhost:8412")
+
+	results := func(at time.Time, apiUp bool) []topResult {
+		return []topResult{
+			{URL: "https://web.example.com", Up: true, StatusCode: 200, ResponseTimeMs: 42, Timestamp: at},
+			{URL: "https://api.example.com", Up: apiUp, StatusCode: 503, ResponseTimeMs: 7, Timestamp: at},
+		}
+	}
+
+	view.update(results(now.Add(-20*time.Second), false), nil)
+	view.update(results(now.Add(-20*time.Second), false), nil) // the same results again
+	view.update(results(now.Add(-10*time.Second), false), nil)
+	assert.Equal(t, 2, view.streaks["https://api.example.com"], "every result counts once")
+	assert.Equal(t, 0, view.streaks["https://web.example.com"])
+
+	var out bytes.Buffer
+	require.NoError(t, view.render(&out, now))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Contains(t, lines[0], "2 targets, 1 down")
+	assert.Equal(t, []string{"STATUS", "CODE", "LATENCY", "FAILS", "CHECKED", "URL", "ERROR"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"DOWN", "503", "7ms", "2", "10s", "ago", "https://api.example.com"}, strings.Fields(lines[3]), "down targets first")
+	assert.Equal(t, []string{"UP", "200", "42ms", "0", "10s", "ago", "https://web.example.com"}, strings.Fields(lines[4]))
+
+	view.update(nil, errors.New("failed to reach exporter"))
+	out.Reset()
+	require.NoError(t, view.render(&out, now))
+	assert.Contains(t, out.String(), "error: failed to reach exporter")
+	assert.Contains(t, out.String(), "https://api.example.com", "the last results stay on screen")
+
+	view.update(results(now, true), nil)
+	assert.Equal(t, 0, view.streaks["https://api.example.com"], "an up result ends the streak")
+}
+
+func TestTopView_Skipped(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	view := newTopView("http://localhost:8412")
+
+	for _, at := range []time.Time{now.Add(-20 * time.Second), now.Add(-10 * time.Second)} {
+		view.update([]topResult{
+			{URL: "https://web.example.com", Up: true, StatusCode: 200, Timestamp: at},
+			{URL: "https://db.example.com", Error: "connection refused", Timestamp: at},
+			{URL: "https://api.example.com", SkippedBy: "https://db.example.com", Timestamp: at},
+		}, nil)
+	}
+	assert.Equal(t, 0, view.streaks["https://api.example.com"], "skipped checks are no failures")
+
+	var out bytes.Buffer
+	require.NoError(t, view.render(&out, now))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Contains(t, lines[0], "3 targets, 1 down, 1 skipped")
+	assert.Equal(t, "DOWN", strings.Fields(lines[3])[0])
+	assert.Equal(t, []string{"SKIPPED", "0", "0ms", "0", "10s", "ago", "https://api.example.com", "dependency", "https://db.example.com", "is", "down"}, strings.Fields(lines[4]))
+	assert.Equal(t, "UP", strings.Fields(lines[5])[0])
+}
+
+func TestTopView_Keys(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	view := newTopView("http://localhost:8412")
+	view.interactive = true
+	view.update([]topResult{
+		{URL: "https://web.example.com", Up: true, Timestamp: now},
+		{URL: "https://api.example.com", Error: "unexpected status code: 503", Timestamp: now},
+		{URL: "https://db.example.com", Error: "connection refused", Timestamp: now},
+	}, nil)
+
+	render := func() string {
+		var out bytes.Buffer
+		require.NoError(t, view.render(&out, now))
+		return out.String()
+	}
+	selected := func() string {
+		for _, line := range strings.Split(render(), "\n") {
+			if strings.HasPrefix(line, ">") {
+				return strings.Fields(line)[7]
+			}
+		}
+		return ""
+	}
+
+	assert.Equal(t, "https://api.example.com", selected(), "the first target is selected")
+	assert.Contains(t, render(), "unexpected status code: 503")
+	assert.False(t, view.handleKey(keyDown))
+	assert.Equal(t, "https://db.example.com", selected())
+	view.handleKey("j")
+	view.handleKey("j")
+	assert.Equal(t, "https://web.example.com", selected(), "the selection stops at the last target")
+	view.handleKey("k")
+	assert.Equal(t, "https://db.example.com", selected())
+
+	for _, key := range parseKeys([]byte("/API\x7fI\r")) {
+		assert.False(t, view.handleKey(key))
+	}
+	assert.Equal(t, "API", view.filter)
+	output := render()
+	assert.Contains(t, output, "filter: API")
+	assert.Contains(t, output, "https://api.example.com")
+	assert.NotContains(t, output, "https://db.example.com")
+	assert.Equal(t, "https://api.example.com", selected(), "the first shown target is selected")
+
+	view.handleKey("/")
+	view.handleKey("q")
+	assert.Equal(t, "APIq", view.filter, "q is typed into the filter")
+	view.handleKey(keyEscape)
+	assert.Empty(t, view.filter)
+	assert.Contains(t, render(), "https://db.example.com")
+
+	assert.True(t, view.handleKey("q"))
+}
+
+func TestParseKeys(t *testing.T) {
+	assert.Equal(t, []topKey{keyUp, keyDown, keyUp, "j", keyEscape, keyEnter, keyEnter, keyBackspace, keyBackspace, "é"},
+		parseKeys([]byte("\x1b[A\x1b[B\x1bOAj\x1b\r\n\x7f\bé\x01")))
+	assert.Empty(t, parseKeys(nil))
+}
+
+func TestRunTop_InvalidOptions(t *testing.T) {
+	assert.Error(t, runTop([]string{"-refresh", "0s"}, &bytes.Buffer{}))
+	assert.Error(t, runTop([]string{"extra"}, &bytes.Buffer{}))
+}